@@ -0,0 +1,159 @@
+package sazabi
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// AccessTimeLayout is the layout of the times of access lines, as written by Apache.
+const AccessTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// AccessRecord describes a request answered by the server, written by AccessLog in the
+// Apache combined log format. Empty strings are written as "-".
+type AccessRecord struct {
+	RemoteHost string    // Address of the client
+	Ident      string    // RFC 1413 identity of the client, almost always empty
+	User       string    // User authenticated by the request
+	Time       time.Time // Time the request was received
+	Method     string    // Method of the request line
+	URI        string    // URI of the request line, as sent by the client
+	Proto      string    // Protocol of the request line, such as "HTTP/1.1"
+	Status     int       // Status code of the response
+	Bytes      int64     // Number of body bytes sent, written as "-" when zero
+	Referer    string    // Referer header of the request
+	UserAgent  string    // User-Agent header of the request
+}
+
+// NewAccessRecord returns the record of request r answered with res, for instance in
+// the function given to WithRequestFields. The client is the host part of the remote
+// address, the user is the user of the URL or of basic authentication, and the time is
+// the time the handler started, res.Duration ago.
+func NewAccessRecord(r *http.Request, res ResponseInfo) AccessRecord {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	var user string
+	if r.URL != nil && r.URL.User != nil {
+		user = r.URL.User.Username()
+	} else if name, _, ok := r.BasicAuth(); ok {
+		user = name
+	}
+	uri := r.RequestURI
+	if uri == "" && r.URL != nil {
+		uri = r.URL.RequestURI()
+	}
+	return AccessRecord{
+		RemoteHost: host,
+		User:       user,
+		Time:       time.Now().Add(-res.Duration),
+		Method:     r.Method,
+		URI:        uri,
+		Proto:      r.Proto,
+		Status:     res.Status,
+		Bytes:      res.Bytes,
+		Referer:    r.Referer(),
+		UserAgent:  r.UserAgent(),
+	}
+}
+
+// String returns the record in the Apache combined log format, without line ending:
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.1" 200 2326 "http://example.com/" "curl/8.0"
+//
+// Quotes, backslashes and control characters in the quoted parts and the user are
+// escaped like Apache does, so that a client cannot forge a line.
+func (rec AccessRecord) String() string {
+	var b strings.Builder
+	b.WriteString(accessField(rec.RemoteHost))
+	b.WriteByte(' ')
+	b.WriteString(accessField(rec.Ident))
+	b.WriteByte(' ')
+	b.WriteString(accessField(rec.User))
+	b.WriteString(" [")
+	b.WriteString(rec.Time.Format(AccessTimeLayout))
+	b.WriteString(`] "`)
+	line := strings.TrimSpace(rec.Method + " " + rec.URI + " " + rec.Proto)
+	b.WriteString(accessField(line))
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(rec.Status))
+	b.WriteByte(' ')
+	if rec.Bytes == 0 {
+		b.WriteByte('-')
+	} else {
+		b.WriteString(strconv.FormatInt(rec.Bytes, 10))
+	}
+	b.WriteString(` "`)
+	b.WriteString(accessField(rec.Referer))
+	b.WriteString(`" "`)
+	b.WriteString(accessField(rec.UserAgent))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// accessField returns s escaped for an access line, or "-" when it is empty.
+func accessField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			b.WriteString(`\x`)
+			b.WriteString(strconv.FormatUint(uint64(c)>>4, 16))
+			b.WriteString(strconv.FormatUint(uint64(c)&0xf, 16))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// WithAccessLogOutput writes the lines of AccessLog to paths, which are opened like the
+// paths of WithOutputPaths, instead of the outputs of the logger, keeping them apart from
+// the entries of the application for the tools reading them, such as GoAccess.
+func WithAccessLogOutput(paths ...string) Option {
+	return func(o *options) {
+		o.accessOutputs = append([]string(nil), paths...)
+	}
+}
+
+// AccessLog writes rec as a line in the Apache combined log format to the access log of
+// the global logger: the outputs given to WithAccessLogOutput, or else the outputs of the
+// logger. Like WriteRaw, the line skips encoding, levels, fields and hooks, is written
+// with a single write so that lines never interleave, and is discarded while logging is
+// disabled and during a capture. A call before Initialize returns ErrNotInitialized.
+func AccessLog(rec AccessRecord) error {
+	in := loadInstance()
+	if in == nil {
+		return ErrNotInitialized
+	}
+	if in.access == nil {
+		return nil
+	}
+
+	ending := in.config.EncoderConfig.LineEnding
+	if ending == "" {
+		ending = zapcore.DefaultLineEnding
+	}
+	_, err := in.access.Write([]byte(rec.String() + ending))
+	return err
+}
+
+// WithAccessLog makes the middleware also write the access line of each request with
+// AccessLog, with the client of client_ip, after the request has been answered. Requests
+// skipped by WithSkippedPaths have no access line either.
+func WithAccessLog() RequestLogOption {
+	return func(o *requestLogOptions) {
+		o.accessLog = true
+	}
+}
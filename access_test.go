@@ -0,0 +1,134 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// combinedLine matches a line in the Apache combined log format, capturing its parts.
+var combinedLine = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[(\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4})\] "((?:[^"\\]|\\.)*)" (\d{3}) (\d+|-) "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)"$`)
+
+// readLines returns the lines of the file at path.
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestAccessLog(t *testing.T) {
+	restoreDefault(t)
+	accessPath := filepath.Join(t.TempDir(), "access.log")
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithAccessLogOutput(accessPath))
+
+	received := time.Date(2000, time.October, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600))
+	err := sazabi.AccessLog(sazabi.AccessRecord{
+		RemoteHost: "127.0.0.1",
+		User:       "frank",
+		Time:       received,
+		Method:     http.MethodGet,
+		URI:        "/apache_pb.gif",
+		Proto:      "HTTP/1.0",
+		Status:     http.StatusOK,
+		Bytes:      2326,
+		Referer:    "http://www.example.com/start.html",
+		UserAgent:  "Mozilla/4.08 \"forged\" \n",
+	})
+	if err != nil {
+		t.Fatalf("AccessLog() error = %v", err)
+	}
+	sazabi.AccessLog(sazabi.AccessRecord{Time: received, Method: http.MethodHead, URI: "/", Proto: "HTTP/1.1", Status: http.StatusNoContent})
+	sazabi.Info("application entry")
+	sazabi.Sync()
+
+	lines := readLines(t, accessPath)
+	want := []string{
+		`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 \"forged\" \x0a"`,
+		`- - - [10/Oct/2000:13:55:36 -0700] "HEAD / HTTP/1.1" 204 - "-" "-"`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("access log = %q, want %d lines", lines, len(want))
+	}
+	for i, line := range lines {
+		if line != want[i] {
+			t.Errorf("line %d = %s, want %s", i, line, want[i])
+		}
+		if !combinedLine.MatchString(line) {
+			t.Errorf("line %d = %s, want the combined log format", i, line)
+		}
+	}
+
+	output := read()
+	if !strings.Contains(output, "application entry") || strings.Contains(output, "apache_pb.gif") {
+		t.Errorf("application output = %q, want the entry without the access lines", output)
+	}
+}
+
+func TestAccessLogOutputs(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName)
+
+	sazabi.AccessLog(sazabi.AccessRecord{Time: time.Now(), Method: http.MethodGet, URI: "/", Proto: "HTTP/1.1", Status: http.StatusOK})
+	if output := read(); !combinedLine.MatchString(strings.TrimSuffix(output, "\n")) {
+		t.Errorf("output = %q, want the access line in the outputs of the logger", output)
+	}
+
+	c, stop := sazabi.StartCapture()
+	defer stop()
+	if err := sazabi.AccessLog(sazabi.AccessRecord{Status: http.StatusOK}); err != nil || len(c.Entries()) != 0 {
+		t.Errorf("AccessLog() during a capture = %v, %d entries; want the line discarded", err, len(c.Entries()))
+	}
+}
+
+func TestRequestLoggerAccessLog(t *testing.T) {
+	restoreDefault(t)
+	accessPath := filepath.Join(t.TempDir(), "access.log")
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithJSONEncoding(), sazabi.WithAccessLogOutput(accessPath))
+
+	handler := sazabi.RequestLogger(sazabi.WithAccessLog(), sazabi.WithTrustedProxies("10.0.0.0/8"), sazabi.WithSkippedPaths("/healthz"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("created"))
+		}))
+	req := httptest.NewRequest(http.MethodPost, "/orders?id=7", nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	req.Header.Set("Referer", "https://shop.example.com/cart")
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.SetBasicAuth("alice", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	sazabi.Sync()
+
+	lines := readLines(t, accessPath)
+	if len(lines) != 1 {
+		t.Fatalf("access log = %q, want one line for the request not skipped", lines)
+	}
+	parts := combinedLine.FindStringSubmatch(lines[0])
+	if parts == nil {
+		t.Fatalf("line = %s, want the combined log format", lines[0])
+	}
+	got := []string{parts[1], parts[2], parts[3], parts[5], parts[6], parts[7], parts[8], parts[9]}
+	want := []string{"203.0.113.9", "-", "alice", "POST /orders?id=7 HTTP/1.1", "201", "7", "https://shop.example.com/cart", "curl/8.0"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("line parts = %q, want %q", got, want)
+	}
+
+	if output := read(); !strings.Contains(output, `"msg":"http request"`) || strings.Contains(output, "curl/8.0 ") || strings.Contains(output, "/orders?id=7 HTTP") {
+		t.Errorf("application output = %q, want the access entry without the access line", output)
+	}
+}
@@ -0,0 +1,157 @@
+package sazabi
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AggregateMessage is the message of the summary entries emitted by an Aggregator.
+const AggregateMessage = "aggregate"
+
+// ErrInvalidInterval is returned by the functions starting periodic tasks when their
+// interval is zero or negative.
+var ErrInvalidInterval = errors.New("sazabi: interval must be positive")
+
+// Histogram layout: bucket 0 holds durations up to aggregateBase, bucket i holds
+// durations up to aggregateBase * 2^(i/aggregateSteps). Quantiles are reported as the
+// upper bound of their bucket, so they are accurate to within about 19%.
+const (
+	aggregateBase    = time.Microsecond
+	aggregateSteps   = 4   // Buckets per doubling
+	aggregateBuckets = 120 // Up to about 18 minutes; longer durations share the last bucket
+)
+
+// Aggregator summarizes repeated operations, such as cache lookups, into one Info entry
+// per interval instead of one entry per operation. Entries carry the number of
+// observations and the p50, p95 and max durations; intervals without observations
+// are skipped. Observe is safe for concurrent use.
+type Aggregator struct {
+	name     string
+	interval time.Duration
+	buckets  [aggregateBuckets]int64 // Observation counts, updated atomically
+	max      int64                   // Largest duration of the interval, updated atomically
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewAggregator starts an Aggregator emitting a summary of the durations observed
+// under name every interval. Close stops it. It returns ErrInvalidInterval when
+// interval is not positive.
+func NewAggregator(name string, interval time.Duration) (*Aggregator, error) {
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+	ticker := time.NewTicker(interval)
+	return newAggregator(name, interval, ticker.C, ticker.Stop), nil
+}
+
+// newAggregator starts an Aggregator emitting a summary every time tick fires.
+// stopTick is called when the aggregator is closed.
+func newAggregator(name string, interval time.Duration, tick <-chan time.Time, stopTick func()) *Aggregator {
+	a := &Aggregator{
+		name:     name,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(a.done)
+		defer stopTick()
+		for {
+			select {
+			case <-tick:
+				a.emit()
+			case <-a.stop:
+				a.emit() // Final summary
+				return
+			}
+		}
+	}()
+	return a
+}
+
+// Observe records one operation lasting d.
+func (a *Aggregator) Observe(d time.Duration) {
+	atomic.AddInt64(&a.buckets[bucketOf(d)], 1)
+	for {
+		current := atomic.LoadInt64(&a.max)
+		if int64(d) <= current || atomic.CompareAndSwapInt64(&a.max, current, int64(d)) {
+			return
+		}
+	}
+}
+
+// Close stops the aggregator after emitting a summary of the pending observations.
+// Closing an aggregator twice is harmless.
+func (a *Aggregator) Close() {
+	a.once.Do(func() {
+		close(a.stop)
+	})
+	<-a.done
+}
+
+// emit logs the summary of the observations since the previous summary, if any, and
+// starts a new interval.
+func (a *Aggregator) emit() {
+	var (
+		counts [aggregateBuckets]int64
+		total  int64
+	)
+	for i := range a.buckets {
+		counts[i] = atomic.SwapInt64(&a.buckets[i], 0)
+		total += counts[i]
+	}
+	max := time.Duration(atomic.SwapInt64(&a.max, 0))
+	if total == 0 {
+		return // Nothing happened during the interval
+	}
+
+	directLogger().Desugar().WithOptions(zap.WithCaller(false)).Info(AggregateMessage,
+		zap.String("name", a.name),
+		zap.Duration("interval", a.interval),
+		zap.Int64("count", total),
+		zap.Duration("p50", quantile(counts[:], total, 0.50, max)),
+		zap.Duration("p95", quantile(counts[:], total, 0.95, max)),
+		zap.Duration("max", max),
+	)
+}
+
+// bucketOf returns the histogram bucket of d.
+func bucketOf(d time.Duration) int {
+	if d <= aggregateBase {
+		return 0
+	}
+	i := int(math.Ceil(math.Log2(float64(d)/float64(aggregateBase)) * aggregateSteps))
+	if i >= aggregateBuckets {
+		return aggregateBuckets - 1
+	}
+	return i
+}
+
+// bucketBound returns the largest duration of bucket i.
+func bucketBound(i int) time.Duration {
+	return time.Duration(float64(aggregateBase) * math.Exp2(float64(i)/aggregateSteps))
+}
+
+// quantile returns the upper bound of the bucket holding the q quantile of the total
+// observations in counts, capped at max.
+func quantile(counts []int64, total int64, q float64, max time.Duration) time.Duration {
+	rank := int64(math.Ceil(q * float64(total)))
+	var seen int64
+	for i, n := range counts {
+		seen += n
+		if seen >= rank {
+			if bound := bucketBound(i); bound < max {
+				return bound
+			}
+			return max
+		}
+	}
+	return max
+}
@@ -0,0 +1,85 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// withinBucket reports whether got is want rounded up to the upper bound of its bucket.
+func withinBucket(got, want time.Duration) bool {
+	return got >= want && float64(got) <= float64(want)*1.19
+}
+
+func TestAggregator(t *testing.T) {
+	Initialize(ProductionEnvName)
+	capture, stop := StartCapture()
+	defer stop()
+
+	tick := make(chan time.Time)
+	a := newAggregator("cache", time.Minute, tick, func() {})
+
+	for i := 1; i <= 100; i++ {
+		a.Observe(time.Duration(i) * time.Millisecond)
+	}
+	tick <- time.Time{} // First interval: one summary
+	tick <- time.Time{} // Second interval: no observations, skipped
+	a.Observe(3 * time.Second)
+	a.Close() // Final summary
+	a.Close() // Closing twice is harmless
+
+	entries := capture.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("emitted %d summaries, want 2", len(entries))
+	}
+
+	first := entries[0]
+	if first.Message != AggregateMessage {
+		t.Errorf("message = %q, want %q", first.Message, AggregateMessage)
+	}
+	if name, _ := first.Str("name"); name != "cache" {
+		t.Errorf("name = %q, want cache", name)
+	}
+	if count, _ := first.Int("count"); count != 100 {
+		t.Errorf("count = %d, want 100", count)
+	}
+	if p50, _ := first.Dur("p50"); !withinBucket(p50, 50*time.Millisecond) {
+		t.Errorf("p50 = %s, want about 50ms", p50)
+	}
+	if p95, _ := first.Dur("p95"); !withinBucket(p95, 95*time.Millisecond) {
+		t.Errorf("p95 = %s, want about 95ms", p95)
+	}
+	if max, _ := first.Dur("max"); max != 100*time.Millisecond {
+		t.Errorf("max = %s, want 100ms", max)
+	}
+
+	last := entries[1]
+	if count, _ := last.Int("count"); count != 1 {
+		t.Errorf("final count = %d, want 1", count)
+	}
+	if p50, _ := last.Dur("p50"); p50 != 3*time.Second {
+		t.Errorf("final p50 = %s, want the 3s max", p50)
+	}
+}
+
+func TestBucketOf(t *testing.T) {
+	for _, d := range []time.Duration{0, time.Microsecond, 1500 * time.Nanosecond, time.Millisecond, time.Second, time.Minute} {
+		if bound := bucketBound(bucketOf(d)); !withinBucket(bound, d) && d > aggregateBase {
+			t.Errorf("bucket bound of %s = %s, want at most 19%% above", d, bound)
+		}
+	}
+	if got := bucketOf(24 * time.Hour); got != aggregateBuckets-1 {
+		t.Errorf("bucketOf(24h) = %d, want the last bucket", got)
+	}
+}
+
+func TestNewAggregatorInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if a, err := NewAggregator("lookup", interval); !errors.Is(err, ErrInvalidInterval) || a != nil {
+			t.Errorf("NewAggregator(%s) = %v, %v, want ErrInvalidInterval", interval, a, err)
+		}
+	}
+}
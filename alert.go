@@ -0,0 +1,279 @@
+package sazabi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of WithAlertWebhook.
+const (
+	defaultAlertTimeout   = 2 * time.Second
+	defaultAlertRateLimit = 5
+	defaultAlertRatePer   = time.Minute
+)
+
+// AlertOption configures a webhook added by WithAlertWebhook.
+type AlertOption func(*alertConfig)
+
+// alertConfig is a webhook added by WithAlertWebhook.
+type alertConfig struct {
+	url     string
+	slack   bool
+	timeout time.Duration
+	limit   int
+	per     time.Duration
+	client  *http.Client
+}
+
+// AlertSlackFormat posts the message of Slack incoming webhooks, {"text": ...}, instead
+// of the JSON alert.
+func AlertSlackFormat() AlertOption {
+	return func(c *alertConfig) {
+		c.slack = true
+	}
+}
+
+// AlertTimeout sets how long the entry waits for the webhook, two seconds by default.
+func AlertTimeout(d time.Duration) AlertOption {
+	return func(c *alertConfig) {
+		c.timeout = d
+	}
+}
+
+// AlertRateLimit posts at most n alerts per period, five per minute by default. Alerts
+// beyond it are not posted, and counted under "suppressed" in the next one.
+func AlertRateLimit(n int, per time.Duration) AlertOption {
+	return func(c *alertConfig) {
+		c.limit = n
+		c.per = per
+	}
+}
+
+// AlertHTTPClient posts with client, such as a client going through a proxy.
+func AlertHTTPClient(client *http.Client) AlertOption {
+	return func(c *alertConfig) {
+		c.client = client
+	}
+}
+
+// WithAlertWebhook posts Panic and Fatal entries to the webhook at url, so that a
+// service dying is seen by a human right away. The alert is JSON with the "level",
+// "message", "fields", "hostname" and "timestamp" of the entry, plus "logger" and
+// "caller" when set; AlertSlackFormat posts a Slack message instead.
+//
+// The alert is posted before the entry panics or exits the process, waiting for
+// AlertTimeout at most, and failures are reported to the internal error output.
+// AlertRateLimit keeps goroutines panicking together from flooding the webhook.
+func WithAlertWebhook(url string, opts ...AlertOption) Option {
+	cfg := alertConfig{url: url, timeout: defaultAlertTimeout, limit: defaultAlertRateLimit, per: defaultAlertRatePer}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(o *options) {
+		o.alerts = append(o.alerts, cfg)
+		format := "json"
+		if cfg.slack {
+			format = "slack"
+		}
+		o.integrations = append(o.integrations, integration{
+			name:     "alert_webhook",
+			settings: map[string]string{"url": redactURL(cfg.url), "format": format},
+		})
+	}
+}
+
+// newAlertCores returns the cores posting the Panic and Fatal entries to the webhooks of
+// o, reporting failures to errorOutput.
+func newAlertCores(errorOutput zapcore.WriteSyncer, o *options) ([]zapcore.Core, error) {
+	cores := make([]zapcore.Core, 0, len(o.alerts))
+	for _, cfg := range o.alerts {
+		switch {
+		case !strings.HasPrefix(cfg.url, "http://") && !strings.HasPrefix(cfg.url, "https://"):
+			return nil, fmt.Errorf("sazabi: invalid alert webhook %q, want an http or https URL", redactURL(cfg.url))
+		case cfg.timeout <= 0 || cfg.limit <= 0 || cfg.per <= 0:
+			return nil, fmt.Errorf("sazabi: invalid alert timeout or rate limit")
+		}
+		client := cfg.client
+		if client == nil {
+			client = &http.Client{}
+		}
+		out := &alertOutput{cfg: cfg, client: client, hostname: o.hostname(), errorOutput: errorOutput, now: time.Now}
+		enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+			EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+			EncodeDuration: zapcore.StringDurationEncoder,
+		})
+		cores = append(cores, &alertCore{enc: enc, out: out})
+	}
+	return cores, nil
+}
+
+// alertCore posts the Panic and Fatal entries to an alert webhook.
+type alertCore struct {
+	enc zapcore.Encoder // Encodes the fields alone, with those added by With
+	out *alertOutput
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *alertCore) Enabled(level zapcore.Level) bool {
+	return level >= zapcore.PanicLevel
+}
+
+// With implements zapcore.Core.
+func (c *alertCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &alertCore{enc: enc, out: c.out}
+}
+
+// Check implements zapcore.Core.
+func (c *alertCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. Entries below Panic are ignored, since the cores
+// wrapping the tee write every entry to each of its cores. Failures are reported by
+// the output rather than returned, since zap would report them a second time.
+func (c *alertCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	buf, err := c.enc.EncodeEntry(zapcore.Entry{}, fields)
+	if err != nil {
+		return err
+	}
+	encoded := bytes.TrimSpace(buf.Bytes())
+	alert := alertPayload{
+		Level:     ent.Level.String(),
+		Message:   ent.Message,
+		Logger:    ent.LoggerName,
+		Fields:    append(json.RawMessage(nil), encoded...),
+		Timestamp: ent.Time.UTC().Format(time.RFC3339Nano),
+	}
+	buf.Free()
+	if ent.Caller.Defined {
+		alert.Caller = ent.Caller.TrimmedPath()
+	}
+	c.out.post(alert)
+	return nil
+}
+
+// Sync implements zapcore.Core. Alerts are posted by Write.
+func (c *alertCore) Sync() error {
+	return nil
+}
+
+// alertPayload is the JSON posted by an alert webhook.
+type alertPayload struct {
+	Level      string          `json:"level"`
+	Message    string          `json:"message"`
+	Logger     string          `json:"logger,omitempty"`
+	Caller     string          `json:"caller,omitempty"`
+	Fields     json.RawMessage `json:"fields"`
+	Hostname   string          `json:"hostname"`
+	Timestamp  string          `json:"timestamp"`
+	Suppressed int             `json:"suppressed,omitempty"` // Alerts not posted since the previous one
+}
+
+// slackText returns the text of the Slack message of a, escaped as Slack requires.
+func (a alertPayload) slackText() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s* on %s at %s: %s", strings.ToUpper(a.Level), a.Hostname, a.Timestamp, a.Message)
+	if a.Logger != "" || a.Caller != "" {
+		fmt.Fprintf(&b, "\n%s %s", a.Logger, a.Caller)
+	}
+	if string(a.Fields) != "{}" {
+		fmt.Fprintf(&b, "\n```%s```", a.Fields)
+	}
+	if a.Suppressed > 0 {
+		fmt.Fprintf(&b, "\n%d more alerts suppressed", a.Suppressed)
+	}
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(b.String())
+}
+
+// alertOutput posts alerts to a webhook, within its rate limit.
+type alertOutput struct {
+	cfg         alertConfig
+	client      *http.Client
+	hostname    string
+	errorOutput zapcore.WriteSyncer
+	now         func() time.Time
+
+	mu         sync.Mutex
+	window     time.Time // Start of the current rate limit period
+	posted     int       // Alerts posted during the current period
+	suppressed int       // Alerts not posted since the last one
+}
+
+// post sends a to the webhook unless the rate limit is reached.
+func (o *alertOutput) post(a alertPayload) {
+	o.mu.Lock()
+	if now := o.now(); now.Sub(o.window) >= o.cfg.per {
+		o.window, o.posted = now, 0
+	}
+	if o.posted >= o.cfg.limit {
+		o.suppressed++
+		o.mu.Unlock()
+		return
+	}
+	o.posted++
+	a.Suppressed, o.suppressed = o.suppressed, 0
+	o.mu.Unlock()
+
+	a.Hostname = o.hostname
+	var body interface{} = a
+	if o.cfg.slack {
+		body = map[string]string{"text": a.slackText()}
+	}
+	if err := postJSON(o.client, o.cfg.url, o.cfg.timeout, body); err != nil {
+		o.reportf("%s alert not posted: %v", a.Level, err)
+	}
+}
+
+// postJSON posts body in JSON to rawURL with client, waiting for timeout at most.
+func postJSON(client *http.Client, rawURL string, timeout time.Duration, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err // Without the URL, whose path holds the token of Slack webhooks
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", redactURL(rawURL), resp.Status)
+	}
+	return nil
+}
+
+// reportf writes a failure to the internal error output.
+func (o *alertOutput) reportf(format string, args ...interface{}) {
+	fmt.Fprintf(o.errorOutput, "%v output alert webhook %s: %s\n", time.Now(), redactURL(o.cfg.url), fmt.Sprintf(format, args...))
+	o.errorOutput.Sync()
+}
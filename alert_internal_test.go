@@ -0,0 +1,59 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAlertRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	var alerts []alertPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a alertPayload
+		json.NewDecoder(r.Body).Decode(&a)
+		mu.Lock()
+		alerts = append(alerts, a)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	cores, err := newAlertCores(&lockedBuffer{}, newOptions([]Option{WithAlertWebhook(srv.URL, AlertRateLimit(2, time.Minute))}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cores[0].(*alertCore)
+	now := time.Unix(1000, 0)
+	c.out.now = func() time.Time { return now }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ { // Goroutines panicking together
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Write(zapcore.Entry{Level: zapcore.PanicLevel, Message: "storm"}, nil)
+		}()
+	}
+	wg.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(alerts) != 2 || c.out.suppressed != 8 {
+		t.Fatalf("%d alerts posted, %d suppressed; want 2 posted and 8 suppressed", len(alerts), c.out.suppressed)
+	}
+
+	now = now.Add(time.Minute)
+	mu.Unlock()
+	c.Write(zapcore.Entry{Level: zapcore.FatalLevel, Message: "exit"}, nil)
+	mu.Lock()
+	if len(alerts) != 3 || alerts[2].Message != "exit" || alerts[2].Suppressed != 8 {
+		t.Errorf("alerts = %+v, want the next period to post with the suppressed count", alerts)
+	}
+}
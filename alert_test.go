@@ -0,0 +1,169 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap/zapcore"
+)
+
+// webhook is an in-process webhook recording the JSON bodies it receives.
+type webhook struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	bodies []map[string]interface{}
+}
+
+func newWebhook(t *testing.T) *webhook {
+	w := &webhook{}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s request with %v, want a JSON POST", r.Method, r.Header)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding alert: %v", err)
+		}
+		w.mu.Lock()
+		w.bodies = append(w.bodies, body)
+		w.mu.Unlock()
+	}))
+	t.Cleanup(w.Close)
+	return w
+}
+
+// received returns the bodies received.
+func (w *webhook) received() []map[string]interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]map[string]interface{}(nil), w.bodies...)
+}
+
+// panics calls fn and reports whether it panicked.
+func panics(fn func()) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+	fn()
+	return false
+}
+
+func TestWithAlertWebhook(t *testing.T) {
+	restoreDefault(t)
+	w := newWebhook(t)
+
+	initializeFile(t, sazabi.ProductionEnvName,
+		sazabi.WithHostnameProvider(func() string { return "api-7" }),
+		sazabi.WithFatalHook(zapcore.WriteThenGoexit),
+		sazabi.WithAlertWebhook(w.URL))
+	sazabi.Errorw("not alerted")
+	if !panics(func() { sazabi.Named("payments").Panicw("ledger corrupted", "account", 42, "elapsed", time.Second) }) {
+		t.Error("Panicw did not panic")
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sazabi.Fatalw("database unreachable")
+	}()
+	<-done
+
+	bodies := w.received()
+	if len(bodies) != 2 {
+		t.Fatalf("received %v, want the Panic and Fatal alerts", bodies)
+	}
+	alert := bodies[0]
+	for key, want := range map[string]interface{}{
+		"level":    "panic",
+		"message":  "ledger corrupted",
+		"logger":   "payments",
+		"hostname": "api-7",
+	} {
+		if alert[key] != want {
+			t.Errorf("alert %s = %v, want %v", key, alert[key], want)
+		}
+	}
+	fields, _ := alert["fields"].(map[string]interface{})
+	if fields["account"] != 42.0 || fields["elapsed"] != "1s" {
+		t.Errorf("alert fields = %v, want the fields of the entry", alert["fields"])
+	}
+	if ts, _ := alert["timestamp"].(string); ts == "" {
+		t.Error("alert has no timestamp")
+	} else if _, err := time.Parse(time.RFC3339Nano, ts); err != nil {
+		t.Errorf("alert timestamp: %v", err)
+	}
+	if caller, _ := alert["caller"].(string); !strings.Contains(caller, "alert_test.go") {
+		t.Errorf("alert caller = %q, want the caller of Panicw", caller)
+	}
+	if bodies[1]["level"] != "fatal" || bodies[1]["message"] != "database unreachable" {
+		t.Errorf("alert = %v, want the Fatal entry", bodies[1])
+	}
+}
+
+func TestWithAlertWebhookSlack(t *testing.T) {
+	restoreDefault(t)
+	w := newWebhook(t)
+
+	initializeFile(t, sazabi.ProductionEnvName,
+		sazabi.WithHostnameProvider(func() string { return "api-7" }),
+		sazabi.WithAlertWebhook(w.URL, sazabi.AlertSlackFormat()))
+	panics(func() { sazabi.Panicw("balance < 0", "account", 42) })
+
+	bodies := w.received()
+	if len(bodies) != 1 || len(bodies[0]) != 1 {
+		t.Fatalf("received %v, want one Slack message", bodies)
+	}
+	text, _ := bodies[0]["text"].(string)
+	for _, want := range []string{"*PANIC* on api-7", "balance &lt; 0", "```{\"account\":42}```"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text = %q, want %q", text, want)
+		}
+	}
+}
+
+func TestWithAlertWebhookTimeout(t *testing.T) {
+	restoreDefault(t)
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer srv.Close()
+	defer close(hang)
+
+	var elapsed time.Duration
+	errs := captureStderr(t, func() {
+		initializeFile(t, sazabi.ProductionEnvName, sazabi.WithAlertWebhook(srv.URL, sazabi.AlertTimeout(50*time.Millisecond)))
+		start := time.Now()
+		panics(func() { sazabi.Panic("stuck") })
+		elapsed = time.Since(start)
+	})
+
+	if elapsed > 2*time.Second {
+		t.Errorf("Panic took %v with a hanging webhook, want the alert to time out", elapsed)
+	}
+	if !strings.Contains(errs, "output alert webhook "+srv.URL+": panic alert not posted: context deadline exceeded") {
+		t.Errorf("internal errors = %q, want the timeout reported", errs)
+	}
+}
+
+func TestWithAlertWebhookInvalid(t *testing.T) {
+	for _, opt := range []sazabi.Option{
+		sazabi.WithAlertWebhook("hooks.slack.com/services/T0/B0/x"),
+		sazabi.WithAlertWebhook("https://hooks.example.com", sazabi.AlertTimeout(0)),
+		sazabi.WithAlertWebhook("https://hooks.example.com", sazabi.AlertRateLimit(0, time.Minute)),
+	} {
+		if _, err := sazabi.New(sazabi.ProductionEnvName, opt); err == nil {
+			t.Error("New() succeeded with an invalid webhook, want an error")
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package sazabi
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// BatchLogger collects prebuilt entries and writes them to the global logger at once,
+// for bulk producers such as importers replaying historical events. A BatchLogger is
+// not safe for concurrent use.
+type BatchLogger struct {
+	entries []batchEntry
+}
+
+// batchEntry is an entry waiting in a BatchLogger.
+type batchEntry struct {
+	ent    zapcore.Entry
+	fields []Field
+}
+
+// Batch returns an empty BatchLogger writing to the global logger.
+func Batch() *BatchLogger {
+	return &BatchLogger{}
+}
+
+// Add appends an entry stamped with the current time.
+func (b *BatchLogger) Add(level zapcore.Level, msg string, fields ...Field) *BatchLogger {
+	return b.AddAt(time.Now(), level, msg, fields...)
+}
+
+// AddAt appends an entry stamped with t, which is written as is so that historical
+// timestamps are preserved.
+func (b *BatchLogger) AddAt(t time.Time, level zapcore.Level, msg string, fields ...Field) *BatchLogger {
+	b.entries = append(b.entries, batchEntry{
+		ent:    zapcore.Entry{Level: level, Time: t, Message: msg},
+		fields: fields,
+	})
+	return b
+}
+
+// Len returns the number of entries waiting to be flushed.
+func (b *BatchLogger) Len() int {
+	return len(b.entries)
+}
+
+// Flush writes the waiting entries, in order, with the configured encoding and outputs,
+// and empties the batch. Each entry is still subject to the level of the global logger,
+// module levels, redaction and the registered extensions, but not to sampling: every
+// entry that passes the level checks is written. The entries are encoded into a single
+// buffer, which is handed to the outputs in one write. Entries are only written: Panic
+// and Fatal entries do not stop the program.
+func (b *BatchLogger) Flush() error {
+	entries := b.entries
+	b.entries = b.entries[:0]
+	in := loadInstance()
+	if in == nil {
+		return nil
+	}
+	if in.batch == nil { // Disabled or capturing logger, without outputs of its own
+		for _, e := range entries {
+			if ce := in.batchCore.Check(e.ent, nil); ce != nil {
+				ce.Write(e.fields...)
+			}
+		}
+		return nil
+	}
+	return in.batch.write(in.batchCore, entries)
+}
+
+// batchTarget is the destination of the batches of a logger: the processing stages of
+// the logger built on top of a buffer, which is written to the outputs on flush.
+type batchTarget struct {
+	mu      sync.Mutex // Serializes flushes, which share buf
+	core    zapcore.Core
+	buf     *batchBuffer
+	outputs zapcore.WriteSyncer // Outputs of the logger, written once per batch
+	ring    *ringBuffer         // Ring buffer of the logger, written once per entry, or nil
+	errSink zapcore.WriteSyncer
+}
+
+// newBatchTarget builds the stages described by conf and o on top of a buffer, writing
+// to outputs and to the ring buffer of o, if any, once flushed.
+func newBatchTarget(conf zap.Config, enc zapcore.Encoder, outputs, errSink zapcore.WriteSyncer, o *options) (*batchTarget, error) {
+	buf := &batchBuffer{}
+	vc := newVolumeCore(enc.Clone(), buf, conf.Level)
+	vc.global = o.global
+	if o.outputValidation {
+		vc.validator = newOutputValidator(conf)
+	}
+	core, err := wrapCore(vc, conf, o)
+	if err != nil {
+		return nil, err
+	}
+	if fields := initialFields(conf); len(fields) > 0 {
+		core = core.With(fields)
+	}
+	return &batchTarget{core: core, buf: buf, outputs: outputs, ring: o.ring, errSink: errSink}, nil
+}
+
+// write encodes entries with core, the core of the target with the extensions applied,
+// and writes them to the outputs at once. The ring buffer receives them one by one,
+// since each of its slots holds a single entry.
+func (t *batchTarget) write(core zapcore.Core, entries []batchEntry) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf.reset()
+	sync := false
+	for _, e := range entries {
+		if ce := core.Check(e.ent, nil); ce != nil {
+			ce.ErrorOutput = t.errSink
+			ce.Write(e.fields...)
+			sync = sync || e.ent.Level > zapcore.ErrorLevel
+		}
+	}
+	if t.buf.Len() == 0 {
+		return nil
+	}
+	if t.ring != nil {
+		start := 0
+		for _, end := range t.buf.ends {
+			t.ring.Write(t.buf.Bytes()[start:end])
+			start = end
+		}
+	}
+	if _, err := t.outputs.Write(t.buf.Bytes()); err != nil {
+		return err
+	}
+	if sync {
+		return t.outputs.Sync()
+	}
+	return nil
+}
+
+// batchBuffer is the WriteSyncer collecting the encoded entries of a batch. Each Write
+// receives one entry, whose end is recorded.
+type batchBuffer struct {
+	bytes.Buffer
+	ends []int // Offsets of the ends of the entries
+}
+
+// Write implements zapcore.WriteSyncer.
+func (b *batchBuffer) Write(p []byte) (int, error) {
+	n, err := b.Buffer.Write(p)
+	b.ends = append(b.ends, b.Len())
+	return n, err
+}
+
+// reset empties the buffer for the next batch.
+func (b *batchBuffer) reset() {
+	b.Reset()
+	b.ends = b.ends[:0]
+}
+
+// Sync implements zapcore.WriteSyncer. The buffer is written out by the flush.
+func (*batchBuffer) Sync() error {
+	return nil
+}
@@ -0,0 +1,44 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestBatchRingBufferEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	Initialize("development", WithOutputPaths(path), WithRingBuffer(4))
+	t.Cleanup(func() { Initialize(ProductionEnvName) })
+
+	Info("before the batch")
+	batch := Batch().Add(zapcore.InfoLevel, "first").Add(zapcore.WarnLevel, "second").Add(zapcore.InfoLevel, "third")
+	if err := batch.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := currentRing().snapshot()
+	if len(entries) < 4 {
+		t.Fatalf("ring buffer holds %d entries, want 4", len(entries))
+	}
+	entries = entries[len(entries)-4:]
+	for i, msg := range []string{"before the batch", "first", "second", "third"} {
+		if !strings.Contains(string(entries[i]), msg) || strings.Count(string(entries[i]), "\n") != 1 {
+			t.Errorf("ring slot %d = %q, want the single entry %q", i, entries[i], msg)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), string(entries[1])+string(entries[2])+string(entries[3])) {
+		t.Errorf("output = %q, want the batch written as in the ring buffer", data)
+	}
+}
@@ -0,0 +1,154 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestBatchFlush(t *testing.T) {
+	read := initializeFile(t, sazabi.ProductionEnvName)
+
+	batch := sazabi.Batch().
+		AddAt(time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC), zapcore.InfoLevel, "first", zap.Int("n", 1)).
+		AddAt(time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC), zapcore.WarnLevel, "second", zap.Int("n", 2)).
+		Add(zapcore.ErrorLevel, "third", zap.Int("n", 3))
+	if batch.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", batch.Len())
+	}
+	if err := batch.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if batch.Len() != 0 {
+		t.Errorf("Len() after Flush = %d, want 0", batch.Len())
+	}
+
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output has %d lines, want 3:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	for i, want := range []string{"2001-02-03T04:05:06.000Z\tINFO\tfirst", "1999-12-31T23:59:59.000Z\tWARN\tsecond", "\tERROR\tthird"} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("line %d = %q, want %q", i, lines[i], want)
+		}
+	}
+	if fields := entryFields(t, lines[1], "second"); fields["n"] != float64(2) {
+		t.Errorf("fields = %v, want n=2", fields)
+	}
+}
+
+func TestBatchLevelFiltering(t *testing.T) {
+	read := initializeFile(t, sazabi.ProductionEnvName) // Info and above
+
+	err := sazabi.Batch().
+		Add(zapcore.DebugLevel, "debug entry").
+		Add(zapcore.InfoLevel, "info entry").
+		Add(zapcore.DebugLevel, "other debug entry").
+		Add(zapcore.WarnLevel, "warn entry").
+		Flush()
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	output := read()
+	if strings.Contains(output, "debug entry") {
+		t.Errorf("output contains debug entries:\n%s", output)
+	}
+	for _, msg := range []string{"info entry", "warn entry"} {
+		if !strings.Contains(output, msg) {
+			t.Errorf("output lacks %q:\n%s", msg, output)
+		}
+	}
+}
+
+func TestBatchNotSampled(t *testing.T) {
+	read := initializeFile(t, sazabi.ProductionEnvName) // Samples after 100 identical entries per second
+
+	batch := sazabi.Batch()
+	for i := 0; i < 150; i++ {
+		batch.Add(zapcore.InfoLevel, "replayed")
+	}
+	if err := batch.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if n := strings.Count(read(), "replayed"); n != 150 {
+		t.Errorf("%d entries written, want 150", n)
+	}
+}
+
+func TestBatchExtensions(t *testing.T) {
+	read := initializeFile(t, sazabi.ProductionEnvName)
+	redacted := sazabi.AddRedactedKeys("password")
+	defer redacted.Remove()
+	global := sazabi.AddGlobalFields("service", "billing")
+	defer global.Remove()
+	var hooked []string
+	hook := sazabi.AddHook(func(ent zapcore.Entry) error {
+		hooked = append(hooked, ent.Message)
+		return nil
+	})
+	defer hook.Remove()
+
+	if err := sazabi.Batch().Add(zapcore.InfoLevel, "login", zap.String("password", "hunter2")).Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	fields := entryFields(t, read(), "login")
+	if fields["password"] != sazabi.RedactedValue || fields["service"] != "billing" {
+		t.Errorf("fields = %v, want a redacted password and the global field", fields)
+	}
+	if len(hooked) != 1 || hooked[0] != "login" {
+		t.Errorf("hooked = %v, want [login]", hooked)
+	}
+}
+
+func TestBatchCapture(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	capture, stop := sazabi.StartCapture()
+	defer stop()
+
+	at := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if err := sazabi.Batch().AddAt(at, zapcore.InfoLevel, "captured").Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	entries := capture.Entries()
+	if len(entries) != 1 || entries[0].Message != "captured" || !entries[0].Time.Equal(at) {
+		t.Errorf("entries = %+v, want the batch entry at %s", entries, at)
+	}
+}
+
+// batchSize is the number of entries written per iteration of the batch benchmarks.
+const batchSize = 100
+
+func BenchmarkBatch(b *testing.B) {
+	fields := []sazabi.Field{zap.String("user", "alice"), zap.Int("attempt", 3)}
+
+	b.Run("Batch", func(b *testing.B) {
+		initializeFile(b, "development")
+		batch := sazabi.Batch()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < batchSize; j++ {
+				batch.Add(zapcore.InfoLevel, "benchmark entry", fields...)
+			}
+			batch.Flush()
+		}
+	})
+	b.Run("InfoFields", func(b *testing.B) {
+		initializeFile(b, "development")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < batchSize; j++ {
+				sazabi.InfoFields("benchmark entry", fields...)
+			}
+		}
+	})
+}
@@ -1,11 +1,19 @@
 #!/bin/bash
-# Run tests for all Go modules in the repository
+# Build and test every Go module in the repository: the core module and each
+# integration module, so that a dependency added to one cannot leak into another.
 
 # Find all directories containing a go.mod file
 modules=$(find . -name "go.mod" -exec dirname {} \;)
 
-# Loop through each module and run tests
+status=0
+
+# Loop through each module, build it on its own and run its tests
 for module in $modules; do
-    echo "Running tests in $module..."
-    (cd $module && go test ./... -tags=test -v)
-done
\ No newline at end of file
+    echo "Building $module..."
+    (cd $module && go build ./... && go vet -tags=test ./...) || status=1
+
+    echo "Running tests in $module with the race detector..."
+    (cd $module && go test ./... -tags=test -race -v) || status=1
+done
+
+exit $status
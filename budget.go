@@ -0,0 +1,249 @@
+package sazabi
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Messages of the entries written by WithVolumeBudget.
+const (
+	VolumeBudgetExceededMessage  = "log_volume_budget_exceeded"
+	VolumeBudgetRecoveredMessage = "log_volume_budget_recovered"
+)
+
+// Actions taken by WithVolumeBudget when the budget is exceeded.
+const (
+	VolumeBudgetWarn       = "warn"        // Only warn
+	VolumeBudgetRaiseLevel = "raise_level" // Warn and raise the level to Warn until recovery
+)
+
+// VolumeBudgetCooldown is how long the rate must stay within the budget before the
+// budget is considered recovered.
+const VolumeBudgetCooldown = time.Minute
+
+// volumeBudgetTalkers is the number of logger names listed by the budget warning.
+const volumeBudgetTalkers = 5
+
+// WithVolumeBudget makes the global logger watch the number of encoded bytes it writes
+// per rolling minute. When the rate exceeds bytesPerMinute, a VolumeBudgetExceededMessage
+// warning is written with the rate and the logger names writing the most (see
+// VolumeStats), then repeated at most once a minute while the rate stays above the
+// budget. Once the rate has stayed within the budget for VolumeBudgetCooldown, a
+// VolumeBudgetRecoveredMessage entry is written. The state is reported by
+// EffectiveConfig.
+func WithVolumeBudget(bytesPerMinute int64) Option {
+	return func(o *options) {
+		o.volumeBudget = bytesPerMinute
+	}
+}
+
+// WithVolumeBudgetAction sets the action taken when the budget of WithVolumeBudget is
+// exceeded: VolumeBudgetWarn (the default) or VolumeBudgetRaiseLevel, which also raises
+// the level of the global logger to Warn until the budget recovers, then restores it.
+func WithVolumeBudgetAction(action string) Option {
+	return func(o *options) {
+		o.volumeBudgetAction = action
+	}
+}
+
+// VolumeBudgetSnapshot describes the volume budget of the global logger.
+type VolumeBudgetSnapshot struct {
+	BytesPerMinute int64  `json:"bytes_per_minute"`
+	Action         string `json:"action"`
+	Rate           int64  `json:"rate"`     // Bytes written during the last minute
+	Exceeded       bool   `json:"exceeded"` // Whether the budget is exceeded, until recovery
+	LevelRaised    bool   `json:"level_raised"`
+}
+
+// volumeBudget tracks the bytes written per second over the last minute.
+type volumeBudget struct {
+	limit  int64
+	raise  bool // Raise the level while exceeded
+	level  zap.AtomicLevel
+	now    func() time.Time
+	window [60]struct {
+		second int64 // Unix second the bucket counts, the bucket is stale otherwise
+		bytes  int64
+	}
+
+	mu          sync.Mutex // Guards the state below, changed by evaluate
+	exceeded    bool
+	lastWarning time.Time
+	withinSince time.Time     // Start of the current period within the budget, while exceeded
+	raised      bool          // Whether the level was raised
+	previous    zapcore.Level // Level restored on recovery
+}
+
+// activeBudget holds the *volumeBudget of the global logger, or a nil one.
+var activeBudget atomic.Value
+
+// loadBudget returns the budget of the global logger, or nil.
+func loadBudget() *volumeBudget {
+	b, _ := activeBudget.Load().(*volumeBudget)
+	return b
+}
+
+// add counts size bytes written now. Concurrent writers moving to a new second may lose
+// a few bytes, which keeps the hot path free of locks.
+func (b *volumeBudget) add(size int) {
+	sec := b.now().Unix()
+	bucket := &b.window[sec%int64(len(b.window))]
+	if old := atomic.LoadInt64(&bucket.second); old != sec && atomic.CompareAndSwapInt64(&bucket.second, old, sec) {
+		atomic.StoreInt64(&bucket.bytes, 0)
+	}
+	atomic.AddInt64(&bucket.bytes, int64(size))
+}
+
+// rate returns the bytes written during the minute ending at now.
+func (b *volumeBudget) rate(now time.Time) int64 {
+	sec := now.Unix()
+	var total int64
+	for i := range b.window {
+		bucket := &b.window[i]
+		if s := atomic.LoadInt64(&bucket.second); s > sec-int64(len(b.window)) && s <= sec {
+			total += atomic.LoadInt64(&bucket.bytes)
+		}
+	}
+	return total
+}
+
+// evaluate compares the current rate with the budget and performs the transitions.
+func (b *volumeBudget) evaluate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	rate := b.rate(now)
+	switch {
+	case rate > b.limit:
+		b.withinSince = time.Time{}
+		if !b.exceeded || now.Sub(b.lastWarning) >= time.Minute {
+			b.exceeded = true
+			b.lastWarning = now
+			b.raiseLevel()
+			b.warn(rate)
+		}
+	case b.exceeded && b.withinSince.IsZero():
+		b.withinSince = now
+	case b.exceeded && now.Sub(b.withinSince) >= VolumeBudgetCooldown:
+		b.exceeded = false
+		b.withinSince = time.Time{}
+		restored := b.restoreLevel()
+		b.recovered(rate, restored)
+	}
+}
+
+// raiseLevel raises the level to Warn if the action requests it. b.mu must be held.
+func (b *volumeBudget) raiseLevel() {
+	if !b.raise || b.raised {
+		return
+	}
+	b.previous = b.level.Level()
+	if b.previous < zapcore.WarnLevel {
+		b.level.SetLevel(zapcore.WarnLevel)
+	}
+	b.raised = true
+}
+
+// restoreLevel restores the level raised by raiseLevel, returning whether it did.
+// b.mu must be held.
+func (b *volumeBudget) restoreLevel() bool {
+	if !b.raised {
+		return false
+	}
+	if b.level.Level() == zapcore.WarnLevel && b.previous < zapcore.WarnLevel {
+		b.level.SetLevel(b.previous) // Unless changed meanwhile
+	}
+	b.raised = false
+	return true
+}
+
+// warn writes the VolumeBudgetExceededMessage warning.
+func (b *volumeBudget) warn(rate int64) {
+	fields := []zap.Field{
+		zap.Int64("rate", rate),
+		zap.Int64("budget", b.limit),
+		zap.Any("top", topTalkers(volumeBudgetTalkers, true)),
+		zap.String("action", b.action()),
+	}
+	if b.raised {
+		fields = append(fields, zap.Stringer("level", b.level.Level()), zap.Stringer("previous_level", b.previous))
+	}
+	unsampledLogger().Desugar().Warn(VolumeBudgetExceededMessage, fields...)
+}
+
+// recovered writes the VolumeBudgetRecoveredMessage entry.
+func (b *volumeBudget) recovered(rate int64, restored bool) {
+	fields := []zap.Field{
+		zap.Int64("rate", rate),
+		zap.Int64("budget", b.limit),
+	}
+	if restored {
+		fields = append(fields, zap.Stringer("level", b.level.Level()))
+	}
+	unsampledLogger().Desugar().Info(VolumeBudgetRecoveredMessage, fields...)
+}
+
+// action returns the name of the action of the budget.
+func (b *volumeBudget) action() string {
+	if b.raise {
+		return VolumeBudgetRaiseLevel
+	}
+	return VolumeBudgetWarn
+}
+
+// snapshot returns the state of the budget.
+func (b *volumeBudget) snapshot() *VolumeBudgetSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return &VolumeBudgetSnapshot{
+		BytesPerMinute: b.limit,
+		Action:         b.action(),
+		Rate:           b.rate(b.now()),
+		Exceeded:       b.exceeded,
+		LevelRaised:    b.raised,
+	}
+}
+
+// stopVolumeBudget stops the evaluation of the budget of the current global logger, if
+// any. Guarded by initMu.
+var stopVolumeBudget func()
+
+// startVolumeBudget replaces the running volume budget with the one configured by o,
+// applying to level. initMu must be held.
+func startVolumeBudget(o *options, level zap.AtomicLevel) {
+	if stopVolumeBudget != nil {
+		stopVolumeBudget()
+		stopVolumeBudget = nil
+	}
+	activeBudget.Store((*volumeBudget)(nil))
+	if o.volumeBudget <= 0 {
+		return
+	}
+
+	b := &volumeBudget{limit: o.volumeBudget, raise: o.volumeBudgetAction == VolumeBudgetRaiseLevel, level: level, now: time.Now}
+	activeBudget.Store(b)
+
+	ticker := time.NewTicker(time.Second)
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				b.evaluate()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	stopVolumeBudget = func() {
+		ticker.Stop()
+		close(stop)
+		activeBudget.Store((*volumeBudget)(nil))
+	}
+}
@@ -0,0 +1,118 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeClock is a clock advanced by tests.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// installBudget initializes the global logger writing to a file and installs a budget
+// of limit bytes per minute driven by clock, returning it with a function reading the
+// file. No evaluation runs in the background: the test calls evaluate.
+func installBudget(t *testing.T, limit int64, raise bool, clock *fakeClock) (*volumeBudget, func() string) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	Initialize(ProductionEnvName, WithOutputPaths(path))
+	ResetVolumeStats()
+	b := &volumeBudget{limit: limit, raise: raise, level: loadInstance().config.Level, now: clock.now}
+	activeBudget.Store(b)
+	t.Cleanup(func() { activeBudget.Store((*volumeBudget)(nil)) })
+
+	return b, func() string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+}
+
+func TestVolumeBudgetRaiseLevel(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_000_000, 0)}
+	b, read := installBudget(t, 1000, true, clock)
+
+	Named("chatty").Infow("bulk entry", "payload", strings.Repeat("x", 1500))
+	b.evaluate()
+
+	output := read()
+	if !strings.Contains(output, VolumeBudgetExceededMessage) || !strings.Contains(output, `"name":"chatty"`) ||
+		!strings.Contains(output, `"action": "raise_level"`) {
+		t.Fatalf("output lacks the budget warning naming chatty:\n%s", output)
+	}
+	if lvl := loadInstance().config.Level.Level(); lvl != zapcore.WarnLevel {
+		t.Errorf("level = %s, want warn while the budget is exceeded", lvl)
+	}
+	snapshot := EffectiveConfig()
+	if snapshot.Level != "warn" || snapshot.VolumeBudget == nil || !snapshot.VolumeBudget.Exceeded || !snapshot.VolumeBudget.LevelRaised {
+		t.Errorf("EffectiveConfig() = %+v, %+v, want the raised level", snapshot, snapshot.VolumeBudget)
+	}
+	Info("suppressed entry")
+	if strings.Contains(read(), "suppressed entry") {
+		t.Error("Info entry written while the level is raised")
+	}
+
+	clock.advance(time.Minute + time.Second) // The bulk entry leaves the window
+	b.evaluate()
+	clock.advance(VolumeBudgetCooldown / 2)
+	b.evaluate()
+	if !b.snapshot().Exceeded {
+		t.Fatal("budget recovered before the cooldown")
+	}
+	clock.advance(VolumeBudgetCooldown / 2)
+	b.evaluate()
+
+	if !strings.Contains(read(), VolumeBudgetRecoveredMessage) {
+		t.Errorf("output lacks the recovery entry:\n%s", read())
+	}
+	if lvl := loadInstance().config.Level.Level(); lvl != zapcore.InfoLevel {
+		t.Errorf("level = %s, want info restored", lvl)
+	}
+	if s := EffectiveConfig().VolumeBudget; s.Exceeded || s.LevelRaised {
+		t.Errorf("VolumeBudget = %+v, want recovered", s)
+	}
+}
+
+func TestVolumeBudgetWarningRateLimited(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(2_000_000, 0)}
+	b, read := installBudget(t, 1000, false, clock)
+	payload := strings.Repeat("x", 1500)
+
+	Infow("bulk entry", "payload", payload)
+	b.evaluate()
+	clock.advance(10 * time.Second)
+	Infow("bulk entry", "payload", payload)
+	b.evaluate()
+	if n := strings.Count(read(), VolumeBudgetExceededMessage); n != 1 {
+		t.Errorf("%d warnings within a minute, want 1", n)
+	}
+	if lvl := loadInstance().config.Level.Level(); lvl != zapcore.InfoLevel {
+		t.Errorf("level = %s, want info with the warn action", lvl)
+	}
+
+	clock.advance(time.Minute)
+	Infow("bulk entry", "payload", payload)
+	b.evaluate()
+	if n := strings.Count(read(), VolumeBudgetExceededMessage); n != 2 {
+		t.Errorf("%d warnings after a minute, want 2", n)
+	}
+}
+
+func TestVolumeBudgetUnknownAction(t *testing.T) {
+	if _, err := New(ProductionEnvName, WithVolumeBudget(1000), WithVolumeBudgetAction("panic")); err == nil {
+		t.Error("New() succeeded with an unknown volume budget action")
+	}
+}
@@ -0,0 +1,290 @@
+package sazabi
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NameKey is the key of the names given by Named in JSON output. Console output writes
+// names as NameKey=name, so that they can be filtered on in the same way.
+const NameKey = "logger"
+
+// encoders maps encoding names to their constructors. It mirrors zap's encoder
+// registry, which is not accessible outside zap, plus the encodings added by sazabi.
+var encoders = map[string]func(zapcore.EncoderConfig) (zapcore.Encoder, error){
+	"console": func(conf zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return newConsoleEncoder(conf), nil
+	},
+	"json": func(conf zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return zapcore.NewJSONEncoder(conf), nil
+	},
+	fullLineColorEncoding: newFullLineColorEncoder,
+	gcpEncoding:           newGCPEncoder,
+	datadogEncoding:       newDatadogEncoder,
+	ecsEncoding:           newECSEncoder,
+}
+
+// newConsoleEncoder returns a console encoder for conf, writing logger names as
+// NameKey=name unless conf has its own name encoder.
+func newConsoleEncoder(conf zapcore.EncoderConfig) zapcore.Encoder {
+	if conf.EncodeName == nil {
+		conf.EncodeName = func(name string, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendString(NameKey + "=" + name)
+		}
+	}
+	return zapcore.NewConsoleEncoder(conf)
+}
+
+// build constructs a logger from conf the same way zap.Config.Build does, except that
+// each output is opened individually so that options can wrap its WriteSyncer.
+func build(conf zap.Config, o *options) (*zap.Logger, error) {
+	newEncoder, ok := encoders[conf.Encoding]
+	if !ok {
+		return nil, fmt.Errorf("sazabi: no encoder registered for name %q", conf.Encoding)
+	}
+	enc, err := newEncoder(conf.EncoderConfig)
+	if err != nil {
+		return nil, err
+	}
+	switch e := enc.(type) { // Settings that are not part of the encoder config
+	case *gcpEncoder:
+		e.project = o.gcpProject
+	case *ecsEncoder:
+		if o.ecsVersion != "" {
+			e.version = o.ecsVersion
+		}
+	}
+	if o.batchCompression != "" {
+		if _, err := compressor(o.batchCompression); err != nil {
+			return nil, err // Unknown algorithm, even without network outputs
+		}
+	}
+	switch o.volumeBudgetAction {
+	case "", VolumeBudgetWarn, VolumeBudgetRaiseLevel:
+	default:
+		return nil, fmt.Errorf("sazabi: unknown volume budget action %q", o.volumeBudgetAction)
+	}
+
+	errSink, _, err := zap.Open(conf.ErrorOutputPaths...)
+	if err != nil {
+		return nil, err
+	}
+	errSink = internalErrorSink{errSink}
+	var extra []zapcore.Core // Outputs written by their own core
+	var journalErr error
+	if o.journald {
+		journal, err := newJournaldCore(conf.Level)
+		if err == nil {
+			extra = append(extra, journal)
+		} else {
+			journalErr = err
+			conf.OutputPaths = withStderr(conf.OutputPaths)
+		}
+	}
+	outputs, failures, err := openOutputs(conf.OutputPaths, enc, errSink, o)
+	if err != nil {
+		return nil, err
+	}
+	sink := outputs
+	if o.ring != nil {
+		sink = zapcore.NewMultiWriteSyncer(outputs, o.ring)
+	}
+	sink = zapcore.Lock(sink) // Shared by entries and WriteRaw, so lines never interleave
+	o.raw = sink
+	o.access = sink
+	if len(o.accessOutputs) > 0 {
+		access, accessFailures, err := openOutputs(o.accessOutputs, enc, errSink, o)
+		if err != nil {
+			return nil, err
+		}
+		failures = append(failures, accessFailures...)
+		o.access = zapcore.Lock(access)
+	}
+
+	vc := newVolumeCore(enc, sink, conf.Level)
+	vc.global = o.global
+	vc.metrics = o.metrics
+	if o.outputValidation {
+		vc.validator = newOutputValidator(conf)
+	}
+	syslogs, err := newSyslogCores(enc, conf.Level, errSink, o)
+	if err != nil {
+		return nil, err
+	}
+	indexers, err := newElasticsearchCores(conf.Level, errSink, o)
+	if err != nil {
+		return nil, err
+	}
+	forwarders, err := newFluentCores(conf.Level, errSink, o)
+	if err != nil {
+		return nil, err
+	}
+	producers, err := newKafkaCores(conf.Level, errSink, o)
+	if err != nil {
+		return nil, err
+	}
+	exporters, err := newOTLPCores(conf.Level, errSink, o)
+	if err != nil {
+		return nil, err
+	}
+	alerts, err := newAlertCores(errSink, o)
+	if err != nil {
+		return nil, err
+	}
+	pagers, err := newPagerDutyCores(conf.Level, errSink, o)
+	if err != nil {
+		return nil, err
+	}
+	var base zapcore.Core = vc
+	for _, cores := range [][]zapcore.Core{syslogs, indexers, forwarders, producers, exporters, alerts, pagers} {
+		extra = append(extra, cores...)
+	}
+	if len(extra) > 0 {
+		base = zapcore.NewTee(append([]zapcore.Core{vc}, extra...)...)
+	}
+	core, err := wrapCore(base, conf, o)
+	if err != nil {
+		return nil, err
+	}
+	if o.batch, err = newBatchTarget(conf, enc, outputs, errSink, o); err != nil {
+		return nil, err
+	}
+	opts := buildOptions(conf, errSink)
+	if o.fatalHook != nil {
+		opts = append(opts, zap.WithFatalHook(o.fatalHook))
+	}
+	log := zap.New(core, opts...)
+	warnSinkFailures(log, failures)
+	if journalErr != nil {
+		log.Warn(JournaldUnavailableMessage, zap.Error(journalErr))
+	}
+	return log, nil
+}
+
+// wrapCore applies the field-processing stages to core, built from conf. The stages
+// sit below sampling, so they only see entries that are actually written. Masking sits
+// below sanitization, so escape sequences cannot split the data it looks for.
+func wrapCore(core zapcore.Core, conf zap.Config, o *options) (zapcore.Core, error) {
+	core, err := newEscalationCore(core, o)
+	if err != nil {
+		return nil, err
+	}
+	if core, err = newMaskCore(core, o); err != nil {
+		return nil, err
+	}
+	core = newSanitizeCore(core, conf.Development, o)
+	if core, err = newCardinalityCore(core, o); err != nil {
+		return nil, err
+	}
+	core = newTranslateCore(newHumanCore(newComponentCore(core, o), conf.Encoding, o), conf.Encoding, o)
+	return newEmptyMessageCore(newDynamicCore(newRedactCore(newNormalizeCore(core, conf.Encoding, o))), conf.Development, o)
+}
+
+// openOutputs opens every output path and combines them into a single WriteSyncer.
+// With WithFallbackOutput each output is wrapped so that it fails over independently.
+// Optional outputs that cannot be opened are left out and returned as failures.
+// Network outputs report their failures to errSink.
+func openOutputs(paths []string, enc zapcore.Encoder, errSink zapcore.WriteSyncer, o *options) (zapcore.WriteSyncer, []sinkFailure, error) {
+	syncers := make([]zapcore.WriteSyncer, 0, len(paths))
+	var failures []sinkFailure
+	for _, path := range paths {
+		ws, err := openOutput(path, errSink, o)
+		if err != nil && o.optional(path) {
+			failures = append(failures, sinkFailure{name: redactURL(path), err: err})
+			if o.global {
+				setInitFailed(redactURL(path), err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if o.fallbackPath != "" {
+			ws, err = newFallbackSyncer(redactURL(path), ws, enc, o)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		syncers = append(syncers, ws)
+	}
+	return zapcore.NewMultiWriteSyncer(syncers...), failures, nil
+}
+
+// openOutput opens a single output path. Network outputs and rotated files implemented
+// by sazabi are opened with the options; other paths are opened by zap.
+func openOutput(path string, errSink zapcore.WriteSyncer, o *options) (zapcore.WriteSyncer, error) {
+	if u, err := url.Parse(path); err == nil && u.Scheme == "tcp" {
+		return newTCPOutput(u, errSink, o)
+	}
+	if cfg, ok := o.rotations[path]; ok {
+		f, err := newRotatingFile(path, cfg, time.Now, errSink)
+		if err != nil {
+			return nil, err
+		}
+		o.rotatingFiles = append(o.rotatingFiles, f)
+		return f, nil
+	}
+	ws, _, err := zap.Open(path)
+	return ws, err
+}
+
+// buildOptions returns the zap options implied by conf, as zap.Config.Build would apply them.
+func buildOptions(conf zap.Config, errSink zapcore.WriteSyncer) []zap.Option {
+	opts := []zap.Option{zap.ErrorOutput(errSink)}
+
+	if conf.Development {
+		opts = append(opts, zap.Development())
+	}
+
+	if !conf.DisableCaller {
+		opts = append(opts, zap.AddCaller())
+	}
+
+	stackLevel := zap.ErrorLevel
+	if conf.Development {
+		stackLevel = zap.WarnLevel
+	}
+	if !conf.DisableStacktrace {
+		opts = append(opts, zap.AddStacktrace(stackLevel))
+	}
+
+	if sampling := conf.Sampling; sampling != nil {
+		resample := func(core zapcore.Core) zapcore.Core {
+			var samplerOpts []zapcore.SamplerOption
+			if sampling.Hook != nil {
+				samplerOpts = append(samplerOpts, zapcore.SamplerHook(sampling.Hook))
+			}
+			return zapcore.NewSamplerWithOptions(core, time.Second, sampling.Initial, sampling.Thereafter, samplerOpts...)
+		}
+		opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			// Lets sazabi's own entries bypass sampling
+			return &samplingCore{Core: resample(core), unsampled: core, resample: resample}
+		}))
+	}
+
+	if fields := initialFields(conf); len(fields) > 0 {
+		opts = append(opts, zap.Fields(fields...))
+	}
+
+	return opts
+}
+
+// initialFields returns the initial fields of conf, sorted by key.
+func initialFields(conf zap.Config) []zap.Field {
+	keys := make([]string, 0, len(conf.InitialFields))
+	for k := range conf.InitialFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]zap.Field, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, zap.Any(k, conf.InitialFields[k]))
+	}
+	return fields
+}
@@ -0,0 +1,123 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// callerLine returns the "file:line" caller the next line of the calling function
+// should report, in the short format of the caller field.
+func callerLine() string {
+	_, file, line, _ := runtime.Caller(1)
+	return fmt.Sprintf("%s:%d", file[strings.LastIndex(file, "/")+1:], line+1)
+}
+
+// assertCaller checks that the entry with message reports want as its caller.
+func assertCaller(t *testing.T, output, message, want string) {
+	t.Helper()
+
+	line := lineContaining(output, message)
+	if line == "" {
+		t.Fatalf("entry %q not found in output: %s", message, output)
+	}
+	if !strings.Contains(line, "/"+want+"\t") {
+		t.Errorf("entry %q should report caller %s: %s", message, want, line)
+	}
+}
+
+func TestCallerPackageFunctions(t *testing.T) {
+	var want string
+	output := captureStderr(t, func() {
+		sazabi.Initialize("development")
+		want = callerLine()
+		sazabi.Infow("package function caller")
+	})
+
+	assertCaller(t, output, "package function caller", want)
+}
+
+func TestCallerDefault(t *testing.T) {
+	var want string
+	output := captureStderr(t, func() {
+		logger := sazabi.Default()
+		want = callerLine()
+		logger.Infow("default logger caller")
+	})
+
+	assertCaller(t, output, "default logger caller", want)
+}
+
+func TestCallerNew(t *testing.T) {
+	var want string
+	output := captureStderr(t, func() {
+		logger, err := sazabi.New("development")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		want = callerLine()
+		logger.Infow("new logger caller")
+	})
+
+	assertCaller(t, output, "new logger caller", want)
+}
+
+func TestCallerNamed(t *testing.T) {
+	var want string
+	output := captureStderr(t, func() {
+		sazabi.Initialize("development")
+		logger := sazabi.Named("db")
+		want = callerLine()
+		logger.Infow("named logger caller")
+	})
+
+	assertCaller(t, output, "named logger caller", want)
+}
+
+func TestCallerFromContext(t *testing.T) {
+	var want string
+	output := captureStderr(t, func() {
+		sazabi.Initialize("development")
+		handler := sazabi.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := sazabi.FromContext(r.Context())
+			want = callerLine()
+			logger.Infow("request logger caller")
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/caller", nil))
+	})
+
+	assertCaller(t, output, "request logger caller", want)
+}
+
+func TestCallerAt(t *testing.T) {
+	var want string
+	output := captureStderr(t, func() {
+		sazabi.Initialize("development")
+		logger := sazabi.At(time.Now().Add(-time.Minute))
+		want = callerLine()
+		logger.Infow("timed logger caller")
+	})
+
+	assertCaller(t, output, "timed logger caller", want)
+}
+
+func TestCallerWith(t *testing.T) {
+	var want string
+	output := captureStderr(t, func() {
+		sazabi.Initialize("development")
+		logger := sazabi.With("request_id", "r-1")
+		want = callerLine()
+		logger.Infow("child logger caller")
+	})
+
+	assertCaller(t, output, "child logger caller", want)
+}
@@ -0,0 +1,187 @@
+package sazabi
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Capture collects the entries written to a capturing logger, the global logger of
+// StartCapture or one returned by NewCapture. Entries are recorded before encoding, so
+// field values keep their Go types.
+type Capture struct {
+	mu      sync.Mutex
+	entries []CapturedEntry
+}
+
+// CapturedEntry is an entry recorded by a Capture. Fields holds the fields of the
+// entry, including those added through With, with their original values.
+type CapturedEntry struct {
+	Level      zapcore.Level       // Level of the entry
+	Time       time.Time           // Time the entry was written
+	LoggerName string              // Name of the logger, if any
+	Message    string              // Message of the entry
+	Caller     zapcore.EntryCaller // Caller, when caller capture is enabled
+	Fields     []zapcore.Field     // Fields of the entry, in order
+}
+
+// StartCapture replaces the global logger with one recording every entry at Debug level
+// and above, with redaction and dynamic fields applied, until stop is called. Stop
+// restores the previous logger and its runtime settings, or a logger discarding every
+// entry when there was none. Intended for tests; Initialize called meanwhile ends the
+// capture, and stop then keeps the logger it built.
+func StartCapture() (c *Capture, stop func()) {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	c = &Capture{}
+	previous := loadInstance()
+	capturing := &instance{base: zap.New(c.core()), callerEnabled: true}
+	if previous != nil {
+		capturing.environment = previous.environment
+		capturing.config = previous.config
+		capturing.options = previous.options
+		capturing.contextDiagnostics = previous.contextDiagnostics
+	}
+	publish(capturing)
+
+	return c, func() {
+		initMu.Lock()
+		defer initMu.Unlock()
+
+		if current := loadInstance(); current == nil || current.base != capturing.base {
+			return // Initialize ended the capture; runtime settings keep the capturing base
+		}
+		if previous == nil {
+			publish(&instance{base: zap.NewNop()})
+			return
+		}
+		globalInstance.Store(previous)
+	}
+}
+
+// NewCapture returns a standalone logger recording every entry it writes at Debug
+// level and above, with redaction and dynamic fields applied, and the Capture holding
+// them. Unlike StartCapture it leaves the global logger alone, so that tests running
+// in parallel can each capture their own entries; see the sazabitest package.
+func NewCapture() (Logger, *Capture) {
+	c := &Capture{}
+	return zap.New(c.core(), zap.AddCaller()).Sugar(), c
+}
+
+// core returns a core recording its entries into c, below redaction and dynamic fields.
+func (c *Capture) core() zapcore.Core {
+	return newDynamicCore(newRedactCore(&captureCore{LevelEnabler: zapcore.DebugLevel, capture: c}))
+}
+
+// Entries returns the entries recorded so far, oldest first.
+func (c *Capture) Entries() []CapturedEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]CapturedEntry(nil), c.entries...)
+}
+
+// record adds e to the entries of c.
+func (c *Capture) record(e CapturedEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = append(c.entries, e)
+}
+
+// captureCore is a core recording the entries it writes into a Capture.
+type captureCore struct {
+	zapcore.LevelEnabler
+	capture *Capture
+	context []zapcore.Field // Fields added through With
+}
+
+// With implements zapcore.Core.
+func (c *captureCore) With(fields []zapcore.Field) zapcore.Core {
+	return &captureCore{
+		LevelEnabler: c.LevelEnabler,
+		capture:      c.capture,
+		context:      append(c.context[:len(c.context):len(c.context)], fields...),
+	}
+}
+
+// Check implements zapcore.Core.
+func (c *captureCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *captureCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.capture.record(CapturedEntry{
+		Level:      ent.Level,
+		Time:       ent.Time,
+		LoggerName: ent.LoggerName,
+		Message:    ent.Message,
+		Caller:     ent.Caller,
+		Fields:     append(c.context[:len(c.context):len(c.context)], fields...),
+	})
+	return nil
+}
+
+// Sync implements zapcore.Core.
+func (c *captureCore) Sync() error {
+	return nil
+}
+
+// Field returns the last field of the entry named key.
+func (e CapturedEntry) Field(key string) (zapcore.Field, bool) {
+	for i := len(e.Fields) - 1; i >= 0; i-- {
+		if e.Fields[i].Key == key {
+			return e.Fields[i], true
+		}
+	}
+	return zapcore.Field{}, false
+}
+
+// Int returns the value of the signed integer field named key.
+func (e CapturedEntry) Int(key string) (int64, bool) {
+	f, ok := e.Field(key)
+	if !ok {
+		return 0, false
+	}
+	switch f.Type {
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+		return f.Integer, true
+	}
+	return 0, false
+}
+
+// Str returns the value of the string field named key.
+func (e CapturedEntry) Str(key string) (string, bool) {
+	f, ok := e.Field(key)
+	if !ok || f.Type != zapcore.StringType {
+		return "", false
+	}
+	return f.String, true
+}
+
+// Dur returns the value of the time.Duration field named key.
+func (e CapturedEntry) Dur(key string) (time.Duration, bool) {
+	f, ok := e.Field(key)
+	if !ok || f.Type != zapcore.DurationType {
+		return 0, false
+	}
+	return time.Duration(f.Integer), true
+}
+
+// Err returns the error attached to the entry under the "error" key, preserving its
+// identity for errors.Is and errors.As, or nil.
+func (e CapturedEntry) Err() error {
+	f, ok := e.Field("error")
+	if !ok || f.Type != zapcore.ErrorType {
+		return nil
+	}
+	err, _ := f.Interface.(error)
+	return err
+}
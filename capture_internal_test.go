@@ -0,0 +1,24 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import "testing"
+
+func TestCaptureStopWithoutPreviousLogger(t *testing.T) {
+	initMu.Lock()
+	saved := loadInstance()
+	globalInstance.Store((*instance)(nil)) // Never initialized
+	initMu.Unlock()
+	t.Cleanup(func() {
+		if saved != nil {
+			globalInstance.Store(saved)
+		}
+	})
+
+	_, stop := StartCapture()
+	stop()
+
+	Info("after capture")    // Must not panic
+	Named("x").Info("named") // Neither through derived loggers
+}
@@ -0,0 +1,126 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+var errTimeout = errors.New("timeout")
+
+func TestCaptureTypedFields(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+
+	capture, stop := sazabi.StartCapture()
+	wrapped := fmt.Errorf("fetch user: %w", errTimeout)
+	sazabi.Errorw("request failed",
+		"attempts", int64(3),
+		"elapsed", 1500*time.Millisecond,
+		"user", "alice",
+		"error", wrapped,
+	)
+	sazabi.Debug("debug entries are captured too")
+	stop()
+	sazabi.Info("not captured")
+
+	entries := capture.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("captured %d entries, want 2", len(entries))
+	}
+
+	e := entries[0]
+	if e.Level != zapcore.ErrorLevel || e.Message != "request failed" {
+		t.Errorf("entry = %v %q, want error %q", e.Level, e.Message, "request failed")
+	}
+	if got, ok := e.Int("attempts"); !ok || got != 3 {
+		t.Errorf("Int(attempts) = %d, %t, want 3, true", got, ok)
+	}
+	if got, ok := e.Dur("elapsed"); !ok || got != 1500*time.Millisecond {
+		t.Errorf("Dur(elapsed) = %s, %t, want 1.5s, true", got, ok)
+	}
+	if got, ok := e.Str("user"); !ok || got != "alice" {
+		t.Errorf("Str(user) = %q, %t, want alice, true", got, ok)
+	}
+	if err := e.Err(); err != wrapped || !errors.Is(err, errTimeout) {
+		t.Errorf("Err() = %v, want the wrapped error", err)
+	}
+
+	if _, ok := e.Int("elapsed"); ok {
+		t.Error("Int(elapsed) succeeded on a duration field")
+	}
+	if _, ok := e.Str("missing"); ok {
+		t.Error("Str(missing) succeeded")
+	}
+	if entries[1].Err() != nil {
+		t.Errorf("Err() = %v on an entry without error", entries[1].Err())
+	}
+}
+
+func TestCaptureRestoresLogger(t *testing.T) {
+	out := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		capture, stop := sazabi.StartCapture()
+		sazabi.Infow("captured", "count", 7)
+		stop()
+		sazabi.Info("after capture")
+
+		entries := capture.Entries()
+		if len(entries) != 1 {
+			t.Fatalf("captured %d entries, want 1", len(entries))
+		}
+		if got, _ := entries[0].Int("count"); got != 7 {
+			t.Errorf("Int(count) = %d, want 7", got)
+		}
+		if !entries[0].Caller.Defined {
+			t.Error("captured entry has no caller")
+		}
+	})
+
+	if lineContaining(out, "\tcaptured") != "" {
+		t.Errorf("captured entry reached the output:\n%s", out)
+	}
+	if lineContaining(out, "after capture") == "" {
+		t.Errorf("entry after stop missing from the output:\n%s", out)
+	}
+}
+
+func TestCaptureStopAfterRuntimeSetting(t *testing.T) {
+	read := initializeFile(t, "development")
+	capture, stop := sazabi.StartCapture()
+	if err := sazabi.SetStacktraceLevel("warn"); err != nil { // Republishes the capture
+		t.Fatal(err)
+	}
+	sazabi.Info("captured")
+	stop()
+	sazabi.Info("after capture")
+
+	if len(capture.Entries()) != 1 {
+		t.Errorf("captured %d entries, want 1", len(capture.Entries()))
+	}
+	if output := read(); lineContaining(output, "\tcaptured") != "" || lineContaining(output, "after capture") == "" {
+		t.Errorf("output = %q, want the previous logger restored", output)
+	}
+}
+
+func TestCaptureStopKeepsInitialize(t *testing.T) {
+	initializeFile(t, "development")
+	_, stop := sazabi.StartCapture()
+	read := initializeFile(t, sazabi.ProductionEnvName)
+	stop()
+
+	if env := sazabi.EffectiveConfig().Environment; env != sazabi.ProductionEnvName {
+		t.Errorf("environment = %q after stop, want the one initialized during the capture", env)
+	}
+	sazabi.Info("after capture")
+	if lineContaining(read(), "after capture") == "" {
+		t.Errorf("entry after stop missing from the output:\n%s", read())
+	}
+}
@@ -0,0 +1,174 @@
+package sazabi
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Actions taken by WithMaxUniqueKeys once the limit is exceeded.
+const (
+	UniqueKeysWarn = "warn" // Warn once and keep writing new keys
+	UniqueKeysFold = "fold" // Warn once and fold new keys into ExtraKey
+)
+
+// ExtraKey is the key of the object new field keys are folded into by UniqueKeysFold.
+const ExtraKey = "extra"
+
+// UniqueKeysExceededMessage is the message of the warning written the first time the
+// limit set by WithMaxUniqueKeys is exceeded.
+const UniqueKeysExceededMessage = "unique field key limit exceeded"
+
+// WithMaxUniqueKeys caps the number of distinct top-level field keys written by the
+// logger, guarding log indexes against keys generated from data. The first n distinct
+// keys are remembered; the first time an entry carries another key, a
+// UniqueKeysExceededMessage warning is written. With UniqueKeysWarn, new keys are then
+// written as usual; with UniqueKeysFold, they are moved, with their values, into a
+// single ExtraKey object. At most n keys are ever remembered.
+func WithMaxUniqueKeys(n int, action string) Option {
+	return func(o *options) {
+		o.maxUniqueKeys = n
+		o.uniqueKeysAction = action
+	}
+}
+
+// keyTracker remembers up to limit distinct field keys.
+type keyTracker struct {
+	limit  int
+	fold   bool  // Fold unknown keys instead of writing them
+	warned int32 // Set once the warning has been written
+
+	mu   sync.RWMutex
+	keys map[string]struct{}
+}
+
+// known reports whether key was seen while the tracker had room, remembering it if
+// there is still room.
+func (t *keyTracker) known(key string) bool {
+	t.mu.RLock()
+	_, ok := t.keys[key]
+	full := len(t.keys) >= t.limit
+	t.mu.RUnlock()
+	if ok || full {
+		return ok
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.keys[key]; ok {
+		return true
+	}
+	if len(t.keys) >= t.limit {
+		return false
+	}
+	t.keys[key] = struct{}{}
+	return true
+}
+
+// cardinalityCore enforces the limit of a keyTracker on the fields it writes. Fields
+// folded from With are kept aside and merged into the ExtraKey object of each entry.
+type cardinalityCore struct {
+	zapcore.Core
+	tracker *keyTracker
+	extra   []zapcore.Field // Context fields to fold into ExtraKey
+}
+
+// newCardinalityCore wraps core with the key limit of o, or returns core when there is
+// no limit. It fails on an unknown action.
+func newCardinalityCore(core zapcore.Core, o *options) (zapcore.Core, error) {
+	if o.maxUniqueKeys <= 0 {
+		return core, nil
+	}
+	if o.uniqueKeysAction != UniqueKeysWarn && o.uniqueKeysAction != UniqueKeysFold {
+		return nil, fmt.Errorf("sazabi: unknown unique keys action %q", o.uniqueKeysAction)
+	}
+	tracker := &keyTracker{
+		limit: o.maxUniqueKeys,
+		fold:  o.uniqueKeysAction == UniqueKeysFold,
+		keys:  make(map[string]struct{}, o.maxUniqueKeys),
+	}
+	return &cardinalityCore{Core: core, tracker: tracker}, nil
+}
+
+// With implements zapcore.Core.
+func (c *cardinalityCore) With(fields []zapcore.Field) zapcore.Core {
+	kept, folded := c.partition(fields)
+	extra := c.extra
+	if len(folded) > 0 {
+		extra = append(append([]zapcore.Field(nil), c.extra...), folded...)
+	}
+	return &cardinalityCore{Core: c.Core.With(kept), tracker: c.tracker, extra: extra}
+}
+
+// Check implements zapcore.Core.
+func (c *cardinalityCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *cardinalityCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	kept, folded := c.partition(fields)
+	if len(c.extra)+len(folded) > 0 {
+		extra := append(append([]zapcore.Field(nil), c.extra...), folded...)
+		kept = append(kept, zap.Object(ExtraKey, foldedFields(extra)))
+	}
+	return c.Core.Write(ent, kept)
+}
+
+// partition splits fields into the fields to write and the fields to fold, writing the
+// warning the first time an unknown key is met. The input slice is only copied when a
+// field is folded.
+func (c *cardinalityCore) partition(fields []zapcore.Field) (kept, folded []zapcore.Field) {
+	kept = fields
+	for i, f := range fields {
+		if f.Key == ExtraKey || c.tracker.known(f.Key) {
+			if folded != nil {
+				kept = append(kept, f)
+			}
+			continue
+		}
+		c.warnOnce(f.Key)
+		if !c.tracker.fold {
+			continue
+		}
+		if folded == nil {
+			kept = append(make([]zapcore.Field, 0, len(fields)), fields[:i]...)
+		}
+		folded = append(folded, f)
+	}
+	return kept, folded
+}
+
+// warnOnce writes the UniqueKeysExceededMessage warning if it was not written yet.
+func (c *cardinalityCore) warnOnce(key string) {
+	if !atomic.CompareAndSwapInt32(&c.tracker.warned, 0, 1) || !c.Enabled(zapcore.WarnLevel) {
+		return
+	}
+	action := UniqueKeysWarn
+	if c.tracker.fold {
+		action = UniqueKeysFold
+	}
+	c.Core.Write(zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Now(), Message: UniqueKeysExceededMessage}, []zapcore.Field{
+		zap.Int("limit", c.tracker.limit),
+		zap.String("key", key),
+		zap.String("action", action),
+	})
+}
+
+// foldedFields encodes fields as the members of an object.
+type foldedFields []zapcore.Field
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (fs foldedFields) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range fs {
+		f.AddTo(enc)
+	}
+	return nil
+}
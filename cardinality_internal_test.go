@@ -0,0 +1,23 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestKeyTrackerBounded(t *testing.T) {
+	tracker := &keyTracker{limit: 5, keys: make(map[string]struct{})}
+	for i := 0; i < 10000; i++ {
+		tracker.known(fmt.Sprintf("key_%d", i))
+	}
+
+	if len(tracker.keys) != 5 {
+		t.Errorf("tracker remembers %d keys, want the limit of 5", len(tracker.keys))
+	}
+	if !tracker.known("key_4") || tracker.known("key_5") {
+		t.Error("the first keys should stay known and later keys unknown")
+	}
+}
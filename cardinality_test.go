@@ -0,0 +1,64 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestMaxUniqueKeysWarn(t *testing.T) {
+	log, output := newFileLogger(t, sazabi.ProductionEnvName, sazabi.WithMaxUniqueKeys(3, sazabi.UniqueKeysWarn))
+	for i := 0; i < 10; i++ {
+		log.Infow(fmt.Sprintf("attempt %d", i), fmt.Sprintf("retry_attempt_%d", i), i)
+	}
+
+	out := output()
+	if n := strings.Count(out, sazabi.UniqueKeysExceededMessage); n != 1 {
+		t.Fatalf("warning written %d times, want once:\n%s", n, out)
+	}
+	warning := entryFields(t, out, sazabi.UniqueKeysExceededMessage)
+	if warning["limit"] != float64(3) || warning["key"] != "retry_attempt_3" || warning["action"] != sazabi.UniqueKeysWarn {
+		t.Errorf("warning fields = %v, want limit 3, key retry_attempt_3 and action warn", warning)
+	}
+	if fields := entryFields(t, out, "attempt 9"); fields["retry_attempt_9"] != float64(9) {
+		t.Errorf("entry fields = %v, want new keys still written", fields)
+	}
+}
+
+func TestMaxUniqueKeysFold(t *testing.T) {
+	log, output := newFileLogger(t, sazabi.ProductionEnvName, sazabi.WithMaxUniqueKeys(2, sazabi.UniqueKeysFold))
+	log.Infow("first", "user", "alice", "order", 1)
+	log.Infow("second", "user", "bob", "widget_9f3a", "blue", "retry_attempt_17", 17)
+	log.With("request_7c1e", "abc").Infow("third", "order", 2, "widget_77aa", "red")
+
+	out := output()
+	if n := strings.Count(out, sazabi.UniqueKeysExceededMessage); n != 1 {
+		t.Errorf("warning written %d times, want once:\n%s", n, out)
+	}
+
+	second := entryFields(t, out, "\tsecond")
+	if second["user"] != "bob" {
+		t.Errorf("second fields = %v, want known keys kept at the top level", second)
+	}
+	extra, _ := second[sazabi.ExtraKey].(map[string]interface{})
+	if extra["widget_9f3a"] != "blue" || extra["retry_attempt_17"] != float64(17) || len(second) != 2 {
+		t.Errorf("second fields = %v, want new keys folded into %q with their values", second, sazabi.ExtraKey)
+	}
+
+	third := entryFields(t, out, "\tthird")
+	extra, _ = third[sazabi.ExtraKey].(map[string]interface{})
+	if third["order"] != float64(2) || extra["request_7c1e"] != "abc" || extra["widget_77aa"] != "red" {
+		t.Errorf("third fields = %v, want context and entry keys folded together", third)
+	}
+}
+
+func TestMaxUniqueKeysUnknownAction(t *testing.T) {
+	if _, err := sazabi.New(sazabi.ProductionEnvName, sazabi.WithMaxUniqueKeys(10, "drop")); err == nil {
+		t.Error("New() with an unknown action should fail")
+	}
+}
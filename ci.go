@@ -0,0 +1,50 @@
+package sazabi
+
+import (
+	"os"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// CIEnvVar is the environment variable set to true by continuous integration systems.
+const CIEnvVar = "CI"
+
+// WithCIEncoding sets the encoding ("json" or "console") of the development
+// configuration when the output is not interactive, so that CI artifacts can be parsed.
+func WithCIEncoding(encoding string) Option {
+	return func(o *options) {
+		o.ciEncoding = encoding
+	}
+}
+
+// WithInteractive overrides the detection of interactive output: true keeps the
+// human-friendly development console, with colors when enabled, false selects
+// machine-readable output.
+func WithInteractive(interactive bool) Option {
+	return func(o *options) {
+		o.interactive = &interactive
+	}
+}
+
+// interactiveOutput reports whether the output is read by a person: stderr is a
+// terminal and the process does not run in CI. WithInteractive takes precedence.
+func interactiveOutput(o *options) bool {
+	if o.interactive != nil {
+		return *o.interactive
+	}
+	if ci, err := strconv.ParseBool(os.Getenv(CIEnvVar)); err == nil && ci {
+		return false
+	}
+	return isTerminal(os.Stderr)
+}
+
+// applyOutputMode makes a development configuration machine-readable when the output
+// is not interactive, using the WithCIEncoding encoding. Colors are handled separately
+// by colorEnabled.
+func applyOutputMode(conf *zap.Config, o *options) {
+	if !conf.Development || o.ciEncoding == "" || interactiveOutput(o) {
+		return
+	}
+	conf.Encoding = o.ciEncoding
+}
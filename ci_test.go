@@ -0,0 +1,60 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestOutputModeDetection(t *testing.T) {
+	t.Setenv("FORCE_COLOR", "")
+
+	tests := []struct {
+		name    string
+		ci      string
+		opts    []sazabi.Option
+		colored bool
+		json    bool
+	}{
+		{name: "not a terminal", colored: false},
+		{name: "not a terminal with CI encoding", opts: []sazabi.Option{sazabi.WithCIEncoding("json")}, json: true},
+		{name: "override interactive", opts: []sazabi.Option{sazabi.WithInteractive(true)}, colored: true},
+		{name: "override wins over CI", ci: "true", opts: []sazabi.Option{sazabi.WithInteractive(true), sazabi.WithCIEncoding("json")}, colored: true},
+		{name: "CI", ci: "true", opts: []sazabi.Option{sazabi.WithCIEncoding("json")}, json: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(sazabi.CIEnvVar, tt.ci)
+			output := captureStderr(t, func() {
+				sazabi.Initialize("development", append(tt.opts, sazabi.WithFullLineColor())...)
+				sazabi.Warn("output mode entry")
+			})
+
+			line := lineContaining(output, "output mode entry")
+			if got := strings.Contains(line, "\x1b["); got != tt.colored {
+				t.Errorf("colored = %t, want %t: %q", got, tt.colored, line)
+			}
+			if got := json.Valid([]byte(line)); got != tt.json {
+				t.Errorf("json = %t, want %t: %q", got, tt.json, line)
+			}
+		})
+	}
+}
+
+func TestOutputModeInStartupSummary(t *testing.T) {
+	for _, interactive := range []bool{false, true} {
+		output := captureStderr(t, func() {
+			sazabi.Initialize("development", sazabi.WithStartupSummary(), sazabi.WithInteractive(interactive))
+		})
+		fields := entryFields(t, output, sazabi.StartupSummaryMessage)
+		if fields["interactive"] != interactive {
+			t.Errorf("summary interactive = %v, want %t", fields["interactive"], interactive)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package sazabi
+
+import (
+	"bytes"
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// fullLineColorEncoding is the name of the full-line color console encoder in encoders.
+const fullLineColorEncoding = "console-fullcolor"
+
+// ANSI escape sequences used to tint whole lines.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+)
+
+// colorBufferPool provides the buffers holding tinted lines.
+var colorBufferPool = buffer.NewPool()
+
+// WithFullLineColor tints the entire console line of warnings (yellow) and errors (red)
+// instead of only the level. It only applies to the console encoding in development, and
+// is disabled when the output is not interactive (see WithInteractive) or the NO_COLOR
+// environment variable is set. Setting FORCE_COLOR enables it regardless of detection.
+func WithFullLineColor() Option {
+	return func(o *options) {
+		o.fullLineColor = true
+	}
+}
+
+// applyFullLineColor switches a development console configuration to the full-line
+// color encoder when the option is set and color output is allowed.
+func applyFullLineColor(conf *zap.Config, o *options) {
+	if !o.fullLineColor || !conf.Development || conf.Encoding != "console" || !colorEnabled(o) {
+		return
+	}
+	conf.Encoding = fullLineColorEncoding
+}
+
+// colorEnabled reports whether ANSI colors may be written to stderr.
+// NO_COLOR always wins, FORCE_COLOR overrides interactive output detection.
+func colorEnabled(o *options) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	if force := os.Getenv("FORCE_COLOR"); force != "" && force != "0" {
+		return true
+	}
+	return interactiveOutput(o)
+}
+
+// isTerminal reports whether f is attached to a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// fullLineColorEncoder wraps the console encoder and surrounds each rendered line
+// with the color of its level.
+type fullLineColorEncoder struct {
+	zapcore.Encoder
+	lineEnding string
+}
+
+// newFullLineColorEncoder builds a console encoder that tints whole lines.
+func newFullLineColorEncoder(conf zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	lineEnding := conf.LineEnding
+	if lineEnding == "" {
+		lineEnding = zapcore.DefaultLineEnding
+	}
+	return fullLineColorEncoder{
+		Encoder:    newConsoleEncoder(conf),
+		lineEnding: lineEnding,
+	}, nil
+}
+
+// Clone implements zapcore.Encoder.
+func (e fullLineColorEncoder) Clone() zapcore.Encoder {
+	return fullLineColorEncoder{Encoder: e.Encoder.Clone(), lineEnding: e.lineEnding}
+}
+
+// EncodeEntry implements zapcore.Encoder. Resets emitted by a coloring level encoder
+// are followed by the line color again so the tint is not cut short, and the line
+// always ends with a single reset placed before the line ending.
+func (e fullLineColorEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	color := lineColor(ent.Level)
+	if err != nil || color == "" {
+		return buf, err
+	}
+
+	line := bytes.TrimSuffix(buf.Bytes(), []byte(e.lineEnding))
+	line = bytes.TrimSuffix(line, []byte(ansiReset))
+
+	out := colorBufferPool.Get()
+	out.AppendString(color)
+	out.Write(bytes.ReplaceAll(line, []byte(ansiReset), []byte(ansiReset+color)))
+	out.AppendString(ansiReset)
+	out.AppendString(e.lineEnding)
+	buf.Free()
+	return out, nil
+}
+
+// lineColor returns the escape sequence used to tint lines of the given level,
+// or an empty string when lines of that level are left uncolored.
+func lineColor(level zapcore.Level) string {
+	switch {
+	case level >= zapcore.ErrorLevel:
+		return ansiRed
+	case level == zapcore.WarnLevel:
+		return ansiYellow
+	default:
+		return ""
+	}
+}
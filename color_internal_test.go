@@ -0,0 +1,36 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFullLineColorEncoderNestedResets(t *testing.T) {
+	conf := newProductionEncoderConfig()
+	conf.EncodeLevel = zapcore.CapitalColorLevelEncoder
+
+	enc, err := newFullLineColorEncoder(conf)
+	if err != nil {
+		t.Fatalf("newFullLineColorEncoder() error = %v", err)
+	}
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "nested"}, nil)
+	if err != nil {
+		t.Fatalf("EncodeEntry() error = %v", err)
+	}
+	line := buf.String()
+
+	if !strings.HasPrefix(line, ansiRed) || !strings.HasSuffix(line, ansiReset+"\n") {
+		t.Errorf("line %q should be wrapped in red and end with a reset before the newline", line)
+	}
+	// Every reset except the final one must immediately re-apply the line color.
+	body := strings.TrimSuffix(line, ansiReset+"\n")
+	if strings.Count(body, ansiReset) != strings.Count(body, ansiReset+ansiRed) {
+		t.Errorf("line %q contains a dangling reset", line)
+	}
+}
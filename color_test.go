@@ -0,0 +1,84 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+)
+
+func logEachLevel() {
+	sazabi.Info("color info message")
+	sazabi.Warn("color warn message")
+	sazabi.Error("color error message")
+}
+
+func TestWithFullLineColor(t *testing.T) {
+	t.Setenv("FORCE_COLOR", "1")
+
+	output := captureStderr(t, func() {
+		sazabi.Initialize("development", sazabi.WithFullLineColor())
+		logEachLevel()
+	})
+
+	tests := []struct {
+		message string
+		color   string
+	}{
+		{message: "color warn message", color: ansiYellow},
+		{message: "color error message", color: ansiRed},
+	}
+	for _, tt := range tests {
+		line := lineContaining(output, tt.message)
+		if !strings.HasPrefix(line, tt.color) || !strings.HasSuffix(line, ansiReset) {
+			t.Errorf("line %q should be wrapped in %q ... %q", line, tt.color, ansiReset)
+		}
+		if strings.Count(line, ansiReset) != 1 {
+			t.Errorf("line %q should contain exactly one reset", line)
+		}
+	}
+
+	if line := lineContaining(output, "color info message"); strings.Contains(line, "\x1b[") {
+		t.Errorf("info line %q should not be colored", line)
+	}
+}
+
+func TestWithFullLineColorDisabled(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		forceColor  string
+		noColor     bool
+	}{
+		{name: "non-TTY output", environment: "development", forceColor: ""},
+		{name: "NO_COLOR set", environment: "development", forceColor: "1", noColor: true},
+		{name: "production", environment: sazabi.ProductionEnvName, forceColor: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FORCE_COLOR", tt.forceColor)
+			if tt.noColor {
+				t.Setenv("NO_COLOR", "1")
+			}
+
+			output := captureStderr(t, func() {
+				sazabi.Initialize(tt.environment, sazabi.WithFullLineColor())
+				logEachLevel()
+			})
+
+			if strings.Contains(output, "\x1b[") {
+				t.Errorf("output should not contain escape sequences, got: %q", output)
+			}
+		})
+	}
+}
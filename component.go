@@ -0,0 +1,133 @@
+package sazabi
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ComponentKey is the key of the field added by WithAutoComponent.
+const ComponentKey = "component"
+
+// WithAutoComponent adds a ComponentKey field naming the package that logged each entry,
+// derived from its caller: the package path relative to the main module, limited to its
+// last two segments ("internal/billing"), the last segment of the module path for its
+// root package, and the last segment of the path for packages of other modules. Entries
+// of Named loggers, entries logged or bound with a component field and entries without a
+// caller are left alone. Components are cached by program counter.
+func WithAutoComponent() Option {
+	return func(o *options) {
+		o.autoComponent = true
+	}
+}
+
+// componentCore adds the component of the caller to the entries it writes.
+type componentCore struct {
+	zapcore.Core
+	bound bool // A component field was bound with With
+}
+
+// newComponentCore wraps core when o asks for components, or returns core.
+func newComponentCore(core zapcore.Core, o *options) zapcore.Core {
+	if !o.autoComponent {
+		return core
+	}
+	return &componentCore{Core: core}
+}
+
+// With implements zapcore.Core.
+func (c *componentCore) With(fields []zapcore.Field) zapcore.Core {
+	return &componentCore{Core: c.Core.With(fields), bound: c.bound || hasKey(fields, ComponentKey)}
+}
+
+// Check implements zapcore.Core.
+func (c *componentCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *componentCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.LoggerName != "" || !ent.Caller.Defined || c.bound || hasKey(fields, ComponentKey) {
+		return c.Core.Write(ent, fields)
+	}
+	if component := callerComponent(ent.Caller.PC); component != "" {
+		fields = append(fields[:len(fields):len(fields)], zap.String(ComponentKey, component))
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// components caches the component of each program counter.
+var components sync.Map
+
+// callerComponent returns the component of the function containing pc.
+func callerComponent(pc uintptr) string {
+	if component, ok := components.Load(pc); ok {
+		return component.(string)
+	}
+	var component string
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		component = packageComponent(functionPackage(fn.Name()), mainModulePath())
+	}
+	components.Store(pc, component)
+	return component
+}
+
+// functionPackage returns the import path of the package of the function named name,
+// as reported by runtime.Func ("example.com/app/billing.(*Invoice).Total").
+func functionPackage(name string) string {
+	slash := strings.LastIndexByte(name, '/')
+	if dot := strings.IndexByte(name[slash+1:], '.'); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+// packageComponent returns the component of the package at path, in the main module
+// at module.
+func packageComponent(path, module string) string {
+	switch {
+	case module != "" && path == module:
+		return path[strings.LastIndexByte(path, '/')+1:]
+	case module != "" && strings.HasPrefix(path, module+"/"):
+		rel := strings.Split(path[len(module)+1:], "/")
+		if len(rel) > 2 {
+			rel = rel[len(rel)-2:]
+		}
+		return strings.Join(rel, "/")
+	}
+	return path[strings.LastIndexByte(path, '/')+1:]
+}
+
+// mainModule holds the path of the main module, read from the build information once.
+var mainModule struct {
+	once sync.Once
+	path string
+}
+
+// mainModulePath returns the path of the main module, or "" when the binary carries no
+// build information.
+func mainModulePath() string {
+	mainModule.once.Do(func() {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			mainModule.path = info.Main.Path
+		}
+	})
+	return mainModule.path
+}
+
+// hasKey reports whether one of fields is named key.
+func hasKey(fields []zapcore.Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
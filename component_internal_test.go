@@ -0,0 +1,28 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import "testing"
+
+func TestPackageComponent(t *testing.T) {
+	const module = "example.com/app"
+	tests := []struct {
+		function string
+		want     string
+	}{
+		{"example.com/app.run", "app"},
+		{"example.com/app/billing.(*Invoice).Total", "billing"},
+		{"example.com/app/internal/billing.Charge.func1", "internal/billing"},
+		{"example.com/app/internal/billing/v2.Charge", "billing/v2"},
+		{"example.com/application.run", "application"},
+		{"github.com/lib/pq.(*conn).query", "pq"},
+		{"main.main", "main"},
+		{"example.com/app/sort.Slice[...]", "sort"},
+	}
+	for _, tt := range tests {
+		if got := packageComponent(functionPackage(tt.function), module); got != tt.want {
+			t.Errorf("component of %s = %q, want %q", tt.function, got, tt.want)
+		}
+	}
+}
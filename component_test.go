@@ -0,0 +1,49 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/testdata/component"
+)
+
+func TestAutoComponent(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithAutoComponent())
+
+	sazabi.Info("from test package")
+	sazabi.Info("from test package") // Served from the cache
+	component.Log("from testdata package")
+	sazabi.Named("billing").Info("from named logger")
+	sazabi.Infow("explicit component", sazabi.ComponentKey, "custom")
+
+	output := read()
+	tests := []struct {
+		msg  string
+		want interface{}
+	}{
+		{"from test package", "sazabi_test"},
+		{"from testdata package", "testdata/component"},
+		{"from named logger", nil},
+		{"explicit component", "custom"},
+	}
+	for _, tt := range tests {
+		if got := entryFields(t, output, tt.msg)[sazabi.ComponentKey]; got != tt.want {
+			t.Errorf("%s: component = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestAutoComponentDisabled(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName)
+
+	component.Log("without option")
+
+	if fields := entryFields(t, read(), "without option"); fields[sazabi.ComponentKey] != nil {
+		t.Errorf("fields = %v, want no component without WithAutoComponent", fields)
+	}
+}
@@ -0,0 +1,37 @@
+// Package compresslog registers the snappy and zstd batch compression algorithms with
+// sazabi. Import it for its side effects:
+//
+//	import _ "github.com/zeroxsolutions/sazabi/compresslog"
+//
+//	sazabi.Initialize("production", sazabi.WithBatchCompression(sazabi.CompressionZstd))
+package compresslog
+
+import (
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// encoder is shared by every zstd compression; EncodeAll is safe for concurrent use.
+var encoder *zstd.Encoder
+
+func init() {
+	var err error
+	encoder, err = zstd.NewWriter(nil)
+	if err != nil {
+		panic(err) // Only fails on invalid options
+	}
+
+	sazabi.RegisterCompressor(sazabi.CompressionSnappy, Snappy)
+	sazabi.RegisterCompressor(sazabi.CompressionZstd, Zstd)
+}
+
+// Snappy compresses data in the snappy block format.
+func Snappy(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+// Zstd compresses data as a single zstd frame.
+func Zstd(data []byte) ([]byte, error) {
+	return encoder.EncodeAll(data, nil), nil
+}
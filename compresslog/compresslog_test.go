@@ -0,0 +1,114 @@
+//go:build test
+// +build test
+
+package compresslog_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/compresslog"
+)
+
+// representativeBatch returns a batch of production JSON entries with repetitive keys.
+func representativeBatch() []byte {
+	var buf bytes.Buffer
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&buf, `{"level":"info","ts":"2024-03-15T10:30:%02d.000Z","caller":"orders/handler.go:42",`+
+			`"msg":"order placed","request_id":"%032x","user_id":%d,"amount":%d.50,"currency":"EUR"}`+"\n", i%60, i*7919, i, i*3)
+	}
+	return buf.Bytes()
+}
+
+func decompressSnappy(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
+
+func decompressZstd(data []byte) ([]byte, error) {
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+	return decoder.DecodeAll(data, nil)
+}
+
+func TestRoundTrip(t *testing.T) {
+	batch := representativeBatch()
+	tests := []struct {
+		name       string
+		compress   func([]byte) ([]byte, error)
+		decompress func([]byte) ([]byte, error)
+	}{
+		{name: sazabi.CompressionSnappy, compress: compresslog.Snappy, decompress: decompressSnappy},
+		{name: sazabi.CompressionZstd, compress: compresslog.Zstd, decompress: decompressZstd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := tt.compress(batch)
+			if err != nil {
+				t.Fatalf("compress error = %v", err)
+			}
+			if len(compressed) >= len(batch) {
+				t.Errorf("compressed to %d bytes, want less than %d", len(compressed), len(batch))
+			}
+			got, err := tt.decompress(compressed)
+			if err != nil {
+				t.Fatalf("decompress error = %v", err)
+			}
+			if !bytes.Equal(got, batch) {
+				t.Error("decompressed batch differs from the original")
+			}
+		})
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	for _, name := range []string{sazabi.CompressionSnappy, sazabi.CompressionZstd} {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("Initialize() with %s compression panicked: %v", name, r)
+				}
+			}()
+			sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithBatchCompression(name))
+		}()
+	}
+}
+
+func BenchmarkCompression(b *testing.B) {
+	batch := representativeBatch()
+	gzipCompress := func(data []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		err := w.Close()
+		return buf.Bytes(), err
+	}
+
+	for _, bm := range []struct {
+		name     string
+		compress func([]byte) ([]byte, error)
+	}{
+		{name: sazabi.CompressionGzip, compress: gzipCompress},
+		{name: sazabi.CompressionSnappy, compress: compresslog.Snappy},
+		{name: sazabi.CompressionZstd, compress: compresslog.Zstd},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(int64(len(batch)))
+			var compressed []byte
+			for i := 0; i < b.N; i++ {
+				compressed, _ = bm.compress(batch)
+			}
+			b.ReportMetric(100*(1-float64(len(compressed))/float64(len(batch))), "%saved")
+		})
+	}
+}
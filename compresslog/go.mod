@@ -0,0 +1,17 @@
+module github.com/zeroxsolutions/sazabi/compresslog
+
+go 1.22
+
+require (
+	github.com/golang/snappy v1.0.0
+	github.com/klauspost/compress v1.18.0
+	github.com/zeroxsolutions/sazabi v0.0.0
+)
+
+require (
+	github.com/zeroxsolutions/barbatos v0.0.1 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	go.uber.org/zap v1.27.0 // indirect
+)
+
+replace github.com/zeroxsolutions/sazabi => ../
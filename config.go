@@ -0,0 +1,242 @@
+package sazabi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Config is a serializable description of a logging configuration, as kept in
+// configuration files and checked by Validate.
+type Config struct {
+	Environment       string          `json:"environment" yaml:"environment"`
+	Level             string          `json:"level" yaml:"level"`
+	Encoding          string          `json:"encoding" yaml:"encoding"`
+	OutputPaths       []string        `json:"outputPaths" yaml:"outputPaths"`
+	ErrorOutputPaths  []string        `json:"errorOutputPaths" yaml:"errorOutputPaths"`
+	Sampling          *SamplingConfig `json:"sampling" yaml:"sampling"`
+	DisableStacktrace bool            `json:"disableStacktrace" yaml:"disableStacktrace"`
+	BatchCompression  string          `json:"batchCompression" yaml:"batchCompression"`
+	Rotation          *RotationConfig `json:"rotation" yaml:"rotation"`
+	TLS               *TLSConfig      `json:"tls" yaml:"tls"`
+	AllowedKeysOnly   []string        `json:"allowedKeysOnly" yaml:"allowedKeysOnly"` // Keys written, all when empty
+	DroppedKeys       []string        `json:"droppedKeys" yaml:"droppedKeys"`         // Keys never written
+}
+
+// SamplingConfig limits repeated entries: per second, the first Initial entries with the
+// same level and message are written, then every Thereafter-th.
+type SamplingConfig struct {
+	Initial    int `json:"initial" yaml:"initial"`
+	Thereafter int `json:"thereafter" yaml:"thereafter"`
+}
+
+// RotationConfig describes the rotation of file outputs.
+type RotationConfig struct {
+	MaxSizeMB  int           `json:"maxSizeMB" yaml:"maxSizeMB"`   // Size triggering a rotation, none when zero
+	Interval   time.Duration `json:"interval" yaml:"interval"`     // Time between rotations, none when zero
+	MaxAge     time.Duration `json:"maxAge" yaml:"maxAge"`         // Age after which rotated files are removed
+	MaxBackups int           `json:"maxBackups" yaml:"maxBackups"` // Number of rotated files kept, all when zero
+	Compress   bool          `json:"compress" yaml:"compress"`     // Gzip rotated files
+}
+
+// TLSConfig names the PEM files securing network outputs.
+type TLSConfig struct {
+	CAFile   string `json:"caFile" yaml:"caFile"`
+	CertFile string `json:"certFile" yaml:"certFile"`
+	KeyFile  string `json:"keyFile" yaml:"keyFile"`
+}
+
+// Severity is the severity of a ValidationIssue.
+type Severity string
+
+// Severities of validation issues.
+const (
+	SeverityError   Severity = "error"   // The configuration cannot be used
+	SeverityWarning Severity = "warning" // The configuration works but probably not as intended
+)
+
+// Codes of validation issues.
+const (
+	IssueInvalidLevel       = "invalid_level"
+	IssueUnknownEncoding    = "unknown_encoding"
+	IssueUnknownScheme      = "unknown_output_scheme"
+	IssueInvalidOutput      = "invalid_output"
+	IssueMissingDirectory   = "missing_output_directory"
+	IssueInvalidSampling    = "invalid_sampling"
+	IssueUnknownCompression = "unknown_compression"
+	IssueInvalidRotation    = "invalid_rotation"
+	IssueInvalidTLS         = "invalid_tls"
+	IssueConflictingKeys    = "conflicting_keys"
+)
+
+// ValidationIssue is a problem found by Validate.
+type ValidationIssue struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`  // Machine-readable class of the issue
+	Field    string   `json:"field"` // Config field at fault, as in JSON
+	Message  string   `json:"message"`
+}
+
+// String returns the issue in a form suitable for command output.
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", i.Severity, i.Field, i.Message, i.Code)
+}
+
+// minRotationInterval is the shortest rotation interval that is not reported.
+const minRotationInterval = time.Minute
+
+// Validate checks cfg without building a logger, so that configurations can be vetted
+// before they are deployed. Nothing is opened or created: the filesystem is only read
+// to check that the directories of file outputs and the TLS files exist. It returns
+// nil when cfg has no issue.
+func Validate(cfg Config) []ValidationIssue {
+	var issues []ValidationIssue
+	report := func(severity Severity, code, field, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Severity: severity, Code: code, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if cfg.Level != "" {
+		if _, err := zapcore.ParseLevel(cfg.Level); err != nil {
+			report(SeverityError, IssueInvalidLevel, "level", "%q is not a level", cfg.Level)
+		}
+	}
+	if cfg.Encoding != "" {
+		if _, ok := encoders[cfg.Encoding]; !ok {
+			report(SeverityError, IssueUnknownEncoding, "encoding", "no encoder registered for %q", cfg.Encoding)
+		}
+	}
+
+	for _, path := range cfg.OutputPaths {
+		validateOutput(path, func(severity Severity, code, format string, args ...interface{}) {
+			report(severity, code, "outputPaths", format, args...)
+		})
+	}
+	for _, path := range cfg.ErrorOutputPaths {
+		validateOutput(path, func(severity Severity, code, format string, args ...interface{}) {
+			report(severity, code, "errorOutputPaths", format, args...)
+		})
+	}
+
+	if s := cfg.Sampling; s != nil && (s.Initial < 0 || s.Thereafter < 0) {
+		report(SeverityError, IssueInvalidSampling, "sampling", "initial (%d) and thereafter (%d) must not be negative", s.Initial, s.Thereafter)
+	}
+	if cfg.BatchCompression != "" {
+		if _, err := compressor(cfg.BatchCompression); err != nil {
+			report(SeverityError, IssueUnknownCompression, "batchCompression", "no compressor registered for %q", cfg.BatchCompression)
+		}
+	}
+	if r := cfg.Rotation; r != nil {
+		validateRotation(*r, func(severity Severity, format string, args ...interface{}) {
+			report(severity, IssueInvalidRotation, "rotation", format, args...)
+		})
+	}
+	if t := cfg.TLS; t != nil {
+		validateTLS(*t, func(format string, args ...interface{}) {
+			report(SeverityError, IssueInvalidTLS, "tls", format, args...)
+		})
+	}
+
+	dropped := make(map[string]struct{}, len(cfg.DroppedKeys))
+	for _, key := range cfg.DroppedKeys {
+		dropped[key] = struct{}{}
+	}
+	for _, key := range cfg.AllowedKeysOnly {
+		if _, ok := dropped[key]; ok {
+			report(SeverityWarning, IssueConflictingKeys, "droppedKeys", "%q is both allowed and dropped; it will be dropped", key)
+		}
+	}
+
+	sortIssues(issues)
+	return issues
+}
+
+// validateOutput checks an output path, reporting its issues with report.
+func validateOutput(path string, report func(severity Severity, code, format string, args ...interface{})) {
+	if path == "stdout" || path == "stderr" {
+		return
+	}
+
+	u, err := url.Parse(path)
+	if err == nil && u.Scheme != "" && !isWindowsVolume(path) {
+		switch u.Scheme {
+		case "file":
+			path = u.Path
+		case "tcp":
+			if u.Host == "" {
+				report(SeverityError, IssueInvalidOutput, "%q has no host", redactURL(path))
+			}
+			return
+		default:
+			report(SeverityWarning, IssueUnknownScheme, "%q is not a built-in scheme; it must be registered with zap.RegisterSink", u.Scheme)
+			return
+		}
+	}
+
+	dir := filepath.Dir(path)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		report(SeverityError, IssueMissingDirectory, "directory %q of output %q does not exist", dir, path)
+	}
+}
+
+// isWindowsVolume reports whether path starts with a Windows drive letter, which
+// url.Parse mistakes for a scheme.
+func isWindowsVolume(path string) bool {
+	return runtime.GOOS == "windows" && filepath.VolumeName(path) != ""
+}
+
+// validateRotation checks rotation parameters, reporting their issues with report.
+func validateRotation(r RotationConfig, report func(severity Severity, format string, args ...interface{})) {
+	if r.MaxSizeMB < 0 || r.MaxBackups < 0 || r.MaxAge < 0 || r.Interval < 0 {
+		report(SeverityError, "maxSizeMB, interval, maxAge and maxBackups must not be negative")
+		return
+	}
+	if r.MaxSizeMB == 0 && r.Interval == 0 {
+		report(SeverityWarning, "neither maxSizeMB nor interval is set, so files are never rotated")
+	}
+	if r.Interval > 0 && r.Interval < minRotationInterval {
+		report(SeverityWarning, "interval %s is shorter than %s", r.Interval, minRotationInterval)
+	}
+	if r.MaxAge > 0 && r.Interval > 0 && r.MaxAge < r.Interval {
+		report(SeverityWarning, "maxAge %s is shorter than interval %s, so every rotated file is removed", r.MaxAge, r.Interval)
+	}
+}
+
+// validateTLS checks that the TLS files exist and parse, reporting their issues with
+// report.
+func validateTLS(t TLSConfig, report func(format string, args ...interface{})) {
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		switch {
+		case err != nil:
+			report("caFile: %v", err)
+		case !x509.NewCertPool().AppendCertsFromPEM(pem):
+			report("caFile: %q holds no PEM certificate", t.CAFile)
+		}
+	}
+
+	switch {
+	case t.CertFile == "" && t.KeyFile == "":
+	case t.CertFile == "" || t.KeyFile == "":
+		report("certFile and keyFile must be set together")
+	default:
+		if _, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile); err != nil {
+			report("certFile and keyFile: %v", err)
+		}
+	}
+}
+
+// sortIssues orders issues by severity, errors first, keeping the order of the checks
+// otherwise.
+func sortIssues(issues []ValidationIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Severity == SeverityError && issues[j].Severity != SeverityError
+	})
+}
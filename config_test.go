@@ -0,0 +1,140 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// writeTLSFiles writes a self-signed certificate and its key to dir and returns their paths.
+func writeTLSFiles(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sazabi test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestValidateValid(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTLSFiles(t, dir)
+
+	cfg := sazabi.Config{
+		Level:            "debug",
+		Encoding:         "json",
+		OutputPaths:      []string{"stdout", filepath.Join(dir, "app.log"), "file://" + filepath.Join(dir, "other.log"), "tcp://collector:5170"},
+		ErrorOutputPaths: []string{"stderr"},
+		Sampling:         &sazabi.SamplingConfig{Initial: 100, Thereafter: 100},
+		BatchCompression: sazabi.CompressionGzip,
+		Rotation:         &sazabi.RotationConfig{Interval: 24 * time.Hour, MaxAge: 7 * 24 * time.Hour, MaxBackups: 7},
+		TLS:              &sazabi.TLSConfig{CAFile: certFile, CertFile: certFile, KeyFile: keyFile},
+		AllowedKeysOnly:  []string{"user", "order"},
+		DroppedKeys:      []string{"password"},
+	}
+	if issues := sazabi.Validate(cfg); len(issues) != 0 {
+		t.Errorf("Validate() = %v, want no issue", issues)
+	}
+}
+
+func TestValidateIssues(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		cfg      sazabi.Config
+		severity sazabi.Severity
+		code     string
+		field    string
+	}{
+		{"level", sazabi.Config{Level: "verbose"}, sazabi.SeverityError, sazabi.IssueInvalidLevel, "level"},
+		{"encoding", sazabi.Config{Encoding: "xml"}, sazabi.SeverityError, sazabi.IssueUnknownEncoding, "encoding"},
+		{"scheme", sazabi.Config{OutputPaths: []string{"kafka://broker/logs"}}, sazabi.SeverityWarning, sazabi.IssueUnknownScheme, "outputPaths"},
+		{"output", sazabi.Config{OutputPaths: []string{"tcp://"}}, sazabi.SeverityError, sazabi.IssueInvalidOutput, "outputPaths"},
+		{"directory", sazabi.Config{ErrorOutputPaths: []string{filepath.Join(dir, "missing", "err.log")}}, sazabi.SeverityError, sazabi.IssueMissingDirectory, "errorOutputPaths"},
+		{"sampling", sazabi.Config{Sampling: &sazabi.SamplingConfig{Initial: -1}}, sazabi.SeverityError, sazabi.IssueInvalidSampling, "sampling"},
+		{"compression", sazabi.Config{BatchCompression: "lz4"}, sazabi.SeverityError, sazabi.IssueUnknownCompression, "batchCompression"},
+		{"rotation negative", sazabi.Config{Rotation: &sazabi.RotationConfig{MaxBackups: -1, MaxSizeMB: 10}}, sazabi.SeverityError, sazabi.IssueInvalidRotation, "rotation"},
+		{"rotation interval", sazabi.Config{Rotation: &sazabi.RotationConfig{Interval: time.Second}}, sazabi.SeverityWarning, sazabi.IssueInvalidRotation, "rotation"},
+		{"tls missing", sazabi.Config{TLS: &sazabi.TLSConfig{CAFile: filepath.Join(dir, "ca.pem")}}, sazabi.SeverityError, sazabi.IssueInvalidTLS, "tls"},
+		{"tls pair", sazabi.Config{TLS: &sazabi.TLSConfig{CertFile: filepath.Join(dir, "cert.pem")}}, sazabi.SeverityError, sazabi.IssueInvalidTLS, "tls"},
+		{"keys", sazabi.Config{AllowedKeysOnly: []string{"user", "token"}, DroppedKeys: []string{"token"}}, sazabi.SeverityWarning, sazabi.IssueConflictingKeys, "droppedKeys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := sazabi.Validate(tt.cfg)
+			if len(issues) != 1 {
+				t.Fatalf("Validate() = %v, want one issue", issues)
+			}
+			if got := issues[0]; got.Severity != tt.severity || got.Code != tt.code || got.Field != tt.field || got.Message == "" {
+				t.Errorf("Validate() = %+v, want %s %s on %s with a message", got, tt.severity, tt.code, tt.field)
+			}
+		})
+	}
+}
+
+func TestValidateErrorsFirst(t *testing.T) {
+	issues := sazabi.Validate(sazabi.Config{
+		OutputPaths: []string{"kafka://broker/logs"},
+		Level:       "verbose",
+	})
+	if len(issues) != 2 || issues[0].Severity != sazabi.SeverityError || issues[1].Severity != sazabi.SeverityWarning {
+		t.Errorf("Validate() = %v, want the error before the warning", issues)
+	}
+}
+
+func TestValidateDoesNotCreateFiles(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "app.log")
+
+	sazabi.Validate(sazabi.Config{
+		OutputPaths:      []string{output, "file://" + filepath.Join(dir, "other.log")},
+		ErrorOutputPaths: []string{filepath.Join(dir, "missing", "err.log")},
+	})
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Validate() created %d entries in the output directory, want none", len(entries))
+	}
+}
@@ -0,0 +1,110 @@
+package sazabi
+
+import (
+	"bytes"
+	"os"
+	"sync"
+)
+
+// EnvironmentEnvVar is the environment variable naming the environment used by
+// AutoInitialize. It takes precedence over the container heuristics.
+const EnvironmentEnvVar = "APP_ENV"
+
+// Heuristics reported by AutoInitialize under the environment_source key of the
+// startup summary, when one of them detected a container.
+const (
+	HeuristicDockerEnv  = "container:dockerenv"  // /.dockerenv exists
+	HeuristicCgroup     = "container:cgroup"     // /proc/1/cgroup names a container runtime
+	HeuristicKubernetes = "container:kubernetes" // KUBERNETES_SERVICE_HOST is set
+)
+
+// cgroupMarkers are the substrings of /proc/1/cgroup identifying a container runtime.
+var cgroupMarkers = [][]byte{[]byte("docker"), []byte("kubepods"), []byte("containerd"), []byte("libpod"), []byte("lxc")}
+
+// containerProbe detects whether the process runs in a container. Its functions access
+// the filesystem and the environment, and are replaced by tests.
+type containerProbe struct {
+	stat     func(path string) error
+	readFile func(path string) ([]byte, error)
+	lookup   func(key string) (string, bool)
+
+	once      sync.Once
+	heuristic string // Heuristic that detected a container, empty when none did
+}
+
+// defaultProbe is the probe of the running process. Its result is cached.
+var defaultProbe = &containerProbe{
+	stat: func(path string) error {
+		_, err := os.Stat(path)
+		return err
+	},
+	readFile: os.ReadFile,
+	lookup:   os.LookupEnv,
+}
+
+// detect returns the heuristic that detected a container, or "". The checks run once.
+func (p *containerProbe) detect() string {
+	p.once.Do(func() {
+		switch {
+		case p.lookupSet("KUBERNETES_SERVICE_HOST"):
+			p.heuristic = HeuristicKubernetes
+		case p.stat("/.dockerenv") == nil:
+			p.heuristic = HeuristicDockerEnv
+		case p.cgroupContainer():
+			p.heuristic = HeuristicCgroup
+		}
+	})
+	return p.heuristic
+}
+
+// lookupSet reports whether the environment variable key is set to a non-empty value.
+func (p *containerProbe) lookupSet(key string) bool {
+	value, ok := p.lookup(key)
+	return ok && value != ""
+}
+
+// cgroupContainer reports whether the cgroups of PID 1 belong to a container runtime.
+func (p *containerProbe) cgroupContainer() bool {
+	data, err := p.readFile("/proc/1/cgroup")
+	if err != nil {
+		return false
+	}
+	for _, marker := range cgroupMarkers {
+		if bytes.Contains(data, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoInitialize initializes the global logger for the environment it runs in. The
+// environment named by EnvironmentEnvVar wins. Otherwise, when the process runs in a
+// container (/.dockerenv exists, /proc/1/cgroup names a container runtime, or
+// KUBERNETES_SERVICE_HOST is set), the production configuration is used with JSON
+// written to stdout, and the startup summary is emitted with the heuristic that fired
+// under environment_source. Otherwise the development configuration is used. opts
+// apply on top, so WithOutputPaths replaces stdout. Call Initialize to choose the
+// environment explicitly. The checks run once per process.
+func AutoInitialize(opts ...Option) {
+	if err := autoInitialize(defaultProbe, opts, callerLocation(2)); err != nil {
+		panic(err)
+	}
+}
+
+// autoInitialize implements AutoInitialize with the container checks of p.
+func autoInitialize(p *containerProbe, opts []Option, caller string) error {
+	if environment, ok := p.lookup(EnvironmentEnvVar); ok && environment != "" {
+		o := newOptions(opts)
+		o.environmentSource = EnvironmentEnvVar
+		return initialize(environment, o, caller)
+	}
+
+	heuristic := p.detect()
+	if heuristic == "" {
+		return initialize("development", newOptions(opts), caller)
+	}
+	defaults := []Option{WithOutputPaths("stdout"), WithStartupSummary(), WithJSONEncoding()}
+	o := newOptions(append(defaults, opts...))
+	o.environmentSource = heuristic
+	return initialize(ProductionEnvName, o, caller)
+}
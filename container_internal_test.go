@@ -0,0 +1,140 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeProbe returns a probe seeing the files and environment variables given, and
+// counting the checks it makes in *checks.
+func fakeProbe(files map[string]string, env map[string]string, checks *int) *containerProbe {
+	return &containerProbe{
+		stat: func(path string) error {
+			*checks++
+			if _, ok := files[path]; ok {
+				return nil
+			}
+			return os.ErrNotExist
+		},
+		readFile: func(path string) ([]byte, error) {
+			*checks++
+			if data, ok := files[path]; ok {
+				return []byte(data), nil
+			}
+			return nil, os.ErrNotExist
+		},
+		lookup: func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		},
+	}
+}
+
+func TestContainerDetection(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		env   map[string]string
+		want  string
+	}{
+		{name: "kubernetes", env: map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"}, want: HeuristicKubernetes},
+		{name: "dockerenv", files: map[string]string{"/.dockerenv": ""}, want: HeuristicDockerEnv},
+		{name: "cgroup docker", files: map[string]string{"/proc/1/cgroup": "12:pids:/docker/4f2a9c\n"}, want: HeuristicCgroup},
+		{name: "cgroup kubepods", files: map[string]string{"/proc/1/cgroup": "0::/kubepods/besteffort/pod1\n"}, want: HeuristicCgroup},
+		{name: "host cgroup", files: map[string]string{"/proc/1/cgroup": "0::/init.scope\n"}, want: ""},
+		{name: "empty kubernetes variable", env: map[string]string{"KUBERNETES_SERVICE_HOST": ""}, want: ""},
+		{name: "nothing", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var checks int
+			if got := fakeProbe(tt.files, tt.env, &checks).detect(); got != tt.want {
+				t.Errorf("detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerDetectionCached(t *testing.T) {
+	var checks int
+	p := fakeProbe(map[string]string{"/proc/1/cgroup": "0::/init.scope\n"}, nil, &checks)
+
+	p.detect()
+	first := checks
+	p.detect()
+	if checks != first {
+		t.Errorf("second detect() made %d more checks, want the cached result", checks-first)
+	}
+}
+
+// autoInitializeFile runs autoInitialize with p, writing to a file, and returns what
+// was written.
+func autoInitializeFile(t *testing.T, p *containerProbe) string {
+	t.Cleanup(func() { Initialize(ProductionEnvName) })
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := autoInitialize(p, []Option{WithOutputPaths(path)}, "main.go:1"); err != nil {
+		t.Fatal(err)
+	}
+	loadInstance().base.Sync()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestAutoInitializeContainer(t *testing.T) {
+	var checks int
+	output := autoInitializeFile(t, fakeProbe(map[string]string{"/.dockerenv": ""}, nil, &checks))
+
+	if init, _ := LastInitializer(); init.Environment != ProductionEnvName {
+		t.Errorf("environment = %q, want production in a container", init.Environment)
+	}
+	var summary string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, `"msg":"`+StartupSummaryMessage+`"`) {
+			summary = line
+		}
+	}
+	if !strings.HasPrefix(summary, "{") || !strings.Contains(summary, `"environment_source":"`+HeuristicDockerEnv+`"`) {
+		t.Errorf("output = %q, want the JSON startup summary naming the heuristic", output)
+	}
+}
+
+func TestAutoInitializeEnvironmentVariable(t *testing.T) {
+	var checks int
+	env := map[string]string{EnvironmentEnvVar: "development", "KUBERNETES_SERVICE_HOST": "10.0.0.1"}
+	output := autoInitializeFile(t, fakeProbe(nil, env, &checks))
+
+	if init, _ := LastInitializer(); init.Environment != "development" {
+		t.Errorf("environment = %q, want %s to override the heuristics", init.Environment, EnvironmentEnvVar)
+	}
+	if strings.Contains(output, "environment_source") || checks != 0 {
+		t.Errorf("heuristics ran (%d checks) despite %s: %q", checks, EnvironmentEnvVar, output)
+	}
+}
+
+func TestAutoInitializeOutsideContainer(t *testing.T) {
+	var checks int
+	autoInitializeFile(t, fakeProbe(nil, nil, &checks))
+
+	if init, _ := LastInitializer(); init.Environment != "development" {
+		t.Errorf("environment = %q, want development outside containers", init.Environment)
+	}
+}
+
+func TestAutoInitializeError(t *testing.T) {
+	var checks int
+	t.Cleanup(func() { Initialize(ProductionEnvName) })
+	err := autoInitialize(fakeProbe(map[string]string{"/.dockerenv": ""}, nil, &checks),
+		[]Option{WithEmptyMessagePolicy("ignore")}, "main.go:1")
+	if err == nil || errors.Is(err, ErrConflictingInitialize) {
+		t.Errorf("autoInitialize() = %v, want the option error", err)
+	}
+}
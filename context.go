@@ -0,0 +1,104 @@
+package sazabi
+
+import (
+	"context"
+
+	"github.com/zeroxsolutions/sazabi/internal/ctxkeys"
+)
+
+// Keys of the fields the Ctx functions add from the context.
+const (
+	CorrelationIDKey = "correlation_id" // Set by ContextWithCorrelationID
+	TenantKey        = "tenant"         // Set by ContextWithTenant
+)
+
+// NewContext returns a copy of ctx carrying l, to be retrieved with FromContext.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, ctxkeys.Logger, l)
+}
+
+// FromContext returns the logger stored in ctx by NewContext, or the global logger
+// when ctx carries none, or a nil logger. The result is never nil, even before
+// Initialize.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := contextLogger(ctx); ok {
+		return l
+	}
+	return directLogger()
+}
+
+// ContextWithFields returns a copy of ctx carrying the key-value pairs, which the Ctx
+// functions add to their entries. Pairs are appended to those already in ctx.
+func ContextWithFields(ctx context.Context, keysValues ...interface{}) context.Context {
+	existing := contextFields(ctx)
+	return context.WithValue(ctx, ctxkeys.Fields, append(existing[:len(existing):len(existing)], keysValues...))
+}
+
+// ContextWithCorrelationID returns a copy of ctx carrying id, which the Ctx functions
+// add to their entries under CorrelationIDKey.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxkeys.CorrelationID, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID stored in ctx, or an empty string.
+func CorrelationIDFromContext(ctx context.Context) string {
+	return contextString(ctx, ctxkeys.CorrelationID)
+}
+
+// ContextWithTenant returns a copy of ctx carrying tenant, which the Ctx functions add
+// to their entries under TenantKey.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, ctxkeys.Tenant, tenant)
+}
+
+// TenantFromContext returns the tenant stored in ctx, or an empty string.
+func TenantFromContext(ctx context.Context) string {
+	return contextString(ctx, ctxkeys.Tenant)
+}
+
+// ContextState reports the sazabi values stored in a context.
+type ContextState struct {
+	Logger        bool   // A logger was stored by NewContext
+	Fields        int    // Number of key-value pairs stored by ContextWithFields
+	CorrelationID string // Correlation ID stored by ContextWithCorrelationID
+	Tenant        string // Tenant stored by ContextWithTenant
+}
+
+// ContextInfo reports which sazabi values ctx carries, to diagnose middleware that
+// runs in the wrong order and loses or never sets them.
+func ContextInfo(ctx context.Context) ContextState {
+	_, hasLogger := contextLogger(ctx)
+	return ContextState{
+		Logger:        hasLogger,
+		Fields:        len(contextFields(ctx)) / 2,
+		CorrelationID: CorrelationIDFromContext(ctx),
+		Tenant:        TenantFromContext(ctx),
+	}
+}
+
+// contextLogger returns the logger stored in ctx by NewContext.
+func contextLogger(ctx context.Context) (Logger, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	l, ok := ctx.Value(ctxkeys.Logger).(Logger)
+	return l, ok && l != nil
+}
+
+// contextFields returns the key-value pairs stored in ctx by ContextWithFields.
+func contextFields(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(ctxkeys.Fields).([]interface{})
+	return fields
+}
+
+// contextString returns the string stored in ctx under key.
+func contextString(ctx context.Context, key *ctxkeys.Key) string {
+	if ctx == nil {
+		return ""
+	}
+	s, _ := ctx.Value(key).(string)
+	return s
+}
@@ -0,0 +1,125 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestNewContextFromContext(t *testing.T) {
+	sazabi.Initialize("development")
+	stored := sazabi.Default()
+
+	ctx := sazabi.NewContext(context.Background(), stored)
+	if got := sazabi.FromContext(ctx); got != stored {
+		t.Error("FromContext() should return the logger stored by NewContext")
+	}
+}
+
+func TestFromContextFallback(t *testing.T) {
+	sazabi.Initialize("development")
+
+	if sazabi.FromContext(context.Background()) == nil {
+		t.Error("FromContext() should fall back to the global logger")
+	}
+	if sazabi.FromContext(nil) == nil {
+		t.Error("FromContext(nil) should fall back to the global logger")
+	}
+}
+
+func TestFromContextNested(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithJSONEncoding())
+
+	outer := sazabi.NewContext(context.Background(), sazabi.With("request_id", "r-1"))
+	inner := sazabi.NewContext(outer, sazabi.With("request_id", "r-1", "step", "charge"))
+	sazabi.FromContext(outer).Info("outer entry")
+	sazabi.FromContext(inner).Info("inner entry")
+	sazabi.FromContext(sazabi.NewContext(context.Background(), nil)).Info("nil logger entry")
+	sazabi.FromContext(context.Background()).Info("global entry")
+
+	output := read()
+	if fields := entryFields(t, output, "outer entry"); fields["request_id"] != "r-1" || fields["step"] != nil {
+		t.Errorf("outer entry fields = %v, want the outer logger unaffected by the override", fields)
+	}
+	if fields := entryFields(t, output, "inner entry"); fields["request_id"] != "r-1" || fields["step"] != "charge" {
+		t.Errorf("inner entry fields = %v, want the overriding logger", fields)
+	}
+	for _, msg := range []string{"nil logger entry", "global entry"} {
+		if fields := entryFields(t, output, msg); fields["request_id"] != nil {
+			t.Errorf("entry %q fields = %v, want the global logger", msg, fields)
+		}
+	}
+}
+
+// userKey is an application context key whose names match those of sazabi's keys.
+type userKey string
+
+func TestContextInfo(t *testing.T) {
+	sazabi.Initialize("development")
+
+	if got := sazabi.ContextInfo(context.Background()); got != (sazabi.ContextState{}) {
+		t.Errorf("ContextInfo(empty) = %+v, want the zero state", got)
+	}
+
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, "logger", "user value")
+	ctx = context.WithValue(ctx, userKey("sazabi.tenant"), "user tenant")
+	ctx = sazabi.NewContext(ctx, sazabi.Default())
+	ctx = sazabi.ContextWithFields(ctx, "order", 42)
+	ctx = sazabi.ContextWithFields(ctx, "cart", "c-1")
+	ctx = sazabi.ContextWithCorrelationID(ctx, "corr-1")
+	ctx = sazabi.ContextWithTenant(ctx, "acme")
+	ctx = context.WithValue(ctx, "correlation_id", "user correlation")
+
+	want := sazabi.ContextState{Logger: true, Fields: 2, CorrelationID: "corr-1", Tenant: "acme"}
+	if got := sazabi.ContextInfo(ctx); got != want {
+		t.Errorf("ContextInfo() = %+v, want %+v", got, want)
+	}
+	if got := ctx.Value("logger"); got != "user value" {
+		t.Errorf("user key %q = %v, want it unaffected", "logger", got)
+	}
+	if got := ctx.Value(userKey("sazabi.tenant")); got != "user tenant" {
+		t.Errorf("user key %q = %v, want it unaffected", "sazabi.tenant", got)
+	}
+	if got := ctx.Value("correlation_id"); got != "user correlation" {
+		t.Errorf("user key %q = %v, want it unaffected", "correlation_id", got)
+	}
+}
+
+func TestCtxFunctionsContextValues(t *testing.T) {
+	sazabi.Initialize("development")
+
+	ctx := sazabi.ContextWithFields(context.Background(), "order", 42)
+	ctx = sazabi.ContextWithCorrelationID(ctx, "corr-1")
+	ctx = sazabi.ContextWithTenant(ctx, "acme")
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	capture, stop := sazabi.StartCapture()
+	sazabi.InfoCtx(ctx, "with context values", "user", "alice")
+	stop()
+
+	entries := capture.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("captured %d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	for key, want := range map[string]string{
+		sazabi.CorrelationIDKey: "corr-1",
+		sazabi.TenantKey:        "acme",
+		"user":                  "alice",
+	} {
+		if got, _ := e.Str(key); got != want {
+			t.Errorf("field %q = %q, want %q", key, got, want)
+		}
+	}
+	if got, _ := e.Int("order"); got != 42 {
+		t.Errorf("field %q = %d, want 42", "order", got)
+	}
+}
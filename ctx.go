@@ -0,0 +1,121 @@
+package sazabi
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Keys of the fields added by WithContextDiagnostics.
+const (
+	ContextDeadlineRemainingKey = "ctx_deadline_remaining_ms" // Milliseconds left before the deadline
+	ContextErrKey               = "ctx_err"                   // Reason the context is done
+)
+
+// WithContextDiagnostics makes the Ctx functions annotate entries with the state of
+// their context: the milliseconds left before its deadline, and the reason it is done
+// once it is cancelled or expired. Contexts with neither add no fields.
+func WithContextDiagnostics() Option {
+	return func(o *options) {
+		o.contextDiagnostics = true
+	}
+}
+
+// contextFieldsFunc returns the fields added by the Ctx functions for a context.
+type contextFieldsFunc func(ctx context.Context) []Field
+
+// WithContextFields makes the Ctx functions add the fields returned by fn for their
+// context, after the values stored with ContextWithFields, ContextWithCorrelationID and
+// ContextWithTenant. It lets integrations, such as the otellog module, derive fields
+// from values they store in contexts. fn returns no fields for contexts it does not
+// know; passing the option several times adds the fields of each function in order.
+func WithContextFields(fn func(ctx context.Context) []Field) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.contextFields = append(o.contextFields, fn)
+		}
+	}
+}
+
+// DebugCtx logs a debug message with key-value pairs using the logger stored in ctx.
+func DebugCtx(ctx context.Context, msg string, keysValues ...interface{}) {
+	ctxLogger(ctx).Debugw(msg, ctxFields(ctx, keysValues)...) // Log with the request-scoped logger
+}
+
+// InfoCtx logs an info message with key-value pairs using the logger stored in ctx.
+func InfoCtx(ctx context.Context, msg string, keysValues ...interface{}) {
+	ctxLogger(ctx).Infow(msg, ctxFields(ctx, keysValues)...) // Log with the request-scoped logger
+}
+
+// WarnCtx logs a warning message with key-value pairs using the logger stored in ctx.
+func WarnCtx(ctx context.Context, msg string, keysValues ...interface{}) {
+	ctxLogger(ctx).Warnw(msg, ctxFields(ctx, keysValues)...) // Log with the request-scoped logger
+}
+
+// ErrorCtx logs an error message with key-value pairs using the logger stored in ctx.
+func ErrorCtx(ctx context.Context, msg string, keysValues ...interface{}) {
+	ctxLogger(ctx).Errorw(msg, ctxFields(ctx, keysValues)...) // Log with the request-scoped logger
+}
+
+// FatalCtx logs a fatal message with key-value pairs using the logger stored in ctx.
+func FatalCtx(ctx context.Context, msg string, keysValues ...interface{}) {
+	ctxLogger(ctx).Fatalw(msg, ctxFields(ctx, keysValues)...) // Log with the request-scoped logger
+}
+
+// PanicCtx logs a panic message with key-value pairs using the logger stored in ctx.
+func PanicCtx(ctx context.Context, msg string, keysValues ...interface{}) {
+	ctxLogger(ctx).Panicw(msg, ctxFields(ctx, keysValues)...) // Log with the request-scoped logger
+}
+
+// ctxLogger returns the logger to use for ctx, skipping the frame of the Ctx function
+// so that entries report its caller.
+func ctxLogger(ctx context.Context) Logger {
+	if l, ok := contextLogger(ctx); ok {
+		if sugar, ok := l.(*zap.SugaredLogger); ok {
+			return sugar.WithOptions(zap.AddCallerSkip(1))
+		}
+		return l
+	}
+	return logger()
+}
+
+// ctxFields returns keysValues preceded by the values stored in ctx and followed by the
+// context diagnostics, when enabled.
+func ctxFields(ctx context.Context, keysValues []interface{}) []interface{} {
+	if ctx == nil {
+		return keysValues
+	}
+
+	stored := contextFields(ctx)
+	if id := CorrelationIDFromContext(ctx); id != "" {
+		stored = append(stored[:len(stored):len(stored)], CorrelationIDKey, id)
+	}
+	if tenant := TenantFromContext(ctx); tenant != "" {
+		stored = append(stored[:len(stored):len(stored)], TenantKey, tenant)
+	}
+	in := loadInstance()
+	if in != nil && in.options != nil {
+		for _, fn := range in.options.contextFields {
+			for _, f := range fn(ctx) {
+				stored = append(stored[:len(stored):len(stored)], f)
+			}
+		}
+	}
+	if len(stored) > 0 {
+		keysValues = append(stored[:len(stored):len(stored)], keysValues...)
+	}
+
+	if in == nil || !in.contextDiagnostics {
+		return keysValues
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline).Milliseconds()
+		keysValues = append(keysValues[:len(keysValues):len(keysValues)], ContextDeadlineRemainingKey, remaining)
+	}
+	if err := ctx.Err(); err != nil {
+		keysValues = append(keysValues[:len(keysValues):len(keysValues)], ContextErrKey, err.Error())
+	}
+	return keysValues
+}
@@ -0,0 +1,137 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestCtxFunctionsCaller(t *testing.T) {
+	var want string
+	output := captureStderr(t, func() {
+		sazabi.Initialize("development")
+		ctx := sazabi.NewContext(context.Background(), sazabi.Named("handler"))
+		want = callerLine()
+		sazabi.InfoCtx(ctx, "ctx stored logger")
+	})
+	assertCaller(t, output, "ctx stored logger", want)
+
+	output = captureStderr(t, func() {
+		sazabi.Initialize("development")
+		want = callerLine()
+		sazabi.InfoCtx(context.Background(), "ctx global logger")
+	})
+	assertCaller(t, output, "ctx global logger", want)
+}
+
+func TestCtxFunctionsStoredFields(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithJSONEncoding())
+
+	ctx := sazabi.NewContext(context.Background(), sazabi.With("request_id", "r-7", "user_id", 42))
+	want := callerLine()
+	sazabi.WarnCtx(ctx, "ctx warning", "attempt", 2)
+
+	output := read()
+	fields := entryFields(t, output, "ctx warning")
+	if fields["request_id"] != "r-7" || fields["user_id"] != float64(42) || fields["attempt"] != float64(2) {
+		t.Errorf("fields = %v, want the preset fields of the stored logger and the ad-hoc fields", fields)
+	}
+	if fields["level"] != "WARN" {
+		t.Errorf("level = %v, want WARN", fields["level"])
+	}
+	if caller, _ := fields["caller"].(string); !strings.HasSuffix(caller, "/"+want) {
+		t.Errorf("caller = %v, want %s", fields["caller"], want)
+	}
+}
+
+// attemptKey is the context key of the attempt number in TestWithContextFields.
+type attemptKey struct{}
+
+func TestWithContextFields(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithJSONEncoding(),
+		sazabi.WithContextFields(func(ctx context.Context) []sazabi.Field {
+			if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+				return []sazabi.Field{sazabi.F("attempt", attempt)}
+			}
+			return nil
+		}),
+		sazabi.WithContextFields(nil))
+
+	ctx := sazabi.ContextWithTenant(context.WithValue(context.Background(), attemptKey{}, 3), "acme")
+	sazabi.InfoCtx(ctx, "with context fields", "key", "value")
+	sazabi.InfoCtx(context.Background(), "without context fields")
+	sazabi.Infow("package function", "key", "value")
+
+	output := read()
+	if fields := entryFields(t, output, "with context fields"); fields["attempt"] != float64(3) || fields["tenant"] != "acme" || fields["key"] != "value" {
+		t.Errorf("fields = %v, want the context fields with the stored and ad-hoc ones", fields)
+	}
+	for _, msg := range []string{"without context fields", "package function"} {
+		if fields := entryFields(t, output, msg); fields["attempt"] != nil {
+			t.Errorf("entry %q fields = %v, want no context fields", msg, fields)
+		}
+	}
+}
+
+func TestContextDiagnostics(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithContextDiagnostics())
+	capture, stop := sazabi.StartCapture()
+
+	deadline, cancelDeadline := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelDeadline()
+	sazabi.InfoCtx(deadline, "with deadline")
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	sazabi.WarnCtx(cancelled, "cancelled")
+
+	sazabi.ErrorCtx(context.Background(), "plain", "key", "value")
+	stop()
+
+	entries := capture.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("captured %d entries, want 3", len(entries))
+	}
+
+	remaining, ok := entries[0].Int(sazabi.ContextDeadlineRemainingKey)
+	if !ok || remaining <= 0 || remaining > time.Hour.Milliseconds() {
+		t.Errorf("%s = %d, %t, want at most an hour", sazabi.ContextDeadlineRemainingKey, remaining, ok)
+	}
+	if _, ok := entries[0].Field(sazabi.ContextErrKey); ok {
+		t.Errorf("entry with a live context has %s", sazabi.ContextErrKey)
+	}
+
+	if got, _ := entries[1].Str(sazabi.ContextErrKey); got != context.Canceled.Error() {
+		t.Errorf("%s = %q, want %q", sazabi.ContextErrKey, got, context.Canceled.Error())
+	}
+	if _, ok := entries[1].Field(sazabi.ContextDeadlineRemainingKey); ok {
+		t.Errorf("entry without deadline has %s", sazabi.ContextDeadlineRemainingKey)
+	}
+
+	if len(entries[2].Fields) != 1 {
+		t.Errorf("plain context entry fields = %v, want only key", entries[2].Fields)
+	}
+}
+
+func TestContextDiagnosticsDisabled(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	capture, stop := sazabi.StartCapture()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	cancel()
+	sazabi.InfoCtx(ctx, "no diagnostics")
+	stop()
+
+	entries := capture.Entries()
+	if len(entries) != 1 || len(entries[0].Fields) != 0 {
+		t.Errorf("captured %v, want one entry without fields", entries)
+	}
+}
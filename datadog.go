@@ -0,0 +1,171 @@
+package sazabi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// datadogEncoding is the name of the Datadog JSON encoder in encoders.
+const datadogEncoding = "datadog"
+
+// Standard attributes of Datadog written by WithDatadogFormat in place of the error
+// fields.
+const (
+	DatadogErrorKindKey    = "error.kind"
+	DatadogErrorMessageKey = "error.message"
+	DatadogErrorStackKey   = "error.stack"
+	DatadogTraceIDKey      = "dd.trace_id"
+	DatadogSpanIDKey       = "dd.span_id"
+)
+
+// DatadogOption configures WithDatadogFormat.
+type DatadogOption func(*options)
+
+// DatadogTraceExtractor makes the Ctx functions add the IDs of the Datadog span in their
+// context, returned by extract, in decimal under DatadogTraceIDKey and DatadogSpanIDKey,
+// so that Datadog links entries to their trace. With dd-trace-go:
+//
+//	sazabi.DatadogTraceExtractor(func(ctx context.Context) (uint64, uint64, bool) {
+//		span, ok := tracer.SpanFromContext(ctx)
+//		if !ok {
+//			return 0, 0, false
+//		}
+//		return span.Context().TraceID(), span.Context().SpanID(), true
+//	})
+func DatadogTraceExtractor(extract func(ctx context.Context) (traceID, spanID uint64, ok bool)) DatadogOption {
+	return func(o *options) {
+		o.contextFields = append(o.contextFields, func(ctx context.Context) []Field {
+			traceID, spanID, ok := extract(ctx)
+			if !ok {
+				return nil
+			}
+			return []Field{
+				F(DatadogTraceIDKey, strconv.FormatUint(traceID, 10)),
+				F(DatadogSpanIDKey, strconv.FormatUint(spanID, 10)),
+			}
+		})
+	}
+}
+
+// WithDatadogFormat writes entries as JSON with the standard attributes of Datadog, in
+// every environment: the level under "status" (debug, info, warn, error, and critical
+// above), the message under "message", the logger name under "logger.name", the time in
+// RFC 3339 under "timestamp" and the stacktrace under DatadogErrorStackKey. The error
+// fields of LogAndWrap, WarnErr, WithError and ErrorErr, and the errors added with
+// zap.Error, are written as the error attribute group: DatadogErrorMessageKey,
+// DatadogErrorKindKey, the type of the error, and DatadogErrorStackKey, its %+v
+// formatting when it differs from the message.
+func WithDatadogFormat(opts ...DatadogOption) Option {
+	return func(o *options) {
+		o.datadogFormat = true
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// applyDatadogFormat switches conf to the Datadog encoder when the option is set.
+func applyDatadogFormat(conf *zap.Config, o *options) {
+	if !o.datadogFormat {
+		return
+	}
+	conf.Encoding = datadogEncoding
+	conf.EncoderConfig = datadogEncoderConfig(conf.EncoderConfig)
+}
+
+// datadogEncoderConfig returns conf with the keys and encoders of Datadog.
+func datadogEncoderConfig(conf zapcore.EncoderConfig) zapcore.EncoderConfig {
+	conf.TimeKey = "timestamp"
+	conf.LevelKey = "status"
+	conf.MessageKey = "message"
+	conf.NameKey = "logger.name"
+	conf.StacktraceKey = DatadogErrorStackKey
+	conf.EncodeLevel = datadogLevelEncoder
+	conf.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	conf.EncodeDuration = zapcore.SecondsDurationEncoder
+	conf.EncodeName = zapcore.FullNameEncoder
+	return conf
+}
+
+// datadogLevelEncoder writes the Datadog status of a level.
+func datadogLevelEncoder(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	if level > zapcore.ErrorLevel {
+		enc.AppendString("critical")
+		return
+	}
+	enc.AppendString(level.String())
+}
+
+// newDatadogEncoder returns the Datadog encoder for conf.
+func newDatadogEncoder(conf zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return &datadogEncoder{Encoder: zapcore.NewJSONEncoder(datadogEncoderConfig(conf))}, nil
+}
+
+// datadogEncoder is a JSON encoder writing error fields as the error attribute group of
+// Datadog.
+type datadogEncoder struct {
+	zapcore.Encoder
+}
+
+// Clone implements zapcore.Encoder.
+func (e *datadogEncoder) Clone() zapcore.Encoder {
+	return &datadogEncoder{Encoder: e.Encoder.Clone()}
+}
+
+// AddString implements zapcore.ObjectEncoder, renaming the error fields added by With.
+// Errors added by With are encoded by zap as strings under their key, with their %+v
+// formatting under the key followed by "Verbose".
+func (e *datadogEncoder) AddString(key, value string) {
+	switch key {
+	case ErrorKey:
+		key = DatadogErrorMessageKey
+	case ErrorTypeKey:
+		key = DatadogErrorKindKey
+	case ErrorVerboseKey, ErrorKey + "Verbose":
+		key = DatadogErrorStackKey
+	}
+	e.Encoder.AddString(key, value)
+}
+
+// EncodeEntry implements zapcore.Encoder.
+func (e *datadogEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	mapped := make([]zapcore.Field, 0, len(fields)+2)
+	for _, f := range fields {
+		mapped = append(mapped, datadogFields(f)...)
+	}
+	return e.Encoder.EncodeEntry(ent, mapped)
+}
+
+// datadogFields returns the fields f is written as: the error attribute group for
+// errors and the error fields of sazabi, else f itself.
+func datadogFields(f zapcore.Field) []zapcore.Field {
+	switch {
+	case f.Key == ErrorKey && f.Type == zapcore.ErrorType:
+		err, ok := f.Interface.(error)
+		if !ok || err == nil {
+			return []zapcore.Field{f}
+		}
+		fields := []zapcore.Field{
+			zap.String(DatadogErrorMessageKey, err.Error()),
+			zap.String(DatadogErrorKindKey, fmt.Sprintf("%T", err)),
+		}
+		if verbose := fmt.Sprintf("%+v", err); verbose != err.Error() {
+			fields = append(fields, zap.String(DatadogErrorStackKey, verbose))
+		}
+		return fields
+	case f.Type != zapcore.StringType:
+		return []zapcore.Field{f}
+	case f.Key == ErrorKey:
+		f.Key = DatadogErrorMessageKey
+	case f.Key == ErrorTypeKey:
+		f.Key = DatadogErrorKindKey
+	case f.Key == ErrorVerboseKey:
+		f.Key = DatadogErrorStackKey
+	}
+	return []zapcore.Field{f}
+}
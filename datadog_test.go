@@ -0,0 +1,84 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// ddSpanKey is the context key of the fake Datadog span IDs of TestWithDatadogFormat.
+type ddSpanKey struct{}
+
+func TestWithDatadogFormat(t *testing.T) {
+	restoreDefault(t)
+	extract := func(ctx context.Context) (uint64, uint64, bool) {
+		ids, ok := ctx.Value(ddSpanKey{}).([2]uint64)
+		return ids[0], ids[1], ok
+	}
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithDatadogFormat(sazabi.DatadogTraceExtractor(extract)))
+	sazabi.Named("billing").Infow("charged", "amount", 42)
+	sazabi.ErrorErr(&quotaError{limit: 3}, "quota error")
+	sazabi.LogAndWrap(errors.New("card declined"), "declined")
+	sazabi.WithError(errors.New("timeout")).Warn("retrying")
+	sazabi.InfoCtx(context.WithValue(context.Background(), ddSpanKey{}, [2]uint64{1234567890123456789, 42}), "traced")
+	sazabi.InfoCtx(context.Background(), "untraced")
+
+	entries := make(map[string]map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(read()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q is not JSON: %v", line, err)
+		}
+		msg, _ := entry["message"].(string)
+		entries[msg] = entry
+	}
+
+	charged := entries["charged"]
+	for _, key := range []string{"status", "message", "timestamp", "logger.name"} {
+		if _, ok := charged[key]; !ok {
+			t.Errorf("entry = %v, want the %s key", charged, key)
+		}
+	}
+	if charged["status"] != "info" || charged["logger.name"] != "billing" {
+		t.Errorf("entry = %v, want status info and logger.name billing", charged)
+	}
+	for _, key := range []string{"level", "msg", "ts", sazabi.NameKey} {
+		if _, ok := charged[key]; ok {
+			t.Errorf("entry = %v, want no %s key", charged, key)
+		}
+	}
+
+	tests := []struct {
+		msg, status, message, kind, stack string
+	}{
+		{"quota error", "error", "quota of 3 exceeded", "*sazabi_test.quotaError", "quota of 3 exceeded (limit set by plan)"},
+		{"declined", "error", "card declined", "*errors.errorString", ""},
+		{"retrying", "warn", "timeout", "*errors.errorString", "timeout"},
+	}
+	for _, tt := range tests {
+		entry := entries[tt.msg]
+		stack, _ := entry[sazabi.DatadogErrorStackKey].(string)
+		if entry["status"] != tt.status || entry[sazabi.DatadogErrorMessageKey] != tt.message || entry[sazabi.DatadogErrorKindKey] != tt.kind || stack != tt.stack {
+			t.Errorf("entry %q = %v, want status %s and error.message %q, error.kind %q, error.stack %q", tt.msg, entry, tt.status, tt.message, tt.kind, tt.stack)
+		}
+		for _, key := range []string{sazabi.ErrorKey, sazabi.ErrorTypeKey, sazabi.ErrorVerboseKey} {
+			if _, ok := entry[key]; ok {
+				t.Errorf("entry %q = %v, want no %s key", tt.msg, entry, key)
+			}
+		}
+	}
+
+	if traced := entries["traced"]; traced[sazabi.DatadogTraceIDKey] != "1234567890123456789" || traced[sazabi.DatadogSpanIDKey] != "42" {
+		t.Errorf("entry = %v, want the dd trace and span IDs", traced)
+	}
+	if _, ok := entries["untraced"][sazabi.DatadogTraceIDKey]; ok {
+		t.Errorf("entry = %v, want no trace ID without span", entries["untraced"])
+	}
+}
@@ -0,0 +1,69 @@
+package sazabi
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// DeprecatedMessage is the message of the warnings written by Deprecated.
+const DeprecatedMessage = "deprecated feature used"
+
+// maxDeprecationSites bounds the number of call sites remembered by Deprecated. Once it
+// is reached, call sites not seen before are no longer warned about.
+const maxDeprecationSites = 1024
+
+// deprecationSites holds the call sites of Deprecated that already warned.
+var deprecationSites = struct {
+	sync.Mutex
+	seen map[string]struct{}
+}{seen: make(map[string]struct{})}
+
+// SuppressDeprecations turns Deprecated into a no-op for the global logger, typically
+// in production where the warnings are only noise for operators.
+func SuppressDeprecations() Option {
+	return func(o *options) {
+		o.suppressDeprecations = true
+	}
+}
+
+// Deprecated writes a DeprecatedMessage warning through the global logger telling that
+// feature is deprecated and will be removed in removal, with deprecated set to true and
+// the key-value pairs, which follow the conventions of Infow. It is meant for library
+// authors to call from deprecated APIs: the warning is written once per process for
+// each file:line calling Deprecated, so calling it on every use is cheap. Up to 1024
+// call sites are remembered; call sites beyond are not warned about.
+func Deprecated(feature, removal string, keysValues ...interface{}) {
+	if currentOptions().suppressDeprecations {
+		return
+	}
+	_, file, line, ok := runtime.Caller(1)
+	if !ok || !firstDeprecation(file+":"+strconv.Itoa(line)) {
+		return
+	}
+	logger().Warnw(DeprecatedMessage, append([]interface{}{
+		"deprecated", true,
+		"feature", feature,
+		"removal_version", removal,
+	}, keysValues...)...)
+}
+
+// firstDeprecation records site and reports whether it had not warned yet.
+func firstDeprecation(site string) bool {
+	deprecationSites.Lock()
+	defer deprecationSites.Unlock()
+
+	if _, ok := deprecationSites.seen[site]; ok || len(deprecationSites.seen) >= maxDeprecationSites {
+		return false
+	}
+	deprecationSites.seen[site] = struct{}{}
+	return true
+}
+
+// resetDeprecations forgets the call sites that already warned.
+func resetDeprecations() {
+	deprecationSites.Lock()
+	defer deprecationSites.Unlock()
+
+	deprecationSites.seen = make(map[string]struct{})
+}
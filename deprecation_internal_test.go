@@ -0,0 +1,47 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeprecatedOncePerCallSite(t *testing.T) {
+	resetDeprecations() // Other runs of the test warned at the same call sites
+	t.Cleanup(resetDeprecations)
+	c, stop := StartCapture()
+	defer stop()
+
+	for i := 0; i < 5; i++ {
+		Deprecated("v1 client", "v3.0.0", "replacement", "v2 client")
+		Deprecated("legacy auth", "v2.5.0")
+	}
+
+	entries := c.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want one per call site: %+v", len(entries), entries)
+	}
+	want := []struct{ feature, removal string }{{"v1 client", "v3.0.0"}, {"legacy auth", "v2.5.0"}}
+	for i, e := range entries {
+		if e.Message != DeprecatedMessage || e.Level.String() != "warn" {
+			t.Errorf("entry %d = %s %q, want a warning", i, e.Level, e.Message)
+		}
+		if f, _ := e.Field("deprecated"); f.Integer != 1 {
+			t.Errorf("entry %d deprecated = %v, want true", i, f)
+		}
+		if feature, _ := e.Str("feature"); feature != want[i].feature {
+			t.Errorf("entry %d feature = %q, want %q", i, feature, want[i].feature)
+		}
+		if removal, _ := e.Str("removal_version"); removal != want[i].removal {
+			t.Errorf("entry %d removal_version = %q, want %q", i, removal, want[i].removal)
+		}
+		if !strings.HasSuffix(e.Caller.File, "deprecation_internal_test.go") {
+			t.Errorf("entry %d caller = %s, want the call site", i, e.Caller)
+		}
+	}
+	if replacement, _ := entries[0].Str("replacement"); replacement != "v2 client" {
+		t.Errorf("replacement = %q, want the key-value pairs added", replacement)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestSuppressDeprecations(t *testing.T) {
+	restoreDefault(t)
+	captureStderr(t, func() { sazabi.Initialize(sazabi.ProductionEnvName, sazabi.SuppressDeprecations()) })
+	c, stop := sazabi.StartCapture()
+	defer stop()
+
+	sazabi.Deprecated("v1 client", "v3.0.0")
+
+	if entries := c.Entries(); len(entries) != 0 {
+		t.Errorf("got %+v, want no entries with SuppressDeprecations", entries)
+	}
+}
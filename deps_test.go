@@ -0,0 +1,78 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"go/build"
+	"os"
+	"strings"
+	"testing"
+)
+
+// coreDependencies are the only third-party modules the core module may depend on.
+// Integrations requiring anything else belong in their own module.
+var coreDependencies = []string{
+	"github.com/zeroxsolutions/barbatos",
+	"go.uber.org/multierr",
+	"go.uber.org/zap",
+}
+
+// coreInternal is the prefix of the core module's own internal packages, which the
+// core package may import.
+const coreInternal = "github.com/zeroxsolutions/sazabi/internal/"
+
+func isCoreDependency(path string) bool {
+	for _, dep := range coreDependencies {
+		if path == dep || strings.HasPrefix(path, dep+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCoreModuleRequirements(t *testing.T) {
+	data, err := os.ReadFile("go.mod")
+	if err != nil {
+		t.Fatalf("failed to read go.mod: %v", err)
+	}
+
+	inRequireBlock := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "require ("):
+			inRequireBlock = true
+			continue
+		case inRequireBlock && line == ")":
+			inRequireBlock = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimPrefix(line, "require ")
+		case !inRequireBlock:
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if !isCoreDependency(fields[0]) {
+			t.Errorf("core go.mod requires %s; move the code needing it into an integration module", fields[0])
+		}
+	}
+}
+
+func TestCorePackageImports(t *testing.T) {
+	pkg, err := build.ImportDir(".", 0)
+	if err != nil {
+		t.Fatalf("failed to load the core package: %v", err)
+	}
+
+	for _, path := range pkg.Imports {
+		isStdlib := !strings.Contains(strings.Split(path, "/")[0], ".")
+		if !isStdlib && !isCoreDependency(path) && !strings.HasPrefix(path, coreInternal) {
+			t.Errorf("core package imports %s; move the code needing it into an integration module", path)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package sazabi
+
+import (
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DisabledEnvVar is the environment variable that, when set to a true value such as
+// "1", replaces every logger built by sazabi with a no-op logger.
+const DisabledEnvVar = "SAZABI_DISABLED"
+
+// suppressedCalls counts the log calls discarded because logging is disabled.
+var suppressedCalls int64
+
+// SuppressedCallCount returns how many log calls were discarded because logging was
+// disabled through SAZABI_DISABLED, letting benchmarks verify the expected log volume.
+func SuppressedCallCount() int64 {
+	return atomic.LoadInt64(&suppressedCalls)
+}
+
+// WithFatalHook sets the action taken after a Fatal entry, in place of os.Exit(1).
+// It applies whether logging is enabled or disabled through SAZABI_DISABLED, so that
+// disabling logging never masks fatal conditions unless explicitly configured to.
+func WithFatalHook(hook zapcore.CheckWriteHook) Option {
+	return func(o *options) {
+		o.fatalHook = hook
+	}
+}
+
+// loggingDisabled reports whether SAZABI_DISABLED asks for logging to be disabled.
+func loggingDisabled() bool {
+	disabled, _ := strconv.ParseBool(os.Getenv(DisabledEnvVar))
+	return disabled
+}
+
+// newDisabledLogger returns a logger that writes nothing and counts the calls made to it.
+// Fatal and Panic keep their terminal behaviour.
+func newDisabledLogger(o *options) *zap.Logger {
+	var opts []zap.Option
+	if o.fatalHook != nil {
+		opts = append(opts, zap.WithFatalHook(o.fatalHook))
+	}
+	return zap.New(suppressCore{}, opts...)
+}
+
+// suppressCore is a zapcore.Core that drops every entry while counting the calls.
+// Calls below DPanic are counted by Enabled, which zap consults once per call;
+// higher levels bypass Enabled and are counted by Check.
+type suppressCore struct{}
+
+// Level reports that no level is enabled, without counting a call.
+func (suppressCore) Level() zapcore.Level { return zapcore.InvalidLevel }
+
+// Enabled implements zapcore.Core.
+func (suppressCore) Enabled(zapcore.Level) bool {
+	atomic.AddInt64(&suppressedCalls, 1)
+	return false
+}
+
+// With implements zapcore.Core.
+func (c suppressCore) With([]zapcore.Field) zapcore.Core { return c }
+
+// Check implements zapcore.Core.
+func (suppressCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zapcore.DPanicLevel {
+		atomic.AddInt64(&suppressedCalls, 1)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (suppressCore) Write(zapcore.Entry, []zapcore.Field) error { return nil }
+
+// Sync implements zapcore.Core.
+func (suppressCore) Sync() error { return nil }
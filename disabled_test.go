@@ -0,0 +1,71 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestDisabledByEnvironment(t *testing.T) {
+	t.Setenv(sazabi.DisabledEnvVar, "1")
+
+	var suppressed int64
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithStartupSummary())
+		before := sazabi.SuppressedCallCount()
+
+		sazabi.Debug("disabled debug")
+		sazabi.Infof("disabled %s", "info")
+		sazabi.Warnw("disabled warn", "key", "value")
+		sazabi.Error("disabled error")
+		sazabi.Errorw("disabled error", "key", "value")
+
+		suppressed = sazabi.SuppressedCallCount() - before
+	})
+
+	if output != "" {
+		t.Errorf("disabled logging should not write anything, got: %s", output)
+	}
+	if suppressed != 5 {
+		t.Errorf("SuppressedCallCount() increased by %d, want 5", suppressed)
+	}
+}
+
+func TestDisabledFatalStillTerminates(t *testing.T) {
+	t.Setenv(sazabi.DisabledEnvVar, "true")
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithFatalHook(zapcore.WriteThenGoexit))
+	before := sazabi.SuppressedCallCount()
+
+	returned := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sazabi.Fatal("disabled fatal")
+		returned = true
+	}()
+	<-done
+
+	if returned {
+		t.Error("Fatal should run the fatal hook even when logging is disabled")
+	}
+	if got := sazabi.SuppressedCallCount() - before; got != 1 {
+		t.Errorf("SuppressedCallCount() increased by %d, want 1", got)
+	}
+}
+
+func TestDisabledPanicStillPanics(t *testing.T) {
+	t.Setenv(sazabi.DisabledEnvVar, "1")
+	sazabi.Initialize(sazabi.ProductionEnvName)
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Panic should still panic when logging is disabled")
+		}
+	}()
+	sazabi.Panic("disabled panic")
+}
@@ -0,0 +1,12 @@
+// Package sazabi provides a simplified global logger built on top of Uber's zap,
+// with production and development configurations.
+//
+// The sazabi module is the dependency-free core: the logger, its options and its
+// encoders depend only on zap and the barbatos logging interface. Integrations with
+// third-party libraries (web frameworks, message brokers, error trackers, cloud SDKs)
+// live in their own Go modules under this repository, each with its own go.mod, so
+// that consumers only pull the dependencies of the integrations they import.
+// Integrations are wired to the core exclusively through its exported extension
+// points, such as Option values, sinks registered with zap.RegisterSink and
+// zapcore.WriteSyncer implementations.
+package sazabi
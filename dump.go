@@ -0,0 +1,94 @@
+package sazabi
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DumpOnSignal writes a crash dump when the process receives sig (SIGQUIT when sig is nil):
+// the configuration summary followed by the entries kept by WithRingBuffer, in a
+// timestamped file in dir. The dump is written without touching the log outputs.
+// The signal is then delivered again: when no other part of the application listens
+// for it, the Go runtime's default behaviour applies, which for SIGQUIT prints the
+// goroutine dump and exits. The handler fires once; stop cancels it.
+func DumpOnSignal(sig os.Signal, dir string) (stop func()) {
+	if sig == nil {
+		sig = syscall.SIGQUIT
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sig)
+
+	go func() {
+		select {
+		case <-ch:
+		case <-done:
+			return
+		}
+
+		writeDump(dir, sig)
+		signal.Stop(ch) // Restore the default behaviour unless others listen for sig
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			p.Signal(sig)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// writeDump writes the configuration summary and the ring buffer to a new file in dir.
+// Failures are reported on stderr since the logger itself may be unusable.
+func writeDump(dir string, sig os.Signal) {
+	now := time.Now().UTC()
+	path := filepath.Join(dir, fmt.Sprintf("sazabi-dump-%s.log", now.Format("20060102T150405.000000000Z")))
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sazabi: failed to create dump file: %v\n", err)
+		return
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	header := map[string]interface{}{
+		"dump_time": now.Format(time.RFC3339Nano),
+		"signal":    sig.String(),
+		"pid":       currentOptions().pid(),
+		"hostname":  currentOptions().hostname(),
+	}
+	if summary, ok := lastSummary.Load().([]interface{}); ok {
+		config := make(map[string]interface{}, len(summary)/2)
+		for i := 0; i+1 < len(summary); i += 2 {
+			config[fmt.Sprint(summary[i])] = summary[i+1]
+		}
+		header["config"] = config
+	}
+	if data, err := json.Marshal(header); err == nil {
+		w.Write(data)
+		w.WriteByte('\n')
+	}
+
+	if r := currentRing(); r != nil {
+		for _, entry := range r.snapshot() {
+			w.Write(entry)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "sazabi: failed to write dump file: %v\n", err)
+	}
+}
@@ -0,0 +1,120 @@
+//go:build test && !windows
+// +build test,!windows
+
+package sazabi_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// waitSignal fails the test when ch does not receive a signal within a few seconds.
+func waitSignal(t *testing.T, ch <-chan os.Signal) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("signal not received")
+	}
+}
+
+func TestDumpOnSignal(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "app.log")
+
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths(out), sazabi.WithRingBuffer(3),
+		sazabi.WithHostnameProvider(func() string { return "fake-host" }),
+		sazabi.WithPIDProvider(func() int { return 4242 }))
+	for _, msg := range []string{"one", "two", "three", "four"} {
+		sazabi.Info(msg)
+	}
+
+	// Listening for the signal here keeps the runtime from killing the test binary.
+	listener := make(chan os.Signal, 2)
+	signal.Notify(listener, syscall.SIGUSR1)
+	defer signal.Stop(listener)
+
+	stop := sazabi.DumpOnSignal(syscall.SIGUSR1, dir)
+	defer stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	waitSignal(t, listener) // Original signal
+	waitSignal(t, listener) // Signal delivered again after the dump
+
+	matches, err := filepath.Glob(filepath.Join(dir, "sazabi-dump-*.log"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("dump files = %v (%v), want one", matches, err)
+	}
+	f, err := os.Open(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 4 {
+		t.Fatalf("dump has %d lines, want header and 3 entries:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+
+	var header struct {
+		Signal   string                 `json:"signal"`
+		PID      int                    `json:"pid"`
+		Hostname string                 `json:"hostname"`
+		Config   map[string]interface{} `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("header is not JSON: %v", err)
+	}
+	if header.Signal != syscall.SIGUSR1.String() {
+		t.Errorf("signal = %q, want %q", header.Signal, syscall.SIGUSR1.String())
+	}
+	if header.PID != 4242 || header.Hostname != "fake-host" {
+		t.Errorf("header pid and hostname = %d, %q, want the injected 4242, %q", header.PID, header.Hostname, "fake-host")
+	}
+	if header.Config["environment"] != sazabi.ProductionEnvName {
+		t.Errorf("config environment = %v, want %q", header.Config["environment"], sazabi.ProductionEnvName)
+	}
+	for i, msg := range []string{"two", "three", "four"} {
+		if !strings.Contains(lines[i+1], "\t"+msg) {
+			t.Errorf("entry %d = %q, want message %q", i, lines[i+1], msg)
+		}
+	}
+}
+
+func TestDumpOnSignalStop(t *testing.T) {
+	dir := t.TempDir()
+
+	listener := make(chan os.Signal, 1)
+	signal.Notify(listener, syscall.SIGUSR2)
+	defer signal.Stop(listener)
+
+	stop := sazabi.DumpOnSignal(syscall.SIGUSR2, dir)
+	stop()
+	stop() // Stopping twice is harmless
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	waitSignal(t, listener)
+	time.Sleep(50 * time.Millisecond)
+
+	if matches, _ := filepath.Glob(filepath.Join(dir, "sazabi-dump-*.log")); len(matches) != 0 {
+		t.Errorf("dump files = %v, want none after stop", matches)
+	}
+}
@@ -0,0 +1,77 @@
+package sazabi
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// dynamicFields holds the []dynamicField registered with RegisterDynamicField. It is
+// rebuilt from the extension registry on every change.
+var dynamicFields atomic.Value
+
+// dynamicField is a field whose value is computed for each entry.
+type dynamicField struct {
+	key   string
+	value func() interface{}
+}
+
+// RegisterDynamicField adds a field named key to every entry, whose value is returned by
+// fn when the entry is written, such as the current deploy color or feature-flag
+// revision. fn is only called for entries that pass the level check and sampling, so it
+// must be fast but is never called for suppressed entries. A panic in fn is recovered
+// and logged as the value. Like redaction, dynamic fields apply to every logger built
+// by this package.
+func RegisterDynamicField(key string, fn func() interface{}) *Extension {
+	return register(&extension{dynamic: &dynamicField{key: key, value: fn}})
+}
+
+// dynamicCore appends the registered dynamic fields to the entries it writes.
+type dynamicCore struct {
+	zapcore.Core
+}
+
+// newDynamicCore wraps core so that entries carry the dynamic fields.
+func newDynamicCore(core zapcore.Core) zapcore.Core {
+	return &dynamicCore{Core: core}
+}
+
+// With implements zapcore.Core.
+func (c *dynamicCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dynamicCore{Core: c.Core.With(fields)}
+}
+
+// Check implements zapcore.Core.
+func (c *dynamicCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *dynamicCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	dynamic, _ := dynamicFields.Load().([]dynamicField)
+	if len(dynamic) == 0 {
+		return c.Core.Write(ent, fields)
+	}
+
+	extended := make([]zapcore.Field, len(fields), len(fields)+len(dynamic))
+	copy(extended, fields)
+	for _, d := range dynamic {
+		extended = append(extended, d.field())
+	}
+	return c.Core.Write(ent, extended)
+}
+
+// field evaluates the dynamic field, recovering from panics.
+func (d dynamicField) field() (f zapcore.Field) {
+	defer func() {
+		if r := recover(); r != nil {
+			f = zap.String(d.key, fmt.Sprintf("<PANIC=%v>", r))
+		}
+	}()
+	return zap.Any(d.key, d.value())
+}
@@ -0,0 +1,72 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestRegisterDynamicField(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	capture, stop := sazabi.StartCapture()
+
+	var revision int64
+	ext := sazabi.RegisterDynamicField("flag_revision", func() interface{} {
+		return atomic.AddInt64(&revision, 1)
+	})
+	defer ext.Remove()
+	sazabi.Info("first")
+	sazabi.Info("second")
+	ext.Remove()
+	sazabi.Info("after removal")
+	stop()
+
+	entries := capture.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("captured %d entries, want 3", len(entries))
+	}
+	first, _ := entries[0].Int("flag_revision")
+	second, _ := entries[1].Int("flag_revision")
+	if first != 1 || second != 2 {
+		t.Errorf("flag_revision = %d, %d, want 1, 2", first, second)
+	}
+	if _, ok := entries[2].Field("flag_revision"); ok {
+		t.Error("removed dynamic field should not be added")
+	}
+}
+
+func TestDynamicFieldSkippedBelowLevel(t *testing.T) {
+	var calls int64
+	ext := sazabi.RegisterDynamicField("color", func() interface{} {
+		atomic.AddInt64(&calls, 1)
+		return "blue"
+	})
+	defer ext.Remove()
+
+	log, output := newFileLogger(t, sazabi.ProductionEnvName)
+	log.Debug("suppressed by level")
+	log.Info("written")
+
+	if got := entryFields(t, output(), "written")["color"]; got != "blue" {
+		t.Errorf("color = %v, want blue", got)
+	}
+	if got := atomic.LoadInt64(&calls); got != 1 {
+		t.Errorf("provider called %d times, want once: never for entries suppressed by level", got)
+	}
+}
+
+func TestDynamicFieldPanic(t *testing.T) {
+	ext := sazabi.RegisterDynamicField("color", func() interface{} { panic("no deploy state") })
+	defer ext.Remove()
+
+	log, output := newFileLogger(t, sazabi.ProductionEnvName)
+	log.Info("still written")
+
+	if got := entryFields(t, output(), "still written")["color"]; got != "<PANIC=no deploy state>" {
+		t.Errorf("color = %v, want the recovered panic", got)
+	}
+}
@@ -0,0 +1,275 @@
+package sazabi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ecsEncoding is the name of the Elastic Common Schema encoder in encoders.
+const ecsEncoding = "ecs"
+
+// DefaultECSVersion is the ecs.version written when WithECSFormat is given none.
+const DefaultECSVersion = "8.11.0"
+
+// ECSReservedPrefix is added to the keys of fields colliding with the fields written by
+// WithECSFormat.
+const ECSReservedPrefix = "fields."
+
+// ecsReserved lists the fields written by the ECS encoder itself. The error fields of
+// sazabi are mapped to the error fields.
+var ecsReserved = []string{
+	"@timestamp",
+	"message",
+	"ecs.version",
+	"log.level",
+	"log.logger",
+	"log.origin.file.name",
+	"log.origin.file.line",
+	"log.origin.function",
+	"error.message",
+	"error.type",
+	"error.stack_trace",
+}
+
+// ecsBufferPool provides the buffers of the ECS encoder.
+var ecsBufferPool = buffer.NewPool()
+
+// WithECSFormat writes entries as JSON following the Elastic Common Schema version (such
+// as "8.11.0", DefaultECSVersion when empty), in every environment: "@timestamp",
+// "log.level", "message", "ecs.version", "log.logger", the caller under "log.origin" and
+// the stacktrace under "error.stack_trace". Keys with dots are written as nested objects,
+// so "http.request.method" is the "method" field of the "request" object of "http". The
+// error fields of LogAndWrap, WarnErr, WithError and ErrorErr, and the errors added with
+// zap.Error, are written as "error.message", "error.type" and "error.stack_trace".
+//
+// Other fields are written as they are, except those colliding with the fields above,
+// such as "message" or "log.level.name", which are prefixed with ECSReservedPrefix. When
+// a field is also the parent of another, such as "a" and "a.b", the child wins.
+func WithECSFormat(version string) Option {
+	return func(o *options) {
+		o.ecsVersion = version
+		if o.ecsVersion == "" {
+			o.ecsVersion = DefaultECSVersion
+		}
+	}
+}
+
+// applyECSFormat switches conf to the ECS encoder when the option is set.
+func applyECSFormat(conf *zap.Config, o *options) {
+	if o.ecsVersion == "" {
+		return
+	}
+	conf.Encoding = ecsEncoding
+	conf.EncoderConfig = ecsEncoderConfig(conf.EncoderConfig)
+}
+
+// ecsEncoderConfig returns conf with the keys of ECS. The ECS encoder writes the entry
+// itself; the keys let the other stages know where it is.
+func ecsEncoderConfig(conf zapcore.EncoderConfig) zapcore.EncoderConfig {
+	conf.TimeKey = "@timestamp"
+	conf.LevelKey = "log.level"
+	conf.MessageKey = "message"
+	conf.NameKey = "log.logger"
+	conf.CallerKey = "log.origin"
+	conf.StacktraceKey = "error.stack_trace"
+	conf.EncodeLevel = zapcore.LowercaseLevelEncoder
+	conf.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	conf.EncodeDuration = zapcore.SecondsDurationEncoder
+	return conf
+}
+
+// newECSEncoder returns the ECS encoder for conf, writing DefaultECSVersion.
+func newECSEncoder(conf zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return &ecsEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), conf: ecsEncoderConfig(conf), version: DefaultECSVersion}, nil
+}
+
+// ecsEncoder collects the fields of entries, then writes them as nested JSON objects.
+type ecsEncoder struct {
+	*zapcore.MapObjectEncoder // Fields added by With
+	conf                      zapcore.EncoderConfig
+	version                   string // ecs.version, set by build
+}
+
+// Clone implements zapcore.Encoder.
+func (e *ecsEncoder) Clone() zapcore.Encoder {
+	clone := zapcore.NewMapObjectEncoder()
+	for key, value := range e.Fields {
+		clone.Fields[key] = copyFieldValue(value)
+	}
+	return &ecsEncoder{MapObjectEncoder: clone, conf: e.conf, version: e.version}
+}
+
+// copyFieldValue returns v, a value added to a zapcore.MapObjectEncoder, with its maps
+// copied, so that the fields added to a copy through namespaces do not change the original.
+func copyFieldValue(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	copied := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		copied[key] = copyFieldValue(value)
+	}
+	return copied
+}
+
+// AddString implements zapcore.ObjectEncoder, mapping the error fields added by With.
+// Errors added by With are encoded by zap as strings under their key, with their %+v
+// formatting under the key followed by "Verbose".
+func (e *ecsEncoder) AddString(key, value string) {
+	switch key {
+	case ErrorKey:
+		key = "error.message"
+	case ErrorTypeKey:
+		key = "error.type"
+	case ErrorVerboseKey, ErrorKey + "Verbose":
+		key = "error.stack_trace"
+	}
+	e.MapObjectEncoder.AddString(key, value)
+}
+
+// EncodeEntry implements zapcore.Encoder.
+func (e *ecsEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := e.Clone().(*ecsEncoder)
+	for _, f := range fields {
+		if f.Key == ErrorKey && f.Type == zapcore.ErrorType {
+			if err, ok := f.Interface.(error); ok && err != nil {
+				final.AddString("error.message", err.Error())
+				final.AddString("error.type", fmt.Sprintf("%T", err))
+				if verbose := fmt.Sprintf("%+v", err); verbose != err.Error() {
+					final.AddString("error.stack_trace", verbose)
+				}
+				continue
+			}
+		}
+		f.AddTo(final)
+	}
+
+	keys := make([]string, 0, len(final.Fields))
+	for key := range final.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // Parents before children, which replace them
+	doc := make(map[string]interface{})
+	for _, key := range keys {
+		value := ecsValue(final.Fields[key])
+		if ecsCollides(key) {
+			key = ECSReservedPrefix + key
+		}
+		ecsSet(doc, key, value)
+	}
+	ecsSet(doc, "@timestamp", ent.Time.Format(time.RFC3339Nano))
+	ecsSet(doc, "log.level", ent.Level.String())
+	ecsSet(doc, "message", ent.Message)
+	ecsSet(doc, "ecs.version", e.version)
+	if ent.LoggerName != "" {
+		ecsSet(doc, "log.logger", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		ecsSet(doc, "log.origin.file.name", ent.Caller.File)
+		ecsSet(doc, "log.origin.file.line", ent.Caller.Line)
+		if ent.Caller.Function != "" {
+			ecsSet(doc, "log.origin.function", ent.Caller.Function)
+		}
+	}
+	if ent.Stack != "" {
+		ecsSet(doc, "error.stack_trace", ent.Stack)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	buf := ecsBufferPool.Get()
+	buf.Write(data)
+	if e.conf.LineEnding != "" {
+		buf.AppendString(e.conf.LineEnding)
+	} else {
+		buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	return buf, nil
+}
+
+// ecsCollides reports whether a field key collides with the fields written by the ECS
+// encoder: it is one of them, or a parent or a child of one of them. The error fields
+// mapped by the encoder are not collisions.
+func ecsCollides(key string) bool {
+	switch key {
+	case "error.message", "error.type", "error.stack_trace":
+		return false
+	}
+	for _, reserved := range ecsReserved {
+		if key == reserved || strings.HasPrefix(reserved, key+".") || strings.HasPrefix(key, reserved+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// ecsSet sets the value of the dotted key in doc, creating the objects on its path.
+// Values on the path are replaced by objects.
+func ecsSet(doc map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := doc[part].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			doc[part] = child
+		}
+		doc = child
+	}
+	last := parts[len(parts)-1]
+	if nested, ok := value.(map[string]interface{}); ok {
+		if existing, ok := doc[last].(map[string]interface{}); ok {
+			for k, v := range nested {
+				ecsSet(existing, k, v)
+			}
+			return
+		}
+	}
+	doc[last] = value
+}
+
+// ecsValue returns v, a value added to a zapcore.MapObjectEncoder, in the form written
+// by the JSON encoder: durations in seconds, and complex numbers, NaN and infinities as
+// their fmt.Sprint text. Keys with dots in objects are
+// nested like field keys.
+func ecsValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Duration:
+		return v.Seconds()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case complex64, complex128:
+		return fmt.Sprint(v)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Sprint(v)
+		}
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return fmt.Sprint(v)
+		}
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, e := range v {
+			values[i] = ecsValue(e)
+		}
+		return values
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			ecsSet(m, key, ecsValue(value))
+		}
+		return m
+	}
+	return v
+}
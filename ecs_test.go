@@ -0,0 +1,134 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// ecsEntries returns the JSON entries of output by message.
+func ecsEntries(t *testing.T, output string) map[string]map[string]interface{} {
+	t.Helper()
+
+	entries := make(map[string]map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q is not JSON: %v", line, err)
+		}
+		msg, _ := entry["message"].(string)
+		entries[msg] = entry
+	}
+	return entries
+}
+
+// ecsPath returns the value at the dotted path of entry, descending into objects.
+func ecsPath(entry map[string]interface{}, path string) interface{} {
+	var v interface{} = entry
+	for _, part := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+func TestWithECSFormat(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithECSFormat("8.11.0"))
+	sazabi.Named("billing").Info("named")
+	sazabi.With("service.name", "billing-api").Infow("charged",
+		"http.request.method", "POST",
+		"http.response.status_code", 201,
+		"labels.env", "prod")
+	sazabi.ErrorErr(&quotaError{limit: 3}, "quota error")
+	sazabi.LogAndWrap(errors.New("card declined"), "declined")
+	entries := ecsEntries(t, read())
+
+	charged := entries["charged"]
+	for path, want := range map[string]interface{}{
+		"ecs.version":               "8.11.0",
+		"log.level":                 "info",
+		"service.name":              "billing-api",
+		"http.request.method":       "POST",
+		"http.response.status_code": 201.0,
+		"labels.env":                "prod",
+	} {
+		if got := ecsPath(charged, path); got != want {
+			t.Errorf("%s = %v, want %v", path, got, want)
+		}
+	}
+	if got := ecsPath(entries["named"], "log.logger"); got != "billing" {
+		t.Errorf("log.logger = %v, want billing", got)
+	}
+	if _, ok := charged["@timestamp"]; !ok {
+		t.Errorf("entry = %v, want @timestamp", charged)
+	}
+	if file, _ := ecsPath(charged, "log.origin.file.name").(string); !strings.HasSuffix(file, "ecs_test.go") || ecsPath(charged, "log.origin.file.line") == nil {
+		t.Errorf("log.origin = %v, want the file and line of the call", charged["log"])
+	}
+	for key := range charged {
+		if strings.Contains(key, ".") {
+			t.Errorf("entry has the dotted key %q, want nested objects", key)
+		}
+	}
+
+	tests := []struct {
+		msg, message, typ, stack string
+	}{
+		{"quota error", "quota of 3 exceeded", "*sazabi_test.quotaError", "quota of 3 exceeded (limit set by plan)"},
+		{"declined", "card declined", "*errors.errorString", ""},
+	}
+	for _, tt := range tests {
+		got := ecsPath(entries[tt.msg], "error")
+		want := map[string]interface{}{"message": tt.message, "type": tt.typ}
+		if tt.stack != "" {
+			want["stack_trace"] = tt.stack
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("error of %q = %v, want %v", tt.msg, got, want)
+		}
+	}
+}
+
+func TestWithECSFormatReservedKeys(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithECSFormat(""))
+	sazabi.Infow("reserved",
+		"message", "user message",
+		"log.level", "user level",
+		"log.level.name", "user level name",
+		"ecs", "user ecs",
+		"log.custom", "kept",
+		"a", 1,
+		"a.b", 2)
+	entry := ecsEntries(t, read())["reserved"]
+
+	for path, want := range map[string]interface{}{
+		"message":               "reserved",
+		"log.level":             "info",
+		"ecs.version":           sazabi.DefaultECSVersion,
+		"fields.message":        "user message",
+		"fields.log.level.name": "user level name",
+		"fields.ecs":            "user ecs",
+		"log.custom":            "kept",
+		"a.b":                   2.0,
+	} {
+		if got := ecsPath(entry, path); got != want {
+			t.Errorf("%s = %v, want %v", path, got, want)
+		}
+	}
+	// "log.level" and "log.level.name" are parent and child: the child wins.
+	if _, ok := ecsPath(entry, "fields.log.level").(map[string]interface{}); !ok {
+		t.Errorf("fields.log.level = %v, want the object of log.level.name", ecsPath(entry, "fields.log.level"))
+	}
+}
@@ -0,0 +1,99 @@
+package sazabi
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// ConfigSnapshot describes the configuration the global logger is running with,
+// including the changes made at runtime. It shares no memory with the logger, so it
+// can be kept and modified freely. Paths are redacted as in the startup summary and
+// the values of global fields are omitted.
+type ConfigSnapshot struct {
+	Environment      string                       `json:"environment"`
+	Level            string                       `json:"level"`
+	ModuleLevels     map[string]string            `json:"module_levels"`
+	Encoding         string                       `json:"encoding"`
+	OutputPaths      []string                     `json:"output_paths"`
+	ErrorOutputPaths []string                     `json:"error_output_paths"`
+	Sampling         *SamplingSnapshot            `json:"sampling"` // Nil when sampling is disabled
+	CallerEnabled    bool                         `json:"caller_enabled"`
+	StacktraceLevel  string                       `json:"stacktrace_level"` // Empty when stacktraces are disabled
+	GlobalFieldKeys  []string                     `json:"global_field_keys"`
+	VolumeBudget     *VolumeBudgetSnapshot        `json:"volume_budget"` // Nil without WithVolumeBudget
+	Integrations     map[string]map[string]string `json:"integrations"`
+	Pipeline         []string                     `json:"pipeline"` // Stages and added cores ("core:name"), in order
+}
+
+// SamplingSnapshot describes the sampling of the global logger: per second, the first
+// Initial entries with the same level and message are written, then every Thereafter-th.
+type SamplingSnapshot struct {
+	Initial    int `json:"initial"`
+	Thereafter int `json:"thereafter"`
+}
+
+// EffectiveConfig returns the configuration the global logger is running with. It
+// returns the zero ConfigSnapshot before the logger is initialized.
+func EffectiveConfig() ConfigSnapshot {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	in := loadInstance()
+	if in == nil || in.options == nil {
+		return ConfigSnapshot{}
+	}
+
+	snapshot := ConfigSnapshot{
+		Environment:      in.environment,
+		Level:            in.config.Level.String(), // Atomic level, reflects runtime changes
+		ModuleLevels:     make(map[string]string),
+		Encoding:         in.config.Encoding,
+		OutputPaths:      redactPaths(in.config.OutputPaths),
+		ErrorOutputPaths: redactPaths(in.config.ErrorOutputPaths),
+		CallerEnabled:    in.callerEnabled,
+		GlobalFieldKeys:  []string{},
+		Integrations:     make(map[string]map[string]string),
+		Pipeline:         pipeline(),
+	}
+	if s := in.config.Sampling; s != nil {
+		snapshot.Sampling = &SamplingSnapshot{Initial: s.Initial, Thereafter: s.Thereafter}
+	}
+	if in.stacktraceOn {
+		snapshot.StacktraceLevel = in.stacktraceLevel.String()
+	}
+	if b := loadBudget(); b != nil {
+		snapshot.VolumeBudget = b.snapshot()
+	}
+
+	for _, ext := range extensions {
+		if ext.module != "" {
+			snapshot.ModuleLevels[ext.module] = ext.moduleLevel.String()
+		}
+		for _, f := range ext.fields {
+			snapshot.GlobalFieldKeys = append(snapshot.GlobalFieldKeys, f.Key)
+		}
+		if ext.dynamic != nil {
+			snapshot.GlobalFieldKeys = append(snapshot.GlobalFieldKeys, ext.dynamic.key)
+		}
+	}
+	sort.Strings(snapshot.GlobalFieldKeys)
+
+	for _, integration := range in.options.integrations {
+		settings := make(map[string]string, len(integration.settings))
+		for k, v := range integration.settings {
+			settings[k] = v
+		}
+		snapshot.Integrations[integration.name] = settings
+	}
+	if in.options.fallbackPath != "" {
+		snapshot.Integrations["fallback_output"] = map[string]string{"path": redactURL(in.options.fallbackPath)}
+	}
+	return snapshot
+}
+
+// MarshalJSON implements json.Marshaler, so that the snapshot can be embedded in health
+// or debug endpoints.
+func (s ConfigSnapshot) MarshalJSON() ([]byte, error) {
+	type plain ConfigSnapshot // Drops the method, avoiding recursion
+	return json.Marshal(plain(s))
+}
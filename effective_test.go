@@ -0,0 +1,86 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+	})
+
+	got := sazabi.EffectiveConfig()
+	if got.Environment != sazabi.ProductionEnvName || got.Level != "info" || got.Encoding != "console" {
+		t.Errorf("EffectiveConfig() = %+v, want production, info, console", got)
+	}
+	if !reflect.DeepEqual(got.OutputPaths, []string{"stderr"}) {
+		t.Errorf("OutputPaths = %v, want [stderr]", got.OutputPaths)
+	}
+	if got.Sampling == nil || got.Sampling.Initial != 100 || got.Sampling.Thereafter != 100 {
+		t.Errorf("Sampling = %+v, want 100/100", got.Sampling)
+	}
+	if !got.CallerEnabled || got.StacktraceLevel != "" {
+		t.Errorf("caller = %t, stacktrace = %q, want true and disabled", got.CallerEnabled, got.StacktraceLevel)
+	}
+}
+
+func TestEffectiveConfigTracksRuntimeChanges(t *testing.T) {
+	captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+	})
+	before := sazabi.EffectiveConfig()
+
+	if err := sazabi.SetCallerEnabled(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := sazabi.SetStacktraceLevel("error"); err != nil {
+		t.Fatal(err)
+	}
+	module, err := sazabi.SetModuleLevel("db", "warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer module.Remove()
+	fields := sazabi.AddGlobalFields("api_key", "s3cr3t", "region", "eu-west-1")
+	defer fields.Remove()
+
+	got := sazabi.EffectiveConfig()
+	if got.CallerEnabled || got.StacktraceLevel != "error" {
+		t.Errorf("caller = %t, stacktrace = %q, want false and error", got.CallerEnabled, got.StacktraceLevel)
+	}
+	if !reflect.DeepEqual(got.ModuleLevels, map[string]string{"db": "warn"}) {
+		t.Errorf("ModuleLevels = %v, want db=warn", got.ModuleLevels)
+	}
+	if !reflect.DeepEqual(got.GlobalFieldKeys, []string{"api_key", "region"}) {
+		t.Errorf("GlobalFieldKeys = %v, want [api_key region]", got.GlobalFieldKeys)
+	}
+	if !before.CallerEnabled || len(before.ModuleLevels) != 0 {
+		t.Errorf("earlier snapshot changed: %+v", before)
+	}
+
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if strings.Contains(string(data), "s3cr3t") {
+		t.Errorf("snapshot exposes a global field value: %s", data)
+	}
+	if !strings.Contains(string(data), `"module_levels":{"db":"warn"}`) {
+		t.Errorf("snapshot JSON = %s, want module_levels", data)
+	}
+
+	captureStderr(t, func() {
+		sazabi.Initialize("development")
+	})
+	if got := sazabi.EffectiveConfig(); got.Environment != "development" || got.Level != "debug" || got.Sampling != nil {
+		t.Errorf("after re-initialization: %+v, want development, debug, no sampling", got)
+	}
+}
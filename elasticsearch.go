@@ -0,0 +1,484 @@
+package sazabi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of WithElasticsearch.
+const (
+	defaultElasticsearchBatchSize     = 500
+	defaultElasticsearchFlushInterval = time.Second
+	defaultElasticsearchQueueSize     = 10000
+	defaultElasticsearchMaxRetries    = 3
+	elasticsearchTimeout              = 10 * time.Second // Timeout of the default HTTP client
+)
+
+// ElasticsearchOption configures an output added by WithElasticsearch.
+type ElasticsearchOption func(*elasticsearchConfig)
+
+// elasticsearchConfig is an Elasticsearch output added by WithElasticsearch.
+type elasticsearchConfig struct {
+	urls          []string
+	index         string // Layout of the index names, formatted with the entry time
+	batchSize     int
+	flushInterval time.Duration
+	queueSize     int
+	maxRetries    int
+	username      string
+	password      string
+	client        *http.Client
+}
+
+// ElasticsearchBatchSize sets the number of documents sent by one bulk request, 500 by
+// default. A full batch is sent immediately.
+func ElasticsearchBatchSize(n int) ElasticsearchOption {
+	return func(c *elasticsearchConfig) {
+		c.batchSize = n
+	}
+}
+
+// ElasticsearchFlushInterval sets the longest time an entry waits before being sent,
+// one second by default. Documents to retry are sent again after the same delay.
+func ElasticsearchFlushInterval(d time.Duration) ElasticsearchOption {
+	return func(c *elasticsearchConfig) {
+		c.flushInterval = d
+	}
+}
+
+// ElasticsearchQueueSize sets the number of documents kept while the cluster is slow or
+// unreachable, 10000 by default. Entries beyond it are dropped.
+func ElasticsearchQueueSize(n int) ElasticsearchOption {
+	return func(c *elasticsearchConfig) {
+		c.queueSize = n
+	}
+}
+
+// ElasticsearchMaxRetries sets how many times a document that failed to index is sent
+// again before being dropped, 3 by default.
+func ElasticsearchMaxRetries(n int) ElasticsearchOption {
+	return func(c *elasticsearchConfig) {
+		c.maxRetries = n
+	}
+}
+
+// ElasticsearchBasicAuth authenticates the bulk requests with HTTP basic authentication.
+func ElasticsearchBasicAuth(username, password string) ElasticsearchOption {
+	return func(c *elasticsearchConfig) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// ElasticsearchHTTPClient sends the bulk requests with client, such as a client trusting
+// the certificate authority of the cluster, instead of a client with a 10 second timeout.
+func ElasticsearchHTTPClient(client *http.Client) ElasticsearchOption {
+	return func(c *elasticsearchConfig) {
+		c.client = client
+	}
+}
+
+// WithElasticsearch adds an output indexing entries into Elasticsearch with the _bulk
+// API of the nodes at urls, such as "https://es-1:9200". Entries are JSON documents,
+// timed under "@timestamp", indexed into the index named by formatting the time layout
+// index with the UTC time of the entry: "app-logs-2006.01.02" writes each UTC day to its
+// own index.
+//
+// Entries are queued and sent in the background by batches, so that a slow or red
+// cluster never blocks logging; Sync sends the queued entries. Nodes are tried in turn
+// when a request fails. Documents the cluster fails to index with status 429 or 5xx,
+// and every document of a failed request, are sent again up to ElasticsearchMaxRetries
+// times; other rejected documents, and entries that do not fit in the queue, are
+// dropped and counted as such by PublishExpvars. Failures are reported to the internal
+// error output.
+func WithElasticsearch(urls []string, index string, opts ...ElasticsearchOption) Option {
+	cfg := elasticsearchConfig{
+		urls:          append([]string(nil), urls...),
+		index:         index,
+		batchSize:     defaultElasticsearchBatchSize,
+		flushInterval: defaultElasticsearchFlushInterval,
+		queueSize:     defaultElasticsearchQueueSize,
+		maxRetries:    defaultElasticsearchMaxRetries,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(o *options) {
+		o.elasticsearch = append(o.elasticsearch, cfg)
+		redacted := make([]string, len(cfg.urls))
+		for i, u := range cfg.urls {
+			redacted[i] = redactURL(u)
+		}
+		o.integrations = append(o.integrations, integration{
+			name: "elasticsearch",
+			settings: map[string]string{
+				"urls":     strings.Join(redacted, ","),
+				"index":    cfg.index,
+				"username": cfg.username,
+				"password": cfg.password,
+			},
+		})
+	}
+}
+
+// newElasticsearchCores returns the cores of the Elasticsearch outputs of o, writing
+// the entries enabled by enab and reporting failures to errorOutput.
+func newElasticsearchCores(enab zapcore.LevelEnabler, errorOutput zapcore.WriteSyncer, o *options) ([]zapcore.Core, error) {
+	cores := make([]zapcore.Core, 0, len(o.elasticsearch))
+	for _, cfg := range o.elasticsearch {
+		switch {
+		case len(cfg.urls) == 0:
+			return nil, fmt.Errorf("sazabi: no Elasticsearch URL")
+		case cfg.index == "":
+			return nil, fmt.Errorf("sazabi: no Elasticsearch index")
+		case cfg.batchSize <= 0 || cfg.queueSize <= 0 || cfg.flushInterval <= 0 || cfg.maxRetries < 0:
+			return nil, fmt.Errorf("sazabi: invalid Elasticsearch batch size, queue size, flush interval or retries")
+		}
+		if cfg.client == nil {
+			cfg.client = &http.Client{Timeout: elasticsearchTimeout}
+		}
+		encConfig := newProductionEncoderConfig()
+		encConfig.TimeKey = "@timestamp"
+		encConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+		out := &elasticsearchOutput{cfg: cfg, global: o.global, errorOutput: errorOutput}
+		cores = append(cores, &elasticsearchCore{LevelEnabler: enab, enc: zapcore.NewJSONEncoder(encConfig), out: out})
+	}
+	return cores, nil
+}
+
+// elasticsearchCore queues entries for an Elasticsearch output.
+type elasticsearchCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out *elasticsearchOutput
+}
+
+// With implements zapcore.Core.
+func (c *elasticsearchCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &elasticsearchCore{LevelEnabler: c.LevelEnabler, enc: enc, out: c.out}
+}
+
+// Check implements zapcore.Core.
+func (c *elasticsearchCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. The entry is sent in the background, so Write only
+// returns encoding errors.
+func (c *elasticsearchCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	body := append([]byte(nil), bytes.TrimRight(buf.Bytes(), "\n")...)
+	buf.Free()
+	c.out.add(elasticsearchDoc{index: ent.Time.UTC().Format(c.out.cfg.index), body: body})
+	return nil
+}
+
+// Sync implements zapcore.Core, sending the queued entries.
+func (c *elasticsearchCore) Sync() error {
+	return c.out.Sync()
+}
+
+// elasticsearchDoc is a document waiting to be indexed.
+type elasticsearchDoc struct {
+	index    string // Name of the index
+	body     []byte // JSON document
+	attempts int    // Failed attempts to index it
+}
+
+// elasticsearchOutput queues documents and indexes them by batches with the _bulk API.
+type elasticsearchOutput struct {
+	cfg         elasticsearchConfig
+	global      bool                // Whether dropped entries are counted by PublishExpvars
+	errorOutput zapcore.WriteSyncer // Receives failures
+
+	sendMu sync.Mutex // Serializes bulk requests; never held with mu while sending
+	next   int        // Node of the next request, in cfg.urls; guarded by sendMu
+
+	mu          sync.Mutex
+	queue       []elasticsearchDoc // Documents waiting to be sent, oldest first
+	timer       *time.Timer        // Flushes the queue after the flush interval, nil when none is scheduled
+	flushing    bool               // Whether a background flush is running
+	overflowing bool               // Whether entries are being dropped because the queue is full
+	dropped     int64              // Entries dropped since the output was opened
+}
+
+// add queues doc, or drops it when the queue is full.
+func (o *elasticsearchOutput) add(doc elasticsearchDoc) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.queue) >= o.cfg.queueSize {
+		o.dropLocked(1)
+		if !o.overflowing {
+			o.overflowing = true
+			o.reportf("queue full, dropping entries")
+		}
+		return
+	}
+	o.overflowing = false
+	o.queue = append(o.queue, doc)
+	if len(o.queue) >= o.cfg.batchSize {
+		o.flushLocked()
+	} else {
+		o.scheduleLocked()
+	}
+}
+
+// scheduleLocked schedules a flush after the flush interval, unless one is scheduled
+// already. o.mu must be held.
+func (o *elasticsearchOutput) scheduleLocked() {
+	if o.timer != nil {
+		return
+	}
+	o.timer = time.AfterFunc(o.cfg.flushInterval, func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.flushLocked()
+	})
+}
+
+// flushLocked starts sending the queue in the background, unless a background flush is
+// already running. o.mu must be held.
+func (o *elasticsearchOutput) flushLocked() {
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	if o.flushing {
+		return
+	}
+	o.flushing = true
+	go func() {
+		if err := o.sendQueued(); err != nil {
+			o.reportf("bulk request failed: %v", err)
+		}
+
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.flushing = false
+		if len(o.queue) > 0 {
+			o.scheduleLocked() // Documents to retry, or queued while sending
+		}
+	}()
+}
+
+// Sync sends the queued documents. It returns the error of the first bulk request that
+// failed; its documents stay queued for a retry.
+func (o *elasticsearchOutput) Sync() error {
+	o.mu.Lock()
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	o.mu.Unlock()
+
+	err := o.sendQueued()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.queue) > 0 {
+		o.scheduleLocked()
+	}
+	return err
+}
+
+// sendQueued sends the documents queued when it is called, by batches, stopping at the
+// first request that fails. Documents to retry are queued again, for the next flush.
+func (o *elasticsearchOutput) sendQueued() error {
+	o.sendMu.Lock()
+	defer o.sendMu.Unlock()
+
+	o.mu.Lock()
+	remaining := len(o.queue)
+	o.mu.Unlock()
+	for remaining > 0 {
+		o.mu.Lock()
+		n := o.cfg.batchSize
+		if n > remaining {
+			n = remaining
+		}
+		if n > len(o.queue) {
+			n = len(o.queue)
+		}
+		batch := append([]elasticsearchDoc(nil), o.queue[:n]...)
+		o.queue = o.queue[n:]
+		o.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		remaining -= n
+
+		retry, err := o.bulk(batch)
+		o.requeue(retry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requeue puts docs back at the front of the queue, dropping those that were sent
+// cfg.maxRetries times already and the oldest ones beyond the queue size.
+func (o *elasticsearchOutput) requeue(docs []elasticsearchDoc) {
+	if len(docs) == 0 {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	kept := make([]elasticsearchDoc, 0, len(docs)+len(o.queue))
+	expired := 0
+	for _, doc := range docs {
+		if doc.attempts++; doc.attempts > o.cfg.maxRetries {
+			expired++
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	o.queue = append(kept, o.queue...)
+	overflow := 0
+	if len(o.queue) > o.cfg.queueSize {
+		overflow = len(o.queue) - o.cfg.queueSize
+		o.queue = o.queue[overflow:]
+	}
+	if expired+overflow > 0 {
+		o.dropLocked(expired + overflow)
+		o.reportf("%d documents dropped after %d retries, %d for lack of room in the queue", expired, o.cfg.maxRetries, overflow)
+	}
+}
+
+// bulk indexes batch, trying each node in turn until one answers. It returns the
+// documents to retry: those the cluster failed to index with a transient status, or
+// the whole batch when no node answered, together with the error of the last node.
+func (o *elasticsearchOutput) bulk(batch []elasticsearchDoc) ([]elasticsearchDoc, error) {
+	var body bytes.Buffer
+	for _, doc := range batch {
+		index, _ := json.Marshal(doc.index)
+		body.WriteString(`{"index":{"_index":`)
+		body.Write(index)
+		body.WriteString("}}\n")
+		body.Write(doc.body)
+		body.WriteByte('\n')
+	}
+
+	var err error
+	for i := 0; i < len(o.cfg.urls); i++ {
+		node := o.cfg.urls[o.next]
+		var items []elasticsearchItem
+		if items, err = o.post(node, body.Bytes()); err == nil {
+			return o.failures(batch, items)
+		}
+		o.next = (o.next + 1) % len(o.cfg.urls)
+	}
+	return batch, err
+}
+
+// elasticsearchItem is the result of a document in the response of a bulk request.
+type elasticsearchItem struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// post sends a bulk request to node, returning the results of its documents when the
+// request succeeded.
+func (o *elasticsearchOutput) post(node string, body []byte) ([]elasticsearchItem, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(node, "/")+"/_bulk", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if o.cfg.username != "" || o.cfg.password != "" {
+		req.SetBasicAuth(o.cfg.username, o.cfg.password)
+	}
+	resp, err := o.cfg.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("%s: %s", redactURL(node), resp.Status)
+	}
+
+	var result struct {
+		Errors bool                           `json:"errors"`
+		Items  []map[string]elasticsearchItem `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%s: decoding bulk response: %w", redactURL(node), err)
+	}
+	if !result.Errors {
+		return nil, nil
+	}
+	items := make([]elasticsearchItem, len(result.Items))
+	for i, item := range result.Items {
+		for _, action := range item { // A single action per item
+			items[i] = action
+		}
+	}
+	return items, nil
+}
+
+// failures returns the documents of batch to retry given the results of their bulk
+// request, items, which are empty when every document was indexed. Documents rejected
+// for good are dropped.
+func (o *elasticsearchOutput) failures(batch []elasticsearchDoc, items []elasticsearchItem) ([]elasticsearchDoc, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if len(items) != len(batch) {
+		return batch, fmt.Errorf("bulk response has %d items for %d documents", len(items), len(batch))
+	}
+	var retry []elasticsearchDoc
+	rejected := 0
+	var reason json.RawMessage
+	for i, item := range items {
+		switch {
+		case item.Status < 300:
+		case item.Status == http.StatusTooManyRequests || item.Status >= 500:
+			retry = append(retry, batch[i])
+		default:
+			rejected++
+			reason = item.Error
+		}
+	}
+	if rejected > 0 {
+		o.mu.Lock()
+		o.dropLocked(rejected)
+		o.mu.Unlock()
+		o.reportf("%d documents rejected, last error: %s", rejected, reason)
+	}
+	return retry, nil
+}
+
+// dropLocked counts n dropped entries. o.mu must be held.
+func (o *elasticsearchOutput) dropLocked(n int) {
+	o.dropped += int64(n)
+	if o.global {
+		countOutputDropped(n)
+	}
+}
+
+// reportf writes a failure to the internal error output.
+func (o *elasticsearchOutput) reportf(format string, args ...interface{}) {
+	fmt.Fprintf(o.errorOutput, "%v output elasticsearch %s: %s\n", time.Now(), redactURL(o.cfg.urls[0]), fmt.Sprintf(format, args...))
+	o.errorOutput.Sync()
+}
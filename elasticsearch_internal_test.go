@@ -0,0 +1,314 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// bulkDoc is a document received by a fakeCluster.
+type bulkDoc struct {
+	Index string
+	Msg   string
+}
+
+// fakeCluster is an Elasticsearch stub recording bulk requests and answering with the
+// item statuses returned by respond, all 201 when it is nil.
+type fakeCluster struct {
+	mu       sync.Mutex
+	requests [][]bulkDoc
+	respond  func(request int, docs []bulkDoc) []int
+}
+
+func (c *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	var docs []bulkDoc
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action struct {
+			Index struct {
+				Index string `json:"_index"`
+			} `json:"index"`
+		}
+		var doc struct {
+			Msg       string `json:"msg"`
+			Timestamp string `json:"@timestamp"`
+		}
+		json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan()
+		json.Unmarshal(scanner.Bytes(), &doc)
+		if doc.Timestamp == "" {
+			http.Error(w, "document without @timestamp", http.StatusBadRequest)
+			return
+		}
+		docs = append(docs, bulkDoc{Index: action.Index.Index, Msg: doc.Msg})
+	}
+
+	c.mu.Lock()
+	c.requests = append(c.requests, docs)
+	n := len(c.requests)
+	c.mu.Unlock()
+
+	statuses := make([]int, len(docs))
+	for i := range statuses {
+		statuses[i] = http.StatusCreated
+	}
+	if c.respond != nil {
+		statuses = c.respond(n, docs)
+	}
+	var items []string
+	failed := false
+	for _, status := range statuses {
+		item := fmt.Sprintf(`{"index":{"status":%d}}`, status)
+		if status >= 300 {
+			failed = true
+			item = fmt.Sprintf(`{"index":{"status":%d,"error":{"type":"error_%d"}}}`, status, status)
+		}
+		items = append(items, item)
+	}
+	fmt.Fprintf(w, `{"took":1,"errors":%t,"items":[%s]}`, failed, strings.Join(items, ","))
+}
+
+// received returns the messages of each request received, as "index:msg".
+func (c *fakeCluster) received() [][]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var all [][]string
+	for _, docs := range c.requests {
+		var msgs []string
+		for _, d := range docs {
+			msgs = append(msgs, d.Index+":"+d.Msg)
+		}
+		all = append(all, msgs)
+	}
+	return all
+}
+
+// newTestIndexer returns a core indexing into the clusters at urls, with the index
+// layout "app-logs-2006.01.02", no background flush and opts, and the buffer receiving
+// its failures.
+func newTestIndexer(t *testing.T, urls []string, opts ...ElasticsearchOption) (*elasticsearchCore, *lockedBuffer) {
+	t.Helper()
+
+	errs := &lockedBuffer{}
+	opts = append([]ElasticsearchOption{ElasticsearchFlushInterval(time.Hour)}, opts...)
+	o := newOptions([]Option{WithElasticsearch(urls, "app-logs-2006.01.02", opts...)})
+	cores, err := newElasticsearchCores(zapcore.DebugLevel, errs, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cores[0].(*elasticsearchCore), errs
+}
+
+// index writes an Info entry with msg, timed at t, to c.
+func index(t *testing.T, c *elasticsearchCore, at time.Time, msg string) {
+	t.Helper()
+
+	if err := c.Write(zapcore.Entry{Level: zapcore.InfoLevel, Time: at, Message: msg}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestElasticsearch(t *testing.T) {
+	cluster := &fakeCluster{}
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+	c, errs := newTestIndexer(t, []string{server.URL})
+
+	at := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	for _, msg := range []string{"a", "b", "c"} {
+		index(t, c, at, msg)
+	}
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[[app-logs-2024.01.31:a app-logs-2024.01.31:b app-logs-2024.01.31:c]]"
+	if got := fmt.Sprint(cluster.received()); got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+	if errs.String() != "" || c.out.dropped != 0 {
+		t.Errorf("internal errors = %q, %d dropped; want none", errs.String(), c.out.dropped)
+	}
+}
+
+func TestElasticsearchIndexRollover(t *testing.T) {
+	cluster := &fakeCluster{}
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+	c, _ := newTestIndexer(t, []string{server.URL})
+
+	clock := &fakeClock{t: time.Date(2024, 1, 31, 23, 59, 59, 999999999, time.UTC)}
+	index(t, c, clock.now(), "before midnight")
+	clock.advance(time.Nanosecond)
+	index(t, c, clock.now(), "midnight")
+	// 08:30 in UTC+9 is still January 31 in UTC: days follow UTC.
+	index(t, c, time.Date(2024, 2, 1, 8, 30, 0, 0, time.FixedZone("UTC+9", 9*3600)), "tokyo morning")
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[[app-logs-2024.01.31:before midnight app-logs-2024.02.01:midnight app-logs-2024.01.31:tokyo morning]]"
+	if got := fmt.Sprint(cluster.received()); got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+}
+
+func TestElasticsearchPartialFailure(t *testing.T) {
+	cluster := &fakeCluster{respond: func(request int, docs []bulkDoc) []int {
+		statuses := make([]int, len(docs))
+		for i, d := range docs {
+			switch {
+			case request == 1 && d.Msg == "throttled":
+				statuses[i] = http.StatusTooManyRequests
+			case request == 1 && d.Msg == "unavailable":
+				statuses[i] = http.StatusServiceUnavailable
+			case d.Msg == "bad mapping":
+				statuses[i] = http.StatusBadRequest
+			default:
+				statuses[i] = http.StatusCreated
+			}
+		}
+		return statuses
+	}}
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+	c, errs := newTestIndexer(t, []string{server.URL})
+
+	at := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	for _, msg := range []string{"ok", "throttled", "bad mapping", "unavailable"} {
+		index(t, c, at, msg)
+	}
+	c.Sync()
+	c.Sync()
+	c.Sync()
+
+	got := cluster.received()
+	if len(got) != 2 {
+		t.Fatalf("requests = %q, want the batch then the retry", got)
+	}
+	if want := "[app-logs-2024.01.31:throttled app-logs-2024.01.31:unavailable]"; fmt.Sprint(got[1]) != want {
+		t.Errorf("retry = %s, want only the documents failing with a transient status %s", got[1], want)
+	}
+	if c.out.dropped != 1 || !strings.Contains(errs.String(), "1 documents rejected") || !strings.Contains(errs.String(), "error_400") {
+		t.Errorf("%d dropped, internal errors = %q; want the rejected document dropped and reported", c.out.dropped, errs.String())
+	}
+}
+
+func TestElasticsearchBoundedRetries(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "cluster red", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	c, errs := newTestIndexer(t, []string{server.URL}, ElasticsearchMaxRetries(2))
+
+	index(t, c, time.Now(), "a")
+	index(t, c, time.Now(), "b")
+	for i := 0; i < 5; i++ {
+		c.Sync()
+	}
+
+	if requests != 3 {
+		t.Errorf("%d requests, want the first attempt and 2 retries", requests)
+	}
+	if c.out.dropped != 2 || len(c.out.queue) != 0 {
+		t.Errorf("%d dropped, %d queued; want both documents dropped", c.out.dropped, len(c.out.queue))
+	}
+	if !strings.Contains(errs.String(), "2 documents dropped after 2 retries") {
+		t.Errorf("internal errors = %q, want the dropped documents reported", errs.String())
+	}
+}
+
+func TestElasticsearchQueueBounded(t *testing.T) {
+	cluster := &fakeCluster{}
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+	c, errs := newTestIndexer(t, []string{server.URL}, ElasticsearchQueueSize(3))
+
+	for i := 0; i < 10; i++ {
+		index(t, c, time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC), fmt.Sprint(i))
+	}
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[[app-logs-2024.01.31:0 app-logs-2024.01.31:1 app-logs-2024.01.31:2]]"
+	if got := fmt.Sprint(cluster.received()); got != want {
+		t.Errorf("requests = %s, want the entries that fit in the queue %s", got, want)
+	}
+	if c.out.dropped != 7 || strings.Count(errs.String(), "queue full") != 1 {
+		t.Errorf("%d dropped, internal errors = %q; want 7 dropped and reported once", c.out.dropped, errs.String())
+	}
+}
+
+func TestElasticsearchBatches(t *testing.T) {
+	cluster := &fakeCluster{}
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+	c, _ := newTestIndexer(t, []string{server.URL}, ElasticsearchBatchSize(2))
+
+	at := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	index(t, c, at, "a")
+	index(t, c, at, "b") // Full batch, sent in the background
+	index(t, c, at, "c")
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	var msgs []string
+	for _, request := range cluster.received() {
+		if len(request) > 2 {
+			t.Errorf("request = %q, want at most 2 documents", request)
+		}
+		msgs = append(msgs, request...)
+	}
+	if got := strings.Join(msgs, ","); got != "app-logs-2024.01.31:a,app-logs-2024.01.31:b,app-logs-2024.01.31:c" {
+		t.Errorf("documents = %s, want a, b and c in order", got)
+	}
+}
+
+func TestElasticsearchFailover(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	cluster := &fakeCluster{}
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+	c, _ := newTestIndexer(t, []string{down.URL, server.URL})
+
+	index(t, c, time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC), "a")
+	if err := c.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v, want the second node to answer", err)
+	}
+	if got := fmt.Sprint(cluster.received()); got != "[[app-logs-2024.01.31:a]]" {
+		t.Errorf("requests = %s, want the document sent to the second node", got)
+	}
+}
+
+func TestElasticsearchInvalidConfig(t *testing.T) {
+	for _, opt := range []Option{
+		WithElasticsearch(nil, "logs"),
+		WithElasticsearch([]string{"http://es:9200"}, ""),
+		WithElasticsearch([]string{"http://es:9200"}, "logs", ElasticsearchQueueSize(0)),
+	} {
+		if _, err := newElasticsearchCores(zapcore.InfoLevel, &lockedBuffer{}, newOptions([]Option{opt})); err == nil {
+			t.Error("newElasticsearchCores succeeded with an invalid config, want an error")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestWithElasticsearch(t *testing.T) {
+	restoreDefault(t)
+	var (
+		mu    sync.Mutex
+		lines []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scanner := bufio.NewScanner(r.Body)
+		mu.Lock()
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		mu.Unlock()
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	sazabi.Initialize(sazabi.ProductionEnvName,
+		sazabi.WithOutputPaths("stderr"),
+		sazabi.WithElasticsearch([]string{server.URL}, "app-logs-2006.01.02"))
+	sazabi.Infow("order placed", "order_id", 7)
+	sazabi.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	// The day may end during the test: only the prefix of the index is checked.
+	prefix := `{"index":{"_index":"app-logs-` + time.Now().UTC().Format("2006.")
+	for i := 0; i+1 < len(lines); i += 2 {
+		if strings.Contains(lines[i+1], `"msg":"order placed"`) {
+			if !strings.HasPrefix(lines[i], prefix) || !strings.Contains(lines[i+1], `"order_id":7`) || !strings.Contains(lines[i+1], `"@timestamp":`) {
+				t.Errorf("bulk lines = %q, %q; want the entry indexed into the index of the day", lines[i], lines[i+1])
+			}
+			return
+		}
+	}
+	t.Errorf("bulk lines = %q, want the entry", lines)
+}
@@ -0,0 +1,82 @@
+package sazabi
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Policies for empty messages, set by WithEmptyMessagePolicy.
+const (
+	EmptyMessageAllow       = "allow"       // Write empty messages as they are
+	EmptyMessagePlaceholder = "placeholder" // Replace them with EmptyMessagePlaceholderText
+	EmptyMessageDPanic      = "dpanic"      // Write them at DPanic level, panicking in development
+)
+
+// EmptyMessagePlaceholderText replaces empty messages under EmptyMessagePlaceholder.
+const EmptyMessagePlaceholderText = "<empty>"
+
+// EmptyMessageKey is the key of the field marking entries whose message was empty.
+const EmptyMessageKey = "empty_message"
+
+// WithEmptyMessagePolicy sets how entries with an empty message, such as those of
+// Infow("", "err", err), are written. Messages made of whitespace only are empty too,
+// and the f-family functions are judged on their formatted message.
+// EmptyMessageAllow, the default, writes them unchanged. EmptyMessagePlaceholder
+// replaces the message with EmptyMessagePlaceholderText and adds EmptyMessageKey=true,
+// so that the entries can be searched for. EmptyMessageDPanic writes them at DPanic
+// level with EmptyMessageKey=true, then panics in development, like zap's DPanic.
+func WithEmptyMessagePolicy(policy string) Option {
+	return func(o *options) {
+		o.emptyMessagePolicy = policy
+	}
+}
+
+// emptyMessageCore applies an empty message policy to the entries it checks.
+type emptyMessageCore struct {
+	zapcore.Core
+	policy      string
+	development bool // Panic after writing under EmptyMessageDPanic
+}
+
+// newEmptyMessageCore wraps core with the empty message policy of o, or returns core
+// when empty messages are allowed. It fails on an unknown policy.
+func newEmptyMessageCore(core zapcore.Core, development bool, o *options) (zapcore.Core, error) {
+	switch o.emptyMessagePolicy {
+	case "", EmptyMessageAllow:
+		return core, nil
+	case EmptyMessagePlaceholder, EmptyMessageDPanic:
+		return &emptyMessageCore{Core: core, policy: o.emptyMessagePolicy, development: development}, nil
+	}
+	return nil, fmt.Errorf("sazabi: unknown empty message policy %q", o.emptyMessagePolicy)
+}
+
+// With implements zapcore.Core.
+func (c *emptyMessageCore) With(fields []zapcore.Field) zapcore.Core {
+	return &emptyMessageCore{Core: c.Core.With(fields), policy: c.policy, development: c.development}
+}
+
+// Check implements zapcore.Core. Entries are rewritten before they reach the cores
+// below, which record them in the checked entry.
+func (c *emptyMessageCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if strings.TrimSpace(ent.Message) != "" || !c.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+
+	core := c.Core.With([]zapcore.Field{zap.Bool(EmptyMessageKey, true)})
+	if c.policy == EmptyMessagePlaceholder {
+		ent.Message = EmptyMessagePlaceholderText
+		return core.Check(ent, ce)
+	}
+
+	if ent.Level < zapcore.DPanicLevel {
+		ent.Level = zapcore.DPanicLevel
+	}
+	ce = core.Check(ent, ce)
+	if ce != nil && c.development && ent.Level == zapcore.DPanicLevel {
+		ce = ce.After(ent, zapcore.WriteThenPanic)
+	}
+	return ce
+}
@@ -0,0 +1,95 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestEmptyMessageAllow(t *testing.T) {
+	log, read := newFileLogger(t, sazabi.ProductionEnvName)
+
+	log.Infow("", "err", "boom")
+
+	output := read()
+	if !strings.Contains(output, "\t\t{\"err\"") || strings.Contains(output, sazabi.EmptyMessageKey) {
+		t.Errorf("output = %q, want the empty message unchanged", output)
+	}
+}
+
+func TestEmptyMessagePlaceholder(t *testing.T) {
+	log, read := newFileLogger(t, sazabi.ProductionEnvName, sazabi.WithEmptyMessagePolicy(sazabi.EmptyMessagePlaceholder))
+
+	log.Infow("", "err", "boom")
+	log.Warn(" \t ")
+	log.Errorf("%s", "")
+	log.Desugar().Info("\n")
+	log.Info("kept message")
+
+	lines := strings.Split(strings.TrimSpace(read()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("output has %d lines, want 5:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	for _, line := range lines[:4] {
+		fields := entryFields(t, line, "\t"+sazabi.EmptyMessagePlaceholderText+"\t")
+		if fields[sazabi.EmptyMessageKey] != true {
+			t.Errorf("line %q: fields = %v, want %s", line, fields, sazabi.EmptyMessageKey)
+		}
+	}
+	if !strings.Contains(lines[0], `"err": "boom"`) {
+		t.Errorf("line %q lost its fields", lines[0])
+	}
+	if strings.Contains(lines[4], sazabi.EmptyMessageKey) {
+		t.Errorf("line %q is marked empty", lines[4])
+	}
+}
+
+func TestEmptyMessagePlaceholderGlobal(t *testing.T) {
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithEmptyMessagePolicy(sazabi.EmptyMessagePlaceholder))
+
+	sazabi.Infof("%s%s", "", "  ")
+	sazabi.InfoFields("")
+
+	if n := strings.Count(read(), "\t"+sazabi.EmptyMessagePlaceholderText+"\t"); n != 2 {
+		t.Errorf("%d placeholders written, want 2:\n%s", n, read())
+	}
+}
+
+func TestEmptyMessageDPanicProduction(t *testing.T) {
+	log, read := newFileLogger(t, sazabi.ProductionEnvName, sazabi.WithEmptyMessagePolicy(sazabi.EmptyMessageDPanic))
+
+	log.Infof("%s", "") // Must not panic in production
+
+	output := read()
+	if !strings.Contains(output, "\tDPANIC\t") || !strings.Contains(output, `"empty_message": true`) {
+		t.Errorf("output = %q, want a DPanic entry marked empty", output)
+	}
+}
+
+func TestEmptyMessageDPanicDevelopment(t *testing.T) {
+	log, read := newFileLogger(t, "development", sazabi.WithEmptyMessagePolicy(sazabi.EmptyMessageDPanic))
+
+	log.Info("not empty") // Must not panic
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("empty message did not panic in development")
+			}
+		}()
+		log.Infow(" ", "err", "boom")
+	}()
+
+	if output := read(); !strings.Contains(output, "\tDPANIC\t") {
+		t.Errorf("output = %q, want the entry written before the panic", output)
+	}
+}
+
+func TestEmptyMessageUnknownPolicy(t *testing.T) {
+	if _, err := sazabi.New(sazabi.ProductionEnvName, sazabi.WithEmptyMessagePolicy("ignore")); err == nil {
+		t.Error("New() succeeded with an unknown empty message policy")
+	}
+}
@@ -0,0 +1,46 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestJSONEncoding(t *testing.T) {
+	restoreDefault(t)
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithJSONEncoding())
+		sazabi.Infow("order placed", "order_id", 42, "currency", "EUR")
+	})
+
+	line := lineContaining(output, "order placed")
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("line %q is not JSON: %v", line, err)
+	}
+	for _, key := range []string{"ts", "level", "msg", "caller"} {
+		if _, ok := entry[key]; !ok {
+			t.Errorf("entry %v lacks %s", entry, key)
+		}
+	}
+	if entry["level"] != "INFO" || entry["msg"] != "order placed" || entry["order_id"] != float64(42) || entry["currency"] != "EUR" {
+		t.Errorf("entry = %v, want the message and fields", entry)
+	}
+}
+
+func TestJSONEncodingDevelopment(t *testing.T) {
+	restoreDefault(t)
+	output := captureStderr(t, func() {
+		sazabi.Initialize("development", sazabi.WithJSONEncoding())
+		sazabi.Infow("order placed", "order_id", 42)
+	})
+
+	if line := lineContaining(output, "order placed"); strings.HasPrefix(line, "{") {
+		t.Errorf("development line = %q, want the console encoding", line)
+	}
+}
@@ -0,0 +1,66 @@
+package sazabi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Environment variables read by InitializeFromEnv, besides EnvironmentEnvVar.
+const (
+	LevelEnvVar  = "LOG_LEVEL"  // debug, info, warn or error
+	FormatEnvVar = "LOG_FORMAT" // json or console
+)
+
+// ErrInvalidEnvVar is wrapped by the errors of InitializeFromEnv for environment
+// variables set to unknown values.
+var ErrInvalidEnvVar = errors.New("sazabi: invalid environment variable")
+
+// envLevels are the levels accepted in LevelEnvVar.
+var envLevels = map[string]zapcore.Level{
+	"debug": zapcore.DebugLevel,
+	"info":  zapcore.InfoLevel,
+	"warn":  zapcore.WarnLevel,
+	"error": zapcore.ErrorLevel,
+}
+
+// InitializeFromEnv initializes the global logger from environment variables: the
+// environment named by EnvironmentEnvVar (APP_ENV), development when it is unset or
+// empty; the level from LevelEnvVar (LOG_LEVEL), the environment default when unset;
+// and the encoding from FormatEnvVar (LOG_FORMAT), which applies to development too.
+// Values are case-insensitive. opts apply on top. A level or format that is not one of
+// the documented values returns an error wrapping ErrInvalidEnvVar, and the current
+// logger is kept; so do the errors of TryInitialize.
+func InitializeFromEnv(opts ...Option) error {
+	return initializeFromEnv(os.LookupEnv, opts, callerLocation(2))
+}
+
+// initializeFromEnv implements InitializeFromEnv with the environment of lookup.
+func initializeFromEnv(lookup func(string) (string, bool), opts []Option, caller string) error {
+	var env []Option
+	environment := "development"
+	if value, ok := lookup(EnvironmentEnvVar); ok && value != "" {
+		environment = strings.ToLower(value)
+		env = append(env, func(o *options) { o.environmentSource = EnvironmentEnvVar })
+	}
+	if value, ok := lookup(LevelEnvVar); ok && value != "" {
+		level, known := envLevels[strings.ToLower(value)]
+		if !known {
+			return fmt.Errorf("%w: %s=%q, want debug, info, warn or error", ErrInvalidEnvVar, LevelEnvVar, value)
+		}
+		env = append(env, func(o *options) { o.level = &level })
+	}
+	if value, ok := lookup(FormatEnvVar); ok && value != "" {
+		format := strings.ToLower(value)
+		if format != "json" && format != "console" {
+			return fmt.Errorf("%w: %s=%q, want json or console", ErrInvalidEnvVar, FormatEnvVar, value)
+		}
+		env = append(env, func(o *options) { o.format = format })
+	}
+
+	o := newOptions(append(env, opts...))
+	return initialize(environment, o, caller)
+}
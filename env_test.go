@@ -0,0 +1,90 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestInitializeFromEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantEnv   string
+		wantJSON  bool
+		wantDebug bool
+		wantInfo  bool
+	}{
+		{name: "unset", wantEnv: "development", wantDebug: true, wantInfo: true},
+		{name: "production", env: map[string]string{"APP_ENV": "production"}, wantEnv: "production", wantInfo: true},
+		{name: "production json warn", env: map[string]string{"APP_ENV": "production", "LOG_FORMAT": "json", "LOG_LEVEL": "warn"}, wantEnv: "production", wantJSON: true},
+		{name: "production debug", env: map[string]string{"APP_ENV": "PRODUCTION", "LOG_LEVEL": "DEBUG"}, wantEnv: "production", wantDebug: true, wantInfo: true},
+		{name: "development json", env: map[string]string{"APP_ENV": "development", "LOG_FORMAT": "json"}, wantEnv: "development", wantJSON: true, wantDebug: true, wantInfo: true},
+		{name: "development error console", env: map[string]string{"LOG_LEVEL": "error", "LOG_FORMAT": "console"}, wantEnv: "development"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreDefault(t)
+			for _, key := range []string{sazabi.EnvironmentEnvVar, sazabi.LevelEnvVar, sazabi.FormatEnvVar} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			var err error
+			output := captureStderr(t, func() {
+				err = sazabi.InitializeFromEnv()
+				sazabi.Debug("env debug entry")
+				sazabi.Info("env info entry")
+				sazabi.Warn("env warn entry")
+			})
+			if err != nil {
+				t.Fatalf("InitializeFromEnv() error = %v", err)
+			}
+
+			if init, _ := sazabi.LastInitializer(); init.Environment != tt.wantEnv {
+				t.Errorf("environment = %q, want %q", init.Environment, tt.wantEnv)
+			}
+			if got := strings.Contains(output, "env debug entry"); got != tt.wantDebug {
+				t.Errorf("debug entry written = %t, want %t:\n%s", got, tt.wantDebug, output)
+			}
+			if got := strings.Contains(output, "env info entry"); got != tt.wantInfo {
+				t.Errorf("info entry written = %t, want %t:\n%s", got, tt.wantInfo, output)
+			}
+			warn := lineContaining(output, "env warn entry")
+			if got := strings.HasPrefix(warn, "{"); got != tt.wantJSON {
+				t.Errorf("warn entry %q is JSON = %t, want %t", warn, got, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func TestInitializeFromEnvInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		val  string
+	}{
+		{name: "level", key: sazabi.LevelEnvVar, val: "verbose"},
+		{name: "format", key: sazabi.FormatEnvVar, val: "xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreDefault(t)
+			t.Setenv(sazabi.EnvironmentEnvVar, "development")
+			t.Setenv(tt.key, tt.val)
+			before, _ := sazabi.LastInitializer()
+
+			err := sazabi.InitializeFromEnv()
+			if !errors.Is(err, sazabi.ErrInvalidEnvVar) || !strings.Contains(err.Error(), tt.val) {
+				t.Errorf("InitializeFromEnv() error = %v, want ErrInvalidEnvVar naming %q", err, tt.val)
+			}
+			if after, _ := sazabi.LastInitializer(); after != before {
+				t.Errorf("logger initialized by %+v despite the error, want it kept", after)
+			}
+		})
+	}
+}
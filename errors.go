@@ -0,0 +1,68 @@
+package sazabi
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Keys of the fields describing errors. ErrorKey carries the error logged by
+// LogAndWrap, WarnErr, WithError and ErrorErr; the latter two add the type of the error
+// under ErrorTypeKey and its %+v formatting, with the stack trace of errors providing
+// one, under ErrorVerboseKey.
+const (
+	ErrorKey        = "error"
+	ErrorTypeKey    = "error_type"
+	ErrorVerboseKey = "error_verbose"
+)
+
+// WithError returns a child of the global logger adding the ErrorKey, ErrorTypeKey and
+// ErrorVerboseKey fields describing err to each of its entries, like With. A nil err
+// adds no fields.
+func WithError(err error) Logger {
+	return directLogger().With(errorFields(err)...)
+}
+
+// ErrorErr logs msg at Error level with the key-value pairs followed by the fields of
+// WithError describing err. A nil err adds no fields, but msg is still logged.
+func ErrorErr(err error, msg string, keysValues ...interface{}) {
+	logger().Errorw(msg, append(keysValues[:len(keysValues):len(keysValues)], errorFields(err)...)...)
+}
+
+// errorFields returns the fields describing err, none when err is nil.
+func errorFields(err error) []interface{} {
+	if err == nil {
+		return nil
+	}
+	return []interface{}{
+		zap.String(ErrorKey, err.Error()),
+		zap.String(ErrorTypeKey, fmt.Sprintf("%T", err)),
+		zap.String(ErrorVerboseKey, fmt.Sprintf("%+v", err)),
+	}
+}
+
+// LogAndWrap logs msg at Error level with err and the key-value pairs, then returns err
+// wrapped as "msg: err", so that errors.Is and errors.As still match it. A nil err is
+// returned untouched without logging, which allows `return sazabi.LogAndWrap(err, ...)`.
+func LogAndWrap(err error, msg string, keysValues ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	logger().Errorw(msg, withError(keysValues, err)...) // Log the error with context
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// WarnErr is LogAndWrap at Warn level, for errors the caller can recover from.
+func WarnErr(err error, msg string, keysValues ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	logger().Warnw(msg, withError(keysValues, err)...) // Log the error with context
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// withError returns keysValues followed by the error pair, without modifying the
+// caller's slice.
+func withError(keysValues []interface{}, err error) []interface{} {
+	return append(keysValues[:len(keysValues):len(keysValues)], ErrorKey, err)
+}
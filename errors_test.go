@@ -0,0 +1,137 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestLogAndWrap(t *testing.T) {
+	tests := []struct {
+		name  string
+		fn    func(error, string, ...interface{}) error
+		level string
+	}{
+		{name: "LogAndWrap", fn: sazabi.LogAndWrap, level: "ERROR"},
+		{name: "WarnErr", fn: sazabi.WarnErr, level: "WARN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				err  error
+				want string
+			)
+			message := tt.name + " read config"
+			output := captureStderr(t, func() {
+				sazabi.Initialize(sazabi.ProductionEnvName)
+				want = callerLine()
+				err = tt.fn(io.ErrUnexpectedEOF, message, "path", "/etc/app.yaml")
+			})
+
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("returned error %v does not wrap io.ErrUnexpectedEOF", err)
+			}
+			if got, want := err.Error(), message+": unexpected EOF"; got != want {
+				t.Errorf("returned error = %q, want %q", got, want)
+			}
+
+			assertCaller(t, output, message, want)
+			line := lineContaining(output, message)
+			if !strings.Contains(line, "\t"+tt.level+"\t") {
+				t.Errorf("entry should be logged at %s: %s", tt.level, line)
+			}
+			fields := entryFields(t, output, message)
+			if fields[sazabi.ErrorKey] != "unexpected EOF" || fields["path"] != "/etc/app.yaml" {
+				t.Errorf("fields = %v, want error and path", fields)
+			}
+		})
+	}
+}
+
+func TestLogAndWrapNil(t *testing.T) {
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		if err := sazabi.LogAndWrap(nil, "nil error"); err != nil {
+			t.Errorf("LogAndWrap(nil) = %v, want nil", err)
+		}
+		if err := sazabi.WarnErr(nil, "nil error"); err != nil {
+			t.Errorf("WarnErr(nil) = %v, want nil", err)
+		}
+	})
+
+	if line := lineContaining(output, "nil error"); line != "" {
+		t.Errorf("nil error was logged: %s", line)
+	}
+}
+
+// quotaError is an error type of the application.
+type quotaError struct {
+	limit int
+}
+
+func (e *quotaError) Error() string { return fmt.Sprintf("quota of %d exceeded", e.limit) }
+
+// Format implements fmt.Formatter, adding details to %+v like errors carrying a stack.
+func (e *quotaError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%s (limit set by plan)", e.Error())
+		return
+	}
+	io.WriteString(s, e.Error())
+}
+
+func TestWithError(t *testing.T) {
+	wrapped := fmt.Errorf("load user: %w", io.ErrUnexpectedEOF)
+	custom := &quotaError{limit: 3}
+
+	c, stop := sazabi.StartCapture()
+	sazabi.WithError(wrapped).Info("wrapped error")
+	sazabi.ErrorErr(custom, "custom error", "user", "alice")
+	sazabi.WithError(nil).Info("nil error")
+	sazabi.ErrorErr(nil, "nil error again")
+	stop()
+
+	tests := []struct {
+		msg, error, errorType, verbose string
+	}{
+		{msg: "wrapped error", error: "load user: unexpected EOF", errorType: "*fmt.wrapError", verbose: "load user: unexpected EOF"},
+		{msg: "custom error", error: "quota of 3 exceeded", errorType: "*sazabi_test.quotaError", verbose: "quota of 3 exceeded (limit set by plan)"},
+	}
+	entries := c.Entries()
+	if len(entries) != 4 {
+		t.Fatalf("captured %d entries, want 4", len(entries))
+	}
+	for i, tt := range tests {
+		e := entries[i]
+		got := make([]string, 3)
+		for j, key := range []string{sazabi.ErrorKey, sazabi.ErrorTypeKey, sazabi.ErrorVerboseKey} {
+			got[j], _ = e.Str(key)
+		}
+		if want := []string{tt.error, tt.errorType, tt.verbose}; strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("entry %q error fields = %q, want %q", tt.msg, got, want)
+		}
+	}
+	if user, _ := entries[1].Str("user"); user != "alice" || entries[1].Level.String() != "error" {
+		t.Errorf("ErrorErr entry = %+v, want Error level with the key-value pairs", entries[1])
+	}
+	for _, e := range entries[:2] {
+		if !strings.HasSuffix(e.Caller.File, "errors_test.go") {
+			t.Errorf("entry %q caller = %s, want errors_test.go", e.Message, e.Caller.TrimmedPath())
+		}
+	}
+	for _, e := range entries[2:] {
+		for _, key := range []string{sazabi.ErrorKey, sazabi.ErrorTypeKey, sazabi.ErrorVerboseKey} {
+			if _, ok := e.Field(key); ok {
+				t.Errorf("entry %q has field %s for a nil error", e.Message, key)
+			}
+		}
+	}
+}
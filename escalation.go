@@ -0,0 +1,234 @@
+package sazabi
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Keys of the fields written by WithErrorEscalation.
+const (
+	EscalatedKey        = "escalated"         // Marks escalation entries and their summaries
+	ErrorFingerprintKey = "error_fingerprint" // Identifies the repeated error
+)
+
+// EscalationSummaryMessage is the message of the entry written when the suppression
+// of an escalated error ends.
+const EscalationSummaryMessage = "escalated error suppression ended"
+
+// WithErrorEscalation escalates errors that keep recurring. Error entries are
+// fingerprinted by logger name, message and error text; when threshold entries with
+// the same fingerprint are written within window, the last of them is written with
+// EscalatedKey=true, ErrorFingerprintKey, the count and the first and last times.
+// Further entries with that fingerprint are then suppressed for a cooldown of window,
+// after which an EscalationSummaryMessage entry reports how many were suppressed. The
+// summary is written with the next Error entry or on Sync. Fatal, Panic and DPanic
+// entries are never suppressed, and neither are those of EmitTestEntries. Zero or less
+// disables escalation, the default.
+func WithErrorEscalation(threshold int, window time.Duration) Option {
+	return func(o *options) {
+		o.escalationThreshold = threshold
+		o.escalationWindow = window
+	}
+}
+
+// errorRun tracks the recent entries of an error fingerprint.
+type errorRun struct {
+	ent         zapcore.Entry // Latest entry, naming the logger and message of summaries
+	fingerprint string
+	count       int       // Entries since first
+	first, last time.Time // Times of the first and latest entries
+	until       time.Time // End of the suppression, zero until escalated
+	suppressed  int       // Entries suppressed since the escalation
+}
+
+// escalationTracker counts the error fingerprints of a logger.
+type escalationTracker struct {
+	threshold int
+	window    time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	runs      map[string]*errorRun
+	lastSweep time.Time
+}
+
+// Decisions of escalationTracker.observe.
+const (
+	errorWrite = iota
+	errorEscalate
+	errorSuppress
+)
+
+// observe records an entry with the fingerprint key at now, returning the decision
+// for the entry, the run it belongs to, and the summaries of the runs whose
+// suppression has ended.
+func (t *escalationTracker) observe(key string, ent zapcore.Entry) (int, errorRun, []errorRun) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	summaries := t.sweepLocked(now, false)
+	r := t.runs[key]
+	if r != nil && !r.until.IsZero() {
+		if now.Before(r.until) {
+			r.suppressed++
+			r.last = now
+			return errorSuppress, *r, summaries
+		}
+		summaries = append(summaries, *r)
+		r = nil
+	}
+	if r == nil || now.Sub(r.first) > t.window {
+		r = &errorRun{fingerprint: fingerprint(key), first: now}
+		t.runs[key] = r
+	}
+	r.ent = ent
+	r.count++
+	r.last = now
+	if r.count < t.threshold {
+		return errorWrite, *r, summaries
+	}
+	r.until = now.Add(t.window)
+	return errorEscalate, *r, summaries
+}
+
+// sweep removes the runs that have ended, returning those whose suppression ended.
+func (t *escalationTracker) sweep() []errorRun {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.sweepLocked(t.now(), true)
+}
+
+// sweepLocked removes the runs that have ended at now, at most once per window unless
+// forced, returning those whose suppression ended. t.mu must be held.
+func (t *escalationTracker) sweepLocked(now time.Time, force bool) []errorRun {
+	if !force && now.Sub(t.lastSweep) < t.window {
+		return nil
+	}
+	t.lastSweep = now
+	var ended []errorRun
+	for key, r := range t.runs {
+		switch {
+		case !r.until.IsZero() && !now.Before(r.until):
+			ended = append(ended, *r)
+			delete(t.runs, key)
+		case r.until.IsZero() && now.Sub(r.first) > t.window:
+			delete(t.runs, key)
+		}
+	}
+	return ended
+}
+
+// escalationCore applies an escalationTracker to the Error entries it writes.
+// Summaries are written to base, the core without the fields added through With.
+type escalationCore struct {
+	zapcore.Core
+	base    zapcore.Core
+	tracker *escalationTracker
+}
+
+// newEscalationCore wraps core with the error escalation of o, or returns core when
+// escalation is disabled. It fails on a threshold without a positive window.
+func newEscalationCore(core zapcore.Core, o *options) (zapcore.Core, error) {
+	if o.escalationThreshold <= 0 {
+		return core, nil
+	}
+	if o.escalationWindow <= 0 {
+		return nil, fmt.Errorf("%w: error escalation window %s", ErrInvalidInterval, o.escalationWindow)
+	}
+	tracker := &escalationTracker{
+		threshold: o.escalationThreshold,
+		window:    o.escalationWindow,
+		now:       time.Now,
+		runs:      make(map[string]*errorRun),
+	}
+	return &escalationCore{Core: core, base: core, tracker: tracker}, nil
+}
+
+// With implements zapcore.Core.
+func (c *escalationCore) With(fields []zapcore.Field) zapcore.Core {
+	return &escalationCore{Core: c.Core.With(fields), base: c.base, tracker: c.tracker}
+}
+
+// Check implements zapcore.Core.
+func (c *escalationCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *escalationCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level != zapcore.ErrorLevel || hasKey(fields, SelfTestKey) {
+		return c.Core.Write(ent, fields)
+	}
+
+	key := ent.LoggerName + "\x00" + ent.Message + "\x00" + errorText(fields)
+	decision, run, summaries := c.tracker.observe(key, ent)
+	c.summarize(summaries)
+	switch decision {
+	case errorSuppress:
+		return nil
+	case errorEscalate:
+		escalated := make([]zapcore.Field, len(fields), len(fields)+5)
+		copy(escalated, fields)
+		escalated = append(escalated,
+			zap.Bool(EscalatedKey, true),
+			zap.String(ErrorFingerprintKey, run.fingerprint),
+			zap.Int("count", run.count),
+			zap.Time("first_seen", run.first),
+			zap.Time("last_seen", run.last),
+		)
+		return c.Core.Write(ent, escalated)
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// Sync implements zapcore.Core, writing the summaries of the suppressions that ended.
+func (c *escalationCore) Sync() error {
+	c.summarize(c.tracker.sweep())
+	return c.Core.Sync()
+}
+
+// summarize writes an EscalationSummaryMessage entry for each run.
+func (c *escalationCore) summarize(runs []errorRun) {
+	for _, r := range runs {
+		c.base.Write(zapcore.Entry{
+			Level:      zapcore.ErrorLevel,
+			Time:       c.tracker.now(),
+			LoggerName: r.ent.LoggerName,
+			Message:    EscalationSummaryMessage,
+		}, []zapcore.Field{
+			zap.Bool(EscalatedKey, true),
+			zap.String(ErrorFingerprintKey, r.fingerprint),
+			zap.String("escalated_message", r.ent.Message),
+			zap.Int("suppressed", r.suppressed),
+			zap.Time("first_seen", r.first),
+			zap.Time("last_seen", r.last),
+		})
+	}
+}
+
+// errorText returns the text of the ErrorKey field of fields, or "".
+func errorText(fields []zapcore.Field) string {
+	for _, f := range fields {
+		if f.Key != ErrorKey {
+			continue
+		}
+		switch f.Type {
+		case zapcore.ErrorType:
+			if err, ok := f.Interface.(error); ok && err != nil {
+				return err.Error()
+			}
+		case zapcore.StringType:
+			return f.String
+		}
+	}
+	return ""
+}
@@ -0,0 +1,188 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newEscalationLogger returns a logger escalating after threshold errors per minute,
+// driven by clock, and the capture of its entries.
+func newEscalationLogger(t *testing.T, threshold int, clock *fakeClock) (*zap.SugaredLogger, *Capture) {
+	c := &Capture{}
+	core, err := newEscalationCore(c.core(), &options{escalationThreshold: threshold, escalationWindow: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	core.(*escalationCore).tracker.now = clock.now
+	return zap.New(core).Sugar(), c
+}
+
+func TestErrorEscalation(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_000_000, 0)}
+	log, c := newEscalationLogger(t, 3, clock)
+	boom := errors.New("connection refused")
+
+	for i := 0; i < 3; i++ {
+		log.Errorw("query failed", "error", boom)
+		clock.advance(time.Second)
+	}
+
+	entries := c.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("%d entries written, want 3", len(entries))
+	}
+	if _, ok := entries[1].Field(EscalatedKey); ok {
+		t.Error("the second entry is escalated, want only the third")
+	}
+	last := entries[2]
+	if f, ok := last.Field(EscalatedKey); !ok || f.Integer != 1 {
+		t.Errorf("the third entry is not escalated: %v", last.Fields)
+	}
+	if count, _ := last.Int("count"); count != 3 {
+		t.Errorf("count = %d, want 3", count)
+	}
+	if fp, _ := last.Str(ErrorFingerprintKey); fp == "" {
+		t.Error("the escalated entry has no fingerprint")
+	}
+	first, _ := last.Field("first_seen")
+	seen, _ := last.Field("last_seen")
+	if time.Duration(seen.Integer-first.Integer) != 2*time.Second {
+		t.Errorf("first_seen and last_seen are %s apart, want 2s", time.Duration(seen.Integer-first.Integer))
+	}
+	if last.Err() != boom {
+		t.Error("the escalated entry lost its error")
+	}
+}
+
+func TestErrorEscalationSuppression(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_000_000, 0)}
+	log, c := newEscalationLogger(t, 2, clock)
+
+	for i := 0; i < 7; i++ {
+		log.Errorw("query failed", "error", "timeout")
+		clock.advance(time.Second)
+	}
+	if n := len(c.Entries()); n != 2 {
+		t.Fatalf("%d entries written during the cooldown, want 2", n)
+	}
+
+	clock.advance(time.Minute)
+	log.Errorw("query failed", "error", "timeout")
+
+	entries := c.Entries()
+	if len(entries) != 4 {
+		t.Fatalf("%d entries written, want the summary and the new entry", len(entries))
+	}
+	summary := entries[2]
+	if summary.Message != EscalationSummaryMessage {
+		t.Fatalf("entry = %q, want the summary", summary.Message)
+	}
+	if suppressed, _ := summary.Int("suppressed"); suppressed != 5 {
+		t.Errorf("suppressed = %d, want 5", suppressed)
+	}
+	if msg, _ := summary.Str("escalated_message"); msg != "query failed" {
+		t.Errorf("escalated_message = %q, want the original message", msg)
+	}
+	escalatedFP, _ := entries[1].Str(ErrorFingerprintKey)
+	if fp, _ := summary.Str(ErrorFingerprintKey); fp != escalatedFP {
+		t.Errorf("summary fingerprint = %q, want the escalated one %q", fp, escalatedFP)
+	}
+	if _, ok := entries[3].Field(EscalatedKey); ok {
+		t.Error("the entry after the cooldown is escalated again at once")
+	}
+}
+
+func TestErrorEscalationSummaryOnSync(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_000_000, 0)}
+	log, c := newEscalationLogger(t, 1, clock)
+
+	log.Error("disk full")
+	log.Error("disk full")
+	log.Sync()
+	if n := len(c.Entries()); n != 1 {
+		t.Fatalf("%d entries written before the cooldown ended, want 1", n)
+	}
+
+	clock.advance(2 * time.Minute)
+	log.Sync()
+	entries := c.Entries()
+	if len(entries) != 2 || entries[1].Message != EscalationSummaryMessage {
+		t.Fatalf("entries = %v, want the summary written on Sync", entries)
+	}
+	if suppressed, _ := entries[1].Int("suppressed"); suppressed != 1 {
+		t.Errorf("suppressed = %d, want 1", suppressed)
+	}
+}
+
+func TestErrorEscalationIndependentFingerprints(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_000_000, 0)}
+	log, c := newEscalationLogger(t, 3, clock)
+
+	for i := 0; i < 3; i++ {
+		log.Errorw("query failed", "error", "timeout")
+		log.Errorw("query failed", "error", "connection reset")
+		log.Named("cache").Errorw("query failed", "error", "timeout")
+		log.Error("disk full")
+	}
+	log.Errorw("query failed", "error", "timeout")
+
+	entries := c.Entries()
+	if len(entries) != 12 {
+		t.Errorf("%d entries written, want 12 with only the last one suppressed", len(entries))
+	}
+	escalated := 0
+	for _, e := range entries {
+		if _, ok := e.Field(EscalatedKey); ok {
+			escalated++
+		}
+	}
+	if escalated != 4 {
+		t.Errorf("%d entries escalated, want one per fingerprint", escalated)
+	}
+}
+
+func TestErrorEscalationWindow(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_000_000, 0)}
+	log, c := newEscalationLogger(t, 3, clock)
+
+	for i := 0; i < 6; i++ {
+		log.Error("slow request")
+		clock.advance(40 * time.Second)
+	}
+
+	for _, e := range c.Entries() {
+		if _, ok := e.Field(EscalatedKey); ok {
+			t.Fatalf("entries spread beyond the window were escalated: %v", e.Fields)
+		}
+	}
+}
+
+func TestErrorEscalationExemptLevels(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_000_000, 0)}
+	log, c := newEscalationLogger(t, 1, clock)
+
+	for i := 0; i < 3; i++ {
+		log.DPanic("invariant broken")
+		log.Warn("retrying")
+		func() {
+			defer func() { recover() }()
+			log.Panic("corrupted state")
+		}()
+	}
+
+	if n := len(c.Entries()); n != 9 {
+		t.Errorf("%d entries written, want every DPanic, Warn and Panic entry", n)
+	}
+}
+
+func TestErrorEscalationInvalidWindow(t *testing.T) {
+	if _, err := New(ProductionEnvName, WithErrorEscalation(3, 0)); !errors.Is(err, ErrInvalidInterval) {
+		t.Errorf("New() error = %v, want ErrInvalidInterval", err)
+	}
+}
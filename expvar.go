@@ -0,0 +1,127 @@
+package sazabi
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ExpvarName is the name of the expvar.Map published by PublishExpvars.
+const ExpvarName = "sazabi"
+
+// Counters of the global logger, kept across re-initializations.
+var (
+	levelCounts        [zapcore.FatalLevel - zapcore.DebugLevel + 1]int64 // Entries written, per level
+	sampledCount       int64                                              // Entries dropped by sampling
+	droppedCount       int64                                              // Entries that failed to encode or write
+	outputDroppedCount int64                                              // Entries dropped by the network outputs, also in droppedCount
+	lastErrorTime      int64                                              // Unix time in nanoseconds of the last Error entry or above
+)
+
+// countLevel counts an entry of level written by the global logger at t.
+func countLevel(level zapcore.Level, t time.Time) {
+	if i := int(level - zapcore.DebugLevel); i >= 0 && i < len(levelCounts) {
+		atomic.AddInt64(&levelCounts[i], 1)
+	}
+	if level >= zapcore.ErrorLevel {
+		atomic.StoreInt64(&lastErrorTime, t.UnixNano())
+	}
+}
+
+// countOutputDropped counts n entries dropped by a network output of the global logger,
+// such as those that did not fit in its queue.
+func countOutputDropped(n int) {
+	atomic.AddInt64(&droppedCount, int64(n))
+	atomic.AddInt64(&outputDroppedCount, int64(n))
+}
+
+// countSampled is the sampling hook of the global logger, counting dropped entries.
+func countSampled(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+	if dec&zapcore.LogDropped != 0 {
+		atomic.AddInt64(&sampledCount, 1)
+	}
+}
+
+// publishExpvars registers the map once per process.
+var publishExpvars sync.Once
+
+// PublishExpvars registers an expvar.Map named ExpvarName, served by the expvar
+// handler at /debug/vars, describing the global logger:
+//   - entries: entries written since the process started, per level
+//   - sampled: entries dropped by sampling
+//   - dropped: entries that failed to encode or write
+//   - output_dropped: entries dropped by the network outputs, such as Kafka or
+//     Elasticsearch, while their destination was slow or unreachable; also counted
+//     under dropped
+//   - last_error: time of the last entry at Error level or above, in RFC 3339, or ""
+//   - sinks: health of the monitored outputs (see Health), by name
+//   - level: current level
+//   - ring_buffer: size and occupancy of the WithRingBuffer buffer
+//
+// Values are read from the counters when the map is served. Calling PublishExpvars
+// again, or re-initializing, registers nothing more; when another package already
+// published a variable named ExpvarName, it is left alone.
+func PublishExpvars() {
+	publishExpvars.Do(func() {
+		if expvar.Get(ExpvarName) != nil {
+			return
+		}
+		m := expvar.NewMap(ExpvarName)
+		m.Set("entries", expvar.Func(levelCountsVar))
+		m.Set("sampled", expvar.Func(func() interface{} { return atomic.LoadInt64(&sampledCount) }))
+		m.Set("dropped", expvar.Func(func() interface{} { return atomic.LoadInt64(&droppedCount) }))
+		m.Set("output_dropped", expvar.Func(func() interface{} { return atomic.LoadInt64(&outputDroppedCount) }))
+		m.Set("last_error", expvar.Func(lastErrorVar))
+		m.Set("sinks", expvar.Func(sinksVar))
+		m.Set("level", expvar.Func(levelVar))
+		m.Set("ring_buffer", expvar.Func(ringBufferVar))
+	})
+}
+
+// levelCountsVar returns the entries written per level name.
+func levelCountsVar() interface{} {
+	counts := make(map[string]int64, len(levelCounts))
+	for i := range levelCounts {
+		counts[(zapcore.DebugLevel + zapcore.Level(i)).String()] = atomic.LoadInt64(&levelCounts[i])
+	}
+	return counts
+}
+
+// lastErrorVar returns the time of the last entry at Error level or above, or "" when
+// there was none.
+func lastErrorVar() interface{} {
+	t := atomic.LoadInt64(&lastErrorTime)
+	if t == 0 {
+		return ""
+	}
+	return time.Unix(0, t).UTC().Format(time.RFC3339Nano)
+}
+
+// sinksVar returns whether each monitored output is healthy.
+func sinksVar() interface{} {
+	sinks := make(map[string]bool)
+	for _, h := range Health() {
+		sinks[h.Name] = h.Healthy
+	}
+	return sinks
+}
+
+// levelVar returns the current level of the global logger, or "" before initialization.
+func levelVar() interface{} {
+	if in := loadInstance(); in != nil {
+		return in.config.Level.String()
+	}
+	return ""
+}
+
+// ringBufferVar returns the size of the ring buffer and the number of entries in it.
+func ringBufferVar() interface{} {
+	size, used := 0, 0
+	if r := currentRing(); r != nil {
+		size, used = r.occupancy()
+	}
+	return map[string]int{"size": size, "used": used}
+}
@@ -0,0 +1,169 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// sazabiVars returns the variables published by PublishExpvars, decoded.
+func sazabiVars(t *testing.T) map[string]json.RawMessage {
+	t.Helper()
+
+	v := expvar.Get(sazabi.ExpvarName)
+	if v == nil {
+		t.Fatalf("expvar %q is not published", sazabi.ExpvarName)
+	}
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(v.String()), &vars); err != nil {
+		t.Fatalf("expvar %q = %s: %v", sazabi.ExpvarName, v, err)
+	}
+	return vars
+}
+
+// decodeVar decodes the variable key of vars into v.
+func decodeVar(t *testing.T, vars map[string]json.RawMessage, key string, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(vars[key], v); err != nil {
+		t.Fatalf("expvar %s = %s: %v", key, vars[key], err)
+	}
+}
+
+func TestPublishExpvars(t *testing.T) {
+	restoreDefault(t)
+	sazabi.PublishExpvars()
+	sazabi.PublishExpvars() // Registering again must not panic
+
+	captureStderr(t, func() {
+		initializeFile(t, sazabi.ProductionEnvName, sazabi.WithRingBuffer(10))
+	})
+	sazabi.PublishExpvars()
+
+	var before struct {
+		Entries map[string]int64 `json:"entries"`
+		Sampled int64            `json:"sampled"`
+	}
+	decodeVar(t, sazabiVars(t), "entries", &before.Entries)
+	decodeVar(t, sazabiVars(t), "sampled", &before.Sampled)
+
+	for i := 0; i < 150; i++ {
+		sazabi.Info("expvar entry")
+	}
+	sazabi.Warn("expvar warning")
+	sazabi.Debug("expvar debug") // Below the level
+
+	vars := sazabiVars(t)
+	var entries map[string]int64
+	var sampled int64
+	decodeVar(t, vars, "entries", &entries)
+	decodeVar(t, vars, "sampled", &sampled)
+	info, dropped := entries["info"]-before.Entries["info"], sampled-before.Sampled
+	if info+dropped != 150 || dropped == 0 {
+		t.Errorf("info entries grew by %d and sampled by %d, want 150 entries split between them", info, dropped)
+	}
+	if warn := entries["warn"] - before.Entries["warn"]; warn != 1 {
+		t.Errorf("warn entries grew by %d, want 1", warn)
+	}
+	if debug := entries["debug"] - before.Entries["debug"]; debug != 0 {
+		t.Errorf("debug entries grew by %d, want 0", debug)
+	}
+
+	var level string
+	decodeVar(t, vars, "level", &level)
+	if level != "info" {
+		t.Errorf("level = %q, want info", level)
+	}
+	var ring map[string]int
+	decodeVar(t, vars, "ring_buffer", &ring)
+	if ring["size"] != 10 || ring["used"] != 10 {
+		t.Errorf("ring_buffer = %v, want a full buffer of 10", ring)
+	}
+	var sinks map[string]bool
+	decodeVar(t, vars, "sinks", &sinks)
+	if len(sinks) != 0 {
+		t.Errorf("sinks = %v, want none without monitored outputs", sinks)
+	}
+}
+
+func TestPublishExpvarsSinks(t *testing.T) {
+	restoreDefault(t)
+	sazabi.PublishExpvars()
+
+	captureStderr(t, func() {
+		initializeFile(t, sazabi.ProductionEnvName,
+			sazabi.WithOptionalSink(sazabi.WithOutput("/nonexistent/dir/app.log")))
+	})
+
+	var sinks map[string]bool
+	decodeVar(t, sazabiVars(t), "sinks", &sinks)
+	if healthy, ok := sinks["/nonexistent/dir/app.log"]; !ok || healthy {
+		t.Errorf("sinks = %v, want the optional output that failed to open unhealthy", sinks)
+	}
+}
+
+func TestPublishExpvarsHTTP(t *testing.T) {
+	restoreDefault(t)
+	sazabi.PublishExpvars()
+	srv := httptest.NewServer(expvar.Handler())
+	defer srv.Close()
+
+	// served returns the variables of sazabi served at /debug/vars.
+	served := func() (vars struct {
+		Entries       map[string]int64 `json:"entries"`
+		Dropped       int64            `json:"dropped"`
+		OutputDropped int64            `json:"output_dropped"`
+		LastError     string           `json:"last_error"`
+	}) {
+		t.Helper()
+
+		resp, err := http.Get(srv.URL + "/debug/vars")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var all map[string]json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&all); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(all[sazabi.ExpvarName], &vars); err != nil {
+			t.Fatalf("%s = %s: %v", sazabi.ExpvarName, all[sazabi.ExpvarName], err)
+		}
+		return vars
+	}
+
+	captureStderr(t, func() {
+		initializeFile(t, sazabi.ProductionEnvName,
+			sazabi.WithFluentForward("127.0.0.1:1", "app", sazabi.FluentBufferSize(1))) // Drops every entry
+		sazabi.Sync()
+		before := served()
+
+		start := time.Now()
+		sazabi.Info("served entry")
+		sazabi.Error("served error")
+		sazabi.Error("served error")
+		after := served()
+
+		if info := after.Entries["info"] - before.Entries["info"]; info != 1 {
+			t.Errorf("info entries grew by %d, want 1", info)
+		}
+		if errs := after.Entries["error"] - before.Entries["error"]; errs != 2 {
+			t.Errorf("error entries grew by %d, want 2", errs)
+		}
+		if dropped := after.OutputDropped - before.OutputDropped; dropped != 3 || after.Dropped-before.Dropped != 3 {
+			t.Errorf("output_dropped grew by %d and dropped by %d, want the 3 entries dropped by the output", dropped, after.Dropped-before.Dropped)
+		}
+		last, err := time.Parse(time.RFC3339Nano, after.LastError)
+		if err != nil || last.Before(start) {
+			t.Errorf("last_error = %q, want the time of the last error", after.LastError)
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package sazabi
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Extension is a handle to an extension registered on the global logger. Extensions
+// are kept in a registry and re-applied whenever the global logger is rebuilt, by
+// Initialize or by a runtime setting, until they are removed.
+type Extension struct {
+	id uint64
+}
+
+// extension is a registry entry. Exactly one of its settings is in use, with the
+// attachment settings of core.
+type extension struct {
+	id           uint64
+	hook         *guardedHook           // Called for every written entry
+	observer     *guardedHook           // Hook of RegisterHook, called by core
+	core         zapcore.Core           // Additional destination for entries
+	position     int                    // Position of core in the pipeline
+	name         string                 // Name of core in the pipeline
+	closer       io.Closer              // Closed by Shutdown, when core implements it
+	closeTimeout time.Duration          // Time Shutdown waits for closer
+	keys         []string               // Keys whose values are redacted
+	fields       []zapcore.Field        // Fields added to every entry
+	module       string                 // Logger name the module level applies to
+	moduleLevel  zapcore.Level          // Minimum level for the module
+	fatalHook    zapcore.CheckWriteHook // Action taken after Fatal entries
+	dynamic      *dynamicField          // Field computed for every entry
+}
+
+// Extension registry, guarded by initMu.
+var (
+	extensions      []*extension
+	nextExtensionID uint64
+)
+
+// AddHook registers hook to be called with every entry written by the global logger.
+// Entries logged while a hook is running on the same goroutine bypass the hooks, so a
+// hook that logs cannot recurse; the first such entry triggers a HookReentryMessage
+// warning naming the place the hook was registered.
+func AddHook(hook func(zapcore.Entry) error) *Extension {
+	return register(&extension{hook: newGuardedHook(hook, callerLocation(2))})
+}
+
+// AddCore registers core as an additional destination of the global logger, for example
+// to feed a metrics pipeline. By default it is attached after StageRedaction, so that
+// it receives the entries written to the outputs, with redacted keys and dynamic fields
+// applied; CoreBefore and CoreAfter attach it elsewhere. When core implements
+// io.Closer, Shutdown closes it and removes it.
+func AddCore(core zapcore.Core, opts ...CoreOption) *Extension {
+	ext := &extension{position: afterRedaction, name: fmt.Sprintf("%T", core)}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(ext)
+		}
+	}
+	ext.closer, _ = core.(io.Closer)
+	ext.core = wrapExtraCore(core, ext.position)
+	return register(ext)
+}
+
+// AddRedactedKeys replaces the value of fields named by keys (case-insensitively) with
+// RedactedValue. Redaction applies to every logger built by this package.
+func AddRedactedKeys(keys ...string) *Extension {
+	lowered := make([]string, len(keys))
+	for i, k := range keys {
+		lowered[i] = strings.ToLower(k)
+	}
+	return register(&extension{keys: lowered})
+}
+
+// AddGlobalFields adds the key-value pairs to every entry of the global logger.
+// Pairs follow the conventions of Infow.
+func AddGlobalFields(keysValues ...interface{}) *Extension {
+	return register(&extension{fields: pairFields(keysValues)})
+}
+
+// pairFields converts key-value pairs, following the conventions of Infow, to fields.
+func pairFields(keysValues []interface{}) []zapcore.Field {
+	var fields []zapcore.Field
+	for i := 0; i < len(keysValues); i += 2 {
+		if i+1 == len(keysValues) {
+			fields = append(fields, zap.Any("!BADKEY", keysValues[i])) // Dangling value, as zap reports it
+			break
+		}
+		fields = append(fields, zap.Any(fmt.Sprint(keysValues[i]), keysValues[i+1]))
+	}
+	return fields
+}
+
+// SetModuleLevel raises the minimum level of the loggers named module, and of the loggers
+// named below it ("module.sub"), to level. It cannot lower the level below the level of
+// the global logger.
+func SetModuleLevel(module, level string) (*Extension, error) {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	return register(&extension{module: module, moduleLevel: lvl}), nil
+}
+
+// SetFatalBehavior replaces the action taken after Fatal entries of the global logger,
+// taking precedence over WithFatalHook. The most recently registered behavior wins.
+func SetFatalBehavior(hook zapcore.CheckWriteHook) *Extension {
+	return register(&extension{fatalHook: hook})
+}
+
+// Remove unregisters the extension and rebuilds the global logger without it.
+// Removing an extension twice is harmless.
+func (e *Extension) Remove() {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	for i, ext := range extensions {
+		if ext.id == e.id {
+			extensions = append(extensions[:i:i], extensions[i+1:]...)
+			break
+		}
+	}
+	extensionsChanged()
+}
+
+// register adds ext to the registry and rebuilds the global logger with it.
+func register(ext *extension) *Extension {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	nextExtensionID++
+	ext.id = nextExtensionID
+	extensions = append(extensions, ext)
+	extensionsChanged()
+	return &Extension{id: ext.id}
+}
+
+// extensionsChanged refreshes the state derived from the registry and republishes the
+// global logger, if any. initMu must be held.
+func extensionsChanged() {
+	keys := make(map[string]struct{})
+	var (
+		hooks   []*guardedHook
+		dynamic []dynamicField
+	)
+	for _, ext := range extensions {
+		for _, k := range ext.keys {
+			keys[k] = struct{}{}
+		}
+		if ext.hook != nil {
+			hooks = append(hooks, ext.hook)
+		}
+		if ext.observer != nil {
+			hooks = append(hooks, ext.observer)
+		}
+		if ext.dynamic != nil {
+			dynamic = append(dynamic, *ext.dynamic)
+		}
+	}
+	redactedKeys.Store(keys)
+	dynamicFields.Store(dynamic)
+	activeHooks.Store(hooks)
+
+	if current := loadInstance(); current != nil {
+		next := *current
+		publish(&next)
+	}
+}
+
+// applyExtensions returns log with the registered extensions applied. initMu must be held.
+func applyExtensions(log *zap.Logger) *zap.Logger {
+	if len(extensions) == 0 {
+		return log
+	}
+
+	var (
+		hooks    []func(zapcore.Entry) error
+		cores    []zapcore.Core // Attached before filtering
+		filtered []zapcore.Core // Attached after filtering
+		fields   []zapcore.Field
+		modules  []*extension
+		opts     []zap.Option
+	)
+	for _, ext := range extensions {
+		switch {
+		case ext.hook != nil:
+			hooks = append(hooks, ext.hook.run)
+		case ext.core != nil && ext.position == beforeFiltering:
+			cores = append(cores, ext.core)
+		case ext.core != nil:
+			filtered = append(filtered, ext.core)
+		case ext.fields != nil:
+			fields = append(fields, ext.fields...)
+		case ext.module != "":
+			modules = append(modules, ext)
+		case ext.fatalHook != nil:
+			opts = append(opts, zap.WithFatalHook(ext.fatalHook))
+		}
+	}
+
+	opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if len(filtered) > 0 {
+			core = teeFiltered(core, filtered)
+		}
+		if len(modules) > 0 {
+			core = &moduleLevelCore{Core: core, modules: modules}
+		}
+		if len(cores) > 0 {
+			core = zapcore.NewTee(append([]zapcore.Core{core}, cores...)...)
+		}
+		return core
+	}))
+	if len(hooks) > 0 {
+		opts = append(opts, zap.Hooks(hooks...))
+	}
+	if len(fields) > 0 {
+		opts = append(opts, zap.Fields(fields...))
+	}
+	return log.WithOptions(opts...)
+}
+
+// globalFields returns the fields added by the registered extensions. initMu must be
+// held.
+func globalFields() []zapcore.Field {
+	var fields []zapcore.Field
+	for _, ext := range extensions {
+		fields = append(fields, ext.fields...)
+	}
+	return fields
+}
+
+// moduleLevelCore drops entries of named loggers below the level of their module.
+type moduleLevelCore struct {
+	zapcore.Core
+	modules []*extension
+}
+
+// With implements zapcore.Core.
+func (c *moduleLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &moduleLevelCore{Core: c.Core.With(fields), modules: c.modules}
+}
+
+// Check implements zapcore.Core. It delegates to the wrapped core, so sampling and the
+// cores below keep making their own decisions.
+func (c *moduleLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if lvl, ok := c.moduleLevel(ent.LoggerName); ok && ent.Level < lvl {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// moduleLevel returns the level of the most specific module matching name.
+func (c *moduleLevelCore) moduleLevel(name string) (zapcore.Level, bool) {
+	var (
+		best  zapcore.Level
+		depth = -1
+	)
+	for _, m := range c.modules {
+		if name != m.module && !strings.HasPrefix(name, m.module+".") {
+			continue
+		}
+		if len(m.module) >= depth { // Later registrations win
+			best, depth = m.moduleLevel, len(m.module)
+		}
+	}
+	return best, depth >= 0
+}
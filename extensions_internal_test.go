@@ -0,0 +1,43 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestModuleLevelCore(t *testing.T) {
+	inner, logs := observer.New(zapcore.DebugLevel)
+	core := &moduleLevelCore{Core: inner, modules: []*extension{
+		{module: "db", moduleLevel: zapcore.WarnLevel},
+		{module: "db.pool", moduleLevel: zapcore.ErrorLevel},
+	}}
+	log := zap.New(core)
+
+	log.Named("db").Info("db info")           // Dropped
+	log.Named("db").Warn("db warn")           // Kept
+	log.Named("db").Named("pool").Warn("pw")  // Dropped by the more specific module
+	log.Named("db").Named("cache").Warn("cw") // Kept, inherits db
+	log.Named("dbx").Info("other module")     // Kept, not below db
+	log.Info("unnamed")                       // Kept
+
+	var got []string
+	for _, e := range logs.AllUntimed() {
+		got = append(got, e.Message)
+	}
+	want := []string{"db warn", "cw", "other module", "unnamed"}
+	if len(got) != len(want) {
+		t.Fatalf("written = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("written = %q, want %q", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,110 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestExtensionsSurviveRebuild(t *testing.T) {
+	var hooked int64
+	hook := sazabi.AddHook(func(ent zapcore.Entry) error {
+		if strings.HasPrefix(ent.Message, "extension entry") {
+			atomic.AddInt64(&hooked, 1)
+		}
+		return nil
+	})
+	defer hook.Remove()
+	keys := sazabi.AddRedactedKeys("Password")
+	defer keys.Remove()
+	fields := sazabi.AddGlobalFields("service", "billing")
+	defer fields.Remove()
+
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		sazabi.Infow("extension entry 1", "password", "hunter2")
+
+		sazabi.Initialize("development")
+		sazabi.Infow("extension entry 2", "PASSWORD", "hunter2")
+
+		if err := sazabi.SetCallerEnabled(false); err != nil {
+			t.Errorf("SetCallerEnabled(false) error = %v", err)
+		}
+		sazabi.Infow("extension entry 3", "password", "hunter2")
+	})
+
+	for _, message := range []string{"extension entry 1", "extension entry 2", "extension entry 3"} {
+		fields := entryFields(t, output, message)
+		for key, value := range fields {
+			if value == "hunter2" {
+				t.Errorf("entry %q leaks %s: %v", message, key, fields)
+			}
+		}
+		if fields["service"] != "billing" {
+			t.Errorf("entry %q service = %v, want billing", message, fields["service"])
+		}
+	}
+	if got := atomic.LoadInt64(&hooked); got != 3 {
+		t.Errorf("hook called %d times, want 3", got)
+	}
+}
+
+func TestExtensionRemove(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	extra := sazabi.AddCore(core)
+	keys := sazabi.AddRedactedKeys("token")
+
+	captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		sazabi.Infow("before remove", "token", "secret")
+		extra.Remove()
+		extra.Remove() // Removing twice is harmless
+		keys.Remove()
+		sazabi.Infow("after remove", "token", "secret")
+	})
+
+	if logs.FilterMessage("after remove").Len() != 0 {
+		t.Error("additional core received an entry after removal")
+	}
+	entries := logs.FilterMessage("before remove").AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("additional core received %d entries before removal, want 1", len(entries))
+	}
+	if got := entries[0].ContextMap()["token"]; got != sazabi.RedactedValue {
+		t.Errorf("additional core token = %v, want %q", got, sazabi.RedactedValue)
+	}
+}
+
+func TestExtensionFatalBehavior(t *testing.T) {
+	fatal := sazabi.SetFatalBehavior(zapcore.WriteThenGoexit)
+	defer fatal.Remove()
+
+	returned := false
+	captureStderr(t, func() {
+		sazabi.Initialize("development") // Without WithFatalHook, Fatal would exit the process
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			sazabi.Fatal("fatal entry")
+			returned = true
+		}()
+		<-done
+	})
+	if returned {
+		t.Error("Fatal returned, want the registered fatal behavior to end the goroutine")
+	}
+}
+
+func TestSetModuleLevelInvalid(t *testing.T) {
+	if _, err := sazabi.SetModuleLevel("db", "loud"); err == nil {
+		t.Error("SetModuleLevel() with an invalid level succeeded")
+	}
+}
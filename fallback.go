@@ -0,0 +1,176 @@
+package sazabi
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Messages of the entries emitted when an output fails over to the fallback and back.
+const (
+	OutputFailedMessage    = "log output failed, writing to fallback"
+	OutputRecoveredMessage = "log output recovered"
+)
+
+// Defaults for WithFallbackOutput.
+const (
+	defaultFallbackPath          = "stderr"
+	defaultFallbackProbeInterval = 5 * time.Second
+	fallbackNoticeInterval       = time.Minute // Minimum time between failure notices
+)
+
+// WithFallbackOutput keeps entries from being lost when an output starts failing.
+// Entries that cannot be written to an output are written to the fallback path
+// instead (stderr when path is empty), together with a rate-limited entry describing
+// the failure. The failed output is probed periodically with the next entry and used
+// again as soon as a write succeeds, which is announced by a recovery entry.
+// Transitions are reported by Health.
+func WithFallbackOutput(path string) Option {
+	return func(o *options) {
+		if path == "" {
+			path = defaultFallbackPath
+		}
+		o.fallbackPath = path
+	}
+}
+
+// WithFallbackProbeInterval sets how often a failed output is retried when
+// WithFallbackOutput is enabled. The default is five seconds.
+func WithFallbackProbeInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.fallbackProbeInterval = interval
+	}
+}
+
+// fallbackSyncer writes to a primary WriteSyncer and fails over to a fallback
+// WriteSyncer while the primary returns errors.
+type fallbackSyncer struct {
+	name          string              // Redacted name of the primary output
+	primary       zapcore.WriteSyncer // Output entries are normally written to
+	fallback      zapcore.WriteSyncer // Output used while the primary fails
+	enc           zapcore.Encoder     // Encoder for failure and recovery notices
+	probeInterval time.Duration       // Time between attempts to write to a failed primary
+	monitored     bool                // Whether transitions are reported by Health
+
+	mu         sync.Mutex
+	failing    bool      // Whether the primary is currently failing
+	failedAt   time.Time // Time the primary started failing
+	nextProbe  time.Time // Earliest time the primary is retried
+	lastNotice time.Time // Time the last failure notice was written
+	lastErr    error     // Last error returned by the primary
+	redirected int       // Entries written to the fallback since the failure
+}
+
+// newFallbackSyncer wraps primary so that it fails over to the fallback configured in o.
+func newFallbackSyncer(name string, primary zapcore.WriteSyncer, enc zapcore.Encoder, o *options) (zapcore.WriteSyncer, error) {
+	fallback, _, err := zap.Open(o.fallbackPath)
+	if err != nil {
+		return nil, err
+	}
+
+	probeInterval := o.fallbackProbeInterval
+	if probeInterval <= 0 {
+		probeInterval = defaultFallbackProbeInterval
+	}
+
+	s := &fallbackSyncer{
+		name:          name,
+		primary:       primary,
+		fallback:      fallback,
+		enc:           enc,
+		probeInterval: probeInterval,
+		monitored:     o.global,
+	}
+	s.report(nil)
+	return s, nil
+}
+
+// Write implements zapcore.WriteSyncer.
+func (s *fallbackSyncer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if !s.failing || !now.Before(s.nextProbe) {
+		_, err := s.primary.Write(p)
+		if err == nil {
+			if s.failing {
+				s.recover(now)
+			}
+			return len(p), nil
+		}
+		s.fail(now, err)
+	}
+
+	s.redirected++
+	if now.Sub(s.lastNotice) >= fallbackNoticeInterval {
+		s.lastNotice = now
+		s.notice(s.fallback, zapcore.Entry{Level: zapcore.ErrorLevel, Time: now, Message: OutputFailedMessage},
+			zap.String("output", s.name),
+			zap.String("error", s.lastErr.Error()),
+			zap.Int("entries_redirected", s.redirected),
+		)
+	}
+	return s.fallback.Write(p)
+}
+
+// Sync implements zapcore.WriteSyncer. Errors of a failing primary are not reported
+// since its entries are already being written to the fallback.
+func (s *fallbackSyncer) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.fallback.Sync()
+	if !s.failing {
+		if primaryErr := s.primary.Sync(); primaryErr != nil {
+			err = primaryErr
+		}
+	}
+	return err
+}
+
+// fail records a failed write to the primary. s.mu must be held.
+func (s *fallbackSyncer) fail(now time.Time, err error) {
+	if !s.failing {
+		s.failing = true
+		s.failedAt = now
+		s.report(err)
+	}
+	s.lastErr = err
+	s.nextProbe = now.Add(s.probeInterval)
+}
+
+// recover switches back to the primary and announces it there. s.mu must be held.
+func (s *fallbackSyncer) recover(now time.Time) {
+	s.notice(s.primary, zapcore.Entry{Level: zapcore.InfoLevel, Time: now, Message: OutputRecoveredMessage},
+		zap.String("output", s.name),
+		zap.Duration("down_for", now.Sub(s.failedAt)),
+		zap.Int("entries_redirected", s.redirected),
+	)
+
+	s.failing = false
+	s.redirected = 0
+	s.lastNotice = time.Time{}
+	s.report(nil)
+}
+
+// report records the state of the primary for Health, unless the syncer belongs to a
+// standalone logger.
+func (s *fallbackSyncer) report(err error) {
+	if s.monitored {
+		setHealth(s.name, err)
+	}
+}
+
+// notice encodes an entry produced by the syncer itself and writes it to ws.
+func (s *fallbackSyncer) notice(ws zapcore.WriteSyncer, ent zapcore.Entry, fields ...zapcore.Field) {
+	ent.LoggerName = "sazabi"
+	buf, err := s.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return
+	}
+	ws.Write(buf.Bytes())
+	buf.Free()
+}
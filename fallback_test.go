@@ -0,0 +1,127 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// flakySink is an output that fails on demand, registered under the "flaky" scheme.
+type flakySink struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	failing bool
+}
+
+func (s *flakySink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failing {
+		return 0, errors.New("no space left on device")
+	}
+	return s.buf.Write(p)
+}
+
+func (s *flakySink) Sync() error  { return nil }
+func (s *flakySink) Close() error { return nil }
+
+func (s *flakySink) setFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+func (s *flakySink) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+var (
+	flakySinks    sync.Map
+	registerFlaky sync.Once
+)
+
+// newFlakySink registers a fresh flakySink and returns it with its output path.
+func newFlakySink(t *testing.T) (*flakySink, string) {
+	registerFlaky.Do(func() {
+		err := zap.RegisterSink("flaky", func(u *url.URL) (zap.Sink, error) {
+			s, _ := flakySinks.Load(u.Host)
+			return s.(*flakySink), nil
+		})
+		if err != nil {
+			t.Fatalf("zap.RegisterSink() error = %v", err)
+		}
+	})
+
+	sink := &flakySink{}
+	flakySinks.Store(t.Name(), sink)
+	return sink, "flaky://" + url.PathEscape(t.Name())
+}
+
+func TestWithFallbackOutput(t *testing.T) {
+	primary, path := newFlakySink(t)
+	fallbackPath := filepath.Join(t.TempDir(), "fallback.log")
+
+	sazabi.Initialize(sazabi.ProductionEnvName,
+		sazabi.WithOutputPaths(path),
+		sazabi.WithFallbackOutput(fallbackPath),
+		sazabi.WithFallbackProbeInterval(20*time.Millisecond),
+	)
+
+	sazabi.Info("entry 1")
+	primary.setFailing(true)
+	sazabi.Info("entry 2")
+	sazabi.Info("entry 3")
+
+	health := sazabi.Health()
+	if len(health) != 1 || health[0].Healthy || !strings.Contains(health[0].LastError, "no space left") {
+		t.Errorf("Health() = %+v, want one unhealthy sink with the write error", health)
+	}
+
+	primary.setFailing(false)
+	time.Sleep(40 * time.Millisecond)
+	sazabi.Info("entry 4")
+	sazabi.Info("entry 5")
+
+	if health := sazabi.Health(); len(health) != 1 || !health[0].Healthy {
+		t.Errorf("Health() = %+v, want the sink to be healthy again", health)
+	}
+
+	fallback, err := os.ReadFile(fallbackPath)
+	if err != nil {
+		t.Fatalf("failed to read fallback output: %v", err)
+	}
+
+	for n := 1; n <= 5; n++ {
+		message := fmt.Sprintf("entry %d", n)
+		inPrimary := strings.Contains(primary.String(), message)
+		inFallback := strings.Contains(string(fallback), message)
+		if inPrimary == inFallback {
+			t.Errorf("%q should be written to exactly one output (primary=%t, fallback=%t)", message, inPrimary, inFallback)
+		}
+	}
+
+	if strings.Count(string(fallback), sazabi.OutputFailedMessage) != 1 {
+		t.Errorf("fallback should contain exactly one failure notice, got: %s", fallback)
+	}
+	recovery := lineContaining(primary.String(), sazabi.OutputRecoveredMessage)
+	if recovery == "" || !strings.Contains(recovery, `"entries_redirected": 2`) {
+		t.Errorf("primary should contain a recovery notice with the redirected count, got: %s", primary.String())
+	}
+}
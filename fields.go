@@ -0,0 +1,125 @@
+package sazabi
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Field is a strongly typed key-value pair, logged without reflection by the Fields
+// functions. It is the zap.Field type, so zap's field constructors can be used too.
+type Field = zap.Field
+
+// F returns the field best suited to the type of value: strings, integers of every
+// width, floats, booleans, time.Time, time.Duration, []byte, errors and fmt.Stringer
+// values get their dedicated zap field, a Secret its redacted form, and anything else
+// falls back to zap.Any.
+// Fields can be passed to the Fields functions, or among the key-value pairs of the
+// sugared functions.
+func F[T any](key string, value T) Field {
+	switch v := any(&value).(type) { // Switching on a pointer keeps value from escaping
+	case *string:
+		return zap.String(key, *v)
+	case *int:
+		return zap.Int(key, *v)
+	case *int8:
+		return zap.Int8(key, *v)
+	case *int16:
+		return zap.Int16(key, *v)
+	case *int32:
+		return zap.Int32(key, *v)
+	case *int64:
+		return zap.Int64(key, *v)
+	case *uint:
+		return zap.Uint(key, *v)
+	case *uint8:
+		return zap.Uint8(key, *v)
+	case *uint16:
+		return zap.Uint16(key, *v)
+	case *uint32:
+		return zap.Uint32(key, *v)
+	case *uint64:
+		return zap.Uint64(key, *v)
+	case *uintptr:
+		return zap.Uintptr(key, *v)
+	case *float32:
+		return zap.Float32(key, *v)
+	case *float64:
+		return zap.Float64(key, *v)
+	case *bool:
+		return zap.Bool(key, *v)
+	case *time.Time:
+		return zap.Time(key, *v)
+	case *time.Duration:
+		return zap.Duration(key, *v)
+	case *[]byte:
+		return zap.Binary(key, *v)
+	case *Secret:
+		return zap.String(key, v.String())
+	}
+	return zap.Any(key, value) // Errors, fmt.Stringer values and everything else
+}
+
+// DebugFields logs a debug message with typed fields using the global logger.
+func DebugFields(msg string, fields ...Field) {
+	typedLogger().Debug(msg, fields...) // Log debug message with typed fields
+}
+
+// InfoFields logs an info message with typed fields using the global logger.
+func InfoFields(msg string, fields ...Field) {
+	typedLogger().Info(msg, fields...) // Log info message with typed fields
+}
+
+// WarnFields logs a warning message with typed fields using the global logger.
+func WarnFields(msg string, fields ...Field) {
+	typedLogger().Warn(msg, fields...) // Log warning message with typed fields
+}
+
+// ErrorFields logs an error message with typed fields using the global logger.
+func ErrorFields(msg string, fields ...Field) {
+	typedLogger().Error(msg, fields...) // Log error message with typed fields
+}
+
+// FatalFields logs a fatal message with typed fields using the global logger.
+func FatalFields(msg string, fields ...Field) {
+	typedLogger().Fatal(msg, fields...) // Log fatal message with typed fields
+}
+
+// PanicFields logs a panic message with typed fields using the global logger.
+func PanicFields(msg string, fields ...Field) {
+	typedLogger().Panic(msg, fields...) // Log panic message with typed fields
+}
+
+// The F functions are short names of the Fields functions: DebugF(msg, fields...) is
+// DebugFields(msg, fields...). Each calls the typed logger itself, so that entries carry
+// the caller of the F function.
+
+// DebugF logs a debug message with typed fields using the global logger.
+func DebugF(msg string, fields ...Field) {
+	typedLogger().Debug(msg, fields...) // Log debug message with typed fields
+}
+
+// InfoF logs an info message with typed fields using the global logger.
+func InfoF(msg string, fields ...Field) {
+	typedLogger().Info(msg, fields...) // Log info message with typed fields
+}
+
+// WarnF logs a warning message with typed fields using the global logger.
+func WarnF(msg string, fields ...Field) {
+	typedLogger().Warn(msg, fields...) // Log warning message with typed fields
+}
+
+// ErrorF logs an error message with typed fields using the global logger.
+func ErrorF(msg string, fields ...Field) {
+	typedLogger().Error(msg, fields...) // Log error message with typed fields
+}
+
+// FatalF logs a fatal message with typed fields using the global logger.
+func FatalF(msg string, fields ...Field) {
+	typedLogger().Fatal(msg, fields...) // Log fatal message with typed fields
+}
+
+// PanicF logs a panic message with typed fields using the global logger.
+func PanicF(msg string, fields ...Field) {
+	typedLogger().Panic(msg, fields...) // Log panic message with typed fields
+}
@@ -0,0 +1,197 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestFFieldTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		field sazabi.Field
+		want  zapcore.FieldType
+	}{
+		{name: "string", field: sazabi.F("k", "v"), want: zapcore.StringType},
+		{name: "int", field: sazabi.F("k", 1), want: zapcore.Int64Type},
+		{name: "int8", field: sazabi.F("k", int8(1)), want: zapcore.Int8Type},
+		{name: "int16", field: sazabi.F("k", int16(1)), want: zapcore.Int16Type},
+		{name: "int32", field: sazabi.F("k", int32(1)), want: zapcore.Int32Type},
+		{name: "int64", field: sazabi.F("k", int64(1)), want: zapcore.Int64Type},
+		{name: "uint", field: sazabi.F("k", uint(1)), want: zapcore.Uint64Type},
+		{name: "uint8", field: sazabi.F("k", uint8(1)), want: zapcore.Uint8Type},
+		{name: "uint16", field: sazabi.F("k", uint16(1)), want: zapcore.Uint16Type},
+		{name: "uint32", field: sazabi.F("k", uint32(1)), want: zapcore.Uint32Type},
+		{name: "uint64", field: sazabi.F("k", uint64(1)), want: zapcore.Uint64Type},
+		{name: "uintptr", field: sazabi.F("k", uintptr(1)), want: zapcore.UintptrType},
+		{name: "float32", field: sazabi.F("k", float32(1)), want: zapcore.Float32Type},
+		{name: "float64", field: sazabi.F("k", 1.5), want: zapcore.Float64Type},
+		{name: "bool", field: sazabi.F("k", true), want: zapcore.BoolType},
+		{name: "time", field: sazabi.F("k", time.Now()), want: zapcore.TimeType},
+		{name: "duration", field: sazabi.F("k", time.Second), want: zapcore.DurationType},
+		{name: "bytes", field: sazabi.F("k", []byte("v")), want: zapcore.BinaryType},
+		{name: "error", field: sazabi.F("k", errors.New("v")), want: zapcore.ErrorType},
+		{name: "stringer", field: sazabi.F("k", net.IPv4(192, 0, 2, 1)), want: zapcore.StringerType},
+		{name: "fallback", field: sazabi.F("k", map[string]int{"a": 1}), want: zapcore.ReflectType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.field.Type != tt.want {
+				t.Errorf("F() type = %v, want %v", tt.field.Type, tt.want)
+			}
+			if tt.field.Key != "k" {
+				t.Errorf("F() key = %q, want k", tt.field.Key)
+			}
+		})
+	}
+}
+
+func TestFieldsFunctions(t *testing.T) {
+	var want string
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		want = callerLine()
+		sazabi.InfoFields("typed entry", sazabi.F("user", "alice"), sazabi.F("attempts", 3))
+		sazabi.Infow("sugared entry", sazabi.F("user", "alice"), "attempts", 3)
+	})
+
+	assertCaller(t, output, "typed entry", want)
+	typed := entryFields(t, output, "typed entry")
+	sugared := entryFields(t, output, "sugared entry")
+	for _, fields := range []map[string]interface{}{typed, sugared} {
+		if fields["user"] != "alice" || fields["attempts"] != float64(3) {
+			t.Errorf("fields = %v, want user and attempts", fields)
+		}
+	}
+}
+
+func TestFFunctions(t *testing.T) {
+	var want string
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		want = callerLine()
+		sazabi.InfoF("short entry", sazabi.F("user", "alice"), sazabi.F("attempts", 3))
+		sazabi.WarnF("short warning")
+		sazabi.ErrorF("short error", sazabi.F("code", 42))
+	})
+
+	assertCaller(t, output, "short entry", want)
+	if fields := entryFields(t, output, "short entry"); fields["user"] != "alice" || fields["attempts"] != float64(3) {
+		t.Errorf("fields = %v, want user and attempts", fields)
+	}
+	for _, msg := range []string{"short warning", "short error"} {
+		if line := lineContaining(output, msg); !strings.Contains(line, "fields_test.go") {
+			t.Errorf("entry %q missing or without its caller: %s", msg, output)
+		}
+	}
+}
+
+func TestFieldsMatchSugared(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithJSONEncoding())
+	global := sazabi.AddGlobalFields("service", "billing")
+	defer global.Remove()
+	if err := sazabi.SetLevel("warn"); err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	err := errors.New("card declined")
+	sazabi.WarnFields("typed entry", sazabi.F("user", "alice"), sazabi.F("attempts", 3), sazabi.F("amount", 12.5),
+		sazabi.F("retry", true), sazabi.F("elapsed", 1500*time.Millisecond), sazabi.F("at", at), sazabi.F("err", err))
+	sazabi.Warnw("sugared entry", "user", "alice", "attempts", 3, "amount", 12.5,
+		"retry", true, "elapsed", 1500*time.Millisecond, "at", at, "err", err)
+	sazabi.WarnF("short entry", sazabi.F("user", "alice"), sazabi.F("attempts", 3), sazabi.F("amount", 12.5),
+		sazabi.F("retry", true), sazabi.F("elapsed", 1500*time.Millisecond), sazabi.F("at", at), sazabi.F("err", err))
+	sazabi.InfoFields("typed info entry")
+	sazabi.InfoF("short info entry")
+	sazabi.Info("sugared info entry")
+
+	output := read()
+	typed := entryFields(t, output, "typed entry")
+	sugared := entryFields(t, output, "sugared entry")
+	short := entryFields(t, output, "short entry")
+	for _, fields := range []map[string]interface{}{typed, sugared, short} {
+		delete(fields, "ts")
+		delete(fields, "caller")
+		delete(fields, "msg")
+	}
+	if !reflect.DeepEqual(typed, sugared) {
+		t.Errorf("typed fields = %v, want the sugared fields %v", typed, sugared)
+	}
+	if !reflect.DeepEqual(short, sugared) {
+		t.Errorf("WarnF fields = %v, want the sugared fields %v", short, sugared)
+	}
+	if typed["service"] != "billing" {
+		t.Errorf("typed fields = %v, want the global fields", typed)
+	}
+	if lineContaining(output, "info entry") != "" {
+		t.Errorf("Info entries written at warn level: %s", output)
+	}
+}
+
+// fieldSink keeps benchmarked field constructors from being optimized away.
+var fieldSink sazabi.Field
+
+func BenchmarkF(b *testing.B) {
+	b.Run("F string", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fieldSink = sazabi.F("key", "value")
+		}
+	})
+	b.Run("zap.String", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fieldSink = zap.String("key", "value")
+		}
+	})
+	b.Run("F int", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fieldSink = sazabi.F("key", i)
+		}
+	})
+	b.Run("zap.Int", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fieldSink = zap.Int("key", i)
+		}
+	})
+}
+
+func BenchmarkFieldsVersusSugared(b *testing.B) {
+	b.Run("Infow", func(b *testing.B) {
+		initializeFile(b, "development")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sazabi.Infow("benchmark entry", "user", "alice", "attempt", i, "elapsed", time.Second)
+		}
+	})
+	b.Run("InfoFields", func(b *testing.B) {
+		initializeFile(b, "development")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sazabi.InfoFields("benchmark entry", sazabi.F("user", "alice"), sazabi.F("attempt", i), sazabi.F("elapsed", time.Second))
+		}
+	})
+	b.Run("InfoF", func(b *testing.B) {
+		initializeFile(b, "development")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sazabi.InfoF("benchmark entry", sazabi.F("user", "alice"), sazabi.F("attempt", i), sazabi.F("elapsed", time.Second))
+		}
+	})
+}
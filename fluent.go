@@ -0,0 +1,336 @@
+package sazabi
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	mathrand "math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of WithFluentForward.
+const (
+	defaultFluentBufferSize = 8 << 20 // Bytes of events kept while Fluentd is unreachable
+	defaultFluentAckTimeout = 5 * time.Second
+	fluentDialTimeout       = 5 * time.Second
+	fluentWriteTimeout      = 5 * time.Second
+	fluentMinBackoff        = 100 * time.Millisecond // Wait before the first reconnection attempt
+	fluentMaxBackoff        = 30 * time.Second       // Longest wait between reconnection attempts
+)
+
+// FluentOption configures an output added by WithFluentForward.
+type FluentOption func(*fluentConfig)
+
+// fluentConfig is a Fluentd output added by WithFluentForward.
+type fluentConfig struct {
+	address    string
+	tag        string
+	ack        bool
+	ackTimeout time.Duration
+	bufferSize int
+}
+
+// FluentRequireAck makes the output wait for Fluentd to acknowledge each event, sending
+// it again on a new connection when no acknowledgment arrives within timeout (five
+// seconds when zero). Without it, events written to a connection Fluentd drops can be
+// lost. The input must have require_ack_response enabled.
+func FluentRequireAck(timeout time.Duration) FluentOption {
+	return func(c *fluentConfig) {
+		c.ack = true
+		c.ackTimeout = timeout
+	}
+}
+
+// FluentBufferSize sets the bytes of encoded events kept while Fluentd is unreachable,
+// 8 MiB by default. Entries beyond it are dropped.
+func FluentBufferSize(bytes int) FluentOption {
+	return func(c *fluentConfig) {
+		c.bufferSize = bytes
+	}
+}
+
+// WithFluentForward adds an output sending entries to Fluentd or Fluent Bit at addr
+// ("host:port") over TCP with the forward protocol. Each entry is a MessagePack
+// [tag, time, record] event: time is an EventTime, with nanoseconds, and record maps the
+// fields of the entry to their values, with the message under "msg", the level under
+// "level", and the logger name, caller and stacktrace when set.
+//
+// Events are sent in the background, in order, and Sync waits until the buffered events
+// are sent or sending fails. While Fluentd is unreachable, events are buffered up to
+// FluentBufferSize and the output reconnects, waiting a jittered delay growing from
+// 100ms to 30s between attempts. Entries that do not fit in the buffer are dropped and
+// counted as such by PublishExpvars. Failures are reported to the internal error output.
+func WithFluentForward(addr, tag string, opts ...FluentOption) Option {
+	cfg := fluentConfig{address: addr, tag: tag, ackTimeout: defaultFluentAckTimeout, bufferSize: defaultFluentBufferSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.ackTimeout <= 0 {
+		cfg.ackTimeout = defaultFluentAckTimeout
+	}
+	return func(o *options) {
+		o.fluent = append(o.fluent, cfg)
+		o.integrations = append(o.integrations, integration{
+			name: "fluent_forward",
+			settings: map[string]string{
+				"address": addr,
+				"tag":     tag,
+				"ack":     strconv.FormatBool(cfg.ack),
+			},
+		})
+	}
+}
+
+// newFluentCores returns the cores of the Fluentd outputs of o, writing the entries
+// enabled by enab and reporting failures to errorOutput.
+func newFluentCores(enab zapcore.LevelEnabler, errorOutput zapcore.WriteSyncer, o *options) ([]zapcore.Core, error) {
+	cores := make([]zapcore.Core, 0, len(o.fluent))
+	for _, cfg := range o.fluent {
+		if cfg.address == "" || cfg.tag == "" {
+			return nil, fmt.Errorf("sazabi: Fluentd output needs an address and a tag")
+		}
+		cores = append(cores, &fluentCore{LevelEnabler: enab, out: newFluentOutput(cfg, errorOutput, o.global)})
+	}
+	return cores, nil
+}
+
+// fluentCore encodes entries as forward protocol events for a Fluentd output.
+type fluentCore struct {
+	zapcore.LevelEnabler
+	fields []zapcore.Field // Fields added by With
+	out    *fluentOutput
+}
+
+// With implements zapcore.Core.
+func (c *fluentCore) With(fields []zapcore.Field) zapcore.Core {
+	return &fluentCore{LevelEnabler: c.LevelEnabler, fields: append(c.fields[:len(c.fields):len(c.fields)], fields...), out: c.out}
+}
+
+// Check implements zapcore.Core.
+func (c *fluentCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. The event is sent in the background, so Write never
+// fails.
+func (c *fluentCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	record := enc.Fields
+	record["msg"] = ent.Message
+	record["level"] = ent.Level.CapitalString()
+	if ent.LoggerName != "" {
+		record[NameKey] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		record["caller"] = ent.Caller.TrimmedPath()
+	}
+	if ent.Stack != "" {
+		record["stacktrace"] = ent.Stack
+	}
+
+	event := appendMsgpackString(nil, c.out.cfg.tag)
+	event = appendMsgpackEventTime(event, ent.Time)
+	c.out.add(appendMsgpackMap(event, record))
+	return nil
+}
+
+// Sync implements zapcore.Core, waiting until the buffered events are sent.
+func (c *fluentCore) Sync() error {
+	return c.out.Sync()
+}
+
+// fluentOutput sends forward protocol events to Fluentd over TCP, in order, from a
+// background goroutine running while events are buffered.
+type fluentOutput struct {
+	cfg         fluentConfig
+	global      bool                // Whether dropped entries are counted by PublishExpvars
+	errorOutput zapcore.WriteSyncer // Receives failures
+	dial        func(network, address string) (net.Conn, error)
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+
+	conn    net.Conn      // Current connection, nil when disconnected; used by the sender only
+	backoff time.Duration // Wait after the last failure; used by the sender only
+
+	mu          sync.Mutex
+	sent        *sync.Cond // Signaled when an event is sent or fails to send
+	queue       [][]byte   // Encoded [tag, time, record] events, without array header, oldest first
+	queued      int        // Size of the events in queue
+	sending     bool       // Whether the sender is running
+	failing     bool       // Whether the last attempt to send failed
+	overflowing bool       // Whether entries are being dropped because the buffer is full
+	dropped     int64      // Entries dropped since the output was opened
+}
+
+// newFluentOutput returns an output sending events as configured by cfg.
+func newFluentOutput(cfg fluentConfig, errorOutput zapcore.WriteSyncer, global bool) *fluentOutput {
+	o := &fluentOutput{
+		cfg:         cfg,
+		global:      global,
+		errorOutput: errorOutput,
+		dial: func(network, address string) (net.Conn, error) {
+			return net.DialTimeout(network, address, fluentDialTimeout)
+		},
+		minBackoff: fluentMinBackoff,
+		maxBackoff: fluentMaxBackoff,
+	}
+	o.sent = sync.NewCond(&o.mu)
+	return o
+}
+
+// add buffers event and starts the sender, or drops the event when the buffer is full.
+func (o *fluentOutput) add(event []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.queued+len(event) > o.cfg.bufferSize {
+		o.dropped++
+		if o.global {
+			countOutputDropped(1)
+		}
+		if !o.overflowing {
+			o.overflowing = true
+			o.reportf("buffer full, dropping entries")
+		}
+		return
+	}
+	o.overflowing = false
+	o.queue = append(o.queue, event)
+	o.queued += len(event)
+	if !o.sending {
+		o.sending = true
+		go o.run()
+	}
+}
+
+// run sends the buffered events until there are none left, waiting a jittered backoff
+// after each failure.
+func (o *fluentOutput) run() {
+	for {
+		o.mu.Lock()
+		if len(o.queue) == 0 {
+			o.sending = false
+			o.sent.Broadcast()
+			o.mu.Unlock()
+			return
+		}
+		event := o.queue[0]
+		o.mu.Unlock()
+
+		err := o.send(event)
+
+		o.mu.Lock()
+		o.failing = err != nil
+		if err == nil {
+			o.queue = o.queue[1:]
+			o.queued -= len(event)
+		}
+		o.sent.Broadcast()
+		o.mu.Unlock()
+
+		if err == nil {
+			o.backoff = 0
+			continue
+		}
+		switch {
+		case o.backoff == 0:
+			o.backoff = o.minBackoff
+		case o.backoff < o.maxBackoff:
+			if o.backoff *= 2; o.backoff > o.maxBackoff {
+				o.backoff = o.maxBackoff
+			}
+		}
+		wait := o.backoff/2 + time.Duration(mathrand.Int63n(int64(o.backoff/2)+1))
+		o.reportf("%v, reconnecting in %s", err, wait)
+		time.Sleep(wait)
+	}
+}
+
+// send writes event to Fluentd, connecting first when needed, and waits for its
+// acknowledgment when configured to. The connection is closed after a failure.
+func (o *fluentOutput) send(event []byte) error {
+	if o.conn == nil {
+		conn, err := o.dial("tcp", o.cfg.address)
+		if err != nil {
+			return err
+		}
+		o.conn = conn
+	}
+
+	var chunk string
+	msg := make([]byte, 0, len(event)+40)
+	if o.cfg.ack {
+		var id [16]byte
+		rand.Read(id[:])
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+		msg = appendMsgpackArrayHeader(msg, 4)
+		msg = append(msg, event...)
+		msg = appendMsgpackMap(msg, map[string]interface{}{"chunk": chunk})
+	} else {
+		msg = appendMsgpackArrayHeader(msg, 3)
+		msg = append(msg, event...)
+	}
+
+	err := o.exchange(msg, chunk)
+	if err != nil {
+		o.conn.Close()
+		o.conn = nil
+	}
+	return err
+}
+
+// exchange writes msg to the connection and, when chunk is set, reads the
+// acknowledgment of chunk.
+func (o *fluentOutput) exchange(msg []byte, chunk string) error {
+	o.conn.SetWriteDeadline(time.Now().Add(fluentWriteTimeout))
+	if _, err := o.conn.Write(msg); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+	o.conn.SetReadDeadline(time.Now().Add(o.cfg.ackTimeout))
+	resp, err := readMsgpack(o.conn)
+	if err != nil {
+		return fmt.Errorf("waiting for acknowledgment: %w", err)
+	}
+	if m, ok := resp.(map[string]interface{}); !ok || m["ack"] != chunk {
+		return fmt.Errorf("unexpected acknowledgment %v", resp)
+	}
+	return nil
+}
+
+// Sync waits until the buffered events are sent, returning an error when sending fails
+// first.
+func (o *fluentOutput) Sync() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for o.sending && !o.failing {
+		o.sent.Wait()
+	}
+	if len(o.queue) > 0 {
+		return fmt.Errorf("sazabi: Fluentd output %s unreachable, %d entries buffered", o.cfg.address, len(o.queue))
+	}
+	return nil
+}
+
+// reportf writes a failure to the internal error output.
+func (o *fluentOutput) reportf(format string, args ...interface{}) {
+	fmt.Fprintf(o.errorOutput, "%v output fluent %s: %s\n", time.Now(), o.cfg.address, fmt.Sprintf(format, args...))
+	o.errorOutput.Sync()
+}
@@ -0,0 +1,249 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeFluentd is a forward protocol server recording the events it receives. With ack,
+// it acknowledges the events that carry a chunk, except the first skipAcks ones, after
+// which it closes the connection.
+type fakeFluentd struct {
+	ln       net.Listener
+	ack      bool
+	skipAcks int
+
+	mu     sync.Mutex
+	events [][]interface{}
+	chunks int
+}
+
+// listenFluentd starts a fakeFluentd at addr, 127.0.0.1 on any port when empty.
+func listenFluentd(t *testing.T, addr string, ack bool, skipAcks int) *fakeFluentd {
+	t.Helper()
+
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeFluentd{ln: ln, ack: ack, skipAcks: skipAcks}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeFluentd) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		v, err := readMsgpack(conn)
+		if err != nil {
+			return
+		}
+		event, _ := v.([]interface{})
+		f.mu.Lock()
+		f.events = append(f.events, event)
+		var chunk interface{}
+		if len(event) == 4 {
+			if option, ok := event[3].(map[string]interface{}); ok {
+				chunk = option["chunk"]
+				f.chunks++
+			}
+		}
+		skip := f.chunks <= f.skipAcks
+		f.mu.Unlock()
+
+		if f.ack && chunk != nil {
+			if skip {
+				return // Lost acknowledgment
+			}
+			conn.Write(appendMsgpackMap(nil, map[string]interface{}{"ack": chunk}))
+		}
+	}
+}
+
+// waitEvents returns the events received once there are n of them, failing t when
+// they do not arrive in time. Events sent without acknowledgment may still be on their
+// way when Sync returns.
+func (f *fakeFluentd) waitEvents(t *testing.T, n int) [][]interface{} {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		events := f.received()
+		if len(events) >= n || time.Now().After(deadline) {
+			return events
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// received returns the events received so far.
+func (f *fakeFluentd) received() [][]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([][]interface{}(nil), f.events...)
+}
+
+// newTestForwarder returns a core sending to addr with a short backoff, and the buffer
+// receiving its failures.
+func newTestForwarder(addr string, opts ...FluentOption) (*fluentCore, *lockedBuffer) {
+	errs := &lockedBuffer{}
+	cfg := fluentConfig{address: addr, tag: "app", ackTimeout: defaultFluentAckTimeout, bufferSize: defaultFluentBufferSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	out := newFluentOutput(cfg, errs, false)
+	out.minBackoff, out.maxBackoff = time.Millisecond, 10*time.Millisecond
+	return &fluentCore{LevelEnabler: zapcore.DebugLevel, out: out}, errs
+}
+
+// syncWithin calls c.Sync until it succeeds, failing t after timeout.
+func syncWithin(t *testing.T, c *fluentCore, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := c.Sync()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Sync() error = %v after %s", err, timeout)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// messages returns the msg of the records of events.
+func messages(events [][]interface{}) []string {
+	var msgs []string
+	for _, e := range events {
+		record, _ := e[2].(map[string]interface{})
+		msg, _ := record["msg"].(string)
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestFluentForward(t *testing.T) {
+	fluentd := listenFluentd(t, "", false, 0)
+	log, _, err := newZapLogger(ProductionEnvName, newOptions([]Option{
+		WithOutputPaths(filepath.Join(t.TempDir(), "app.log")),
+		WithFluentForward(fluentd.ln.Addr().String(), "app.web"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	log.Named("orders").Info("order placed", zap.Int("order_id", 7), zap.String("user", "alice"), zap.Strings("items", []string{"a", "b"}))
+	after := time.Now()
+	if err := log.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := fluentd.waitEvents(t, 1)
+	if len(events) != 1 || len(events[0]) != 3 {
+		t.Fatalf("events = %v, want one [tag, time, record] event", events)
+	}
+	event := events[0]
+	if event[0] != "app.web" {
+		t.Errorf("tag = %v, want app.web", event[0])
+	}
+	ext, ok := event[1].(msgpackExt)
+	if !ok || ext.Type != 0 {
+		t.Fatalf("time = %#v, want an EventTime", event[1])
+	}
+	if at := decodeEventTime(ext); at.Before(before) || at.After(after) {
+		t.Errorf("time = %v, want the time of the entry, between %v and %v", at, before, after)
+	}
+
+	record, _ := event[2].(map[string]interface{})
+	want := map[string]interface{}{
+		"msg":      "order placed",
+		"level":    "INFO",
+		NameKey:    "orders",
+		"order_id": int64(7),
+		"user":     "alice",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("record[%q] = %#v, want %#v", key, record[key], value)
+		}
+	}
+	if items, _ := record["items"].([]interface{}); len(items) != 2 || items[0] != "a" {
+		t.Errorf("record[items] = %#v, want an array", record["items"])
+	}
+	if caller, _ := record["caller"].(string); !strings.Contains(caller, "fluent_internal_test.go") {
+		t.Errorf("record[caller] = %#v, want the caller", record["caller"])
+	}
+}
+
+func TestFluentForwardAck(t *testing.T) {
+	fluentd := listenFluentd(t, "", true, 1)
+	c, errs := newTestForwarder(fluentd.ln.Addr().String(), FluentRequireAck(100*time.Millisecond))
+
+	c.Write(zapcore.Entry{Time: time.Now(), Message: "first"}, nil)
+	c.Write(zapcore.Entry{Time: time.Now(), Message: "second"}, nil)
+	syncWithin(t, c, 5*time.Second)
+
+	// The acknowledgment of the first event is lost, so it is sent again.
+	if got := strings.Join(messages(fluentd.received()), ","); got != "first,first,second" {
+		t.Errorf("events = %s, want first sent again before second", got)
+	}
+	if !strings.Contains(errs.String(), "waiting for acknowledgment") {
+		t.Errorf("internal errors = %q, want the missing acknowledgment reported", errs.String())
+	}
+}
+
+func TestFluentForwardOutage(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	c, errs := newTestForwarder(addr)
+	entries := []string{"one", "two", "three", "four", "five"}
+	// Room for 3 events: the last entries are dropped.
+	size := len(appendMsgpackMap(appendMsgpackEventTime(appendMsgpackString(nil, "app"), time.Now()), map[string]interface{}{"msg": "three", "level": "INFO"}))
+	c.out.cfg.bufferSize = 3 * size
+	for _, msg := range entries {
+		c.Write(zapcore.Entry{Time: time.Now(), Message: msg}, nil)
+	}
+	if err := c.Sync(); err == nil || !strings.Contains(err.Error(), "3 entries buffered") {
+		t.Errorf("Sync() error = %v during the outage, want the buffered entries reported", err)
+	}
+	if c.out.dropped != 2 || strings.Count(errs.String(), "buffer full") != 1 {
+		t.Errorf("%d dropped, internal errors = %q; want 2 dropped and reported once", c.out.dropped, errs.String())
+	}
+
+	fluentd := listenFluentd(t, addr, false, 0)
+	syncWithin(t, c, 5*time.Second)
+	if got := strings.Join(messages(fluentd.waitEvents(t, 3)), ","); got != "one,two,three" {
+		t.Errorf("events = %s, want the buffered events in order", got)
+	}
+}
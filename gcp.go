@@ -0,0 +1,159 @@
+package sazabi
+
+import (
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// gcpEncoding is the name of the Cloud Logging JSON encoder in encoders.
+const gcpEncoding = "gcp"
+
+// Special fields of Cloud Logging, written by WithGCPFormat.
+const (
+	GCPSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	GCPTraceKey          = "logging.googleapis.com/trace"
+	GCPSpanIDKey         = "logging.googleapis.com/spanId"
+	GCPTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+)
+
+// Keys of the trace and span IDs added by otellog.WithCorrelation, which WithGCPFormat
+// maps to the trace fields of Cloud Logging.
+const (
+	traceIDKey = "trace_id"
+	spanIDKey  = "span_id"
+)
+
+// GCPOption configures WithGCPFormat.
+type GCPOption func(*options)
+
+// GCPTraceProject correlates entries with Cloud Trace: the trace and span IDs added by
+// otellog.WithCorrelation are written as GCPTraceKey, the trace resource name
+// "projects/<projectID>/traces/<trace ID>", and GCPSpanIDKey, with GCPTraceSampledKey.
+func GCPTraceProject(projectID string) GCPOption {
+	return func(o *options) {
+		o.gcpProject = projectID
+	}
+}
+
+// WithGCPFormat writes entries as the structured JSON expected by Cloud Logging, such as
+// on GKE or Cloud Run, in every environment: the level under "severity" (DEBUG, INFO,
+// WARNING, ERROR, and CRITICAL above), the message under "message", the time in RFC 3339
+// under "timestamp", the caller as a GCPSourceLocationKey object and the stacktrace
+// under "stack_trace", where Error Reporting finds it.
+func WithGCPFormat(opts ...GCPOption) Option {
+	return func(o *options) {
+		o.gcpFormat = true
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// applyGCPFormat switches conf to the Cloud Logging encoder when the option is set.
+func applyGCPFormat(conf *zap.Config, o *options) {
+	if !o.gcpFormat {
+		return
+	}
+	conf.Encoding = gcpEncoding
+	conf.EncoderConfig = gcpEncoderConfig(conf.EncoderConfig)
+}
+
+// gcpEncoderConfig returns conf with the keys and encoders of Cloud Logging.
+func gcpEncoderConfig(conf zapcore.EncoderConfig) zapcore.EncoderConfig {
+	conf.TimeKey = "timestamp"
+	conf.LevelKey = "severity"
+	conf.MessageKey = "message"
+	conf.CallerKey = zapcore.OmitKey // Written as GCPSourceLocationKey
+	conf.StacktraceKey = "stack_trace"
+	conf.EncodeLevel = gcpLevelEncoder
+	conf.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	conf.EncodeDuration = zapcore.SecondsDurationEncoder
+	return conf
+}
+
+// gcpLevelEncoder writes the Cloud Logging severity of a level.
+func gcpLevelEncoder(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	switch {
+	case level <= zapcore.DebugLevel:
+		enc.AppendString("DEBUG")
+	case level == zapcore.InfoLevel:
+		enc.AppendString("INFO")
+	case level == zapcore.WarnLevel:
+		enc.AppendString("WARNING")
+	case level == zapcore.ErrorLevel:
+		enc.AppendString("ERROR")
+	default:
+		enc.AppendString("CRITICAL")
+	}
+}
+
+// newGCPEncoder returns the Cloud Logging encoder for conf.
+func newGCPEncoder(conf zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return &gcpEncoder{Encoder: zapcore.NewJSONEncoder(gcpEncoderConfig(conf))}, nil
+}
+
+// gcpEncoder is a JSON encoder writing the caller and trace fields the way Cloud Logging
+// expects them.
+type gcpEncoder struct {
+	zapcore.Encoder
+	project string // Project of the traces, set by build; trace fields are kept as they are when empty
+}
+
+// Clone implements zapcore.Encoder.
+func (e *gcpEncoder) Clone() zapcore.Encoder {
+	return &gcpEncoder{Encoder: e.Encoder.Clone(), project: e.project}
+}
+
+// AddString implements zapcore.ObjectEncoder, mapping the trace fields added by With.
+func (e *gcpEncoder) AddString(key, value string) {
+	for _, f := range e.traceFields(zap.String(key, value)) {
+		f.AddTo(e.Encoder)
+	}
+}
+
+// EncodeEntry implements zapcore.Encoder.
+func (e *gcpEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	mapped := make([]zapcore.Field, 0, len(fields)+1)
+	for _, f := range fields {
+		mapped = append(mapped, e.traceFields(f)...)
+	}
+	if ent.Caller.Defined {
+		mapped = append(mapped, zap.Object(GCPSourceLocationKey, gcpSourceLocation(ent.Caller)))
+	}
+	return e.Encoder.EncodeEntry(ent, mapped)
+}
+
+// traceFields returns the fields f is written as: the Cloud Logging trace fields for
+// the trace and span IDs when the project is known, else f itself.
+func (e *gcpEncoder) traceFields(f zapcore.Field) []zapcore.Field {
+	if e.project == "" || f.Type != zapcore.StringType {
+		return []zapcore.Field{f}
+	}
+	switch f.Key {
+	case traceIDKey:
+		return []zapcore.Field{
+			zap.String(GCPTraceKey, "projects/"+e.project+"/traces/"+f.String),
+			zap.Bool(GCPTraceSampledKey, true), // otellog only adds sampled spans
+		}
+	case spanIDKey:
+		return []zapcore.Field{zap.String(GCPSpanIDKey, f.String)}
+	}
+	return []zapcore.Field{f}
+}
+
+// gcpSourceLocation is the GCPSourceLocationKey object of a caller.
+type gcpSourceLocation zapcore.EntryCaller
+
+// MarshalLogObject implements zapcore.ObjectMarshaler. The line is a string, as int64
+// values are in the JSON form of LogEntrySourceLocation.
+func (c gcpSourceLocation) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file", c.File)
+	enc.AddString("line", strconv.Itoa(c.Line))
+	if c.Function != "" {
+		enc.AddString("function", c.Function)
+	}
+	return nil
+}
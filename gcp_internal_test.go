@@ -0,0 +1,39 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGCPSeverity(t *testing.T) {
+	enc, err := newGCPEncoder(newProductionEncoderConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for level, want := range map[zapcore.Level]string{
+		zapcore.DebugLevel:  "DEBUG",
+		zapcore.InfoLevel:   "INFO",
+		zapcore.WarnLevel:   "WARNING",
+		zapcore.ErrorLevel:  "ERROR",
+		zapcore.DPanicLevel: "CRITICAL",
+		zapcore.PanicLevel:  "CRITICAL",
+		zapcore.FatalLevel:  "CRITICAL",
+	} {
+		buf, err := enc.EncodeEntry(zapcore.Entry{Level: level, Message: "m"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry["severity"] != want {
+			t.Errorf("severity of %s = %v, want %s", level, entry["severity"], want)
+		}
+	}
+}
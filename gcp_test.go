@@ -0,0 +1,74 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// gcpEntries returns the JSON entries of output by message.
+func gcpEntries(t *testing.T, output string) map[string]map[string]interface{} {
+	t.Helper()
+
+	entries := make(map[string]map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q is not JSON: %v", line, err)
+		}
+		msg, _ := entry["message"].(string)
+		entries[msg] = entry
+	}
+	return entries
+}
+
+func TestWithGCPFormat(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, "development", sazabi.WithGCPFormat(sazabi.GCPTraceProject("billing-prod")))
+	sazabi.With("span_id", "00f067aa0ba902b7").Infow("charged", "trace_id", "4bf92f3577b34da6a3ce929d0e0e4736", "amount", 42)
+	sazabi.Warn("slow charge")
+	sazabi.Error("charge failed")
+	entries := gcpEntries(t, read())
+
+	entry := entries["charged"]
+	var keys []string
+	for key := range entry {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	want := []string{"amount", sazabi.GCPSourceLocationKey, sazabi.GCPSpanIDKey, sazabi.GCPTraceKey, sazabi.GCPTraceSampledKey, "message", "severity", "timestamp"}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Errorf("keys = %q, want %q", keys, want)
+	}
+	if entry["severity"] != "INFO" || entry[sazabi.GCPTraceKey] != "projects/billing-prod/traces/4bf92f3577b34da6a3ce929d0e0e4736" ||
+		entry[sazabi.GCPSpanIDKey] != "00f067aa0ba902b7" || entry[sazabi.GCPTraceSampledKey] != true {
+		t.Errorf("entry = %v, want the INFO severity and the trace fields of billing-prod", entry)
+	}
+	location, _ := entry[sazabi.GCPSourceLocationKey].(map[string]interface{})
+	if file, _ := location["file"].(string); !strings.HasSuffix(file, "gcp_test.go") || location["line"] == "" || !strings.Contains(location["function"].(string), "TestWithGCPFormat") {
+		t.Errorf("sourceLocation = %v, want the file, line and function of the call", location)
+	}
+
+	for msg, severity := range map[string]string{"slow charge": "WARNING", "charge failed": "ERROR"} {
+		if got := entries[msg]["severity"]; got != severity {
+			t.Errorf("severity of %q = %v, want %s", msg, got, severity)
+		}
+	}
+}
+
+func TestWithGCPFormatWithoutProject(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithGCPFormat())
+	sazabi.Infow("charged", "trace_id", "4bf92f3577b34da6a3ce929d0e0e4736")
+	entry := gcpEntries(t, read())["charged"]
+
+	if entry["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || entry[sazabi.GCPTraceKey] != nil {
+		t.Errorf("entry = %v, want the trace ID kept as it is without project", entry)
+	}
+}
@@ -4,7 +4,6 @@ go 1.18
 
 require (
 	github.com/zeroxsolutions/barbatos v0.0.1
+	go.uber.org/multierr v1.10.0
 	go.uber.org/zap v1.27.0
 )
-
-require go.uber.org/multierr v1.10.0 // indirect
@@ -0,0 +1,91 @@
+package sazabi
+
+import (
+	"net/http"
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sensitiveHeaders are the canonical names of the headers that Headers never logs,
+// even when allowed, because they carry credentials.
+var sensitiveHeaders = map[string]struct{}{
+	"Authorization": {},
+	"Cookie":        {},
+	"Set-Cookie":    {},
+}
+
+// Headers returns a field holding the headers of h named by allow (case-insensitively),
+// under their canonical names. Single values are logged as strings and multiple values
+// as arrays. The values of Authorization, Cookie and Set-Cookie are never logged: each
+// is reduced to an object marking its presence and giving its length.
+func Headers(key string, h http.Header, allow ...string) zap.Field {
+	allowed := make(map[string]struct{}, len(allow))
+	for _, name := range allow {
+		allowed[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+
+	logged := make(loggedHeaders, 0, len(allow))
+	for name, values := range h {
+		canonical := http.CanonicalHeaderKey(name)
+		if _, ok := allowed[canonical]; ok && len(values) > 0 {
+			logged = append(logged, loggedHeader{name: canonical, values: values})
+		}
+	}
+	sort.Slice(logged, func(i, j int) bool { return logged[i].name < logged[j].name })
+	return zap.Object(key, logged)
+}
+
+// loggedHeader is a header selected by Headers.
+type loggedHeader struct {
+	name   string
+	values []string
+}
+
+// loggedHeaders encodes the headers selected by Headers as an object.
+type loggedHeaders []loggedHeader
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (hs loggedHeaders) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, h := range hs {
+		_, sensitive := sensitiveHeaders[h.name]
+		switch {
+		case len(h.values) == 1 && sensitive:
+			enc.AddObject(h.name, redactedHeader(h.values[0]))
+		case len(h.values) == 1:
+			enc.AddString(h.name, h.values[0])
+		default:
+			enc.AddArray(h.name, headerValues{values: h.values, sensitive: sensitive})
+		}
+	}
+	return nil
+}
+
+// headerValues encodes the values of a multi-valued header as an array.
+type headerValues struct {
+	values    []string
+	sensitive bool
+}
+
+// MarshalLogArray implements zapcore.ArrayMarshaler.
+func (hv headerValues) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, v := range hv.values {
+		if hv.sensitive {
+			enc.AppendObject(redactedHeader(v))
+		} else {
+			enc.AppendString(v)
+		}
+	}
+	return nil
+}
+
+// redactedHeader encodes the value of a sensitive header as its presence and length.
+type redactedHeader string
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (v redactedHeader) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddBool("present", true)
+	enc.AddInt("length", len(v))
+	return nil
+}
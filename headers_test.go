@@ -0,0 +1,103 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// encodeHeaders returns the value of the field built by sazabi.Headers.
+func encodeHeaders(h http.Header, allow ...string) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	sazabi.Headers("headers", h, allow...).AddTo(enc)
+	return enc.Fields["headers"].(map[string]interface{})
+}
+
+func TestHeadersAllowlist(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Cache", "HIT")
+	h.Set("X-Internal-Token", "secret")
+	h["x-lowercase"] = []string{"kept"} // Not canonicalized
+
+	got := encodeHeaders(h, "content-type", "X-CACHE", "X-Lowercase")
+	want := map[string]interface{}{
+		"Content-Type": "application/json",
+		"X-Cache":      "HIT",
+		"X-Lowercase":  "kept",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Headers() = %v, want %v", got, want)
+	}
+}
+
+func TestHeadersSensitive(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer abcdef")
+	h.Set("Cookie", "session=123")
+	h.Add("Set-Cookie", "a=1")
+	h.Add("Set-Cookie", "bb=22")
+
+	got := encodeHeaders(h, "authorization", "Cookie", "Set-Cookie")
+	want := map[string]interface{}{
+		"Authorization": map[string]interface{}{"present": true, "length": len("Bearer abcdef")},
+		"Cookie":        map[string]interface{}{"present": true, "length": len("session=123")},
+		"Set-Cookie": []interface{}{
+			map[string]interface{}{"present": true, "length": 3},
+			map[string]interface{}{"present": true, "length": 5},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Headers() = %v, want %v", got, want)
+	}
+}
+
+func TestHeadersMultiValue(t *testing.T) {
+	h := http.Header{}
+	h.Add("Accept", "text/html")
+	h.Add("Accept", "application/json")
+	h.Add("Vary", "Origin")
+
+	got := encodeHeaders(h, "Accept", "Vary", "Missing")
+	want := map[string]interface{}{
+		"Accept": []interface{}{"text/html", "application/json"},
+		"Vary":   "Origin",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Headers() = %v, want %v", got, want)
+	}
+}
+
+func TestRequestLoggerLoggedHeaders(t *testing.T) {
+	handler := sazabi.RequestLogger(sazabi.WithLoggedHeaders("Cache-Control", "Cookie", "Set-Cookie"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("Set-Cookie", "session=abc")
+		}))
+
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Cookie", "session=abc")
+		req.Header.Set("User-Agent", "test")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	})
+
+	fields := entryFields(t, output, sazabi.HTTPRequestMessage)
+	request, _ := fields["request_headers"].(map[string]interface{})
+	response, _ := fields["response_headers"].(map[string]interface{})
+	if len(request) != 1 || request["Cookie"] == "session=abc" || request["Cookie"] == nil {
+		t.Errorf("request_headers = %v, want only a redacted Cookie", request)
+	}
+	if response["Cache-Control"] != "no-store" || response["Set-Cookie"] == "session=abc" {
+		t.Errorf("response_headers = %v, want Cache-Control and a redacted Set-Cookie", response)
+	}
+}
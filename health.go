@@ -0,0 +1,73 @@
+package sazabi
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SinkHealth reports the state of an output monitored by sazabi.
+type SinkHealth struct {
+	Name       string    // Output the sink writes to, with credentials redacted
+	Healthy    bool      // Whether the last write to the output succeeded
+	LastError  string    // Error of the last failed write, if any
+	Since      time.Time // Time of the last transition between healthy and unhealthy
+	InitFailed bool      // The output could not be opened and is never retried
+}
+
+// healthRegistry tracks the monitored sinks of the current logger by name.
+var healthRegistry = struct {
+	sync.Mutex
+	sinks map[string]*SinkHealth
+}{sinks: make(map[string]*SinkHealth)}
+
+// Health returns the state of every monitored sink, sorted by name.
+// Only sinks wrapped by options such as WithFallbackOutput or WithOptionalSink are monitored.
+func Health() []SinkHealth {
+	healthRegistry.Lock()
+	defer healthRegistry.Unlock()
+
+	health := make([]SinkHealth, 0, len(healthRegistry.sinks))
+	for _, h := range healthRegistry.sinks {
+		health = append(health, *h)
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Name < health[j].Name })
+	return health
+}
+
+// resetHealth forgets every monitored sink. It is called when the logger is rebuilt.
+func resetHealth() {
+	healthRegistry.Lock()
+	defer healthRegistry.Unlock()
+
+	healthRegistry.sinks = make(map[string]*SinkHealth)
+}
+
+// setHealth records the state of the named sink, updating Since on transitions.
+func setHealth(name string, err error) {
+	healthRegistry.Lock()
+	defer healthRegistry.Unlock()
+
+	h, ok := healthRegistry.sinks[name]
+	if !ok {
+		h = &SinkHealth{Name: name, Healthy: true, Since: time.Now()}
+		healthRegistry.sinks[name] = h
+	}
+
+	healthy := err == nil
+	if healthy != h.Healthy {
+		h.Healthy = healthy
+		h.Since = time.Now()
+	}
+	if err != nil {
+		h.LastError = err.Error()
+	}
+}
+
+// setInitFailed records that the named sink could not be opened.
+func setInitFailed(name string, err error) {
+	healthRegistry.Lock()
+	defer healthRegistry.Unlock()
+
+	healthRegistry.sinks[name] = &SinkHealth{Name: name, LastError: err.Error(), Since: time.Now(), InitFailed: true}
+}
@@ -0,0 +1,89 @@
+package sazabi
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// HeartbeatMessage is the message of the entries emitted by StartHeartbeat.
+const HeartbeatMessage = "heartbeat"
+
+// processStart is the reference time of the uptime reported by heartbeats.
+var processStart = time.Now()
+
+// StartHeartbeat emits an Info HeartbeatMessage entry immediately and then every
+// interval, carrying the process uptime, the number of goroutines and the key-value
+// pairs, so that log aggregators can tell a quiet service from a dead one. Heartbeats
+// are never sampled. They stop when stop is called or on Shutdown. It returns
+// ErrInvalidInterval, and starts nothing, when interval is not positive.
+func StartHeartbeat(interval time.Duration, keysValues ...interface{}) (stop func(), err error) {
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+	ticker := time.NewTicker(interval)
+	return startHeartbeat(ticker.C, ticker.Stop, keysValues), nil
+}
+
+// startHeartbeat emits a heartbeat immediately and then every time tick fires.
+// stopTick is called when the heartbeat stops.
+func startHeartbeat(tick <-chan time.Time, stopTick func(), keysValues []interface{}) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer stopTick()
+		emitHeartbeat(keysValues)
+		for {
+			select {
+			case <-tick:
+				emitHeartbeat(keysValues)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	stopHeartbeat := func() {
+		once.Do(func() {
+			close(done)
+		})
+		<-finished
+	}
+	removeHook := addShutdownHook(stopHeartbeat)
+	return func() {
+		removeHook()
+		stopHeartbeat()
+	}
+}
+
+// emitHeartbeat logs one heartbeat entry, bypassing sampling.
+func emitHeartbeat(keysValues []interface{}) {
+	unsampledLogger().Infow(HeartbeatMessage, append([]interface{}{
+		zap.Duration("uptime", time.Since(processStart)),
+		zap.Int("goroutines", runtime.NumGoroutine()),
+	}, keysValues...)...)
+}
+
+// samplingCore is the sampler of a logger built by build, keeping a reference to the
+// core below it so that some entries can bypass sampling.
+type samplingCore struct {
+	zapcore.Core                                 // Sampler
+	unsampled    zapcore.Core                    // Core below the sampler
+	resample     func(zapcore.Core) zapcore.Core // Wraps another core in the same sampling
+}
+
+// With implements zapcore.Core.
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{Core: c.Core.With(fields), unsampled: c.unsampled.With(fields), resample: c.resample}
+}
+
+// unsampledLogger returns the global logger without sampling and without caller, for
+// entries emitted by sazabi itself that must always be written.
+func unsampledLogger() *zap.SugaredLogger {
+	return currentInstance().unsampled
+}
@@ -0,0 +1,98 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countLines returns the number of lines of the file at path containing substr.
+func countLines(t *testing.T, path, substr string) int {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), substr)
+}
+
+func TestHeartbeatBypassesSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heartbeat.log")
+	Initialize(ProductionEnvName, WithOutputPaths(path)) // Samples after 100 identical entries per second
+
+	tick := make(chan time.Time)
+	stopped := false
+	stop := startHeartbeat(tick, func() { stopped = true }, []interface{}{"service", "billing"})
+	for i := 0; i < 200; i++ {
+		tick <- time.Time{}
+	}
+	stop()
+	stop() // Stopping twice is harmless
+
+	if !stopped {
+		t.Error("stopping the heartbeat did not stop its ticker")
+	}
+	select {
+	case tick <- time.Time{}:
+		t.Error("heartbeat still running after stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for i := 0; i < 200; i++ {
+		Info("sampled entry")
+	}
+	loadInstance().base.Sync()
+
+	if got := countLines(t, path, "\t"+HeartbeatMessage+"\t"); got != 201 {
+		t.Errorf("wrote %d heartbeats, want 201 (immediate beat and 200 ticks)", got)
+	}
+	if got := countLines(t, path, "sampled entry"); got >= 200 {
+		t.Errorf("wrote %d regular entries, want sampling to drop some", got)
+	}
+	if got := countLines(t, path, `"service": "billing"`); got != 201 {
+		t.Errorf("wrote %d heartbeats with static fields, want 201", got)
+	}
+}
+
+func TestHeartbeatStopsOnShutdown(t *testing.T) {
+	Initialize(ProductionEnvName, WithOutputPaths(filepath.Join(t.TempDir(), "heartbeat.log")))
+	capture, stopCapture := StartCapture()
+	defer stopCapture()
+
+	tick := make(chan time.Time)
+	stop := startHeartbeat(tick, func() {}, nil)
+	defer stop()
+	Shutdown()
+
+	select {
+	case tick <- time.Time{}:
+		t.Error("heartbeat still running after Shutdown")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	entries := capture.Entries()
+	if len(entries) != 1 || entries[0].Message != HeartbeatMessage {
+		t.Fatalf("captured %v, want the immediate heartbeat", entries)
+	}
+	if uptime, ok := entries[0].Dur("uptime"); !ok || uptime <= 0 {
+		t.Errorf("uptime = %s, %t, want a positive duration", uptime, ok)
+	}
+	if goroutines, ok := entries[0].Int("goroutines"); !ok || goroutines < 1 {
+		t.Errorf("goroutines = %d, %t, want at least 1", goroutines, ok)
+	}
+}
+
+func TestStartHeartbeatInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if stop, err := StartHeartbeat(interval); !errors.Is(err, ErrInvalidInterval) || stop != nil {
+			t.Errorf("StartHeartbeat(%s) error = %v, want ErrInvalidInterval", interval, err)
+		}
+	}
+}
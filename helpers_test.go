@@ -0,0 +1,125 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// captureStderr redirects os.Stderr while fn runs and returns everything written to it.
+// The logger must be (re)initialized inside fn so that it picks up the redirected stream.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+
+	originalStderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error = %v", err)
+	}
+	os.Stderr = w
+
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		done <- buf.String()
+	}()
+
+	defer func() {
+		w.Close()
+		os.Stderr = originalStderr
+	}()
+	fn()
+	w.Close()
+	os.Stderr = originalStderr
+
+	return <-done
+}
+
+// lineContaining returns the first line of output containing substr, or an empty string.
+func lineContaining(output, substr string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, substr) {
+			return line
+		}
+	}
+	return ""
+}
+
+// entryFields parses the structured fields of the console entry containing message.
+func entryFields(t *testing.T, output, message string) map[string]interface{} {
+	t.Helper()
+
+	line := lineContaining(output, message)
+	if line == "" {
+		t.Fatalf("entry %q not found in output: %s", message, output)
+	}
+
+	fields := make(map[string]interface{})
+	if i := strings.Index(line, "{"); i >= 0 {
+		if err := json.Unmarshal([]byte(line[i:]), &fields); err != nil {
+			t.Fatalf("failed to parse fields of %q: %v", line, err)
+		}
+	}
+	return fields
+}
+
+// newFileLogger returns a standalone logger for environment writing to a temporary
+// file, and a function returning what it wrote so far.
+func newFileLogger(t *testing.T, environment string, opts ...sazabi.Option) (*zap.SugaredLogger, func() string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	log, err := sazabi.New(environment, append([]sazabi.Option{sazabi.WithOutputPaths(path)}, opts...)...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	sugar := log.(*zap.SugaredLogger)
+	return sugar, func() string {
+		sugar.Sync()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+}
+
+// initializeFile initializes the global logger for environment writing to a file, and
+// returns a function reading the file, without the warning about the re-initialization.
+func initializeFile(t testing.TB, environment string, opts ...sazabi.Option) func() string {
+	path := filepath.Join(t.TempDir(), "app.log")
+	sazabi.Initialize(environment, append([]sazabi.Option{sazabi.WithOutputPaths(path)}, opts...)...)
+	return func() string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		for _, line := range strings.SplitAfter(string(data), "\n") {
+			if !strings.Contains(line, sazabi.ConflictingInitializeMessage) {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "")
+	}
+}
+
+// restoreDefault initializes the global logger without options when the test ends, so
+// that the following tests do not warn about a conflicting re-initialization.
+func restoreDefault(t *testing.T) {
+	t.Cleanup(func() {
+		captureStderr(t, func() { sazabi.Initialize(sazabi.ProductionEnvName) })
+	})
+}
@@ -0,0 +1,106 @@
+package sazabi
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is an entry of the global logger, as observed by the hooks of RegisterHook.
+type Entry struct {
+	Time       time.Time              // Time of the entry
+	Level      zapcore.Level          // Level of the entry
+	Message    string                 // Message of the entry
+	LoggerName string                 // Name of the logger, if any
+	Fields     map[string]interface{} // Fields of the entry, as encoded by zapcore.MapObjectEncoder
+}
+
+// RegisterHook registers h to be called with every entry written by the global logger,
+// once past the level, sampling and module levels, with redacted keys and dynamic fields
+// applied. Hooks are called in the order they were registered, on the goroutine logging
+// the entry, so they must be fast. They may be registered before Initialize and are kept
+// across re-initializations until removed.
+//
+// A hook that panics does not break logging: the panic is recovered and reported to the
+// internal error output. As with AddHook, entries logged from inside a hook bypass the
+// hooks.
+func RegisterHook(h func(Entry)) *Extension {
+	hook := newGuardedObserver(h, callerLocation(2))
+	return register(&extension{
+		observer: hook,
+		core:     wrapExtraCore(&hookCore{hook: hook, fields: make(map[string]interface{})}, afterRedaction),
+		position: afterRedaction,
+		name:     "hook",
+	})
+}
+
+// hookCore calls a hook of RegisterHook with the entries enabled at the level of the
+// global logger.
+type hookCore struct {
+	hook   *guardedHook
+	fields map[string]interface{} // Fields added by With, as encoded by zapcore.MapObjectEncoder
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *hookCore) Enabled(level zapcore.Level) bool {
+	in := loadInstance()
+	return in != nil && in.config.Level.Enabled(level)
+}
+
+// encoder returns an encoder holding a copy of the fields added by With.
+func (c *hookCore) encoder() *zapcore.MapObjectEncoder {
+	enc := zapcore.NewMapObjectEncoder()
+	for key, value := range c.fields {
+		enc.Fields[key] = copyFieldValue(value)
+	}
+	return enc
+}
+
+// With implements zapcore.Core.
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.encoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &hookCore{hook: c.hook, fields: enc.Fields}
+}
+
+// Check implements zapcore.Core.
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. Entries below the level are ignored, since the cores
+// wrapping the tee write every entry to each of its cores. A panic of the hook is
+// returned as an error, which zap reports to the internal error output.
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) (err error) {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	enc := c.encoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("sazabi: hook registered at %s panicked: %v", c.hook.site, r)
+		}
+	}()
+	c.hook.observe(Entry{
+		Time:       ent.Time,
+		Level:      ent.Level,
+		Message:    ent.Message,
+		LoggerName: ent.LoggerName,
+		Fields:     enc.Fields,
+	})
+	return nil
+}
+
+// Sync implements zapcore.Core.
+func (c *hookCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,106 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// hookRecorder records the entries its hook is called with, tagged with its name in a
+// log shared by several hooks.
+type hookRecorder struct {
+	mu    *sync.Mutex
+	calls *[]string
+	name  string
+	seen  []sazabi.Entry
+}
+
+func (r *hookRecorder) hook(e sazabi.Entry) {
+	if !strings.HasPrefix(e.Message, "hooked") {
+		return // Such as the warning about the re-initialization
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	*r.calls = append(*r.calls, r.name+":"+e.Message)
+	r.seen = append(r.seen, e)
+}
+
+func TestRegisterHook(t *testing.T) {
+	restoreDefault(t)
+	var mu sync.Mutex
+	var calls []string
+	first := &hookRecorder{mu: &mu, calls: &calls, name: "first"}
+	second := &hookRecorder{mu: &mu, calls: &calls, name: "second"}
+
+	h1 := sazabi.RegisterHook(first.hook) // Before Initialize
+	defer h1.Remove()
+	captureStderr(t, func() {
+		initializeFile(t, sazabi.ProductionEnvName)
+	})
+	h2 := sazabi.RegisterHook(second.hook)
+	defer h2.Remove()
+	keys := sazabi.AddRedactedKeys("password")
+	defer keys.Remove()
+
+	sazabi.With("order", 7).(*zap.SugaredLogger).Named("payments").Errorw("hooked charge", "amount", 42, "password", "hunter2")
+	sazabi.Debug("hooked below the level")
+	captureStderr(t, func() {
+		initializeFile(t, "development") // Hooks survive
+	})
+	sazabi.Debug("hooked debug")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(calls, " "); got != "first:hooked charge second:hooked charge first:hooked debug second:hooked debug" {
+		t.Errorf("calls = %s, want each entry above the level given to the hooks in registration order", got)
+	}
+	e := first.seen[0]
+	if e.Level != zapcore.ErrorLevel || e.LoggerName != "payments" || e.Time.IsZero() {
+		t.Errorf("entry = %+v, want the level, logger name and time of the entry", e)
+	}
+	if e.Fields["order"] != int64(7) || e.Fields["amount"] != int64(42) || e.Fields["password"] != sazabi.RedactedValue {
+		t.Errorf("fields = %v, want the fields of the entry and of With, redacted", e.Fields)
+	}
+}
+
+func TestRegisterHookPanic(t *testing.T) {
+	restoreDefault(t)
+	dir := t.TempDir()
+	var mu sync.Mutex
+	var calls []string
+	after := &hookRecorder{mu: &mu, calls: &calls, name: "after"}
+
+	panicking := sazabi.RegisterHook(func(e sazabi.Entry) {
+		if strings.HasPrefix(e.Message, "hooked") {
+			panic("hook bug")
+		}
+	})
+	defer panicking.Remove()
+	h := sazabi.RegisterHook(after.hook)
+	defer h.Remove()
+
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithInternalErrorOutput(filepath.Join(dir, "internal.log")))
+	before := sazabi.InternalErrorCount()
+	sazabi.Info("hooked entry")
+
+	if out := read(); !strings.Contains(out, "hooked entry") {
+		t.Errorf("output = %q, want the entry written despite the panicking hook", out)
+	}
+	if len(calls) != 1 {
+		t.Errorf("calls = %v, want the next hooks still called", calls)
+	}
+	errs := sazabi.InternalErrors()
+	if sazabi.InternalErrorCount()-before != 1 || !strings.Contains(errs[len(errs)-1], "hook_test.go") || !strings.Contains(errs[len(errs)-1], "panicked: hook bug") {
+		t.Errorf("internal errors = %q, want the panic reported with the registration site", errs)
+	}
+}
@@ -0,0 +1,137 @@
+package sazabi
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// HookReentryMessage is the message of the warning written the first time a hook logs
+// through the global logger while it is running.
+const HookReentryMessage = "log call from inside a hook"
+
+// hookReentries counts the hook invocations skipped because a hook was already running
+// on the same goroutine.
+var hookReentries int64
+
+// activeHooks holds the []*guardedHook of the registry, so that the offending hook can
+// be identified without taking initMu, which may be held by the caller.
+var activeHooks atomic.Value
+
+// HookReentryCount returns how many hook invocations were skipped because the entry was
+// logged from inside a hook. Such entries are still written to the outputs; only the
+// hooks are bypassed, which keeps a hook that logs from recursing until the stack
+// overflows.
+func HookReentryCount() int64 {
+	return atomic.LoadInt64(&hookReentries)
+}
+
+// guardedHook is a hook that is bypassed by entries logged while a hook is running on
+// the same goroutine.
+type guardedHook struct {
+	fn       func(zapcore.Entry) error
+	observer func(Entry) // Called instead of fn for the hooks of RegisterHook
+	entry    uintptr     // Entry point of fn or observer, to recognize it in stack frames
+	site     string      // Location of the registration, reported by the warning
+	inflight int32       // Number of invocations in progress, on any goroutine
+	warned   int32       // Set once the warning naming this hook has been written
+}
+
+// newGuardedHook wraps fn, registered at site.
+func newGuardedHook(fn func(zapcore.Entry) error, site string) *guardedHook {
+	return &guardedHook{fn: fn, entry: reflect.ValueOf(fn).Pointer(), site: site}
+}
+
+// newGuardedObserver wraps fn, registered at site by RegisterHook.
+func newGuardedObserver(fn func(Entry), site string) *guardedHook {
+	return &guardedHook{observer: fn, entry: reflect.ValueOf(fn).Pointer(), site: site}
+}
+
+// run calls the hook unless a hook is already running on the calling goroutine. The
+// stack is only inspected while another invocation of the hook is in progress, so the
+// usual path costs two atomic operations.
+func (h *guardedHook) run(ent zapcore.Entry) error {
+	if !h.enter() {
+		return nil
+	}
+	defer atomic.AddInt32(&h.inflight, -1)
+	return h.fn(ent)
+}
+
+// observe calls the observer like run calls fn.
+func (h *guardedHook) observe(e Entry) {
+	if !h.enter() {
+		return
+	}
+	defer atomic.AddInt32(&h.inflight, -1)
+	h.observer(e)
+}
+
+// enter counts an invocation of the hook, and reports whether it may run: false when a
+// hook is already running on the calling goroutine.
+func (h *guardedHook) enter() bool {
+	if atomic.AddInt32(&h.inflight, 1) > 1 {
+		if offender, ok := runningHook(); ok {
+			atomic.AddInt32(&h.inflight, -1)
+			atomic.AddInt64(&hookReentries, 1)
+			offender.warnReentry()
+			return false
+		}
+	}
+	return true
+}
+
+// warnReentry writes the HookReentryMessage warning naming h, once. It is written while
+// the hook is running, so hooks are bypassed for it as well.
+func (h *guardedHook) warnReentry() {
+	if atomic.CompareAndSwapInt32(&h.warned, 0, 1) {
+		unsampledLogger().Warnw(HookReentryMessage, "hook", h.site)
+	}
+}
+
+// Suffixes of the names of guardedHook methods in stack frames.
+const (
+	guardedHookRun     = ".(*guardedHook).run"
+	guardedHookObserve = ".(*guardedHook).observe"
+	guardedHookEnter   = ".(*guardedHook).enter"
+)
+
+// runningHook returns the hook running further up the calling goroutine's stack, if
+// any. The hook is recognized by the frame called by its run or observe frame; runs
+// that are writing the warning, from enter, are passed over, so the warning names the
+// hook that logged.
+func runningHook() (*guardedHook, bool) {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(4, pcs)]) // Skip Callers, runningHook, enter and run
+	var callee runtime.Frame
+	for {
+		frame, more := frames.Next()
+		running := strings.HasSuffix(frame.Function, guardedHookRun) || strings.HasSuffix(frame.Function, guardedHookObserve)
+		if running && !strings.HasSuffix(callee.Function, guardedHookEnter) {
+			return lookupHook(callee)
+		}
+		if !more {
+			return nil, false
+		}
+		callee = frame
+	}
+}
+
+// unknownHookWarned is set once the warning has been written for a hook that is not
+// in the registry, such as a hook removed while running.
+var unknownHookWarned int32
+
+// lookupHook returns the registered hook whose function is running in frame. A hook
+// missing from the registry is named by its function, and warned about once in total.
+func lookupHook(frame runtime.Frame) (*guardedHook, bool) {
+	hooks, _ := activeHooks.Load().([]*guardedHook)
+	for _, h := range hooks {
+		if h.entry == frame.Entry {
+			return h, true
+		}
+	}
+	return &guardedHook{site: frame.Function, warned: atomic.SwapInt32(&unknownHookWarned, 1)}, true
+}
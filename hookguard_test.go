@@ -0,0 +1,91 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestHookReentry(t *testing.T) {
+	var depth, maxDepth, calls int64
+	before := sazabi.HookReentryCount()
+
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		hook := sazabi.AddHook(func(ent zapcore.Entry) error {
+			d := atomic.AddInt64(&depth, 1)
+			defer atomic.AddInt64(&depth, -1)
+			if d > atomic.LoadInt64(&maxDepth) {
+				atomic.StoreInt64(&maxDepth, d)
+			}
+			atomic.AddInt64(&calls, 1)
+			sazabi.Errorw("logged from hook", "about", ent.Message)
+			return nil
+		})
+		defer hook.Remove()
+
+		sazabi.Info("first entry")
+		sazabi.Info("second entry")
+	})
+
+	if got := atomic.LoadInt64(&maxDepth); got != 1 {
+		t.Errorf("hook recursion depth = %d, want 1", got)
+	}
+	if got := atomic.LoadInt64(&calls); got != 2 {
+		t.Errorf("hook called %d times, want once per entry logged outside hooks", got)
+	}
+	if got := sazabi.HookReentryCount() - before; got < 2 {
+		t.Errorf("HookReentryCount() increased by %d, want at least 2", got)
+	}
+
+	if n := strings.Count(output, "logged from hook"); n != 2 {
+		t.Errorf("entries logged from the hook written %d times, want 2:\n%s", n, output)
+	}
+	if n := strings.Count(output, sazabi.HookReentryMessage); n != 1 {
+		t.Fatalf("warning written %d times, want once:\n%s", n, output)
+	}
+	if site, _ := entryFields(t, output, sazabi.HookReentryMessage)["hook"].(string); !strings.Contains(site, "hookguard_test.go") {
+		t.Errorf("warning hook = %q, want the registration site in hookguard_test.go", site)
+	}
+}
+
+func TestHookConcurrentCalls(t *testing.T) {
+	var calls int64
+	before := sazabi.HookReentryCount()
+
+	captureStderr(t, func() {
+		sazabi.Initialize("development") // Not sampled
+		hook := sazabi.AddHook(func(zapcore.Entry) error {
+			atomic.AddInt64(&calls, 1)
+			return nil
+		})
+		defer hook.Remove()
+
+		done := make(chan struct{})
+		for g := 0; g < 8; g++ {
+			go func() {
+				defer func() { done <- struct{}{} }()
+				for i := 0; i < 100; i++ {
+					sazabi.Info("concurrent entry")
+				}
+			}()
+		}
+		for g := 0; g < 8; g++ {
+			<-done
+		}
+	})
+
+	if got := atomic.LoadInt64(&calls); got != 800 {
+		t.Errorf("hook called %d times, want 800", got)
+	}
+	if got := sazabi.HookReentryCount() - before; got != 0 {
+		t.Errorf("HookReentryCount() increased by %d for concurrent entries, want 0", got)
+	}
+}
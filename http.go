@@ -0,0 +1,375 @@
+package sazabi
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RequestIDHeader is the header the HTTP middleware reads the request ID from,
+// and sets on the response when it has to generate one.
+const RequestIDHeader = "X-Request-ID"
+
+// HTTPRequestMessage is the message of the access entry logged for every request.
+const HTTPRequestMessage = "http request"
+
+// HTTPPanicKey is the key of the panic value on the access entry of a request whose
+// handler panicked.
+const HTTPPanicKey = "panic"
+
+// RequestLogOption configures the middleware returned by RequestLogger.
+type RequestLogOption func(*requestLogOptions)
+
+// requestLogOptions holds the settings collected from RequestLogOption values.
+type requestLogOptions struct {
+	loggedHeaders  []string        // Headers logged on the access entry, see Headers
+	trustedProxies []*net.IPNet    // Peers whose X-Forwarded-For header is believed
+	skippedPaths   map[string]bool // Paths of the requests without access entry
+	debug          bool            // Successful requests are logged at Debug level
+	idHeader       string          // Header of the request ID, RequestIDHeader when empty
+	fieldFuncs     []fieldsFunc    // Sources of additional fields, see WithRequestFields
+	levelFunc      levelFunc       // Level of the access entries, by status class when nil
+	accessLog      bool            // Write the access line of each request, see WithAccessLog
+}
+
+// fieldsFunc returns key-value pairs to add to the access entry of a request.
+type fieldsFunc func(r *http.Request, res ResponseInfo) []interface{}
+
+// levelFunc returns the level of the access entry of a request.
+type levelFunc func(r *http.Request, res ResponseInfo) zapcore.Level
+
+// ResponseInfo describes the response to a request, as recorded by the middleware.
+type ResponseInfo struct {
+	Status   int           // Status code sent, 200 when the handler wrote nothing
+	Bytes    int64         // Number of body bytes written
+	Duration time.Duration // Time taken by the handler
+	Header   http.Header   // Headers of the response
+}
+
+// WithRequestFields adds the key-value pairs returned by fn, which follow the
+// conventions of Infow, to the access entry of each request. fn is called once the
+// handler returned, with the request received by the middleware, so that it can read
+// what routers store in the request while routing it, such as the route pattern matched
+// by a router the middleware is installed in. Keys already on the access entry must not
+// be reused.
+func WithRequestFields(fn func(r *http.Request, res ResponseInfo) []interface{}) RequestLogOption {
+	return func(o *requestLogOptions) {
+		o.fieldFuncs = append(o.fieldFuncs, fn)
+	}
+}
+
+// WithRequestIDHeader reads and sets the request ID in header name instead of
+// RequestIDHeader.
+func WithRequestIDHeader(name string) RequestLogOption {
+	return func(o *requestLogOptions) {
+		o.idHeader = name
+	}
+}
+
+// WithRequestLevel writes the access entry of each request at the level returned by fn,
+// instead of the level of its status class. It does not apply to requests whose handler
+// panicked, which are logged at Error level.
+func WithRequestLevel(fn func(r *http.Request, res ResponseInfo) zapcore.Level) RequestLogOption {
+	return func(o *requestLogOptions) {
+		o.levelFunc = fn
+	}
+}
+
+// WithLoggedHeaders logs the named request and response headers on the access entry,
+// under request_headers and response_headers. Headers are selected by Headers, so
+// credentials are never logged.
+func WithLoggedHeaders(names ...string) RequestLogOption {
+	return func(o *requestLogOptions) {
+		o.loggedHeaders = append(o.loggedHeaders, names...)
+	}
+}
+
+// WithSkippedPaths writes no access entry for the requests to the given paths, such as
+// "/healthz", compared with the path of the URL. Their handlers still get the
+// request-scoped logger.
+func WithSkippedPaths(paths ...string) RequestLogOption {
+	return func(o *requestLogOptions) {
+		if o.skippedPaths == nil {
+			o.skippedPaths = make(map[string]bool)
+		}
+		for _, path := range paths {
+			o.skippedPaths[path] = true
+		}
+	}
+}
+
+// WithDebugRequests writes the access entries of 1xx, 2xx and 3xx responses at Debug
+// level instead of Info. Client and server errors keep their levels.
+func WithDebugRequests() RequestLogOption {
+	return func(o *requestLogOptions) {
+		o.debug = true
+	}
+}
+
+// HTTPMiddleware logs one access entry per request and makes a request-scoped logger
+// available to handlers. The logger is bound with request_id, method, route and
+// client_ip and stored in the request context, so handlers retrieve it with
+// FromContext(r.Context()) and their entries inherit those fields. The access entry is
+// written at Info level, Warn for 4xx responses and Error for 5xx responses.
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return RequestLogger()(next)
+}
+
+// WithTrustedProxies makes client_ip honour the X-Forwarded-For header of requests
+// received from the given proxies, IP addresses or CIDR ranges such as "10.0.0.0/8".
+// The client is the last address of the header that is not a trusted proxy. Without
+// this option, the header is ignored, since any client can set it. RequestLogger
+// panics if a proxy is neither an IP address nor a CIDR range.
+func WithTrustedProxies(proxies ...string) RequestLogOption {
+	return func(o *requestLogOptions) {
+		for _, proxy := range proxies {
+			o.trustedProxies = append(o.trustedProxies, parseProxy(proxy))
+		}
+	}
+}
+
+// parseProxy returns the network of proxy, an IP address or a CIDR range.
+func parseProxy(proxy string) *net.IPNet {
+	if _, network, err := net.ParseCIDR(proxy); err == nil {
+		return network
+	}
+	ip := net.ParseIP(proxy)
+	if ip == nil {
+		panic(fmt.Sprintf("sazabi: invalid trusted proxy %q", proxy))
+	}
+	bits := 8 * net.IPv4len
+	if ip.To4() == nil {
+		bits = 8 * net.IPv6len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
+// RequestLogger returns a middleware behaving like HTTPMiddleware, configured by opts.
+func RequestLogger(opts ...RequestLogOption) func(http.Handler) http.Handler {
+	o := &requestLogOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return o.handler(next)
+	}
+}
+
+// handler returns next wrapped with request logging.
+func (o *requestLogOptions) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		idHeader := o.idHeader
+		if idHeader == "" {
+			idHeader = RequestIDHeader
+		}
+		requestID := r.Header.Get(idHeader)
+		if requestID == "" {
+			requestID = currentOptions().newID()
+			w.Header().Set(idHeader, requestID)
+		}
+
+		l := directLogger().With(
+			"request_id", requestID,
+			"method", r.Method,
+			"route", r.URL.Path,
+			"client_ip", o.clientIP(r),
+		)
+
+		rw := &responseWriter{ResponseWriter: w}
+		defer func() {
+			// The access entry is written even if the handler panics, which is then
+			// propagated to the server.
+			recovered := recover()
+			if recovered == nil && o.skippedPaths[r.URL.Path] {
+				return
+			}
+			status := rw.status()
+			if recovered != nil && rw.code == 0 {
+				status = http.StatusInternalServerError
+			}
+			res := ResponseInfo{Status: status, Bytes: rw.bytes, Duration: time.Since(start), Header: w.Header()}
+			if o.accessLog {
+				rec := NewAccessRecord(r, res)
+				rec.RemoteHost, rec.Time = o.clientIP(r), start
+				AccessLog(rec)
+			}
+			fields := []zap.Field{
+				zap.Int("status", status),
+				zap.Int64("bytes", rw.bytes),
+				zap.Duration("duration", res.Duration),
+				zap.String("user_agent", r.UserAgent()),
+			}
+			if len(o.loggedHeaders) > 0 {
+				fields = append(fields,
+					Headers("request_headers", r.Header, o.loggedHeaders...),
+					Headers("response_headers", w.Header(), o.loggedHeaders...),
+				)
+			}
+			for _, fn := range o.fieldFuncs {
+				fields = append(fields, pairFields(fn(r, res))...)
+			}
+			if recovered == nil {
+				if ce := l.Desugar().Check(o.level(r, res), HTTPRequestMessage); ce != nil {
+					ce.Write(fields...)
+				}
+				return
+			}
+			l.Desugar().Error(HTTPRequestMessage, append(fields, zap.String(HTTPPanicKey, fmt.Sprint(recovered)))...)
+			panic(recovered)
+		}()
+		next.ServeHTTP(rw, r.WithContext(NewContext(r.Context(), l)))
+	})
+}
+
+// level returns the level of the access entry of request r answered with res.
+func (o *requestLogOptions) level(r *http.Request, res ResponseInfo) zapcore.Level {
+	if o.levelFunc != nil {
+		return o.levelFunc(r, res)
+	}
+	switch status := res.Status; {
+	case status >= http.StatusInternalServerError:
+		return zapcore.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return zapcore.WarnLevel
+	case o.debug:
+		return zapcore.DebugLevel
+	}
+	return zapcore.InfoLevel
+}
+
+// responseWriter records the status code and number of bytes written by a handler.
+type responseWriter struct {
+	http.ResponseWriter
+	code  int   // Status code written, zero until WriteHeader or Write is called
+	bytes int64 // Number of body bytes written
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *responseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter.
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher when the underlying writer supports it.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer supports it, so that
+// websocket upgrades work through the middleware.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("sazabi: response writer does not support hijacking")
+	}
+	if w.code == 0 {
+		w.code = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// ReadFrom implements io.ReaderFrom, keeping the optimizations of the underlying writer,
+// such as sendfile, when it supports them.
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, r)
+	}
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// status returns the status code sent to the client, which is 200 when the handler
+// never called WriteHeader.
+func (w *responseWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// randomID returns a random 16-byte hexadecimal ID.
+func randomID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// clientIP returns the originating client address: the host part of the remote
+// address, or when it is a trusted proxy, the last X-Forwarded-For entry that is not.
+func (o *requestLogOptions) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !o.trusted(host) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		host = hop
+		if !o.trusted(hop) {
+			break
+		}
+	}
+	return host
+}
+
+// trusted reports whether addr is one of the trusted proxies.
+func (o *requestLogOptions) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range o.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,320 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestHTTPMiddlewareRequestLogger(t *testing.T) {
+	handler := sazabi.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sazabi.FromContext(r.Context()).Infow("handler entry", "user", "alice")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		req.Header.Set(sazabi.RequestIDHeader, "req-123")
+		req.RemoteAddr = "203.0.113.7:51234"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	})
+
+	bound := map[string]interface{}{
+		"request_id": "req-123",
+		"method":     http.MethodPost,
+		"route":      "/users",
+		"client_ip":  "203.0.113.7",
+	}
+
+	handlerFields := entryFields(t, output, "handler entry")
+	if handlerFields["user"] != "alice" {
+		t.Errorf("handler entry should keep its own fields, got %v", handlerFields)
+	}
+	accessFields := entryFields(t, output, sazabi.HTTPRequestMessage)
+	if accessFields["status"] != float64(http.StatusCreated) || accessFields["bytes"] != float64(len("created")) {
+		t.Errorf("access entry should record status and bytes, got %v", accessFields)
+	}
+
+	for key, want := range bound {
+		if got := handlerFields[key]; got != want {
+			t.Errorf("handler entry field %q = %v, want %v", key, got, want)
+		}
+		if got := accessFields[key]; got != want {
+			t.Errorf("access entry field %q = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestHTTPMiddlewareGeneratesRequestID(t *testing.T) {
+	var fromContext sazabi.Logger
+	handler := sazabi.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext = sazabi.FromContext(r.Context())
+	}))
+
+	recorder := httptest.NewRecorder()
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+
+	requestID := recorder.Header().Get(sazabi.RequestIDHeader)
+	if len(requestID) != 32 {
+		t.Errorf("response should carry a generated request ID, got %q", requestID)
+	}
+	if fields := entryFields(t, output, sazabi.HTTPRequestMessage); fields["request_id"] != requestID || fields["status"] != float64(http.StatusOK) {
+		t.Errorf("access entry = %v, want request_id %q and status 200", fields, requestID)
+	}
+	if fromContext == nil {
+		t.Error("FromContext() should return the request-scoped logger")
+	}
+}
+
+func TestHTTPMiddlewareClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []sazabi.RequestLogOption
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{name: "spoofed header ignored", remote: "203.0.113.7:51234", forwarded: "198.51.100.1", want: "203.0.113.7"},
+		{name: "untrusted peer", opts: []sazabi.RequestLogOption{sazabi.WithTrustedProxies("10.0.0.0/8")},
+			remote: "203.0.113.7:51234", forwarded: "198.51.100.1", want: "203.0.113.7"},
+		{name: "trusted proxy", opts: []sazabi.RequestLogOption{sazabi.WithTrustedProxies("10.0.0.0/8")},
+			remote: "10.0.0.2:51234", forwarded: "198.51.100.1", want: "198.51.100.1"},
+		{name: "client spoofing behind proxy", opts: []sazabi.RequestLogOption{sazabi.WithTrustedProxies("10.0.0.0/8", "192.0.2.1")},
+			remote: "10.0.0.2:51234", forwarded: "1.2.3.4, 198.51.100.1, 192.0.2.1", want: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := sazabi.RequestLogger(tt.opts...)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			output := captureStderr(t, func() {
+				sazabi.Initialize(sazabi.ProductionEnvName)
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = tt.remote
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			})
+			if got := entryFields(t, output, sazabi.HTTPRequestMessage)["client_ip"]; got != tt.want {
+				t.Errorf("client_ip = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewarePanic(t *testing.T) {
+	handler := sazabi.HTTPMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("handler failed")
+	}))
+
+	var recovered interface{}
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		func() {
+			defer func() { recovered = recover() }()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}()
+	})
+
+	if recovered != "handler failed" {
+		t.Errorf("recovered %v, want the panic propagated", recovered)
+	}
+	line := lineContaining(output, sazabi.HTTPRequestMessage)
+	fields := entryFields(t, output, sazabi.HTTPRequestMessage)
+	if !strings.Contains(line, "\tERROR\t") || fields["status"] != float64(http.StatusInternalServerError) || fields[sazabi.HTTPPanicKey] != "handler failed" {
+		t.Errorf("access entry = %s, want an error with status 500 and the panic", line)
+	}
+}
+
+func TestHTTPMiddlewareHijack(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	server := httptest.NewServer(sazabi.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijack() error = %v", err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		buf.Flush()
+	})))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "hijacked" {
+		t.Errorf("body = %q, want the hijacked connection's response", body)
+	}
+}
+
+func TestHTTPMiddlewareReadFrom(t *testing.T) {
+	handler := sazabi.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(io.ReaderFrom); !ok {
+			t.Error("response writer does not implement io.ReaderFrom")
+		}
+		if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok || u.Unwrap() == nil {
+			t.Error("response writer does not unwrap")
+		}
+		io.Copy(w, strings.NewReader("copied body"))
+	}))
+
+	recorder := httptest.NewRecorder()
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+
+	if recorder.Body.String() != "copied body" {
+		t.Errorf("body = %q", recorder.Body.String())
+	}
+	if fields := entryFields(t, output, sazabi.HTTPRequestMessage); fields["bytes"] != float64(len("copied body")) {
+		t.Errorf("access entry = %v, want the copied bytes counted", fields)
+	}
+}
+
+func TestHTTPMiddlewareLevels(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []sazabi.RequestLogOption
+		status int
+		write  bool // The handler writes a body without calling WriteHeader
+		want   string
+	}{
+		{name: "implicit 200", write: true, status: http.StatusOK, want: "INFO"},
+		{name: "no response", status: http.StatusOK, want: "INFO"},
+		{name: "redirect", status: http.StatusFound, want: "INFO"},
+		{name: "client error", status: http.StatusNotFound, want: "WARN"},
+		{name: "server error", status: http.StatusServiceUnavailable, want: "ERROR"},
+		{name: "debug success", opts: []sazabi.RequestLogOption{sazabi.WithDebugRequests()}, status: http.StatusNoContent, want: "DEBUG"},
+		{name: "debug client error", opts: []sazabi.RequestLogOption{sazabi.WithDebugRequests()}, status: http.StatusBadRequest, want: "WARN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := sazabi.RequestLogger(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.write {
+					w.Write([]byte("body"))
+				} else if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+				}
+			}))
+			output := captureStderr(t, func() {
+				sazabi.Initialize("development")
+				req := httptest.NewRequest(http.MethodGet, "/items", nil)
+				req.Header.Set("User-Agent", "probe/1.0")
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			})
+
+			line := lineContaining(output, sazabi.HTTPRequestMessage)
+			if !strings.Contains(line, "\t"+tt.want+"\t") {
+				t.Errorf("access entry = %q, want level %s", line, tt.want)
+			}
+			for _, want := range []string{`"status": ` + strconv.Itoa(tt.status), `"user_agent": "probe/1.0"`, `"duration": `} {
+				if !strings.Contains(line, want) {
+					t.Errorf("access entry = %q, want %s", line, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareSkippedPaths(t *testing.T) {
+	var fromContext sazabi.Logger
+	handler := sazabi.RequestLogger(sazabi.WithSkippedPaths("/healthz", "/readyz"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext = sazabi.FromContext(r.Context())
+	}))
+
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/readyz?full=1", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz/deep", nil))
+	})
+
+	if n := strings.Count(output, sazabi.HTTPRequestMessage); n != 1 {
+		t.Errorf("got %d access entries, want only the one of /healthz/deep:\n%s", n, output)
+	}
+	if fields := entryFields(t, output, sazabi.HTTPRequestMessage); fields["route"] != "/healthz/deep" {
+		t.Errorf("access entry = %v, want route /healthz/deep", fields)
+	}
+	if fromContext == nil {
+		t.Error("handlers of skipped paths should get the request-scoped logger")
+	}
+}
+
+func TestRequestLoggerServeMux(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("alice"))
+	})
+	mux.HandleFunc("/admin/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	handler := sazabi.RequestLogger(
+		sazabi.WithRequestIDHeader("X-Correlation-ID"),
+		sazabi.WithRequestFields(func(r *http.Request, res sazabi.ResponseInfo) []interface{} {
+			_, pattern := mux.Handler(r)
+			return []interface{}{"pattern", pattern, "content_type", res.Header.Get("Content-Type")}
+		}),
+		sazabi.WithRequestLevel(func(r *http.Request, res sazabi.ResponseInfo) zapcore.Level {
+			if strings.HasPrefix(r.URL.Path, "/admin/") {
+				return zapcore.ErrorLevel
+			}
+			return zapcore.DebugLevel
+		}),
+	)(mux)
+
+	recorder := httptest.NewRecorder()
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithJSONEncoding())
+		sazabi.SetLevel("debug")
+		req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+		req.Header.Set("X-Correlation-ID", "corr-1")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+	})
+
+	var access []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if strings.Contains(line, sazabi.HTTPRequestMessage) {
+			access = append(access, entryFields(t, line, sazabi.HTTPRequestMessage))
+		}
+	}
+	if len(access) != 2 {
+		t.Fatalf("got %d access entries, want 2:\n%s", len(access), output)
+	}
+	want := []map[string]interface{}{
+		{"level": "DEBUG", "request_id": "corr-1", "pattern": "/users/", "content_type": "text/plain; charset=utf-8"},
+		{"level": "ERROR", "pattern": "/admin/", "status": float64(http.StatusForbidden)},
+	}
+	for i, fields := range access {
+		for key, value := range want[i] {
+			if fields[key] != value {
+				t.Errorf("access entry %d field %q = %v, want %v", i, key, fields[key], value)
+			}
+		}
+		// The caller is the middleware, not zap or the test.
+		if caller, _ := fields["caller"].(string); !strings.Contains(caller, "/http.go:") {
+			t.Errorf("access entry %d caller = %v, want the middleware", i, fields["caller"])
+		}
+	}
+	if id := recorder.Header().Get("X-Correlation-ID"); id == "" || recorder.Header().Get(sazabi.RequestIDHeader) != "" {
+		t.Errorf("response headers = %v, want a generated ID under X-Correlation-ID only", recorder.Header())
+	}
+}
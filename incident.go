@@ -0,0 +1,269 @@
+package sazabi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+	"unsafe"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithIncidentBuffer keeps every entry of the global logger, at any level, for the last
+// window and within maxBytes of memory, so that a snapshot of recent activity can be
+// taken during an incident (see Snapshot). Entries below the level of the logger, such
+// as Debug entries in production, are recorded even though they are not written to the
+// outputs. Entries are evicted oldest first, once older than window or to make room
+// within maxBytes. The buffer survives re-initialization with the same parameters.
+// New ignores this option, since only the global logger is snapshotted.
+func WithIncidentBuffer(window time.Duration, maxBytes int) Option {
+	return func(o *options) {
+		o.incidentWindow = window
+		o.incidentMaxBytes = maxBytes
+	}
+}
+
+// incidentEntryOverhead is the memory accounted for an entry in addition to its strings
+// and encoded fields.
+const incidentEntryOverhead = int(unsafe.Sizeof(incidentEntry{}))
+
+// incidentEntry is an entry kept by an incidentBuffer.
+type incidentEntry struct {
+	ent      zapcore.Entry
+	fields   []byte    // Fields of the entry, encoded as a JSON object
+	recorded time.Time // Time the entry was recorded, which drives eviction
+	size     int       // Memory accounted for the entry
+}
+
+// incidentBuffer keeps the entries recorded during a rolling window, within a memory
+// budget.
+type incidentBuffer struct {
+	window   time.Duration
+	maxBytes int
+
+	mu      sync.Mutex
+	entries []incidentEntry // Oldest first
+	size    int             // Sum of the sizes of entries
+}
+
+// incident is the buffer of the global logger, nil when WithIncidentBuffer is not in
+// effect.
+var incident struct {
+	sync.Mutex
+	buffer *incidentBuffer
+}
+
+// configureIncident returns the incident buffer to use for window and maxBytes, reusing
+// the current one when they are unchanged so that its content survives
+// re-initialization.
+func configureIncident(window time.Duration, maxBytes int) *incidentBuffer {
+	incident.Lock()
+	defer incident.Unlock()
+
+	switch {
+	case window <= 0 || maxBytes <= 0:
+		incident.buffer = nil
+	case incident.buffer == nil || incident.buffer.window != window || incident.buffer.maxBytes != maxBytes:
+		incident.buffer = &incidentBuffer{window: window, maxBytes: maxBytes}
+	}
+	return incident.buffer
+}
+
+// currentIncident returns the incident buffer of the global logger, or nil.
+func currentIncident() *incidentBuffer {
+	incident.Lock()
+	defer incident.Unlock()
+
+	return incident.buffer
+}
+
+// record adds e, evicting the entries that are too old or do not fit anymore. An entry
+// larger than the whole budget is dropped.
+func (b *incidentBuffer) record(e incidentEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.evictExpired(e.recorded)
+	if e.size > b.maxBytes {
+		return
+	}
+	for b.size+e.size > b.maxBytes {
+		b.evictOldest()
+	}
+	b.entries = append(b.entries, e)
+	b.size += e.size
+}
+
+// evictExpired removes the entries recorded before the window ending at now. b.mu must
+// be held.
+func (b *incidentBuffer) evictExpired(now time.Time) {
+	cutoff := now.Add(-b.window)
+	for len(b.entries) > 0 && b.entries[0].recorded.Before(cutoff) {
+		b.evictOldest()
+	}
+}
+
+// evictOldest removes the oldest entry. b.mu must be held.
+func (b *incidentBuffer) evictOldest() {
+	b.size -= b.entries[0].size
+	b.entries[0] = incidentEntry{} // Release the entry's memory
+	b.entries = b.entries[1:]
+}
+
+// since returns the entries recorded during the last d, oldest first.
+func (b *incidentBuffer) since(d time.Duration) []incidentEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.evictExpired(now)
+	cutoff := now.Add(-d)
+	i := len(b.entries)
+	for i > 0 && !b.entries[i-1].recorded.Before(cutoff) {
+		i--
+	}
+	return append([]incidentEntry(nil), b.entries[i:]...)
+}
+
+// Snapshot returns the entries recorded by WithIncidentBuffer during the last since,
+// oldest first, or nil when the option is not in effect. Field values are those of the
+// entries encoded as JSON and decoded again: numbers are float64, objects are
+// map[string]interface{}.
+func Snapshot(since time.Duration) []CapturedEntry {
+	b := currentIncident()
+	if b == nil {
+		return nil
+	}
+	recorded := b.since(since)
+	entries := make([]CapturedEntry, len(recorded))
+	for i, e := range recorded {
+		entries[i] = CapturedEntry{
+			Level:      e.ent.Level,
+			Time:       e.ent.Time,
+			LoggerName: e.ent.LoggerName,
+			Message:    e.ent.Message,
+			Caller:     e.ent.Caller,
+			Fields:     decodeFields(e.fields),
+		}
+	}
+	return entries
+}
+
+// SnapshotTo writes the entries returned by Snapshot to w as JSON lines, with the keys
+// of the production encoding.
+func SnapshotTo(w io.Writer, since time.Duration) error {
+	b := currentIncident()
+	if b == nil {
+		return nil
+	}
+	enc := zapcore.NewJSONEncoder(newProductionEncoderConfig())
+	for _, e := range b.since(since) {
+		buf, err := enc.EncodeEntry(e.ent, decodeFields(e.fields))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(buf.Bytes())
+		buf.Free()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeFields returns the fields of the JSON object data, in order.
+func decodeFields(data []byte) []zapcore.Field {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil { // Opening brace
+		return nil
+	}
+	var fields []zapcore.Field
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			break
+		}
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			break
+		}
+		fields = append(fields, zap.Any(key.(string), value))
+	}
+	return fields
+}
+
+// incidentCore records every entry in an incidentBuffer. It is teed with the core of
+// the global logger, so it also sees the entries that core drops for their level.
+type incidentCore struct {
+	buffer *incidentBuffer
+	enc    zapcore.Encoder // Encodes the fields only, including those added through With
+}
+
+// newIncidentCore returns the core recording into b, with redaction and dynamic fields
+// applied.
+func newIncidentCore(b *incidentBuffer) zapcore.Core {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	})
+	return newDynamicCore(newRedactCore(&incidentCore{buffer: b, enc: enc}))
+}
+
+// Enabled implements zapcore.LevelEnabler. Every level is recorded.
+func (c *incidentCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+// With implements zapcore.Core.
+func (c *incidentCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &incidentCore{buffer: c.buffer, enc: enc}
+}
+
+// Check implements zapcore.Core.
+func (c *incidentCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+// Write implements zapcore.Core.
+func (c *incidentCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(zapcore.Entry{}, fields) // The encoder has no key for the entry itself
+	if err != nil {
+		return err
+	}
+	encoded := bytes.TrimRight(buf.Bytes(), "\n")
+	e := incidentEntry{ent: ent, fields: append(make([]byte, 0, len(encoded)), encoded...), recorded: time.Now()}
+	buf.Free()
+
+	e.size = incidentEntryOverhead + len(e.fields) + len(ent.Message) + len(ent.LoggerName) +
+		len(ent.Stack) + len(ent.Caller.File) + len(ent.Caller.Function)
+	c.buffer.record(e)
+	return nil
+}
+
+// Sync implements zapcore.Core.
+func (c *incidentCore) Sync() error {
+	return nil
+}
+
+// withIncident returns log teed with a core recording into b, if any, placed outside the
+// level checks, sampling and hooks of log so that it sees every entry. fields are the
+// fields log adds to its entries, which the recording core must add as well.
+func withIncident(log *zap.Logger, b *incidentBuffer, fields []zapcore.Field) *zap.Logger {
+	if b == nil {
+		return log
+	}
+	recorder := newIncidentCore(b)
+	if len(fields) > 0 {
+		recorder = recorder.With(fields)
+	}
+	return log.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, recorder)
+	}))
+}
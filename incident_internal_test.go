@@ -0,0 +1,39 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestIncidentBufferAccounting(t *testing.T) {
+	const maxBytes = 2048
+	b := &incidentBuffer{window: time.Minute, maxBytes: maxBytes}
+	core := newIncidentCore(b).With([]zapcore.Field{zap.String("service", "billing")})
+
+	for i := 0; i < 100; i++ {
+		core.Write(zapcore.Entry{Message: "entry", Time: time.Now()}, []zapcore.Field{zap.Int("i", i)})
+
+		sum := 0
+		for _, e := range b.entries {
+			sum += e.size
+		}
+		if b.size != sum || b.size > maxBytes {
+			t.Fatalf("after %d entries: size = %d, sum = %d, want equal and at most %d", i+1, b.size, sum, maxBytes)
+		}
+	}
+	if got := string(b.entries[len(b.entries)-1].fields); got != `{"service":"billing","i":99}` {
+		t.Errorf("fields = %s, want the context and entry fields", got)
+	}
+
+	core.Write(zapcore.Entry{Message: strings.Repeat("x", maxBytes)}, nil)
+	if last := b.entries[len(b.entries)-1]; strings.HasPrefix(last.ent.Message, "xxx") {
+		t.Error("an entry larger than the budget was kept")
+	}
+}
@@ -0,0 +1,137 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// The buffer survives re-initialization with the same parameters, so every test uses
+// its own parameters to start empty.
+
+// snapshot returns sazabi.Snapshot(since) without the warning about the
+// re-initialization done by the test.
+func snapshot(since time.Duration) []sazabi.CapturedEntry {
+	var entries []sazabi.CapturedEntry
+	for _, e := range sazabi.Snapshot(since) {
+		if e.Message != sazabi.ConflictingInitializeMessage {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func TestIncidentBufferKeepsDebug(t *testing.T) {
+	stderr := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithIncidentBuffer(time.Minute, 1<<20))
+		sazabi.Debugw("cache miss", "key", "user:42")
+		sazabi.Info("request served")
+	})
+
+	if strings.Contains(stderr, "cache miss") {
+		t.Errorf("stderr contains the debug entry:\n%s", stderr)
+	}
+	entries := snapshot(time.Minute)
+	if len(entries) != 2 {
+		t.Fatalf("Snapshot() = %+v, want 2 entries", entries)
+	}
+	if entries[0].Message != "cache miss" || entries[0].Level.String() != "debug" || entries[1].Message != "request served" {
+		t.Errorf("Snapshot() = %+v, want the debug entry then the info entry", entries)
+	}
+	if f, ok := entries[0].Field("key"); !ok || f.String != "user:42" {
+		t.Errorf("key field = %+v, want user:42", f)
+	}
+}
+
+func TestIncidentBufferByteCap(t *testing.T) {
+	const maxBytes = 4096
+	sazabi.Initialize("development", sazabi.WithOutputPaths(t.TempDir()+"/app.log"),
+		sazabi.WithIncidentBuffer(time.Minute, maxBytes))
+
+	for i := 0; i < 200; i++ {
+		sazabi.Debugw(fmt.Sprintf("entry-%03d", i), "payload", strings.Repeat("x", 64))
+	}
+
+	entries := snapshot(time.Minute)
+	if len(entries) == 0 || len(entries) >= 200 {
+		t.Fatalf("Snapshot() has %d entries, want some evicted", len(entries))
+	}
+	first := 200 - len(entries)
+	for i, e := range entries {
+		if want := fmt.Sprintf("entry-%03d", first+i); e.Message != want {
+			t.Fatalf("entry %d = %q, want %q: the oldest entries must be evicted first", i, e.Message, want)
+		}
+	}
+}
+
+func TestIncidentBufferWindow(t *testing.T) {
+	sazabi.Initialize("development", sazabi.WithOutputPaths(t.TempDir()+"/app.log"),
+		sazabi.WithIncidentBuffer(100*time.Millisecond, 1<<20))
+
+	sazabi.Info("expired")
+	time.Sleep(150 * time.Millisecond)
+	sazabi.Info("recent")
+
+	entries := snapshot(time.Hour)
+	if len(entries) != 1 || entries[0].Message != "recent" {
+		t.Errorf("Snapshot() = %+v, want only the entry within the window", entries)
+	}
+}
+
+func TestSnapshotSince(t *testing.T) {
+	sazabi.Initialize("development", sazabi.WithOutputPaths(t.TempDir()+"/app.log"),
+		sazabi.WithIncidentBuffer(time.Minute, 1<<21))
+
+	sazabi.Info("older")
+	time.Sleep(100 * time.Millisecond)
+	sazabi.Info("newer")
+
+	if entries := snapshot(50 * time.Millisecond); len(entries) != 1 || entries[0].Message != "newer" {
+		t.Errorf("Snapshot(50ms) = %+v, want only the newer entry", entries)
+	}
+	if entries := snapshot(time.Minute); len(entries) != 2 {
+		t.Errorf("Snapshot(1m) = %+v, want both entries", entries)
+	}
+}
+
+func TestSnapshotTo(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths(t.TempDir()+"/app.log"),
+		sazabi.WithIncidentBuffer(time.Minute, 1<<22))
+	redacted := sazabi.AddRedactedKeys("token")
+	defer redacted.Remove()
+
+	sazabi.Named("auth").Debugw("token refreshed", "token", "abc123", "user", "alice")
+
+	var buf bytes.Buffer
+	if err := sazabi.SnapshotTo(&buf, time.Minute); err != nil {
+		t.Fatalf("SnapshotTo() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+		t.Fatalf("SnapshotTo() wrote %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "token refreshed" || entry["level"] != "DEBUG" || entry["logger"] != "auth" {
+		t.Errorf("entry = %v, want the debug entry of auth", entry)
+	}
+	if entry["token"] != sazabi.RedactedValue || entry["user"] != "alice" {
+		t.Errorf("entry = %v, want the token redacted", entry)
+	}
+}
+
+func TestSnapshotDisabled(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths(t.TempDir()+"/app.log"))
+	sazabi.Info("not kept")
+
+	if entries := snapshot(time.Minute); entries != nil {
+		t.Errorf("Snapshot() = %+v, want nil without WithIncidentBuffer", entries)
+	}
+}
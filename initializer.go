@@ -0,0 +1,111 @@
+package sazabi
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrConflictingInitialize is returned by TryInitialize, and raised as a panic value
+// by Initialize, when WithStrictSingleInit is in effect and the logger is initialized
+// again with a different environment or different options.
+var ErrConflictingInitialize = errors.New("sazabi: conflicting logger initialization")
+
+// ConflictingInitializeMessage is the message of the Warn entry emitted when the
+// logger is re-initialized with a different environment or different options.
+const ConflictingInitializeMessage = "logger re-initialized with a different configuration"
+
+// Initializer describes a completed call to Initialize.
+type Initializer struct {
+	Environment string    // Environment passed to Initialize
+	Caller      string    // Call site of Initialize, formatted as file:line
+	Time        time.Time // Time the initialization completed
+
+	signature string // Summary of the options used, to detect conflicting calls
+	strict    bool   // Whether WithStrictSingleInit was in effect
+}
+
+// initMu serializes initializations, lastInit records the most recent one and
+// initializeCount how many have completed.
+var (
+	initMu          sync.Mutex
+	lastInit        *Initializer
+	initializeCount int64
+)
+
+// InitializeCount returns how many times the global logger has been initialized.
+// Values greater than one indicate re-initialization, which health checks may want to flag.
+func InitializeCount() int64 {
+	return atomic.LoadInt64(&initializeCount)
+}
+
+// LastInitializer returns the most recent initialization of the global logger.
+// The boolean result is false when the logger has not been initialized yet.
+func LastInitializer() (Initializer, bool) {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	if lastInit == nil {
+		return Initializer{}, false
+	}
+	return *lastInit, true
+}
+
+// WithStrictSingleInit turns a conflicting re-initialization into an error: instead of
+// replacing the logger and emitting a warning, TryInitialize returns an error wrapping
+// ErrConflictingInitialize, Initialize panics with it, and the existing logger is kept.
+func WithStrictSingleInit() Option {
+	return func(o *options) {
+		o.strictSingleInit = true
+	}
+}
+
+// checkInitializer compares current with the previous initialization, reporting the
+// previous one and whether the two conflict. In strict mode a conflict is returned as
+// an error wrapping ErrConflictingInitialize. initMu must be held.
+func checkInitializer(current Initializer, strict bool) (Initializer, bool, error) {
+	if lastInit == nil {
+		return Initializer{}, false, nil
+	}
+
+	previous := *lastInit
+	if previous.Environment == current.Environment && previous.signature == current.signature {
+		return previous, false, nil
+	}
+
+	if strict || previous.strict {
+		return previous, true, fmt.Errorf("%w: %q at %s conflicts with %q at %s", ErrConflictingInitialize,
+			current.Environment, current.Caller, previous.Environment, previous.Caller)
+	}
+	return previous, true, nil
+}
+
+// recordInitializer stores current as the latest initialization and returns the new
+// initialization count. initMu must be held.
+func recordInitializer(current Initializer) int64 {
+	current.Time = time.Now()
+	lastInit = &current
+	return atomic.AddInt64(&initializeCount, 1)
+}
+
+// warnConflictingInitialize logs a warning identifying both initialization call sites.
+func warnConflictingInitialize(previous, current Initializer) {
+	logger().Warnw(ConflictingInitializeMessage,
+		"previous_environment", previous.Environment,
+		"previous_initializer", previous.Caller,
+		"environment", current.Environment,
+		"initializer", current.Caller,
+	)
+}
+
+// callerLocation returns the package/file:line of the function skip frames above the caller,
+// in the same short format used for the caller field of log entries.
+func callerLocation(skip int) string {
+	pc, file, line, ok := runtime.Caller(skip)
+	return zapcore.NewEntryCaller(pc, file, line, ok).TrimmedPath()
+}
@@ -0,0 +1,147 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestConflictingInitializeWarning(t *testing.T) {
+	sazabi.Initialize("development")
+	first, ok := sazabi.LastInitializer()
+	if !ok {
+		t.Fatal("LastInitializer() should report an initialization")
+	}
+	count := sazabi.InitializeCount()
+
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+	})
+
+	if got := sazabi.InitializeCount(); got != count+1 {
+		t.Errorf("InitializeCount() = %d, want %d", got, count+1)
+	}
+
+	second, _ := sazabi.LastInitializer()
+	if second.Environment != sazabi.ProductionEnvName {
+		t.Errorf("LastInitializer().Environment = %q, want %q", second.Environment, sazabi.ProductionEnvName)
+	}
+	if !strings.Contains(second.Caller, "initializer_test.go:") || second.Caller == first.Caller {
+		t.Errorf("LastInitializer().Caller = %q, want a distinct initializer_test.go call site", second.Caller)
+	}
+
+	line := lineContaining(output, sazabi.ConflictingInitializeMessage)
+	if line == "" {
+		t.Fatalf("expected a conflicting initialization warning, got: %s", output)
+	}
+	for _, want := range []string{"WARN", first.Caller, second.Caller, `"previous_environment": "development"`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("warning %q should contain %q", line, want)
+		}
+	}
+}
+
+func TestIdenticalInitializeDoesNotWarn(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+	})
+
+	if strings.Contains(output, sazabi.ConflictingInitializeMessage) {
+		t.Errorf("identical re-initialization should not warn, got: %s", output)
+	}
+}
+
+func TestWithStrictSingleInit(t *testing.T) {
+	sazabi.Initialize("development")
+	before, _ := sazabi.LastInitializer()
+
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		if !ok || !errors.Is(err, sazabi.ErrConflictingInitialize) {
+			t.Fatalf("Initialize() should panic with ErrConflictingInitialize, got %v", r)
+		}
+		if !strings.Contains(err.Error(), before.Caller) {
+			t.Errorf("error %q should identify the previous call site %q", err, before.Caller)
+		}
+
+		after, _ := sazabi.LastInitializer()
+		if after.Environment != "development" {
+			t.Errorf("the existing logger should be kept, LastInitializer().Environment = %q", after.Environment)
+		}
+	}()
+
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithStrictSingleInit())
+}
+
+func TestTryInitializeStrictConflict(t *testing.T) {
+	sazabi.Initialize("development")
+	restoreDefault(t)
+
+	err := sazabi.TryInitialize(sazabi.ProductionEnvName, sazabi.WithStrictSingleInit())
+	if !errors.Is(err, sazabi.ErrConflictingInitialize) {
+		t.Fatalf("TryInitialize() error = %v, want ErrConflictingInitialize", err)
+	}
+	if after, _ := sazabi.LastInitializer(); after.Environment != "development" {
+		t.Errorf("the existing logger should be kept, LastInitializer().Environment = %q", after.Environment)
+	}
+}
+
+func TestTryInitializeInvalidOption(t *testing.T) {
+	sazabi.Initialize("development")
+	restoreDefault(t)
+
+	if err := sazabi.TryInitialize("development", sazabi.WithEmptyMessagePolicy("ignore")); err == nil {
+		t.Error("TryInitialize() succeeded with an unknown empty message policy")
+	}
+}
+
+// TestInitializeWhileLogging re-initializes the global logger and changes its runtime
+// settings while goroutines log through it. Run with -race to detect unsynchronized
+// accesses to the global logger.
+func TestInitializeWhileLogging(t *testing.T) {
+	restoreDefault(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths(path))
+
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; ; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					sazabi.Info("racing entry")
+					sazabi.Infow("racing entry", "goroutine", g, "i", i)
+					sazabi.InfoFields("racing entry", sazabi.F("goroutine", g))
+					sazabi.GetLevel()
+				}
+			}(g)
+		}
+
+		environments := []string{sazabi.ProductionEnvName, "development"}
+		for i := 0; i < 50; i++ {
+			sazabi.Initialize(environments[i%2], sazabi.WithOutputPaths(path))
+			sazabi.SetLevel("debug")
+			sazabi.SetCallerEnabled(i%3 != 0)
+		}
+		close(stop)
+		wg.Wait()
+	})
+}
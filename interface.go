@@ -0,0 +1,75 @@
+package sazabi
+
+import (
+	"github.com/zeroxsolutions/barbatos/log"
+)
+
+// Logger is the logging interface returned by sazabi, offering unformatted, formatted
+// and structured (key-value) variants for every severity level.
+// Its method set is identical to barbatos log.Logger, so values of either type can be
+// assigned to the other, but consumers do not need to import barbatos to use it.
+type Logger interface {
+	// Debug logs debug messages without any specific formatting.
+	Debug(args ...interface{})
+	// Debugf logs formatted debug messages using a format template and additional arguments.
+	Debugf(template string, args ...interface{})
+	// Debugw logs debug messages with additional key-value pairs for structured logging.
+	Debugw(msg string, keysValues ...interface{})
+
+	// Info logs informational messages without any specific formatting.
+	Info(args ...interface{})
+	// Infof logs formatted informational messages using a format template and additional arguments.
+	Infof(template string, args ...interface{})
+	// Infow logs informational messages with additional key-value pairs for structured logging.
+	Infow(msg string, keysValues ...interface{})
+
+	// Warn logs warning messages without any specific formatting.
+	Warn(args ...interface{})
+	// Warnf logs formatted warning messages using a format template and additional arguments.
+	Warnf(template string, args ...interface{})
+	// Warnw logs warning messages with additional key-value pairs for structured logging.
+	Warnw(msg string, keysValues ...interface{})
+
+	// Error logs error messages without any specific formatting.
+	Error(args ...interface{})
+	// Errorf logs formatted error messages using a format template and additional arguments.
+	Errorf(template string, args ...interface{})
+	// Errorw logs error messages with additional key-value pairs for structured logging.
+	Errorw(msg string, keysValues ...interface{})
+
+	// Panic logs panic messages without any specific formatting and triggers a panic.
+	Panic(args ...interface{})
+	// Panicf logs formatted panic messages using a format template and additional arguments, then triggers a panic.
+	Panicf(template string, args ...interface{})
+	// Panicw logs panic messages with additional key-value pairs for structured logging and then triggers a panic.
+	Panicw(msg string, keysValues ...interface{})
+
+	// Fatal logs fatal messages without any specific formatting and terminates the program.
+	Fatal(args ...interface{})
+	// Fatalf logs formatted fatal messages using a format template and additional arguments, then terminates the program.
+	Fatalf(template string, args ...interface{})
+	// Fatalw logs fatal messages with additional key-value pairs for structured logging and then terminates the program.
+	Fatalw(msg string, keysValues ...interface{})
+}
+
+// Compile-time checks that Logger and barbatos log.Logger stay interchangeable.
+var (
+	_ log.Logger = Logger(nil)
+	_ Logger     = log.Logger(nil)
+)
+
+// FromBarbatos adapts a barbatos logger to Logger.
+func FromBarbatos(l log.Logger) Logger {
+	if l == nil {
+		return nil
+	}
+	return l
+}
+
+// ToBarbatos adapts a Logger to the barbatos logger interface.
+func ToBarbatos(l Logger) log.Logger {
+	if l == nil {
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,54 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"testing"
+
+	"github.com/zeroxsolutions/barbatos/log"
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestLoggerInterfaceSatisfaction(t *testing.T) {
+	var local sazabi.Logger = sazabi.Default()
+	var barbatos log.Logger = sazabi.Default()
+
+	// Both interfaces must remain assignable to each other.
+	local = barbatos
+	barbatos = local
+
+	if local == nil || barbatos == nil {
+		t.Error("Default() should return a non-nil logger")
+	}
+}
+
+func TestFromBarbatos(t *testing.T) {
+	var barbatos log.Logger = sazabi.Default()
+
+	local := sazabi.FromBarbatos(barbatos)
+	if local == nil {
+		t.Fatal("FromBarbatos() should return a non-nil logger")
+	}
+	if local != sazabi.Logger(barbatos) {
+		t.Error("FromBarbatos() should wrap the same logger")
+	}
+	if sazabi.FromBarbatos(nil) != nil {
+		t.Error("FromBarbatos(nil) should return nil")
+	}
+}
+
+func TestToBarbatos(t *testing.T) {
+	local := sazabi.Default()
+
+	barbatos := sazabi.ToBarbatos(local)
+	if barbatos == nil {
+		t.Fatal("ToBarbatos() should return a non-nil logger")
+	}
+	if sazabi.FromBarbatos(barbatos) != local {
+		t.Error("converting back and forth should preserve the logger")
+	}
+	if sazabi.ToBarbatos(nil) != nil {
+		t.Error("ToBarbatos(nil) should return nil")
+	}
+}
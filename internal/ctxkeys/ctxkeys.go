@@ -0,0 +1,24 @@
+// Package ctxkeys defines the keys of every value sazabi stores in a context.
+//
+// Keys are pointers to an unexported-field struct, so they can only be compared with
+// themselves: no key defined elsewhere, whatever its type or name, can collide with
+// them. New context helpers must declare their key here.
+package ctxkeys
+
+// Key is a context key owned by sazabi.
+type Key struct {
+	name string
+}
+
+// String returns the name of the key, as shown when printing a context.
+func (k *Key) String() string {
+	return "sazabi." + k.name
+}
+
+// Keys of the values sazabi stores in contexts.
+var (
+	Logger        = &Key{name: "logger"}         // Request-scoped Logger
+	Fields        = &Key{name: "fields"}         // Key-value pairs added by the Ctx functions
+	CorrelationID = &Key{name: "correlation_id"} // Correlation ID of the operation
+	Tenant        = &Key{name: "tenant"}         // Tenant the operation runs for
+)
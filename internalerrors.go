@@ -0,0 +1,71 @@
+package sazabi
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// maxInternalErrors is the number of internal errors kept for InternalErrors.
+const maxInternalErrors = 100
+
+// WithInternalErrorOutput sends the internal errors of the logger, such as encoder
+// failures and errors writing to the outputs, to path instead of stderr, so that they
+// are not mixed with the entries of the application. path is opened like an output:
+// a file, "stdout", "stderr" or the URL of a registered sink.
+func WithInternalErrorOutput(path string) Option {
+	return func(o *options) {
+		o.internalErrorOutput = path
+	}
+}
+
+// internalErrors keeps the last internal errors reported by the loggers of this
+// package, and counts them all.
+var internalErrors = struct {
+	sync.Mutex
+	count  int64
+	recent []string // Oldest first, at most maxInternalErrors
+}{}
+
+// InternalErrors returns the last internal errors reported by the loggers of this
+// package, oldest first, as written to the internal error output. Only the last 100
+// are kept; InternalErrorCount counts them all.
+func InternalErrors() []string {
+	internalErrors.Lock()
+	defer internalErrors.Unlock()
+
+	return append([]string(nil), internalErrors.recent...)
+}
+
+// InternalErrorCount returns the number of internal errors reported by the loggers of
+// this package since the start of the process.
+func InternalErrorCount() int64 {
+	return atomic.LoadInt64(&internalErrors.count)
+}
+
+// recordInternalError adds msg to the internal errors.
+func recordInternalError(msg string) {
+	atomic.AddInt64(&internalErrors.count, 1)
+
+	internalErrors.Lock()
+	defer internalErrors.Unlock()
+
+	if len(internalErrors.recent) == maxInternalErrors {
+		internalErrors.recent = append(internalErrors.recent[:0], internalErrors.recent[1:]...)
+	}
+	internalErrors.recent = append(internalErrors.recent, msg)
+}
+
+// internalErrorSink is the error output of the loggers, recording every error written
+// to it. zap writes each internal error with a single Write call.
+type internalErrorSink struct {
+	zapcore.WriteSyncer
+}
+
+// Write implements zapcore.WriteSyncer.
+func (s internalErrorSink) Write(p []byte) (int, error) {
+	recordInternalError(strings.TrimRight(string(p), "\n"))
+	return s.WriteSyncer.Write(p)
+}
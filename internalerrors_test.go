@@ -0,0 +1,90 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func init() {
+	// Sink whose writes always fail, like a full disk.
+	err := zap.RegisterSink("failwrite", func(*url.URL) (zap.Sink, error) {
+		return failingSink{}, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// failingSink is a zap.Sink failing every write.
+type failingSink struct{}
+
+func (failingSink) Write([]byte) (int, error) { return 0, errors.New("no space left on device") }
+func (failingSink) Sync() error               { return nil }
+func (failingSink) Close() error              { return nil }
+
+func TestInternalErrorOutput(t *testing.T) {
+	dir := t.TempDir()
+	appLog, errLog := filepath.Join(dir, "app.log"), filepath.Join(dir, "internal.log")
+	var before int64
+
+	stderr := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths(appLog, "failwrite://disk"),
+			sazabi.WithInternalErrorOutput(errLog))
+		before = sazabi.InternalErrorCount() // After the warning about the re-initialization, if any
+		sazabi.Info("application entry")
+	})
+
+	app, err := os.ReadFile(appLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal, err := os.ReadFile(errLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(app), "application entry") || strings.Contains(string(app), "write error") {
+		t.Errorf("application output = %q, want the entry without internal errors", app)
+	}
+	if !strings.Contains(string(internal), "no space left on device") || strings.Contains(string(internal), "application entry") {
+		t.Errorf("internal error output = %q, want the write error only", internal)
+	}
+	if strings.Contains(stderr, "no space left on device") {
+		t.Errorf("internal error written to stderr:\n%s", stderr)
+	}
+
+	if n := sazabi.InternalErrorCount(); n != before+1 {
+		t.Errorf("InternalErrorCount() = %d, want %d", n, before+1)
+	}
+	errs := sazabi.InternalErrors()
+	if len(errs) == 0 || !strings.Contains(errs[len(errs)-1], "write error: no space left on device") {
+		t.Errorf("InternalErrors() = %q, want the write error last", errs)
+	}
+}
+
+func TestInternalErrorsBounded(t *testing.T) {
+	sazabi.Initialize("development", sazabi.WithOutputPaths("failwrite://disk"), // Not sampled
+		sazabi.WithInternalErrorOutput(filepath.Join(t.TempDir(), "internal.log")))
+	before := sazabi.InternalErrorCount()
+
+	for i := 0; i < 150; i++ {
+		sazabi.Infow("failing entry", "i", i)
+	}
+
+	if n := sazabi.InternalErrorCount(); n != before+150 {
+		t.Errorf("InternalErrorCount() = %d, want %d", n, before+150)
+	}
+	if errs := sazabi.InternalErrors(); len(errs) != 100 {
+		t.Errorf("InternalErrors() has %d errors, want the last 100", len(errs))
+	}
+}
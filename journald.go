@@ -0,0 +1,209 @@
+package sazabi
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// JournaldUnavailableMessage is the message of the warning written when WithJournald
+// cannot reach the journal and the logger writes to stderr instead.
+const JournaldUnavailableMessage = "journald unavailable, logging to stderr"
+
+// journalSocketPath is the socket of the native protocol of journald.
+var journalSocketPath = "/run/systemd/journal/socket"
+
+// maxJournalFieldName is the longest field name accepted by journald.
+const maxJournalFieldName = 64
+
+// Fields set by the journald output itself. Entry fields with the same name are written
+// with the prefix "F_".
+var journalReservedFields = map[string]struct{}{
+	"MESSAGE":           {},
+	"PRIORITY":          {},
+	"SYSLOG_IDENTIFIER": {},
+	"CODE_FILE":         {},
+	"CODE_LINE":         {},
+	"CODE_FUNC":         {},
+	"LOGGER":            {},
+	"STACKTRACE":        {},
+}
+
+// WithJournald writes entries to the systemd journal through its native protocol, so
+// that journalctl shows their priority and fields. Each entry is sent with MESSAGE,
+// PRIORITY (mapped from the level as by WithSyslog), SYSLOG_IDENTIFIER (the name of the
+// program), CODE_FILE, CODE_LINE and CODE_FUNC when the caller is known, LOGGER for named
+// loggers and STACKTRACE, plus one field per entry field: the key in upper case, with
+// the characters journald rejects replaced by "_", leading underscores removed and "F_"
+// added before a leading digit or a name listed above. Strings are written as they are,
+// objects and arrays in JSON.
+//
+// The journal replaces the default stderr output, which systemd would also send to the
+// journal; outputs set by WithOutputPaths are kept. When the journal socket cannot be
+// reached, such as outside of systemd, entries are written to stderr instead, after a
+// JournaldUnavailableMessage warning. Entries too large for a datagram, about 200 KiB,
+// fail to write and are reported to the internal error output.
+func WithJournald() Option {
+	return func(o *options) {
+		o.journald = true
+		o.integrations = append(o.integrations, integration{name: "journald", settings: map[string]string{}})
+	}
+}
+
+// newJournaldCore returns a core writing the entries enabled by enab to the journal, or
+// the error of connecting to its socket.
+func newJournaldCore(enab zapcore.LevelEnabler) (zapcore.Core, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journaldCore{LevelEnabler: enab, conn: conn, identifier: filepath.Base(os.Args[0])}, nil
+}
+
+// withStderr returns paths with "stderr" added, unless they already contain it.
+func withStderr(paths []string) []string {
+	for _, path := range paths {
+		if path == "stderr" {
+			return paths
+		}
+	}
+	return append(paths[:len(paths):len(paths)], "stderr")
+}
+
+// journaldCore writes entries to the journal, one datagram each.
+type journaldCore struct {
+	zapcore.LevelEnabler
+	conn       *net.UnixConn
+	identifier string          // SYSLOG_IDENTIFIER of the entries
+	fields     []zapcore.Field // Fields added by With
+}
+
+// With implements zapcore.Core.
+func (c *journaldCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *journaldCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *journaldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	_, err := c.conn.Write(c.encode(ent, fields))
+	return err
+}
+
+// Sync implements zapcore.Core. Entries are sent as they are written, so there is
+// nothing to flush.
+func (c *journaldCore) Sync() error {
+	return nil
+}
+
+// encode returns the datagram of the entry ent with fields in the native protocol of
+// journald.
+func (c *journaldCore) encode(ent zapcore.Entry, fields []zapcore.Field) []byte {
+	b := appendJournalField(nil, "MESSAGE", ent.Message)
+	b = appendJournalField(b, "PRIORITY", strconv.Itoa(int(syslogSeverity(ent.Level))))
+	b = appendJournalField(b, "SYSLOG_IDENTIFIER", c.identifier)
+	if ent.Caller.Defined {
+		b = appendJournalField(b, "CODE_FILE", ent.Caller.File)
+		b = appendJournalField(b, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		if ent.Caller.Function != "" {
+			b = appendJournalField(b, "CODE_FUNC", ent.Caller.Function)
+		}
+	}
+	if ent.LoggerName != "" {
+		b = appendJournalField(b, "LOGGER", ent.LoggerName)
+	}
+	if ent.Stack != "" {
+		b = appendJournalField(b, "STACKTRACE", ent.Stack)
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	keys := make([]string, 0, len(enc.Fields))
+	for key := range enc.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b = appendJournalField(b, journalFieldName(key), journalFieldValue(enc.Fields[key]))
+	}
+	return b
+}
+
+// journalFieldName returns key as a field name accepted by journald: upper case letters,
+// digits and underscores, not starting with an underscore or a digit, and at most 64
+// characters.
+func journalFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, ch := range name {
+		if (ch < 'A' || ch > 'Z') && (ch < '0' || ch > '9') {
+			name[i] = '_'
+		}
+	}
+	cleaned := strings.TrimLeft(string(name), "_")
+	if _, reserved := journalReservedFields[cleaned]; reserved || cleaned == "" || cleaned[0] <= '9' {
+		cleaned = "F_" + cleaned
+	}
+	if len(cleaned) > maxJournalFieldName {
+		cleaned = cleaned[:maxJournalFieldName]
+	}
+	return cleaned
+}
+
+// journalFieldValue returns the text of the field value v, as added to a
+// zapcore.MapObjectEncoder.
+func journalFieldValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
+
+// appendJournalField appends the field name with value to b. Values with a newline are
+// written in the binary form: the name, a newline, the length of the value as a
+// little-endian uint64, the value and a newline.
+func appendJournalField(b []byte, name, value string) []byte {
+	b = append(b, name...)
+	if !strings.Contains(value, "\n") {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	b = append(b, '\n')
+	b = append(b, size[:]...)
+	b = append(b, value...)
+	return append(b, '\n')
+}
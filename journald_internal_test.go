@@ -0,0 +1,180 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// listenJournal points the journald output at a datagram socket in a temporary
+// directory for the duration of the test, and returns the socket.
+func listenJournal(t *testing.T) *net.UnixConn {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+	previous := journalSocketPath
+	journalSocketPath = path
+	t.Cleanup(func() {
+		journalSocketPath = previous
+		conn.Close()
+	})
+	return conn
+}
+
+// readJournalEntry reads a datagram from conn and decodes its fields, in the native
+// protocol of journald.
+func readJournalEntry(t *testing.T, conn *net.UnixConn) map[string]string {
+	t.Helper()
+
+	buf := make([]byte, 1<<20)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf[:n]
+
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			t.Fatalf("field without newline: %q", data)
+		}
+		line := data[:end]
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			fields[string(line[:eq])] = string(line[eq+1:])
+			data = data[end+1:]
+			continue
+		}
+		// Binary form: name, newline, little-endian uint64 length, value, newline.
+		size := binary.LittleEndian.Uint64(data[end+1 : end+9])
+		value := data[end+9 : end+9+int(size)]
+		if data[end+9+int(size)] != '\n' {
+			t.Fatalf("binary field %q not followed by a newline", line)
+		}
+		fields[string(line)] = string(value)
+		data = data[end+10+int(size):]
+	}
+	return fields
+}
+
+func TestJournald(t *testing.T) {
+	conn := listenJournal(t)
+	log, conf, err := newZapLogger(ProductionEnvName, newOptions([]Option{WithJournald()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conf.OutputPaths) != 0 {
+		t.Errorf("outputs = %q, want the journal to replace stderr", conf.OutputPaths)
+	}
+
+	log.Named("billing").With(zap.String("tenant", "acme")).Warn("payment retried",
+		zap.Int("user_id", 42),
+		zap.String("request.path", "/pay"),
+		zap.String("_source", "api"),
+		zap.String("9lives", "cat"),
+		zap.String("message", "shadowed"),
+		zap.String("query", "SELECT 1\nFROM dual"),
+		zap.Strings("tags", []string{"a", "b"}),
+	)
+	fields := readJournalEntry(t, conn)
+
+	want := map[string]string{
+		"MESSAGE":           "payment retried",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": filepath.Base(os.Args[0]),
+		"LOGGER":            "billing",
+		"TENANT":            "acme",
+		"USER_ID":           "42",
+		"REQUEST_PATH":      "/pay",
+		"SOURCE":            "api",
+		"F_9LIVES":          "cat",
+		"F_MESSAGE":         "shadowed",
+		"QUERY":             "SELECT 1\nFROM dual",
+		"TAGS":              `["a","b"]`,
+	}
+	for name, value := range want {
+		if fields[name] != value {
+			t.Errorf("%s = %q, want %q", name, fields[name], value)
+		}
+	}
+	if !strings.HasSuffix(fields["CODE_FILE"], "journald_internal_test.go") || fields["CODE_LINE"] == "" || !strings.Contains(fields["CODE_FUNC"], "TestJournald") {
+		t.Errorf("code fields = %q, %q, %q, want the caller", fields["CODE_FILE"], fields["CODE_LINE"], fields["CODE_FUNC"])
+	}
+
+	log.Error("failed")
+	if fields := readJournalEntry(t, conn); fields["PRIORITY"] != "3" || fields["MESSAGE"] != "failed" {
+		t.Errorf("error entry = %q, want PRIORITY 3", fields)
+	}
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	tests := map[string]string{
+		"user_id":                "USER_ID",
+		"http.status-code":       "HTTP_STATUS_CODE",
+		"__cursor":               "CURSOR",
+		"2fa":                    "F_2FA",
+		"priority":               "F_PRIORITY",
+		"":                       "F_",
+		"é":                      "F_",
+		strings.Repeat("k", 100): strings.Repeat("K", maxJournalFieldName),
+	}
+	for key, want := range tests {
+		if got := journalFieldName(key); got != want {
+			t.Errorf("journalFieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestJournaldUnavailable(t *testing.T) {
+	previous := journalSocketPath
+	journalSocketPath = filepath.Join(t.TempDir(), "missing.sock")
+	defer func() { journalSocketPath = previous }()
+
+	stderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		done <- buf.String()
+	}()
+
+	log, _, err := newZapLogger(ProductionEnvName, newOptions([]Option{WithJournald()}))
+	if err == nil {
+		log.Info("still logged")
+		log.Sync()
+	}
+	w.Close()
+	output := <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(output, JournaldUnavailableMessage) || !strings.Contains(output, "missing.sock") {
+		t.Errorf("stderr = %q, want the warning with the socket error", output)
+	}
+	if !strings.Contains(output, "still logged") {
+		t.Errorf("stderr = %q, want the entries written to stderr", output)
+	}
+}
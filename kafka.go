@@ -0,0 +1,431 @@
+package sazabi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of WithKafka.
+const (
+	defaultKafkaQueueSize      = 10000
+	defaultKafkaEnqueueTimeout = 100 * time.Millisecond
+	defaultKafkaFlushTimeout   = 5 * time.Second
+)
+
+// KafkaMessage is a message sent by a Kafka output.
+type KafkaMessage struct {
+	Topic string
+	Key   []byte // Value of the key field of the entry, nil when it has none
+	Value []byte // Entry encoded in JSON
+	Time  time.Time
+}
+
+// KafkaProducer sends messages to Kafka asynchronously, for WithKafka.
+type KafkaProducer interface {
+	// Produce starts sending msg and calls done once with the result of its delivery,
+	// from any goroutine. It may block while the producer is full, but must return once
+	// Close is called.
+	Produce(msg KafkaMessage, done func(err error))
+	// Close waits until deadline at most for the messages being sent, then releases the
+	// producer. Messages not delivered by then fail.
+	Close(deadline time.Time) error
+}
+
+// kafkaProducers opens the producers of WithKafka, registered by RegisterKafkaProducer.
+var kafkaProducers = struct {
+	sync.RWMutex
+	open func(brokers []string) (KafkaProducer, error)
+}{}
+
+// RegisterKafkaProducer makes WithKafka send messages with the producers returned by
+// open, connected to brokers. It is called once with an adapter of the Kafka client of
+// the application, such as the one shown in the README.
+func RegisterKafkaProducer(open func(brokers []string) (KafkaProducer, error)) {
+	kafkaProducers.Lock()
+	defer kafkaProducers.Unlock()
+
+	kafkaProducers.open = open
+}
+
+// KafkaOption configures an output added by WithKafka.
+type KafkaOption func(*kafkaConfig)
+
+// kafkaConfig is a Kafka output added by WithKafka.
+type kafkaConfig struct {
+	brokers        []string
+	topic          string
+	keyField       string
+	queueSize      int
+	enqueueTimeout time.Duration
+	flushTimeout   time.Duration
+}
+
+// KafkaKeyField keys messages with the value of the field key of their entry, such as
+// "request_id", so that the entries sharing it go to the same partition. Entries without
+// the field are sent without key.
+func KafkaKeyField(key string) KafkaOption {
+	return func(c *kafkaConfig) {
+		c.keyField = key
+	}
+}
+
+// KafkaQueueSize sets the number of entries waiting for the producer, 10000 by default.
+func KafkaQueueSize(n int) KafkaOption {
+	return func(c *kafkaConfig) {
+		c.queueSize = n
+	}
+}
+
+// KafkaEnqueueTimeout sets how long a log call waits for room in a full queue before
+// dropping its entry, 100ms by default. Zero drops it immediately.
+func KafkaEnqueueTimeout(d time.Duration) KafkaOption {
+	return func(c *kafkaConfig) {
+		c.enqueueTimeout = d
+	}
+}
+
+// KafkaFlushTimeout sets how long Sync and Shutdown wait for the pending entries to be
+// delivered, 5s by default.
+func KafkaFlushTimeout(d time.Duration) KafkaOption {
+	return func(c *kafkaConfig) {
+		c.flushTimeout = d
+	}
+}
+
+// WithKafka adds an output producing entries to topic on the Kafka cluster of brokers,
+// with the producer registered by RegisterKafkaProducer. Messages hold the entry encoded
+// in JSON, as by WithJSONEncoding, keyed by KafkaKeyField.
+//
+// Entries are queued and handed to the producer in the background, so that a slow or
+// unreachable cluster never blocks a log call longer than KafkaEnqueueTimeout: entries
+// that do not fit in the queue by then are dropped. Sync waits for the pending entries
+// to be delivered, for KafkaFlushTimeout at most, and Shutdown then closes the producer.
+// Dropped entries and failed deliveries are counted as dropped by PublishExpvars, and
+// reported with their count to the internal error output.
+func WithKafka(brokers []string, topic string, opts ...KafkaOption) Option {
+	cfg := kafkaConfig{
+		brokers:        append([]string(nil), brokers...),
+		topic:          topic,
+		queueSize:      defaultKafkaQueueSize,
+		enqueueTimeout: defaultKafkaEnqueueTimeout,
+		flushTimeout:   defaultKafkaFlushTimeout,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(o *options) {
+		o.kafka = append(o.kafka, cfg)
+		o.integrations = append(o.integrations, integration{
+			name: "kafka",
+			settings: map[string]string{
+				"brokers":   strings.Join(cfg.brokers, ","),
+				"topic":     cfg.topic,
+				"key_field": cfg.keyField,
+			},
+		})
+	}
+}
+
+// newKafkaCores returns the cores of the Kafka outputs of o, writing the entries enabled
+// by enab and reporting failures to errorOutput. The outputs are recorded in o, so that
+// Shutdown closes those of the global logger.
+func newKafkaCores(enab zapcore.LevelEnabler, errorOutput zapcore.WriteSyncer, o *options) ([]zapcore.Core, error) {
+	if len(o.kafka) == 0 {
+		return nil, nil
+	}
+	kafkaProducers.RLock()
+	open := kafkaProducers.open
+	kafkaProducers.RUnlock()
+	if open == nil {
+		return nil, fmt.Errorf("sazabi: no Kafka producer registered with RegisterKafkaProducer")
+	}
+
+	cores := make([]zapcore.Core, 0, len(o.kafka))
+	for _, cfg := range o.kafka {
+		switch {
+		case len(cfg.brokers) == 0:
+			return nil, fmt.Errorf("sazabi: no Kafka broker")
+		case cfg.topic == "":
+			return nil, fmt.Errorf("sazabi: no Kafka topic")
+		case cfg.queueSize <= 0 || cfg.enqueueTimeout < 0 || cfg.flushTimeout <= 0:
+			return nil, fmt.Errorf("sazabi: invalid Kafka queue size, enqueue timeout or flush timeout")
+		}
+		producer, err := open(cfg.brokers)
+		if err != nil {
+			return nil, fmt.Errorf("sazabi: Kafka producer: %w", err)
+		}
+		out := newKafkaOutput(cfg, producer, errorOutput, o.global)
+		o.kafkaOutputs = append(o.kafkaOutputs, out)
+		enc := zapcore.NewJSONEncoder(newProductionEncoderConfig())
+		cores = append(cores, &kafkaCore{LevelEnabler: enab, enc: enc, out: out})
+	}
+	return cores, nil
+}
+
+// kafkaCore queues entries for a Kafka output.
+type kafkaCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	key []byte // Key of the messages, from the fields added by With
+	out *kafkaOutput
+}
+
+// With implements zapcore.Core.
+func (c *kafkaCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &kafkaCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), key: c.key, out: c.out}
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+		if key, ok := c.out.key(fields[i]); ok {
+			clone.key = key
+		}
+	}
+	return clone
+}
+
+// Check implements zapcore.Core.
+func (c *kafkaCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. The entry is sent in the background, so Write only
+// returns encoding errors.
+func (c *kafkaCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := KafkaMessage{
+		Topic: c.out.cfg.topic,
+		Key:   c.key,
+		Value: append([]byte(nil), bytes.TrimRight(buf.Bytes(), "\n")...),
+		Time:  ent.Time,
+	}
+	buf.Free()
+	for _, f := range fields {
+		if key, ok := c.out.key(f); ok {
+			msg.Key = key
+		}
+	}
+	c.out.add(msg)
+	return nil
+}
+
+// Sync implements zapcore.Core, waiting for the pending entries to be delivered.
+func (c *kafkaCore) Sync() error {
+	return c.out.Sync()
+}
+
+// kafkaOutput queues messages and hands them to its producer from a background
+// goroutine, tracking their delivery.
+type kafkaOutput struct {
+	cfg         kafkaConfig
+	producer    KafkaProducer
+	global      bool                // Whether dropped entries are counted by PublishExpvars
+	errorOutput zapcore.WriteSyncer // Receives failures
+	queue       chan KafkaMessage   // Messages waiting for the producer
+	stop        chan struct{}       // Closed by close
+	stopped     chan struct{}       // Closed when the producing goroutine returns
+
+	mu          sync.Mutex
+	delivered   *sync.Cond // Signaled when pending decreases
+	pending     int        // Messages queued or being produced
+	closed      bool       // Whether close was called
+	overflowing bool       // Whether entries are being dropped because the queue is full
+	failing     bool       // Whether the last delivery failed
+	failed      int64      // Deliveries failed since the output was opened
+	dropped     int64      // Entries dropped since the output was opened, failed deliveries included
+}
+
+// newKafkaOutput returns an output sending messages as configured by cfg with producer,
+// and starts its producing goroutine.
+func newKafkaOutput(cfg kafkaConfig, producer KafkaProducer, errorOutput zapcore.WriteSyncer, global bool) *kafkaOutput {
+	o := &kafkaOutput{
+		cfg:         cfg,
+		producer:    producer,
+		global:      global,
+		errorOutput: errorOutput,
+		queue:       make(chan KafkaMessage, cfg.queueSize),
+		stop:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	o.delivered = sync.NewCond(&o.mu)
+	go o.run()
+	return o
+}
+
+// key returns the message key held by f when it is the key field.
+func (o *kafkaOutput) key(f zapcore.Field) ([]byte, bool) {
+	if o.cfg.keyField == "" || f.Key != o.cfg.keyField {
+		return nil, false
+	}
+	if f.Type == zapcore.StringType {
+		return []byte(f.String), true
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return []byte(fmt.Sprint(enc.Fields[f.Key])), true
+}
+
+// add queues msg, waiting for the enqueue timeout at most while the queue is full, or
+// drops it.
+func (o *kafkaOutput) add(msg KafkaMessage) {
+	o.mu.Lock()
+	if o.closed {
+		o.dropLocked(1)
+		o.mu.Unlock()
+		return
+	}
+	o.pending++
+	o.mu.Unlock()
+
+	select {
+	case o.queue <- msg:
+		o.mu.Lock()
+		o.overflowing = false
+		o.mu.Unlock()
+		return
+	default:
+	}
+	if o.cfg.enqueueTimeout > 0 {
+		timer := time.NewTimer(o.cfg.enqueueTimeout)
+		defer timer.Stop()
+		select {
+		case o.queue <- msg:
+			return
+		case <-timer.C:
+		case <-o.stop:
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending--
+	o.dropLocked(1)
+	if !o.overflowing {
+		o.overflowing = true
+		o.reportf("queue full, dropping entries")
+	}
+	o.delivered.Broadcast()
+}
+
+// run hands the queued messages to the producer until the output is closed.
+func (o *kafkaOutput) run() {
+	defer close(o.stopped)
+	for {
+		select {
+		case msg := <-o.queue:
+			o.producer.Produce(msg, o.done)
+		case <-o.stop:
+			return
+		}
+	}
+}
+
+// done records the delivery of a message, reporting the first failure of a series and
+// the end of the series.
+func (o *kafkaOutput) done(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed && o.pending == 0 {
+		return // Delivered after close gave up on it
+	}
+	o.pending--
+	o.delivered.Broadcast()
+	if err == nil {
+		if o.failing {
+			o.failing = false
+			o.reportf("delivery recovered, %d entries failed", o.failed)
+		}
+		return
+	}
+	o.failed++
+	o.dropLocked(1)
+	if !o.failing {
+		o.failing = true
+		o.reportf("delivery failed: %v, %d entries failed", err, o.failed)
+	}
+}
+
+// waitLocked waits until the pending messages are delivered or deadline passes, and
+// returns the number of messages still pending. o.mu must be held.
+func (o *kafkaOutput) waitLocked(deadline time.Time) int {
+	timer := time.AfterFunc(time.Until(deadline), func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.delivered.Broadcast()
+	})
+	defer timer.Stop()
+	for o.pending > 0 && time.Now().Before(deadline) {
+		o.delivered.Wait()
+	}
+	return o.pending
+}
+
+// Sync waits for the pending messages to be delivered, for the flush timeout at most.
+func (o *kafkaOutput) Sync() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if n := o.waitLocked(time.Now().Add(o.cfg.flushTimeout)); n > 0 {
+		return fmt.Errorf("sazabi: Kafka output %s: %d entries not delivered after %s", o.cfg.topic, n, o.cfg.flushTimeout)
+	}
+	return nil
+}
+
+// close waits for the pending messages to be delivered, for the flush timeout at most,
+// then closes the producer. Entries written afterwards are dropped, and messages not
+// delivered by the deadline are dropped and reported.
+func (o *kafkaOutput) close() {
+	deadline := time.Now().Add(o.cfg.flushTimeout)
+
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return
+	}
+	o.closed = true
+	o.waitLocked(deadline)
+	o.mu.Unlock()
+
+	close(o.stop)
+	err := o.producer.Close(deadline)
+	<-o.stopped
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for len(o.queue) > 0 {
+		<-o.queue
+	}
+	if undelivered := o.pending; undelivered > 0 {
+		o.pending = 0 // Their delivery no longer matters
+		o.dropLocked(undelivered)
+		o.reportf("closed with %d entries undelivered", undelivered)
+	}
+	if err != nil {
+		o.reportf("closing producer: %v", err)
+	}
+	o.delivered.Broadcast()
+}
+
+// dropLocked counts n dropped entries. o.mu must be held.
+func (o *kafkaOutput) dropLocked(n int) {
+	o.dropped += int64(n)
+	if o.global {
+		countOutputDropped(n)
+	}
+}
+
+// reportf writes a failure to the internal error output.
+func (o *kafkaOutput) reportf(format string, args ...interface{}) {
+	fmt.Fprintf(o.errorOutput, "%v output kafka %s: %s\n", time.Now(), o.cfg.topic, fmt.Sprintf(format, args...))
+	o.errorOutput.Sync()
+}
@@ -0,0 +1,318 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeProducer is an in-memory KafkaProducer. It delivers each message as soon as it is
+// produced, with the error returned by fail, unless hold is set: held messages are
+// delivered by release, or fail when the producer is closed.
+type fakeProducer struct {
+	fail     func(msg KafkaMessage) error
+	hold     bool
+	block    chan struct{}     // When set, Produce blocks until it is closed or Close is called
+	produced chan KafkaMessage // Receives the messages blocked or held
+
+	mu       sync.Mutex
+	messages []KafkaMessage
+	held     []func(error)
+	closed   chan struct{}
+	deadline time.Time
+}
+
+func newFakeProducer() *fakeProducer {
+	return &fakeProducer{produced: make(chan KafkaMessage, 100), closed: make(chan struct{})}
+}
+
+func (p *fakeProducer) Produce(msg KafkaMessage, done func(error)) {
+	if p.block != nil {
+		p.produced <- msg
+		select {
+		case <-p.block:
+		case <-p.closed:
+			done(errors.New("producer closed"))
+			return
+		}
+	}
+
+	p.mu.Lock()
+	p.messages = append(p.messages, msg)
+	if p.hold {
+		p.held = append(p.held, done)
+		p.mu.Unlock()
+		p.produced <- msg
+		return
+	}
+	p.mu.Unlock()
+
+	var err error
+	if p.fail != nil {
+		err = p.fail(msg)
+	}
+	done(err)
+}
+
+// release delivers the held messages.
+func (p *fakeProducer) release() {
+	p.mu.Lock()
+	held := p.held
+	p.held = nil
+	p.mu.Unlock()
+
+	for _, done := range held {
+		done(nil)
+	}
+}
+
+func (p *fakeProducer) Close(deadline time.Time) error {
+	p.mu.Lock()
+	p.deadline = deadline
+	held := p.held
+	p.held = nil
+	p.mu.Unlock()
+
+	close(p.closed)
+	for _, done := range held {
+		done(errors.New("producer closed"))
+	}
+	return nil
+}
+
+// sent returns the messages produced so far.
+func (p *fakeProducer) sent() []KafkaMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]KafkaMessage(nil), p.messages...)
+}
+
+// registerProducer makes WithKafka use p for the duration of the test.
+func registerProducer(t *testing.T, p *fakeProducer) {
+	t.Helper()
+
+	RegisterKafkaProducer(func(brokers []string) (KafkaProducer, error) {
+		if len(brokers) != 1 || brokers[0] != "kafka-1:9092" {
+			t.Errorf("brokers = %q, want kafka-1:9092", brokers)
+		}
+		return p, nil
+	})
+	t.Cleanup(func() { RegisterKafkaProducer(nil) })
+}
+
+// newTestProducer returns a core producing to topic "logs" with p and opts, and the
+// buffer receiving its failures.
+func newTestProducer(t *testing.T, p *fakeProducer, opts ...KafkaOption) (*kafkaCore, *lockedBuffer) {
+	t.Helper()
+
+	registerProducer(t, p)
+	errs := &lockedBuffer{}
+	o := newOptions([]Option{WithKafka([]string{"kafka-1:9092"}, "logs", opts...)})
+	cores, err := newKafkaCores(zapcore.DebugLevel, errs, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o.kafkaOutputs) != 1 {
+		t.Fatalf("%d outputs recorded, want 1", len(o.kafkaOutputs))
+	}
+	return cores[0].(*kafkaCore), errs
+}
+
+// produce writes an Info entry with msg and fields to c.
+func produce(t *testing.T, c zapcore.Core, msg string, fields ...zapcore.Field) {
+	t.Helper()
+
+	if err := c.Write(zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: msg}, fields); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKafka(t *testing.T) {
+	p := newFakeProducer()
+	c, errs := newTestProducer(t, p, KafkaKeyField("request_id"))
+
+	produce(t, c.With([]zapcore.Field{zap.String("request_id", "r-1"), zap.String("service", "billing")}), "charged", zap.Int("amount", 42))
+	produce(t, c.With([]zapcore.Field{zap.String("request_id", "r-1")}), "overridden", zap.String("request_id", "r-2"))
+	produce(t, c, "numeric key", zap.Int("request_id", 7))
+	produce(t, c, "no key")
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := p.sent()
+	if len(sent) != 4 {
+		t.Fatalf("%d messages, want 4", len(sent))
+	}
+	var keys []string
+	for _, msg := range sent {
+		if msg.Topic != "logs" {
+			t.Errorf("topic = %q, want logs", msg.Topic)
+		}
+		keys = append(keys, string(msg.Key))
+	}
+	if got := strings.Join(keys, ","); got != "r-1,r-2,7," {
+		t.Errorf("keys = %s, want r-1,r-2,7 and none", got)
+	}
+	if sent[3].Key != nil {
+		t.Errorf("key = %q, want nil for an entry without the key field", sent[3].Key)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(sent[0].Value, &entry); err != nil {
+		t.Fatalf("value %q is not JSON: %v", sent[0].Value, err)
+	}
+	if entry["msg"] != "charged" || entry["level"] != "INFO" || entry["service"] != "billing" || entry["amount"] != 42.0 {
+		t.Errorf("value = %s, want the entry with its fields", sent[0].Value)
+	}
+	if errs.String() != "" || c.out.dropped != 0 {
+		t.Errorf("internal errors = %q, %d dropped; want none", errs.String(), c.out.dropped)
+	}
+}
+
+func TestKafkaEnqueueTimeout(t *testing.T) {
+	p := newFakeProducer()
+	p.block = make(chan struct{})
+	c, errs := newTestProducer(t, p, KafkaQueueSize(1), KafkaEnqueueTimeout(20*time.Millisecond))
+
+	produce(t, c, "a")
+	<-p.produced // Blocked in Produce
+	produce(t, c, "b")
+	start := time.Now()
+	produce(t, c, "c")
+	produce(t, c, "d")
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > time.Second {
+		t.Errorf("two log calls on a full queue took %s, want about twice the enqueue timeout", elapsed)
+	}
+	close(p.block)
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	var msgs []string
+	for _, msg := range p.sent() {
+		var entry map[string]interface{}
+		json.Unmarshal(msg.Value, &entry)
+		msgs = append(msgs, entry["msg"].(string))
+	}
+	if got := strings.Join(msgs, ","); got != "a,b" {
+		t.Errorf("messages = %s, want the entries that fit in the queue a,b", got)
+	}
+	if c.out.dropped != 2 || strings.Count(errs.String(), "queue full") != 1 {
+		t.Errorf("%d dropped, internal errors = %q; want 2 dropped and reported once", c.out.dropped, errs.String())
+	}
+}
+
+func TestKafkaDeliveryFailure(t *testing.T) {
+	p := newFakeProducer()
+	p.fail = func(msg KafkaMessage) error {
+		if strings.Contains(string(msg.Value), "lost") {
+			return errors.New("leader not available")
+		}
+		return nil
+	}
+	c, errs := newTestProducer(t, p)
+
+	for _, msg := range []string{"lost 1", "lost 2", "lost 3", "delivered"} {
+		produce(t, c, msg)
+	}
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.out.failed != 3 || c.out.dropped != 3 {
+		t.Errorf("%d failed, %d dropped; want 3", c.out.failed, c.out.dropped)
+	}
+	reports := errs.String()
+	if strings.Count(reports, "delivery failed: leader not available, 1 entries failed") != 1 || !strings.Contains(reports, "delivery recovered, 3 entries failed") {
+		t.Errorf("internal errors = %q, want the first failure and the recovery reported with the count", reports)
+	}
+}
+
+func TestKafkaClose(t *testing.T) {
+	p := newFakeProducer()
+	p.hold = true
+	c, errs := newTestProducer(t, p, KafkaFlushTimeout(5*time.Second))
+
+	produce(t, c, "a")
+	produce(t, c, "b")
+	<-p.produced
+	<-p.produced
+	time.AfterFunc(20*time.Millisecond, p.release)
+	start := time.Now()
+	c.out.close()
+
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("close took %s, want it to return once the entries are delivered", elapsed)
+	}
+	if p.deadline.IsZero() {
+		t.Error("producer not closed")
+	}
+	if len(p.sent()) != 2 || c.out.dropped != 0 || errs.String() != "" {
+		t.Errorf("%d sent, %d dropped, internal errors = %q; want both delivered", len(p.sent()), c.out.dropped, errs.String())
+	}
+
+	produce(t, c, "after close")
+	if c.out.dropped != 1 || len(p.sent()) != 2 {
+		t.Errorf("%d dropped, %d sent; want the entry written after close dropped", c.out.dropped, len(p.sent()))
+	}
+	if err := c.Sync(); err != nil {
+		t.Errorf("Sync() after close = %v, want nil", err)
+	}
+	c.out.close() // Harmless
+}
+
+func TestKafkaCloseDeadline(t *testing.T) {
+	p := newFakeProducer()
+	p.hold = true
+	c, errs := newTestProducer(t, p, KafkaFlushTimeout(50*time.Millisecond))
+
+	produce(t, c, "a")
+	produce(t, c, "b")
+	if err := c.Sync(); err == nil || !strings.Contains(err.Error(), "2 entries not delivered") {
+		t.Errorf("Sync() = %v, want the undelivered entries reported", err)
+	}
+
+	start := time.Now()
+	c.out.close()
+	elapsed := time.Since(start)
+	if elapsed < 40*time.Millisecond || elapsed > time.Second {
+		t.Errorf("close took %s, want about the flush timeout", elapsed)
+	}
+	if p.deadline.Sub(start) > 60*time.Millisecond {
+		t.Errorf("producer closed with deadline %s after close, want the flush timeout", p.deadline.Sub(start))
+	}
+	if c.out.dropped != 2 || !strings.Contains(errs.String(), "delivery failed: producer closed") {
+		t.Errorf("%d dropped, internal errors = %q; want the undelivered entries dropped and reported", c.out.dropped, errs.String())
+	}
+}
+
+func TestKafkaInvalidConfig(t *testing.T) {
+	o := newOptions([]Option{WithKafka([]string{"kafka-1:9092"}, "logs")})
+	if _, err := newKafkaCores(zapcore.InfoLevel, &lockedBuffer{}, o); err == nil || !strings.Contains(err.Error(), "RegisterKafkaProducer") {
+		t.Errorf("newKafkaCores() without producer = %v, want an error naming RegisterKafkaProducer", err)
+	}
+
+	registerProducer(t, newFakeProducer())
+	for _, opt := range []Option{
+		WithKafka([]string{"kafka-1:9092"}, ""),
+		WithKafka([]string{"kafka-1:9092"}, "logs", KafkaQueueSize(0)),
+		WithKafka([]string{"kafka-1:9092"}, "logs", KafkaFlushTimeout(0)),
+	} {
+		if _, err := newKafkaCores(zapcore.InfoLevel, &lockedBuffer{}, newOptions([]Option{opt})); err == nil {
+			t.Error("newKafkaCores succeeded with an invalid config, want an error")
+		}
+	}
+	if _, err := newKafkaCores(zapcore.InfoLevel, &lockedBuffer{}, newOptions([]Option{WithKafka(nil, "logs")})); err == nil {
+		t.Error("newKafkaCores succeeded without brokers, want an error")
+	}
+}
@@ -0,0 +1,67 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// memoryProducer is a KafkaProducer delivering every message immediately.
+type memoryProducer struct {
+	mu       sync.Mutex
+	messages []sazabi.KafkaMessage
+	closed   bool
+}
+
+func (p *memoryProducer) Produce(msg sazabi.KafkaMessage, done func(error)) {
+	p.mu.Lock()
+	p.messages = append(p.messages, msg)
+	p.mu.Unlock()
+	done(nil)
+}
+
+func (p *memoryProducer) Close(deadline time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	return nil
+}
+
+func TestWithKafka(t *testing.T) {
+	restoreDefault(t)
+	p := &memoryProducer{}
+	sazabi.RegisterKafkaProducer(func(brokers []string) (sazabi.KafkaProducer, error) {
+		return p, nil
+	})
+	defer sazabi.RegisterKafkaProducer(nil)
+
+	captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName,
+			sazabi.WithOutputPaths("stderr"),
+			sazabi.WithKafka([]string{"kafka-1:9092"}, "logs", sazabi.KafkaKeyField("request_id")))
+		sazabi.Infow("order placed", "request_id", "r-1")
+		sazabi.Shutdown()
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		t.Error("producer not closed by Shutdown")
+	}
+	for _, msg := range p.messages {
+		if strings.Contains(string(msg.Value), `"msg":"order placed"`) {
+			if msg.Topic != "logs" || string(msg.Key) != "r-1" {
+				t.Errorf("message = %s/%s, want topic logs and key r-1", msg.Topic, msg.Key)
+			}
+			return
+		}
+	}
+	t.Errorf("messages = %v, want the entry", p.messages)
+}
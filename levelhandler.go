@@ -0,0 +1,26 @@
+package sazabi
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// LevelHandler returns an HTTP handler reading and changing the level of the global
+// logger, to be mounted on an admin mux. GET responds with the level as
+// {"level":"info"}; PUT sets it from a JSON body of the same form, or from a level
+// form value, and responds with the new level, as with zap.AtomicLevel. The handler
+// follows re-initializations, and responds 503 Service Unavailable before Initialize.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := loadInstance()
+		if in == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(struct {
+				Error string `json:"error"`
+			}{ErrNotInitialized.Error()})
+			return
+		}
+		in.config.Level.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,23 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLevelHandlerBeforeInitialize(t *testing.T) {
+	previous := loadInstance()
+	globalInstance.Store((*instance)(nil))
+	defer globalInstance.Store(previous)
+
+	rec := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/loglevel", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), ErrNotInitialized.Error()) {
+		t.Errorf("GET = %d %s, want 503 with %q", rec.Code, rec.Body, ErrNotInitialized)
+	}
+}
@@ -0,0 +1,72 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// serveLevel sends a request with method and body to the handler of LevelHandler and
+// returns the response.
+func serveLevel(t *testing.T, method, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	sazabi.LevelHandler().ServeHTTP(rec, httptest.NewRequest(method, "/admin/loglevel", strings.NewReader(body)))
+	return rec
+}
+
+func TestLevelHandler(t *testing.T) {
+	for _, environment := range []string{sazabi.ProductionEnvName, "development"} {
+		t.Run(environment, func(t *testing.T) {
+			restoreDefault(t)
+			read := initializeFile(t, environment)
+			if err := sazabi.SetLevel("info"); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec := serveLevel(t, http.MethodGet, ""); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"level":"info"}` {
+				t.Errorf("GET = %d %s, want 200 {\"level\":\"info\"}", rec.Code, rec.Body)
+			}
+			sazabi.Debug("handler entry 1")
+
+			if rec := serveLevel(t, http.MethodPut, `{"level":"debug"}`); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"level":"debug"}` {
+				t.Errorf("PUT = %d %s, want 200 {\"level\":\"debug\"}", rec.Code, rec.Body)
+			}
+			sazabi.Debug("handler entry 2")
+
+			if rec := serveLevel(t, http.MethodPut, `{"level":"loud"}`); rec.Code != http.StatusBadRequest {
+				t.Errorf("PUT of an invalid level = %d %s, want 400", rec.Code, rec.Body)
+			}
+			if got := sazabi.GetLevel(); got != "debug" {
+				t.Errorf("GetLevel() = %q after an invalid PUT, want debug", got)
+			}
+
+			output := read()
+			if lineContaining(output, "handler entry 1") != "" {
+				t.Errorf("entry written at info level: %s", output)
+			}
+			if lineContaining(output, "handler entry 2") == "" {
+				t.Errorf("entry not written after PUT debug: %s", output)
+			}
+		})
+	}
+}
+
+func TestLevelHandlerFollowsInitialize(t *testing.T) {
+	restoreDefault(t)
+	handler := sazabi.LevelHandler()
+	captureStderr(t, func() { sazabi.Initialize("development") })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/loglevel", nil))
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"level":"debug"}` {
+		t.Errorf("GET = %s, want the level of the current logger {\"level\":\"debug\"}", got)
+	}
+}
@@ -0,0 +1,166 @@
+package sazabi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// IssueUnsupported is the code of the issues reported by InitializeWithConfig for
+// settings it does not apply.
+const IssueUnsupported = "unsupported_setting"
+
+// ConfigError is returned by LoadConfig and InitializeWithConfig for configurations
+// with issues of SeverityError. Warnings are not reported.
+type ConfigError struct {
+	Path   string            // File the configuration was read from, if any
+	Issues []ValidationIssue // Issues of SeverityError, at least one
+}
+
+// Error implements error, listing the field and the reason of every issue.
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	b.WriteString("sazabi: invalid configuration")
+	if e.Path != "" {
+		fmt.Fprintf(&b, " %s", e.Path)
+	}
+	for i, issue := range e.Issues {
+		sep := "; "
+		if i == 0 {
+			sep = ": "
+		}
+		fmt.Fprintf(&b, "%s%s: %s", sep, issue.Field, issue.Message)
+	}
+	return b.String()
+}
+
+// configDecoders maps file extensions to the decoders of LoadConfig.
+var configDecoders = struct {
+	sync.RWMutex
+	m map[string]func([]byte, interface{}) error
+}{m: map[string]func([]byte, interface{}) error{".json": decodeJSONConfig}}
+
+// RegisterConfigDecoder makes LoadConfig decode the files with extension ext, such as
+// ".yaml", with decode. Importing github.com/zeroxsolutions/sazabi/yamlconfig registers
+// YAML. JSON is built in.
+func RegisterConfigDecoder(ext string, decode func(data []byte, v interface{}) error) {
+	configDecoders.Lock()
+	defer configDecoders.Unlock()
+
+	configDecoders.m[strings.ToLower(ext)] = decode
+}
+
+// decodeJSONConfig decodes data into v, rejecting unknown fields.
+func decodeJSONConfig(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// LoadConfig reads the configuration file at path, decoded according to its extension
+// (see RegisterConfigDecoder), and validates it. A configuration with errors found by
+// Validate returns a *ConfigError naming each field at fault.
+func LoadConfig(path string) (Config, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	configDecoders.RLock()
+	decode, ok := configDecoders.m[ext]
+	configDecoders.RUnlock()
+	if !ok {
+		return Config{}, fmt.Errorf("sazabi: no configuration decoder registered for %q files", ext)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var cfg Config
+	if err := decode(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("sazabi: decoding configuration %s: %w", path, err)
+	}
+	if issues := configErrors(Validate(cfg)); len(issues) > 0 {
+		return Config{}, &ConfigError{Path: path, Issues: issues}
+	}
+	return cfg, nil
+}
+
+// InitializeWithConfig initializes the global logger from cfg, as Initialize would for
+// cfg.Environment (development when empty) with opts applied on top. Level, Encoding,
+// OutputPaths and ErrorOutputPaths replace the defaults of the environment when set,
+// and Encoding applies to development too. Sampling replaces the default sampling; a
+// zero Initial and Thereafter disables it. Unless DisableStacktrace is set, entries
+// carry a stacktrace from Error level in production and Warn level in development, as
+// with zap. Configurations with errors found by Validate return a *ConfigError, and so
+// do those setting Rotation, TLS, AllowedKeysOnly or DroppedKeys, which are not
+// applied; the current logger is then kept.
+func InitializeWithConfig(cfg Config, opts ...Option) error {
+	issues := configErrors(Validate(cfg))
+	report := func(field string) {
+		issues = append(issues, ValidationIssue{Severity: SeverityError, Code: IssueUnsupported, Field: field, Message: "not supported by InitializeWithConfig"})
+	}
+	if cfg.Rotation != nil {
+		report("rotation")
+	}
+	if cfg.TLS != nil {
+		report("tls")
+	}
+	if len(cfg.AllowedKeysOnly) > 0 {
+		report("allowedKeysOnly")
+	}
+	if len(cfg.DroppedKeys) > 0 {
+		report("droppedKeys")
+	}
+	if len(issues) > 0 {
+		return &ConfigError{Issues: issues}
+	}
+
+	environment := cfg.Environment
+	if environment == "" {
+		environment = "development"
+	}
+	o := newOptions(append(configOptions(cfg, environment), opts...))
+	return initialize(environment, o, callerLocation(2))
+}
+
+// configOptions returns the options applying the settings of cfg, validated, for
+// environment.
+func configOptions(cfg Config, environment string) []Option {
+	return []Option{func(o *options) {
+		if cfg.Level != "" {
+			level, _ := zapcore.ParseLevel(cfg.Level)
+			o.level = &level
+		}
+		o.format = cfg.Encoding
+		o.outputPaths = append([]string(nil), cfg.OutputPaths...)
+		o.errorOutputPaths = append([]string(nil), cfg.ErrorOutputPaths...)
+		if s := cfg.Sampling; s != nil {
+			o.sampling = &SamplingConfig{Initial: s.Initial, Thereafter: s.Thereafter}
+		}
+		if !cfg.DisableStacktrace {
+			level := zapcore.WarnLevel
+			if environment == ProductionEnvName || environment == ProductionEnvShortName {
+				level = zapcore.ErrorLevel
+			}
+			o.stacktraceLevel = &level
+		}
+		if cfg.BatchCompression != "" {
+			WithBatchCompression(cfg.BatchCompression)(o)
+		}
+	}}
+}
+
+// configErrors returns the issues of SeverityError.
+func configErrors(issues []ValidationIssue) []ValidationIssue {
+	var errs []ValidationIssue
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			errs = append(errs, issue)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,117 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := sazabi.LoadConfig(filepath.Join("testdata", "config", "good.json"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	want := sazabi.Config{
+		Environment:      "production",
+		Level:            "warn",
+		Encoding:         "json",
+		OutputPaths:      []string{"stdout"},
+		ErrorOutputPaths: []string{"stdout"},
+		Sampling:         &sazabi.SamplingConfig{Initial: 10, Thereafter: 5},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("LoadConfig() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	_, err := sazabi.LoadConfig(filepath.Join("testdata", "config", "bad.json"))
+	var cerr *sazabi.ConfigError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("LoadConfig() error = %v, want a *ConfigError", err)
+	}
+	fields := make(map[string]bool)
+	for _, issue := range cerr.Issues {
+		fields[issue.Field] = true
+	}
+	for _, field := range []string{"level", "encoding", "sampling"} {
+		if !fields[field] || !strings.Contains(err.Error(), field+": ") {
+			t.Errorf("error %q does not name %s", err, field)
+		}
+	}
+	if !strings.Contains(err.Error(), `"loud" is not a level`) {
+		t.Errorf("error %q does not say why the level is bad", err)
+	}
+}
+
+func TestLoadConfigDecodeErrors(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join("testdata", "config", "unknown.json"), `unknown field "levle"`},
+		{filepath.Join("testdata", "config", "missing.json"), "no such file"},
+		{filepath.Join("testdata", "config", "logging.toml"), `no configuration decoder registered for ".toml" files`},
+	}
+	for _, tt := range tests {
+		if _, err := sazabi.LoadConfig(tt.path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadConfig(%s) error = %v, want %q", tt.path, err, tt.want)
+		}
+	}
+}
+
+func TestInitializeWithConfig(t *testing.T) {
+	restoreDefault(t)
+	cfg, err := sazabi.LoadConfig(filepath.Join("testdata", "config", "good.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sazabi.InitializeWithConfig(cfg); err != nil {
+		t.Fatalf("InitializeWithConfig() error = %v", err)
+	}
+
+	got := sazabi.EffectiveConfig()
+	if got.Environment != cfg.Environment || got.Level != cfg.Level || got.Encoding != cfg.Encoding ||
+		!reflect.DeepEqual(got.OutputPaths, cfg.OutputPaths) || !reflect.DeepEqual(got.ErrorOutputPaths, cfg.ErrorOutputPaths) {
+		t.Errorf("EffectiveConfig() = %+v, want the declared configuration %+v", got, cfg)
+	}
+	if got.Sampling == nil || got.Sampling.Initial != 10 || got.Sampling.Thereafter != 5 {
+		t.Errorf("sampling = %+v, want 10 then every 5th", got.Sampling)
+	}
+	if got.StacktraceLevel != "error" {
+		t.Errorf("stacktrace level = %q, want error in production unless disabled", got.StacktraceLevel)
+	}
+}
+
+func TestInitializeWithConfigDefaults(t *testing.T) {
+	restoreDefault(t)
+	cfg := sazabi.Config{DisableStacktrace: true, Sampling: &sazabi.SamplingConfig{}}
+	if err := sazabi.InitializeWithConfig(cfg, sazabi.WithOutputPaths(filepath.Join(t.TempDir(), "app.log"))); err != nil {
+		t.Fatalf("InitializeWithConfig() error = %v", err)
+	}
+
+	got := sazabi.EffectiveConfig()
+	if got.Environment != "development" || got.Level != "debug" || got.Sampling != nil || got.StacktraceLevel != "" {
+		t.Errorf("EffectiveConfig() = %+v, want development without sampling or stacktraces", got)
+	}
+}
+
+func TestInitializeWithConfigUnsupported(t *testing.T) {
+	before, _ := sazabi.LastInitializer()
+	err := sazabi.InitializeWithConfig(sazabi.Config{Level: "info", DroppedKeys: []string{"password"}})
+
+	var cerr *sazabi.ConfigError
+	if !errors.As(err, &cerr) || len(cerr.Issues) != 1 || cerr.Issues[0].Code != sazabi.IssueUnsupported || cerr.Issues[0].Field != "droppedKeys" {
+		t.Errorf("InitializeWithConfig() error = %v, want droppedKeys reported as unsupported", err)
+	}
+	if after, _ := sazabi.LastInitializer(); after != before {
+		t.Errorf("logger initialized by %+v despite the error", after)
+	}
+}
@@ -1,10 +1,10 @@
 package sazabi
 
 import (
+	"sync/atomic"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-
-	"github.com/zeroxsolutions/barbatos/log"
 )
 
 // Environment constants for logging configuration.
@@ -13,16 +13,103 @@ const (
 	ProductionEnvShortName = "prod"       // Short name for production environment
 )
 
-// logger represents the global logger instance used throughout the application.
-var (
-	logger log.Logger
-)
-
 // Initialize sets up the logger based on the specified environment.
 // It configures the logger for production or development mode.
 // In production, it uses a specific configuration to manage log levels and formats.
-// If an error occurs during logger initialization, the application panics.
-func Initialize(environment string) {
+// Optional behaviour can be enabled by passing one or more Option values.
+// If an error occurs during logger initialization, the application panics; use
+// TryInitialize to handle it instead.
+func Initialize(environment string, opts ...Option) {
+	if err := initialize(environment, newOptions(opts), callerLocation(2)); err != nil {
+		panic(err)
+	}
+}
+
+// TryInitialize is like Initialize, but returns the error instead of panicking,
+// keeping the existing logger. Conflicts detected under WithStrictSingleInit wrap
+// ErrConflictingInitialize.
+func TryInitialize(environment string, opts ...Option) error {
+	return initialize(environment, newOptions(opts), callerLocation(2))
+}
+
+// initialize builds the global logger on behalf of the exported initialization
+// functions. caller is the location of the user's call, used to detect
+// conflicting initializations.
+func initialize(environment string, o *options, caller string) error {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	current := Initializer{Environment: environment, Caller: caller, signature: o.signature(), strict: o.strictSingleInit}
+	previous, conflict, err := checkInitializer(current, o.strictSingleInit)
+	if err != nil {
+		return err
+	}
+
+	resetHealth()
+	o.global = true
+	o.ring = configureRing(o.ringBufferSize)
+	o.incident = configureIncident(o.incidentWindow, o.incidentMaxBytes)
+	log, conf, err := newZapLogger(environment, o)
+	if err != nil {
+		return err
+	}
+
+	in := &instance{
+		base:               log,
+		environment:        environment,
+		config:             conf,
+		options:            o,
+		batch:              o.batch,
+		raw:                o.raw,
+		access:             o.access,
+		callerEnabled:      true,
+		contextDiagnostics: o.contextDiagnostics,
+	}
+	if o.stacktraceLevel != nil {
+		in.stacktraceOn, in.stacktraceLevel = true, *o.stacktraceLevel
+	}
+	publish(in) // Set the global logger
+	atomic.StoreInt32(&shutDown, 0)
+	for _, out := range o.kafkaOutputs {
+		addShutdownHookLocked(out.close)
+	}
+	for _, out := range o.otlpOutputs {
+		addShutdownHookLocked(out.close)
+	}
+	for _, f := range o.rotatingFiles {
+		addShutdownHookLocked(f.close)
+	}
+
+	startVolumeReport(o)
+	startVolumeBudget(o, conf.Level)
+
+	reinitialized := recordInitializer(current) > 1
+	if conflict {
+		warnConflictingInitialize(previous, current)
+	}
+	summary := configSummary(environment, conf, o, reinitialized)
+	lastSummary.Store(summary)
+	if o.startupSummary {
+		emitStartupSummary(summary)
+	}
+	return nil
+}
+
+// New creates a standalone logger configured for the specified environment, exactly
+// like Initialize would, without replacing the global logger. Options that only concern
+// the global logger, such as WithStartupSummary, are ignored, and the outputs and
+// entries of the logger are left out of Health, VolumeStats and the volume budget.
+func New(environment string, opts ...Option) (Logger, error) {
+	log, _, err := newZapLogger(environment, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return log.Sugar(), nil
+}
+
+// newZapLogger builds the zap logger for environment and o, returning it together with
+// the configuration it was built from.
+func newZapLogger(environment string, o *options) (*zap.Logger, zap.Config, error) {
 	var conf zap.Config
 	conf = newProductionConfig()
 
@@ -30,13 +117,61 @@ func Initialize(environment string) {
 		conf = zap.NewDevelopmentConfig()
 	}
 
+	if loggingDisabled() {
+		return newDisabledLogger(o), conf, nil // Logging disabled through SAZABI_DISABLED
+	}
+
 	conf.DisableStacktrace = true
-	log, err := conf.Build()
-	if err != nil {
-		panic(err) // Panic if logger configuration fails
+	if o.encoding != "" && !conf.Development {
+		conf.Encoding = o.encoding
+	}
+	if o.format != "" {
+		conf.Encoding = o.format
+	}
+	if o.level != nil {
+		conf.Level = zap.NewAtomicLevelAt(*o.level)
+	}
+	if o.journald {
+		conf.OutputPaths = nil // Replaced by the journal, or stderr when it is unavailable
+	}
+	if len(o.outputPaths) > 0 {
+		conf.OutputPaths = o.outputPaths
 	}
+	if len(o.extraOutputs) > 0 {
+		conf.OutputPaths = append(conf.OutputPaths[:len(conf.OutputPaths):len(conf.OutputPaths)], o.extraOutputs...)
+	}
+	if len(o.errorOutputPaths) > 0 {
+		conf.ErrorOutputPaths = o.errorOutputPaths
+	}
+	if o.internalErrorOutput != "" {
+		conf.ErrorOutputPaths = []string{o.internalErrorOutput}
+	}
+	if o.hostFields {
+		conf.InitialFields = map[string]interface{}{HostnameKey: o.hostname(), PIDKey: o.pid()}
+	}
+	if s := o.sampling; s != nil {
+		conf.Sampling = nil
+		if s.Initial != 0 || s.Thereafter != 0 {
+			conf.Sampling = &zap.SamplingConfig{Initial: s.Initial, Thereafter: s.Thereafter}
+		}
+	}
+	if o.global && conf.Sampling != nil {
+		hook := conf.Sampling.Hook
+		conf.Sampling.Hook = func(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+			countSampled(ent, dec)
+			if hook != nil {
+				hook(ent, dec)
+			}
+		}
+	}
+	applyOutputMode(&conf, o)
+	applyFullLineColor(&conf, o)
+	applyGCPFormat(&conf, o)
+	applyDatadogFormat(&conf, o)
+	applyECSFormat(&conf, o)
 
-	logger = log.WithOptions(zap.AddCallerSkip(1)).Sugar() // Set the global logger
+	log, err := build(conf, o)
+	return log, conf, err
 }
 
 // newProductionConfig returns a zap.Config configured for production environment.
@@ -63,7 +198,7 @@ func newProductionEncoderConfig() zapcore.EncoderConfig {
 	return zapcore.EncoderConfig{
 		TimeKey:        "ts",                           // Key for timestamp
 		LevelKey:       "level",                        // Key for log level
-		NameKey:        "logger",                       // Key for logger name
+		NameKey:        NameKey,                        // Key for logger name
 		CallerKey:      "caller",                       // Key for caller information
 		MessageKey:     "msg",                          // Key for log message
 		StacktraceKey:  "stacktrace",                   // Key for stack trace
@@ -77,97 +212,98 @@ func newProductionEncoderConfig() zapcore.EncoderConfig {
 
 // Debug logs debug messages using the global logger.
 func Debug(args ...interface{}) {
-	logger.Debug(args...) // Log debug message
+	logger().Debug(args...) // Log debug message
 }
 
 // Debugf logs formatted debug messages using the global logger.
 func Debugf(template string, args ...interface{}) {
-	logger.Debugf(template, args...) // Log formatted debug message
+	logger().Debugf(template, args...) // Log formatted debug message
 }
 
 // Debugw logs debug messages with additional key-value pairs for structured logging using the global logger.
 func Debugw(msg string, keysValues ...interface{}) {
-	logger.Debugw(msg, keysValues...) // Log debug message with structured key-value pairs
+	logger().Debugw(msg, keysValues...) // Log debug message with structured key-value pairs
 }
 
 // Info logs info messages using the global logger.
 func Info(args ...interface{}) {
-	logger.Info(args...) // Log info message
+	logger().Info(args...) // Log info message
 }
 
 // Infof logs formatted info messages using the global logger.
 func Infof(template string, args ...interface{}) {
-	logger.Infof(template, args...) // Log formatted info message
+	logger().Infof(template, args...) // Log formatted info message
 }
 
 // Infow logs info messages with additional key-value pairs for structured logging using the global logger.
 func Infow(msg string, keysValues ...interface{}) {
-	logger.Infow(msg, keysValues...) // Log info message with structured key-value pairs
+	logger().Infow(msg, keysValues...) // Log info message with structured key-value pairs
 }
 
 // Warn logs warning messages using the global logger.
 func Warn(args ...interface{}) {
-	logger.Warn(args...) // Log warning message
+	logger().Warn(args...) // Log warning message
 }
 
 // Warnf logs formatted warning messages using the global logger.
 func Warnf(template string, args ...interface{}) {
-	logger.Warnf(template, args...) // Log formatted warning message
+	logger().Warnf(template, args...) // Log formatted warning message
 }
 
 // Warnw logs warning messages with additional key-value pairs for structured logging using the global logger.
 func Warnw(msg string, keysValues ...interface{}) {
-	logger.Warnw(msg, keysValues...) // Log warning message with structured key-value pairs
+	logger().Warnw(msg, keysValues...) // Log warning message with structured key-value pairs
 }
 
 // Error logs error messages using the global logger.
 func Error(args ...interface{}) {
-	logger.Error(args...) // Log error message
+	logger().Error(args...) // Log error message
 }
 
 // Errorf logs formatted error messages using the global logger.
 func Errorf(template string, args ...interface{}) {
-	logger.Errorf(template, args...) // Log formatted error message
+	logger().Errorf(template, args...) // Log formatted error message
 }
 
 // Errorw logs error messages with additional key-value pairs for structured logging using the global logger.
 func Errorw(msg string, keysValues ...interface{}) {
-	logger.Errorw(msg, keysValues...) // Log error message with structured key-value pairs
+	logger().Errorw(msg, keysValues...) // Log error message with structured key-value pairs
 }
 
 // Fatal logs fatal messages using the global logger.
 func Fatal(args ...interface{}) {
-	logger.Fatal(args...) // Log fatal message
+	logger().Fatal(args...) // Log fatal message
 }
 
 // Fatalf logs formatted fatal messages using the global logger.
 func Fatalf(template string, args ...interface{}) {
-	logger.Fatalf(template, args...) // Log formatted fatal message
+	logger().Fatalf(template, args...) // Log formatted fatal message
 }
 
 // Fatalw logs fatal messages with additional key-value pairs for structured logging using the global logger.
 func Fatalw(msg string, keysValues ...interface{}) {
-	logger.Fatalw(msg, keysValues...) // Log fatal message with structured key-value pairs
+	logger().Fatalw(msg, keysValues...) // Log fatal message with structured key-value pairs
 }
 
 // Panic logs panic messages using the global logger.
 func Panic(args ...interface{}) {
-	logger.Panic(args...) // Log panic message
+	logger().Panic(args...) // Log panic message
 }
 
 // Panicf logs formatted panic messages using the global logger.
 func Panicf(template string, args ...interface{}) {
-	logger.Panicf(template, args...) // Log formatted panic message
+	logger().Panicf(template, args...) // Log formatted panic message
 }
 
 // Panicw logs panic messages with additional key-value pairs for structured logging using the global logger.
 func Panicw(msg string, keysValues ...interface{}) {
-	logger.Panicw(msg, keysValues...) // Log panic message with structured key-value pairs
+	logger().Panicw(msg, keysValues...) // Log panic message with structured key-value pairs
 }
 
 // Default creates and returns a default logger configured for development environment.
 // It disables stack traces and panics if there's an error while building the logger.
-func Default() log.Logger {
+// The returned logger is used directly by callers, so no caller frames are skipped.
+func Default() Logger {
 	var conf zap.Config = zap.NewDevelopmentConfig() // Set up development logger configuration
 
 	conf.DisableStacktrace = true // Disable stack trace for development logger
@@ -176,5 +312,30 @@ func Default() log.Logger {
 		panic(err) // Panic if logger configuration fails
 	}
 
-	return log.WithOptions(zap.AddCallerSkip(1)).Sugar() // Return a sugar logger with caller information
+	return log.Sugar() // Return a sugar logger with caller information
+}
+
+// Named returns a child of the global logger whose entries carry name under the
+// "logger" key. Calling Named on a child joins the names with dots.
+func Named(name string) Logger {
+	return directLogger().Named(name)
+}
+
+// With returns a child of the global logger adding the key-value pairs, which follow
+// the conventions of Infow, to each of its entries. Like Named, the child reports the
+// real call site and follows the level of the global logger, including later SetLevel
+// calls; the global logger itself is left unchanged.
+func With(keysValues ...interface{}) Logger {
+	return directLogger().With(keysValues...)
+}
+
+// CallerSkip returns the logger used by the package functions, for functions of other
+// packages logging on behalf of their callers, such as the stdshim package. Like the
+// package functions, it reports the caller of the function calling its methods; skip
+// adds frames to skip, one per additional level of wrapping.
+func CallerSkip(skip int) Logger {
+	if skip == 0 {
+		return logger()
+	}
+	return logger().WithOptions(zap.AddCallerSkip(skip))
 }
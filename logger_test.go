@@ -6,9 +6,13 @@ package sazabi_test
 import (
 	"bytes"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/zeroxsolutions/barbatos/log"
+	"go.uber.org/zap"
+
 	"github.com/zeroxsolutions/sazabi"
 )
 
@@ -382,3 +386,82 @@ func TestLoggingIntegration(t *testing.T) {
 		t.Errorf("Expected output to contain 'integration test message', got: %s", output)
 	}
 }
+
+func TestNewLeavesGlobalStateAlone(t *testing.T) {
+	initializeFile(t, sazabi.ProductionEnvName)
+	sazabi.ResetVolumeStats()
+	before := sazabi.Health()
+
+	standalone, read := newFileLogger(t, sazabi.ProductionEnvName,
+		sazabi.WithOptionalSink(sazabi.WithOutput("broken://token@sentry.example/1")),
+		sazabi.WithFallbackOutput(filepath.Join(t.TempDir(), "fallback.log")))
+	standalone.Named("standalone").Info("standalone entry")
+
+	if !strings.Contains(read(), "standalone entry") {
+		t.Fatal("standalone logger wrote nothing")
+	}
+	if after := sazabi.Health(); len(after) != len(before) {
+		t.Errorf("Health() = %+v after New, want the global logger's %+v", after, before)
+	}
+	if stats := sazabi.VolumeStats(); len(stats) != 0 {
+		t.Errorf("VolumeStats() = %v, want the entries of New loggers left out", stats)
+	}
+}
+
+func TestWith(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithJSONEncoding())
+
+	child := sazabi.With("request_id", "r-42", "user_id", 7)
+	child.Info("child entry 1")
+	child.Warnw("child entry 2", "attempt", 2)
+	sazabi.Info("parent entry")
+	child.Debug("child entry 3")
+	if err := sazabi.SetLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+	child.Debug("child entry 4")
+
+	output := read()
+	for _, msg := range []string{"child entry 1", "child entry 2", "child entry 4"} {
+		fields := entryFields(t, output, msg)
+		if fields["request_id"] != "r-42" || fields["user_id"] != float64(7) {
+			t.Errorf("entry %q fields = %v, want the preset fields", msg, fields)
+		}
+	}
+	if fields := entryFields(t, output, "child entry 2"); fields["attempt"] != float64(2) {
+		t.Errorf("entry fields = %v, want the fields of the call too", fields)
+	}
+	if fields := entryFields(t, output, "parent entry"); fields["request_id"] != nil || fields["user_id"] != nil {
+		t.Errorf("parent entry fields = %v, want no preset fields", fields)
+	}
+	if lineContaining(output, "child entry 3") != "" {
+		t.Errorf("debug entry written at info level: %s", output)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	tests := []struct {
+		environment string
+		opts        []sazabi.Option
+		want        string
+	}{
+		{environment: sazabi.ProductionEnvName, want: "\tlogger=http.client\t"},
+		{environment: sazabi.ProductionEnvName, opts: []sazabi.Option{sazabi.WithJSONEncoding()}, want: `"logger":"http.client"`},
+		{environment: "development", want: "\tlogger=http.client\t"},
+	}
+	for _, tt := range tests {
+		restoreDefault(t)
+		read := initializeFile(t, tt.environment, tt.opts...)
+		sazabi.Named("http").(*zap.SugaredLogger).Named("client").Info("named entry")
+		sazabi.Info("unnamed entry")
+
+		output := read()
+		if line := lineContaining(output, "named entry"); !strings.Contains(line, tt.want) {
+			t.Errorf("%s entry = %q, want the name as %s", tt.environment, line, tt.want)
+		}
+		if line := lineContaining(output, "unnamed entry"); strings.Contains(line, "logger=") || strings.Contains(line, `"logger":`) {
+			t.Errorf("%s entry = %q, want no name", tt.environment, line)
+		}
+	}
+}
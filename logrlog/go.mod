@@ -0,0 +1,16 @@
+module github.com/zeroxsolutions/sazabi/logrlog
+
+go 1.18
+
+require (
+	github.com/go-logr/logr v1.4.2
+	github.com/zeroxsolutions/sazabi v0.0.0
+	go.uber.org/zap v1.27.0
+)
+
+require (
+	github.com/zeroxsolutions/barbatos v0.0.1 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+)
+
+replace github.com/zeroxsolutions/sazabi => ../
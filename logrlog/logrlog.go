@@ -0,0 +1,92 @@
+// Package logrlog adapts the global logger of sazabi to logr, the logging interface of
+// the Kubernetes ecosystem, so that libraries such as controller-runtime log through it:
+//
+//	ctrl.SetLogger(logrlog.New())
+package logrlog
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// New returns a logr.Logger writing through the global logger of sazabi, following its
+// re-initializations. V(0) entries are written at Info level and more verbose ones at
+// Debug level; Error entries are written at Error level with the error under
+// sazabi.ErrorKey. Names given by WithName are joined with dots like those of
+// sazabi.Named, values given by WithValues become fields, and entries report the call
+// site of the logr.Logger methods.
+func New() logr.Logger {
+	return logr.New(&sink{})
+}
+
+// sink implements logr.LogSink and logr.CallDepthLogSink.
+type sink struct {
+	depth  int           // Frames between the caller and the sink methods
+	names  []string      // Names given by WithName, outermost first
+	values []interface{} // Key-value pairs given by WithValues
+}
+
+// Init implements logr.LogSink.
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.depth = info.CallDepth
+}
+
+// Enabled implements logr.LogSink.
+func (s *sink) Enabled(level int) bool {
+	return sazabi.CallerSkip(0).(*zap.SugaredLogger).Desugar().Core().Enabled(zapLevel(level))
+}
+
+// Info implements logr.LogSink.
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.logger().Logw(zapLevel(level), msg, keysAndValues...)
+}
+
+// Error implements logr.LogSink.
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.logger().Errorw(msg, append(keysAndValues[:len(keysAndValues):len(keysAndValues)], sazabi.ErrorKey, err)...)
+}
+
+// WithValues implements logr.LogSink.
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	next := *s
+	next.values = append(s.values[:len(s.values):len(s.values)], keysAndValues...)
+	return &next
+}
+
+// WithName implements logr.LogSink.
+func (s *sink) WithName(name string) logr.LogSink {
+	next := *s
+	next.names = append(s.names[:len(s.names):len(s.names)], name)
+	return &next
+}
+
+// WithCallDepth implements logr.CallDepthLogSink.
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	next := *s
+	next.depth += depth
+	return &next
+}
+
+// logger returns the current global logger with the names and values of s, reporting
+// the caller of the logr.Logger method that called the sink method calling it.
+func (s *sink) logger() *zap.SugaredLogger {
+	log := sazabi.CallerSkip(s.depth).(*zap.SugaredLogger) // The sink method is skipped like a package function
+	for _, name := range s.names {
+		log = log.Named(name)
+	}
+	if len(s.values) > 0 {
+		log = log.With(s.values...)
+	}
+	return log
+}
+
+// zapLevel returns the level of the entries of logr verbosity level: Info for V(0),
+// Debug beyond.
+func zapLevel(level int) zapcore.Level {
+	if level > 0 {
+		return zapcore.DebugLevel
+	}
+	return zapcore.InfoLevel
+}
@@ -0,0 +1,113 @@
+//go:build test
+// +build test
+
+package logrlog_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/logrlog"
+)
+
+// capture returns the entries logged by fn through the global logger.
+func capture(fn func()) []sazabi.CapturedEntry {
+	c, stop := sazabi.StartCapture()
+	fn()
+	stop()
+	return c.Entries()
+}
+
+func TestVerbosity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths(path))
+	log := logrlog.New()
+
+	log.Info("v0 entry")
+	log.V(1).Info("v1 entry at info level")
+	if log.V(1).Enabled() {
+		t.Error("V(1).Enabled() = true at info level")
+	}
+	if err := sazabi.SetLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+	if !log.V(1).Enabled() {
+		t.Error("V(1).Enabled() = false at debug level")
+	}
+	log.V(1).Info("v1 entry at debug level")
+	log.V(2).Info("v2 entry at debug level")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Split(line, "\t")
+		got = append(got, fields[1]+" "+fields[3])
+	}
+	want := []string{"INFO v0 entry", "DEBUG v1 entry at debug level", "DEBUG v2 entry at debug level"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+}
+
+func TestNamesAndValues(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	log := logrlog.New().WithName("controller").WithValues("kind", "Pod").WithName("reconciler")
+	failure := errors.New("conflict")
+
+	var line int
+	entries := capture(func() {
+		log.WithValues("name", "web-0").Info("reconciled", "attempt", 2)
+		_, _, line, _ = runtime.Caller(0)
+		log.Error(failure, "reconcile failed")
+	})
+	if len(entries) != 2 {
+		t.Fatalf("captured %d entries, want 2", len(entries))
+	}
+
+	info, errEntry := entries[0], entries[1]
+	if info.LoggerName != "controller.reconciler" || errEntry.LoggerName != "controller.reconciler" {
+		t.Errorf("logger names = %q, %q, want controller.reconciler", info.LoggerName, errEntry.LoggerName)
+	}
+	kind, _ := info.Str("kind")
+	name, _ := info.Str("name")
+	attempt, _ := info.Int("attempt")
+	if kind != "Pod" || name != "web-0" || attempt != 2 {
+		t.Errorf("info fields = %v, want the values and the key-value pairs", info.Fields)
+	}
+	if errEntry.Level.String() != "error" || !errors.Is(errEntry.Err(), failure) {
+		t.Errorf("error entry = %+v, want Error level with the error", errEntry)
+	}
+	if _, ok := errEntry.Field("name"); ok {
+		t.Error("values of a derived logger leaked into its parent")
+	}
+	if !strings.HasSuffix(errEntry.Caller.File, "logrlog_test.go") || errEntry.Caller.Line != line+1 {
+		t.Errorf("caller = %s, want logrlog_test.go:%d", errEntry.Caller.TrimmedPath(), line+1)
+	}
+}
+
+// logVia logs msg through log on behalf of its caller, like logging helpers do.
+func logVia(log logr.Logger, msg string) {
+	log.WithCallDepth(1).Info(msg)
+}
+
+func TestCallDepth(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+
+	var line int
+	entries := capture(func() {
+		_, _, line, _ = runtime.Caller(0)
+		logVia(logrlog.New(), "via helper")
+	})
+	if len(entries) != 1 || entries[0].Caller.Line != line+1 || !strings.HasSuffix(entries[0].Caller.File, "logrlog_test.go") {
+		t.Fatalf("entries = %+v, want the caller of the helper at logrlog_test.go:%d", entries, line+1)
+	}
+}
@@ -0,0 +1,189 @@
+package sazabi
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultMaskReplacement replaces the matches of masking rules without a replacement.
+const DefaultMaskReplacement = "***"
+
+// MaskRule is a masking rule of WithMaskingPatterns: the matches of Pattern are
+// replaced by Replacement, in which $1 or ${name} stand for the submatches as in
+// regexp.Regexp.Expand, or by DefaultMaskReplacement when it is empty. When Valid is
+// set, only the matches for which it returns true are replaced.
+type MaskRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+	Valid       func(match string) bool
+}
+
+// Patterns of the built-in masking rules.
+var (
+	emailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+	cardNumberPattern  = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+	bearerTokenPattern = regexp.MustCompile(`\b([Bb]earer\s+)[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// MaskEmails returns the rule masking email addresses.
+func MaskEmails() MaskRule {
+	return MaskRule{Pattern: emailPattern}
+}
+
+// MaskCardNumbers returns the rule masking payment card numbers: runs of 13 to 19
+// digits, possibly grouped with spaces or dashes, that pass the Luhn check. Other
+// numbers, such as timestamps and order IDs, are left alone.
+func MaskCardNumbers() MaskRule {
+	return MaskRule{Pattern: cardNumberPattern, Valid: luhn}
+}
+
+// MaskBearerTokens returns the rule masking the token of "Bearer <token>"
+// authorization headers, keeping the scheme.
+func MaskBearerTokens() MaskRule {
+	return MaskRule{Pattern: bearerTokenPattern, Replacement: "${1}" + DefaultMaskReplacement}
+}
+
+// WithMaskingPatterns masks personal data in the message and in the string and error
+// field values of entries before they are encoded, for the data that is not under a
+// known key (see AddRedactedKeys). Rules are applied in order, each to the result of
+// the previous ones, so a match replaced by one rule is not seen by the next ones.
+// MaskEmails, MaskCardNumbers and MaskBearerTokens are built in. Without rules,
+// entries do not go through masking at all.
+func WithMaskingPatterns(patterns ...MaskRule) Option {
+	return func(o *options) {
+		o.maskRules = append(o.maskRules, patterns...)
+	}
+}
+
+// maskCore replaces the matches of masking rules in the messages and field values of
+// the entries it writes.
+type maskCore struct {
+	zapcore.Core
+	rules []MaskRule
+}
+
+// newMaskCore wraps core with the masking rules of o, or returns core when there are
+// none.
+func newMaskCore(core zapcore.Core, o *options) (zapcore.Core, error) {
+	if len(o.maskRules) == 0 {
+		return core, nil
+	}
+	rules := make([]MaskRule, len(o.maskRules))
+	for i, rule := range o.maskRules {
+		if rule.Pattern == nil {
+			return nil, fmt.Errorf("sazabi: masking rule %d has no pattern", i)
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = DefaultMaskReplacement
+		}
+		rules[i] = rule
+	}
+	return &maskCore{Core: core, rules: rules}, nil
+}
+
+// With implements zapcore.Core.
+func (c *maskCore) With(fields []zapcore.Field) zapcore.Core {
+	return &maskCore{Core: c.Core.With(c.maskFields(fields)), rules: c.rules}
+}
+
+// Check implements zapcore.Core.
+func (c *maskCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *maskCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message, _ = c.mask(ent.Message)
+	return c.Core.Write(ent, c.maskFields(fields))
+}
+
+// maskFields returns fields with their string and error values masked. The input slice
+// is only copied when a value is actually masked.
+func (c *maskCore) maskFields(fields []zapcore.Field) []zapcore.Field {
+	masked, copied := fields, false
+	for i, f := range fields {
+		var value string
+		switch f.Type {
+		case zapcore.StringType:
+			value = f.String
+		case zapcore.ErrorType:
+			err, ok := f.Interface.(error)
+			if !ok || err == nil {
+				continue
+			}
+			value = err.Error()
+		default:
+			continue
+		}
+		s, changed := c.mask(value)
+		if !changed {
+			continue
+		}
+		if !copied {
+			masked, copied = append([]zapcore.Field(nil), fields...), true
+		}
+		masked[i] = zap.String(f.Key, s)
+	}
+	return masked
+}
+
+// mask applies the rules to s, reporting whether s changed.
+func (c *maskCore) mask(s string) (string, bool) {
+	changed := false
+	for _, rule := range c.rules {
+		var ok bool
+		if s, ok = rule.apply(s); ok {
+			changed = true
+		}
+	}
+	return s, changed
+}
+
+// apply replaces the valid matches of r in s, reporting whether s changed.
+func (r MaskRule) apply(s string) (string, bool) {
+	matches := r.Pattern.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s, false
+	}
+	var dst []byte
+	last, replaced := 0, false
+	for _, m := range matches {
+		if r.Valid != nil && !r.Valid(s[m[0]:m[1]]) {
+			continue
+		}
+		dst = append(dst, s[last:m[0]]...)
+		dst = r.Pattern.ExpandString(dst, r.Replacement, s, m)
+		last, replaced = m[1], true
+	}
+	if !replaced {
+		return s, false
+	}
+	return string(append(dst, s[last:]...)), true
+}
+
+// luhn reports whether the digits of s, ignoring spaces and dashes, are between 13 and
+// 19 and pass the Luhn check of payment card numbers.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
@@ -0,0 +1,37 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMaskDisabled(t *testing.T) {
+	core := zapcore.NewNopCore()
+	for _, opts := range [][]Option{nil, {WithMaskingPatterns()}} {
+		got, err := newMaskCore(core, newOptions(opts))
+		if err != nil || got != core {
+			t.Errorf("newMaskCore() = %T, %v without rules; want the core itself", got, err)
+		}
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	for s, want := range map[string]bool{
+		"4111111111111111":     true,
+		"4111 1111 1111 1111":  true,
+		"378282246310005":      true, // 15 digits
+		"6011000990139424":     true,
+		"4111111111111112":     false,
+		"0000000000000":        true,
+		"000000000000":         false, // 12 digits
+		"00000000000000000000": false, // 20 digits
+	} {
+		if got := luhn(s); got != want {
+			t.Errorf("luhn(%q) = %t, want %t", s, got, want)
+		}
+	}
+}
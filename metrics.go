@@ -0,0 +1,22 @@
+package sazabi
+
+import "go.uber.org/zap/zapcore"
+
+// MetricsCollector counts the entries of the loggers built with WithMetricsCollector,
+// for metrics systems such as Prometheus (see the promlog module).
+type MetricsCollector interface {
+	// EntryWritten counts an entry of level written to the outputs.
+	EntryWritten(level zapcore.Level)
+	// WriteFailed counts an entry that could not be encoded or written to the outputs.
+	WriteFailed()
+}
+
+// WithMetricsCollector reports the entries of the logger to c once written to the
+// outputs, whichever they are. Entries dropped by sampling are neither written nor
+// counted. c is called on the logging path, so it must be fast and safe for concurrent
+// use.
+func WithMetricsCollector(c MetricsCollector) Option {
+	return func(o *options) {
+		o.metrics = c
+	}
+}
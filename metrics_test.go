@@ -0,0 +1,63 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap/zapcore"
+)
+
+// countingCollector is a MetricsCollector keeping its counts in memory.
+type countingCollector struct {
+	mu      sync.Mutex
+	entries map[zapcore.Level]int
+	failed  int
+}
+
+func (c *countingCollector) EntryWritten(level zapcore.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[zapcore.Level]int)
+	}
+	c.entries[level]++
+}
+
+func (c *countingCollector) WriteFailed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failed++
+}
+
+func TestWithMetricsCollector(t *testing.T) {
+	c := &countingCollector{}
+	log, _ := newFileLogger(t, sazabi.ProductionEnvName, sazabi.WithMetricsCollector(c))
+
+	log.Info("charged")
+	log.With("order", 7).Info("shipped")
+	log.Error("declined")
+	log.Debug("below the level")
+	if c.entries[zapcore.InfoLevel] != 2 || c.entries[zapcore.ErrorLevel] != 1 || len(c.entries) != 2 || c.failed != 0 {
+		t.Errorf("entries = %v, %d failed; want 2 info and 1 error entries", c.entries, c.failed)
+	}
+
+	c = &countingCollector{}
+	failing, err := sazabi.New(sazabi.ProductionEnvName,
+		sazabi.WithOutputPaths("failwrite://disk"),
+		sazabi.WithInternalErrorOutput(filepath.Join(t.TempDir(), "internal.log")),
+		sazabi.WithMetricsCollector(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing.Info("lost")
+	if c.failed != 1 || len(c.entries) != 0 {
+		t.Errorf("entries = %v, %d failed; want the failed write counted", c.entries, c.failed)
+	}
+}
@@ -0,0 +1,360 @@
+package sazabi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// The MessagePack encoding used by WithFluentForward, limited to the types field values
+// take once added to a zapcore.MapObjectEncoder.
+
+// appendMsgpackArrayHeader appends the header of an array of n elements to b.
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return appendBigEndian(append(b, 0xdc), uint64(n), 2)
+	}
+	return appendBigEndian(append(b, 0xdd), uint64(n), 4)
+}
+
+// appendMsgpackMapHeader appends the header of a map of n pairs to b.
+func appendMsgpackMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return appendBigEndian(append(b, 0xde), uint64(n), 2)
+	}
+	return appendBigEndian(append(b, 0xdf), uint64(n), 4)
+}
+
+// appendMsgpackString appends the string s to b.
+func appendMsgpackString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = appendBigEndian(append(b, 0xda), uint64(n), 2)
+	default:
+		b = appendBigEndian(append(b, 0xdb), uint64(n), 4)
+	}
+	return append(b, s...)
+}
+
+// appendMsgpackBinary appends the bytes data to b.
+func appendMsgpackBinary(b []byte, data []byte) []byte {
+	n := len(data)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = appendBigEndian(append(b, 0xc5), uint64(n), 2)
+	default:
+		b = appendBigEndian(append(b, 0xc6), uint64(n), 4)
+	}
+	return append(b, data...)
+}
+
+// appendMsgpackInt appends the integer i to b.
+func appendMsgpackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i < 128:
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		return append(b, byte(i))
+	}
+	return appendBigEndian(append(b, 0xd3), uint64(i), 8)
+}
+
+// appendMsgpackUint appends the unsigned integer u to b.
+func appendMsgpackUint(b []byte, u uint64) []byte {
+	if u < 128 {
+		return append(b, byte(u))
+	}
+	return appendBigEndian(append(b, 0xcf), u, 8)
+}
+
+// appendMsgpackFloat appends the float f to b.
+func appendMsgpackFloat(b []byte, f float64) []byte {
+	return appendBigEndian(append(b, 0xcb), math.Float64bits(f), 8)
+}
+
+// appendMsgpackEventTime appends t as the EventTime extension of the forward protocol
+// of Fluentd: type 0, seconds and nanoseconds as big-endian uint32.
+func appendMsgpackEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = appendBigEndian(b, uint64(uint32(t.Unix())), 4)
+	return appendBigEndian(b, uint64(t.Nanosecond()), 4)
+}
+
+// appendMsgpackValue appends v, a value added to a zapcore.MapObjectEncoder, to b. Times
+// are written in RFC 3339, durations in seconds as by the production encoder, and types
+// without MessagePack counterpart as their fmt.Sprint text.
+func appendMsgpackValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case string:
+		return appendMsgpackString(b, v)
+	case []byte:
+		return appendMsgpackBinary(b, v)
+	case int:
+		return appendMsgpackInt(b, int64(v))
+	case int8:
+		return appendMsgpackInt(b, int64(v))
+	case int16:
+		return appendMsgpackInt(b, int64(v))
+	case int32:
+		return appendMsgpackInt(b, int64(v))
+	case int64:
+		return appendMsgpackInt(b, v)
+	case uint:
+		return appendMsgpackUint(b, uint64(v))
+	case uint8:
+		return appendMsgpackUint(b, uint64(v))
+	case uint16:
+		return appendMsgpackUint(b, uint64(v))
+	case uint32:
+		return appendMsgpackUint(b, uint64(v))
+	case uint64:
+		return appendMsgpackUint(b, v)
+	case uintptr:
+		return appendMsgpackUint(b, uint64(v))
+	case float32:
+		return appendMsgpackFloat(b, float64(v))
+	case float64:
+		return appendMsgpackFloat(b, v)
+	case time.Time:
+		return appendMsgpackString(b, v.Format(time.RFC3339Nano))
+	case time.Duration:
+		return appendMsgpackFloat(b, v.Seconds())
+	case []interface{}:
+		b = appendMsgpackArrayHeader(b, len(v))
+		for _, e := range v {
+			b = appendMsgpackValue(b, e)
+		}
+		return b
+	case map[string]interface{}:
+		return appendMsgpackMap(b, v)
+	}
+	return appendMsgpackString(b, fmt.Sprint(v))
+}
+
+// appendMsgpackMap appends m to b, with its keys sorted.
+func appendMsgpackMap(b []byte, m map[string]interface{}) []byte {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	b = appendMsgpackMapHeader(b, len(m))
+	for _, key := range keys {
+		b = appendMsgpackString(b, key)
+		b = appendMsgpackValue(b, m[key])
+	}
+	return b
+}
+
+// appendBigEndian appends the size low bytes of v to b, most significant first.
+func appendBigEndian(b []byte, v uint64, size int) []byte {
+	for i := size - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+// msgpackExt is an extension value read by readMsgpack.
+type msgpackExt struct {
+	Type int8
+	Data []byte
+}
+
+// errMsgpackTooDeep is returned for values nested deeper than readMsgpack accepts.
+var errMsgpackTooDeep = errors.New("msgpack value nested too deeply")
+
+// readMsgpack reads a value from r: nil, bool, int64, uint64, float64, string, []byte,
+// []interface{}, map[string]interface{} (other keys are written with fmt.Sprint) or
+// msgpackExt.
+func readMsgpack(r io.Reader) (interface{}, error) {
+	return readMsgpackDepth(r, 0)
+}
+
+func readMsgpackDepth(r io.Reader, depth int) (interface{}, error) {
+	if depth > 32 {
+		return nil, errMsgpackTooDeep
+	}
+	var tag [1]byte
+	if _, err := io.ReadFull(r, tag[:]); err != nil {
+		return nil, err
+	}
+	t := tag[0]
+	switch {
+	case t <= 0x7f:
+		return int64(t), nil
+	case t >= 0xe0:
+		return int64(int8(t)), nil
+	case t&0xf0 == 0x80:
+		return readMsgpackMap(r, int(t&0x0f), depth)
+	case t&0xf0 == 0x90:
+		return readMsgpackArray(r, int(t&0x0f), depth)
+	case t&0xe0 == 0xa0:
+		return readMsgpackString(r, int(t&0x1f))
+	}
+
+	switch t {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readMsgpackLength(r, 1<<(t-0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackBytes(r, n)
+	case 0xca:
+		u, err := readMsgpackLength(r, 4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		data, err := readMsgpackBytes(r, 8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		data, err := readMsgpackBytes(r, 1<<(t-0xcc))
+		if err != nil {
+			return nil, err
+		}
+		var u uint64
+		for _, c := range data {
+			u = u<<8 | uint64(c)
+		}
+		return u, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (t - 0xd0)
+		data, err := readMsgpackBytes(r, size)
+		if err != nil {
+			return nil, err
+		}
+		var u uint64
+		for _, c := range data {
+			u = u<<8 | uint64(c)
+		}
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return readMsgpackExt(r, 1<<(t-0xd4))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readMsgpackLength(r, 1<<(t-0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackExt(r, n)
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackLength(r, 1<<(t-0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, n)
+	case 0xdc, 0xdd:
+		n, err := readMsgpackLength(r, 2<<(t-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackArray(r, n, depth)
+	case 0xde, 0xdf:
+		n, err := readMsgpackLength(r, 2<<(t-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(r, n, depth)
+	}
+	return nil, fmt.Errorf("unsupported msgpack type 0x%x", t)
+}
+
+// readMsgpackLength reads a big-endian unsigned integer of size bytes.
+func readMsgpackLength(r io.Reader, size int) (int, error) {
+	data, err := readMsgpackBytes(r, size)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, c := range data {
+		n = n<<8 | int(c)
+	}
+	return n, nil
+}
+
+// readMsgpackBytes reads n bytes.
+func readMsgpackBytes(r io.Reader, n int) ([]byte, error) {
+	if n > 64<<20 {
+		return nil, fmt.Errorf("msgpack value of %d bytes too large", n)
+	}
+	data := make([]byte, n)
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
+
+func readMsgpackString(r io.Reader, n int) (interface{}, error) {
+	data, err := readMsgpackBytes(r, n)
+	return string(data), err
+}
+
+func readMsgpackExt(r io.Reader, n int) (interface{}, error) {
+	typ, err := readMsgpackBytes(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readMsgpackBytes(r, n)
+	return msgpackExt{Type: int8(typ[0]), Data: data}, err
+}
+
+func readMsgpackArray(r io.Reader, n, depth int) (interface{}, error) {
+	a := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := readMsgpackDepth(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	return a, nil
+}
+
+func readMsgpackMap(r io.Reader, n, depth int) (interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := readMsgpackDepth(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := readMsgpackDepth(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+		m[key] = v
+	}
+	return m, nil
+}
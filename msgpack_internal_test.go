@@ -0,0 +1,83 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 70000)
+	tests := []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{in: nil, want: nil},
+		{in: true, want: true},
+		{in: false, want: false},
+		{in: "", want: ""},
+		{in: "short", want: "short"},
+		{in: strings.Repeat("y", 200), want: strings.Repeat("y", 200)},
+		{in: long, want: long},
+		{in: []byte{1, 2, 3}, want: []byte{1, 2, 3}},
+		{in: 0, want: int64(0)},
+		{in: 127, want: int64(127)},
+		{in: int8(-32), want: int64(-32)},
+		{in: int64(-33), want: int64(-33)},
+		{in: int64(math.MinInt64), want: int64(math.MinInt64)},
+		{in: uint8(200), want: uint64(200)},
+		{in: uint64(math.MaxUint64), want: uint64(math.MaxUint64)},
+		{in: float32(1.5), want: 1.5},
+		{in: 3.25, want: 3.25},
+		{in: 1500 * time.Millisecond, want: 1.5},
+		{in: time.Date(2024, 1, 31, 12, 0, 0, 5, time.UTC), want: "2024-01-31T12:00:00.000000005Z"},
+		{in: []interface{}{"a", int64(1), nil}, want: []interface{}{"a", int64(1), nil}},
+		{in: make([]interface{}, 20), want: make([]interface{}, 20)},
+		{
+			in:   map[string]interface{}{"k": "v", "nested": map[string]interface{}{"n": int64(-1)}},
+			want: map[string]interface{}{"k": "v", "nested": map[string]interface{}{"n": int64(-1)}},
+		},
+		{in: struct{ A int }{A: 1}, want: "{1}"},
+	}
+	for _, tt := range tests {
+		got, err := readMsgpack(bytes.NewReader(appendMsgpackValue(nil, tt.in)))
+		if err != nil {
+			t.Errorf("readMsgpack(%v) error = %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("round trip of %T = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMsgpackEventTime(t *testing.T) {
+	at := time.Date(2024, 1, 31, 12, 0, 0, 123456789, time.UTC)
+	got, err := readMsgpack(bytes.NewReader(appendMsgpackEventTime(nil, at)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext, ok := got.(msgpackExt)
+	if !ok || ext.Type != 0 || len(ext.Data) != 8 {
+		t.Fatalf("EventTime = %#v, want an extension of type 0 with 8 bytes", got)
+	}
+	if decoded := decodeEventTime(ext); !decoded.Equal(at) {
+		t.Errorf("EventTime = %v, want %v", decoded, at)
+	}
+}
+
+// decodeEventTime returns the time of an EventTime extension.
+func decodeEventTime(ext msgpackExt) time.Time {
+	var sec, nsec int64
+	for i := 0; i < 4; i++ {
+		sec = sec<<8 | int64(ext.Data[i])
+		nsec = nsec<<8 | int64(ext.Data[4+i])
+	}
+	return time.Unix(sec, nsec)
+}
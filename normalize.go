@@ -0,0 +1,164 @@
+package sazabi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithFieldTimeLayout renders time.Time field values with layout (see time.Format)
+// instead of the time encoder used for entry timestamps.
+func WithFieldTimeLayout(layout string) Option {
+	return func(o *options) {
+		o.fieldTimeLayout = layout
+	}
+}
+
+// normalizeCore rewrites field values so that they render the same way regardless of
+// the encoding, instead of relying on reflection:
+//   - time.Time and *time.Time use the time encoder, or the WithFieldTimeLayout layout
+//   - time.Duration uses the duration encoder
+//   - Secret uses its redacted form
+//   - fmt.Stringer uses String(), recovering from panics
+//   - encoding.TextMarshaler uses the marshaled text
+//   - json.RawMessage is embedded verbatim
+//   - RawJSON is embedded verbatim by the JSON encoding, and compacted into a string
+//     by the others
+//
+// Strings and RawJSON values are also cut at the WithMaxFieldBytes cap.
+type normalizeCore struct {
+	zapcore.Core
+	timeLayout string // Layout for time values, empty to use the time encoder
+	json       bool   // Whether core uses the JSON encoding
+	maxBytes   int    // Size cap of string values, unlimited when zero or less
+}
+
+// newNormalizeCore wraps core so that field values are normalized before encoding.
+// encoding is the name of the encoding of core.
+func newNormalizeCore(core zapcore.Core, encoding string, o *options) zapcore.Core {
+	return &normalizeCore{Core: core, timeLayout: o.fieldTimeLayout, json: encoding == "json", maxBytes: o.maxFieldBytes}
+}
+
+// With implements zapcore.Core.
+func (c *normalizeCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(c.normalize(fields))
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *normalizeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *normalizeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.normalize(fields))
+}
+
+// normalize returns fields with every value rewritten by normalizeField. The input
+// slice is only copied when a field actually changes.
+func (c *normalizeCore) normalize(fields []zapcore.Field) []zapcore.Field {
+	normalized, copied := fields, false
+	for i, f := range fields {
+		n, changed := c.normalizeField(f)
+		if !changed {
+			continue
+		}
+		if !copied {
+			normalized, copied = append([]zapcore.Field(nil), fields...), true
+		}
+		normalized[i] = n
+	}
+	return normalized
+}
+
+// normalizeField returns the normalized form of f and whether it differs from f.
+func (c *normalizeCore) normalizeField(f zapcore.Field) (zapcore.Field, bool) {
+	switch f.Type {
+	case zapcore.TimeType:
+		if c.timeLayout == "" {
+			return f, false
+		}
+		t := time.Unix(0, f.Integer)
+		if loc, ok := f.Interface.(*time.Location); ok {
+			t = t.In(loc)
+		}
+		return zap.String(f.Key, t.Format(c.timeLayout)), true
+	case zapcore.TimeFullType:
+		if c.timeLayout == "" {
+			return f, false
+		}
+		return zap.String(f.Key, f.Interface.(time.Time).Format(c.timeLayout)), true
+	case zapcore.StringType:
+		if s, cut := truncate(f.String, c.maxBytes); cut {
+			return zap.String(f.Key, s), true
+		}
+		return f, false
+	case zapcore.InlineMarshalerType:
+		if r, ok := f.Interface.(invalidRawJSON); ok {
+			if s, cut := truncate(r.data, c.maxBytes); cut {
+				return zap.Inline(invalidRawJSON{key: r.key, data: s}), true
+			}
+		}
+		return f, false
+	case zapcore.StringerType, zapcore.ReflectType:
+		return c.normalizeValue(f)
+	}
+	return f, false
+}
+
+// normalizeRawJSON renders a RawJSON value for the encoding of the core.
+func (c *normalizeCore) normalizeRawJSON(key string, data rawJSON) (zapcore.Field, bool) {
+	if c.json && (c.maxBytes <= 0 || len(data) <= c.maxBytes) {
+		return zap.Reflect(key, data), false
+	}
+	s, _ := truncate(compactJSON(data), c.maxBytes)
+	return zap.String(key, s), true
+}
+
+// normalizeValue handles values zap would otherwise encode through reflection.
+func (c *normalizeCore) normalizeValue(f zapcore.Field) (zapcore.Field, bool) {
+	switch v := f.Interface.(type) {
+	case rawJSON:
+		return c.normalizeRawJSON(f.Key, v)
+	case json.RawMessage:
+		if f.Type == zapcore.ReflectType {
+			return f, false // Reflection already embeds raw JSON verbatim
+		}
+		return zap.Reflect(f.Key, v), true // Newer Go releases make json.RawMessage a fmt.Stringer
+	case *time.Time:
+		if v == nil {
+			return f, false
+		}
+		return c.normalizeField(zap.Time(f.Key, *v))
+	case Secret:
+		return zap.String(f.Key, v.String()), true
+	case fmt.Stringer:
+		return zap.String(f.Key, safeString(v)), true
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err != nil {
+			return zap.String(f.Key, fmt.Sprintf("<MarshalText error: %v>", err)), true
+		}
+		return zap.String(f.Key, string(text)), true
+	}
+	return f, false
+}
+
+// safeString calls s.String(), returning a placeholder instead of panicking.
+func safeString(s fmt.Stringer) (str string) {
+	defer func() {
+		if r := recover(); r != nil {
+			str = fmt.Sprintf("<PANIC=%v>", r)
+		}
+	}()
+	return s.String()
+}
@@ -0,0 +1,136 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// version implements fmt.Stringer.
+type version struct{ major, minor int }
+
+func (v version) String() string { return fmt.Sprintf("v%d.%d", v.major, v.minor) }
+
+// panicky is a fmt.Stringer whose String method panics.
+type panicky struct{}
+
+func (panicky) String() string { panic("boom") }
+
+// level implements only encoding.TextMarshaler.
+type level int
+
+func (l level) MarshalText() ([]byte, error) { return []byte(strings.Repeat("!", int(l))), nil }
+
+// encodeNormalized writes one entry with fields through a normalizing core using the
+// production encoder configuration and returns the rendered fields.
+func encodeNormalized(t *testing.T, encoding string, o *options, fields ...interface{}) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc, _ := encoders[encoding](newProductionEncoderConfig())
+	core := newNormalizeCore(zapcore.NewCore(enc, zapcore.AddSync(&buf), zapcore.DebugLevel), encoding, o)
+	zap.New(core).Sugar().Infow("golden", fields...)
+
+	line := strings.TrimSpace(buf.String())
+	if encoding == "json" {
+		return "{" + line[strings.Index(line, `"msg":"golden",`)+len(`"msg":"golden",`):]
+	}
+	return line[strings.Index(line, "{"):]
+}
+
+func TestNormalizeGolden(t *testing.T) {
+	at := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		options *options
+		value   interface{}
+		json    string
+		console string
+	}{
+		{
+			name:    "time",
+			options: &options{},
+			value:   at,
+			json:    `{"v":"2024-03-15T10:30:00.000Z"}`,
+			console: `{"v": "2024-03-15T10:30:00.000Z"}`,
+		},
+		{
+			name:    "time pointer",
+			options: &options{},
+			value:   &at,
+			json:    `{"v":"2024-03-15T10:30:00.000Z"}`,
+			console: `{"v": "2024-03-15T10:30:00.000Z"}`,
+		},
+		{
+			name:    "time with field layout",
+			options: &options{fieldTimeLayout: time.RFC1123},
+			value:   at,
+			json:    `{"v":"Fri, 15 Mar 2024 10:30:00 UTC"}`,
+			console: `{"v": "Fri, 15 Mar 2024 10:30:00 UTC"}`,
+		},
+		{
+			name:    "duration",
+			options: &options{},
+			value:   1500 * time.Millisecond,
+			json:    `{"v":1.5}`,
+			console: `{"v": 1.5}`,
+		},
+		{
+			name:    "stringer",
+			options: &options{},
+			value:   version{major: 1, minor: 2},
+			json:    `{"v":"v1.2"}`,
+			console: `{"v": "v1.2"}`,
+		},
+		{
+			name:    "panicking stringer",
+			options: &options{},
+			value:   panicky{},
+			json:    `{"v":"<PANIC=boom>"}`,
+			console: `{"v": "<PANIC=boom>"}`,
+		},
+		{
+			name:    "text marshaler",
+			options: &options{},
+			value:   level(3),
+			json:    `{"v":"!!!"}`,
+			console: `{"v": "!!!"}`,
+		},
+		{
+			name:    "stringer and text marshaler",
+			options: &options{},
+			value:   net.IPv4(192, 0, 2, 1),
+			json:    `{"v":"192.0.2.1"}`,
+			console: `{"v": "192.0.2.1"}`,
+		},
+		{
+			name:    "raw json",
+			options: &options{},
+			value:   json.RawMessage(`{"a":[1,2]}`),
+			json:    `{"v":{"a":[1,2]}}`,
+			console: `{"v": {"a":[1,2]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeNormalized(t, "json", tt.options, "v", tt.value); got != tt.json {
+				t.Errorf("json = %s, want %s", got, tt.json)
+			}
+			if got := encodeNormalized(t, "console", tt.options, "v", tt.value); got != tt.console {
+				t.Errorf("console = %s, want %s", got, tt.console)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package sazabi
+
+import (
+	"go.uber.org/zap"
+)
+
+// OptionalSinkFailedMessage is the message of the warning written when an output added
+// through WithOptionalSink cannot be opened.
+const OptionalSinkFailedMessage = "optional output failed to initialize"
+
+// WithOutput adds an output to those of the environment configuration, or to those set
+// by WithOutputPaths. The path takes the same forms as in WithOutputPaths.
+func WithOutput(path string) Option {
+	return func(o *options) {
+		o.extraOutputs = append(o.extraOutputs, path)
+	}
+}
+
+// WithOptionalSink makes the outputs added by opt, such as WithOutput, optional: when one
+// cannot be opened, for example because of a typo in its URL, the logger is built without
+// it instead of failing. An OptionalSinkFailedMessage warning carrying the error is then
+// written, and Health reports the output as unhealthy with InitFailed set. It is never
+// retried. Outputs added without WithOptionalSink keep failing the initialization.
+func WithOptionalSink(opt Option) Option {
+	return func(o *options) {
+		if opt == nil {
+			return
+		}
+		before := make(map[string]struct{}, len(o.outputPaths)+len(o.extraOutputs))
+		for _, path := range o.allOutputs() {
+			before[path] = struct{}{}
+		}
+		opt(o)
+		for _, path := range o.allOutputs() {
+			if _, ok := before[path]; !ok {
+				if o.optionalOutputs == nil {
+					o.optionalOutputs = make(map[string]struct{})
+				}
+				o.optionalOutputs[path] = struct{}{}
+			}
+		}
+	}
+}
+
+// allOutputs returns the outputs set by options.
+func (o *options) allOutputs() []string {
+	return append(o.outputPaths[:len(o.outputPaths):len(o.outputPaths)], o.extraOutputs...)
+}
+
+// optional reports whether path was added through WithOptionalSink.
+func (o *options) optional(path string) bool {
+	_, ok := o.optionalOutputs[path]
+	return ok
+}
+
+// sinkFailure is an optional output that could not be opened.
+type sinkFailure struct {
+	name string // Output, with credentials redacted
+	err  error
+}
+
+// warnSinkFailures writes a warning for each optional output that failed, using log.
+func warnSinkFailures(log *zap.Logger, failures []sinkFailure) {
+	for _, f := range failures {
+		log.Warn(OptionalSinkFailedMessage, zap.String("output", f.name), zap.Error(f.err))
+	}
+}
@@ -0,0 +1,74 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func init() {
+	// Sink standing for an integration with an invalid DSN.
+	err := zap.RegisterSink("broken", func(*url.URL) (zap.Sink, error) {
+		return nil, errors.New("invalid DSN")
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestOptionalSinkFailure(t *testing.T) {
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOptionalSink(sazabi.WithOutput("broken://token@sentry.example/1")))
+		sazabi.Info("still logging")
+	})
+
+	if lineContaining(output, "still logging") == "" {
+		t.Errorf("logger should keep the remaining outputs:\n%s", output)
+	}
+	fields := entryFields(t, output, sazabi.OptionalSinkFailedMessage)
+	if fields["output"] != "broken://sentry.example" || !strings.Contains(fields["error"].(string), "invalid DSN") {
+		t.Errorf("warning fields = %v, want the redacted output and its error", fields)
+	}
+
+	var found bool
+	for _, h := range sazabi.Health() {
+		if h.Name != "broken://sentry.example" {
+			continue
+		}
+		found = true
+		if h.Healthy || !h.InitFailed || !strings.Contains(h.LastError, "invalid DSN") {
+			t.Errorf("Health() = %+v, want unhealthy with the initialization error", h)
+		}
+	}
+	if !found {
+		t.Errorf("Health() = %+v, want the failed output", sazabi.Health())
+	}
+}
+
+func TestRequiredSinkFailure(t *testing.T) {
+	if _, err := sazabi.New(sazabi.ProductionEnvName, sazabi.WithOutput("broken://sentry.example/1")); err == nil {
+		t.Error("New() with a failing required output should fail")
+	}
+}
+
+func TestOptionalSinkHealthy(t *testing.T) {
+	path := t.TempDir() + "/optional.log"
+	log, err := sazabi.New(sazabi.ProductionEnvName, sazabi.WithOptionalSink(sazabi.WithOutput(path)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	log.Info("written")
+	for _, h := range sazabi.Health() {
+		if h.Name == path {
+			t.Errorf("Health() = %+v, want working optional outputs not reported", h)
+		}
+	}
+}
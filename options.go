@@ -0,0 +1,203 @@
+package sazabi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Option configures optional behaviour of the logger built by Initialize.
+// Options are applied in the order they are passed.
+type Option func(*options)
+
+// options holds the settings collected from the Option values passed to Initialize.
+type options struct {
+	startupSummary        bool                         // Emit a configuration summary entry after initialization
+	strictSingleInit      bool                         // Fail instead of warning on conflicting re-initialization
+	fullLineColor         bool                         // Tint whole console lines by level
+	outputPaths           []string                     // Outputs replacing the environment defaults
+	encoding              string                       // Production encoding replacing console
+	format                string                       // Encoding chosen by LOG_FORMAT, in every environment
+	gcpFormat             bool                         // Write the structured JSON of Cloud Logging
+	gcpProject            string                       // Project of the traces written by the Cloud Logging format
+	datadogFormat         bool                         // Write the standard attributes of Datadog
+	ecsVersion            string                       // Version of the Elastic Common Schema written, none when empty
+	level                 *zapcore.Level               // Level replacing the environment default
+	errorOutputPaths      []string                     // Outputs of internal errors replacing stderr
+	sampling              *SamplingConfig              // Sampling replacing the environment default, disabled when zero
+	stacktraceLevel       *zapcore.Level               // Level from which entries carry a stacktrace, none when nil
+	environmentSource     string                       // How AutoInitialize chose the environment, reported in the summary
+	extraOutputs          []string                     // Outputs added to the others
+	rotations             map[string]RotationConfig    // Settings of the outputs added by WithRotation
+	syslogs               []syslogConfig               // Syslog outputs added by WithSyslog
+	journald              bool                         // Write entries to the systemd journal
+	elasticsearch         []elasticsearchConfig        // Elasticsearch outputs added by WithElasticsearch
+	fluent                []fluentConfig               // Fluentd outputs added by WithFluentForward
+	kafka                 []kafkaConfig                // Kafka outputs added by WithKafka
+	kafkaOutputs          []*kafkaOutput               // Kafka outputs of the logger, set by build
+	rotatingFiles         []*rotatingFile              // Rotated file outputs of the logger, set by build
+	otlp                  []otlpConfig                 // OTLP outputs added by WithOTLP
+	otlpOutputs           []*otlpOutput                // OTLP outputs of the logger, set by build
+	alerts                []alertConfig                // Webhooks added by WithAlertWebhook
+	pagerDuty             []pagerDutyConfig            // PagerDuty services added by WithPagerDuty
+	metrics               MetricsCollector             // Counts the entries written, set by WithMetricsCollector
+	optionalOutputs       map[string]struct{}          // Outputs whose failure to open is tolerated
+	fallbackPath          string                       // Output used while another output fails
+	internalErrorOutput   string                       // Output of the logger's internal errors, stderr when empty
+	fallbackProbeInterval time.Duration                // Time between attempts to write to a failed output
+	fieldTimeLayout       string                       // Layout for time.Time field values
+	fatalHook             zapcore.CheckWriteHook       // Action taken after Fatal entries, os.Exit(1) when nil
+	ringBufferSize        int                          // Number of recent entries kept in memory
+	ring                  *ringBuffer                  // Ring buffer of the global logger, set by Initialize
+	incidentWindow        time.Duration                // Age of the entries kept for snapshots
+	incidentMaxBytes      int                          // Memory taken by the entries kept for snapshots
+	incident              *incidentBuffer              // Incident buffer of the global logger, set by Initialize
+	batch                 *batchTarget                 // Destination of the batches of the logger, set by build
+	raw                   zapcore.WriteSyncer          // Outputs of the logger, for WriteRaw, set by build
+	accessOutputs         []string                     // Outputs of AccessLog replacing the outputs of the logger
+	access                zapcore.WriteSyncer          // Outputs of AccessLog, set by build
+	global                bool                         // Building the global logger, whose health and volume are reported
+	volumeReportInterval  time.Duration                // Time between volume reports, none when zero
+	volumeReportTop       int                          // Number of logger names listed in volume reports
+	volumeBudget          int64                        // Encoded bytes written per minute before warning, none when zero
+	volumeBudgetAction    string                       // Action taken when the volume budget is exceeded
+	batchCompression      string                       // Compression of the batches of network outputs
+	ciEncoding            string                       // Development encoding when the output is not interactive
+	interactive           *bool                        // Overrides the detection of interactive output
+	outputValidation      bool                         // Check that encoded entries are well-formed
+	contextDiagnostics    bool                         // Annotate Ctx entries with the state of their context
+	contextFields         []contextFieldsFunc          // Sources of the fields added by the Ctx functions
+	maxFieldBytes         int                          // Size cap of string field values, unlimited when zero
+	maxUniqueKeys         int                          // Number of distinct field keys written, unlimited when zero
+	uniqueKeysAction      string                       // Action taken on keys beyond maxUniqueKeys
+	messageTranslator     func(string, []Field) string // Translates messages for operators
+	humanReadableConsole  bool                         // Render Bytes and Rate fields with IEC units in the console encoding
+	suppressDeprecations  bool                         // Make Deprecated a no-op
+	autoComponent         bool                         // Add the component of the caller to entries
+	emptyMessagePolicy    string                       // Handling of entries with an empty message
+	sanitize              bool                         // Clean messages and field values of production entries
+	sanitizeBinaryKeys    []string                     // Keys whose values are written in base64 when sanitizing
+	maskRules             []MaskRule                   // Masking rules added by WithMaskingPatterns
+	escalationThreshold   int                          // Repeated Error entries escalated, none when zero
+	escalationWindow      time.Duration                // Period counted for escalation and suppression cooldown
+	hostFields            bool                         // Add the hostname and process ID to every entry
+	ingestTime            bool                         // Keep the write time of entries timed by At
+	hostnameProvider      func() string                // Source of the hostname, os.Hostname when nil
+	pidProvider           func() int                   // Source of the process ID, os.Getpid when nil
+	idGenerator           func() string                // Source of generated IDs, random when nil
+	integrations          []integration                // Integrations enabled by other options, reported in the summary
+}
+
+// integration describes an optional integration enabled through an Option.
+// The startup summary redacts the settings: URLs keep their scheme and host, and
+// settings named like secrets (ending in key, token, secret or password) become
+// fingerprints.
+type integration struct {
+	name     string
+	settings map[string]string
+}
+
+// newOptions applies opts to a zero options value and returns it.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// signature returns a stable description of the settings that shape the logger,
+// used to tell whether two initializations were made with different options.
+// Settings that only affect initialization itself are left out.
+func (o *options) signature() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "fullLineColor=%t;", o.fullLineColor)
+	fmt.Fprintf(&b, "outputPaths=%q;", o.outputPaths)
+	fmt.Fprintf(&b, "encoding=%q,%q;", o.encoding, o.format)
+	fmt.Fprintf(&b, "gcpFormat=%t,%q;", o.gcpFormat, o.gcpProject)
+	fmt.Fprintf(&b, "datadogFormat=%t;", o.datadogFormat)
+	fmt.Fprintf(&b, "ecsVersion=%q;", o.ecsVersion)
+	if o.level != nil {
+		fmt.Fprintf(&b, "level=%s;", *o.level)
+	}
+	fmt.Fprintf(&b, "errorOutputPaths=%q;", o.errorOutputPaths)
+	if o.sampling != nil {
+		fmt.Fprintf(&b, "sampling=%d,%d;", o.sampling.Initial, o.sampling.Thereafter)
+	}
+	if o.stacktraceLevel != nil {
+		fmt.Fprintf(&b, "stacktraceLevel=%s;", *o.stacktraceLevel)
+	}
+	fmt.Fprintf(&b, "extraOutputs=%q;", o.extraOutputs)
+	fmt.Fprintf(&b, "accessOutputs=%q;", o.accessOutputs)
+	fmt.Fprintf(&b, "rotations=%v;", o.rotations)
+	optional := make([]string, 0, len(o.optionalOutputs))
+	for path := range o.optionalOutputs {
+		optional = append(optional, path)
+	}
+	sort.Strings(optional)
+	fmt.Fprintf(&b, "optionalOutputs=%q;", optional)
+	fmt.Fprintf(&b, "fallback=%q,%s;", o.fallbackPath, o.fallbackProbeInterval)
+	fmt.Fprintf(&b, "internalErrorOutput=%q;", o.internalErrorOutput)
+	fmt.Fprintf(&b, "fieldTimeLayout=%q;", o.fieldTimeLayout)
+	fmt.Fprintf(&b, "fatalHook=%t;", o.fatalHook != nil)
+	fmt.Fprintf(&b, "ringBufferSize=%d;", o.ringBufferSize)
+	fmt.Fprintf(&b, "incidentBuffer=%s,%d;", o.incidentWindow, o.incidentMaxBytes)
+	fmt.Fprintf(&b, "ciEncoding=%q;", o.ciEncoding)
+	if o.interactive != nil {
+		fmt.Fprintf(&b, "interactive=%t;", *o.interactive)
+	}
+	fmt.Fprintf(&b, "outputValidation=%t;", o.outputValidation)
+	fmt.Fprintf(&b, "contextDiagnostics=%t;", o.contextDiagnostics)
+	fmt.Fprintf(&b, "contextFields=%d;", len(o.contextFields))
+	fmt.Fprintf(&b, "maxFieldBytes=%d;", o.maxFieldBytes)
+	fmt.Fprintf(&b, "maxUniqueKeys=%d,%q;", o.maxUniqueKeys, o.uniqueKeysAction)
+	fmt.Fprintf(&b, "messageTranslator=%t;", o.messageTranslator != nil)
+	fmt.Fprintf(&b, "humanReadableConsole=%t;", o.humanReadableConsole)
+	fmt.Fprintf(&b, "suppressDeprecations=%t;", o.suppressDeprecations)
+	fmt.Fprintf(&b, "autoComponent=%t;", o.autoComponent)
+	fmt.Fprintf(&b, "emptyMessagePolicy=%q;", o.emptyMessagePolicy)
+	fmt.Fprintf(&b, "sanitize=%t,%q;", o.sanitize, o.sanitizeBinaryKeys)
+	for _, rule := range o.maskRules {
+		fmt.Fprintf(&b, "mask=%q,%q,%t;", rule.Pattern, rule.Replacement, rule.Valid != nil)
+	}
+	fmt.Fprintf(&b, "errorEscalation=%d,%s;", o.escalationThreshold, o.escalationWindow)
+	fmt.Fprintf(&b, "hostFields=%t;", o.hostFields)
+	fmt.Fprintf(&b, "ingestTime=%t;", o.ingestTime)
+	fmt.Fprintf(&b, "providers=%t,%t,%t;", o.hostnameProvider != nil, o.pidProvider != nil, o.idGenerator != nil)
+	fmt.Fprintf(&b, "volumeReport=%s,%d;", o.volumeReportInterval, o.volumeReportTop)
+	fmt.Fprintf(&b, "volumeBudget=%d,%q;", o.volumeBudget, o.volumeBudgetAction)
+	for _, in := range o.integrations {
+		fmt.Fprintf(&b, "%s=%v;", in.name, in.settings)
+	}
+	return b.String()
+}
+
+// WithStartupSummary makes Initialize emit a single Info entry describing the
+// active logging configuration once the logger has been built.
+func WithStartupSummary() Option {
+	return func(o *options) {
+		o.startupSummary = true
+	}
+}
+
+// WithJSONEncoding writes production entries as one JSON object per line instead of
+// the console encoding, for log shippers, with the same keys (ts, level, msg, caller,
+// logger, stacktrace). Development keeps the console encoding.
+func WithJSONEncoding() Option {
+	return func(o *options) {
+		o.encoding = "json"
+	}
+}
+
+// WithOutputPaths replaces the outputs of the environment configuration ("stderr").
+// Paths are file paths, "stdout", "stderr" or URLs of sinks registered with zap.RegisterSink.
+func WithOutputPaths(paths ...string) Option {
+	return func(o *options) {
+		o.outputPaths = append([]string(nil), paths...)
+	}
+}
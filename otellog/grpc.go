@@ -0,0 +1,82 @@
+package otellog
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/zeroxsolutions/sazabi"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// init registers the gRPC exporter of sazabi.WithOTLP, over connections without TLS as
+// collectors usually listen on 4317 inside the cluster.
+func init() {
+	RegisterGRPC(grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+// RegisterGRPC makes sazabi.WithOTLP export over gRPC with connections opened with
+// opts, such as grpc.WithTransportCredentials for a collector requiring TLS, when used
+// with sazabi.OTLPProtocol(sazabi.OTLPProtocolGRPC).
+func RegisterGRPC(opts ...grpc.DialOption) {
+	sazabi.RegisterOTLPExporter(sazabi.OTLPProtocolGRPC, func(endpoint string, headers map[string]string) (sazabi.OTLPExporter, error) {
+		conn, err := grpc.NewClient(endpoint, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &grpcExporter{conn: conn, client: collogspb.NewLogsServiceClient(conn), headers: metadata.New(headers)}, nil
+	})
+}
+
+// grpcExporter exports the requests of sazabi.WithOTLP with the LogsService of a
+// collector.
+type grpcExporter struct {
+	conn    *grpc.ClientConn
+	client  collogspb.LogsServiceClient
+	headers metadata.MD // Sent with each request
+}
+
+// Export implements sazabi.OTLPExporter.
+func (e *grpcExporter) Export(ctx context.Context, request []byte) error {
+	var req collogspb.ExportLogsServiceRequest
+	var err error
+	if err = protojson.Unmarshal(request, &req); err != nil {
+		return err
+	}
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			for _, rec := range sl.LogRecords {
+				if rec.TraceId, err = hexID(rec.TraceId); err != nil {
+					return err
+				}
+				if rec.SpanId, err = hexID(rec.SpanId); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if len(e.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, e.headers)
+	}
+	_, err = e.client.Export(ctx, &req)
+	return err
+}
+
+// hexID returns the ID decoded by protojson from id. The JSON encoding of OTLP writes IDs
+// in hexadecimal, which protojson reads as base64: encoding id back to base64 gives the
+// hexadecimal form.
+func hexID(id []byte) ([]byte, error) {
+	if len(id) == 0 {
+		return nil, nil
+	}
+	return hex.DecodeString(base64.StdEncoding.EncodeToString(id))
+}
+
+// Close implements sazabi.OTLPExporter.
+func (e *grpcExporter) Close() error {
+	return e.conn.Close()
+}
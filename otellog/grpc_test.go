@@ -0,0 +1,111 @@
+//go:build test
+// +build test
+
+package otellog_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/otellog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// collector is an in-process OTLP/gRPC collector recording the records it receives.
+type collector struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu      sync.Mutex
+	records []*logspb.LogRecord
+	apiKeys []string
+}
+
+func (c *collector) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiKeys = append(c.apiKeys, md.Get("api-key")...)
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			c.records = append(c.records, sl.LogRecords...)
+		}
+	}
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+// startCollector starts a collector listening on a local port.
+func startCollector(t *testing.T) (*collector, string) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &collector{}
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, c)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return c, lis.Addr().String()
+}
+
+func TestGRPCExporter(t *testing.T) {
+	c, addr := startCollector(t)
+	sazabi.Initialize("development",
+		sazabi.WithOutputPaths(t.TempDir()+"/app.log"),
+		otellog.WithCorrelation(),
+		sazabi.WithOTLP(addr,
+			sazabi.OTLPProtocol(sazabi.OTLPProtocolGRPC),
+			sazabi.OTLPHeaders(map[string]string{"api-key": "k-1"})))
+	defer sazabi.Initialize("development")
+
+	provider := sdktrace.NewTracerProvider()
+	defer provider.Shutdown(context.Background())
+	ctx, span := provider.Tracer("test").Start(context.Background(), "charge")
+	sazabi.InfoCtx(ctx, "charged", "amount", 42)
+	span.End()
+	if err := sazabi.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var rec *logspb.LogRecord
+	for _, r := range c.records {
+		if r.Body.GetStringValue() == "charged" {
+			rec = r
+		}
+	}
+	if rec == nil {
+		t.Fatalf("records = %v, want the entry exported by Shutdown", c.records)
+	}
+	sc := span.SpanContext()
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	if !bytes.Equal(rec.TraceId, traceID[:]) || !bytes.Equal(rec.SpanId, spanID[:]) {
+		t.Errorf("trace context = %x/%x, want %s/%s", rec.TraceId, rec.SpanId, traceID, spanID)
+	}
+	if rec.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_INFO || rec.SeverityText != "INFO" {
+		t.Errorf("severity = %v %q, want INFO", rec.SeverityNumber, rec.SeverityText)
+	}
+	var amount int64
+	for _, kv := range rec.Attributes {
+		if kv.Key == "amount" {
+			amount = kv.Value.GetIntValue()
+		}
+	}
+	if amount != 42 {
+		t.Errorf("attributes = %v, want amount 42", rec.Attributes)
+	}
+	if len(c.apiKeys) == 0 || c.apiKeys[0] != "k-1" {
+		t.Errorf("api-key metadata = %q, want the headers of the output", c.apiKeys)
+	}
+}
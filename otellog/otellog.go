@@ -0,0 +1,46 @@
+// Package otellog correlates the entries of sazabi with OpenTelemetry traces: with
+// WithCorrelation, the Ctx functions add the IDs of the span active in their context,
+// so that log backends can link entries to their trace.
+//
+//	sazabi.Initialize("production", otellog.WithCorrelation())
+//
+//	ctx, span := tracer.Start(ctx, "charge")
+//	defer span.End()
+//	sazabi.InfoCtx(ctx, "charging card") // trace_id and span_id added
+//
+// Importing the package also lets sazabi.WithOTLP export over gRPC, with
+// sazabi.OTLPProtocol(sazabi.OTLPProtocolGRPC); see RegisterGRPC.
+package otellog
+
+import (
+	"context"
+
+	"github.com/zeroxsolutions/sazabi"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Keys of the fields added by WithCorrelation, as in the OpenTelemetry log data model.
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// WithCorrelation makes the Ctx functions add the trace and span IDs of the span in
+// their context, in hexadecimal, under TraceIDKey and SpanIDKey. Contexts without a
+// valid, sampled span add no fields.
+func WithCorrelation() sazabi.Option {
+	return sazabi.WithContextFields(SpanFields)
+}
+
+// SpanFields returns the TraceIDKey and SpanIDKey fields of the span in ctx, or none
+// when ctx carries no valid, sampled span.
+func SpanFields(ctx context.Context) []sazabi.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || !sc.IsSampled() {
+		return nil
+	}
+	return []sazabi.Field{
+		sazabi.F(TraceIDKey, sc.TraceID().String()),
+		sazabi.F(SpanIDKey, sc.SpanID().String()),
+	}
+}
@@ -0,0 +1,68 @@
+//go:build test
+// +build test
+
+package otellog_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/otellog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// ctxEntry logs msg with InfoCtx and returns the fields of the captured entry.
+func ctxEntry(t *testing.T, ctx context.Context, msg string) sazabi.CapturedEntry {
+	t.Helper()
+
+	c, stop := sazabi.StartCapture()
+	sazabi.InfoCtx(ctx, msg)
+	stop()
+	entries := c.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("captured %d entries, want 1", len(entries))
+	}
+	return entries[0]
+}
+
+func TestWithCorrelation(t *testing.T) {
+	sazabi.Initialize("development", otellog.WithCorrelation())
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "charge")
+	e := ctxEntry(t, ctx, "in span")
+	span.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(ended))
+	}
+	sc := ended[0].SpanContext()
+	if got, _ := e.Str(otellog.TraceIDKey); got != sc.TraceID().String() {
+		t.Errorf("trace_id = %q, want %s", got, sc.TraceID())
+	}
+	if got, _ := e.Str(otellog.SpanIDKey); got != sc.SpanID().String() {
+		t.Errorf("span_id = %q, want %s", got, sc.SpanID())
+	}
+}
+
+func TestWithCorrelationWithoutSpan(t *testing.T) {
+	sazabi.Initialize("development", otellog.WithCorrelation())
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()))
+	defer provider.Shutdown(context.Background())
+	unsampled, span := provider.Tracer("test").Start(context.Background(), "dropped")
+	defer span.End()
+
+	for name, ctx := range map[string]context.Context{"no span": context.Background(), "unsampled span": unsampled} {
+		e := ctxEntry(t, ctx, name)
+		for _, key := range []string{otellog.TraceIDKey, otellog.SpanIDKey} {
+			if _, ok := e.Field(key); ok {
+				t.Errorf("entry %q has field %s", name, key)
+			}
+		}
+	}
+}
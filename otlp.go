@@ -0,0 +1,863 @@
+package sazabi
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Protocols of WithOTLP. Only OTLP/HTTP with the JSON encoding is built in; gRPC is
+// registered by importing the otellog module.
+const (
+	OTLPProtocolHTTP = "http/json"
+	OTLPProtocolGRPC = "grpc"
+)
+
+// OTLPScopeName is the name of the instrumentation scope of the records exported by
+// WithOTLP.
+const OTLPScopeName = "github.com/zeroxsolutions/sazabi"
+
+// Defaults of WithOTLP.
+const (
+	defaultOTLPBatchSize     = 512
+	defaultOTLPFlushInterval = time.Second
+	defaultOTLPQueueSize     = 10000
+	defaultOTLPMaxRetries    = 3
+	defaultOTLPTimeout       = 10 * time.Second
+)
+
+// OTLPExporter sends export requests to an OpenTelemetry collector, for WithOTLP.
+type OTLPExporter interface {
+	// Export sends request, an ExportLogsServiceRequest in the JSON encoding of OTLP,
+	// and returns once the collector accepted it or ctx is done.
+	Export(ctx context.Context, request []byte) error
+	// Close releases the exporter once the last request was sent.
+	Close() error
+}
+
+// otlpExporters opens the exporters of the protocols registered by RegisterOTLPExporter.
+var otlpExporters = struct {
+	sync.RWMutex
+	m map[string]func(endpoint string, headers map[string]string) (OTLPExporter, error)
+}{m: make(map[string]func(string, map[string]string) (OTLPExporter, error))}
+
+// RegisterOTLPExporter makes WithOTLP export with the exporters returned by open for the
+// outputs using protocol, such as OTLPProtocolGRPC. open is given the endpoint and the
+// headers of the output.
+func RegisterOTLPExporter(protocol string, open func(endpoint string, headers map[string]string) (OTLPExporter, error)) {
+	otlpExporters.Lock()
+	defer otlpExporters.Unlock()
+
+	otlpExporters.m[protocol] = open
+}
+
+// OTLPOption configures an output added by WithOTLP.
+type OTLPOption func(*otlpConfig)
+
+// otlpConfig is an OTLP output added by WithOTLP.
+type otlpConfig struct {
+	endpoint      string
+	protocol      string
+	headers       map[string]string
+	resource      map[string]string
+	batchSize     int
+	flushInterval time.Duration
+	queueSize     int
+	maxRetries    int
+	timeout       time.Duration
+	client        *http.Client
+}
+
+// OTLPProtocol exports with protocol, OTLPProtocolHTTP by default.
+func OTLPProtocol(protocol string) OTLPOption {
+	return func(c *otlpConfig) {
+		c.protocol = protocol
+	}
+}
+
+// OTLPHeaders sends headers with each export request, such as the API key of a vendor
+// collector.
+func OTLPHeaders(headers map[string]string) OTLPOption {
+	return func(c *otlpConfig) {
+		c.headers = headers
+	}
+}
+
+// OTLPResource describes the process producing the records with attributes, such as
+// "service.name", which collectors use to tell applications apart.
+func OTLPResource(attributes map[string]string) OTLPOption {
+	return func(c *otlpConfig) {
+		c.resource = attributes
+	}
+}
+
+// OTLPBatchSize sets the number of records sent by one export request, 512 by default.
+// A full batch is sent immediately.
+func OTLPBatchSize(n int) OTLPOption {
+	return func(c *otlpConfig) {
+		c.batchSize = n
+	}
+}
+
+// OTLPFlushInterval sets the longest time an entry waits before being exported, one
+// second by default. Failed requests are sent again after the same delay.
+func OTLPFlushInterval(d time.Duration) OTLPOption {
+	return func(c *otlpConfig) {
+		c.flushInterval = d
+	}
+}
+
+// OTLPQueueSize sets the number of records kept while the collector is slow or
+// unreachable, 10000 by default. Entries beyond it are dropped.
+func OTLPQueueSize(n int) OTLPOption {
+	return func(c *otlpConfig) {
+		c.queueSize = n
+	}
+}
+
+// OTLPMaxRetries sets how many times a failed export request is sent again before its
+// records are dropped, 3 by default.
+func OTLPMaxRetries(n int) OTLPOption {
+	return func(c *otlpConfig) {
+		c.maxRetries = n
+	}
+}
+
+// OTLPTimeout sets how long an export request may take, 10 seconds by default.
+func OTLPTimeout(d time.Duration) OTLPOption {
+	return func(c *otlpConfig) {
+		c.timeout = d
+	}
+}
+
+// OTLPHTTPClient sends the requests of OTLPProtocolHTTP with client, such as a client
+// trusting the certificate authority of the collector.
+func OTLPHTTPClient(client *http.Client) OTLPOption {
+	return func(c *otlpConfig) {
+		c.client = client
+	}
+}
+
+// WithOTLP adds an output exporting entries as OpenTelemetry log records to the
+// collector at endpoint. With OTLPProtocolHTTP, the default, endpoint is the URL of the
+// collector, such as "http://otel-collector:4318", to which "/v1/logs" is added unless
+// it has a path; with OTLPProtocolGRPC, it is the address of the collector, such as
+// "otel-collector:4317".
+//
+// Each entry is a record with the severity number and text of its level, the message as
+// body, and the fields as attributes, with the logger name under NameKey and the caller
+// under "code.filepath", "code.lineno" and "code.function". The trace and span IDs added
+// by otellog.WithCorrelation become the trace context of the record, and the errors of
+// zap.Error and of the error helpers become "exception.message", "exception.type" and
+// "exception.stacktrace".
+//
+// Records are queued and exported in the background by batches, so that a slow or
+// unreachable collector never blocks logging; Sync exports the queued records, and
+// Shutdown exports them and closes the exporter. Failed requests are sent again up to
+// OTLPMaxRetries times. Records dropped then, and entries that do not fit in the queue,
+// are counted as dropped by PublishExpvars. Failures are reported to the internal error
+// output.
+func WithOTLP(endpoint string, opts ...OTLPOption) Option {
+	cfg := otlpConfig{
+		endpoint:      endpoint,
+		protocol:      OTLPProtocolHTTP,
+		batchSize:     defaultOTLPBatchSize,
+		flushInterval: defaultOTLPFlushInterval,
+		queueSize:     defaultOTLPQueueSize,
+		maxRetries:    defaultOTLPMaxRetries,
+		timeout:       defaultOTLPTimeout,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(o *options) {
+		o.otlp = append(o.otlp, cfg)
+		headers := make([]string, 0, len(cfg.headers))
+		for name := range cfg.headers {
+			headers = append(headers, name)
+		}
+		sort.Strings(headers)
+		o.integrations = append(o.integrations, integration{
+			name: "otlp",
+			settings: map[string]string{
+				"endpoint": redactURL(cfg.endpoint),
+				"protocol": cfg.protocol,
+				"headers":  strings.Join(headers, ","),
+			},
+		})
+	}
+}
+
+// newOTLPCores returns the cores of the OTLP outputs of o, writing the entries enabled
+// by enab and reporting failures to errorOutput. The outputs are recorded in o, so that
+// Shutdown closes those of the global logger.
+func newOTLPCores(enab zapcore.LevelEnabler, errorOutput zapcore.WriteSyncer, o *options) ([]zapcore.Core, error) {
+	cores := make([]zapcore.Core, 0, len(o.otlp))
+	for _, cfg := range o.otlp {
+		switch {
+		case cfg.endpoint == "":
+			return nil, fmt.Errorf("sazabi: no OTLP endpoint")
+		case cfg.batchSize <= 0 || cfg.queueSize <= 0 || cfg.flushInterval <= 0 || cfg.maxRetries < 0 || cfg.timeout <= 0:
+			return nil, fmt.Errorf("sazabi: invalid OTLP batch size, queue size, flush interval, retries or timeout")
+		}
+		exporter, err := openOTLPExporter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		resource, err := json.Marshal(otlpStringAttributes(cfg.resource))
+		if err != nil {
+			return nil, err
+		}
+		out := &otlpOutput{cfg: cfg, exporter: exporter, resource: resource, global: o.global, errorOutput: errorOutput}
+		o.otlpOutputs = append(o.otlpOutputs, out)
+		cores = append(cores, &otlpCore{LevelEnabler: enab, fields: make(map[string]interface{}), out: out})
+	}
+	return cores, nil
+}
+
+// openOTLPExporter returns the exporter of cfg: the built-in one for OTLPProtocolHTTP,
+// else the one registered for its protocol.
+func openOTLPExporter(cfg otlpConfig) (OTLPExporter, error) {
+	if cfg.protocol == OTLPProtocolHTTP {
+		return newOTLPHTTPExporter(cfg)
+	}
+	otlpExporters.RLock()
+	open, ok := otlpExporters.m[cfg.protocol]
+	otlpExporters.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("sazabi: no OTLP exporter registered for protocol %q", cfg.protocol)
+	}
+	exporter, err := open(cfg.endpoint, cfg.headers)
+	if err != nil {
+		return nil, fmt.Errorf("sazabi: OTLP exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+// otlpCore queues entries as log records for an OTLP output.
+type otlpCore struct {
+	zapcore.LevelEnabler
+	fields map[string]interface{} // Fields added by With, as encoded by zapcore.MapObjectEncoder
+	out    *otlpOutput
+}
+
+// encoder returns an encoder holding a copy of the fields added by With.
+func (c *otlpCore) encoder() *zapcore.MapObjectEncoder {
+	enc := zapcore.NewMapObjectEncoder()
+	for key, value := range c.fields {
+		enc.Fields[key] = copyFieldValue(value)
+	}
+	return enc
+}
+
+// With implements zapcore.Core.
+func (c *otlpCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.encoder()
+	for _, f := range fields {
+		for _, mapped := range otlpFields(f) {
+			mapped.AddTo(enc)
+		}
+	}
+	return &otlpCore{LevelEnabler: c.LevelEnabler, fields: enc.Fields, out: c.out}
+}
+
+// Check implements zapcore.Core.
+func (c *otlpCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. The record is exported in the background, so Write only
+// returns encoding errors.
+func (c *otlpCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := c.encoder()
+	for _, f := range fields {
+		for _, mapped := range otlpFields(f) {
+			mapped.AddTo(enc)
+		}
+	}
+	record, err := json.Marshal(newOTLPRecord(ent, enc.Fields, time.Now()))
+	if err != nil {
+		return err
+	}
+	c.out.add(record)
+	return nil
+}
+
+// Sync implements zapcore.Core, exporting the queued records.
+func (c *otlpCore) Sync() error {
+	return c.out.Sync()
+}
+
+// otlpFields returns the fields f is written as: the exception attributes for errors,
+// else f itself.
+func otlpFields(f zapcore.Field) []zapcore.Field {
+	if f.Key != ErrorKey || f.Type != zapcore.ErrorType {
+		return []zapcore.Field{f}
+	}
+	err, ok := f.Interface.(error)
+	if !ok || err == nil {
+		return []zapcore.Field{f}
+	}
+	fields := []zapcore.Field{
+		zap.String("exception.message", err.Error()),
+		zap.String("exception.type", fmt.Sprintf("%T", err)),
+	}
+	if verbose := fmt.Sprintf("%+v", err); verbose != err.Error() {
+		fields = append(fields, zap.String("exception.stacktrace", verbose))
+	}
+	return fields
+}
+
+// otlpRecord is a LogRecord in the JSON encoding of OTLP, where 64-bit integers are
+// strings and IDs are hexadecimal.
+type otlpRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpValue      `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+	Flags                uint32         `json:"flags,omitempty"`
+}
+
+// otlpKeyValue is a KeyValue of OTLP.
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue is an AnyValue of OTLP: a single key naming the type of the value, such as
+// "stringValue", mapped to the value.
+type otlpValue map[string]interface{}
+
+// newOTLPRecord returns the record of ent with fields, encoded by a
+// zapcore.MapObjectEncoder, observed at observed.
+func newOTLPRecord(ent zapcore.Entry, fields map[string]interface{}, observed time.Time) otlpRecord {
+	rec := otlpRecord{
+		TimeUnixNano:         strconv.FormatInt(ent.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(observed.UnixNano(), 10),
+		SeverityNumber:       otlpSeverity(ent.Level),
+		SeverityText:         ent.Level.CapitalString(),
+		Body:                 otlpValue{"stringValue": ent.Message},
+	}
+	traceID, _ := fields[traceIDKey].(string)
+	spanID, _ := fields[spanIDKey].(string)
+	if isHexID(traceID, 16) && isHexID(spanID, 8) {
+		rec.TraceID, rec.SpanID, rec.Flags = traceID, spanID, 1 // otellog only adds sampled spans
+		delete(fields, traceIDKey)
+		delete(fields, spanIDKey)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		rec.Attributes = append(rec.Attributes, otlpKeyValue{Key: otlpAttributeKey(key), Value: newOTLPValue(fields[key])})
+	}
+	if ent.LoggerName != "" {
+		rec.Attributes = append(rec.Attributes, otlpKeyValue{Key: NameKey, Value: otlpValue{"stringValue": ent.LoggerName}})
+	}
+	if ent.Caller.Defined {
+		rec.Attributes = append(rec.Attributes,
+			otlpKeyValue{Key: "code.filepath", Value: otlpValue{"stringValue": ent.Caller.File}},
+			otlpKeyValue{Key: "code.lineno", Value: otlpValue{"intValue": strconv.Itoa(ent.Caller.Line)}},
+		)
+		if ent.Caller.Function != "" {
+			rec.Attributes = append(rec.Attributes, otlpKeyValue{Key: "code.function", Value: otlpValue{"stringValue": ent.Caller.Function}})
+		}
+	}
+	if ent.Stack != "" {
+		rec.Attributes = append(rec.Attributes, otlpKeyValue{Key: "code.stacktrace", Value: otlpValue{"stringValue": ent.Stack}})
+	}
+	return rec
+}
+
+// otlpAttributeKey returns the attribute key of a field: the exception attributes for the
+// error fields of sazabi and those added by With, else key itself.
+func otlpAttributeKey(key string) string {
+	switch key {
+	case ErrorKey:
+		return "exception.message"
+	case ErrorTypeKey:
+		return "exception.type"
+	case ErrorVerboseKey, ErrorKey + "Verbose":
+		return "exception.stacktrace"
+	}
+	return key
+}
+
+// otlpSeverity returns the SeverityNumber of level.
+func otlpSeverity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 5 // DEBUG
+	case zapcore.InfoLevel:
+		return 9 // INFO
+	case zapcore.WarnLevel:
+		return 13 // WARN
+	case zapcore.ErrorLevel:
+		return 17 // ERROR
+	case zapcore.DPanicLevel:
+		return 21 // FATAL
+	case zapcore.PanicLevel:
+		return 22 // FATAL2
+	case zapcore.FatalLevel:
+		return 23 // FATAL3
+	}
+	return 0 // UNSPECIFIED
+}
+
+// isHexID reports whether s is the hexadecimal form of a non-zero ID of n bytes.
+func isHexID(s string, n int) bool {
+	id, err := hex.DecodeString(s)
+	return err == nil && len(id) == n && strings.Trim(s, "0") != ""
+}
+
+// newOTLPValue returns the AnyValue of v, a value added to a zapcore.MapObjectEncoder.
+// Durations are in seconds, as in JSON output, and times in RFC 3339.
+func newOTLPValue(v interface{}) otlpValue {
+	switch v := v.(type) {
+	case nil:
+		return otlpValue{}
+	case string:
+		return otlpValue{"stringValue": v}
+	case bool:
+		return otlpValue{"boolValue": v}
+	case int:
+		return otlpValue{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int8:
+		return otlpValue{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int16:
+		return otlpValue{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int32:
+		return otlpValue{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int64:
+		return otlpValue{"intValue": strconv.FormatInt(v, 10)}
+	case uint:
+		return otlpUint(uint64(v))
+	case uint8:
+		return otlpUint(uint64(v))
+	case uint16:
+		return otlpUint(uint64(v))
+	case uint32:
+		return otlpUint(uint64(v))
+	case uint64:
+		return otlpUint(v)
+	case uintptr:
+		return otlpUint(uint64(v))
+	case float32:
+		return otlpDouble(float64(v))
+	case float64:
+		return otlpDouble(v)
+	case complex64, complex128:
+		return otlpValue{"stringValue": fmt.Sprint(v)}
+	case []byte:
+		return otlpValue{"bytesValue": v}
+	case time.Duration:
+		return otlpDouble(v.Seconds())
+	case time.Time:
+		return otlpValue{"stringValue": v.Format(time.RFC3339Nano)}
+	case []interface{}:
+		values := make([]otlpValue, len(v))
+		for i, e := range v {
+			values[i] = newOTLPValue(e)
+		}
+		return otlpValue{"arrayValue": map[string]interface{}{"values": values}}
+	case map[string]interface{}:
+		return otlpKvlist(v, newOTLPValue)
+	}
+	return otlpReflected(v)
+}
+
+// otlpKvlist returns the AnyValue of m, with its values converted by convert.
+func otlpKvlist(m map[string]interface{}, convert func(interface{}) otlpValue) otlpValue {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]otlpKeyValue, len(keys))
+	for i, key := range keys {
+		values[i] = otlpKeyValue{Key: key, Value: convert(m[key])}
+	}
+	return otlpValue{"kvlistValue": map[string]interface{}{"values": values}}
+}
+
+// otlpUint returns the AnyValue of an unsigned integer, a string beyond the range of
+// int64.
+func otlpUint(v uint64) otlpValue {
+	if v > math.MaxInt64 {
+		return otlpValue{"stringValue": strconv.FormatUint(v, 10)}
+	}
+	return otlpValue{"intValue": strconv.FormatUint(v, 10)}
+}
+
+// otlpDouble returns the AnyValue of a float, with NaN and infinities in the string form
+// of the JSON encoding of protobuf.
+func otlpDouble(v float64) otlpValue {
+	switch {
+	case math.IsNaN(v):
+		return otlpValue{"doubleValue": "NaN"}
+	case math.IsInf(v, 1):
+		return otlpValue{"doubleValue": "Infinity"}
+	case math.IsInf(v, -1):
+		return otlpValue{"doubleValue": "-Infinity"}
+	}
+	return otlpValue{"doubleValue": v}
+}
+
+// otlpReflected returns the AnyValue of a value added with AddReflected, such as by
+// zap.Any, from its JSON form, or its fmt.Sprint text when it has none.
+func otlpReflected(v interface{}) otlpValue {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return otlpValue{"stringValue": fmt.Sprint(v)}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return otlpValue{"stringValue": string(data)}
+	}
+	return otlpJSONValue(decoded)
+}
+
+// otlpJSONValue returns the AnyValue of v, decoded from JSON with json.Number numbers.
+func otlpJSONValue(v interface{}) otlpValue {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return otlpValue{"intValue": strconv.FormatInt(n, 10)}
+		}
+		f, _ := v.Float64()
+		return otlpDouble(f)
+	case []interface{}:
+		values := make([]otlpValue, len(v))
+		for i, e := range v {
+			values[i] = otlpJSONValue(e)
+		}
+		return otlpValue{"arrayValue": map[string]interface{}{"values": values}}
+	case map[string]interface{}:
+		return otlpKvlist(v, otlpJSONValue)
+	}
+	return newOTLPValue(v) // Strings, booleans and null
+}
+
+// otlpStringAttributes returns attributes as KeyValues, sorted by key.
+func otlpStringAttributes(attributes map[string]string) []otlpKeyValue {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]otlpKeyValue, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, otlpKeyValue{Key: key, Value: otlpValue{"stringValue": attributes[key]}})
+	}
+	return values
+}
+
+// otlpItem is a record waiting to be exported.
+type otlpItem struct {
+	record   []byte // LogRecord in the JSON encoding of OTLP
+	attempts int    // Failed attempts to export it
+}
+
+// otlpOutput queues records and exports them by batches.
+type otlpOutput struct {
+	cfg         otlpConfig
+	exporter    OTLPExporter
+	resource    []byte              // Attributes of the resource, in JSON
+	global      bool                // Whether dropped entries are counted by PublishExpvars
+	errorOutput zapcore.WriteSyncer // Receives failures
+
+	sendMu sync.Mutex // Serializes export requests; never held with mu while sending
+
+	mu          sync.Mutex
+	queue       []otlpItem  // Records waiting to be exported, oldest first
+	timer       *time.Timer // Flushes the queue after the flush interval, nil when none is scheduled
+	flushing    bool        // Whether a background flush is running
+	overflowing bool        // Whether entries are being dropped because the queue is full
+	closed      bool        // Whether Shutdown closed the output
+	dropped     int64       // Entries dropped since the output was opened
+}
+
+// add queues record, or drops it when the queue is full or the output closed.
+func (o *otlpOutput) add(record []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	switch {
+	case o.closed:
+		o.dropLocked(1)
+		return
+	case len(o.queue) >= o.cfg.queueSize:
+		o.dropLocked(1)
+		if !o.overflowing {
+			o.overflowing = true
+			o.reportf("queue full, dropping entries")
+		}
+		return
+	}
+	o.overflowing = false
+	o.queue = append(o.queue, otlpItem{record: record})
+	if len(o.queue) >= o.cfg.batchSize {
+		o.flushLocked()
+	} else {
+		o.scheduleLocked()
+	}
+}
+
+// scheduleLocked schedules a flush after the flush interval, unless one is scheduled
+// already or the output is closed. o.mu must be held.
+func (o *otlpOutput) scheduleLocked() {
+	if o.timer != nil || o.closed {
+		return
+	}
+	o.timer = time.AfterFunc(o.cfg.flushInterval, func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.flushLocked()
+	})
+}
+
+// flushLocked starts exporting the queue in the background, unless a background flush
+// is already running. o.mu must be held.
+func (o *otlpOutput) flushLocked() {
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	if o.flushing {
+		return
+	}
+	o.flushing = true
+	go func() {
+		if err := o.sendQueued(); err != nil {
+			o.reportf("export failed: %v", err)
+		}
+
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.flushing = false
+		if len(o.queue) > 0 {
+			o.scheduleLocked() // Records to retry, or queued while sending
+		}
+	}()
+}
+
+// Sync exports the queued records. It returns the error of the first request that
+// failed; its records stay queued for a retry.
+func (o *otlpOutput) Sync() error {
+	o.mu.Lock()
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	o.mu.Unlock()
+
+	err := o.sendQueued()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.queue) > 0 {
+		o.scheduleLocked()
+	}
+	return err
+}
+
+// close exports the queued records, then closes the exporter. Records that fail to be
+// exported are dropped and reported, and so are the entries written afterwards.
+func (o *otlpOutput) close() {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return
+	}
+	o.closed = true
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	o.mu.Unlock()
+
+	err := o.sendQueued() // Waits for a background flush
+
+	o.mu.Lock()
+	if n := len(o.queue); n > 0 {
+		o.queue = nil
+		o.dropLocked(n)
+		o.reportf("closed with %d entries not exported: %v", n, err)
+	}
+	o.mu.Unlock()
+	if err := o.exporter.Close(); err != nil {
+		o.reportf("closing exporter: %v", err)
+	}
+}
+
+// sendQueued exports the records queued when it is called, by batches, stopping at the
+// first request that fails. Its records are queued again, for the next flush.
+func (o *otlpOutput) sendQueued() error {
+	o.sendMu.Lock()
+	defer o.sendMu.Unlock()
+
+	o.mu.Lock()
+	remaining := len(o.queue)
+	o.mu.Unlock()
+	for remaining > 0 {
+		o.mu.Lock()
+		n := o.cfg.batchSize
+		if n > remaining {
+			n = remaining
+		}
+		if n > len(o.queue) {
+			n = len(o.queue)
+		}
+		batch := append([]otlpItem(nil), o.queue[:n]...)
+		o.queue = o.queue[n:]
+		o.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		remaining -= n
+
+		ctx, cancel := context.WithTimeout(context.Background(), o.cfg.timeout)
+		err := o.exporter.Export(ctx, o.request(batch))
+		cancel()
+		if err != nil {
+			o.requeue(batch)
+			return err
+		}
+	}
+	return nil
+}
+
+// request returns the ExportLogsServiceRequest of batch.
+func (o *otlpOutput) request(batch []otlpItem) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"resourceLogs":[{"resource":{"attributes":`)
+	b.Write(o.resource)
+	b.WriteString(`},"scopeLogs":[{"scope":{"name":"` + OTLPScopeName + `"},"logRecords":[`)
+	for i, item := range batch {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(item.record)
+	}
+	b.WriteString(`]}]}]}`)
+	return b.Bytes()
+}
+
+// requeue puts the items of a failed request back at the front of the queue, dropping
+// those that were sent cfg.maxRetries times already and the oldest ones beyond the queue
+// size.
+func (o *otlpOutput) requeue(items []otlpItem) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	kept := make([]otlpItem, 0, len(items)+len(o.queue))
+	expired := 0
+	for _, item := range items {
+		if item.attempts++; item.attempts > o.cfg.maxRetries {
+			expired++
+			continue
+		}
+		kept = append(kept, item)
+	}
+	o.queue = append(kept, o.queue...)
+	overflow := 0
+	if len(o.queue) > o.cfg.queueSize {
+		overflow = len(o.queue) - o.cfg.queueSize
+		o.queue = o.queue[overflow:]
+	}
+	if expired+overflow > 0 {
+		o.dropLocked(expired + overflow)
+		o.reportf("%d entries dropped after %d retries, %d for lack of room in the queue", expired, o.cfg.maxRetries, overflow)
+	}
+}
+
+// dropLocked counts n dropped entries. o.mu must be held.
+func (o *otlpOutput) dropLocked(n int) {
+	o.dropped += int64(n)
+	if o.global {
+		countOutputDropped(n)
+	}
+}
+
+// reportf writes a failure to the internal error output.
+func (o *otlpOutput) reportf(format string, args ...interface{}) {
+	fmt.Fprintf(o.errorOutput, "%v output otlp %s: %s\n", time.Now(), redactURL(o.cfg.endpoint), fmt.Sprintf(format, args...))
+	o.errorOutput.Sync()
+}
+
+// otlpHTTPExporter exports with OTLP/HTTP and the JSON encoding.
+type otlpHTTPExporter struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// newOTLPHTTPExporter returns the OTLPProtocolHTTP exporter of cfg.
+func newOTLPHTTPExporter(cfg otlpConfig) (*otlpHTTPExporter, error) {
+	u, err := url.Parse(cfg.endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("sazabi: invalid OTLP endpoint %q, want an http or https URL", redactURL(cfg.endpoint))
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/logs"
+	}
+	client := cfg.client
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &otlpHTTPExporter{url: u.String(), headers: cfg.headers, client: client}, nil
+}
+
+// Export implements OTLPExporter.
+func (e *otlpHTTPExporter) Export(ctx context.Context, request []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", redactURL(e.url), resp.Status)
+	}
+	return nil
+}
+
+// Close implements OTLPExporter.
+func (e *otlpHTTPExporter) Close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
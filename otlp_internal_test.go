@@ -0,0 +1,222 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeExporter is an in-memory OTLPExporter, failing with the error returned by fail.
+type fakeExporter struct {
+	fail func() error
+
+	mu       sync.Mutex
+	requests []string
+	attempts int
+	closed   bool
+}
+
+func (e *fakeExporter) Export(ctx context.Context, request []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.attempts++
+	if e.fail != nil {
+		if err := e.fail(); err != nil {
+			return err
+		}
+	}
+	e.requests = append(e.requests, string(request))
+	return nil
+}
+
+func (e *fakeExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	return nil
+}
+
+// newTestExporter returns a core exporting with e and opts, and the buffer receiving its
+// failures. Batches are only sent by Sync and close.
+func newTestExporter(t *testing.T, e *fakeExporter, opts ...OTLPOption) (*otlpCore, *lockedBuffer) {
+	t.Helper()
+
+	RegisterOTLPExporter("fake", func(endpoint string, headers map[string]string) (OTLPExporter, error) {
+		return e, nil
+	})
+	t.Cleanup(func() {
+		otlpExporters.Lock()
+		delete(otlpExporters.m, "fake")
+		otlpExporters.Unlock()
+	})
+	errs := &lockedBuffer{}
+	opts = append([]OTLPOption{OTLPProtocol("fake"), OTLPFlushInterval(time.Hour)}, opts...)
+	o := newOptions([]Option{WithOTLP("collector:4317", opts...)})
+	cores, err := newOTLPCores(zapcore.DebugLevel, errs, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o.otlpOutputs) != 1 {
+		t.Fatalf("%d outputs recorded, want 1", len(o.otlpOutputs))
+	}
+	return cores[0].(*otlpCore), errs
+}
+
+// export writes an Info entry with msg to c.
+func export(t *testing.T, c zapcore.Core, msg string, fields ...zapcore.Field) {
+	t.Helper()
+
+	if err := c.Write(zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: msg}, fields); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOTLPExportFailure(t *testing.T) {
+	e := &fakeExporter{fail: func() error { return errors.New("collector unavailable") }}
+	c, errs := newTestExporter(t, e, OTLPMaxRetries(2))
+
+	export(t, c, "lost")
+	for i := 0; i < 3; i++ {
+		if err := c.Sync(); err == nil || !strings.Contains(err.Error(), "collector unavailable") {
+			t.Errorf("Sync() = %v, want the export error", err)
+		}
+	}
+	if len(c.out.queue) != 0 || c.out.dropped != 1 || e.attempts != 3 {
+		t.Errorf("%d queued, %d dropped after %d attempts; want the record dropped after 2 retries", len(c.out.queue), c.out.dropped, e.attempts)
+	}
+	if !strings.Contains(errs.String(), "1 entries dropped after 2 retries") {
+		t.Errorf("internal errors = %q, want the dropped record reported", errs.String())
+	}
+	if err := c.Sync(); err != nil || e.attempts != 3 {
+		t.Errorf("Sync() with an empty queue = %v after %d attempts, want nil without request", err, e.attempts)
+	}
+}
+
+func TestOTLPQueueFull(t *testing.T) {
+	e := &fakeExporter{}
+	c, errs := newTestExporter(t, e, OTLPQueueSize(2))
+
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		export(t, c, msg)
+	}
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(e.requests) != 1 || strings.Count(e.requests[0], `"body"`) != 2 {
+		t.Errorf("requests = %q, want one with the records that fit in the queue", e.requests)
+	}
+	if c.out.dropped != 2 || strings.Count(errs.String(), "queue full") != 1 {
+		t.Errorf("%d dropped, internal errors = %q; want 2 dropped and reported once", c.out.dropped, errs.String())
+	}
+}
+
+func TestOTLPClose(t *testing.T) {
+	var failing bool
+	e := &fakeExporter{fail: func() error {
+		if failing {
+			return errors.New("collector unavailable")
+		}
+		return nil
+	}}
+	c, errs := newTestExporter(t, e)
+
+	export(t, c, "a")
+	export(t, c, "b")
+	c.out.close()
+	if len(e.requests) != 1 || strings.Count(e.requests[0], `"body"`) != 2 || !e.closed {
+		t.Errorf("requests = %q, closed = %t; want the queued records exported, then the exporter closed", e.requests, e.closed)
+	}
+
+	export(t, c, "after close")
+	if c.out.dropped != 1 || len(e.requests) != 1 {
+		t.Errorf("%d dropped, %d requests; want the entry written after close dropped", c.out.dropped, len(e.requests))
+	}
+	c.out.close() // Harmless
+	if errs.String() != "" {
+		t.Errorf("internal errors = %q, want none", errs.String())
+	}
+
+	e, failing = &fakeExporter{fail: e.fail}, true
+	c, errs = newTestExporter(t, e)
+	export(t, c, "a")
+	c.out.close()
+	if c.out.dropped != 1 || !strings.Contains(errs.String(), "closed with 1 entries not exported: collector unavailable") || !e.closed {
+		t.Errorf("%d dropped, internal errors = %q; want the record dropped and reported", c.out.dropped, errs.String())
+	}
+}
+
+func TestOTLPRecord(t *testing.T) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range []zapcore.Field{
+		zap.Uint64("big", math.MaxUint64),
+		zap.Float64("nan", math.NaN()),
+		zap.Duration("elapsed", 1500*time.Millisecond),
+		zap.Binary("raw", []byte{1, 2}),
+		zap.Strings("tags", []string{"a", "b"}),
+		zap.Any("order", struct {
+			ID    int     `json:"id"`
+			Total float64 `json:"total"`
+		}{7, 9.5}),
+		zap.String(ErrorKey, "boom"),
+		zap.String(ErrorTypeKey, "*errors.errorString"),
+		zap.String(traceIDKey, "not hex"),
+	} {
+		f.AddTo(enc)
+	}
+	ent := zapcore.Entry{Level: zapcore.DPanicLevel, Time: time.Unix(1, 5), Message: "m"}
+	data, err := json.Marshal(newOTLPRecord(ent, enc.Fields, time.Unix(2, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := string(data)
+
+	for _, want := range []string{
+		`"timeUnixNano":"1000000005"`,
+		`"observedTimeUnixNano":"2000000000"`,
+		`"severityNumber":21,"severityText":"DPANIC"`,
+		`{"key":"big","value":{"stringValue":"18446744073709551615"}}`,
+		`{"key":"nan","value":{"doubleValue":"NaN"}}`,
+		`{"key":"elapsed","value":{"doubleValue":1.5}}`,
+		`{"key":"raw","value":{"bytesValue":"AQI="}}`,
+		`{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{"stringValue":"b"}]}}}`,
+		`{"key":"order","value":{"kvlistValue":{"values":[{"key":"id","value":{"intValue":"7"}},{"key":"total","value":{"doubleValue":9.5}}]}}}`,
+		`{"key":"exception.message","value":{"stringValue":"boom"}}`,
+		`{"key":"exception.type","value":{"stringValue":"*errors.errorString"}}`,
+		`{"key":"trace_id","value":{"stringValue":"not hex"}}`,
+	} {
+		if !strings.Contains(record, want) {
+			t.Errorf("record = %s, want %s", record, want)
+		}
+	}
+	if strings.Contains(record, "traceId") {
+		t.Errorf("record = %s, want no trace context for an invalid trace ID", record)
+	}
+}
+
+func TestOTLPInvalidConfig(t *testing.T) {
+	for _, opt := range []Option{
+		WithOTLP(""),
+		WithOTLP("otel-collector:4318"), // Not a URL
+		WithOTLP("http://otel-collector:4318", OTLPBatchSize(0)),
+		WithOTLP("http://otel-collector:4318", OTLPTimeout(0)),
+		WithOTLP("otel-collector:4317", OTLPProtocol("unknown")),
+	} {
+		if _, err := newOTLPCores(zapcore.InfoLevel, &lockedBuffer{}, newOptions([]Option{opt})); err == nil {
+			t.Error("newOTLPCores succeeded with an invalid config, want an error")
+		}
+	}
+}
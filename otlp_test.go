@@ -0,0 +1,213 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap"
+)
+
+// otlpRequest is the part of an ExportLogsServiceRequest checked by the tests.
+type otlpRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Name string `json:"name"`
+			} `json:"scope"`
+			LogRecords []otlpLogRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string                     `json:"timeUnixNano"`
+	SeverityNumber int                        `json:"severityNumber"`
+	SeverityText   string                     `json:"severityText"`
+	Body           map[string]json.RawMessage `json:"body"`
+	Attributes     []otlpAttribute            `json:"attributes"`
+	TraceID        string                     `json:"traceId"`
+	SpanID         string                     `json:"spanId"`
+	Flags          int                        `json:"flags"`
+}
+
+type otlpAttribute struct {
+	Key   string                     `json:"key"`
+	Value map[string]json.RawMessage `json:"value"`
+}
+
+// attribute returns the JSON of the value of the attribute key, such as
+// {"stringValue":"x"}, or "" when the record has none.
+func (r otlpLogRecord) attribute(key string) string {
+	for _, a := range r.Attributes {
+		if a.Key == key {
+			data, _ := json.Marshal(a.Value)
+			return string(data)
+		}
+	}
+	return ""
+}
+
+// collector is an in-process OTLP/HTTP collector recording the requests it receives.
+type collector struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []otlpRequest
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Api-Key") != "k-1" {
+			t.Errorf("request to %s with %v, want JSON to /v1/logs with the headers", r.URL.Path, r.Header)
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		c.mu.Lock()
+		c.requests = append(c.requests, req)
+		c.mu.Unlock()
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// records returns the records received, by request.
+func (c *collector) records() [][]otlpLogRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var batches [][]otlpLogRecord
+	for _, req := range c.requests {
+		var batch []otlpLogRecord
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				batch = append(batch, sl.LogRecords...)
+			}
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+func TestWithOTLP(t *testing.T) {
+	restoreDefault(t)
+	c := newCollector(t)
+	traced := sazabi.WithContextFields(func(ctx context.Context) []sazabi.Field {
+		return []sazabi.Field{
+			sazabi.F("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"),
+			sazabi.F("span_id", "00f067aa0ba902b7"),
+		}
+	})
+
+	captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName,
+			sazabi.WithOutputPaths("stderr"),
+			traced,
+			sazabi.WithOTLP(c.URL,
+				sazabi.OTLPHeaders(map[string]string{"Api-Key": "k-1"}),
+				sazabi.OTLPResource(map[string]string{"service.name": "billing"})))
+		sazabi.Sync() // Exports the warning about the re-initialization, if any
+		sazabi.InfoCtx(context.Background(), "charged", "amount", 42, "card", map[string]interface{}{"brand": "visa"}, "ok", true)
+		sazabi.Named("payments").Warnw("declined", zap.Error(errors.New("insufficient funds")), "ratio", 0.5)
+		if err := sazabi.Sync(); err != nil {
+			t.Errorf("Sync() error = %v", err)
+		}
+	})
+
+	batches := c.records()
+	last := batches[len(batches)-1]
+	if len(last) != 2 {
+		t.Fatalf("last request has %d records, want both entries", len(last))
+	}
+	c.mu.Lock()
+	req := c.requests[len(c.requests)-1]
+	c.mu.Unlock()
+	if name := req.ResourceLogs[0].ScopeLogs[0].Scope.Name; name != sazabi.OTLPScopeName {
+		t.Errorf("scope = %q, want %s", name, sazabi.OTLPScopeName)
+	}
+	if attrs := req.ResourceLogs[0].Resource.Attributes; len(attrs) != 1 || attrs[0].Key != "service.name" {
+		t.Errorf("resource attributes = %v, want service.name", attrs)
+	}
+
+	charged, declined := last[0], last[1]
+	if charged.SeverityNumber != 9 || charged.SeverityText != "INFO" || string(charged.Body["stringValue"]) != `"charged"` || charged.TimeUnixNano == "" {
+		t.Errorf("record = %+v, want an INFO record with the message as body", charged)
+	}
+	for key, want := range map[string]string{
+		"amount": `{"intValue":"42"}`,
+		"ok":     `{"boolValue":true}`,
+		"card":   `{"kvlistValue":{"values":[{"key":"brand","value":{"stringValue":"visa"}}]}}`,
+	} {
+		if got := charged.attribute(key); got != want {
+			t.Errorf("attribute %s = %s, want %s", key, got, want)
+		}
+	}
+	if file := charged.attribute("code.filepath"); !strings.HasSuffix(file, `otlp_test.go"}`) {
+		t.Errorf("code.filepath = %s, want the file of the caller", file)
+	}
+	if charged.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || charged.SpanID != "00f067aa0ba902b7" || charged.Flags != 1 || charged.attribute("trace_id") != "" {
+		t.Errorf("trace context = %s/%s/%d, want the IDs of the context, not as attributes", charged.TraceID, charged.SpanID, charged.Flags)
+	}
+
+	if declined.SeverityNumber != 13 || declined.SeverityText != "WARN" || declined.TraceID != "" {
+		t.Errorf("record = %+v, want a WARN record without trace context", declined)
+	}
+	for key, want := range map[string]string{
+		"exception.message": `{"stringValue":"insufficient funds"}`,
+		"exception.type":    `{"stringValue":"*errors.errorString"}`,
+		"ratio":             `{"doubleValue":0.5}`,
+		sazabi.NameKey:      `{"stringValue":"payments"}`,
+	} {
+		if got := declined.attribute(key); got != want {
+			t.Errorf("attribute %s = %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestWithOTLPBatches(t *testing.T) {
+	restoreDefault(t)
+	c := newCollector(t)
+
+	captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName,
+			sazabi.WithOutputPaths("stderr"),
+			sazabi.WithOTLP(c.URL+"/", sazabi.OTLPHeaders(map[string]string{"Api-Key": "k-1"}), sazabi.OTLPBatchSize(2)))
+		sazabi.Sync() // Exports the warning about the re-initialization, if any
+		for _, msg := range []string{"a", "b", "c"} {
+			sazabi.Info(msg)
+		}
+		sazabi.Shutdown() // Exports the record left in the queue
+	})
+
+	var batches []string
+	for _, batch := range c.records() {
+		var bodies []string
+		for _, rec := range batch {
+			if body := string(rec.Body["stringValue"]); body != `"`+sazabi.ConflictingInitializeMessage+`"` {
+				bodies = append(bodies, body)
+			}
+		}
+		if len(bodies) > 0 {
+			batches = append(batches, strings.Join(bodies, ","))
+		}
+	}
+	if got := strings.Join(batches, " "); got != `"a","b" "c"` {
+		t.Errorf("batches = %s, want a full batch of 2, then the last record exported by Shutdown", got)
+	}
+}
@@ -0,0 +1,281 @@
+package sazabi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// PagerDutyEventsURL is the endpoint of the Events API v2 of PagerDuty.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Defaults of WithPagerDuty.
+const (
+	defaultPagerDutyLevel   = zapcore.FatalLevel
+	defaultPagerDutyTimeout = 2 * time.Second
+)
+
+// pagerDutySummaryLimit is the longest summary accepted by PagerDuty, in bytes.
+const pagerDutySummaryLimit = 1024
+
+// PagerDutyOption configures the events sent by WithPagerDuty.
+type PagerDutyOption func(*pagerDutyConfig)
+
+// pagerDutyConfig is the PagerDuty service added by WithPagerDuty.
+type pagerDutyConfig struct {
+	routingKey string
+	level      zapcore.Level
+	dedupKey   func(ent zapcore.Entry, fields map[string]interface{}) string
+	url        string
+	source     string
+	timeout    time.Duration
+	client     *http.Client
+}
+
+// PagerDutyLevel sends the entries at level and above, Fatal by default.
+func PagerDutyLevel(level zapcore.Level) PagerDutyOption {
+	return func(c *pagerDutyConfig) {
+		c.level = level
+	}
+}
+
+// PagerDutyDedupKey derives the deduplication key of the events from their entry and
+// its fields with fn, instead of from the logger name and message. Events with the same
+// key update the open incident rather than opening a new one.
+func PagerDutyDedupKey(fn func(ent zapcore.Entry, fields map[string]interface{}) string) PagerDutyOption {
+	return func(c *pagerDutyConfig) {
+		c.dedupKey = fn
+	}
+}
+
+// PagerDutyEndpoint sends the events to url instead of PagerDutyEventsURL, such as the
+// endpoint of the EU service region.
+func PagerDutyEndpoint(url string) PagerDutyOption {
+	return func(c *pagerDutyConfig) {
+		c.url = url
+	}
+}
+
+// PagerDutySource sets the source of the events, the hostname by default.
+func PagerDutySource(source string) PagerDutyOption {
+	return func(c *pagerDutyConfig) {
+		c.source = source
+	}
+}
+
+// PagerDutyTimeout sets how long the entry waits for PagerDuty, two seconds by default.
+func PagerDutyTimeout(d time.Duration) PagerDutyOption {
+	return func(c *pagerDutyConfig) {
+		c.timeout = d
+	}
+}
+
+// PagerDutyHTTPClient sends the events with client, such as a client going through a
+// proxy.
+func PagerDutyHTTPClient(client *http.Client) PagerDutyOption {
+	return func(c *pagerDutyConfig) {
+		c.client = client
+	}
+}
+
+// WithPagerDuty triggers an incident on the PagerDuty service of routingKey for each
+// Fatal entry, or each entry at the level set by PagerDutyLevel and above, with the
+// Events API v2. The severity of the event is mapped from the level: critical from
+// DPanic up, then error, warning and info. Its summary is the message, its component
+// the logger name, and its custom details the fields of the entry.
+//
+// The deduplication key is derived from the logger name and message, so that a crash
+// loop updates one incident; PagerDutyDedupKey changes it. The event is sent before
+// the entry panics or exits the process, waiting for PagerDutyTimeout at most, and
+// failures are reported to the internal error output.
+func WithPagerDuty(routingKey string, opts ...PagerDutyOption) Option {
+	cfg := pagerDutyConfig{routingKey: routingKey, level: defaultPagerDutyLevel, url: PagerDutyEventsURL, timeout: defaultPagerDutyTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(o *options) {
+		o.pagerDuty = append(o.pagerDuty, cfg)
+		o.integrations = append(o.integrations, integration{
+			name: "pagerduty",
+			settings: map[string]string{
+				"routing_key": cfg.routingKey,
+				"endpoint":    cfg.url,
+				"level":       cfg.level.String(),
+			},
+		})
+	}
+}
+
+// newPagerDutyCores returns the cores sending the entries enabled by enab to the
+// PagerDuty services of o, reporting failures to errorOutput.
+func newPagerDutyCores(enab zapcore.LevelEnabler, errorOutput zapcore.WriteSyncer, o *options) ([]zapcore.Core, error) {
+	cores := make([]zapcore.Core, 0, len(o.pagerDuty))
+	for _, cfg := range o.pagerDuty {
+		switch {
+		case cfg.routingKey == "":
+			return nil, fmt.Errorf("sazabi: no PagerDuty routing key")
+		case cfg.timeout <= 0:
+			return nil, fmt.Errorf("sazabi: invalid PagerDuty timeout %v", cfg.timeout)
+		}
+		if cfg.source == "" {
+			cfg.source = o.hostname()
+		}
+		if cfg.client == nil {
+			cfg.client = &http.Client{}
+		}
+		cores = append(cores, &pagerDutyCore{
+			LevelEnabler: enab,
+			cfg:          cfg,
+			fields:       make(map[string]interface{}),
+			errorOutput:  errorOutput,
+		})
+	}
+	return cores, nil
+}
+
+// pagerDutyCore triggers PagerDuty incidents for the entries at its level and above.
+type pagerDutyCore struct {
+	zapcore.LevelEnabler
+	cfg         pagerDutyConfig
+	fields      map[string]interface{} // Fields added by With, as encoded by zapcore.MapObjectEncoder
+	errorOutput zapcore.WriteSyncer
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *pagerDutyCore) Enabled(level zapcore.Level) bool {
+	return level >= c.cfg.level && c.LevelEnabler.Enabled(level)
+}
+
+// encoder returns an encoder holding a copy of the fields added by With.
+func (c *pagerDutyCore) encoder() *zapcore.MapObjectEncoder {
+	enc := zapcore.NewMapObjectEncoder()
+	for key, value := range c.fields {
+		enc.Fields[key] = copyFieldValue(value)
+	}
+	return enc
+}
+
+// With implements zapcore.Core.
+func (c *pagerDutyCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.encoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	clone := *c
+	clone.fields = enc.Fields
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *pagerDutyCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. Entries below the level are ignored, since the cores
+// wrapping the tee write every entry to each of its cores. Failures are reported to
+// the internal error output rather than returned, since zap would report them a second
+// time.
+func (c *pagerDutyCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	enc := c.encoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	details, err := pagerDutyDetails(enc.Fields)
+	if err != nil {
+		return err
+	}
+	dedupKey := pagerDutyDedupKey(ent)
+	if c.cfg.dedupKey != nil {
+		dedupKey = c.cfg.dedupKey(ent, enc.Fields)
+	}
+	summary, _ := truncate(ent.Message, pagerDutySummaryLimit-len(TruncatedSuffix))
+	event := pagerDutyEvent{
+		RoutingKey:  c.cfg.routingKey,
+		EventAction: "trigger",
+		DedupKey:    dedupKey,
+		Payload: pagerDutyPayload{
+			Summary:       summary,
+			Source:        c.cfg.source,
+			Severity:      pagerDutySeverity(ent.Level),
+			Timestamp:     ent.Time.UTC().Format(time.RFC3339Nano),
+			Component:     ent.LoggerName,
+			CustomDetails: details,
+		},
+	}
+	if err := postJSON(c.cfg.client, c.cfg.url, c.cfg.timeout, event); err != nil {
+		fmt.Fprintf(c.errorOutput, "%v output pagerduty %s: %s event not sent: %v\n", time.Now(), redactURL(c.cfg.url), ent.Level, err)
+		c.errorOutput.Sync()
+	}
+	return nil
+}
+
+// Sync implements zapcore.Core. Events are sent by Write.
+func (c *pagerDutyCore) Sync() error {
+	return nil
+}
+
+// pagerDutyEvent is an event of the Events API v2.
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key"`
+	Payload     pagerDutyPayload `json:"payload"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string          `json:"summary"`
+	Source        string          `json:"source"`
+	Severity      string          `json:"severity"`
+	Timestamp     string          `json:"timestamp"`
+	Component     string          `json:"component,omitempty"`
+	CustomDetails json.RawMessage `json:"custom_details"`
+}
+
+// pagerDutyDedupKey returns the default deduplication key of ent: a hash of its logger
+// name and message, the same on every host and restart.
+func pagerDutyDedupKey(ent zapcore.Entry) string {
+	sum := sha256.Sum256([]byte(ent.LoggerName + "\x00" + ent.Message))
+	return "sazabi-" + hex.EncodeToString(sum[:16])
+}
+
+// pagerDutySeverity returns the severity of the events of the entries at level.
+func pagerDutySeverity(level zapcore.Level) string {
+	switch {
+	case level >= zapcore.DPanicLevel:
+		return "critical"
+	case level == zapcore.ErrorLevel:
+		return "error"
+	case level == zapcore.WarnLevel:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// pagerDutyDetails returns fields in JSON, with the values that cannot be marshaled,
+// such as NaN, written as strings.
+func pagerDutyDetails(fields map[string]interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(fields)
+	if err == nil {
+		return data, nil
+	}
+	safe := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if _, err := json.Marshal(value); err != nil {
+			value = fmt.Sprint(value)
+		}
+		safe[key] = value
+	}
+	return json.Marshal(safe)
+}
@@ -0,0 +1,179 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap/zapcore"
+)
+
+// pagerDutyEvent is an event received by the stub of the Events API.
+type pagerDutyEvent struct {
+	RoutingKey  string `json:"routing_key"`
+	EventAction string `json:"event_action"`
+	DedupKey    string `json:"dedup_key"`
+	Payload     struct {
+		Summary       string                 `json:"summary"`
+		Source        string                 `json:"source"`
+		Severity      string                 `json:"severity"`
+		Timestamp     string                 `json:"timestamp"`
+		Component     string                 `json:"component"`
+		CustomDetails map[string]interface{} `json:"custom_details"`
+	} `json:"payload"`
+}
+
+// newEventsAPI returns the URL of a stub of the Events API and a function returning the
+// events it received.
+func newEventsAPI(t *testing.T) (string, func() []pagerDutyEvent) {
+	var mu sync.Mutex
+	var events []pagerDutyEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decoding event: %v", err)
+		}
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() []pagerDutyEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]pagerDutyEvent(nil), events...)
+	}
+}
+
+// fatal writes a Fatal entry with log, whose fatal hook must end the goroutine.
+func fatal(log sazabi.Logger, msg string, keysValues ...interface{}) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		log.Fatalw(msg, keysValues...)
+	}()
+	<-done
+}
+
+func TestWithPagerDuty(t *testing.T) {
+	url, events := newEventsAPI(t)
+	log, _ := newFileLogger(t, sazabi.ProductionEnvName,
+		sazabi.WithHostnameProvider(func() string { return "api-7" }),
+		sazabi.WithFatalHook(zapcore.WriteThenGoexit),
+		sazabi.WithPagerDuty("R0UT1NG", sazabi.PagerDutyEndpoint(url)))
+
+	log.Errorw("not paged")
+	panics(func() { log.Panicw("not paged either") })
+	fatal(log.Named("payments"), "ledger corrupted", "account", 42, "elapsed", time.Second)
+	fatal(log.Named("payments"), "ledger corrupted", "account", 43)
+	fatal(log.Named("payments"), "database unreachable")
+
+	got := events()
+	if len(got) != 3 {
+		t.Fatalf("received %d events, want one per Fatal entry", len(got))
+	}
+	event := got[0]
+	if event.RoutingKey != "R0UT1NG" || event.EventAction != "trigger" {
+		t.Errorf("event = %+v, want a trigger for the routing key", event)
+	}
+	p := event.Payload
+	if p.Summary != "ledger corrupted" || p.Source != "api-7" || p.Severity != "critical" || p.Component != "payments" {
+		t.Errorf("payload = %+v, want a critical event with the message, hostname and logger name", p)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, p.Timestamp); err != nil {
+		t.Errorf("payload timestamp: %v", err)
+	}
+	if p.CustomDetails["account"] != 42.0 || p.CustomDetails["elapsed"] != 1e9 {
+		t.Errorf("custom details = %v, want the fields of the entry", p.CustomDetails)
+	}
+
+	if got[0].DedupKey == "" || got[1].DedupKey != got[0].DedupKey {
+		t.Errorf("dedup keys = %q and %q, want the same key for the same message", got[0].DedupKey, got[1].DedupKey)
+	}
+	if got[2].DedupKey == got[0].DedupKey {
+		t.Errorf("dedup key = %q for another message, want a different key", got[2].DedupKey)
+	}
+
+	// The key does not depend on the logger, so it is the same after a restart
+	other, _ := newFileLogger(t, sazabi.ProductionEnvName,
+		sazabi.WithFatalHook(zapcore.WriteThenGoexit),
+		sazabi.WithPagerDuty("R0UT1NG", sazabi.PagerDutyEndpoint(url)))
+	fatal(other.Named("payments"), "ledger corrupted")
+	if got := events(); got[3].DedupKey != got[0].DedupKey {
+		t.Errorf("dedup key = %q in another logger, want %q", got[3].DedupKey, got[0].DedupKey)
+	}
+}
+
+func TestPagerDutyOptions(t *testing.T) {
+	url, events := newEventsAPI(t)
+	log, _ := newFileLogger(t, sazabi.ProductionEnvName,
+		sazabi.WithPagerDuty("R0UT1NG",
+			sazabi.PagerDutyEndpoint(url),
+			sazabi.PagerDutyLevel(zapcore.ErrorLevel),
+			sazabi.PagerDutySource("billing"),
+			sazabi.PagerDutyDedupKey(func(ent zapcore.Entry, fields map[string]interface{}) string {
+				return "billing/" + fields["account"].(string)
+			})))
+
+	log.Warnw("not paged", "account", "a-1")
+	log.With("account", "a-1").Errorw("charge failed")
+	log.Errorw("refund failed", "account", "a-2")
+
+	got := events()
+	if len(got) != 2 {
+		t.Fatalf("received %d events, want one per Error entry", len(got))
+	}
+	if got[0].DedupKey != "billing/a-1" || got[1].DedupKey != "billing/a-2" {
+		t.Errorf("dedup keys = %q, %q; want those of PagerDutyDedupKey", got[0].DedupKey, got[1].DedupKey)
+	}
+	if p := got[0].Payload; p.Severity != "error" || p.Source != "billing" || p.CustomDetails["account"] != "a-1" {
+		t.Errorf("payload = %+v, want an error event from billing with the fields added by With", p)
+	}
+}
+
+func TestPagerDutyTimeout(t *testing.T) {
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer srv.Close()
+	defer close(hang)
+
+	errPath := filepath.Join(t.TempDir(), "errors.log")
+	log, _ := newFileLogger(t, sazabi.ProductionEnvName,
+		sazabi.WithInternalErrorOutput(errPath),
+		sazabi.WithFatalHook(zapcore.WriteThenGoexit),
+		sazabi.WithPagerDuty("R0UT1NG", sazabi.PagerDutyEndpoint(srv.URL), sazabi.PagerDutyTimeout(50*time.Millisecond)))
+
+	start := time.Now()
+	fatal(log, "stuck")
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Fatal took %v with a hanging endpoint, want the event to time out", elapsed)
+	}
+	errs, _ := os.ReadFile(errPath)
+	if !strings.Contains(string(errs), "output pagerduty "+srv.URL+": fatal event not sent: context deadline exceeded") {
+		t.Errorf("internal errors = %q, want the timeout reported", errs)
+	}
+}
+
+func TestWithPagerDutyInvalid(t *testing.T) {
+	for _, opt := range []sazabi.Option{
+		sazabi.WithPagerDuty(""),
+		sazabi.WithPagerDuty("R0UT1NG", sazabi.PagerDutyTimeout(0)),
+	} {
+		if _, err := sazabi.New(sazabi.ProductionEnvName, opt); err == nil {
+			t.Error("New() succeeded with an invalid PagerDuty config, want an error")
+		}
+	}
+}
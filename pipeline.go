@@ -0,0 +1,151 @@
+package sazabi
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Stage is a stage of the pipeline entries of the global logger go through. Entries
+// that pass the level check go through StageFiltering, then StageRedaction, then reach
+// the outputs; cores added with AddCore are attached before or after a stage.
+type Stage int
+
+// Stages of the pipeline, in order.
+const (
+	StageFiltering Stage = iota + 1 // Module levels and sampling
+	StageRedaction                  // Redacted keys and dynamic fields
+)
+
+// String returns the name of the stage, as listed by EffectiveConfig.
+func (s Stage) String() string {
+	switch s {
+	case StageFiltering:
+		return "filtering"
+	case StageRedaction:
+		return "redaction"
+	}
+	return fmt.Sprintf("Stage(%d)", int(s))
+}
+
+// Positions of the cores added with AddCore, between the stages.
+const (
+	beforeFiltering = iota
+	afterFiltering
+	afterRedaction
+)
+
+// DefaultCoreCloseTimeout is how long Shutdown waits for an added core to close.
+const DefaultCoreCloseTimeout = 5 * time.Second
+
+// CoreOption configures a core added with AddCore.
+type CoreOption func(*extension)
+
+// CoreBefore attaches the core before stage, so that it sees the entries the stage
+// would drop or modify. CoreBefore(StageRedaction) is CoreAfter(StageFiltering).
+func CoreBefore(stage Stage) CoreOption {
+	return func(ext *extension) {
+		if stage == StageFiltering || stage == StageRedaction {
+			ext.position = int(stage) - 1
+		}
+	}
+}
+
+// CoreAfter attaches the core after stage, so that it only sees the entries the stage
+// let through, as the stage left them. CoreAfter(StageRedaction) is the default.
+func CoreAfter(stage Stage) CoreOption {
+	return func(ext *extension) {
+		if stage == StageFiltering || stage == StageRedaction {
+			ext.position = int(stage)
+		}
+	}
+}
+
+// CoreName names the core in the pipeline listed by EffectiveConfig. The default is the
+// type of the core.
+func CoreName(name string) CoreOption {
+	return func(ext *extension) {
+		ext.name = name
+	}
+}
+
+// CoreCloseTimeout sets how long Shutdown waits for the core to close, instead of
+// DefaultCoreCloseTimeout.
+func CoreCloseTimeout(d time.Duration) CoreOption {
+	return func(ext *extension) {
+		ext.closeTimeout = d
+	}
+}
+
+// pipeline returns the stages and the registered cores in the order entries reach them.
+// initMu must be held.
+func pipeline() []string {
+	var cores [afterRedaction + 1][]string
+	for _, ext := range extensions {
+		if ext.core != nil {
+			cores[ext.position] = append(cores[ext.position], "core:"+ext.name)
+		}
+	}
+	steps := append([]string(nil), cores[beforeFiltering]...)
+	steps = append(steps, StageFiltering.String())
+	steps = append(steps, cores[afterFiltering]...)
+	steps = append(steps, StageRedaction.String())
+	steps = append(steps, cores[afterRedaction]...)
+	return append(steps, "outputs")
+}
+
+// teeFiltered adds cores to core below its sampling, if any, so that they only receive
+// the entries it lets through.
+func teeFiltered(core zapcore.Core, cores []zapcore.Core) zapcore.Core {
+	sampled, ok := core.(*samplingCore)
+	if !ok || sampled.resample == nil {
+		return zapcore.NewTee(append([]zapcore.Core{core}, cores...)...)
+	}
+	tee := zapcore.NewTee(append([]zapcore.Core{sampled.unsampled}, cores...)...)
+	return &samplingCore{Core: sampled.resample(tee), unsampled: tee, resample: sampled.resample}
+}
+
+// closeCores closes the closers of exts in reverse order, waiting at most the close
+// timeout of each, and returns the first error.
+func closeCores(exts []*extension) error {
+	var first error
+	for i := len(exts) - 1; i >= 0; i-- {
+		if err := closeCore(exts[i]); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// closeCore closes the closer of ext, giving up after its close timeout.
+func closeCore(ext *extension) error {
+	timeout := ext.closeTimeout
+	if timeout <= 0 {
+		timeout = DefaultCoreCloseTimeout
+	}
+	done := make(chan error, 1)
+	go func(c io.Closer) { done <- c.Close() }(ext.closer)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("sazabi: closing core %s: %w", ext.name, err)
+		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("sazabi: core %s did not close within %s", ext.name, timeout)
+	}
+}
+
+// wrapExtraCore returns core as attached at position: after redaction, redacted keys
+// and dynamic fields apply to it.
+func wrapExtraCore(core zapcore.Core, position int) zapcore.Core {
+	if position == afterRedaction {
+		return newDynamicCore(newRedactCore(core))
+	}
+	return core
+}
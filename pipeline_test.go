@@ -0,0 +1,169 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestAddCoreStages(t *testing.T) {
+	raw, rawLogs := observer.New(zapcore.DebugLevel)
+	filtered, filteredLogs := observer.New(zapcore.DebugLevel)
+	redacted, redactedLogs := observer.New(zapcore.DebugLevel)
+	module, err := sazabi.SetModuleLevel("db", "warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ext := range []*sazabi.Extension{
+		sazabi.AddCore(raw, sazabi.CoreBefore(sazabi.StageFiltering), sazabi.CoreName("raw")),
+		sazabi.AddCore(filtered, sazabi.CoreAfter(sazabi.StageFiltering), sazabi.CoreName("filtered")),
+		sazabi.AddCore(redacted, sazabi.CoreName("redacted")),
+		sazabi.AddRedactedKeys("password"),
+		module,
+	} {
+		defer ext.Remove()
+	}
+
+	captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		sazabi.Infow("login", "password", "hunter2")
+		sazabi.Named("db").Info("query")
+		for i := 0; i < 1000; i++ {
+			sazabi.Info("repeated")
+		}
+	})
+
+	password := func(logs *observer.ObservedLogs) interface{} {
+		entries := logs.FilterMessage("login").AllUntimed()
+		if len(entries) != 1 {
+			t.Fatalf("core received %d login entries, want 1", len(entries))
+		}
+		return entries[0].ContextMap()["password"]
+	}
+	if got := password(rawLogs); got != "hunter2" {
+		t.Errorf("core before filtering saw password %v, want the original value", got)
+	}
+	if got := password(filteredLogs); got != "hunter2" {
+		t.Errorf("core after filtering saw password %v, want the original value", got)
+	}
+	if got := password(redactedLogs); got != sazabi.RedactedValue {
+		t.Errorf("core after redaction saw password %v, want %q", got, sazabi.RedactedValue)
+	}
+
+	if rawLogs.FilterMessage("query").Len() != 1 {
+		t.Error("core before filtering missed the entry below its module level")
+	}
+	if filteredLogs.FilterMessage("query").Len() != 0 || redactedLogs.FilterMessage("query").Len() != 0 {
+		t.Error("cores after filtering received the entry below its module level")
+	}
+
+	if n := rawLogs.FilterMessage("repeated").Len(); n != 1000 {
+		t.Errorf("core before filtering received %d repeated entries, want all 1000", n)
+	}
+	sampled := filteredLogs.FilterMessage("repeated").Len()
+	if sampled >= 1000 || redactedLogs.FilterMessage("repeated").Len() != sampled {
+		t.Errorf("cores after filtering received %d and %d repeated entries, want the same sampled number",
+			sampled, redactedLogs.FilterMessage("repeated").Len())
+	}
+
+	want := []string{"core:raw", "filtering", "core:filtered", "redaction", "core:redacted", "outputs"}
+	if got := sazabi.EffectiveConfig().Pipeline; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pipeline = %v, want %v", got, want)
+	}
+}
+
+// closingCore records when it is closed. Close blocks until release is closed, if set.
+type closingCore struct {
+	zapcore.Core
+	name    string
+	release chan struct{}
+
+	mu     *sync.Mutex
+	closed *[]string
+	at     time.Time
+}
+
+func (c *closingCore) Close() error {
+	if c.release != nil {
+		<-c.release
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.at = time.Now()
+	*c.closed = append(*c.closed, c.name)
+	return nil
+}
+
+func TestShutdownClosesCores(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		closed []string
+	)
+	cores := make([]*closingCore, 3)
+	for i, name := range []string{"first", "second", "third"} {
+		cores[i] = &closingCore{Core: zapcore.NewNopCore(), name: name, mu: &mu, closed: &closed}
+		defer sazabi.AddCore(cores[i], sazabi.CoreName(name)).Remove()
+	}
+
+	restoreDefault(t)
+	captureStderr(t, func() {
+		initializeFile(t, sazabi.ProductionEnvName) // Unlike stderr, files can be synced
+		if err := sazabi.Shutdown(); err != nil {
+			t.Errorf("Shutdown() = %v", err)
+		}
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"third", "second", "first"}; !reflect.DeepEqual(closed, want) {
+		t.Fatalf("cores closed in order %v, want %v", closed, want)
+	}
+	if cores[2].at.After(cores[1].at) || cores[1].at.After(cores[0].at) {
+		t.Errorf("close times %v, %v, %v are not in reverse order", cores[0].at, cores[1].at, cores[2].at)
+	}
+	for _, step := range sazabi.EffectiveConfig().Pipeline {
+		if strings.HasPrefix(step, "core:") {
+			t.Errorf("closed core %s is still in the pipeline", step)
+		}
+	}
+}
+
+func TestShutdownCoreCloseTimeout(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		closed []string
+	)
+	release := make(chan struct{})
+	defer close(release)
+	stuck := &closingCore{Core: zapcore.NewNopCore(), name: "stuck", release: release, mu: &mu, closed: &closed}
+	quick := &closingCore{Core: zapcore.NewNopCore(), name: "quick", mu: &mu, closed: &closed}
+	defer sazabi.AddCore(quick, sazabi.CoreName("quick")).Remove()
+	defer sazabi.AddCore(stuck, sazabi.CoreName("stuck"), sazabi.CoreCloseTimeout(20*time.Millisecond)).Remove()
+
+	var err error
+	restoreDefault(t)
+	captureStderr(t, func() {
+		initializeFile(t, sazabi.ProductionEnvName)
+		err = sazabi.Shutdown()
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "stuck") {
+		t.Errorf("Shutdown() = %v, want the close timeout of the stuck core", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(closed, []string{"quick"}) {
+		t.Errorf("closed cores = %v, want the quick core closed after the timeout", closed)
+	}
+}
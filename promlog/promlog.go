@@ -0,0 +1,80 @@
+// Package promlog exports the log volume of sazabi as Prometheus counters, so that
+// alerts can watch the rate of error entries without parsing logs:
+//
+//	sazabi.Initialize("production", promlog.WithMetrics(prometheus.DefaultRegisterer))
+//
+//	# Error entries per second over 5 minutes
+//	rate(sazabi_log_entries_total{level="error"}[5m])
+package promlog
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap/zapcore"
+)
+
+// Names of the counters registered by WithMetrics.
+const (
+	EntriesName     = "sazabi_log_entries_total"
+	WriteErrorsName = "sazabi_log_write_errors_total"
+)
+
+// WithMetrics registers on reg, and increments, the counters of the entries of the
+// logger:
+//   - sazabi_log_entries_total: entries written to the outputs, by "level"
+//   - sazabi_log_write_errors_total: entries that could not be encoded or written
+//
+// Entries dropped by sampling are not counted. Registering the counters again, when
+// re-initializing with WithMetrics, reuses the registered ones, so that counts carry
+// on. WithMetrics panics when reg rejects the counters for another reason, such as a
+// collector of another kind under the same name, as prometheus.MustRegister does.
+func WithMetrics(reg prometheus.Registerer) sazabi.Option {
+	entries := register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: EntriesName,
+		Help: "Log entries written to the outputs, by level.",
+	}, []string{"level"})).(*prometheus.CounterVec)
+	writeErrors := register(reg, prometheus.NewCounter(prometheus.CounterOpts{
+		Name: WriteErrorsName,
+		Help: "Log entries that could not be encoded or written to the outputs.",
+	})).(prometheus.Counter)
+
+	c := &collector{writeErrors: writeErrors}
+	for level := zapcore.DebugLevel; level <= zapcore.FatalLevel; level++ {
+		c.entries[level-zapcore.DebugLevel] = entries.WithLabelValues(level.String()) // Exported at 0 until the first entry
+	}
+	return sazabi.WithMetricsCollector(c)
+}
+
+// register registers c on reg and returns it, or the collector already registered in
+// its place.
+func register(reg prometheus.Registerer, c prometheus.Collector) prometheus.Collector {
+	err := reg.Register(c)
+	if err == nil {
+		return c
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		return are.ExistingCollector
+	}
+	panic(err)
+}
+
+// collector increments the counters of WithMetrics.
+type collector struct {
+	entries     [zapcore.FatalLevel - zapcore.DebugLevel + 1]prometheus.Counter // By level, from Debug
+	writeErrors prometheus.Counter
+}
+
+// EntryWritten implements sazabi.MetricsCollector.
+func (c *collector) EntryWritten(level zapcore.Level) {
+	if i := int(level - zapcore.DebugLevel); i >= 0 && i < len(c.entries) {
+		c.entries[i].Inc()
+	}
+}
+
+// WriteFailed implements sazabi.MetricsCollector.
+func (c *collector) WriteFailed() {
+	c.writeErrors.Inc()
+}
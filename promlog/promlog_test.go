@@ -0,0 +1,130 @@
+//go:build test
+// +build test
+
+package promlog_test
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/promlog"
+	"go.uber.org/zap"
+)
+
+// failingSink is an output whose writes fail.
+type failingSink struct{}
+
+func (failingSink) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+func (failingSink) Sync() error               { return nil }
+func (failingSink) Close() error              { return nil }
+
+func init() {
+	zap.RegisterSink("failing", func(*url.URL) (zap.Sink, error) { return failingSink{}, nil })
+}
+
+// counter returns the value of the counter name of reg, for level when not empty.
+func counter(t *testing.T, reg *prometheus.Registry, name, level string) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			if level == "" {
+				return m.GetCounter().GetValue()
+			}
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "level" && l.GetValue() == level {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	t.Fatalf("no %s counter for level %q", name, level)
+	return 0
+}
+
+// entries returns the value of sazabi_log_entries_total for level in reg.
+func entries(t *testing.T, reg *prometheus.Registry, level string) float64 {
+	t.Helper()
+
+	return counter(t, reg, promlog.EntriesName, level)
+}
+
+func TestWithMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths(filepath.Join(t.TempDir(), "app.log")), promlog.WithMetrics(reg))
+	sazabi.Sync()
+	base := entries(t, reg, "warn") // The warning about the re-initialization, if any
+
+	sazabi.Info("charged")
+	sazabi.Info("refunded")
+	sazabi.Error("declined")
+	sazabi.Debug("below the level")
+
+	for level, want := range map[string]float64{"debug": 0, "info": 2, "warn": base, "error": 1, "dpanic": 0, "panic": 0, "fatal": 0} {
+		if got := entries(t, reg, level); got != want {
+			t.Errorf("%s entries = %v, want %v", level, got, want)
+		}
+	}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP sazabi_log_write_errors_total Log entries that could not be encoded or written to the outputs.
+# TYPE sazabi_log_write_errors_total counter
+sazabi_log_write_errors_total 0
+`), promlog.WriteErrorsName); err != nil {
+		t.Error(err)
+	}
+
+	// Re-initializing registers nothing more and keeps counting
+	sazabi.Initialize(sazabi.ProductionEnvName,
+		sazabi.WithOutputPaths("failing://"),
+		sazabi.WithInternalErrorOutput(filepath.Join(t.TempDir(), "errors.log")),
+		promlog.WithMetrics(reg))
+	sazabi.Info("lost")
+	if got := entries(t, reg, "info"); got != 2 {
+		t.Errorf("info entries = %v after a failed write, want 2", got)
+	}
+	if n := testutil.CollectAndCount(reg, promlog.EntriesName); n != 7 {
+		t.Errorf("%d entry counters registered, want one per level", n)
+	}
+	if errs := counter(t, reg, promlog.WriteErrorsName, ""); errs < 1 {
+		t.Errorf("write errors = %v, want the failed write counted", errs)
+	}
+	sazabi.Initialize(sazabi.ProductionEnvName)
+}
+
+func TestWithMetricsSampling(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	path := filepath.Join(t.TempDir(), "app.log")
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths(path), promlog.WithMetrics(reg))
+	defer sazabi.Initialize(sazabi.ProductionEnvName)
+
+	for i := 0; i < 300; i++ {
+		sazabi.Info("sampled entry") // Production samples after 100 entries per second
+	}
+	sazabi.Sync()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := strings.Count(string(data), "sampled entry")
+	if written >= 300 {
+		t.Fatalf("%d entries written, want some dropped by sampling", written)
+	}
+	if got := entries(t, reg, "info"); got != float64(written) {
+		t.Errorf("info entries = %v, want the %d entries written", got, written)
+	}
+}
@@ -0,0 +1,238 @@
+// Package protolog renders protobuf messages as compact log fields for sazabi.
+//
+// Messages are rendered with protojson, without insignificant whitespace, and embedded
+// in the entry as JSON. Renderings larger than a size cap are truncated, fields can be
+// restricted to a set of paths, and sensitive fields are redacted:
+//
+//	sazabi.Infow("request received", protolog.Msg("request", req))
+package protolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DefaultMaxBytes is the size cap of renderings when WithMaxBytes is not given.
+const DefaultMaxBytes = 4096
+
+// TruncatedSuffix ends renderings cut at the size cap. Truncated renderings are no
+// longer valid JSON, so they are logged as strings.
+const TruncatedSuffix = "...(truncated)"
+
+// Option configures the rendering of a single message.
+type Option func(*config)
+
+// config holds the settings collected from the Option values passed to Msg.
+type config struct {
+	maxBytes int      // Size cap of the rendering, unlimited when zero or less
+	paths    []string // Field paths to keep, all fields when empty
+}
+
+// WithMaxBytes sets the size cap of the rendering. Zero or less disables the cap.
+func WithMaxBytes(n int) Option {
+	return func(c *config) {
+		c.maxBytes = n
+	}
+}
+
+// WithFieldPaths keeps only the fields named by paths, such as "id" or "address.city",
+// like a field mask. Paths use the field names of the .proto file.
+func WithFieldPaths(paths ...string) Option {
+	return func(c *config) {
+		c.paths = append(c.paths, paths...)
+	}
+}
+
+// sensitive holds the registered ways of recognizing sensitive fields.
+var sensitive struct {
+	sync.RWMutex
+	extensions []protoreflect.ExtensionType // Boolean field options marking sensitive fields
+	names      map[string]struct{}          // Field names that are always sensitive
+}
+
+// RegisterSensitiveExtension makes Msg redact the fields annotated with the boolean
+// field option xt, for example `string email = 2 [(sensitive) = true];`.
+func RegisterSensitiveExtension(xt protoreflect.ExtensionType) {
+	sensitive.Lock()
+	defer sensitive.Unlock()
+
+	sensitive.extensions = append(sensitive.extensions, xt)
+}
+
+// RegisterSensitiveNames makes Msg redact the fields with one of names, in any message.
+func RegisterSensitiveNames(names ...string) {
+	sensitive.Lock()
+	defer sensitive.Unlock()
+
+	if sensitive.names == nil {
+		sensitive.names = make(map[string]struct{})
+	}
+	for _, name := range names {
+		sensitive.names[name] = struct{}{}
+	}
+}
+
+// Msg returns a field rendering m as compact JSON under key. Sensitive fields are
+// replaced by sazabi.RedactedValue, or cleared when they are not strings. A nil
+// message renders as null.
+func Msg(key string, m proto.Message, opts ...Option) zap.Field {
+	if m == nil || !m.ProtoReflect().IsValid() {
+		return zap.Reflect(key, nil)
+	}
+
+	c := &config{maxBytes: DefaultMaxBytes}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	m = proto.Clone(m)
+	if len(c.paths) > 0 {
+		mask(m.ProtoReflect(), newPathTree(c.paths))
+	}
+	redact(m.ProtoReflect())
+
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return zap.String(key, fmt.Sprintf("<protojson error: %v>", err))
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err == nil {
+		data = compact.Bytes() // protojson adds random whitespace to discourage byte comparisons
+	}
+
+	if c.maxBytes > 0 && len(data) > c.maxBytes {
+		cut := c.maxBytes
+		for cut > 0 && !utf8.RuneStart(data[cut]) {
+			cut-- // Never split a character
+		}
+		return zap.String(key, string(data[:cut])+TruncatedSuffix)
+	}
+	return zap.Reflect(key, json.RawMessage(data))
+}
+
+// pathTree is a set of field paths indexed by their first element.
+type pathTree map[string]pathTree
+
+// newPathTree returns the tree of the dotted paths.
+func newPathTree(paths []string) pathTree {
+	tree := pathTree{}
+	for _, path := range paths {
+		node := tree
+		for _, name := range strings.Split(path, ".") {
+			next, ok := node[name]
+			if !ok {
+				next = pathTree{}
+				node[name] = next
+			}
+			node = next
+		}
+	}
+	return tree
+}
+
+// mask clears the fields of m not covered by tree. A field whose node has no children
+// is kept entirely.
+func mask(m protoreflect.Message, tree pathTree) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		sub, ok := tree[string(fd.Name())]
+		switch {
+		case !ok:
+			m.Clear(fd)
+		case len(sub) == 0 || fd.Message() == nil || fd.IsMap():
+			// Kept as a whole
+		case fd.IsList():
+			for i, list := 0, v.List(); i < list.Len(); i++ {
+				mask(list.Get(i).Message(), sub)
+			}
+		default:
+			mask(v.Message(), sub)
+		}
+		return true
+	})
+}
+
+// redact replaces the sensitive fields of m and of the messages it contains.
+func redact(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if isSensitive(fd) {
+			redactField(m, fd)
+			return true
+		}
+
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, value protoreflect.Value) bool {
+					redact(value.Message())
+					return true
+				})
+			}
+		case fd.Message() == nil:
+		case fd.IsList():
+			for i, list := 0, v.List(); i < list.Len(); i++ {
+				redact(list.Get(i).Message())
+			}
+		default:
+			redact(v.Message())
+		}
+		return true
+	})
+}
+
+// redactField replaces the value of the sensitive field fd of m.
+func redactField(m protoreflect.Message, fd protoreflect.FieldDescriptor) {
+	var redacted protoreflect.Value
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		redacted = protoreflect.ValueOfString(sazabi.RedactedValue)
+	case protoreflect.BytesKind:
+		redacted = protoreflect.ValueOfBytes([]byte(sazabi.RedactedValue))
+	default:
+		m.Clear(fd) // No way to show a placeholder in other kinds
+		return
+	}
+
+	switch {
+	case fd.IsMap():
+		m.Clear(fd)
+	case fd.IsList():
+		list := m.Mutable(fd).List()
+		for i := 0; i < list.Len(); i++ {
+			list.Set(i, redacted)
+		}
+	default:
+		m.Set(fd, redacted)
+	}
+}
+
+// isSensitive reports whether fd is registered as sensitive, by name or by option.
+func isSensitive(fd protoreflect.FieldDescriptor) bool {
+	sensitive.RLock()
+	defer sensitive.RUnlock()
+
+	if _, ok := sensitive.names[string(fd.Name())]; ok {
+		return true
+	}
+	opts := fd.Options()
+	if opts == nil {
+		return false
+	}
+	for _, xt := range sensitive.extensions {
+		if proto.HasExtension(opts, xt) {
+			if on, ok := proto.GetExtension(opts, xt).(bool); ok && on {
+				return true
+			}
+		}
+	}
+	return false
+}
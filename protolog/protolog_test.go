@@ -0,0 +1,122 @@
+//go:build test
+// +build test
+
+package protolog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/protolog"
+	"github.com/zeroxsolutions/sazabi/protolog/internal/testpb"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	protolog.RegisterSensitiveExtension(testpb.E_Sensitive)
+	protolog.RegisterSensitiveNames("password")
+}
+
+// render encodes field with a JSON encoder and returns its value.
+func render(t *testing.T, field zap.Field) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{}), zapcore.AddSync(&buf), zapcore.DebugLevel)
+	zap.New(core).Info("", field)
+
+	var entry map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid entry %q: %v", buf.String(), err)
+	}
+	return string(entry[field.Key])
+}
+
+func newUser() *testpb.User {
+	return &testpb.User{
+		Name:     "alice",
+		Email:    "alice@example.com",
+		Password: "hunter2",
+		Address:  &testpb.Address{City: "Lyon", Street: "1 rue de la Paix"},
+		Tags:     []string{"admin"},
+		Id:       42,
+	}
+}
+
+func TestMsgRedaction(t *testing.T) {
+	got := render(t, protolog.Msg("user", newUser()))
+	want := `{"name":"alice","email":"[REDACTED]","password":"[REDACTED]",` +
+		`"address":{"city":"Lyon","street":"[REDACTED]"},"tags":["admin"],"id":"42"}`
+	if got != want {
+		t.Errorf("Msg() = %s, want %s", got, want)
+	}
+}
+
+func TestMsgDoesNotModifyMessage(t *testing.T) {
+	user := newUser()
+	protolog.Msg("user", user, protolog.WithFieldPaths("id"))
+	if user.Email != "alice@example.com" || user.Address.Street != "1 rue de la Paix" {
+		t.Errorf("Msg() modified its argument: %v", user)
+	}
+}
+
+func TestMsgFieldPaths(t *testing.T) {
+	got := render(t, protolog.Msg("user", newUser(), protolog.WithFieldPaths("id", "address.city", "email")))
+	want := `{"email":"[REDACTED]","address":{"city":"Lyon"},"id":"42"}`
+	if got != want {
+		t.Errorf("Msg() = %s, want %s", got, want)
+	}
+}
+
+func TestMsgTruncation(t *testing.T) {
+	user := &testpb.User{Name: strings.Repeat("é", 100)}
+	field := protolog.Msg("user", user, protolog.WithMaxBytes(20))
+
+	if field.Type != zapcore.StringType {
+		t.Fatalf("truncated field type = %v, want a string", field.Type)
+	}
+	if !strings.HasSuffix(field.String, protolog.TruncatedSuffix) {
+		t.Errorf("truncated field = %q, want suffix %q", field.String, protolog.TruncatedSuffix)
+	}
+	if n := len(strings.TrimSuffix(field.String, protolog.TruncatedSuffix)); n > 20 || n < 18 {
+		t.Errorf("truncated to %d bytes, want at most 20 on a character boundary", n)
+	}
+	if !json.Valid([]byte(render(t, field))) {
+		t.Error("truncated field does not render as a JSON string")
+	}
+}
+
+func TestMsgNil(t *testing.T) {
+	var user *testpb.User
+	for name, field := range map[string]zap.Field{
+		"nil interface": protolog.Msg("user", nil),
+		"nil pointer":   protolog.Msg("user", user),
+	} {
+		if got := render(t, field); got != "null" {
+			t.Errorf("%s: Msg() = %s, want null", name, got)
+		}
+	}
+}
+
+func TestMsgGlobalLogger(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	capture, stop := sazabi.StartCapture()
+	sazabi.Infow("user loaded", protolog.Msg("user", newUser(), protolog.WithFieldPaths("id")))
+	stop()
+
+	entries := capture.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("captured %d entries, want 1", len(entries))
+	}
+	field, ok := entries[0].Field("user")
+	if !ok {
+		t.Fatal("entry has no user field")
+	}
+	if got := render(t, field); got != `{"id":"42"}` {
+		t.Errorf("user = %s, want {\"id\":\"42\"}", got)
+	}
+}
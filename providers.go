@@ -0,0 +1,104 @@
+package sazabi
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// Keys of the fields added by WithHostFields.
+const (
+	HostnameKey = "hostname"
+	PIDKey      = "pid"
+)
+
+// Values returned by the providers installed by WithGoldenProviders.
+const (
+	GoldenHostname = "golden-host"
+	GoldenPID      = 1
+)
+
+// WithHostFields adds the hostname and the process ID to every entry, under the
+// "hostname" and "pid" keys. The values are read once, when the logger is built.
+func WithHostFields() Option {
+	return func(o *options) {
+		o.hostFields = true
+	}
+}
+
+// WithHostnameProvider replaces os.Hostname as the source of the hostname reported by
+// WithHostFields and crash dumps, for environments where the system hostname is
+// meaningless, such as containers named after a random hash.
+func WithHostnameProvider(fn func() string) Option {
+	return func(o *options) {
+		o.hostnameProvider = fn
+	}
+}
+
+// WithPIDProvider replaces os.Getpid as the source of the process ID reported by
+// WithHostFields and crash dumps.
+func WithPIDProvider(fn func() int) Option {
+	return func(o *options) {
+		o.pidProvider = fn
+	}
+}
+
+// WithIDGenerator replaces the random generator of the IDs returned by
+// NewCorrelationID and of the request IDs generated by HTTPMiddleware.
+func WithIDGenerator(fn func() string) Option {
+	return func(o *options) {
+		o.idGenerator = fn
+	}
+}
+
+// WithGoldenProviders installs fixed providers so that output can be compared with
+// golden files: the hostname is GoldenHostname, the process ID is GoldenPID and IDs
+// are a sequence starting at "id-000001". Providers passed after it take precedence.
+func WithGoldenProviders() Option {
+	return func(o *options) {
+		var seq int64
+		o.hostnameProvider = func() string { return GoldenHostname }
+		o.pidProvider = func() int { return GoldenPID }
+		o.idGenerator = func() string { return fmt.Sprintf("id-%06d", atomic.AddInt64(&seq, 1)) }
+	}
+}
+
+// NewCorrelationID returns a new ID for correlating entries, from the generator set by
+// WithIDGenerator or a random 16-byte hexadecimal ID by default.
+func NewCorrelationID() string {
+	return currentOptions().newID()
+}
+
+// currentOptions returns the options of the global logger, or the defaults before the
+// first initialization.
+func currentOptions() *options {
+	if in := loadInstance(); in != nil && in.options != nil {
+		return in.options
+	}
+	return &options{}
+}
+
+// hostname returns the hostname from the configured provider or os.Hostname.
+func (o *options) hostname() string {
+	if o.hostnameProvider != nil {
+		return o.hostnameProvider()
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// pid returns the process ID from the configured provider or os.Getpid.
+func (o *options) pid() int {
+	if o.pidProvider != nil {
+		return o.pidProvider()
+	}
+	return os.Getpid()
+}
+
+// newID returns an ID from the configured generator or a random one.
+func (o *options) newID() string {
+	if o.idGenerator != nil {
+		return o.idGenerator()
+	}
+	return randomID()
+}
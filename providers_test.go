@@ -0,0 +1,93 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// fakeProviders returns options installing fake hostname, PID and ID providers.
+func fakeProviders() []sazabi.Option {
+	return []sazabi.Option{
+		sazabi.WithHostnameProvider(func() string { return "fake-host" }),
+		sazabi.WithPIDProvider(func() int { return 4242 }),
+		sazabi.WithIDGenerator(func() string { return "fake-id" }),
+	}
+}
+
+func TestHostFieldsUseProviders(t *testing.T) {
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, append(fakeProviders(), sazabi.WithHostFields())...)
+		ext := sazabi.AddGlobalFields("service", "checkout")
+		defer ext.Remove()
+		sazabi.Infow("enriched entry")
+	})
+
+	fields := entryFields(t, output, "enriched entry")
+	if fields["service"] != "checkout" {
+		t.Errorf("entry fields = %v, want the global fields kept", fields)
+	}
+	if fields[sazabi.HostnameKey] != "fake-host" || fields[sazabi.PIDKey] != float64(4242) {
+		t.Errorf("entry fields = %v, want the injected hostname and pid", fields)
+	}
+}
+
+func TestHTTPMiddlewareUsesIDGenerator(t *testing.T) {
+	handler := sazabi.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sazabi.FromContext(r.Context()).Info("handler entry")
+	}))
+
+	recorder := httptest.NewRecorder()
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, append(fakeProviders(), sazabi.WithHostFields())...)
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+
+	if got := recorder.Header().Get(sazabi.RequestIDHeader); got != "fake-id" {
+		t.Errorf("response request ID = %q, want the generated %q", got, "fake-id")
+	}
+	for _, msg := range []string{"handler entry", sazabi.HTTPRequestMessage} {
+		fields := entryFields(t, output, msg)
+		if fields["request_id"] != "fake-id" || fields[sazabi.HostnameKey] != "fake-host" {
+			t.Errorf("%q fields = %v, want the injected request ID and hostname", msg, fields)
+		}
+	}
+}
+
+func TestNewCorrelationID(t *testing.T) {
+	captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+	})
+	if id := sazabi.NewCorrelationID(); len(id) != 32 {
+		t.Errorf("default NewCorrelationID() = %q, want 32 hexadecimal characters", id)
+	}
+
+	captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithIDGenerator(func() string { return "fake-id" }))
+	})
+	if id := sazabi.NewCorrelationID(); id != "fake-id" {
+		t.Errorf("NewCorrelationID() = %q, want the generated %q", id, "fake-id")
+	}
+}
+
+func TestGoldenProviders(t *testing.T) {
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithGoldenProviders(), sazabi.WithHostFields())
+		sazabi.Info("golden entry")
+	})
+
+	fields := entryFields(t, output, "golden entry")
+	if fields[sazabi.HostnameKey] != sazabi.GoldenHostname || fields[sazabi.PIDKey] != float64(sazabi.GoldenPID) {
+		t.Errorf("entry fields = %v, want the golden hostname and pid", fields)
+	}
+	for _, want := range []string{"id-000001", "id-000002"} {
+		if id := sazabi.NewCorrelationID(); id != want {
+			t.Errorf("NewCorrelationID() = %q, want %q", id, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package sazabi
+
+import (
+	"bytes"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// WriteRaw writes line as is to the outputs of the global logger, for lines whose exact
+// bytes are dictated by an external contract, such as a banner expected by a legacy
+// parser or a CSV audit record. The line ending of the encoder is appended when line
+// does not end with it. level, such as "info", is checked against the level of the
+// logger: lines below it are discarded.
+//
+// The line skips encoding, fields, sampling, redaction and hooks, and is not counted by
+// VolumeStats. It is written with a single write under the lock shared with the entries,
+// so it never interleaves with them. Lines are discarded while logging is disabled,
+// during a capture and after Shutdown. An unknown level returns an error, and so does a
+// call before Initialize with ErrNotInitialized.
+func WriteRaw(level string, line []byte) error {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	in := loadInstance()
+	if in == nil {
+		return ErrNotInitialized
+	}
+	if in.raw == nil || !in.config.Level.Enabled(lvl) {
+		return nil
+	}
+
+	ending := in.config.EncoderConfig.LineEnding
+	if ending == "" {
+		ending = zapcore.DefaultLineEnding
+	}
+	if !bytes.HasSuffix(line, []byte(ending)) {
+		line = append(line[:len(line):len(line)], ending...)
+	}
+	_, err = in.raw.Write(line)
+	return err
+}
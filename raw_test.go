@@ -0,0 +1,85 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestWriteRaw(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName)
+
+	if err := sazabi.WriteRaw("info", []byte("BANNER v1")); err != nil {
+		t.Fatalf("WriteRaw() error = %v", err)
+	}
+	if err := sazabi.WriteRaw("warn", []byte("id,amount\n")); err != nil {
+		t.Fatalf("WriteRaw() error = %v", err)
+	}
+	if err := sazabi.WriteRaw("debug", []byte("below level")); err != nil {
+		t.Fatalf("WriteRaw() error = %v", err)
+	}
+	if err := sazabi.WriteRaw("loud", []byte("unknown level")); err == nil {
+		t.Error("WriteRaw() with an unknown level succeeded, want an error")
+	}
+
+	if output := read(); output != "BANNER v1\nid,amount\n" {
+		t.Errorf("output = %q, want the raw lines only", output)
+	}
+}
+
+func TestWriteRawConcurrent(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName)
+
+	raw := "RAW," + strings.Repeat("x", 4096)
+	const writers, lines = 4, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				if err := sazabi.WriteRaw("info", []byte(raw)); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				sazabi.Infow("encoded entry", "payload", strings.Repeat("y", 4096))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var rawLines, entries int
+	for _, line := range strings.Split(strings.TrimSuffix(read(), "\n"), "\n") {
+		switch {
+		case line == raw:
+			rawLines++
+		case strings.Contains(line, "\tencoded entry\t") && strings.HasSuffix(line, `"}`) && !strings.Contains(line, "RAW"):
+			entries++
+		default:
+			t.Fatalf("torn line: %.120q", line)
+		}
+	}
+	if rawLines != writers*lines || entries == 0 {
+		t.Errorf("got %d raw lines and %d entries, want %d raw lines among the entries", rawLines, entries, writers*lines)
+	}
+}
+
+func TestWriteRawDuringCapture(t *testing.T) {
+	c, stop := sazabi.StartCapture()
+	defer stop()
+
+	if err := sazabi.WriteRaw("info", []byte("captured")); err != nil || len(c.Entries()) != 0 {
+		t.Errorf("WriteRaw() during capture = %v with %d entries, want the line discarded", err, len(c.Entries()))
+	}
+}
@@ -0,0 +1,80 @@
+package sazabi
+
+import (
+	"bytes"
+	"encoding/json"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RawInvalidKey is the key of the field marking RawJSON values that were not valid JSON.
+const RawInvalidKey = "raw_invalid"
+
+// TruncatedSuffix ends the values cut at the WithMaxFieldBytes cap.
+const TruncatedSuffix = "...(truncated)"
+
+// WithMaxFieldBytes caps the size of string and RawJSON field values at n bytes.
+// Longer values are cut at a character boundary and end with TruncatedSuffix; cut
+// RawJSON values are no longer valid JSON, so they are written as strings.
+// Zero or less, the default, disables the cap.
+func WithMaxFieldBytes(n int) Option {
+	return func(o *options) {
+		o.maxFieldBytes = n
+	}
+}
+
+// rawJSON is the value of RawJSON fields. It marshals to itself, so that it is embedded
+// as it is by the JSON encoder of loggers not built by this package too.
+type rawJSON []byte
+
+// MarshalJSON implements json.Marshaler.
+func (r rawJSON) MarshalJSON() ([]byte, error) {
+	return r, nil
+}
+
+// RawJSON returns a field embedding data, an already encoded JSON document, without
+// decoding it again. The JSON encoding writes it verbatim; the console encodings write
+// it as a compact string. Invalid JSON is written as a string, with RawInvalidKey=true.
+func RawJSON(key string, data []byte) Field {
+	if !json.Valid(data) {
+		return zap.Inline(invalidRawJSON{key: key, data: string(data)})
+	}
+	return zap.Reflect(key, rawJSON(data))
+}
+
+// invalidRawJSON writes invalid RawJSON data as a string field with RawInvalidKey.
+type invalidRawJSON struct {
+	key  string
+	data string
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (r invalidRawJSON) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString(r.key, r.data)
+	enc.AddBool(RawInvalidKey, true)
+	return nil
+}
+
+// compactJSON returns data without insignificant whitespace. data must be valid JSON.
+func compactJSON(data []byte) string {
+	var b bytes.Buffer
+	if err := json.Compact(&b, data); err != nil {
+		return string(data)
+	}
+	return b.String()
+}
+
+// truncate cuts s to at most max bytes plus TruncatedSuffix, without splitting a
+// character, and reports whether it did. A max of zero or less leaves s unchanged.
+func truncate(s string, max int) (string, bool) {
+	if max <= 0 || len(s) <= max {
+		return s, false
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut-- // Never split a character
+	}
+	return s[:cut] + TruncatedSuffix, true
+}
@@ -0,0 +1,83 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// newJSONLogger returns a development logger using the JSON encoding.
+func newJSONLogger(t *testing.T, opts ...sazabi.Option) (*zap.SugaredLogger, func() string) {
+	return newFileLogger(t, "development", append([]sazabi.Option{sazabi.WithInteractive(false), sazabi.WithCIEncoding("json")}, opts...)...)
+}
+
+func TestRawJSONObject(t *testing.T) {
+	log, read := newJSONLogger(t)
+
+	log.Infow("policy decided", sazabi.RawJSON("decision", []byte(`{"allow":true,"rules":["r1","r2"]}`)))
+
+	if output := read(); !strings.Contains(output, `"decision":{"allow":true,"rules":["r1","r2"]}`) {
+		t.Errorf("output = %q, want the object embedded", output)
+	}
+}
+
+func TestRawJSONArray(t *testing.T) {
+	log, read := newJSONLogger(t)
+
+	log.Desugar().Info("batch received", sazabi.RawJSON("ids", []byte(`[1,2,3]`)))
+
+	if output := read(); !strings.Contains(output, `"ids":[1,2,3]`) {
+		t.Errorf("output = %q, want the array embedded", output)
+	}
+}
+
+func TestRawJSONConsole(t *testing.T) {
+	log, read := newFileLogger(t, sazabi.ProductionEnvName) // Console encoding
+
+	log.Infow("webhook received", sazabi.RawJSON("payload", []byte("{\n  \"event\": \"push\",\n  \"size\": 2\n}")))
+
+	fields := entryFields(t, read(), "webhook received")
+	if fields["payload"] != `{"event":"push","size":2}` {
+		t.Errorf("payload = %#v, want a compact string", fields["payload"])
+	}
+}
+
+func TestRawJSONInvalid(t *testing.T) {
+	log, read := newJSONLogger(t)
+
+	log.Infow("webhook received", sazabi.RawJSON("payload", []byte(`{"event": "push"`)))
+
+	output := read()
+	if !strings.Contains(output, `"payload":"{\"event\": \"push\""`) || !strings.Contains(output, `"`+sazabi.RawInvalidKey+`":true`) {
+		t.Errorf("output = %q, want a quoted string marked invalid", output)
+	}
+}
+
+func TestRawJSONTruncated(t *testing.T) {
+	log, read := newJSONLogger(t, sazabi.WithMaxFieldBytes(10))
+
+	log.Infow("capped",
+		sazabi.RawJSON("small", []byte(`{"a":1}`)),
+		sazabi.RawJSON("large", []byte(`{"event":"push","size":2}`)),
+		sazabi.RawJSON("invalid", []byte(`{"event": "push"`)),
+		"text", "héééééééé",
+	)
+
+	output := read()
+	for _, want := range []string{
+		`"small":{"a":1}`,
+		`"large":"{\"event\":\"` + sazabi.TruncatedSuffix + `"`,
+		`"invalid":"{\"event\": ` + sazabi.TruncatedSuffix + `"`,
+		`"text":"héééé` + sazabi.TruncatedSuffix + `"`, // Characters are not split
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output = %q, want %s", output, want)
+		}
+	}
+}
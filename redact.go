@@ -0,0 +1,66 @@
+package sazabi
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactedValue replaces the value of fields whose key is redacted.
+const RedactedValue = "[REDACTED]"
+
+// redactedKeys holds the map[string]struct{} of lower-cased keys registered with
+// AddRedactedKeys. It is rebuilt from the extension registry on every change.
+var redactedKeys atomic.Value
+
+// redactCore replaces the value of fields with a redacted key by RedactedValue. Keys are
+// read at write time, so registering keys affects loggers that are already built.
+type redactCore struct {
+	zapcore.Core
+}
+
+// newRedactCore wraps core so that redacted keys never reach it.
+func newRedactCore(core zapcore.Core) zapcore.Core {
+	return &redactCore{Core: core}
+}
+
+// With implements zapcore.Core.
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(redact(fields))}
+}
+
+// Check implements zapcore.Core.
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, redact(fields))
+}
+
+// redact returns fields with the value of every redacted key replaced. The input slice
+// is only copied when a field is actually redacted.
+func redact(fields []zapcore.Field) []zapcore.Field {
+	keys, _ := redactedKeys.Load().(map[string]struct{})
+	if len(keys) == 0 {
+		return fields
+	}
+
+	redacted, copied := fields, false
+	for i, f := range fields {
+		if _, ok := keys[strings.ToLower(f.Key)]; !ok {
+			continue
+		}
+		if !copied {
+			redacted, copied = append([]zapcore.Field(nil), fields...), true
+		}
+		redacted[i] = zap.String(f.Key, RedactedValue)
+	}
+	return redacted
+}
@@ -0,0 +1,95 @@
+package sazabi
+
+import (
+	"sync"
+)
+
+// WithRingBuffer keeps the last size encoded entries in memory, so that they can be
+// written to a crash dump (see DumpOnSignal) even if the outputs are unavailable.
+// The buffer survives re-initialization; a size of zero or less disables it.
+// New ignores this option, since only the global logger is dumped.
+func WithRingBuffer(size int) Option {
+	return func(o *options) {
+		o.ringBufferSize = size
+	}
+}
+
+// ringBuffer is a zapcore.WriteSyncer keeping copies of the last entries written to it.
+// Every Write call receives exactly one encoded entry.
+type ringBuffer struct {
+	mu      sync.Mutex
+	entries [][]byte // Circular storage for the entries
+	next    int      // Index the next entry is stored at
+	full    bool     // Whether entries has wrapped around
+}
+
+// ring is the buffer of the global logger, nil when WithRingBuffer is not in effect.
+var ring struct {
+	sync.Mutex
+	buffer *ringBuffer
+}
+
+// configureRing returns the ring buffer to use for size, reusing the current one
+// when its size is unchanged so that its content survives re-initialization.
+func configureRing(size int) *ringBuffer {
+	ring.Lock()
+	defer ring.Unlock()
+
+	switch {
+	case size <= 0:
+		ring.buffer = nil
+	case ring.buffer == nil || len(ring.buffer.entries) != size:
+		ring.buffer = &ringBuffer{entries: make([][]byte, size)}
+	}
+	return ring.buffer
+}
+
+// currentRing returns the ring buffer of the global logger, or nil.
+func currentRing() *ringBuffer {
+	ring.Lock()
+	defer ring.Unlock()
+
+	return ring.buffer
+}
+
+// Write implements zapcore.WriteSyncer.
+func (r *ringBuffer) Write(p []byte) (int, error) {
+	entry := append([]byte(nil), p...)
+
+	r.mu.Lock()
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.mu.Unlock()
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer.
+func (r *ringBuffer) Sync() error {
+	return nil
+}
+
+// occupancy returns the size of the buffer and the number of entries it holds.
+func (r *ringBuffer) occupancy() (size, used int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.full {
+		return len(r.entries), len(r.entries)
+	}
+	return len(r.entries), r.next
+}
+
+// snapshot returns the buffered entries, oldest first. It only holds the lock while
+// copying slice headers, never while doing I/O.
+func (r *ringBuffer) snapshot() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([][]byte(nil), r.entries[:r.next]...)
+	}
+	return append(append([][]byte(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
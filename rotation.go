@@ -0,0 +1,295 @@
+package sazabi
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// RotationTimeLayout is the layout of the start of the period in the names of the files
+// written by WithTimedRotation.
+const RotationTimeLayout = "20060102T150405Z"
+
+// WithTimedRotation adds a file output cut every interval, such as 24*time.Hour for
+// one file per UTC day. Each period is written to its own file, named after path with
+// the UTC start of the period in RotationTimeLayout inserted before the extension:
+// "/var/log/app.log" gives "/var/log/app-20240131T000000Z.log". Periods start at the
+// multiples of interval since UTC midnight for intervals dividing a day, and since the
+// zero time otherwise. The first entry written after a boundary goes to the file of the
+// new period. Options such as WithOptionalSink and WithFallbackOutput apply to the output
+// as to the outputs added by WithOutput.
+func WithTimedRotation(path string, interval time.Duration) Option {
+	return WithRotation(path, RotationConfig{Interval: interval})
+}
+
+// WithRotation adds a file output rotated as described by cfg, which is WithTimedRotation
+// for cfg.Interval with the settings of cfg applied on top. With Compress, the file of a
+// period that ended is compressed with gzip in the background into the same name with a
+// ".gz" suffix, which replaces it once written and synced to disk; the file of the
+// current period is never compressed. Compression failures are reported to the internal
+// error output (see WithInternalErrorOutput) and leave the file uncompressed. Shutdown
+// waits for the compressions in progress, then closes the file of the current period.
+//
+// After each rotation, the files of past periods are removed once their period ended
+// more than MaxAge ago, and beyond the MaxBackups most recent periods. Only the files
+// named as the output names them, compressed or not, are considered, and their age is
+// read from their name rather than their modification time. Removal failures are
+// reported to the internal error output, and Shutdown waits for the removals in
+// progress. MaxSizeMB is not supported: the logger fails to build when it is set.
+func WithRotation(path string, cfg RotationConfig) Option {
+	return func(o *options) {
+		if o.rotations == nil {
+			o.rotations = make(map[string]RotationConfig)
+		}
+		o.rotations[path] = cfg
+		o.extraOutputs = append(o.extraOutputs, path)
+	}
+}
+
+// rotatingFile is a WriteSyncer writing each period to its own file.
+type rotatingFile struct {
+	path        string              // Path the names of the files are derived from
+	cfg         RotationConfig      // Rotation settings
+	now         func() time.Time    // Clock deciding the period of writes
+	errorOutput zapcore.WriteSyncer // Receives the failures of compressions and removals
+
+	mu      sync.Mutex
+	file    *os.File       // File of the current period
+	end     int64          // End of the current period, in Unix nanoseconds
+	closed  bool           // Set by close
+	pending sync.WaitGroup // Compressions and removals in progress
+
+	cleanup sync.Mutex // Serializes compressions and removals
+}
+
+// newRotatingFile opens the file of the current period of the output at path.
+func newRotatingFile(path string, cfg RotationConfig, now func() time.Time, errorOutput zapcore.WriteSyncer) (*rotatingFile, error) {
+	switch {
+	case cfg.Interval <= 0:
+		return nil, errors.New("sazabi: timed rotation interval must be positive")
+	case cfg.MaxSizeMB != 0:
+		return nil, errors.New("sazabi: rotation by size is not supported")
+	case cfg.MaxAge < 0 || cfg.MaxBackups < 0:
+		return nil, errors.New("sazabi: rotation maxAge and maxBackups must not be negative")
+	}
+	f := &rotatingFile{path: path, cfg: cfg, now: now, errorOutput: errorOutput}
+	if err := f.rotate(now()); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write implements zapcore.WriteSyncer, switching to the file of the current period
+// first when the previous one ended.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if t := f.now(); t.UnixNano() >= f.end {
+		if err := f.rotate(t); err != nil {
+			return 0, err
+		}
+	}
+	return f.file.Write(p)
+}
+
+// Sync implements zapcore.WriteSyncer. Once closed, there is nothing to sync.
+func (f *rotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// close waits for the compressions and removals in progress, so that the process does
+// not exit with a file half compressed, then syncs and closes the file of the current
+// period. Entries written afterwards fail with os.ErrClosed.
+func (f *rotatingFile) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return
+	}
+	f.closed = true
+	f.pending.Wait() // Rotations, which start the work, hold f.mu
+	f.file.Sync()
+	if err := f.file.Close(); err != nil {
+		f.reportError("closing file", err)
+	}
+}
+
+// rotate closes the current file and opens the file of the period containing t, then
+// compresses the closed file and removes expired ones in the background. f.mu must be
+// held, except by newRotatingFile.
+func (f *rotatingFile) rotate(t time.Time) error {
+	start := t.UTC().Truncate(f.cfg.Interval)
+	file, err := os.OpenFile(periodPath(f.path, start), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o666)
+	if err != nil {
+		return err
+	}
+	if previous := f.file; previous != nil {
+		previous.Close()
+		f.pending.Add(1)
+		go f.rotated(previous.Name(), file.Name(), t)
+	}
+	f.file = file
+	f.end = start.Add(f.cfg.Interval).UnixNano()
+	return nil
+}
+
+// rotated compresses the file name closed by a rotation at t, then removes the expired
+// files of past periods, current being the file of the current period. Both run under
+// f.pending, so that close waits for them. Failures are reported to the internal error
+// output.
+func (f *rotatingFile) rotated(name, current string, t time.Time) {
+	defer f.pending.Done()
+	f.cleanup.Lock()
+	defer f.cleanup.Unlock()
+
+	if f.cfg.Compress {
+		if err := gzipFile(name); err != nil {
+			f.reportError("compressing rotated file", err)
+		}
+	}
+	if f.cfg.MaxAge > 0 || f.cfg.MaxBackups > 0 {
+		f.removeExpired(current, t)
+	}
+}
+
+// removeExpired removes the files of the periods that ended more than MaxAge before t,
+// and of the periods beyond the MaxBackups most recent ones, current being the file of
+// the current period. f.cleanup must be held.
+func (f *rotatingFile) removeExpired(current string, t time.Time) {
+	backups, err := f.backups(current)
+	if err != nil {
+		f.reportError("listing rotated files", err)
+		return
+	}
+	for i, b := range backups {
+		kept := len(backups) - i
+		expired := f.cfg.MaxAge > 0 && t.Sub(b.start.Add(f.cfg.Interval)) > f.cfg.MaxAge
+		if !expired && (f.cfg.MaxBackups == 0 || kept <= f.cfg.MaxBackups) {
+			continue
+		}
+		for _, path := range b.paths {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				f.reportError("removing rotated file", err)
+			}
+		}
+	}
+}
+
+// reportError writes a failure of the background work of f to the internal error
+// output.
+func (f *rotatingFile) reportError(action string, err error) {
+	fmt.Fprintf(f.errorOutput, "%v output %s: %s: %v\n", time.Now(), f.path, action, err)
+	f.errorOutput.Sync()
+}
+
+// backup is the files of a past period of a rotating file.
+type backup struct {
+	start time.Time // Start of the period
+	paths []string  // Files of the period, compressed or not
+}
+
+// backups returns the files of the past periods of f, oldest first, current being the
+// file of the current period. Files are recognized by their name alone: the path of the
+// output with a time in RotationTimeLayout inserted before the extension, followed by
+// nothing or ".gz".
+func (f *rotatingFile) backups(current string) ([]backup, error) {
+	dir := filepath.Dir(f.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+
+	periods := make(map[time.Time]*backup)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || len(name) < len(prefix)+len(RotationTimeLayout) {
+			continue
+		}
+		stamp, suffix := name[len(prefix):len(prefix)+len(RotationTimeLayout)], name[len(prefix)+len(RotationTimeLayout):]
+		if suffix != ext && suffix != ext+".gz" {
+			continue
+		}
+		start, err := time.Parse(RotationTimeLayout, stamp)
+		if err != nil || name == filepath.Base(current) {
+			continue
+		}
+		b, ok := periods[start]
+		if !ok {
+			b = &backup{start: start}
+			periods[start] = b
+		}
+		b.paths = append(b.paths, filepath.Join(dir, name))
+	}
+
+	backups := make([]backup, 0, len(periods))
+	for _, b := range periods {
+		backups = append(backups, *b)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].start.Before(backups[j].start) })
+	return backups, nil
+}
+
+// gzipFile replaces the file name with its gzip compression, name with a ".gz" suffix.
+// The compressed file is written under a temporary name and renamed once synced, so
+// that name is only removed when its compression is complete.
+func gzipFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := name + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, name+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(name)
+}
+
+// periodPath returns the path of the file of the period starting at start, for the
+// output at path.
+func periodPath(path string, start time.Time) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + start.UTC().Format(RotationTimeLayout) + ext
+}
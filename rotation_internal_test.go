@@ -0,0 +1,310 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// readFile returns the content of path, failing t when it cannot be read.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 1, 31, 23, 59, 58, 0, time.FixedZone("UTC+9", 9*3600))}
+	f, err := newRotatingFile(filepath.Join(dir, "app.log"), RotationConfig{Interval: 24 * time.Hour}, clock.now, zapcore.AddSync(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 23:59:58 UTC+9 is 14:59:58 UTC: days follow UTC, not the zone of the clock.
+	f.Write([]byte("before\n"))
+	clock.t = time.Date(2024, 1, 31, 23, 59, 59, 999999999, time.UTC)
+	f.Write([]byte("last\n"))
+	clock.t = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	f.Write([]byte("first\n"))
+	clock.advance(time.Hour)
+	f.Write([]byte("second\n"))
+	if err := f.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 {
+		t.Fatalf("files = %q, want one per day", names)
+	}
+	if got := readFile(t, filepath.Join(dir, "app-20240131T000000Z.log")); got != "before\nlast\n" {
+		t.Errorf("first day = %q, want the entries up to the boundary", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "app-20240201T000000Z.log")); got != "first\nsecond\n" {
+		t.Errorf("second day = %q, want the entries from the boundary", got)
+	}
+}
+
+func TestRotatingFileHourly(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)}
+	f, err := newRotatingFile(filepath.Join(dir, "audit"), RotationConfig{Interval: time.Hour}, clock.now, zapcore.AddSync(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("a\n"))
+	clock.advance(3 * time.Hour) // Skips the periods without entries
+	f.Write([]byte("b\n"))
+	clock.advance(-2 * time.Hour) // A clock going back does not rotate
+	f.Write([]byte("c\n"))
+
+	for name, want := range map[string]string{"audit-20240131T100000Z": "a\n", "audit-20240131T130000Z": "b\nc\n"} {
+		if got := readFile(t, filepath.Join(dir, name)); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRotatingFileInvalidConfig(t *testing.T) {
+	for _, cfg := range []RotationConfig{{}, {Interval: time.Hour, MaxSizeMB: 10}, {Interval: time.Hour, MaxBackups: -1}} {
+		if _, err := newRotatingFile(filepath.Join(t.TempDir(), "app.log"), cfg, time.Now, zapcore.AddSync(io.Discard)); err == nil {
+			t.Errorf("newRotatingFile succeeded with %+v, want an error", cfg)
+		}
+	}
+}
+
+func TestRotatingFileCompress(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)}
+	var errs bytes.Buffer
+	f, err := newRotatingFile(filepath.Join(dir, "app.log"), RotationConfig{Interval: time.Hour, Compress: true}, clock.now, zapcore.AddSync(&errs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Repeat("first period\n", 1000)
+	f.Write([]byte(content))
+	clock.advance(time.Hour)
+	f.Write([]byte("second period\n"))
+	f.close()
+
+	names, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "app-20240131T100000Z.log.gz"), filepath.Join(dir, "app-20240131T110000Z.log")}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("files = %q, want %q", names, want)
+	}
+	if got := gunzipFile(t, want[0]); got != content {
+		t.Errorf("decompressed first period = %d bytes, want the %d bytes written", len(got), len(content))
+	}
+	if got := readFile(t, want[1]); got != "second period\n" {
+		t.Errorf("current period = %q, want it uncompressed", got)
+	}
+	if errs.Len() != 0 {
+		t.Errorf("internal errors = %q, want none", errs.String())
+	}
+}
+
+func TestRotatingFileCompressFailure(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)}
+	var errs bytes.Buffer
+	f, err := newRotatingFile(filepath.Join(dir, "app.log"), RotationConfig{Interval: time.Hour, Compress: true}, clock.now, zapcore.AddSync(&errs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A directory in place of the temporary file makes the compression fail.
+	rotated := filepath.Join(dir, "app-20240131T100000Z.log")
+	if err := os.Mkdir(rotated+".gz.tmp", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("kept\n"))
+	clock.advance(time.Hour)
+	f.Write([]byte("next\n"))
+	f.close()
+
+	if !strings.Contains(errs.String(), "compressing rotated file") {
+		t.Errorf("internal errors = %q, want the compression failure", errs.String())
+	}
+	if got := readFile(t, rotated); got != "kept\n" {
+		t.Errorf("rotated file = %q, want it left uncompressed", got)
+	}
+	if _, err := os.Stat(rotated + ".gz"); !os.IsNotExist(err) {
+		t.Errorf("compressed file exists after a failure (%v), want none", err)
+	}
+}
+
+func TestRotatingFileClose(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)}
+	f, err := newRotatingFile(filepath.Join(dir, "app.log"), RotationConfig{Interval: time.Hour}, clock.now, zapcore.AddSync(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("a\n"))
+	f.close()
+	f.close() // Closing again does nothing
+
+	if _, err := f.Write([]byte("b\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Write() after close error = %v, want os.ErrClosed", err)
+	}
+	if err := f.Sync(); err != nil {
+		t.Errorf("Sync() after close error = %v, want nil", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "app-20240131T100000Z.log")); got != "a\n" {
+		t.Errorf("file = %q, want the entries written before close", got)
+	}
+}
+
+// gunzipFile returns the decompressed content of path, failing t when it cannot be read.
+func gunzipFile(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// touch creates the files names in dir, with modification times increasing in the
+// reverse order of names.
+func touch(t *testing.T, dir string, names ...string) {
+	t.Helper()
+
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Now().Add(-time.Duration(i) * time.Hour)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listDir returns the names of the files in dir, sorted.
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestRotatingFileRetention(t *testing.T) {
+	// Unrelated files, kept whatever the settings.
+	unrelated := []string{"app.log", "app-notes.log", "app-20240101T000000Z.txt", "other-20240101T000000Z.log", "app-20240101T000000Z.log.gz.tmp"}
+	tests := []struct {
+		name string
+		cfg  RotationConfig
+		kept []string
+	}{
+		{
+			name: "max backups",
+			cfg:  RotationConfig{Interval: 24 * time.Hour, MaxBackups: 2},
+			kept: []string{"app-20240129T000000Z.log.gz", "app-20240130T000000Z.log"},
+		},
+		{
+			name: "max age",
+			cfg:  RotationConfig{Interval: 24 * time.Hour, MaxAge: 36 * time.Hour},
+			kept: []string{"app-20240129T000000Z.log.gz", "app-20240130T000000Z.log"},
+		},
+		{
+			name: "max age and backups",
+			cfg:  RotationConfig{Interval: 24 * time.Hour, MaxAge: 72 * time.Hour, MaxBackups: 1},
+			kept: []string{"app-20240130T000000Z.log"},
+		},
+		{
+			name: "none",
+			cfg:  RotationConfig{Interval: 24 * time.Hour},
+			kept: []string{"app-20240101T000000Z.log", "app-20240115T000000Z.log.gz", "app-20240128T000000Z.log", "app-20240128T000000Z.log.gz", "app-20240129T000000Z.log.gz", "app-20240130T000000Z.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// Oldest by name, newest by modification time: the order comes from names. The
+			// period of January 28 has both a compressed and an uncompressed file.
+			touch(t, dir, "app-20240101T000000Z.log", "app-20240115T000000Z.log.gz", "app-20240128T000000Z.log", "app-20240128T000000Z.log.gz", "app-20240129T000000Z.log.gz")
+			touch(t, dir, unrelated...)
+
+			clock := &fakeClock{t: time.Date(2024, 1, 30, 12, 0, 0, 0, time.UTC)}
+			var errs bytes.Buffer
+			f, err := newRotatingFile(filepath.Join(dir, "app.log"), tt.cfg, clock.now, zapcore.AddSync(&errs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write([]byte("a\n"))
+			clock.advance(24 * time.Hour)
+			f.Write([]byte("b\n"))
+			f.close()
+
+			want := append(append([]string{}, unrelated...), "app-20240131T000000Z.log")
+			want = append(want, tt.kept...)
+			sort.Strings(want)
+			if got := listDir(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("files = %q, want %q", got, want)
+			}
+			if errs.Len() != 0 {
+				t.Errorf("internal errors = %q, want none", errs.String())
+			}
+		})
+	}
+}
+
+func TestRotatingFileRetentionCompressed(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "app-20240129T000000Z.log.gz")
+	clock := &fakeClock{t: time.Date(2024, 1, 30, 12, 0, 0, 0, time.UTC)}
+	f, err := newRotatingFile(filepath.Join(dir, "app.log"), RotationConfig{Interval: 24 * time.Hour, MaxBackups: 1, Compress: true}, clock.now, zapcore.AddSync(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("a\n"))
+	clock.advance(24 * time.Hour)
+	f.Write([]byte("b\n"))
+	f.close()
+
+	want := []string{"app-20240130T000000Z.log.gz", "app-20240131T000000Z.log"}
+	if got := listDir(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("files = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestWithTimedRotation(t *testing.T) {
+	restoreDefault(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	before := time.Now().UTC().Truncate(24 * time.Hour)
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths("stderr"), sazabi.WithTimedRotation(path, 24*time.Hour))
+	sazabi.Info("rotated entry")
+	sazabi.Sync()
+	after := time.Now().UTC().Truncate(24 * time.Hour)
+
+	var data []byte
+	var err error
+	for _, start := range []time.Time{before, after} { // The day may end during the test
+		name := filepath.Join(dir, "app-"+start.Format(sazabi.RotationTimeLayout)+".log")
+		if data, err = os.ReadFile(name); err == nil && strings.Contains(string(data), "rotated entry") {
+			break
+		}
+	}
+	if !strings.Contains(string(data), "rotated entry") {
+		names, _ := filepath.Glob(filepath.Join(dir, "*"))
+		t.Errorf("no file of the current day with the entry among %q", names)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Stat(%s) error = %v, want the file not to exist", path, err)
+	}
+}
+
+func TestWithTimedRotationInvalidInterval(t *testing.T) {
+	restoreDefault(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := sazabi.TryInitialize(sazabi.ProductionEnvName, sazabi.WithTimedRotation(path, -time.Hour)); err == nil {
+		t.Error("TryInitialize succeeded with a negative interval, want an error")
+	}
+}
+
+func TestRotationShutdown(t *testing.T) {
+	restoreDefault(t)
+	dir := t.TempDir()
+	interval := time.Second // The shortest period with its own file name
+	sazabi.Initialize("development", sazabi.WithOutputPaths(filepath.Join(dir, "other.log")),
+		sazabi.WithRotation(filepath.Join(dir, "app.log"), sazabi.RotationConfig{Interval: interval, Compress: true}))
+
+	sazabi.Info(strings.Repeat("first period ", 1<<18)) // Long enough for the compression to take a while
+	now := time.Now()
+	time.Sleep(now.Truncate(interval).Add(interval).Sub(now))
+	sazabi.Info("second period")
+	if err := sazabi.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	compressed, _ := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	plain, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	tmp, _ := filepath.Glob(filepath.Join(dir, "*.gz.tmp"))
+	if len(compressed) != 1 || len(plain) != 1 || len(tmp) != 0 {
+		names, _ := filepath.Glob(filepath.Join(dir, "*"))
+		t.Errorf("files after Shutdown = %q, want the first period compressed and no temporary file", names)
+	}
+}
+
+func TestRotationShutdownRetention(t *testing.T) {
+	restoreDefault(t)
+	dir := t.TempDir()
+	for _, name := range []string{"app-20240101T000000Z.log.gz", "app-20240102T000000Z.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("old\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	interval := time.Second // The shortest period with its own file name
+	sazabi.Initialize("development", sazabi.WithOutputPaths(filepath.Join(dir, "other.log")),
+		sazabi.WithRotation(filepath.Join(dir, "app.log"), sazabi.RotationConfig{Interval: interval, MaxBackups: 1}))
+
+	sazabi.Info("first period")
+	now := time.Now()
+	time.Sleep(now.Truncate(interval).Add(interval).Sub(now))
+	sazabi.Info("second period")
+	if err := sazabi.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if names, _ := filepath.Glob(filepath.Join(dir, "app-*")); len(names) != 2 || strings.Contains(strings.Join(names, ","), "2024010") {
+		t.Errorf("files after Shutdown = %q, want the last two periods alone", names)
+	}
+}
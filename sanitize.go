@@ -0,0 +1,203 @@
+package sazabi
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SanitizedKey is the key of the field marking entries whose message or field values
+// were changed by WithSanitization.
+const SanitizedKey = "sanitized"
+
+// WithSanitization protects the terminals of engineers reading production logs: the
+// message and the string, byte string and error field values of production entries
+// are cleaned before encoding. ANSI escape sequences, such as CSI color codes and OSC
+// window titles, are removed; control characters other than tab and newline are
+// escaped, "\r" as `\r` and the others as `\xNN`. Entries that were changed carry
+// SanitizedKey=true. The values of binaryKeys, fields that legitimately contain raw
+// bytes, are written in base64 instead. Development entries are left alone.
+func WithSanitization(binaryKeys ...string) Option {
+	return func(o *options) {
+		o.sanitize = true
+		o.sanitizeBinaryKeys = append(o.sanitizeBinaryKeys, binaryKeys...)
+	}
+}
+
+// sanitizeCore cleans the messages and field values of the entries it writes.
+type sanitizeCore struct {
+	zapcore.Core
+	binary map[string]struct{} // Keys whose values are written in base64
+}
+
+// newSanitizeCore wraps core with the sanitization of o, or returns core when
+// sanitization is disabled or the logger is a development one.
+func newSanitizeCore(core zapcore.Core, development bool, o *options) zapcore.Core {
+	if !o.sanitize || development {
+		return core
+	}
+	binary := make(map[string]struct{}, len(o.sanitizeBinaryKeys))
+	for _, key := range o.sanitizeBinaryKeys {
+		binary[key] = struct{}{}
+	}
+	return &sanitizeCore{Core: core, binary: binary}
+}
+
+// With implements zapcore.Core. Context fields that were changed mark every entry.
+func (c *sanitizeCore) With(fields []zapcore.Field) zapcore.Core {
+	fields, changed := c.sanitize(fields)
+	if changed {
+		fields = append(fields, zap.Bool(SanitizedKey, true))
+	}
+	return &sanitizeCore{Core: c.Core.With(fields), binary: c.binary}
+}
+
+// Check implements zapcore.Core.
+func (c *sanitizeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *sanitizeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	msg, msgChanged := sanitizeString(ent.Message)
+	fields, changed := c.sanitize(fields)
+	if msgChanged || changed {
+		ent.Message = msg
+		fields = append(fields[:len(fields):len(fields)], zap.Bool(SanitizedKey, true))
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// sanitize returns fields with their values cleaned, and whether a value other than a
+// binary one changed. The input slice is only copied when a field changes.
+func (c *sanitizeCore) sanitize(fields []zapcore.Field) ([]zapcore.Field, bool) {
+	sanitized, copied, changed := fields, false, false
+	for i, f := range fields {
+		s, isBinary, ok := c.sanitizeField(f)
+		if !ok {
+			continue
+		}
+		if !copied {
+			sanitized, copied = append([]zapcore.Field(nil), fields...), true
+		}
+		sanitized[i] = s
+		changed = changed || !isBinary
+	}
+	return sanitized, changed
+}
+
+// sanitizeField returns the cleaned form of f, whether it is a binary field, and
+// whether it differs from f.
+func (c *sanitizeCore) sanitizeField(f zapcore.Field) (zapcore.Field, bool, bool) {
+	var value string
+	switch f.Type {
+	case zapcore.StringType:
+		value = f.String
+	case zapcore.ByteStringType:
+		value = string(f.Interface.([]byte))
+	case zapcore.ErrorType:
+		err, ok := f.Interface.(error)
+		if !ok || err == nil {
+			return f, false, false
+		}
+		value = err.Error()
+	default:
+		return f, false, false
+	}
+
+	if _, ok := c.binary[f.Key]; ok && f.Type != zapcore.ErrorType {
+		return zap.String(f.Key, base64.StdEncoding.EncodeToString([]byte(value))), true, true
+	}
+	s, changed := sanitizeString(value)
+	if !changed {
+		return f, false, false
+	}
+	return zap.String(f.Key, s), false, true
+}
+
+// sanitizeString removes the ANSI escape sequences of s and escapes its control
+// characters other than tab and newline, reporting whether s changed.
+func sanitizeString(s string) (string, bool) {
+	if clean(s) {
+		return s, false
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == 0x1b:
+			i += escapeSequenceLen(s[i:])
+			continue
+		case r == '\t' || r == '\n':
+			b.WriteRune(r)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r >= 0x80 && r < 0xa0:
+			fmt.Fprintf(&b, `\u%04x`, r) // C1 controls, including the single-character CSI
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String(), true
+}
+
+// clean reports whether s contains nothing sanitizeString would change.
+func clean(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < 0x20 && c != '\t' && c != '\n') || c == 0x7f {
+			return false
+		}
+		if c == 0xc2 && i+1 < len(s) && s[i+1] >= 0x80 && s[i+1] < 0xa0 {
+			return false // UTF-8 encoding of a C1 control
+		}
+	}
+	return true
+}
+
+// escapeSequenceLen returns the length of the escape sequence at the start of s, which
+// starts with ESC: CSI sequences end with a final byte, OSC sequences with BEL or ST
+// (ESC \), and the others after the byte following ESC.
+func escapeSequenceLen(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case '[': // CSI: parameter and intermediate bytes, then a final byte
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+			if s[i] < 0x20 || s[i] > 0x7e {
+				return i // Malformed, keep what follows
+			}
+		}
+		return len(s)
+	case ']', 'P', '_', '^': // OSC, DCS, APC and PM: a string ended by BEL or ST
+		for i := 2; i < len(s); i++ {
+			if s[i] == 0x07 {
+				return i + 1
+			}
+			if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+	if s[1] >= 0x20 && s[1] <= 0x7e {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,74 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestSanitization(t *testing.T) {
+	log, read := newFileLogger(t, sazabi.ProductionEnvName, sazabi.WithSanitization("payload"))
+
+	log.Infow("user \x1b[31mlogin\x1b[0m",
+		"title", "\x1b]0;pwned\x07done",
+		"color", "\x1b[1;32mgreen\x1b[0m",
+		"line", "progress\r100%",
+		"bell", "ding\x07\x00",
+		"stack", "main.go:1\n\tmain.go:2",
+		"payload", "\x00\x01\xff",
+		"error", errors.New("bad \x1b[2Jinput"),
+	)
+	log.Infow("clean entry", "user", "alice")
+
+	output := read()
+	if strings.Contains(output, "\x1b") || strings.Contains(output, `\u001b`) || strings.Contains(output, "pwned") {
+		t.Fatalf("output still carries escape sequences:\n%s", output)
+	}
+
+	fields := entryFields(t, output, "\tuser login\t")
+	want := map[string]interface{}{
+		sazabi.SanitizedKey: true,
+		"title":             "done",
+		"color":             "green",
+		"line":              `progress\r100%`,
+		"bell":              `ding\x07\x00`,
+		"stack":             "main.go:1\n\tmain.go:2",
+		"payload":           "AAH/",
+		"error":             "bad input",
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %q, want %q", key, fields[key], value)
+		}
+	}
+
+	if fields := entryFields(t, output, "clean entry"); fields[sazabi.SanitizedKey] != nil {
+		t.Errorf("clean entry is marked sanitized: %v", fields)
+	}
+}
+
+func TestSanitizationContextFields(t *testing.T) {
+	log, read := newFileLogger(t, sazabi.ProductionEnvName, sazabi.WithSanitization())
+
+	log.With("agent", "curl\x1b[8m").Info("request")
+
+	fields := entryFields(t, read(), "request")
+	if fields["agent"] != "curl" || fields[sazabi.SanitizedKey] != true {
+		t.Errorf("fields = %v, want the context field sanitized and marked", fields)
+	}
+}
+
+func TestSanitizationDevelopment(t *testing.T) {
+	log, read := newFileLogger(t, "development", sazabi.WithSanitization())
+
+	log.Infow("request", "agent", "curl\x1b[8m")
+
+	if output := read(); !strings.Contains(output, `curl\u001b[8m`) || strings.Contains(output, sazabi.SanitizedKey) {
+		t.Errorf("development output = %q, want the value left alone", output)
+	}
+}
@@ -0,0 +1,31 @@
+// Package sazabichi adds the route patterns of chi to the access entries of
+// sazabi.RequestLogger, for middleware installed in a chi router:
+//
+//	r := chi.NewRouter()
+//	r.Use(sazabi.RequestLogger(sazabi.WithRequestFields(sazabichi.RoutePattern)))
+package sazabichi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// RoutePatternKey is the key of the field added by RoutePattern.
+const RoutePatternKey = "route_pattern"
+
+// RoutePattern returns the route pattern matched by chi for r, such as
+// "/users/{id}", under RoutePatternKey, for sazabi.WithRequestFields. It returns nothing
+// for requests chi did not route, such as those of middleware installed outside of the
+// router.
+func RoutePattern(r *http.Request, _ sazabi.ResponseInfo) []interface{} {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return nil
+	}
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		return []interface{}{RoutePatternKey, pattern}
+	}
+	return nil
+}
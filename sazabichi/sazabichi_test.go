@@ -0,0 +1,75 @@
+//go:build test
+// +build test
+
+package sazabichi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/sazabichi"
+)
+
+// serve returns the access entries logged while handler serves GET requests to paths.
+func serve(handler http.Handler, paths ...string) []sazabi.CapturedEntry {
+	c, stop := sazabi.StartCapture()
+	defer stop()
+	for _, path := range paths {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var access []sazabi.CapturedEntry
+	for _, e := range c.Entries() {
+		if e.Message == sazabi.HTTPRequestMessage {
+			access = append(access, e)
+		}
+	}
+	return access
+}
+
+func TestRoutePattern(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	r := chi.NewRouter()
+	r.Use(sazabi.RequestLogger(sazabi.WithRequestFields(sazabichi.RoutePattern)))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Route("/orgs/{org}", func(r chi.Router) {
+		r.Get("/repos/{repo}", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	var got []string
+	for _, e := range serve(r, "/users/42", "/orgs/acme/repos/sazabi", "/missing") {
+		pattern, ok := e.Str(sazabichi.RoutePatternKey)
+		if !ok {
+			pattern = "none"
+		}
+		status, _ := e.Int("status")
+		caller := e.Caller.TrimmedPath()
+		if !strings.Contains(caller, "/http.go:") {
+			t.Errorf("caller = %s, want the middleware of sazabi", caller)
+		}
+		got = append(got, pattern+" "+http.StatusText(int(status)))
+	}
+	want := []string{"/users/{id} OK", "/orgs/{org}/repos/{repo} OK", "none Not Found"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("access entries = %q, want %q", got, want)
+	}
+}
+
+func TestRoutePatternOutsideRouter(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	r := chi.NewRouter()
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	handler := sazabi.RequestLogger(sazabi.WithRequestFields(sazabichi.RoutePattern))(r)
+
+	access := serve(handler, "/users/42")
+	if len(access) != 1 {
+		t.Fatalf("got %d access entries, want 1", len(access))
+	}
+	if _, ok := access[0].Field(sazabichi.RoutePatternKey); ok {
+		t.Errorf("access entry fields = %v, want no route pattern outside of the router", access[0].Fields)
+	}
+}
@@ -0,0 +1,26 @@
+module github.com/zeroxsolutions/sazabi/sazabiecho
+
+go 1.23.0
+
+require (
+	github.com/labstack/echo/v4 v4.13.4
+	github.com/zeroxsolutions/sazabi v0.0.0
+	go.uber.org/zap v1.27.0
+)
+
+require (
+	github.com/labstack/gommon v0.4.2 // indirect
+	github.com/mattn/go-colorable v0.1.14 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasttemplate v1.2.2 // indirect
+	github.com/zeroxsolutions/barbatos v0.0.1 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/crypto v0.38.0 // indirect
+	golang.org/x/net v0.40.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.25.0 // indirect
+	golang.org/x/time v0.11.0 // indirect
+)
+
+replace github.com/zeroxsolutions/sazabi => ../
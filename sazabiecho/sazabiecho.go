@@ -0,0 +1,117 @@
+// Package sazabiecho provides Echo middleware logging through the global logger of
+// sazabi, in place of the Logger and Recover middleware of Echo:
+//
+//	e := echo.New()
+//	e.Use(middleware.RequestID(), sazabiecho.Logger(), sazabiecho.Recover())
+package sazabiecho
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// PanicMessage is the message of the entries written by Recover.
+const PanicMessage = "http panic recovered"
+
+// Logger returns a middleware writing one sazabi.HTTPRequestMessage entry per request
+// with request_id, method, route, client_ip, status, bytes and duration, and the error
+// returned by the handler under sazabi.ErrorKey. The route is the pattern of the matched
+// route, such as "/users/:id", never the raw path, to keep the cardinality of log-based
+// metrics low. The request ID is the one set by the RequestID middleware of Echo, when
+// it comes before Logger, or the X-Request-ID request header. Entries are written at
+// Info level, Warn for 4xx responses and Error for 5xx responses.
+//
+// Errors returned by the handler are passed to the error handler of Echo before the
+// entry is written, so that it records the status sent, and are not returned further.
+// Handlers get a logger bound with the request fields through
+// sazabi.FromContext(c.Request().Context()).
+func Logger() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+			l := sazabi.With(
+				"request_id", requestID(c),
+				"method", req.Method,
+				"route", c.Path(),
+				"client_ip", c.RealIP(),
+			)
+			c.SetRequest(req.WithContext(sazabi.NewContext(req.Context(), l)))
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			res := c.Response()
+			fields := []zap.Field{
+				zap.Int("status", res.Status),
+				zap.Int64("bytes", res.Size),
+				zap.Duration("duration", time.Since(start)),
+			}
+			if err != nil {
+				fields = append(fields, zap.NamedError(sazabi.ErrorKey, err))
+			}
+			if ce := l.(*zap.SugaredLogger).Desugar().Check(level(res.Status), sazabi.HTTPRequestMessage); ce != nil {
+				ce.Write(fields...)
+			}
+			return nil
+		}
+	}
+}
+
+// requestID returns the ID of the request handled by c: the one set on the response by
+// the RequestID middleware, or else the one sent by the client.
+func requestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
+
+// level returns the level of the access entry of a response with status.
+func level(status int) zapcore.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return zapcore.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return zapcore.WarnLevel
+	}
+	return zapcore.InfoLevel
+}
+
+// Recover returns a middleware recovering the panics of the handlers that follow it. It
+// writes a PanicMessage entry at Error level with the panic value under
+// sazabi.HTTPPanicKey, the stack under stack, method and route, then returns a 500
+// *echo.HTTPError, which Echo answers through its error handler. http.ErrAbortHandler is
+// propagated, as net/http expects.
+func Recover() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+				sazabi.Errorw(PanicMessage,
+					sazabi.HTTPPanicKey, fmt.Sprint(recovered),
+					"stack", string(debug.Stack()),
+					"method", c.Request().Method,
+					"route", c.Path(),
+				)
+				err = echo.NewHTTPError(http.StatusInternalServerError)
+			}()
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+//go:build test
+// +build test
+
+package sazabiecho_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/sazabiecho"
+	"go.uber.org/zap/zapcore"
+)
+
+// serve returns the response and the entries logged while e handles a GET request to
+// path.
+func serve(e *echo.Echo, path string) (*httptest.ResponseRecorder, []sazabi.CapturedEntry) {
+	c, stop := sazabi.StartCapture()
+	defer stop()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, req)
+	return recorder, c.Entries()
+}
+
+// entry returns the entry with message msg among entries.
+func entry(t *testing.T, entries []sazabi.CapturedEntry, msg string) sazabi.CapturedEntry {
+	t.Helper()
+
+	for _, e := range entries {
+		if e.Message == msg {
+			return e
+		}
+	}
+	t.Fatalf("no %q entry among %v", msg, entries)
+	return sazabi.CapturedEntry{}
+}
+
+// value returns the value of the field key of e as encoded to JSON, nil when e has no
+// such field.
+func value(e sazabi.CapturedEntry, key string) interface{} {
+	f, ok := e.Field(key)
+	if !ok {
+		return nil
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return enc.Fields[key]
+}
+
+// newEcho returns an Echo instance using the middleware after middleware.RequestID, with
+// a route answering normally, routes returning errors and a panicking route.
+func newEcho() *echo.Echo {
+	e := echo.New()
+	e.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		Generator: func() string { return "req-1" },
+	}), sazabiecho.Logger(), sazabiecho.Recover())
+	e.GET("/users/:id", func(c echo.Context) error {
+		sazabi.FromContext(c.Request().Context()).Infow("handler entry")
+		return c.String(http.StatusOK, "alice")
+	})
+	e.GET("/items/:id", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "no such item")
+	})
+	e.GET("/broken", func(c echo.Context) error {
+		return errors.New("database unreachable")
+	})
+	e.GET("/boom/:id", func(c echo.Context) error {
+		panic("handler bug")
+	})
+	return e
+}
+
+func TestLogger(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	_, entries := serve(newEcho(), "/users/42")
+
+	access := entry(t, entries, sazabi.HTTPRequestMessage)
+	fields := map[string]interface{}{
+		"request_id": "req-1",
+		"method":     http.MethodGet,
+		"route":      "/users/:id",
+		"client_ip":  "203.0.113.7",
+		"status":     int64(http.StatusOK),
+		"bytes":      int64(len("alice")),
+	}
+	for key, want := range fields {
+		if got := value(access, key); got != want {
+			t.Errorf("access entry field %q = %v (%T), want %v", key, got, got, want)
+		}
+	}
+	if access.Level != zapcore.InfoLevel {
+		t.Errorf("access entry at %v, want info", access.Level)
+	}
+	if _, ok := access.Dur("duration"); !ok {
+		t.Errorf("access entry fields = %v, want a duration", access.Fields)
+	}
+	if _, ok := access.Field("path"); ok {
+		t.Errorf("access entry fields = %v, want the route and not the raw path", access.Fields)
+	}
+	if err := access.Err(); err != nil {
+		t.Errorf("access entry error = %v, want none", err)
+	}
+	if handler := entry(t, entries, "handler entry"); value(handler, "request_id") != "req-1" || value(handler, "route") != "/users/:id" {
+		t.Errorf("handler entry fields = %v, want the request fields", handler.Fields)
+	}
+}
+
+func TestLoggerRequestIDHeader(t *testing.T) {
+	e := echo.New()
+	e.Use(sazabiecho.Logger())
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) })
+
+	c, stop := sazabi.StartCapture()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "from-client")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	stop()
+
+	if got := value(entry(t, c.Entries(), sazabi.HTTPRequestMessage), "request_id"); got != "from-client" {
+		t.Errorf("request_id = %v, want the header of the request", got)
+	}
+}
+
+func TestLoggerHandlerErrors(t *testing.T) {
+	tests := []struct {
+		path   string
+		status int
+		level  zapcore.Level
+		err    string
+	}{
+		{path: "/items/7", status: http.StatusNotFound, level: zapcore.WarnLevel, err: "no such item"},
+		{path: "/broken", status: http.StatusInternalServerError, level: zapcore.ErrorLevel, err: "database unreachable"},
+		{path: "/missing", status: http.StatusNotFound, level: zapcore.WarnLevel, err: "Not Found"},
+	}
+
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	for _, tt := range tests {
+		recorder, entries := serve(newEcho(), tt.path)
+		if recorder.Code != tt.status {
+			t.Errorf("response status of %s = %d, want %d", tt.path, recorder.Code, tt.status)
+		}
+		access := entry(t, entries, sazabi.HTTPRequestMessage)
+		if access.Level != tt.level || value(access, "status") != int64(tt.status) {
+			t.Errorf("access entry of %s at %v with status %v, want %v and %d", tt.path, access.Level, value(access, "status"), tt.level, tt.status)
+		}
+		if err := access.Err(); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("access entry error of %s = %v, want %q", tt.path, err, tt.err)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	recorder, entries := serve(newEcho(), "/boom/1")
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("response status = %d, want 500", recorder.Code)
+	}
+	recovered := entry(t, entries, sazabiecho.PanicMessage)
+	if recovered.Level != zapcore.ErrorLevel || value(recovered, sazabi.HTTPPanicKey) != "handler bug" || value(recovered, "route") != "/boom/:id" {
+		t.Errorf("panic entry = %+v, want an error with the panic value and route", recovered)
+	}
+	if stack, _ := recovered.Str("stack"); !strings.Contains(stack, "sazabiecho_test.newEcho") {
+		t.Errorf("stack = %q, want the frames of the panicking handler", stack)
+	}
+	if access := entry(t, entries, sazabi.HTTPRequestMessage); access.Level != zapcore.ErrorLevel || value(access, "status") != int64(http.StatusInternalServerError) {
+		t.Errorf("access entry = %+v, want an error with status 500", access)
+	}
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	e := echo.New()
+	e.Use(sazabiecho.Recover())
+	e.GET("/", func(echo.Context) error {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler propagated", recovered)
+		}
+	}()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,121 @@
+// Package sazabifiber provides Fiber middleware logging through the global logger of
+// sazabi, in place of the logger and recover middleware of Fiber:
+//
+//	app := fiber.New()
+//	app.Use(sazabifiber.New(), sazabifiber.Recover())
+package sazabifiber
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// PanicMessage is the message of the entries written by Recover.
+const PanicMessage = "http panic recovered"
+
+// Config configures the middleware returned by New.
+type Config struct {
+	SkipPaths []string      // Paths of the requests without access entry, such as "/healthz"
+	Level     zapcore.Level // Level of the entries of 1xx, 2xx and 3xx responses, Info by default
+}
+
+// New returns a middleware writing one sazabi.HTTPRequestMessage entry per request with
+// method, route, path, client_ip, status, bytes and duration, and the error returned by
+// the handlers under sazabi.ErrorKey. Entries are written at the level of the config,
+// Warn for 4xx responses and Error for 5xx responses, unless the level of the config is
+// higher. Only the first config is used.
+//
+// Errors returned by the handlers are passed to the error handler of the app before the
+// entry is written, so that it records the status sent, and are not returned further.
+// Since Fiber reuses the memory of its contexts once the handler returns, every value is
+// copied before being logged.
+func New(config ...Config) fiber.Handler {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	skipped := make(map[string]bool, len(cfg.SkipPaths))
+	for _, path := range cfg.SkipPaths {
+		skipped[path] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		if skipped[c.Path()] {
+			return c.Next()
+		}
+
+		start := time.Now()
+		err := c.Next()
+		if err != nil {
+			if herr := c.App().ErrorHandler(c, err); herr != nil {
+				c.Status(http.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		fields := []zap.Field{
+			zap.String("method", strings.Clone(c.Method())),
+			zap.String("route", strings.Clone(c.Route().Path)),
+			zap.String("path", strings.Clone(c.Path())),
+			zap.String("client_ip", strings.Clone(c.IP())),
+			zap.Int("status", status),
+			zap.Int("bytes", len(c.Response().Body())),
+			zap.Duration("duration", time.Since(start)),
+		}
+		if err != nil {
+			fields = append(fields, zap.String(sazabi.ErrorKey, err.Error()))
+		}
+		log := sazabi.With().(*zap.SugaredLogger).Desugar()
+		if ce := log.Check(level(cfg.Level, status), sazabi.HTTPRequestMessage); ce != nil {
+			ce.Write(fields...)
+		}
+		return nil
+	}
+}
+
+// level returns the level of the access entry of a response with status, base being the
+// level of successful responses.
+func level(base zapcore.Level, status int) zapcore.Level {
+	lvl := base
+	switch {
+	case status >= http.StatusInternalServerError:
+		lvl = zapcore.ErrorLevel
+	case status >= http.StatusBadRequest:
+		lvl = zapcore.WarnLevel
+	}
+	if lvl < base {
+		return base
+	}
+	return lvl
+}
+
+// Recover returns a middleware recovering the panics of the handlers that follow it. It
+// writes a PanicMessage entry at Error level with the panic value under
+// sazabi.HTTPPanicKey, the stack under stack, method and path, then returns
+// fiber.ErrInternalServerError, which the error handler of the app answers.
+func Recover() fiber.Handler {
+	return func(c *fiber.Ctx) (err error) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			sazabi.Errorw(PanicMessage,
+				sazabi.HTTPPanicKey, fmt.Sprint(recovered),
+				"stack", string(debug.Stack()),
+				"method", strings.Clone(c.Method()),
+				"path", strings.Clone(c.Path()),
+			)
+			err = fiber.ErrInternalServerError
+		}()
+		return c.Next()
+	}
+}
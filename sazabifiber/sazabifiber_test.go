@@ -0,0 +1,175 @@
+//go:build test
+// +build test
+
+package sazabifiber_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/sazabifiber"
+	"go.uber.org/zap/zapcore"
+)
+
+// newApp returns an app using the middleware configured by config, with routes
+// answering each status class and a panicking route.
+func newApp(config ...sazabifiber.Config) *fiber.App {
+	app := fiber.New()
+	app.Use(sazabifiber.New(config...), sazabifiber.Recover())
+	app.Get("/users/:name", func(c *fiber.Ctx) error {
+		return c.SendString("hello " + c.Params("name"))
+	})
+	app.Get("/healthz", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusNoContent)
+	})
+	app.Get("/items/:id", func(c *fiber.Ctx) error {
+		return fiber.NewError(http.StatusNotFound, "no such item")
+	})
+	app.Get("/broken", func(c *fiber.Ctx) error {
+		return errors.New("database unreachable")
+	})
+	app.Get("/boom", func(c *fiber.Ctx) error {
+		panic("handler bug")
+	})
+	return app
+}
+
+// serve returns the entries logged while app handles GET requests to paths.
+func serve(t *testing.T, app *fiber.App, paths ...string) []sazabi.CapturedEntry {
+	t.Helper()
+
+	c, stop := sazabi.StartCapture()
+	defer stop()
+	for _, path := range paths {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	return c.Entries()
+}
+
+// entries returns the entries with message msg.
+func entries(all []sazabi.CapturedEntry, msg string) []sazabi.CapturedEntry {
+	var found []sazabi.CapturedEntry
+	for _, e := range all {
+		if e.Message == msg {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// str returns the string field key of e.
+func str(e sazabi.CapturedEntry, key string) string {
+	s, _ := e.Str(key)
+	return s
+}
+
+// num returns the integer field key of e.
+func num(e sazabi.CapturedEntry, key string) int64 {
+	n, _ := e.Int(key)
+	return n
+}
+
+func TestNew(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	logged := entries(serve(t, newApp(), "/users/alice", "/users/bob"), sazabi.HTTPRequestMessage)
+
+	if len(logged) != 2 {
+		t.Fatalf("got %d access entries, want 2", len(logged))
+	}
+	// The second request reuses the context of the first one: the values of the first
+	// entry must have been copied.
+	for i, name := range []string{"alice", "bob"} {
+		e := logged[i]
+		if e.Level != zapcore.InfoLevel || str(e, "method") != http.MethodGet || str(e, "route") != "/users/:name" || str(e, "path") != "/users/"+name {
+			t.Errorf("access entry %d = %v %v, want an info entry for /users/%s", i, e.Level, e.Fields, name)
+		}
+		if num(e, "status") != http.StatusOK || num(e, "bytes") != int64(len("hello "+name)) || str(e, "client_ip") == "" {
+			t.Errorf("access entry %d fields = %v, want status, bytes and client_ip", i, e.Fields)
+		}
+		if _, ok := e.Dur("duration"); !ok {
+			t.Errorf("access entry %d fields = %v, want a duration", i, e.Fields)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		path   string
+		status int64
+		level  zapcore.Level
+		err    string
+	}{
+		{path: "/items/7", status: http.StatusNotFound, level: zapcore.WarnLevel, err: "no such item"},
+		{path: "/broken", status: http.StatusInternalServerError, level: zapcore.ErrorLevel, err: "database unreachable"},
+		{path: "/missing", status: http.StatusNotFound, level: zapcore.WarnLevel, err: "Cannot GET /missing"},
+	}
+
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	for _, tt := range tests {
+		logged := entries(serve(t, newApp(), tt.path), sazabi.HTTPRequestMessage)
+		if len(logged) != 1 {
+			t.Fatalf("got %d access entries for %s, want 1", len(logged), tt.path)
+		}
+		if e := logged[0]; e.Level != tt.level || num(e, "status") != tt.status || str(e, sazabi.ErrorKey) != tt.err {
+			t.Errorf("access entry of %s = %v %v, want %v with status %d and error %q", tt.path, e.Level, e.Fields, tt.level, tt.status, tt.err)
+		}
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	app := newApp(sazabifiber.Config{SkipPaths: []string{"/healthz"}, Level: zapcore.DebugLevel})
+	logged := entries(serve(t, app, "/healthz", "/users/alice", "/items/1"), sazabi.HTTPRequestMessage)
+
+	var got []string
+	for _, e := range logged {
+		got = append(got, e.Level.String()+" "+str(e, "path"))
+	}
+	if want := "debug /users/alice,warn /items/1"; strings.Join(got, ",") != want {
+		t.Errorf("access entries = %q, want %q", got, want)
+	}
+
+	app = newApp(sazabifiber.Config{Level: zapcore.WarnLevel})
+	if logged := entries(serve(t, app, "/users/alice"), sazabi.HTTPRequestMessage); len(logged) != 1 || logged[0].Level != zapcore.WarnLevel {
+		t.Errorf("access entries = %v, want one at the level of the config", logged)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	app := newApp()
+
+	c, stop := sazabi.StartCapture()
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/boom", nil))
+	stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("response status = %d, want 500", resp.StatusCode)
+	}
+
+	recovered := entries(c.Entries(), sazabifiber.PanicMessage)
+	if len(recovered) != 1 {
+		t.Fatalf("got %d panic entries, want 1", len(recovered))
+	}
+	if e := recovered[0]; e.Level != zapcore.ErrorLevel || str(e, sazabi.HTTPPanicKey) != "handler bug" || str(e, "path") != "/boom" {
+		t.Errorf("panic entry = %v %v, want an error with the panic value and path", e.Level, e.Fields)
+	}
+	if stack := str(recovered[0], "stack"); !strings.Contains(stack, "sazabifiber_test.newApp") {
+		t.Errorf("stack = %q, want the frames of the panicking handler", stack)
+	}
+	if access := entries(c.Entries(), sazabi.HTTPRequestMessage); len(access) != 1 || access[0].Level != zapcore.ErrorLevel || num(access[0], "status") != http.StatusInternalServerError {
+		t.Errorf("access entries = %v, want an error with status 500", access)
+	}
+}
@@ -0,0 +1,123 @@
+// Package sazabigin provides Gin middleware logging through the global logger of
+// sazabi, in place of gin.Logger and gin.Recovery:
+//
+//	router := gin.New()
+//	router.Use(sazabigin.Logger(), sazabigin.Recovery())
+package sazabigin
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// PanicMessage is the message of the entries written by Recovery.
+const PanicMessage = "http panic recovered"
+
+// ErrorsKey is the key of the errors attached to the context with c.Error, on the
+// access entries written by Logger.
+const ErrorsKey = "errors"
+
+// Option configures the middleware returned by Logger.
+type Option func(*options)
+
+// options holds the settings collected from Option values.
+type options struct {
+	slowThreshold time.Duration // Duration from which requests are logged at Warn level
+}
+
+// WithSlowThreshold writes the access entries of requests taking longer than d at Warn
+// level, unless their status already calls for Error.
+func WithSlowThreshold(d time.Duration) Option {
+	return func(o *options) {
+		o.slowThreshold = d
+	}
+}
+
+// Logger returns a middleware writing one sazabi.HTTPRequestMessage entry per request
+// with status, method, path, route, client_ip, bytes, user_agent and duration, and the
+// errors attached to the context under ErrorsKey. Entries are written at Info level, Warn
+// for 4xx responses and Error for 5xx responses. Handlers get a logger bound with method,
+// route and client_ip through sazabi.FromContext(c.Request.Context()). Logger must come
+// before Recovery, so that recovered requests are logged with their 500 status.
+func Logger(opts ...Option) gin.HandlerFunc {
+	o := &options{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		l := sazabi.With(
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"client_ip", c.ClientIP(),
+		)
+		c.Request = c.Request.WithContext(sazabi.NewContext(c.Request.Context(), l))
+
+		c.Next()
+
+		duration := time.Since(start)
+		status := c.Writer.Status()
+		fields := []zap.Field{
+			zap.Int("status", status),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("bytes", c.Writer.Size()),
+			zap.String("user_agent", c.Request.UserAgent()),
+			zap.Duration("duration", duration),
+		}
+		if errs := c.Errors.Errors(); len(errs) > 0 {
+			fields = append(fields, zap.Strings(ErrorsKey, errs))
+		}
+		if ce := l.(*zap.SugaredLogger).Desugar().Check(o.level(status, duration), sazabi.HTTPRequestMessage); ce != nil {
+			ce.Write(fields...)
+		}
+	}
+}
+
+// level returns the level of the access entry of a request answered with status after
+// duration.
+func (o *options) level(status int, duration time.Duration) zapcore.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return zapcore.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return zapcore.WarnLevel
+	case o.slowThreshold > 0 && duration > o.slowThreshold:
+		return zapcore.WarnLevel
+	}
+	return zapcore.InfoLevel
+}
+
+// Recovery returns a middleware recovering the panics of the handlers that follow it. It
+// writes a PanicMessage entry at Error level with the panic value under
+// sazabi.HTTPPanicKey, the stack under stack, method and path, then aborts the request
+// with a 500 status. http.ErrAbortHandler is propagated, as net/http expects.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			sazabi.Errorw(PanicMessage,
+				sazabi.HTTPPanicKey, fmt.Sprint(recovered),
+				"stack", string(debug.Stack()),
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+			)
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,192 @@
+//go:build test
+// +build test
+
+package sazabigin_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/sazabigin"
+	"go.uber.org/zap/zapcore"
+)
+
+// serve returns the entries logged while router handles a GET request to path.
+func serve(router *gin.Engine, path string) (*httptest.ResponseRecorder, []sazabi.CapturedEntry) {
+	c, stop := sazabi.StartCapture()
+	defer stop()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("User-Agent", "probe/1.0")
+	req.RemoteAddr = "203.0.113.7:51234"
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder, c.Entries()
+}
+
+// entry returns the entry with message msg among entries.
+func entry(t *testing.T, entries []sazabi.CapturedEntry, msg string) sazabi.CapturedEntry {
+	t.Helper()
+
+	for _, e := range entries {
+		if e.Message == msg {
+			return e
+		}
+	}
+	t.Fatalf("no %q entry among %v", msg, entries)
+	return sazabi.CapturedEntry{}
+}
+
+// value returns the value of the field key of e as encoded to JSON, nil when e has no
+// such field.
+func value(e sazabi.CapturedEntry, key string) interface{} {
+	f, ok := e.Field(key)
+	if !ok {
+		return nil
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return enc.Fields[key]
+}
+
+// newRouter returns a router using the middleware, with routes answering each status
+// class, a slow route and a panicking route.
+func newRouter(opts ...sazabigin.Option) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sazabigin.Logger(opts...), sazabigin.Recovery())
+	router.GET("/users/:id", func(c *gin.Context) {
+		sazabi.FromContext(c.Request.Context()).Infow("handler entry")
+		c.String(http.StatusOK, "alice")
+	})
+	router.GET("/moved", func(c *gin.Context) {
+		c.Redirect(http.StatusFound, "/users/1")
+	})
+	router.GET("/invalid", func(c *gin.Context) {
+		c.Error(errors.New("missing name"))
+		c.Error(errors.New("missing email"))
+		c.AbortWithStatus(http.StatusBadRequest)
+	})
+	router.GET("/unavailable", func(c *gin.Context) {
+		c.Status(http.StatusServiceUnavailable)
+	})
+	router.GET("/slow", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+	})
+	router.GET("/boom", func(c *gin.Context) {
+		panic("handler bug")
+	})
+	return router
+}
+
+func TestLogger(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	_, entries := serve(newRouter(), "/users/42")
+
+	access := entry(t, entries, sazabi.HTTPRequestMessage)
+	fields := map[string]interface{}{
+		"status":     int64(http.StatusOK),
+		"method":     http.MethodGet,
+		"path":       "/users/42",
+		"route":      "/users/:id",
+		"client_ip":  "203.0.113.7",
+		"bytes":      int64(len("alice")),
+		"user_agent": "probe/1.0",
+	}
+	for key, want := range fields {
+		if got := value(access, key); got != want {
+			t.Errorf("access entry field %q = %v (%T), want %v", key, got, got, want)
+		}
+	}
+	if access.Level != zapcore.InfoLevel {
+		t.Errorf("access entry at %v, want info", access.Level)
+	}
+	if _, ok := access.Dur("duration"); !ok {
+		t.Errorf("access entry fields = %v, want a duration", access.Fields)
+	}
+	if _, ok := access.Field(sazabigin.ErrorsKey); ok {
+		t.Errorf("access entry fields = %v, want no errors", access.Fields)
+	}
+	if handler := entry(t, entries, "handler entry"); value(handler, "route") != "/users/:id" || value(handler, "client_ip") != "203.0.113.7" {
+		t.Errorf("handler entry fields = %v, want the request fields", handler.Fields)
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	tests := []struct {
+		path string
+		opts []sazabigin.Option
+		want zapcore.Level
+	}{
+		{path: "/moved", want: zapcore.InfoLevel},
+		{path: "/invalid", want: zapcore.WarnLevel},
+		{path: "/unavailable", want: zapcore.ErrorLevel},
+		{path: "/slow", want: zapcore.InfoLevel},
+		{path: "/slow", opts: []sazabigin.Option{sazabigin.WithSlowThreshold(10 * time.Millisecond)}, want: zapcore.WarnLevel},
+		{path: "/users/1", opts: []sazabigin.Option{sazabigin.WithSlowThreshold(time.Minute)}, want: zapcore.InfoLevel},
+		{path: "/unavailable", opts: []sazabigin.Option{sazabigin.WithSlowThreshold(time.Nanosecond)}, want: zapcore.ErrorLevel},
+	}
+
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	for _, tt := range tests {
+		_, entries := serve(newRouter(tt.opts...), tt.path)
+		if got := entry(t, entries, sazabi.HTTPRequestMessage).Level; got != tt.want {
+			t.Errorf("access entry of %s with %d options at %v, want %v", tt.path, len(tt.opts), got, tt.want)
+		}
+	}
+}
+
+func TestLoggerErrors(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	_, entries := serve(newRouter(), "/invalid")
+
+	access := entry(t, entries, sazabi.HTTPRequestMessage)
+	errs, _ := value(access, sazabigin.ErrorsKey).([]interface{})
+	if len(errs) != 2 || errs[0] != "missing name" || errs[1] != "missing email" {
+		t.Errorf("%s = %#v, want the errors attached to the context", sazabigin.ErrorsKey, value(access, sazabigin.ErrorsKey))
+	}
+	if status, _ := access.Int("status"); status != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", status)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	recorder, entries := serve(newRouter(), "/boom")
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("response status = %d, want 500", recorder.Code)
+	}
+	recovered := entry(t, entries, sazabigin.PanicMessage)
+	if recovered.Level != zapcore.ErrorLevel || value(recovered, sazabi.HTTPPanicKey) != "handler bug" || value(recovered, "path") != "/boom" {
+		t.Errorf("panic entry = %+v, want an error with the panic value and path", recovered)
+	}
+	if stack, _ := recovered.Str("stack"); !strings.Contains(stack, "sazabigin_test.newRouter") {
+		t.Errorf("stack = %q, want the frames of the panicking handler", stack)
+	}
+	if access := entry(t, entries, sazabi.HTTPRequestMessage); access.Level != zapcore.ErrorLevel || value(access, "status") != int64(http.StatusInternalServerError) {
+		t.Errorf("access entry = %+v, want an error with status 500", access)
+	}
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sazabigin.Recovery())
+	router.GET("/", func(*gin.Context) {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler propagated", recovered)
+		}
+	}()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,309 @@
+// Package sazabigrpc provides gRPC interceptors logging calls through the global logger
+// of sazabi:
+//
+//	server := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(sazabigrpc.UnaryServerInterceptor()),
+//		grpc.ChainStreamInterceptor(sazabigrpc.StreamServerInterceptor()),
+//	)
+package sazabigrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ServerCallMessage is the message of the entries written by the server interceptors.
+const ServerCallMessage = "grpc server call"
+
+// ClientCallMessage is the message of the entries written by the client interceptors.
+const ClientCallMessage = "grpc client call"
+
+// PayloadMessage is the message of the entries enabled by WithPayloads.
+const PayloadMessage = "grpc payload"
+
+// PanicMessage is the message of the entries written for the panics recovered by the
+// server interceptors.
+const PanicMessage = "grpc panic recovered"
+
+// Option configures the interceptors.
+type Option func(*options)
+
+// options holds the settings collected from Option values.
+type options struct {
+	payloadBytes int // Size from which payloads are truncated, zero when not logged
+}
+
+// WithPayloads writes every message received and sent as a PayloadMessage entry at
+// Debug level, with method, direction ("received" or "sent") and the message rendered as
+// protojson under payload, truncated to maxBytes. Without this option, payloads are never
+// logged.
+func WithPayloads(maxBytes int) Option {
+	return func(o *options) {
+		o.payloadBytes = maxBytes
+	}
+}
+
+// newOptions returns the settings of opts.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// UnaryServerInterceptor returns an interceptor writing one ServerCallMessage entry per
+// call with method, code, duration and peer, and the error under sazabi.ErrorKey. OK
+// calls are written at Info level, calls failing with a code caused by the request, such
+// as InvalidArgument or NotFound, at Warn level, and the other failures, such as Internal
+// or Unavailable, at Error level. Handlers get a logger bound with method and peer
+// through sazabi.FromContext(ctx). Panics of the handler are recovered, written as a
+// PanicMessage entry with the panic value and the stack, and returned as Internal errors.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		start := time.Now()
+		l := serverLogger(ctx, info.FullMethod)
+		o.logPayload(l, "received", req)
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = recoverPanic(l, recovered)
+			}
+			if err == nil {
+				o.logPayload(l, "sent", resp)
+			}
+			logCall(l, ServerCallMessage, start, err)
+		}()
+		return handler(sazabi.NewContext(ctx, l), req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor behaving like UnaryServerInterceptor for
+// streaming calls, with one entry per call.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		start := time.Now()
+		l := serverLogger(ss.Context(), info.FullMethod)
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = recoverPanic(l, recovered)
+			}
+			logCall(l, ServerCallMessage, start, err)
+		}()
+		return handler(srv, &serverStream{
+			ServerStream: ss,
+			ctx:          sazabi.NewContext(ss.Context(), l),
+			log:          l,
+			o:            o,
+		})
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor writing one ClientCallMessage entry per
+// call with method, code, duration and peer, at the levels of UnaryServerInterceptor.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		start := time.Now()
+		var p peer.Peer
+		l := sazabi.With("method", method).(*zap.SugaredLogger)
+		o.logPayload(l, "sent", req)
+		err := invoker(ctx, method, req, reply, cc, append(callOpts, grpc.Peer(&p))...)
+		if err == nil {
+			o.logPayload(l, "received", reply)
+		}
+		if p.Addr != nil {
+			l = l.With("peer", p.Addr.String())
+		}
+		logCall(l, ClientCallMessage, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor writing one ClientCallMessage entry per
+// streaming call with method, code, duration and target, once the stream ends.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		l := sazabi.With("method", method, "target", cc.Target()).(*zap.SugaredLogger)
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			logCall(l, ClientCallMessage, start, err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, log: l, o: o, start: start, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// serverLogger returns the logger of a call to method received in ctx.
+func serverLogger(ctx context.Context, method string) *zap.SugaredLogger {
+	l := sazabi.With("method", method).(*zap.SugaredLogger)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		l = l.With("peer", p.Addr.String())
+	}
+	return l
+}
+
+// recoverPanic writes the PanicMessage entry of recovered and returns the error of the
+// call.
+func recoverPanic(l *zap.SugaredLogger, recovered interface{}) error {
+	l.Errorw(PanicMessage,
+		sazabi.HTTPPanicKey, fmt.Sprint(recovered),
+		"stack", string(debug.Stack()),
+	)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// logCall writes the msg entry of a call started at start and ending with err.
+func logCall(l *zap.SugaredLogger, msg string, start time.Time, err error) {
+	code := status.Code(err)
+	fields := []zap.Field{
+		zap.String("code", code.String()),
+		zap.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		fields = append(fields, zap.NamedError(sazabi.ErrorKey, err))
+	}
+	if ce := l.Desugar().Check(level(code), msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// level returns the level of the entry of a call ending with code.
+func level(code codes.Code) zapcore.Level {
+	switch code {
+	case codes.OK:
+		return zapcore.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return zapcore.WarnLevel
+	}
+	return zapcore.ErrorLevel
+}
+
+// logPayload writes the PayloadMessage entry of msg, going in direction, when payloads
+// are logged.
+func (o *options) logPayload(l *zap.SugaredLogger, direction string, msg interface{}) {
+	if o.payloadBytes <= 0 || msg == nil {
+		return
+	}
+	log := l.Desugar()
+	if ce := log.Check(zapcore.DebugLevel, PayloadMessage); ce != nil {
+		ce.Write(zap.String("direction", direction), zap.String("payload", o.render(msg)))
+	}
+}
+
+// render returns msg as protojson, or formatted with fmt when it is not a protobuf
+// message, truncated to the payload cap.
+func (o *options) render(msg interface{}) string {
+	var s string
+	if m, ok := msg.(proto.Message); ok {
+		data, err := protojson.Marshal(m)
+		if err != nil {
+			return fmt.Sprintf("!ERROR: %v", err)
+		}
+		s = string(data)
+	} else {
+		s = fmt.Sprint(msg)
+	}
+	if len(s) > o.payloadBytes {
+		s = s[:o.payloadBytes]
+	}
+	return s
+}
+
+// serverStream carries the request-scoped logger of a streaming call and logs its
+// payloads.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	log *zap.SugaredLogger
+	o   *options
+}
+
+// Context implements grpc.ServerStream.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// SendMsg implements grpc.ServerStream.
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.o.logPayload(s.log, "sent", m)
+	}
+	return err
+}
+
+// RecvMsg implements grpc.ServerStream.
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.o.logPayload(s.log, "received", m)
+	}
+	return err
+}
+
+// clientStream logs the payloads of a streaming call and its entry once it ends.
+type clientStream struct {
+	grpc.ClientStream
+	log           *zap.SugaredLogger
+	o             *options
+	start         time.Time
+	serverStreams bool // The server sends a stream of messages, ended by io.EOF
+	done          bool // The entry of the call was written
+}
+
+// SendMsg implements grpc.ClientStream.
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.o.logPayload(s.log, "sent", m)
+	}
+	return err
+}
+
+// RecvMsg implements grpc.ClientStream. The call ends with the first error, io.EOF
+// meaning success, or with the only message of a stream from the client.
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.o.logPayload(s.log, "received", m)
+		if !s.serverStreams {
+			s.end(nil)
+		}
+	case err == io.EOF:
+		s.end(nil)
+	default:
+		s.end(err)
+	}
+	return err
+}
+
+// end writes the entry of the call ending with err, once.
+func (s *clientStream) end(err error) {
+	if s.done {
+		return
+	}
+	s.done = true
+	logCall(s.log, ClientCallMessage, s.start, err)
+}
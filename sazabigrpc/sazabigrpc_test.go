@@ -0,0 +1,289 @@
+//go:build test
+// +build test
+
+package sazabigrpc_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/sazabigrpc"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthServer answers according to the requested service name: "missing" fails with
+// NotFound, "down" with Unavailable and "panic" panics.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+// check returns the response for service.
+func check(ctx context.Context, service string) (*healthpb.HealthCheckResponse, error) {
+	sazabi.FromContext(ctx).Infow("handler entry")
+	switch service {
+	case "missing":
+		return nil, status.Error(codes.NotFound, "unknown service")
+	case "down":
+		return nil, status.Error(codes.Unavailable, "backend down")
+	case "panic":
+		panic("handler bug")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+// Check implements healthpb.HealthServer.
+func (healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	return check(ctx, req.Service)
+}
+
+// Watch implements healthpb.HealthServer, sending the response twice.
+func (healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	for i := 0; i < 2; i++ {
+		resp, err := check(stream.Context(), req.Service)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dial starts a server with the server interceptors over bufconn and returns a client
+// using the client interceptors, both configured with opts.
+func dial(t *testing.T, opts ...sazabigrpc.Option) healthpb.HealthClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(sazabigrpc.UnaryServerInterceptor(opts...)),
+		grpc.ChainStreamInterceptor(sazabigrpc.StreamServerInterceptor(opts...)),
+	)
+	healthpb.RegisterHealthServer(server, healthServer{})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(sazabigrpc.UnaryClientInterceptor(opts...)),
+		grpc.WithChainStreamInterceptor(sazabigrpc.StreamClientInterceptor(opts...)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+// capture returns the entries logged by fn through the global logger.
+func capture(fn func()) []sazabi.CapturedEntry {
+	c, stop := sazabi.StartCapture()
+	fn()
+	stop()
+	return c.Entries()
+}
+
+// entries returns the entries with message msg.
+func entries(all []sazabi.CapturedEntry, msg string) []sazabi.CapturedEntry {
+	var found []sazabi.CapturedEntry
+	for _, e := range all {
+		if e.Message == msg {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// entry returns the only entry with message msg.
+func entry(t *testing.T, all []sazabi.CapturedEntry, msg string) sazabi.CapturedEntry {
+	t.Helper()
+
+	found := entries(all, msg)
+	if len(found) != 1 {
+		t.Fatalf("got %d %q entries among %v, want 1", len(found), msg, all)
+	}
+	return found[0]
+}
+
+// str returns the string field key of e.
+func str(e sazabi.CapturedEntry, key string) string {
+	s, _ := e.Str(key)
+	return s
+}
+
+func TestUnary(t *testing.T) {
+	tests := []struct {
+		service string
+		code    codes.Code
+		level   zapcore.Level
+	}{
+		{service: "", code: codes.OK, level: zapcore.InfoLevel},
+		{service: "missing", code: codes.NotFound, level: zapcore.WarnLevel},
+		{service: "down", code: codes.Unavailable, level: zapcore.ErrorLevel},
+	}
+
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	client := dial(t)
+	for _, tt := range tests {
+		var err error
+		logged := capture(func() {
+			_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: tt.service})
+		})
+		if status.Code(err) != tt.code {
+			t.Errorf("Check(%q) error = %v, want %v", tt.service, err, tt.code)
+		}
+
+		for _, msg := range []string{sazabigrpc.ServerCallMessage, sazabigrpc.ClientCallMessage} {
+			call := entry(t, logged, msg)
+			if call.Level != tt.level || str(call, "code") != tt.code.String() || str(call, "method") != healthpb.Health_Check_FullMethodName {
+				t.Errorf("%s entry of %q = %v %v, want %v with code %v", msg, tt.service, call.Level, call.Fields, tt.level, tt.code)
+			}
+			if str(call, "peer") == "" {
+				t.Errorf("%s entry of %q fields = %v, want the peer", msg, tt.service, call.Fields)
+			}
+			if _, ok := call.Dur("duration"); !ok {
+				t.Errorf("%s entry of %q fields = %v, want a duration", msg, tt.service, call.Fields)
+			}
+			if err := call.Err(); (err != nil) != (tt.code != codes.OK) {
+				t.Errorf("%s entry of %q error = %v, want one only for failures", msg, tt.service, err)
+			}
+		}
+		if handler := entry(t, logged, "handler entry"); str(handler, "method") != healthpb.Health_Check_FullMethodName {
+			t.Errorf("handler entry fields = %v, want the method", handler.Fields)
+		}
+	}
+}
+
+func TestUnaryPanic(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	client := dial(t)
+
+	var err error
+	logged := capture(func() {
+		_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "panic"})
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Check error = %v, want Internal", err)
+	}
+	recovered := entry(t, logged, sazabigrpc.PanicMessage)
+	if recovered.Level != zapcore.ErrorLevel || str(recovered, sazabi.HTTPPanicKey) != "handler bug" {
+		t.Errorf("panic entry = %+v, want an error with the panic value", recovered)
+	}
+	if !strings.Contains(str(recovered, "stack"), "sazabigrpc_test.check") {
+		t.Errorf("stack = %q, want the frames of the panicking handler", str(recovered, "stack"))
+	}
+	if call := entry(t, logged, sazabigrpc.ServerCallMessage); call.Level != zapcore.ErrorLevel || str(call, "code") != codes.Internal.String() {
+		t.Errorf("call entry = %+v, want an Internal error", call)
+	}
+}
+
+// watch calls Watch for service and reads the stream until it ends.
+func watch(t *testing.T, client healthpb.HealthClient, service string) (received int, err error) {
+	t.Helper()
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return received, err
+		}
+		received++
+	}
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		service string
+		code    codes.Code
+		level   zapcore.Level
+	}{
+		{service: "", code: codes.OK, level: zapcore.InfoLevel},
+		{service: "missing", code: codes.NotFound, level: zapcore.WarnLevel},
+		{service: "panic", code: codes.Internal, level: zapcore.ErrorLevel},
+	}
+
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	client := dial(t)
+	for _, tt := range tests {
+		var err error
+		logged := capture(func() {
+			_, err = watch(t, client, tt.service)
+		})
+		if status.Code(err) != tt.code {
+			t.Errorf("Watch(%q) error = %v, want %v", tt.service, err, tt.code)
+		}
+		for _, msg := range []string{sazabigrpc.ServerCallMessage, sazabigrpc.ClientCallMessage} {
+			call := entry(t, logged, msg)
+			if call.Level != tt.level || str(call, "code") != tt.code.String() || str(call, "method") != healthpb.Health_Watch_FullMethodName {
+				t.Errorf("%s entry of %q = %v %v, want %v with code %v", msg, tt.service, call.Level, call.Fields, tt.level, tt.code)
+			}
+		}
+		if tt.code == codes.Internal {
+			entry(t, logged, sazabigrpc.PanicMessage)
+		}
+	}
+}
+
+func TestPayloads(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+	if err := sazabi.SetLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+	client := dial(t)
+	logged := capture(func() {
+		client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "example"})
+	})
+	if payloads := entries(logged, sazabigrpc.PayloadMessage); len(payloads) != 0 {
+		t.Errorf("got %d payload entries without WithPayloads, want none", len(payloads))
+	}
+
+	client = dial(t, sazabigrpc.WithPayloads(12))
+	logged = capture(func() {
+		client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "example"})
+		watch(t, client, "")
+	})
+	// protojson varies its whitespace, and the entries of both ends of the stream
+	// interleave.
+	renderings := []string{`{"service":"example"}`, `{}`, `{"status":"SERVING"}`}
+	counts := make(map[string]int)
+	for _, e := range entries(logged, sazabigrpc.PayloadMessage) {
+		payload := str(e, "payload")
+		if e.Level != zapcore.DebugLevel || len(payload) > 12 {
+			t.Errorf("payload entry at %v with %q, want debug and at most 12 bytes", e.Level, payload)
+		}
+		rendered := false
+		for _, r := range renderings {
+			rendered = rendered || strings.HasPrefix(r, strings.ReplaceAll(payload, " ", ""))
+		}
+		if !rendered {
+			t.Errorf("payload = %q, want the beginning of a message as protojson", payload)
+		}
+		counts[str(e, "method")[len("/grpc.health.v1.Health/"):]+" "+str(e, "direction")]++
+	}
+	want := map[string]int{"Check sent": 2, "Check received": 2, "Watch sent": 3, "Watch received": 3}
+	for key, n := range want {
+		if counts[key] != n {
+			t.Errorf("got %d %q payload entries, want %d", counts[key], key, n)
+		}
+	}
+	if len(counts) != len(want) {
+		t.Errorf("payload entries = %v, want %v", counts, want)
+	}
+}
@@ -0,0 +1,41 @@
+// Package sazabitest helps testing code that logs with sazabi.
+//
+// TestContext gives each test a logger of its own, carried by a context, so that
+// parallel tests capture their own entries without touching the global logger:
+//
+//	func TestHandler(t *testing.T) {
+//		t.Parallel()
+//		ctx, capture := sazabitest.TestContext(t)
+//		handle(ctx, req)
+//		entries := capture.Entries()
+//		...
+//	}
+package sazabitest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// TestContext returns a context carrying a logger of its own, and the Capture recording
+// every entry it writes at Debug level and above. Code under test logging through the
+// Ctx functions or sazabi.FromContext writes to it instead of the global logger, which
+// need not be initialized. The context is canceled when the test ends, and the captured
+// entries are added to the test log if the test failed.
+func TestContext(t testing.TB) (context.Context, *sazabi.Capture) {
+	log, capture := sazabi.NewCapture()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		if !t.Failed() {
+			return
+		}
+		for _, e := range capture.Entries() {
+			t.Logf("%s\t%s\t%s\t%d fields", e.Time.Format("15:04:05.000"), e.Level.CapitalString(), e.Message, len(e.Fields))
+		}
+	})
+	return sazabi.NewContext(ctx, log), capture
+}
@@ -0,0 +1,60 @@
+//go:build test
+// +build test
+
+package sazabitest_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/sazabitest"
+)
+
+// handle stands for code under test, logging through its context.
+func handle(ctx context.Context, name string, n int) {
+	for i := 0; i < n; i++ {
+		sazabi.InfoCtx(ctx, "handled by "+name, "i", i)
+		sazabi.FromContext(ctx).Debugw("details of " + name)
+	}
+}
+
+func TestTestContextIsolation(t *testing.T) {
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("sub%d", i)
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ctx, capture := sazabitest.TestContext(t)
+
+			handle(ctx, name, 50)
+
+			entries := capture.Entries()
+			if len(entries) != 100 {
+				t.Fatalf("captured %d entries, want 100", len(entries))
+			}
+			for _, e := range entries {
+				if e.Message != "handled by "+name && e.Message != "details of "+name {
+					t.Fatalf("captured entry %q of another subtest", e.Message)
+				}
+			}
+			if file := filepath.Base(entries[0].Caller.File); file != "sazabitest_test.go" {
+				t.Errorf("caller = %s, want the code under test", entries[0].Caller)
+			}
+		})
+	}
+}
+
+func TestTestContextCanceled(t *testing.T) {
+	var ctx context.Context
+	t.Run("sub", func(t *testing.T) {
+		ctx, _ = sazabitest.TestContext(t)
+		if ctx.Err() != nil {
+			t.Fatal("context canceled during the test")
+		}
+	})
+	if ctx.Err() == nil {
+		t.Error("context not canceled at the end of the test")
+	}
+}
@@ -0,0 +1,147 @@
+package sazabi
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// ScopeMisuseMessage is the message of the panic raised, in builds with the race
+// detector or the test tag, when the done function returned by Scoped is called from
+// another goroutine than the one that opened the scope.
+const ScopeMisuseMessage = "sazabi: scope closed from another goroutine"
+
+// activeScopes counts the scopes opened and not yet closed, on every goroutine. The
+// package functions only look up the calling goroutine's scopes while it is positive.
+var activeScopes int32
+
+// scopes holds the open scopes of each goroutine, by goroutine ID.
+var scopes = struct {
+	sync.Mutex
+	stacks map[uint64]*scopeStack
+}{stacks: make(map[uint64]*scopeStack)}
+
+// scopeStack is the stack of open scopes of a goroutine, innermost last.
+type scopeStack struct {
+	frames []*scopeFrame
+}
+
+// scopeFrame is an open scope. Its loggers are derived from the instance in use when
+// the scope is first logged through, and derived again after a re-initialization.
+type scopeFrame struct {
+	fields []zap.Field // Fields of the scope and of the enclosing ones, inner shadowing outer
+	in     *instance   // Instance the loggers were derived from
+	sugar  *zap.SugaredLogger
+	typed  *zap.Logger
+}
+
+// Scoped adds the key-value pairs to the entries logged through the package functions
+// by the calling goroutine until done is called. Scopes nest: the pairs of the
+// enclosing scopes are kept, and a key opened again shadows the outer value until the
+// inner scope is done. Pairs follow the conventions of Infow.
+//
+// Scopes belong to the goroutine that opened them: other goroutines, including those
+// started inside the scope, are unaffected, and done must be called by the same
+// goroutine, typically with defer. Builds with the race detector or the test tag panic
+// with ScopeMisuseMessage when it is not. Calling done again is harmless; calling the
+// done function of an outer scope also closes the scopes opened inside it. While any
+// scope is open, each package function call identifies its goroutine from the stack,
+// which costs about a microsecond.
+func Scoped(keysValues ...interface{}) (done func()) {
+	owner := goroutineID()
+
+	scopes.Lock()
+	stack := scopes.stacks[owner]
+	if stack == nil {
+		stack = &scopeStack{}
+		scopes.stacks[owner] = stack
+	}
+	var outer []zap.Field
+	if n := len(stack.frames); n > 0 {
+		outer = stack.frames[n-1].fields
+	}
+	frame := &scopeFrame{fields: shadowFields(outer, pairFields(keysValues))}
+	stack.frames = append(stack.frames, frame)
+	atomic.AddInt32(&activeScopes, 1)
+	scopes.Unlock()
+
+	var once sync.Once
+	return func() {
+		if scopeOwnerCheck && goroutineID() != owner {
+			panic(ScopeMisuseMessage)
+		}
+		once.Do(func() { closeScope(owner, stack, frame) })
+	}
+}
+
+// closeScope removes frame, and the frames opened after it, from the stack of owner.
+func closeScope(owner uint64, stack *scopeStack, frame *scopeFrame) {
+	scopes.Lock()
+	defer scopes.Unlock()
+
+	for i, f := range stack.frames {
+		if f == frame {
+			atomic.AddInt32(&activeScopes, -int32(len(stack.frames)-i))
+			stack.frames = stack.frames[:i]
+			break
+		}
+	}
+	if len(stack.frames) == 0 && scopes.stacks[owner] == stack {
+		delete(scopes.stacks, owner)
+	}
+}
+
+// scopedFrame returns the innermost scope of the calling goroutine, with its loggers
+// derived from in, or nil when the goroutine has no open scope.
+func scopedFrame(in *instance) *scopeFrame {
+	if atomic.LoadInt32(&activeScopes) == 0 {
+		return nil
+	}
+	id := goroutineID()
+
+	scopes.Lock()
+	defer scopes.Unlock()
+
+	stack := scopes.stacks[id]
+	if stack == nil || len(stack.frames) == 0 {
+		return nil
+	}
+	frame := stack.frames[len(stack.frames)-1]
+	if frame.in != in {
+		frame.in = in
+		frame.typed = in.typed.With(frame.fields...)
+		frame.sugar = in.sugar.Desugar().With(frame.fields...).Sugar()
+	}
+	return frame
+}
+
+// shadowFields returns the fields of outer whose key is not in inner, followed by inner.
+func shadowFields(outer, inner []zap.Field) []zap.Field {
+	keys := make(map[string]struct{}, len(inner))
+	for _, f := range inner {
+		keys[f.Key] = struct{}{}
+	}
+	fields := make([]zap.Field, 0, len(outer)+len(inner))
+	for _, f := range outer {
+		if _, ok := keys[f.Key]; !ok {
+			fields = append(fields, f)
+		}
+	}
+	return append(fields, inner...)
+}
+
+// goroutineID returns the ID of the calling goroutine, read from the header of its stack
+// trace ("goroutine 42 [running]:").
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
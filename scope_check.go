@@ -0,0 +1,7 @@
+//go:build race || test
+// +build race test
+
+package sazabi
+
+// scopeOwnerCheck makes the done function returned by Scoped verify its goroutine.
+const scopeOwnerCheck = true
@@ -0,0 +1,129 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestScopedNesting(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName)
+
+	doneOuter := sazabi.Scoped("request", "r1", "user", "alice")
+	sazabi.Info("outer entry")
+	doneInner := sazabi.Scoped("user", "bob", "step", 2)
+	sazabi.Infow("inner entry", "extra", true)
+	sazabi.InfoFields("typed inner entry")
+	doneInner()
+	sazabi.Info("after inner")
+	doneOuter()
+	sazabi.Info("after outer")
+
+	output := read()
+	tests := []struct {
+		msg  string
+		want map[string]interface{}
+	}{
+		{"outer entry", map[string]interface{}{"request": "r1", "user": "alice"}},
+		{"inner entry", map[string]interface{}{"request": "r1", "user": "bob", "step": float64(2), "extra": true}},
+		{"typed inner entry", map[string]interface{}{"request": "r1", "user": "bob", "step": float64(2)}},
+		{"after inner", map[string]interface{}{"request": "r1", "user": "alice"}},
+		{"after outer", map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		fields := entryFields(t, output, tt.msg)
+		if len(fields) != len(tt.want) {
+			t.Errorf("%s: fields = %v, want %v", tt.msg, fields, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if fields[k] != v {
+				t.Errorf("%s: %s = %v, want %v", tt.msg, k, fields[k], v)
+			}
+		}
+	}
+}
+
+func TestScopedOtherGoroutines(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName)
+
+	done := sazabi.Scoped("request", "r1")
+	defer done()
+	logged := make(chan struct{})
+	go func() {
+		defer close(logged)
+		sazabi.Info("other goroutine")
+	}()
+	<-logged
+
+	if fields := entryFields(t, read(), "other goroutine"); len(fields) != 0 {
+		t.Errorf("fields = %v, want the scope of another goroutine ignored", fields)
+	}
+}
+
+func TestScopedMisuse(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName)
+
+	done := sazabi.Scoped("request", "r1")
+	recovered := make(chan interface{})
+	go func() {
+		defer func() { recovered <- recover() }()
+		done()
+	}()
+	if r := <-recovered; r != sazabi.ScopeMisuseMessage {
+		t.Errorf("done() from another goroutine panicked with %v, want %q", r, sazabi.ScopeMisuseMessage)
+	}
+
+	sazabi.Info("still scoped")
+	done()
+	done() // Closing twice is harmless
+	sazabi.Info("unscoped")
+
+	output := read()
+	if fields := entryFields(t, output, "still scoped"); fields["request"] != "r1" {
+		t.Errorf("fields = %v, want the scope kept after the misuse", fields)
+	}
+	if fields := entryFields(t, output, "unscoped"); len(fields) != 0 {
+		t.Errorf("fields = %v, want none once the scope is done", fields)
+	}
+}
+
+func TestScopedOuterDoneClosesInner(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName)
+
+	doneOuter := sazabi.Scoped("request", "r1")
+	doneInner := sazabi.Scoped("step", 1)
+	doneOuter()
+	sazabi.Info("closed")
+	doneInner()
+	sazabi.Info("still closed")
+
+	output := read()
+	for _, msg := range []string{"closed", "still closed"} {
+		if fields := entryFields(t, output, msg); len(fields) != 0 {
+			t.Errorf("%s: fields = %v, want none", msg, fields)
+		}
+	}
+}
+
+func TestScopedReinitialize(t *testing.T) {
+	restoreDefault(t)
+	initializeFile(t, sazabi.ProductionEnvName)
+
+	done := sazabi.Scoped("request", "r1")
+	defer done()
+	sazabi.Info("before")
+	read := initializeFile(t, "development")
+	sazabi.Info("after")
+
+	if fields := entryFields(t, read(), "after"); fields["request"] != "r1" {
+		t.Errorf("fields = %v, want the scope applied to the new logger", fields)
+	}
+}
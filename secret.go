@@ -0,0 +1,49 @@
+package sazabi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Secret is a string that never appears in log output, such as a password or an API
+// token. Every way of rendering it, whether by an encoder, fmt verbs including %#v, or
+// JSON and text marshaling, produces "[REDACTED:len=N]", where N is the length of the
+// value in bytes. Declaring sensitive values as Secret makes leaking them through a log
+// call a deliberate act: Reveal returns the value.
+//
+// Like any string, a Secret stored in an unexported struct field is printed raw by fmt,
+// which cannot call methods on unexported fields; keep secrets in exported fields or
+// log them directly.
+type Secret string
+
+// Reveal returns the value of the secret, for code that genuinely needs it.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// String implements fmt.Stringer without revealing the value.
+func (s Secret) String() string {
+	return "[REDACTED:len=" + strconv.Itoa(len(s)) + "]"
+}
+
+// GoString implements fmt.GoStringer without revealing the value.
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+// Format implements fmt.Formatter, so that every verb renders the redacted form.
+func (s Secret) Format(f fmt.State, _ rune) {
+	io.WriteString(f, s.String())
+}
+
+// MarshalJSON implements json.Marshaler without revealing the value.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// MarshalText implements encoding.TextMarshaler without revealing the value.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
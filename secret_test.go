@@ -0,0 +1,108 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+const (
+	secretValue    = "hunter2"
+	secretRedacted = "[REDACTED:len=7]"
+)
+
+// credentials is a struct dumped whole by the tests.
+type credentials struct {
+	User     string
+	Password sazabi.Secret
+}
+
+func TestSecretLoggingPaths(t *testing.T) {
+	secret := sazabi.Secret(secretValue)
+	creds := credentials{User: "alice", Password: secret}
+
+	paths := map[string]func(){
+		"sugared":         func() { sazabi.Info("login ", secret) },
+		"w-style":         func() { sazabi.Infow("login", "password", secret) },
+		"f-style %v":      func() { sazabi.Infof("login %v", secret) },
+		"f-style %s":      func() { sazabi.Infof("login %s", secret) },
+		"f-style %q":      func() { sazabi.Infof("login %q", secret) },
+		"f-style %x":      func() { sazabi.Infof("login %x", secret) },
+		"f-style %d":      func() { sazabi.Infof("login %d", secret) },
+		"f-style %#v":     func() { sazabi.Infof("login %#v", secret) },
+		"typed F":         func() { sazabi.InfoFields("login", sazabi.F("password", secret)) },
+		"typed zap.Any":   func() { sazabi.InfoFields("login", zap.Any("password", secret)) },
+		"typed Reflect":   func() { sazabi.InfoFields("login", zap.Reflect("password", secret)) },
+		"struct w-style":  func() { sazabi.Infow("login", "credentials", creds) },
+		"struct f-style":  func() { sazabi.Infof("login %+v", creds) },
+		"struct f-go":     func() { sazabi.Infof("login %#v", creds) },
+		"struct pointer":  func() { sazabi.Infow("login", "credentials", &creds) },
+		"struct map":      func() { sazabi.Infow("login", "secrets", map[string]sazabi.Secret{"db": secret}) },
+		"struct map keys": func() { sazabi.Infow("login", "secrets", map[sazabi.Secret]int{secret: 1}) },
+	}
+	for name, log := range paths {
+		t.Run(name, func(t *testing.T) {
+			read := initializeFile(t, sazabi.ProductionEnvName)
+			log()
+			output := read()
+			if strings.Contains(output, secretValue) {
+				t.Errorf("output reveals the secret: %q", output)
+			}
+			if !strings.Contains(output, secretRedacted) {
+				t.Errorf("output = %q, want %s", output, secretRedacted)
+			}
+		})
+	}
+}
+
+func TestSecretJSONEncoding(t *testing.T) {
+	log, read := newFileLogger(t, "development", sazabi.WithCIEncoding("json"), sazabi.WithInteractive(false))
+
+	log.Infow("login", "password", sazabi.Secret(secretValue))
+
+	output := read()
+	if strings.Contains(output, secretValue) {
+		t.Errorf("output reveals the secret: %q", output)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(output), &entry); err != nil {
+		t.Fatalf("output %q: %v", output, err)
+	}
+	if entry["password"] != secretRedacted {
+		t.Errorf("password = %v, want %s", entry["password"], secretRedacted)
+	}
+}
+
+func TestSecretFormatting(t *testing.T) {
+	secret := sazabi.Secret(secretValue)
+
+	for _, s := range []string{
+		secret.String(),
+		fmt.Sprint(secret),
+		fmt.Sprintf("%v|%+v|%#v|%s|%q|%x|%X|%d|%10s", secret, secret, secret, secret, secret, secret, secret, secret, secret),
+		fmt.Sprintf("%+v|%#v", credentials{Password: secret}, credentials{Password: secret}),
+	} {
+		if strings.Contains(s, secretValue) {
+			t.Errorf("%q reveals the secret", s)
+		}
+	}
+
+	data, err := json.Marshal(credentials{Password: secret})
+	if err != nil || strings.Contains(string(data), secretValue) {
+		t.Errorf("json.Marshal() = %s, %v, want the redacted form", data, err)
+	}
+	if text, _ := secret.MarshalText(); string(text) != secretRedacted {
+		t.Errorf("MarshalText() = %s, want %s", text, secretRedacted)
+	}
+	if secret.Reveal() != secretValue {
+		t.Errorf("Reveal() = %q, want %q", secret.Reveal(), secretValue)
+	}
+}
@@ -0,0 +1,63 @@
+package sazabi
+
+import (
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SelfTestKey is the key of the marker carried by the entries of EmitTestEntries.
+const SelfTestKey = "selftest"
+
+// SelfTestSummaryMessage is the message of the last entry written by EmitTestEntries.
+const SelfTestSummaryMessage = "selftest complete"
+
+// SelfTestEntry describes an entry written by EmitTestEntries.
+type SelfTestEntry struct {
+	Level   zapcore.Level // Level of the entry
+	Message string        // Message of the entry
+	Fields  []Field       // Fields of the entry, the marker first
+}
+
+// selfTestLevels are the levels of the entries written by EmitTestEntries.
+var selfTestLevels = []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel}
+
+// EmitTestEntries writes one entry per level from Debug to Error through the global
+// logger, to verify delivery after changing a pipeline. Each carries marker under
+// SelfTestKey and fields of representative types: string, integer, float, duration,
+// error, nested object and non-ASCII text. A SelfTestSummaryMessage entry at Info level
+// then gives the count of entries written before it.
+//
+// The entries bypass sampling and error escalation, but not the level: entries below
+// it are not written. The entries actually written are returned, in order, to be
+// compared with what the aggregator received.
+func EmitTestEntries(marker string) []SelfTestEntry {
+	log := unsampledLogger().Desugar()
+	var written []SelfTestEntry
+	emit := func(level zapcore.Level, msg string, fields []Field) {
+		if ce := log.Check(level, msg); ce != nil {
+			ce.Write(fields...)
+			written = append(written, SelfTestEntry{Level: level, Message: msg, Fields: fields})
+		}
+	}
+
+	for _, level := range selfTestLevels {
+		emit(level, "selftest "+level.String(), []Field{
+			zap.String(SelfTestKey, marker),
+			zap.String("string", "value"),
+			zap.Int("int", 42),
+			zap.Float64("float", 3.14),
+			zap.Duration("duration", 1500*time.Millisecond),
+			zap.Error(errors.New("selftest error")),
+			zap.Dict("group", zap.String("name", "nested"), zap.Int("depth", 1)),
+			zap.String("unicode", "héllo 世界 ✓"),
+		})
+	}
+	emit(zapcore.InfoLevel, SelfTestSummaryMessage, []Field{
+		zap.String(SelfTestKey, marker),
+		zap.Int("count", len(written)),
+	})
+	return written
+}
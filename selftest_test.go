@@ -0,0 +1,72 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestEmitTestEntries(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, "development")
+
+	entries := sazabi.EmitTestEntries("run-1")
+
+	want := []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel, zapcore.InfoLevel}
+	if len(entries) != len(want) {
+		t.Fatalf("EmitTestEntries() returned %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, e := range entries {
+		if e.Level != want[i] {
+			t.Errorf("entry %d level = %s, want %s", i, e.Level, want[i])
+		}
+	}
+
+	output := read()
+	for _, e := range entries[:4] {
+		fields := entryFields(t, output, e.Message)
+		if fields[sazabi.SelfTestKey] != "run-1" {
+			t.Errorf("%s: %s = %v, want the marker", e.Message, sazabi.SelfTestKey, fields[sazabi.SelfTestKey])
+		}
+		group, _ := fields["group"].(map[string]interface{})
+		if fields["string"] != "value" || fields["int"] != float64(42) || fields["float"] != 3.14 ||
+			fields["duration"] != "1.5s" || fields["error"] != "selftest error" ||
+			group["name"] != "nested" || fields["unicode"] != "héllo 世界 ✓" {
+			t.Errorf("%s: fields = %v, want every representative type", e.Message, fields)
+		}
+	}
+	summary := entryFields(t, output, sazabi.SelfTestSummaryMessage)
+	if summary[sazabi.SelfTestKey] != "run-1" || summary["count"] != float64(4) {
+		t.Errorf("summary fields = %v, want the marker and a count of 4", summary)
+	}
+}
+
+func TestEmitTestEntriesExempt(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithErrorEscalation(2, time.Minute))
+
+	const runs = 150
+	for i := 0; i < runs; i++ {
+		entries := sazabi.EmitTestEntries("exempt")
+		if len(entries) != 4 || entries[0].Level != zapcore.InfoLevel {
+			t.Fatalf("EmitTestEntries() = %+v, want the entries from Info level", entries)
+		}
+	}
+
+	output := read()
+	for _, msg := range []string{"selftest info", "selftest warn", "selftest error", sazabi.SelfTestSummaryMessage} {
+		if n := strings.Count(output, "\t"+msg+"\t"); n != runs {
+			t.Errorf("%q written %d times, want %d despite sampling and escalation", msg, n, runs)
+		}
+	}
+	if strings.Contains(output, "selftest debug") || strings.Contains(output, sazabi.EscalatedKey) {
+		t.Errorf("output carries debug entries or escalations:\n%s", output)
+	}
+}
@@ -0,0 +1,346 @@
+package sazabi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Names of the batch compression algorithms. Only gzip is built in; snappy and zstd
+// are registered by importing the compresslog module.
+const (
+	CompressionGzip   = "gzip"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// Batching of network outputs.
+const (
+	batchMaxBytes        = 64 << 10    // Size at which a batch is sent immediately
+	batchInterval        = time.Second // Maximum time an entry waits in a batch
+	retryMaxBytes        = 1 << 20     // Size of the batches kept while the collector fails
+	compressionThreshold = 1 << 10     // Batches below this size are sent uncompressed
+)
+
+// compressors maps compression names to their implementation.
+var compressors = struct {
+	sync.RWMutex
+	m map[string]func([]byte) ([]byte, error)
+}{m: map[string]func([]byte) ([]byte, error){CompressionGzip: gzipCompress}}
+
+// RegisterCompressor makes a batch compression algorithm available to
+// WithBatchCompression under name.
+func RegisterCompressor(name string, compress func([]byte) ([]byte, error)) {
+	compressors.Lock()
+	defer compressors.Unlock()
+
+	compressors.m[name] = compress
+}
+
+// compressor returns the compression algorithm registered under name.
+func compressor(name string) (func([]byte) ([]byte, error), error) {
+	compressors.RLock()
+	defer compressors.RUnlock()
+
+	compress, ok := compressors.m[name]
+	if !ok {
+		return nil, fmt.Errorf("sazabi: unknown batch compression %q", name)
+	}
+	return compress, nil
+}
+
+// WithBatchCompression compresses the batches sent by network outputs with the named
+// algorithm (CompressionGzip, or CompressionSnappy and CompressionZstd once compresslog
+// is imported). Each protocol declares the algorithm its own way; batches under 1 KiB,
+// or that compression would not shrink, are sent uncompressed.
+func WithBatchCompression(name string) Option {
+	return func(o *options) {
+		o.batchCompression = name
+		o.integrations = append(o.integrations, integration{
+			name:     "batch_compression",
+			settings: map[string]string{"algorithm": name},
+		})
+	}
+}
+
+// gzipCompress compresses data with gzip.
+func gzipCompress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// shipper is the WriteSyncer shared by network outputs. It batches encoded entries and
+// sends each batch, compressed when configured, with the send function of the output.
+// Sending happens in the background, outside the lock taken by Write, so that a slow or
+// unreachable collector never blocks the goroutines logging. Batches that fail to send
+// are kept and retried first, up to retryMaxBytes; older ones are then dropped to the
+// fallback output, if any. Failures are reported to the internal error output.
+type shipper struct {
+	name        string                                         // Redacted URL of the output, for error reports
+	send        func(payload []byte, compression string) error // Sends one batch
+	compression string                                         // Configured algorithm, empty for none
+	compress    func([]byte) ([]byte, error)
+	interval    time.Duration       // Maximum time an entry waits in a batch
+	errorOutput zapcore.WriteSyncer // Receives send failures
+	fallback    zapcore.WriteSyncer // Receives dropped batches, nil to discard them
+
+	sendMu sync.Mutex // Serializes sends; never held with mu while sending
+
+	mu       sync.Mutex
+	batch    []byte      // Encoded entries waiting to be queued
+	queue    [][]byte    // Batches waiting to be sent, oldest first
+	queued   int         // Size of the batches in queue
+	timer    *time.Timer // Flushes the batch after interval, nil when none is scheduled
+	flushing bool        // Whether a background flush is running
+}
+
+// newShipper returns a shipper named name sending batches with send, compressed with
+// the named algorithm unless compression is empty. Send failures are reported to
+// errorOutput and dropped batches written to fallback, unless it is nil.
+func newShipper(name string, send func([]byte, string) error, compression string, errorOutput, fallback zapcore.WriteSyncer) (*shipper, error) {
+	s := &shipper{
+		name:        name,
+		send:        send,
+		compression: compression,
+		interval:    batchInterval,
+		errorOutput: errorOutput,
+		fallback:    fallback,
+	}
+	if compression != "" {
+		compress, err := compressor(compression)
+		if err != nil {
+			return nil, err
+		}
+		s.compress = compress
+	}
+	return s, nil
+}
+
+// Write implements zapcore.WriteSyncer. The entry is sent in the background with its
+// batch, so Write never fails: send failures are reported to the internal error output.
+func (s *shipper) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batch = append(s.batch, p...)
+	if len(s.batch) >= batchMaxBytes {
+		s.flushLocked()
+	} else if s.timer == nil {
+		s.timer = time.AfterFunc(s.interval, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.flushLocked()
+		})
+	}
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer, sending the pending batches. It returns the
+// error of the first batch that failed to send, which stays queued.
+func (s *shipper) Sync() error {
+	s.mu.Lock()
+	dropped := s.enqueueLocked()
+	s.mu.Unlock()
+	s.drop(dropped)
+
+	return s.sendQueued()
+}
+
+// flushLocked queues the pending batch and starts sending the queue in the background,
+// unless a background flush is already running. s.mu must be held.
+func (s *shipper) flushLocked() {
+	dropped := s.enqueueLocked()
+	if !s.flushing {
+		s.flushing = true
+		go func() {
+			s.drop(dropped)
+			s.report(s.sendQueued())
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.flushing = false
+		}()
+		return
+	}
+	if len(dropped) > 0 {
+		go s.drop(dropped)
+	}
+}
+
+// enqueueLocked moves the pending batch to the queue, and returns the batches dropped
+// to keep the queue within retryMaxBytes. s.mu must be held.
+func (s *shipper) enqueueLocked() [][]byte {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if len(s.batch) > 0 {
+		s.queue = append(s.queue, s.batch)
+		s.queued += len(s.batch)
+		s.batch = nil
+	}
+	return s.trimLocked()
+}
+
+// trimLocked drops the oldest batches of the queue while it exceeds retryMaxBytes, and
+// returns them. The newest batch is always kept. s.mu must be held.
+func (s *shipper) trimLocked() [][]byte {
+	var dropped [][]byte
+	for s.queued > retryMaxBytes && len(s.queue) > 1 {
+		dropped = append(dropped, s.queue[0])
+		s.queued -= len(s.queue[0])
+		s.queue = s.queue[1:]
+	}
+	return dropped
+}
+
+// sendQueued sends the queued batches, oldest first. A batch that fails to send is put
+// back at the front of the queue, and its error returned.
+func (s *shipper) sendQueued() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		batch := s.queue[0]
+		s.queue = s.queue[1:]
+		s.queued -= len(batch)
+		s.mu.Unlock()
+
+		payload, compression, err := s.encodeBatch(batch)
+		if err == nil {
+			err = s.send(payload, compression)
+		}
+		if err != nil {
+			s.mu.Lock()
+			s.queue = append([][]byte{batch}, s.queue...)
+			s.queued += len(batch)
+			dropped := s.trimLocked()
+			s.mu.Unlock()
+			s.drop(dropped)
+			return err
+		}
+	}
+}
+
+// drop writes the batches dropped from the queue to the fallback output, and reports
+// their loss when there is none.
+func (s *shipper) drop(batches [][]byte) {
+	if len(batches) == 0 {
+		return
+	}
+	size := 0
+	for _, batch := range batches {
+		size += len(batch)
+		if s.fallback != nil {
+			s.fallback.Write(batch)
+		}
+	}
+	if s.fallback == nil {
+		fmt.Fprintf(s.errorOutput, "%v output %s: retry buffer full, %d bytes dropped\n", time.Now(), s.name, size)
+		s.errorOutput.Sync()
+	}
+}
+
+// report writes err, a failure to send queued batches, to the internal error output.
+func (s *shipper) report(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	queued := s.queued
+	s.mu.Unlock()
+
+	fmt.Fprintf(s.errorOutput, "%v output %s: send error: %v, %d bytes kept for retry\n", time.Now(), s.name, err, queued)
+	s.errorOutput.Sync()
+}
+
+// encodeBatch returns the payload to send for batch and the compression applied to it,
+// empty when the batch is sent as is.
+func (s *shipper) encodeBatch(batch []byte) ([]byte, string, error) {
+	if s.compress == nil || len(batch) < compressionThreshold {
+		return batch, "", nil
+	}
+	compressed, err := s.compress(batch)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(compressed) >= len(batch) {
+		return batch, "", nil // Compression would not pay off
+	}
+	return compressed, s.compression, nil
+}
+
+// newTCPOutput returns the output for a tcp://host:port URL. Without compression,
+// batches are written as the newline-delimited entries they contain. With
+// WithBatchCompression, each batch is written as a frame: the length of the
+// compression name (one byte), the name (empty for an uncompressed batch), the
+// length of the payload (four bytes, big endian) and the payload.
+// Send failures are reported to errorOutput.
+func newTCPOutput(u *url.URL, errorOutput zapcore.WriteSyncer, o *options) (zapcore.WriteSyncer, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("sazabi: missing host in output %q", redactURL(u.String()))
+	}
+	var fallback zapcore.WriteSyncer
+	if o.fallbackPath != "" {
+		var err error
+		if fallback, _, err = zap.Open(o.fallbackPath); err != nil {
+			return nil, err
+		}
+	}
+	out := &tcpOutput{address: u.Host, framed: o.batchCompression != ""}
+	return newShipper(redactURL(u.String()), out.send, o.batchCompression, errorOutput, fallback)
+}
+
+// tcpOutput sends batches over a TCP connection, reconnecting after failures.
+type tcpOutput struct {
+	address string
+	framed  bool     // Whether batches are written as frames
+	conn    net.Conn // Current connection, nil when disconnected
+}
+
+// send writes one batch. Calls are serialized by the shipper.
+func (t *tcpOutput) send(payload []byte, compression string) error {
+	if t.conn == nil {
+		conn, err := net.DialTimeout("tcp", t.address, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		t.conn = conn
+	}
+
+	data := payload
+	if t.framed {
+		data = make([]byte, 1+len(compression)+4+len(payload))
+		data[0] = byte(len(compression))
+		n := 1 + copy(data[1:], compression)
+		binary.BigEndian.PutUint32(data[n:], uint32(len(payload)))
+		copy(data[n+4:], payload)
+	}
+
+	if _, err := t.conn.Write(data); err != nil {
+		t.conn.Close()
+		t.conn = nil // Reconnect for the next batch
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeCollector records the batches sent to it, failing while down is set.
+type fakeCollector struct {
+	mu      sync.Mutex
+	down    bool
+	block   chan struct{} // Blocks sends until closed, when not nil
+	batches []string
+}
+
+func (c *fakeCollector) send(payload []byte, _ string) error {
+	c.mu.Lock()
+	block := c.block
+	c.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return errors.New("connection refused")
+	}
+	c.batches = append(c.batches, string(payload))
+	return nil
+}
+
+func (c *fakeCollector) received() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return strings.Join(c.batches, "")
+}
+
+// lockedBuffer is a WriteSyncer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Sync() error { return nil }
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newTestShipper(t *testing.T, c *fakeCollector, fallback zapcore.WriteSyncer) (*shipper, *lockedBuffer) {
+	errs := &lockedBuffer{}
+	s, err := newShipper("tcp://collector", c.send, "", errs, fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, errs
+}
+
+func TestShipperRetriesFailedBatches(t *testing.T) {
+	c := &fakeCollector{down: true}
+	s, errs := newTestShipper(t, c, nil)
+
+	s.Write([]byte("entry 1\n"))
+	if err := s.Sync(); err == nil {
+		t.Fatal("Sync() succeeded while the collector is down")
+	}
+	s.Write([]byte("entry 2\n"))
+	s.Sync()
+
+	c.mu.Lock()
+	c.down = false
+	c.mu.Unlock()
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.received(); got != "entry 1\nentry 2\n" {
+		t.Errorf("received %q, want both entries in order", got)
+	}
+	if errs.String() != "" {
+		t.Errorf("Sync() failures reported as internal errors: %q", errs)
+	}
+}
+
+func TestShipperReportsBackgroundFailures(t *testing.T) {
+	c := &fakeCollector{down: true}
+	s, errs := newTestShipper(t, c, nil)
+	s.interval = 10 * time.Millisecond
+
+	s.Write([]byte("entry\n"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(errs.String(), "connection refused") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := errs.String(); !strings.Contains(got, "output tcp://collector: send error: connection refused, 6 bytes kept for retry") {
+		t.Errorf("internal errors = %q, want the send error", got)
+	}
+}
+
+func TestShipperRetryBufferBounded(t *testing.T) {
+	c := &fakeCollector{down: true}
+	fallback := &lockedBuffer{}
+	s, _ := newTestShipper(t, c, fallback)
+
+	entry := bytes.Repeat([]byte("x"), 1023)
+	entry = append(entry, '\n')
+	for i := 0; i < 2*retryMaxBytes/len(entry); i++ {
+		s.Write(entry)
+		if i%64 == 0 {
+			s.Sync()
+		}
+	}
+	s.Sync()
+
+	// Batches are dropped in the background too.
+	var queued, dropped int
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		s.mu.Lock()
+		queued = s.queued
+		s.mu.Unlock()
+		if dropped = len(fallback.String()); queued+dropped >= 2*retryMaxBytes-len(entry) {
+			break
+		}
+	}
+	if queued > retryMaxBytes+batchMaxBytes {
+		t.Errorf("%d bytes queued, want at most %d", queued, retryMaxBytes+batchMaxBytes)
+	}
+	if queued+dropped < 2*retryMaxBytes-len(entry) {
+		t.Errorf("%d bytes in the fallback and %d queued, want the dropped batches in the fallback", dropped, queued)
+	}
+}
+
+func TestShipperWriteDoesNotWaitForSend(t *testing.T) {
+	c := &fakeCollector{block: make(chan struct{})}
+	s, _ := newTestShipper(t, c, nil)
+	defer close(c.block)
+
+	full := bytes.Repeat([]byte("x"), batchMaxBytes)
+	s.Write(full) // Starts a background send, blocked by the collector
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Write([]byte("entry\n"))
+		}
+		s.Write(full)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Write blocked while a batch is being sent")
+	}
+}
@@ -0,0 +1,154 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// tcpFrame is a batch received from a framed TCP output.
+type tcpFrame struct {
+	compression string
+	payload     []byte
+}
+
+// readFrames accepts one connection on l and returns the frames received until the
+// connection is idle.
+func readFrames(t *testing.T, l net.Listener) <-chan []tcpFrame {
+	t.Helper()
+
+	result := make(chan []tcpFrame, 1)
+	go func() {
+		var frames []tcpFrame
+		defer func() { result <- frames }()
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+			n, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			name := make([]byte, n)
+			var size uint32
+			if _, err := io.ReadFull(r, name); err != nil {
+				return
+			}
+			if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+				return
+			}
+			payload := make([]byte, size)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			frames = append(frames, tcpFrame{compression: string(name), payload: payload})
+		}
+	}()
+	return result
+}
+
+func TestTCPOutputGzip(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	frames := readFrames(t, l)
+
+	sazabi.Initialize(sazabi.ProductionEnvName,
+		sazabi.WithOutputPaths("tcp://"+l.Addr().String()),
+		sazabi.WithBatchCompression(sazabi.CompressionGzip))
+	for i := 0; i < 50; i++ {
+		sazabi.Infow("shipped entry", "index", i, "payload", strings.Repeat("x", 40))
+	}
+	sazabi.Named("flush").(*zap.SugaredLogger).Sync() // Sends the batch
+	sazabi.Info("small batch")
+	sazabi.Shutdown()
+
+	got := <-frames
+	if len(got) != 2 {
+		t.Fatalf("received %d frames, want 2", len(got))
+	}
+
+	if got[0].compression != sazabi.CompressionGzip {
+		t.Fatalf("first frame compression = %q, want gzip", got[0].compression)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(got[0].payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(batch), "shipped entry"); n != 50 || !strings.HasSuffix(string(batch), `"index": 49, "payload": "`+strings.Repeat("x", 40)+"\"}\n") {
+		t.Errorf("decompressed %d entries, want 50 in order", n)
+	}
+	if len(got[0].payload) >= len(batch) {
+		t.Errorf("compressed batch is %d bytes for %d bytes of entries", len(got[0].payload), len(batch))
+	}
+
+	if got[1].compression != "" || !strings.Contains(string(got[1].payload), "small batch") {
+		t.Errorf("small batch = %q compressed with %q, want it uncompressed", got[1].payload, got[1].compression)
+	}
+}
+
+func TestTCPOutputUncompressed(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths("tcp://"+l.Addr().String()))
+	for i := 0; i < 3; i++ {
+		sazabi.Info(fmt.Sprintf("plain entry %d", i))
+	}
+	sazabi.Shutdown()
+
+	data := <-received
+	if n := strings.Count(data, "plain entry"); n != 3 || !strings.HasSuffix(data, "plain entry 2\n") {
+		t.Errorf("received %q, want 3 newline-delimited entries", data)
+	}
+}
+
+func TestBatchCompressionUnknown(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Initialize() with an unknown compression did not panic")
+		}
+	}()
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithBatchCompression("lz4"))
+}
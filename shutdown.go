@@ -0,0 +1,212 @@
+package sazabi
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"syscall"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LoggingAfterShutdownMessage is the message of the Warn entry written, once, by the
+// first log call made after Shutdown.
+const LoggingAfterShutdownMessage = "logging_after_shutdown"
+
+// shutDown is set by Shutdown and cleared by Initialize.
+var shutDown int32
+
+// IsShutdown reports whether Shutdown has run since the last initialization, so that
+// long-lived goroutines can skip building expensive fields.
+func IsShutdown() bool {
+	return atomic.LoadInt32(&shutDown) == 1
+}
+
+// shutdownHook is a function run by Shutdown.
+type shutdownHook struct {
+	id uint64
+	fn func()
+}
+
+// Shutdown hooks, guarded by initMu.
+var (
+	shutdownHooks      []shutdownHook
+	nextShutdownHookID uint64
+)
+
+// Sync flushes the global logger: its outputs, the pending batches of network outputs
+// and the cores added with AddCore. Failures to sync terminals and pipes, such as stderr,
+// which cannot be synced, are not reported. Sync does nothing before Initialize and can
+// be called any number of times, typically deferred in main:
+//
+//	defer sazabi.Sync()
+//
+// Fatal entries flush the outputs themselves before exiting, so the last entry is not
+// lost when Fatal ends the process before deferred functions run.
+func Sync() error {
+	in := loadInstance()
+	if in == nil {
+		return nil
+	}
+	var errs []error
+	for _, err := range multierr.Errors(in.direct.Sync()) {
+		if !errors.Is(err, syscall.EINVAL) && !errors.Is(err, syscall.ENOTTY) {
+			errs = append(errs, err)
+		}
+	}
+	return multierr.Combine(errs...)
+}
+
+// Shutdown stops the background tasks started by sazabi, such as heartbeats, volume
+// reports and volume budgets, in the reverse order they were started, then flushes the
+// global logger. Cores added with AddCore that implement io.Closer are then removed and
+// closed in the reverse order they were added, each within its close timeout. It
+// returns the error of the flush, or else the first close error, if any.
+//
+// Until the next initialization, the package functions then write to stderr, and the
+// first of them writes a LoggingAfterShutdownMessage warning naming its call site.
+// Fatal still exits. Calling Shutdown again does nothing.
+func Shutdown() error {
+	if IsShutdown() {
+		return nil
+	}
+
+	initMu.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	if stopVolumeReport != nil {
+		stopVolumeReport()
+		stopVolumeReport = nil
+	}
+	if stopVolumeBudget != nil {
+		stopVolumeBudget()
+		stopVolumeBudget = nil
+	}
+	initMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].fn()
+	}
+
+	var err error
+	if in := loadInstance(); in != nil {
+		err = in.direct.Sync() // Flushes the added cores too
+	}
+	if closeErr := closeCores(removeClosers()); err == nil {
+		err = closeErr
+	}
+
+	initMu.Lock()
+	defer initMu.Unlock()
+	if in := loadInstance(); in != nil && !loggingDisabled() {
+		publish(afterShutdown(in))
+	}
+	atomic.StoreInt32(&shutDown, 1)
+	return err
+}
+
+// afterShutdown returns in writing to stderr instead of its outputs.
+func afterShutdown(in *instance) *instance {
+	enc := newConsoleEncoder(in.config.EncoderConfig)
+	if newEncoder, ok := encoders[in.config.Encoding]; ok {
+		if e, err := newEncoder(in.config.EncoderConfig); err == nil {
+			enc = e
+		}
+	}
+	stderr := zapcore.Lock(os.Stderr)
+	core := newRedactCore(zapcore.NewCore(enc, stderr, in.config.Level))
+
+	opts := []zap.Option{zap.ErrorOutput(stderr), zap.AddCaller()}
+	if in.options != nil && in.options.fatalHook != nil {
+		opts = append(opts, zap.WithFatalHook(in.options.fatalHook))
+	}
+	next := *in
+	next.base = zap.New(newDynamicCore(&shutdownCore{Core: core, base: core, warned: new(int32)}), opts...)
+	next.batch = nil
+	return &next
+}
+
+// shutdownCore writes a LoggingAfterShutdownMessage warning before its first entry.
+// The warning is written to base, the core without the fields added through With.
+type shutdownCore struct {
+	zapcore.Core
+	base   zapcore.Core
+	warned *int32
+}
+
+// With implements zapcore.Core.
+func (c *shutdownCore) With(fields []zapcore.Field) zapcore.Core {
+	return &shutdownCore{Core: c.Core.With(fields), base: c.base, warned: c.warned}
+}
+
+// Check implements zapcore.Core.
+func (c *shutdownCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *shutdownCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if atomic.CompareAndSwapInt32(c.warned, 0, 1) {
+		caller := "unknown"
+		if ent.Caller.Defined {
+			caller = ent.Caller.TrimmedPath()
+		}
+		c.base.Write(zapcore.Entry{Level: zapcore.WarnLevel, Time: ent.Time, Message: LoggingAfterShutdownMessage, Caller: ent.Caller},
+			[]zapcore.Field{zap.String("first_call", caller)})
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// removeClosers unregisters the added cores implementing io.Closer and returns them.
+func removeClosers() []*extension {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	var closers []*extension
+	kept := extensions[:0:0]
+	for _, ext := range extensions {
+		if ext.closer != nil {
+			closers = append(closers, ext)
+		} else {
+			kept = append(kept, ext)
+		}
+	}
+	if len(closers) > 0 {
+		extensions = kept
+		extensionsChanged()
+	}
+	return closers
+}
+
+// addShutdownHook registers fn to be run by Shutdown and returns a function
+// unregistering it.
+func addShutdownHook(fn func()) (remove func()) {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	return addShutdownHookLocked(fn)
+}
+
+// addShutdownHookLocked is addShutdownHook for callers holding initMu.
+func addShutdownHookLocked(fn func()) (remove func()) {
+	nextShutdownHookID++
+	id := nextShutdownHookID
+	shutdownHooks = append(shutdownHooks, shutdownHook{id: id, fn: fn})
+
+	return func() {
+		initMu.Lock()
+		defer initMu.Unlock()
+
+		for i, h := range shutdownHooks {
+			if h.id == id {
+				shutdownHooks = append(shutdownHooks[:i:i], shutdownHooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestLoggingAfterShutdown(t *testing.T) {
+	restoreDefault(t)
+
+	var file string
+	output := captureStderr(t, func() {
+		read := initializeFile(t, sazabi.ProductionEnvName)
+		sazabi.Info("before shutdown")
+		if sazabi.IsShutdown() {
+			t.Error("IsShutdown() = true before Shutdown")
+		}
+		sazabi.Shutdown()
+		if !sazabi.IsShutdown() {
+			t.Error("IsShutdown() = false after Shutdown")
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			sazabi.Infow("after shutdown", "attempt", 1)
+			sazabi.Warn("after shutdown again")
+		}()
+		<-done
+		file = read()
+	})
+
+	if !strings.Contains(file, "before shutdown") || strings.Contains(file, "after shutdown") {
+		t.Errorf("file output = %q, want only the entry logged before Shutdown", file)
+	}
+	if n := strings.Count(output, sazabi.LoggingAfterShutdownMessage); n != 1 {
+		t.Fatalf("stderr has %d %s warnings, want 1:\n%s", n, sazabi.LoggingAfterShutdownMessage, output)
+	}
+	fields := entryFields(t, output, sazabi.LoggingAfterShutdownMessage)
+	if call, _ := fields["first_call"].(string); !strings.Contains(call, "shutdown_test.go") {
+		t.Errorf("first_call = %q, want the call site in shutdown_test.go", call)
+	}
+	if !strings.Contains(output, "after shutdown\t{\"attempt\": 1}") || !strings.Contains(output, "after shutdown again") {
+		t.Errorf("stderr = %q, want the entries logged after Shutdown", output)
+	}
+
+	captureStderr(t, func() { sazabi.Initialize(sazabi.ProductionEnvName) })
+	if sazabi.IsShutdown() {
+		t.Error("IsShutdown() = true after Initialize")
+	}
+}
+
+func TestFatalAfterShutdown(t *testing.T) {
+	restoreDefault(t)
+
+	returned := false
+	output := captureStderr(t, func() {
+		initializeFile(t, sazabi.ProductionEnvName, sazabi.WithFatalHook(zapcore.WriteThenGoexit))
+		sazabi.Shutdown()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			sazabi.Fatal("fatal after shutdown")
+			returned = true
+		}()
+		<-done
+	})
+
+	if returned {
+		t.Error("Fatal returned after Shutdown, want the fatal action to run")
+	}
+	if !strings.Contains(output, "fatal after shutdown") {
+		t.Errorf("stderr = %q, want the Fatal entry", output)
+	}
+}
+
+func TestShutdownTwice(t *testing.T) {
+	restoreDefault(t)
+
+	captureStderr(t, func() {
+		initializeFile(t, sazabi.ProductionEnvName)
+		sazabi.Shutdown()
+		if err := sazabi.Shutdown(); err != nil {
+			t.Errorf("second Shutdown() = %v, want nil", err)
+		}
+	})
+}
+
+// receiveLines returns the address of a TCP listener and a channel receiving the lines
+// sent to it by an uncompressed network output.
+func receiveLines(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	lines := make(chan string, 16)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return "tcp://" + l.Addr().String(), lines
+}
+
+// receivedWithin reports whether lines receives a line containing msg within d, well
+// below the one-second batch interval of network outputs.
+func receivedWithin(lines <-chan string, msg string, d time.Duration) bool {
+	timeout := time.After(d)
+	for {
+		select {
+		case line := <-lines:
+			if strings.Contains(line, msg) {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	restoreDefault(t)
+	addr, lines := receiveLines(t)
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths(addr))
+
+	sazabi.Info("synced entry")
+	for i := 0; i < 2; i++ {
+		if err := sazabi.Sync(); err != nil {
+			t.Errorf("Sync() #%d error = %v", i+1, err)
+		}
+	}
+	if !receivedWithin(lines, "synced entry", 200*time.Millisecond) {
+		t.Error("entry not sent by Sync")
+	}
+}
+
+func TestSyncStderr(t *testing.T) {
+	restoreDefault(t)
+	captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName) // stderr is a pipe, which cannot be synced
+		sazabi.Info("stderr entry")
+		if err := sazabi.Sync(); err != nil {
+			t.Errorf("Sync() error = %v, want nil for stderr", err)
+		}
+	})
+}
+
+func TestFatalSyncs(t *testing.T) {
+	restoreDefault(t)
+	addr, lines := receiveLines(t)
+	sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths(addr), sazabi.WithFatalHook(zapcore.WriteThenGoexit))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sazabi.Fatal("last entry")
+	}()
+	<-done
+	if !receivedWithin(lines, "last entry", 200*time.Millisecond) {
+		t.Error("Fatal entry not sent before exiting")
+	}
+}
@@ -0,0 +1,85 @@
+package sazabi
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SignalLevelMessage is the message of the entries written when a signal installed by
+// EnableSignalLevelToggle changes the level.
+const SignalLevelMessage = "log level changed by signal"
+
+// signalToggle holds the state of EnableSignalLevelToggle.
+var signalToggle struct {
+	once     sync.Once
+	mu       sync.Mutex
+	raised   zap.AtomicLevel // Level raised to debug, zero when not raised
+	previous zapcore.Level   // Level of raised before it was raised
+}
+
+// EnableSignalLevelToggle makes SIGUSR1 set the level of the global logger to debug and
+// SIGUSR2 restore the level it had before, each change being logged at Info level with
+// SignalLevelMessage. Signals received before Initialize, or that would not change the
+// level, are ignored, and re-initializing the logger forgets the level to restore. The
+// signals are received on a dedicated channel, so handlers installed by the application
+// for them keep working. Calling it again has no effect. It does nothing on platforms
+// without SIGUSR1 and SIGUSR2, such as Windows.
+func EnableSignalLevelToggle() {
+	signalToggle.once.Do(func() {
+		if levelSignals[0] == nil {
+			return
+		}
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, levelSignals[:]...)
+		go func() {
+			for sig := range ch {
+				toggleLevel(sig, sig == levelSignals[0])
+			}
+		}()
+	})
+}
+
+// toggleLevel raises the level to debug, or restores the level it was raised from, on
+// receiving sig.
+func toggleLevel(sig os.Signal, raise bool) {
+	signalToggle.mu.Lock()
+	defer signalToggle.mu.Unlock()
+
+	in := loadInstance()
+	if in == nil {
+		return
+	}
+	level := in.config.Level
+	current := level.Level()
+
+	if raise {
+		if current == zapcore.DebugLevel {
+			return
+		}
+		if signalToggle.raised != level {
+			signalToggle.raised, signalToggle.previous = level, current
+		}
+		level.SetLevel(zapcore.DebugLevel)
+		logLevelChange(in, sig, zapcore.DebugLevel, current)
+		return
+	}
+
+	if signalToggle.raised != level || current == signalToggle.previous {
+		return // Not raised, or raised before the logger was re-initialized
+	}
+	logLevelChange(in, sig, signalToggle.previous, current) // Before a higher level suppresses it
+	level.SetLevel(signalToggle.previous)
+	signalToggle.raised = zap.AtomicLevel{}
+}
+
+// logLevelChange writes the SignalLevelMessage entry of a change from previous to level.
+func logLevelChange(in *instance, sig os.Signal, level, previous zapcore.Level) {
+	in.unsampled.Infow(SignalLevelMessage,
+		"signal", sig.String(),
+		"level", level.String(),
+		"previous_level", previous.String())
+}
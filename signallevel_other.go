@@ -0,0 +1,10 @@
+//go:build windows || plan9 || js
+// +build windows plan9 js
+
+package sazabi
+
+import "os"
+
+// levelSignals are the signals raising and restoring the level with
+// EnableSignalLevelToggle, none on this platform.
+var levelSignals [2]os.Signal
@@ -0,0 +1,59 @@
+//go:build test && !windows
+// +build test,!windows
+
+package sazabi_test
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// signalLevel sends sig to the current process and waits for the level to become want.
+func signalLevel(t *testing.T, sig syscall.Signal, want string) {
+	t.Helper()
+
+	if err := syscall.Kill(os.Getpid(), sig); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for sazabi.GetLevel() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("level = %q after %v, want %q", sazabi.GetLevel(), sig, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEnableSignalLevelToggle(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName)
+	if err := sazabi.SetLevel("warn"); err != nil {
+		t.Fatal(err)
+	}
+	sazabi.EnableSignalLevelToggle()
+	sazabi.EnableSignalLevelToggle() // No effect
+
+	signalLevel(t, syscall.SIGUSR1, "debug")
+	sazabi.Debug("toggle entry 1")
+	signalLevel(t, syscall.SIGUSR2, "warn")
+	sazabi.Debug("toggle entry 2")
+
+	output := read()
+	if lineContaining(output, "toggle entry 1") == "" || lineContaining(output, "toggle entry 2") != "" {
+		t.Errorf("want only the entry logged at debug level, output: %s", output)
+	}
+
+	raised := entryFields(t, output, sazabi.SignalLevelMessage)
+	if raised["signal"] != syscall.SIGUSR1.String() || raised["level"] != "debug" || raised["previous_level"] != "warn" {
+		t.Errorf("raise entry fields = %v", raised)
+	}
+	restored := entryFields(t, output[strings.Index(output, "toggle entry 1"):], sazabi.SignalLevelMessage)
+	if restored["signal"] != syscall.SIGUSR2.String() || restored["level"] != "warn" || restored["previous_level"] != "debug" {
+		t.Errorf("restore entry fields = %v", restored)
+	}
+}
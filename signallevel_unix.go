@@ -0,0 +1,13 @@
+//go:build !windows && !plan9 && !js
+// +build !windows,!plan9,!js
+
+package sazabi
+
+import (
+	"os"
+	"syscall"
+)
+
+// levelSignals are the signals raising and restoring the level with
+// EnableSignalLevelToggle.
+var levelSignals = [2]os.Signal{syscall.SIGUSR1, syscall.SIGUSR2}
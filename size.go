@@ -0,0 +1,122 @@
+package sazabi
+
+import (
+	"math"
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldUnit marks the fields built by Bytes and Rate in their Interface, which zap
+// ignores for numeric fields, so that WithHumanReadableConsole can recognize them.
+type fieldUnit int
+
+const (
+	unitBytes fieldUnit = iota + 1 // Int64 field holding a byte size
+	unitRate                       // Float64 field holding bytes per second
+)
+
+// iecUnits are the binary prefixes of humanized sizes, by power of 1024.
+var iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// Bytes returns a field holding the byte size n. It is written as a number, except in
+// the console encoding with WithHumanReadableConsole, where it reads like 1.5MiB.
+func Bytes(key string, n int64) Field {
+	return Field{Key: key, Type: zapcore.Int64Type, Integer: n, Interface: unitBytes}
+}
+
+// Rate returns a field holding a throughput in bytes per second. It is written as a
+// number, except in the console encoding with WithHumanReadableConsole, where it reads
+// like 1.5MiB/s.
+func Rate(key string, bytesPerSec float64) Field {
+	f := zap.Float64(key, bytesPerSec)
+	f.Interface = unitRate
+	return f
+}
+
+// WithHumanReadableConsole renders the fields built by Bytes and Rate with IEC units
+// (KiB, MiB, GiB...) in the console encoding. Other encodings keep the numbers, for
+// dashboards and queries.
+func WithHumanReadableConsole() Option {
+	return func(o *options) {
+		o.humanReadableConsole = true
+	}
+}
+
+// humanCore renders the fields built by Bytes and Rate as human-readable strings.
+type humanCore struct {
+	zapcore.Core
+}
+
+// newHumanCore wraps core when o asks for human-readable sizes and encoding is a
+// console encoding, or returns core.
+func newHumanCore(core zapcore.Core, encoding string, o *options) zapcore.Core {
+	if !o.humanReadableConsole || (encoding != "console" && encoding != fullLineColorEncoding) {
+		return core
+	}
+	return &humanCore{Core: core}
+}
+
+// With implements zapcore.Core.
+func (c *humanCore) With(fields []zapcore.Field) zapcore.Core {
+	return &humanCore{Core: c.Core.With(humanize(fields))}
+}
+
+// Check implements zapcore.Core.
+func (c *humanCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *humanCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, humanize(fields))
+}
+
+// humanize returns fields with every Bytes and Rate field rendered as a string. The
+// input slice is only copied when a field actually changes.
+func humanize(fields []zapcore.Field) []zapcore.Field {
+	humanized, copied := fields, false
+	for i, f := range fields {
+		var s string
+		switch unit, _ := f.Interface.(fieldUnit); {
+		case unit == unitBytes && f.Type == zapcore.Int64Type:
+			s = formatIEC(float64(f.Integer))
+		case unit == unitRate && f.Type == zapcore.Float64Type:
+			s = formatIEC(math.Float64frombits(uint64(f.Integer))) + "/s"
+		default:
+			continue
+		}
+		if !copied {
+			humanized, copied = append([]zapcore.Field(nil), fields...), true
+		}
+		humanized[i] = zap.String(f.Key, s)
+	}
+	return humanized
+}
+
+// formatIEC formats n bytes with the largest IEC unit keeping it at least 1, and one
+// decimal: 512B, 1.0KiB, -1.5MiB. Sizes below 1KiB are written in full.
+func formatIEC(n float64) string {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return strconv.FormatFloat(n, 'f', -1, 64) + "B"
+	}
+	abs := math.Abs(n)
+	if abs < 1024 {
+		return strconv.FormatFloat(n, 'f', -1, 64) + "B"
+	}
+	unit := 0
+	for abs >= 1024 && unit < len(iecUnits)-1 {
+		abs /= 1024
+		n /= 1024
+		unit++
+	}
+	if abs >= 1023.95 && unit < len(iecUnits)-1 { // Would round up to 1024.0
+		n /= 1024
+		unit++
+	}
+	return strconv.FormatFloat(n, 'f', 1, 64) + iecUnits[unit]
+}
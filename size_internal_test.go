@@ -0,0 +1,96 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestFormatIEC(t *testing.T) {
+	tests := []struct {
+		n    float64
+		want string
+	}{
+		{0, "0B"},
+		{1, "1B"},
+		{1023, "1023B"},
+		{1024, "1.0KiB"},
+		{1536, "1.5KiB"},
+		{1048575, "1.0MiB"},
+		{1048576, "1.0MiB"},
+		{1 << 30, "1.0GiB"},
+		{5 << 30, "5.0GiB"},
+		{1 << 40, "1.0TiB"},
+		{-512, "-512B"},
+		{-1536, "-1.5KiB"},
+		{-(1 << 20), "-1.0MiB"},
+		{12.5, "12.5B"},
+	}
+	for _, tt := range tests {
+		if got := formatIEC(tt.n); got != tt.want {
+			t.Errorf("formatIEC(%v) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+// writeSizes logs Bytes and Rate fields with a logger built for environment with opts
+// and returns the output.
+func writeSizes(t *testing.T, environment string, opts ...Option) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, err := New(environment, append([]Option{WithOutputPaths(path)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := l.(*zap.SugaredLogger)
+	log.With(Bytes("limit", 1<<30)).Infow("transfer",
+		Bytes("zero", 0), Bytes("small", 1023), Bytes("kib", 1024), Bytes("mib", 3<<20), Bytes("negative", -1536),
+		Rate("rate", 2.5*(1<<20)))
+	log.Sync()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestHumanReadableConsole(t *testing.T) {
+	output := writeSizes(t, ProductionEnvName, WithHumanReadableConsole())
+
+	want := `{"limit": "1.0GiB", "zero": "0B", "small": "1023B", "kib": "1.0KiB", "mib": "3.0MiB", "negative": "-1.5KiB", "rate": "2.5MiB/s"}`
+	if !strings.Contains(output, want) {
+		t.Errorf("console output = %q, want fields %s", output, want)
+	}
+}
+
+func TestHumanReadableConsoleNumbers(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{
+			name: "json",
+			opts: []Option{WithHumanReadableConsole(), WithJSONEncoding()},
+			want: `"limit":1073741824,"zero":0,"small":1023,"kib":1024,"mib":3145728,"negative":-1536,"rate":2621440}`,
+		},
+		{
+			name: "console without option",
+			want: `{"limit": 1073741824, "zero": 0, "small": 1023, "kib": 1024, "mib": 3145728, "negative": -1536, "rate": 2621440}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if output := writeSizes(t, ProductionEnvName, tt.opts...); !strings.Contains(output, tt.want) {
+				t.Errorf("output = %q, want numeric fields %s", output, tt.want)
+			}
+		})
+	}
+}
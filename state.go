@@ -0,0 +1,138 @@
+package sazabi
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// instance is an immutable snapshot of the global logger together with the runtime
+// settings it was derived with. Changing a setting publishes a new instance.
+type instance struct {
+	base               *zap.Logger         // Logger as built from the configuration
+	direct             *zap.SugaredLogger  // Global logger with runtime settings, for direct use by callers
+	sugar              *zap.SugaredLogger  // Global logger used by the package functions
+	typed              *zap.Logger         // Global logger used by the Fields functions
+	unsampled          *zap.SugaredLogger  // Global logger bypassing sampling, without caller
+	environment        string              // Environment passed to Initialize
+	config             zap.Config          // Configuration the base logger was built from
+	options            *options            // Options passed to Initialize
+	callerEnabled      bool                // Whether entries carry the caller
+	stacktraceLevel    zapcore.Level       // Minimum level capturing a stacktrace
+	stacktraceOn       bool                // Whether stacktraces are captured at all
+	contextDiagnostics bool                // Whether Ctx functions annotate entries with their context state
+	batch              *batchTarget        // Destination of BatchLogger entries, nil to write them through typed
+	batchCore          zapcore.Core        // Core writing BatchLogger entries
+	raw                zapcore.WriteSyncer // Outputs written by WriteRaw, nil to discard raw lines
+	access             zapcore.WriteSyncer // Outputs written by AccessLog, nil to discard access lines
+}
+
+// globalInstance holds the current *instance. Loading it is the only synchronization
+// on the logging hot path; writers are serialized by initMu.
+var globalInstance atomic.Value
+
+// logger returns the global logger used by the package functions, carrying the fields
+// of the calling goroutine's open scopes.
+func logger() *zap.SugaredLogger {
+	in := currentInstance()
+	if frame := scopedFrame(in); frame != nil {
+		return frame.sugar
+	}
+	return in.sugar
+}
+
+// typedLogger returns the global logger used by the Fields functions, carrying the
+// fields of the calling goroutine's open scopes.
+func typedLogger() *zap.Logger {
+	in := currentInstance()
+	if frame := scopedFrame(in); frame != nil {
+		return frame.typed
+	}
+	return in.typed
+}
+
+// directLogger returns the global logger for callers using it directly rather than
+// through a package function, such as loggers derived for requests.
+func directLogger() *zap.SugaredLogger {
+	return currentInstance().direct
+}
+
+// NotInitializedMessage is the message of the warning written the first time the global
+// logger is used before Initialize.
+const NotInitializedMessage = "logger used before Initialize, logging to stderr for development"
+
+// fallback holds the instance used by the package functions before Initialize.
+var fallback struct {
+	once sync.Once
+	in   *instance
+}
+
+// currentInstance returns the current instance or, before the first initialization, a
+// development logger writing to stderr like Default, without the registered extensions.
+// The fallback is built on first use, writing a NotInitializedMessage warning.
+func currentInstance() *instance {
+	if in := loadInstance(); in != nil {
+		return in
+	}
+	fallback.once.Do(func() {
+		o := newOptions(nil)
+		log, conf, err := newZapLogger("development", o)
+		if err != nil {
+			log = zap.NewNop() // stderr cannot be opened, nowhere to log to
+		}
+		in := &instance{base: log, environment: "development", config: conf, options: o, callerEnabled: true}
+		in.direct = log.Sugar()
+		in.typed = log.WithOptions(zap.AddCallerSkip(1)) // Skip the package function frame
+		in.sugar = in.typed.Sugar()
+		in.unsampled = log.WithOptions(zap.WithCaller(false)).Sugar()
+		in.batchCore = in.typed.Core()
+		in.unsampled.Warn(NotInitializedMessage)
+		fallback.in = in
+	})
+	if in := loadInstance(); in != nil {
+		return in // Initialized meanwhile
+	}
+	return fallback.in
+}
+
+// loadInstance returns the current instance, or nil before the first initialization.
+func loadInstance() *instance {
+	in, _ := globalInstance.Load().(*instance)
+	return in
+}
+
+// publish derives the global logger from in.base using the runtime settings of in and
+// the registered extensions, and makes it visible to all goroutines. initMu must be held.
+func publish(in *instance) {
+	stacktrace := zap.AddStacktrace(zap.LevelEnablerFunc(func(zapcore.Level) bool { return false }))
+	if in.stacktraceOn {
+		stacktrace = zap.AddStacktrace(in.stacktraceLevel)
+	}
+
+	var (
+		incident       *incidentBuffer
+		incidentFields []zapcore.Field
+	)
+	if in.options != nil && in.options.incident != nil {
+		incident = in.options.incident
+		incidentFields = append(initialFields(in.config), globalFields()...)
+	}
+
+	direct := withIncident(applyExtensions(in.base), incident, incidentFields).WithOptions(zap.WithCaller(in.callerEnabled), stacktrace)
+	in.direct = direct.Sugar()
+	in.typed = direct.WithOptions(zap.AddCallerSkip(1)) // Skip the package function frame
+	in.sugar = in.typed.Sugar()
+
+	unsampled := in.base
+	if sampled, ok := in.base.Core().(*samplingCore); ok {
+		unsampled = in.base.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core { return sampled.unsampled }))
+	}
+	in.unsampled = withIncident(applyExtensions(unsampled), incident, incidentFields).WithOptions(zap.WithCaller(false)).Sugar()
+	in.batchCore = in.typed.Core()
+	if in.batch != nil {
+		in.batchCore = applyExtensions(zap.New(in.batch.core)).Core()
+	}
+	globalInstance.Store(in)
+}
@@ -0,0 +1,85 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// uninitialized runs fn as if Initialize had never been called, returning what was
+// written to stderr.
+func uninitialized(t *testing.T, fn func()) string {
+	t.Helper()
+
+	previous := loadInstance()
+	globalInstance.Store((*instance)(nil))
+	fallback.once, fallback.in = sync.Once{}, nil
+	defer func() {
+		globalInstance.Store(previous)
+		fallback.once, fallback.in = sync.Once{}, nil
+	}()
+
+	stderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		done <- buf.String()
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestLoggingBeforeInitialize(t *testing.T) {
+	output := uninitialized(t, func() {
+		Debugw("early entry", "key", "value")
+		Infow("second entry")
+	})
+
+	if strings.Count(output, NotInitializedMessage) != 1 {
+		t.Errorf("output = %q, want one %q warning", output, NotInitializedMessage)
+	}
+	for _, msg := range []string{"early entry", "second entry"} {
+		if !strings.Contains(output, msg) {
+			t.Errorf("output = %q, want %q", output, msg)
+		}
+	}
+	if !strings.Contains(output, "state_internal_test.go:") {
+		t.Errorf("output = %q, want the caller in state_internal_test.go", output)
+	}
+}
+
+func TestLoggingBeforeInitializeConcurrent(t *testing.T) {
+	output := uninitialized(t, func() {
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				Info("concurrent early entry")
+			}()
+		}
+		wg.Wait()
+	})
+
+	if got := strings.Count(output, NotInitializedMessage); got != 1 {
+		t.Errorf("%d %q warnings, want 1", got, NotInitializedMessage)
+	}
+	if got := strings.Count(output, "concurrent early entry"); got != 8 {
+		t.Errorf("%d entries written, want 8", got)
+	}
+}
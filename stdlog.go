@@ -0,0 +1,39 @@
+package sazabi
+
+import (
+	"log"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewStdLogger returns a standard library logger whose writes become entries of the
+// global logger at level, such as "error", for packages that only accept a *log.Logger,
+// like http.Server.ErrorLog. Each write becomes an entry whose message is the text
+// written, without its trailing newline; the *log.Logger adds no prefix, date or file,
+// and entries report the caller of its methods. Entries follow re-initializations of
+// the global logger. Writes at Panic or Fatal level panic or exit like the package
+// functions.
+func NewStdLogger(level string) (*log.Logger, error) {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	return log.New(stdWriter{level: lvl}, "", 0), nil
+}
+
+// stdWriter writes the output of a *log.Logger to the global logger.
+type stdWriter struct {
+	level zapcore.Level
+}
+
+// Write implements io.Writer. The frames of the *log.Logger method and of the function
+// formatting its output are skipped.
+func (w stdWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	if ce := typedLogger().WithOptions(zap.AddCallerSkip(2)).Check(w.level, msg); ce != nil {
+		ce.Write()
+	}
+	return len(p), nil
+}
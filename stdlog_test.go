@@ -0,0 +1,76 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestNewStdLogger(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithJSONEncoding())
+	std, err := sazabi.NewStdLogger("warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := callerLine()
+	std.Printf("disk %d%% full", 91)
+	std.Print("no newline")
+
+	output := read()
+	fields := entryFields(t, output, "disk 91% full")
+	if fields["level"] != "WARN" || fields["msg"] != "disk 91% full" {
+		t.Errorf("entry = %v, want a WARN entry without the trailing newline", fields)
+	}
+	if caller, _ := fields["caller"].(string); !strings.HasSuffix(caller, "/"+want) {
+		t.Errorf("caller = %v, want %s", fields["caller"], want)
+	}
+	if fields := entryFields(t, output, "no newline"); fields["msg"] != "no newline" {
+		t.Errorf("entry = %v, want the message as written", fields)
+	}
+
+	read = initializeFile(t, sazabi.ProductionEnvName, sazabi.WithJSONEncoding())
+	std.Print("after reinitialization")
+	if lineContaining(read(), "after reinitialization") == "" {
+		t.Error("entry not written to the outputs of the current global logger")
+	}
+}
+
+func TestNewStdLoggerHTTPServer(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithJSONEncoding())
+	errorLog, err := sazabi.NewStdLogger("error")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("handler bug")
+	}))
+	server.Config.ErrorLog = errorLog
+	server.Start()
+	defer server.Close()
+	if resp, err := http.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("request succeeded, want the connection closed by the panic")
+	}
+
+	fields := entryFields(t, read(), "http: panic serving")
+	msg, _ := fields["msg"].(string)
+	if fields["level"] != "ERROR" || !strings.Contains(msg, "handler bug") || strings.HasSuffix(msg, "\n") {
+		t.Errorf("entry = %v, want the server error at ERROR level", fields)
+	}
+}
+
+func TestNewStdLoggerInvalidLevel(t *testing.T) {
+	if _, err := sazabi.NewStdLogger("loud"); err == nil {
+		t.Error("NewStdLogger(loud) error = nil, want an error")
+	}
+}
@@ -0,0 +1,130 @@
+// Package stdshim mirrors the API of the standard library log package on top of the
+// global sazabi logger, so that code written for log can migrate by changing its
+// import:
+//
+//	import log "github.com/zeroxsolutions/sazabi/stdshim"
+//
+//	log.SetPrefix("billing: ")
+//	log.Printf("charged %d invoices", n)
+//
+// Print, Printf and Println write Info entries, Fatal, Fatalf and Fatalln write Fatal
+// entries and Panic, Panicf and Panicln write Panic entries, each reporting the caller
+// of the stdshim function. The output differs from the log package:
+//   - Entries are encoded by sazabi, so their format, time and caller come from its
+//     configuration; no date or file prefix is written.
+//   - The prefix set by SetPrefix is not prepended to the message but added to every
+//     entry as a field under PrefixKey, trimmed of surrounding spaces and colons.
+//   - Flags are recorded by SetFlags and returned by Flags, but otherwise ignored.
+//   - Fatal runs the fatal behavior of the global logger (see sazabi.WithFatalHook),
+//     which exits with status 1 by default.
+package stdshim
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// PrefixKey is the key of the field carrying the prefix set by SetPrefix.
+const PrefixKey = "prefix"
+
+// Flags of the log package, accepted by SetFlags for compatibility.
+const (
+	Ldate         = log.Ldate
+	Ltime         = log.Ltime
+	Lmicroseconds = log.Lmicroseconds
+	Llongfile     = log.Llongfile
+	Lshortfile    = log.Lshortfile
+	LUTC          = log.LUTC
+	Lmsgprefix    = log.Lmsgprefix
+	LstdFlags     = log.LstdFlags
+)
+
+var (
+	prefix atomic.Value // string set by SetPrefix
+	flags  int32        = LstdFlags
+)
+
+// SetPrefix sets the prefix added to the following entries under PrefixKey. An empty
+// prefix adds no field.
+func SetPrefix(p string) {
+	prefix.Store(p)
+}
+
+// Prefix returns the prefix set by SetPrefix.
+func Prefix() string {
+	p, _ := prefix.Load().(string)
+	return p
+}
+
+// SetFlags records flag, which is returned by Flags but has no effect on the output.
+func SetFlags(flag int) {
+	atomic.StoreInt32(&flags, int32(flag))
+}
+
+// Flags returns the flags recorded by SetFlags, LstdFlags by default.
+func Flags() int {
+	return int(atomic.LoadInt32(&flags))
+}
+
+// Print logs its arguments, formatted as by fmt.Sprint, at Info level.
+func Print(v ...interface{}) {
+	sazabi.CallerSkip(0).Infow(fmt.Sprint(v...), fields()...)
+}
+
+// Printf logs its arguments, formatted as by fmt.Sprintf, at Info level.
+func Printf(format string, v ...interface{}) {
+	sazabi.CallerSkip(0).Infow(fmt.Sprintf(format, v...), fields()...)
+}
+
+// Println logs its arguments, formatted as by fmt.Sprintln, at Info level.
+func Println(v ...interface{}) {
+	sazabi.CallerSkip(0).Infow(sprintln(v), fields()...)
+}
+
+// Fatal logs its arguments, formatted as by fmt.Sprint, at Fatal level.
+func Fatal(v ...interface{}) {
+	sazabi.CallerSkip(0).Fatalw(fmt.Sprint(v...), fields()...)
+}
+
+// Fatalf logs its arguments, formatted as by fmt.Sprintf, at Fatal level.
+func Fatalf(format string, v ...interface{}) {
+	sazabi.CallerSkip(0).Fatalw(fmt.Sprintf(format, v...), fields()...)
+}
+
+// Fatalln logs its arguments, formatted as by fmt.Sprintln, at Fatal level.
+func Fatalln(v ...interface{}) {
+	sazabi.CallerSkip(0).Fatalw(sprintln(v), fields()...)
+}
+
+// Panic logs its arguments, formatted as by fmt.Sprint, at Panic level, then panics.
+func Panic(v ...interface{}) {
+	sazabi.CallerSkip(0).Panicw(fmt.Sprint(v...), fields()...)
+}
+
+// Panicf logs its arguments, formatted as by fmt.Sprintf, at Panic level, then panics.
+func Panicf(format string, v ...interface{}) {
+	sazabi.CallerSkip(0).Panicw(fmt.Sprintf(format, v...), fields()...)
+}
+
+// Panicln logs its arguments, formatted as by fmt.Sprintln, at Panic level, then panics.
+func Panicln(v ...interface{}) {
+	sazabi.CallerSkip(0).Panicw(sprintln(v), fields()...)
+}
+
+// sprintln formats v as fmt.Sprintln does, without the trailing newline.
+func sprintln(v []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+}
+
+// fields returns the fields added to every entry: the prefix, when set.
+func fields() []interface{} {
+	p := strings.Trim(Prefix(), " :")
+	if p == "" {
+		return nil
+	}
+	return []interface{}{PrefixKey, p}
+}
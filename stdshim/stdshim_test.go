@@ -0,0 +1,98 @@
+//go:build test
+// +build test
+
+package stdshim_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/zeroxsolutions/sazabi"
+	log "github.com/zeroxsolutions/sazabi/stdshim"
+)
+
+func TestLevels(t *testing.T) {
+	c, stop := sazabi.StartCapture()
+	defer stop()
+
+	log.Print("print ", 1)
+	log.Printf("printf %d", 2)
+	log.Println("println", 3)
+	func() {
+		defer func() {
+			if r := recover(); r != "panic 4" {
+				t.Errorf("Panicf() panicked with %v, want the message", r)
+			}
+		}()
+		log.Panicf("panic %d", 4)
+	}()
+
+	want := []struct {
+		level   zapcore.Level
+		message string
+	}{
+		{zapcore.InfoLevel, "print 1"},
+		{zapcore.InfoLevel, "printf 2"},
+		{zapcore.InfoLevel, "println 3"},
+		{zapcore.PanicLevel, "panic 4"},
+	}
+	entries := c.Entries()
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, e := range entries {
+		if e.Level != want[i].level || e.Message != want[i].message {
+			t.Errorf("entry %d = %s %q, want %s %q", i, e.Level, e.Message, want[i].level, want[i].message)
+		}
+		if file := filepath.Base(e.Caller.File); file != "stdshim_test.go" {
+			t.Errorf("entry %d caller = %s, want the caller of stdshim", i, e.Caller)
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	c, stop := sazabi.StartCapture()
+	defer stop()
+	defer log.SetPrefix("")
+
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	defer log.SetFlags(log.LstdFlags)
+	log.SetPrefix("billing: ")
+	log.Print("with prefix")
+	log.SetPrefix("")
+	log.Print("without prefix")
+
+	entries := c.Entries()
+	if p, _ := entries[0].Str(log.PrefixKey); p != "billing" || log.Flags() != log.LstdFlags|log.Lshortfile {
+		t.Errorf("prefix = %q, flags = %d, want billing and the flags recorded", p, log.Flags())
+	}
+	if _, ok := entries[1].Field(log.PrefixKey); ok {
+		t.Errorf("entry without prefix carries %v", entries[1].Fields)
+	}
+}
+
+func TestFatal(t *testing.T) {
+	c, stop := sazabi.StartCapture()
+	defer stop()
+	behavior := sazabi.SetFatalBehavior(zapcore.WriteThenGoexit)
+	defer behavior.Remove()
+
+	returned := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		log.Fatalf("fatal %d", 5)
+		returned = true
+	}()
+	<-done
+
+	entries := c.Entries()
+	if returned || len(entries) != 1 || entries[0].Level != zapcore.FatalLevel || entries[0].Message != "fatal 5" {
+		t.Fatalf("Fatalf() returned = %t with entries %+v, want one Fatal entry and the fatal behavior run", returned, entries)
+	}
+	if file := filepath.Base(entries[0].Caller.File); file != "stdshim_test.go" {
+		t.Errorf("caller = %s, want the caller of stdshim", entries[0].Caller)
+	}
+}
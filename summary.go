@@ -0,0 +1,116 @@
+package sazabi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// StartupSummaryMessage is the message of the entry emitted by WithStartupSummary.
+const StartupSummaryMessage = "logging configured"
+
+// lastSummary holds the configuration summary of the current global logger, used by
+// crash dumps. It is written under initMu.
+var lastSummary atomic.Value
+
+// emitStartupSummary logs the configuration summary entry using the global logger.
+func emitStartupSummary(keysValues []interface{}) {
+	logger().Infow(StartupSummaryMessage, keysValues...)
+}
+
+// configSummary returns the key-value pairs describing the active configuration.
+// Sensitive values are redacted before being returned.
+func configSummary(environment string, conf zap.Config, o *options, reinitialized bool) []interface{} {
+	integrations := make(map[string]interface{}, len(o.integrations))
+	for _, in := range o.integrations {
+		integrations[in.name] = redactSettings(in.settings)
+	}
+	if o.fallbackPath != "" {
+		integrations["fallback_output"] = map[string]string{"path": redactURL(o.fallbackPath)}
+	}
+
+	sampling := interface{}(false)
+	if conf.Sampling != nil {
+		sampling = map[string]int{
+			"initial":    conf.Sampling.Initial,
+			"thereafter": conf.Sampling.Thereafter,
+		}
+	}
+
+	summary := []interface{}{
+		"environment", environment,
+		"level", conf.Level.String(),
+		"encoding", conf.Encoding,
+		"development", conf.Development,
+		"interactive", interactiveOutput(o),
+		"outputs", redactPaths(conf.OutputPaths),
+		"error_outputs", redactPaths(conf.ErrorOutputPaths),
+		"sampling", sampling,
+		"integrations", integrations,
+		"reinitialized", reinitialized,
+	}
+	if o.environmentSource != "" {
+		summary = append(summary, "environment_source", o.environmentSource)
+	}
+	return summary
+}
+
+// redactSettings returns a copy of the settings of an integration with URLs reduced by
+// redactURL and secrets, such as API keys and tokens, replaced by their fingerprint.
+func redactSettings(settings map[string]string) map[string]string {
+	redacted := make(map[string]string, len(settings))
+	for name, value := range settings {
+		switch {
+		case secretSetting(name):
+			redacted[name] = fingerprint(value)
+		default:
+			redacted[name] = redactURL(value)
+		}
+	}
+	return redacted
+}
+
+// secretSetting reports whether the integration setting name holds a secret.
+func secretSetting(name string) bool {
+	name = strings.ToLower(name)
+	for _, suffix := range []string{"key", "token", "secret", "password"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactPaths returns a copy of paths with every URL reduced by redactURL.
+func redactPaths(paths []string) []string {
+	redacted := make([]string, len(paths))
+	for i, p := range paths {
+		redacted[i] = redactURL(p)
+	}
+	return redacted
+}
+
+// redactURL reduces a URL to its scheme and host so that tokens embedded in
+// paths, query strings or user info are never logged. Values that are not
+// absolute URLs (such as "stderr" or file paths) are returned unchanged.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return raw
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// fingerprint returns a short, stable identifier for a secret such as an API key,
+// allowing operators to tell keys apart without exposing them.
+func fingerprint(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:])[:12]
+}
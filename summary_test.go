@@ -0,0 +1,106 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// summaryFields extracts the structured fields of the startup summary entry from console output.
+func summaryFields(t *testing.T, output string) map[string]interface{} {
+	t.Helper()
+
+	line := lineContaining(output, sazabi.StartupSummaryMessage)
+	if line == "" {
+		t.Fatalf("startup summary entry not found in output: %s", output)
+	}
+
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(line[strings.Index(line, "{"):]), &fields); err != nil {
+		t.Fatalf("failed to parse summary fields %q: %v", line, err)
+	}
+	return fields
+}
+
+func TestWithStartupSummary(t *testing.T) {
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithStartupSummary())
+	})
+
+	fields := summaryFields(t, output)
+	expected := map[string]interface{}{
+		"environment": sazabi.ProductionEnvName,
+		"level":       "info",
+		"encoding":    "console",
+		"development": false,
+	}
+	for key, want := range expected {
+		if got := fields[key]; got != want {
+			t.Errorf("summary field %q = %v, want %v", key, got, want)
+		}
+	}
+
+	outputs, ok := fields["outputs"].([]interface{})
+	if !ok || len(outputs) != 1 || outputs[0] != "stderr" {
+		t.Errorf("summary field outputs = %v, want [stderr]", fields["outputs"])
+	}
+
+	sampling, ok := fields["sampling"].(map[string]interface{})
+	if !ok || sampling["initial"] != float64(100) || sampling["thereafter"] != float64(100) {
+		t.Errorf("summary field sampling = %v, want initial=100 thereafter=100", fields["sampling"])
+	}
+
+	if _, ok := fields["integrations"].(map[string]interface{}); !ok {
+		t.Errorf("summary field integrations = %v, want an object", fields["integrations"])
+	}
+}
+
+func TestWithStartupSummaryReinitialized(t *testing.T) {
+	sazabi.Initialize("development")
+
+	output := captureStderr(t, func() {
+		sazabi.Initialize("development", sazabi.WithStartupSummary())
+	})
+
+	fields := summaryFields(t, output)
+	if fields["reinitialized"] != true {
+		t.Errorf("summary field reinitialized = %v, want true", fields["reinitialized"])
+	}
+	if fields["sampling"] != false {
+		t.Errorf("summary field sampling = %v, want false in development", fields["sampling"])
+	}
+}
+
+func TestStartupSummaryDisabledByDefault(t *testing.T) {
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+	})
+
+	if strings.Contains(output, sazabi.StartupSummaryMessage) {
+		t.Errorf("startup summary should not be emitted without WithStartupSummary, got: %s", output)
+	}
+}
+
+func TestStartupSummaryRedactsWebhookURL(t *testing.T) {
+	read := initializeFile(t, "development", sazabi.WithInteractive(false), sazabi.WithCIEncoding("json"),
+		sazabi.WithStartupSummary(),
+		sazabi.WithOptionalSink(sazabi.WithOutput("broken://hooks.slack.com/services/T000/B000/XXXXSECRET?token=abc")))
+	restoreDefault(t)
+
+	var summary map[string]interface{}
+	if err := json.Unmarshal([]byte(lineContaining(read(), sazabi.StartupSummaryMessage)), &summary); err != nil {
+		t.Fatalf("summary entry is not JSON: %v\n%s", err, read())
+	}
+	outputs, _ := summary["outputs"].([]interface{})
+	if len(outputs) != 2 || outputs[1] != "broken://hooks.slack.com" {
+		t.Errorf("summary outputs = %v, want the webhook reduced to its scheme and host", summary["outputs"])
+	}
+	if line := lineContaining(read(), sazabi.StartupSummaryMessage); strings.Contains(line, "XXXXSECRET") || strings.Contains(line, "token=abc") {
+		t.Errorf("summary leaks the webhook secret: %s", line)
+	}
+}
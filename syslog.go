@@ -0,0 +1,273 @@
+package sazabi
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Priority is the facility of the messages written by WithSyslog, with the values of
+// the facilities of log/syslog.
+type Priority int
+
+// Syslog facilities.
+const (
+	SyslogKern Priority = iota << 3
+	SyslogUser
+	SyslogMail
+	SyslogDaemon
+	SyslogAuth
+	SyslogSyslog
+	SyslogLpr
+	SyslogNews
+	SyslogUucp
+	SyslogCron
+	SyslogAuthPriv
+	SyslogFTP
+	_
+	_
+	_
+	_
+	SyslogLocal0
+	SyslogLocal1
+	SyslogLocal2
+	SyslogLocal3
+	SyslogLocal4
+	SyslogLocal5
+	SyslogLocal6
+	SyslogLocal7
+)
+
+// Syslog severities, combined with the facility in the priority of messages.
+const (
+	syslogCrit    Priority = 2
+	syslogErr     Priority = 3
+	syslogWarning Priority = 4
+	syslogInfo    Priority = 6
+	syslogDebug   Priority = 7
+)
+
+// Reconnection of syslog outputs.
+const (
+	syslogDialTimeout  = 5 * time.Second
+	syslogWriteTimeout = 5 * time.Second        // Longest wait for a server that stopped reading
+	syslogMinBackoff   = 100 * time.Millisecond // Wait before the first reconnection attempt
+	syslogMaxBackoff   = 30 * time.Second       // Longest wait between reconnection attempts
+)
+
+// syslogConfig is a syslog output added by WithSyslog.
+type syslogConfig struct {
+	network  string
+	address  string
+	tag      string
+	facility Priority
+}
+
+// WithSyslog adds a syslog output, such as a local rsyslog, sending each entry as an
+// RFC 3164 message: "<priority>Jan  2 15:04:05 hostname tag[pid]: entry", the entry
+// encoded as for the other outputs. network is "udp", "tcp" or "unixgram", or one of
+// their variants accepted by net.Dial; TCP messages end with a newline. An empty tag is
+// the name of the program. The severity follows the level of the entry: LOG_DEBUG for
+// Debug, LOG_INFO for Info, LOG_WARNING for Warn, LOG_ERR for Error and LOG_CRIT above.
+//
+// The output connects on the first entry. When the connection fails or is lost, entries
+// are dropped and counted as such by PublishExpvars while it reconnects, waiting from
+// 100ms up to 30s between attempts; each failure is reported to the internal error
+// output.
+func WithSyslog(network, addr, tag string, facility Priority) Option {
+	return func(o *options) {
+		o.syslogs = append(o.syslogs, syslogConfig{network: network, address: addr, tag: tag, facility: facility})
+		o.integrations = append(o.integrations, integration{
+			name: "syslog",
+			settings: map[string]string{
+				"network":  network,
+				"address":  addr,
+				"tag":      tag,
+				"facility": strconv.Itoa(int(facility)),
+			},
+		})
+	}
+}
+
+// newSyslogCores returns the cores of the syslog outputs of o, encoding entries enabled
+// by enab with enc and reporting failures to errorOutput.
+func newSyslogCores(enc zapcore.Encoder, enab zapcore.LevelEnabler, errorOutput zapcore.WriteSyncer, o *options) ([]zapcore.Core, error) {
+	cores := make([]zapcore.Core, 0, len(o.syslogs))
+	for _, cfg := range o.syslogs {
+		switch cfg.network {
+		case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+		default:
+			return nil, fmt.Errorf("sazabi: unsupported syslog network %q", cfg.network)
+		}
+		tag := cfg.tag
+		if tag == "" {
+			tag = filepath.Base(os.Args[0])
+		}
+		w := &syslogWriter{
+			network:     cfg.network,
+			address:     cfg.address,
+			header:      fmt.Sprintf(" %s %s[%d]: ", o.hostname(), tag, o.pid()),
+			facility:    cfg.facility,
+			global:      o.global,
+			errorOutput: errorOutput,
+			dial: func(network, address string) (net.Conn, error) {
+				return net.DialTimeout(network, address, syslogDialTimeout)
+			},
+			writeTimeout: syslogWriteTimeout,
+			now:          time.Now,
+		}
+		cores = append(cores, &syslogCore{LevelEnabler: enab, enc: enc.Clone(), out: w})
+	}
+	return cores, nil
+}
+
+// syslogCore writes entries to a syslog output.
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out *syslogWriter
+}
+
+// With implements zapcore.Core.
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, out: c.out}
+}
+
+// Check implements zapcore.Core.
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. Entries the output fails to send are dropped, so it
+// only returns encoding errors.
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	c.out.send(syslogSeverity(ent.Level), ent.Time, bytes.TrimRight(buf.Bytes(), "\n"))
+	buf.Free()
+	return nil
+}
+
+// Sync implements zapcore.Core. Messages are sent as they are written, so there is
+// nothing to flush.
+func (c *syslogCore) Sync() error {
+	return nil
+}
+
+// syslogSeverity returns the syslog severity of the entries of level.
+func syslogSeverity(level zapcore.Level) Priority {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return syslogDebug
+	case level == zapcore.InfoLevel:
+		return syslogInfo
+	case level == zapcore.WarnLevel:
+		return syslogWarning
+	case level == zapcore.ErrorLevel:
+		return syslogErr
+	}
+	return syslogCrit
+}
+
+// syslogWriter sends messages to a syslog server, reconnecting with backoff after
+// failures.
+type syslogWriter struct {
+	network      string
+	address      string
+	header       string   // Hostname, tag and process ID, between the timestamp and the entry
+	facility     Priority // Facility of the messages
+	global       bool     // Whether dropped messages are counted by PublishExpvars
+	errorOutput  zapcore.WriteSyncer
+	dial         func(network, address string) (net.Conn, error)
+	now          func() time.Time
+	writeTimeout time.Duration // Deadline of each write, so that a server that stops reading cannot block logging
+
+	mu       sync.Mutex
+	conn     net.Conn      // Current connection, nil when disconnected
+	backoff  time.Duration // Wait after the last failure, zero while connected
+	nextDial time.Time     // Earliest time of the next connection attempt
+	dropped  int64         // Messages dropped since the output was opened
+}
+
+// send writes the message of an entry of severity timed t, dropping it while the server
+// cannot be reached or does not read it within the write timeout.
+func (w *syslogWriter) send(severity Priority, t time.Time, entry []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		now := w.now()
+		if now.Before(w.nextDial) {
+			w.dropLocked()
+			return
+		}
+		conn, err := w.dial(w.network, w.address)
+		if err != nil {
+			w.failLocked(now, err)
+			return
+		}
+		w.conn = conn
+		w.backoff = 0
+	}
+
+	msg := make([]byte, 0, len(entry)+len(w.header)+32)
+	msg = append(msg, '<')
+	msg = strconv.AppendInt(msg, int64(w.facility|severity), 10)
+	msg = append(msg, '>')
+	msg = t.AppendFormat(msg, time.Stamp)
+	msg = append(msg, w.header...)
+	msg = append(msg, entry...)
+	if strings.HasPrefix(w.network, "tcp") {
+		msg = append(msg, '\n')
+	}
+	w.conn.SetWriteDeadline(time.Now().Add(w.writeTimeout))
+	if _, err := w.conn.Write(msg); err != nil { // Timeouts included: the message may be partly sent
+		w.conn.Close()
+		w.conn = nil
+		w.failLocked(w.now(), err)
+	}
+}
+
+// failLocked drops the current message after err, a failure to connect or send at now,
+// and schedules the next connection attempt. w.mu must be held.
+func (w *syslogWriter) failLocked(now time.Time, err error) {
+	w.dropLocked()
+	switch {
+	case w.backoff == 0:
+		w.backoff = syslogMinBackoff
+	case w.backoff < syslogMaxBackoff:
+		w.backoff *= 2
+		if w.backoff > syslogMaxBackoff {
+			w.backoff = syslogMaxBackoff
+		}
+	}
+	w.nextDial = now.Add(w.backoff)
+
+	fmt.Fprintf(w.errorOutput, "%v output syslog %s://%s: %v, reconnecting in %s, %d entries dropped\n", time.Now(), w.network, w.address, err, w.backoff, w.dropped)
+	w.errorOutput.Sync()
+}
+
+// dropLocked counts a dropped message. w.mu must be held.
+func (w *syslogWriter) dropLocked() {
+	w.dropped++
+	if w.global {
+		countOutputDropped(1)
+	}
+}
@@ -0,0 +1,167 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeConn is a connection recording the messages written to it, failing writes once
+// broken.
+type fakeConn struct {
+	net.Conn
+	written []string
+	broken  bool
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) {
+	if c.broken {
+		return 0, errors.New("connection reset")
+	}
+	c.written = append(c.written, string(p))
+	return len(p), nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+func TestSyslogWriterStalledServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept() // Never read
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	var errs bytes.Buffer
+	w := &syslogWriter{
+		network:      "tcp",
+		address:      ln.Addr().String(),
+		errorOutput:  zapcore.AddSync(&errs),
+		dial:         net.Dial,
+		now:          time.Now,
+		writeTimeout: 100 * time.Millisecond,
+	}
+	entry := bytes.Repeat([]byte("x"), 1<<20)
+	for i := 0; i < 64 && w.dropped == 0; i++ { // Until the buffers of the connection are full
+		start := time.Now()
+		w.send(syslogInfo, start, entry)
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("send took %v with a server that does not read, want the write to time out", elapsed)
+		}
+	}
+	if w.dropped != 1 || w.conn != nil || w.backoff != syslogMinBackoff {
+		t.Errorf("after the write timed out: %d dropped, connected %t, backoff %s; want 1, disconnected and %s", w.dropped, w.conn != nil, w.backoff, syslogMinBackoff)
+	}
+	if !strings.Contains(errs.String(), "i/o timeout") {
+		t.Errorf("internal errors = %q, want the timeout reported", errs.String())
+	}
+}
+
+func TestSyslogWriterReconnects(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)}
+	var errs bytes.Buffer
+	var dials int
+	conn := &fakeConn{}
+	w := &syslogWriter{
+		network:     "tcp",
+		address:     "syslog:514",
+		header:      " host app[42]: ",
+		facility:    SyslogLocal0,
+		errorOutput: zapcore.AddSync(&errs),
+		dial: func(network, address string) (net.Conn, error) {
+			dials++
+			if dials <= 2 {
+				return nil, errors.New("connection refused")
+			}
+			return conn, nil
+		},
+		now: clock.now,
+	}
+	entryTime := time.Date(2024, 1, 31, 10, 0, 0, 0, time.Local)
+
+	w.send(syslogErr, entryTime, []byte("first"))
+	w.send(syslogErr, entryTime, []byte("during backoff"))
+	if dials != 1 || w.dropped != 2 || w.backoff != syslogMinBackoff {
+		t.Fatalf("after a failed dial: %d dials, %d dropped, backoff %s; want 1, 2 and %s", dials, w.dropped, w.backoff, syslogMinBackoff)
+	}
+	clock.advance(syslogMinBackoff)
+	w.send(syslogErr, entryTime, []byte("second attempt"))
+	if dials != 2 || w.dropped != 3 || w.backoff != 2*syslogMinBackoff {
+		t.Fatalf("after two failed dials: %d dials, %d dropped, backoff %s; want 2, 3 and %s", dials, w.dropped, w.backoff, 2*syslogMinBackoff)
+	}
+	clock.advance(2 * syslogMinBackoff)
+	w.send(syslogWarning, entryTime, []byte("delivered"))
+	if want := "<132>Jan 31 10:00:00 host app[42]: delivered\n"; len(conn.written) != 1 || conn.written[0] != want {
+		t.Fatalf("written = %q, want %q", conn.written, want)
+	}
+	if w.backoff != 0 {
+		t.Errorf("backoff = %s once connected, want it reset", w.backoff)
+	}
+
+	conn.broken = true
+	w.send(syslogErr, entryTime, []byte("lost"))
+	if w.conn != nil || w.dropped != 4 || w.backoff != syslogMinBackoff {
+		t.Errorf("after a failed write: connected %t, %d dropped, backoff %s; want disconnected, 4 and %s", w.conn != nil, w.dropped, w.backoff, syslogMinBackoff)
+	}
+	if got := strings.Count(errs.String(), "output syslog tcp://syslog:514"); got != 3 {
+		t.Errorf("internal errors = %q, want the 3 failures", errs.String())
+	}
+}
+
+func TestSyslogWriterBackoffCapped(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	w := &syslogWriter{
+		network:     "udp",
+		errorOutput: zapcore.AddSync(&bytes.Buffer{}),
+		dial: func(network, address string) (net.Conn, error) {
+			return nil, errors.New("no route to host")
+		},
+		now: clock.now,
+	}
+	for i := 0; i < 20; i++ {
+		w.send(syslogInfo, clock.t, []byte("entry"))
+		clock.advance(w.backoff)
+	}
+	if w.backoff != syslogMaxBackoff {
+		t.Errorf("backoff = %s after 20 failures, want %s", w.backoff, syslogMaxBackoff)
+	}
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	tests := map[zapcore.Level]Priority{
+		zapcore.DebugLevel:  syslogDebug,
+		zapcore.InfoLevel:   syslogInfo,
+		zapcore.WarnLevel:   syslogWarning,
+		zapcore.ErrorLevel:  syslogErr,
+		zapcore.DPanicLevel: syslogCrit,
+		zapcore.PanicLevel:  syslogCrit,
+		zapcore.FatalLevel:  syslogCrit,
+	}
+	for level, want := range tests {
+		if got := syslogSeverity(level); got != want {
+			t.Errorf("syslogSeverity(%s) = %d, want %d", level, got, want)
+		}
+	}
+}
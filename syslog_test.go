@@ -0,0 +1,96 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestWithSyslog(t *testing.T) {
+	restoreDefault(t)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sazabi.Initialize(sazabi.ProductionEnvName,
+		sazabi.WithOutputPaths("stderr"),
+		sazabi.WithHostnameProvider(func() string { return "edge-1" }),
+		sazabi.WithPIDProvider(func() int { return 42 }),
+		sazabi.WithSyslog("udp", conn.LocalAddr().String(), "billing", sazabi.SyslogLocal0),
+	)
+	sazabi.SetLevel("debug")
+	sazabi.Debugw("cache warmed", "entries", 12)
+	sazabi.Warn("disk almost full")
+	sazabi.Error("payment failed")
+
+	tests := []struct {
+		priority string
+		message  string
+	}{
+		{priority: "<135>", message: "cache warmed"},     // local0, debug
+		{priority: "<132>", message: "disk almost full"}, // local0, warning
+		{priority: "<131>", message: "payment failed"},   // local0, err
+	}
+	// Other entries, such as the re-initialization warning, may come first.
+	var messages []string
+	buf := make([]byte, 64<<10)
+	for len(messages) < 8 {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		messages = append(messages, string(buf[:n]))
+	}
+	for _, tt := range tests {
+		var msg string
+		for _, m := range messages {
+			if strings.Contains(m, tt.message) {
+				msg = m
+			}
+		}
+		if !strings.HasPrefix(msg, tt.priority) || !strings.Contains(msg, " edge-1 billing[42]: ") {
+			t.Errorf("message of %q = %q, want priority %s and the header", tt.message, msg, tt.priority)
+		}
+		if strings.HasSuffix(msg, "\n") {
+			t.Errorf("message = %q, want no trailing newline over UDP", msg)
+		}
+	}
+}
+
+func TestWithSyslogUnreachable(t *testing.T) {
+	restoreDefault(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // Nothing listens at addr any more
+
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName, sazabi.WithOutputPaths("stderr"), sazabi.WithSyslog("tcp", addr, "app", sazabi.SyslogUser))
+		sazabi.Info("still logged")
+		sazabi.Sync()
+	})
+	if !strings.Contains(output, "still logged") {
+		t.Errorf("output = %q, want the entry written to the other outputs", output)
+	}
+	if !strings.Contains(output, "output syslog tcp://"+addr) {
+		t.Errorf("output = %q, want the connection failure reported", output)
+	}
+}
+
+func TestWithSyslogInvalidNetwork(t *testing.T) {
+	restoreDefault(t)
+	if err := sazabi.TryInitialize(sazabi.ProductionEnvName, sazabi.WithSyslog("http", "localhost:514", "app", sazabi.SyslogUser)); err == nil {
+		t.Error("TryInitialize succeeded with an http syslog network, want an error")
+	}
+}
@@ -0,0 +1,10 @@
+// Package component logs through sazabi from another package than the tests, for the
+// tests of WithAutoComponent.
+package component
+
+import "github.com/zeroxsolutions/sazabi"
+
+// Log logs msg at Info level through the global logger.
+func Log(msg string) {
+	sazabi.Info(msg)
+}
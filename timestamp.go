@@ -0,0 +1,64 @@
+package sazabi
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// IngestedAtKey is the key of the write time added by WithIngestTime to entries whose
+// time is set by At.
+const IngestedAtKey = "ingested_at"
+
+// WithIngestTime makes the loggers returned by At keep the actual write time of their
+// entries under IngestedAtKey, next to the overridden entry time.
+func WithIngestTime() Option {
+	return func(o *options) {
+		o.ingestTime = true
+	}
+}
+
+// At returns a child of the global logger whose entries carry t as their time instead
+// of the current time, for adapters logging events that happened earlier, such as
+// queued webhooks or imported records. A zero t falls back to the clock.
+func At(t time.Time) Logger {
+	log := directLogger()
+	if t.IsZero() {
+		return log
+	}
+	ingest := currentOptions().ingestTime
+	return log.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &timeCore{Core: core, at: t, ingest: ingest}
+	})).Sugar()
+}
+
+// timeCore sets the time of the entries it checks.
+type timeCore struct {
+	zapcore.Core
+	at     time.Time
+	ingest bool // Add the write time under IngestedAtKey
+}
+
+// With implements zapcore.Core.
+func (c *timeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &timeCore{Core: c.Core.With(fields), at: c.at, ingest: c.ingest}
+}
+
+// Check implements zapcore.Core. The cores below check the entry with the time set by
+// the logger, so that sampling keeps counting in real time; the time is replaced in the
+// checked entry, which is what they write. The time set by the logger, the write time,
+// is kept as a field when requested.
+func (c *timeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	core := c.Core
+	if c.ingest {
+		core = core.With([]zapcore.Field{zap.Time(IngestedAtKey, ent.Time)})
+	}
+	if ce = core.Check(ent, ce); ce != nil {
+		ce.Time = c.at
+	}
+	return ce
+}
@@ -0,0 +1,75 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// iso8601 is the layout of the times written by the production encoder.
+const iso8601 = "2006-01-02T15:04:05.000Z0700"
+
+func TestAt(t *testing.T) {
+	read := initializeFile(t, sazabi.ProductionEnvName)
+
+	sazabi.At(time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)).Infow("queued webhook", "id", 7)
+
+	output := read()
+	if !strings.HasPrefix(output, "2001-02-03T04:05:06.000Z\tINFO\t") {
+		t.Errorf("output = %q, want the overridden time", output)
+	}
+	if _, ok := entryFields(t, output, "queued webhook")[sazabi.IngestedAtKey]; ok {
+		t.Errorf("output = %q, want no %s without WithIngestTime", output, sazabi.IngestedAtKey)
+	}
+}
+
+func TestAtIngestTime(t *testing.T) {
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithIngestTime())
+
+	before := time.Now().Add(-time.Second)
+	sazabi.At(time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)).Infow("imported record", "source", "import")
+
+	output := read()
+	if !strings.HasPrefix(output, "2001-02-03T04:05:06.000Z\t") {
+		t.Errorf("output = %q, want the overridden time", output)
+	}
+	fields := entryFields(t, output, "imported record")
+	ingested, err := time.Parse(iso8601, fields[sazabi.IngestedAtKey].(string))
+	if err != nil || ingested.Before(before) || ingested.After(time.Now()) {
+		t.Errorf("%s = %v (%v), want the write time", sazabi.IngestedAtKey, fields[sazabi.IngestedAtKey], err)
+	}
+	if fields["source"] != "import" {
+		t.Errorf("fields = %v, want source", fields)
+	}
+}
+
+func TestAtZeroTime(t *testing.T) {
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithIngestTime())
+
+	before := time.Now().Add(-time.Second)
+	sazabi.At(time.Time{}).Info("clock entry")
+
+	output := read()
+	written, err := time.Parse(iso8601, strings.SplitN(output, "\t", 2)[0])
+	if err != nil || written.Before(before) || written.After(time.Now()) {
+		t.Errorf("output = %q (%v), want the current time", output, err)
+	}
+	if strings.Contains(output, sazabi.IngestedAtKey) {
+		t.Errorf("output = %q, want no %s for clock times", output, sazabi.IngestedAtKey)
+	}
+}
+
+func TestAtLevel(t *testing.T) {
+	read := initializeFile(t, sazabi.ProductionEnvName)
+
+	sazabi.At(time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)).Debug("debug entry")
+
+	if output := read(); output != "" {
+		t.Errorf("output = %q, want nothing below Info", output)
+	}
+}
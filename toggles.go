@@ -0,0 +1,88 @@
+package sazabi
+
+import (
+	"errors"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrNotInitialized is returned by runtime settings changed before Initialize.
+var ErrNotInitialized = errors.New("sazabi: logger is not initialized")
+
+// SetLevel sets the minimum level of the global logger, for example "debug", without
+// re-initializing it: subsequent entries are filtered at the new level, including those
+// of loggers derived from the global logger. The level lasts until it is changed again
+// or the logger is re-initialized.
+func SetLevel(level string) error {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	in := loadInstance()
+	if in == nil {
+		return ErrNotInitialized
+	}
+	in.config.Level.SetLevel(lvl)
+	return nil
+}
+
+// GetLevel returns the current minimum level of the global logger, such as "info", or
+// an empty string before Initialize.
+func GetLevel() string {
+	in := loadInstance()
+	if in == nil {
+		return ""
+	}
+	return in.config.Level.String()
+}
+
+// SetCallerEnabled turns caller capture on or off for subsequent entries of the global
+// logger without re-initializing it. Outputs, fields and extensions are preserved.
+// The setting lasts until it is changed again or the logger is re-initialized.
+func SetCallerEnabled(enabled bool) error {
+	return updateInstance(func(in *instance) error {
+		in.callerEnabled = enabled
+		return nil
+	})
+}
+
+// SetStacktraceLevel sets the minimum level at which entries of the global logger carry
+// a stacktrace, for example "error". An empty level or "none" disables stacktraces.
+// The setting lasts until it is changed again or the logger is re-initialized.
+func SetStacktraceLevel(level string) error {
+	if level == "" || strings.EqualFold(level, "none") {
+		return updateInstance(func(in *instance) error {
+			in.stacktraceOn = false
+			return nil
+		})
+	}
+
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	return updateInstance(func(in *instance) error {
+		in.stacktraceOn = true
+		in.stacktraceLevel = lvl
+		return nil
+	})
+}
+
+// updateInstance applies fn to a copy of the current instance and publishes the result.
+func updateInstance(fn func(*instance) error) error {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	current := loadInstance()
+	if current == nil {
+		return ErrNotInitialized
+	}
+
+	next := *current
+	if err := fn(&next); err != nil {
+		return err
+	}
+	publish(&next)
+	return nil
+}
@@ -0,0 +1,144 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestSetCallerEnabled(t *testing.T) {
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		sazabi.Info("caller entry 1")
+		if err := sazabi.SetCallerEnabled(false); err != nil {
+			t.Errorf("SetCallerEnabled(false) error = %v", err)
+		}
+		sazabi.Info("caller entry 2")
+		if err := sazabi.SetCallerEnabled(true); err != nil {
+			t.Errorf("SetCallerEnabled(true) error = %v", err)
+		}
+		sazabi.Info("caller entry 3")
+	})
+
+	tests := []struct {
+		message    string
+		wantCaller bool
+	}{
+		{message: "caller entry 1", wantCaller: true},
+		{message: "caller entry 2", wantCaller: false},
+		{message: "caller entry 3", wantCaller: true},
+	}
+	for _, tt := range tests {
+		line := lineContaining(output, tt.message)
+		if line == "" {
+			t.Errorf("entry %q was dropped, output: %s", tt.message, output)
+			continue
+		}
+		if got := strings.Contains(line, "toggles_test.go:"); got != tt.wantCaller {
+			t.Errorf("entry %q has caller = %t, want %t: %s", tt.message, got, tt.wantCaller, line)
+		}
+	}
+}
+
+func TestSetStacktraceLevel(t *testing.T) {
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		sazabi.Error("stack entry 1")
+		if err := sazabi.SetStacktraceLevel("warn"); err != nil {
+			t.Errorf("SetStacktraceLevel(warn) error = %v", err)
+		}
+		sazabi.Warn("stack entry 2")
+		sazabi.Info("stack entry 3")
+		if err := sazabi.SetStacktraceLevel("none"); err != nil {
+			t.Errorf("SetStacktraceLevel(none) error = %v", err)
+		}
+		sazabi.Error("stack entry 4")
+	})
+
+	entries := strings.Split(output, "\n")
+	stacks := map[string]bool{}
+	for i, line := range entries {
+		for n := 1; n <= 4; n++ {
+			message := fmt.Sprintf("stack entry %d", n)
+			if strings.Contains(line, message) {
+				stacks[message] = i+1 < len(entries) && strings.Contains(entries[i+1], "TestSetStacktraceLevel")
+			}
+		}
+	}
+
+	want := map[string]bool{
+		"stack entry 1": false,
+		"stack entry 2": true,
+		"stack entry 3": false,
+		"stack entry 4": false,
+	}
+	for message, wantStack := range want {
+		gotStack, ok := stacks[message]
+		if !ok {
+			t.Errorf("entry %q was dropped, output: %s", message, output)
+			continue
+		}
+		if gotStack != wantStack {
+			t.Errorf("entry %q has stacktrace = %t, want %t", message, gotStack, wantStack)
+		}
+	}
+}
+
+func TestSetStacktraceLevelInvalid(t *testing.T) {
+	sazabi.Initialize(sazabi.ProductionEnvName)
+
+	if err := sazabi.SetStacktraceLevel("verbose"); err == nil {
+		t.Error("SetStacktraceLevel() should reject an unknown level")
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	var levels []string
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		levels = append(levels, sazabi.GetLevel())
+		sazabi.Debug("level entry 1")
+		if err := sazabi.SetLevel("debug"); err != nil {
+			t.Errorf("SetLevel(debug) error = %v", err)
+		}
+		levels = append(levels, sazabi.GetLevel())
+		sazabi.Debug("level entry 2")
+		sazabi.Debugw("level entry 3", "key", "value")
+		if err := sazabi.SetLevel("info"); err != nil {
+			t.Errorf("SetLevel(info) error = %v", err)
+		}
+		levels = append(levels, sazabi.GetLevel())
+		sazabi.Debugw("level entry 4", "key", "value")
+	})
+
+	if want := []string{"info", "debug", "info"}; strings.Join(levels, ",") != strings.Join(want, ",") {
+		t.Errorf("GetLevel() = %v, want %v", levels, want)
+	}
+	want := map[string]bool{
+		"level entry 1": false,
+		"level entry 2": true,
+		"level entry 3": true,
+		"level entry 4": false,
+	}
+	for message, wantWritten := range want {
+		if got := lineContaining(output, message) != ""; got != wantWritten {
+			t.Errorf("entry %q written = %t, want %t, output: %s", message, got, wantWritten, output)
+		}
+	}
+}
+
+func TestSetLevelInvalid(t *testing.T) {
+	captureStderr(t, func() { sazabi.Initialize(sazabi.ProductionEnvName) })
+
+	if err := sazabi.SetLevel("loud"); err == nil {
+		t.Error("SetLevel(loud) error = nil, want an error")
+	}
+	if got := sazabi.GetLevel(); got != "info" {
+		t.Errorf("GetLevel() = %q after an invalid level, want info", got)
+	}
+}
@@ -0,0 +1,79 @@
+package sazabi
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// MsgLocalizedKey is the key of the translated message added to entries of structured
+// encodings by WithMessageTranslator.
+const MsgLocalizedKey = "msg_localized"
+
+// WithMessageTranslator translates messages for operators. With the console encoding,
+// the rendered message is replaced by its translation; other encodings keep the original
+// message, so that it stays stable for support, and add the translation under
+// MsgLocalizedKey. The translator receives the message and the fields of the entry,
+// without those bound with With. Returning the message unchanged or an empty string
+// leaves the entry untouched, and so does a translator that panics.
+func WithMessageTranslator(fn func(msg string, fields []Field) string) Option {
+	return func(o *options) {
+		o.messageTranslator = fn
+	}
+}
+
+// translateCore applies a message translator to the entries it writes.
+type translateCore struct {
+	zapcore.Core
+	translate func(string, []Field) string
+	console   bool // Replace the message instead of adding MsgLocalizedKey
+}
+
+// newTranslateCore wraps core with the translator of o, or returns core when there is
+// none. encoding is the name of the encoding of core.
+func newTranslateCore(core zapcore.Core, encoding string, o *options) zapcore.Core {
+	if o.messageTranslator == nil {
+		return core
+	}
+	return &translateCore{
+		Core:      core,
+		translate: o.messageTranslator,
+		console:   encoding == "console" || encoding == fullLineColorEncoding,
+	}
+}
+
+// With implements zapcore.Core.
+func (c *translateCore) With(fields []zapcore.Field) zapcore.Core {
+	return &translateCore{Core: c.Core.With(fields), translate: c.translate, console: c.console}
+}
+
+// Check implements zapcore.Core.
+func (c *translateCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *translateCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	localized := c.safeTranslate(ent.Message, fields)
+	switch {
+	case localized == "" || localized == ent.Message:
+	case c.console:
+		ent.Message = localized
+	default:
+		fields = append(fields[:len(fields):len(fields)], zap.String(MsgLocalizedKey, localized))
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// safeTranslate returns the translation of msg, or an empty string if the translator
+// panics.
+func (c *translateCore) safeTranslate(msg string, fields []zapcore.Field) (localized string) {
+	defer func() {
+		if recover() != nil {
+			localized = ""
+		}
+	}()
+	return c.translate(msg, fields)
+}
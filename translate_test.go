@@ -0,0 +1,78 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+// translateToFrench translates the messages it knows, using the disk field.
+func translateToFrench(msg string, fields []sazabi.Field) string {
+	if msg != "disk almost full" {
+		return msg
+	}
+	for _, f := range fields {
+		if f.Key == "disk" {
+			return "disque " + f.String + " presque plein"
+		}
+	}
+	return "disque presque plein"
+}
+
+func TestMessageTranslatorConsole(t *testing.T) {
+	log, output := newFileLogger(t, sazabi.ProductionEnvName, sazabi.WithMessageTranslator(translateToFrench))
+	log.Warnw("disk almost full", "disk", "/var")
+	log.Info("not translated")
+
+	out := output()
+	if line := lineContaining(out, "/var"); !strings.Contains(line, "\tdisque /var presque plein\t") {
+		t.Errorf("console line = %q, want the translated message", line)
+	}
+	if strings.Contains(out, sazabi.MsgLocalizedKey) {
+		t.Errorf("console output should not carry %s:\n%s", sazabi.MsgLocalizedKey, out)
+	}
+	if lineContaining(out, "not translated") == "" {
+		t.Errorf("untranslated message missing:\n%s", out)
+	}
+}
+
+func TestMessageTranslatorJSON(t *testing.T) {
+	log, output := newFileLogger(t, "development",
+		sazabi.WithCIEncoding("json"), sazabi.WithInteractive(false), sazabi.WithMessageTranslator(translateToFrench))
+	log.Warnw("disk almost full", "disk", "/var")
+	log.Info("not translated")
+
+	lines := strings.Split(strings.TrimSpace(output()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var translated, untranslated map[string]interface{} // Development keys: "M" is the message
+	if err := json.Unmarshal([]byte(lines[0]), &translated); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &untranslated); err != nil {
+		t.Fatal(err)
+	}
+	if translated["M"] != "disk almost full" || translated[sazabi.MsgLocalizedKey] != "disque /var presque plein" {
+		t.Errorf("entry = %v, want the original message and the translation", translated)
+	}
+	if _, ok := untranslated[sazabi.MsgLocalizedKey]; ok {
+		t.Errorf("entry = %v, want no %s without translation", untranslated, sazabi.MsgLocalizedKey)
+	}
+}
+
+func TestMessageTranslatorPanic(t *testing.T) {
+	log, output := newFileLogger(t, sazabi.ProductionEnvName, sazabi.WithMessageTranslator(func(string, []sazabi.Field) string {
+		panic("broken catalog")
+	}))
+	log.Info("still written")
+
+	if line := lineContaining(output(), "still written"); line == "" {
+		t.Error("entry should be written with its original message when the translator panics")
+	}
+}
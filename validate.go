@@ -0,0 +1,106 @@
+package sazabi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Keys of the fields of the entries replacing invalid output.
+const (
+	EncodeErrorKey        = "encode_error"         // Always true
+	EncodeErrorMessageKey = "encode_error_message" // Reason the output was rejected
+)
+
+// outputValidators maps encodings to the function checking that an encoded entry is
+// well-formed. Encodings missing from the map are never checked.
+var outputValidators = map[string]func(zapcore.Entry, []byte) error{
+	"json":          validateJSON,
+	"console":       validateConsole,
+	gcpEncoding:     validateJSON,
+	datadogEncoding: validateJSON,
+	ecsEncoding:     validateJSON,
+}
+
+// invalidOutputs counts the entries replaced by WithOutputValidation.
+var invalidOutputs int64
+
+// WithOutputValidation checks that every entry is well-formed after encoding: a single
+// JSON value with the JSON encoding, and a single line whose fields are a JSON object
+// with the console encoding (stack traces excepted). An invalid entry is
+// replaced by a JSON entry with the original message, EncodeErrorKey set to true and
+// the reason under EncodeErrorMessageKey, and counted by InvalidOutputCount. Intended
+// for staging environments, since it costs a parse per entry.
+func WithOutputValidation() Option {
+	return func(o *options) {
+		o.outputValidation = true
+	}
+}
+
+// InvalidOutputCount returns the number of entries replaced by WithOutputValidation.
+func InvalidOutputCount() int64 {
+	return atomic.LoadInt64(&invalidOutputs)
+}
+
+// validateJSON checks that line is a single valid JSON value.
+func validateJSON(_ zapcore.Entry, line []byte) error {
+	if !json.Valid(bytes.TrimRight(line, "\r\n")) {
+		return errors.New("invalid JSON")
+	}
+	return nil
+}
+
+// validateConsole checks that line, a console entry, holds a single line followed by
+// the stack trace of ent, if any, and that its fields, if any, are a JSON object.
+func validateConsole(ent zapcore.Entry, line []byte) error {
+	line = bytes.TrimRight(line, "\r\n")
+	if ent.Stack != "" {
+		line = bytes.TrimSuffix(line, []byte("\n"+ent.Stack))
+	}
+	if bytes.ContainsAny(line, "\r\n") {
+		return errors.New("entry spans several lines")
+	}
+	if i := bytes.LastIndex(line, []byte("\t{")); i >= 0 && bytes.HasSuffix(line, []byte("}")) {
+		if !json.Valid(line[i+1:]) {
+			return errors.New("invalid JSON fields")
+		}
+	}
+	return nil
+}
+
+// outputValidator checks encoded entries and builds their replacement.
+type outputValidator struct {
+	validate    func(zapcore.Entry, []byte) error // Returns why an encoded entry is invalid
+	replacement zapcore.Encoder                   // Trusted encoder for replacement entries
+}
+
+// newOutputValidator returns the validator of conf's encoding, or nil when the
+// encoding has none.
+func newOutputValidator(conf zap.Config) *outputValidator {
+	validate, ok := outputValidators[conf.Encoding]
+	if !ok {
+		return nil
+	}
+	return &outputValidator{validate: validate, replacement: zapcore.NewJSONEncoder(conf.EncoderConfig)}
+}
+
+// check returns buf when it holds a valid entry, or frees it and returns the encoded
+// replacement of ent.
+func (v *outputValidator) check(ent zapcore.Entry, buf *buffer.Buffer) (*buffer.Buffer, error) {
+	err := v.validate(ent, buf.Bytes())
+	if err == nil {
+		return buf, nil
+	}
+	buf.Free()
+
+	atomic.AddInt64(&invalidOutputs, 1)
+	return v.replacement.EncodeEntry(ent, []zapcore.Field{
+		zap.Bool(EncodeErrorKey, true),
+		zap.String(EncodeErrorMessageKey, err.Error()),
+	})
+}
@@ -0,0 +1,87 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// brokenEncoder is a JSON encoder emitting invalid JSON for entries with a "bad" field.
+type brokenEncoder struct {
+	zapcore.Encoder
+}
+
+func (e brokenEncoder) Clone() zapcore.Encoder {
+	return brokenEncoder{e.Encoder.Clone()}
+}
+
+func (e brokenEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	for _, f := range fields {
+		if f.Key == "bad" {
+			buf.TrimNewline()
+			buf.AppendString(`,}` + "\n")
+		}
+	}
+	return buf, err
+}
+
+func TestOutputValidation(t *testing.T) {
+	encoders["broken-json"] = func(conf zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return brokenEncoder{zapcore.NewJSONEncoder(conf)}, nil
+	}
+	outputValidators["broken-json"] = validateJSON
+	defer func() {
+		delete(encoders, "broken-json")
+		delete(outputValidators, "broken-json")
+	}()
+
+	conf := newProductionConfig()
+	conf.Encoding = "broken-json"
+	path := filepath.Join(t.TempDir(), "validation.log")
+	conf.OutputPaths = []string{path}
+
+	before := InvalidOutputCount()
+	log, err := build(conf, &options{outputValidation: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Sugar().Infow("fine entry", "good", 1)
+	log.Sugar().Infow("broken entry", "bad", 1)
+	log.Sync()
+
+	if got := InvalidOutputCount() - before; got != 1 {
+		t.Errorf("InvalidOutputCount() increased by %d, want 1", got)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrote %d lines, want 2:\n%s", len(lines), data)
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("invalid line written: %s", line)
+		}
+	}
+
+	var replacement map[string]interface{}
+	json.Unmarshal([]byte(lines[1]), &replacement)
+	if replacement["msg"] != "broken entry" || replacement[EncodeErrorKey] != true || replacement[EncodeErrorMessageKey] == nil {
+		t.Errorf("replacement entry = %v, want message, %s and %s", replacement, EncodeErrorKey, EncodeErrorMessageKey)
+	}
+	if _, ok := replacement["bad"]; ok {
+		t.Errorf("replacement entry kept the original fields: %v", replacement)
+	}
+}
@@ -0,0 +1,60 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestOutputValidationConsole(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, sazabi.ProductionEnvName, sazabi.WithOutputValidation())
+	before := sazabi.InvalidOutputCount()
+
+	sazabi.Info("first line\nsecond line")
+	sazabi.Infow("fine entry", "value", "escaped\nin the fields")
+	sazabi.Error("failed entry") // Multi-line with its stack trace, still valid
+
+	if got := sazabi.InvalidOutputCount() - before; got != 1 {
+		t.Errorf("InvalidOutputCount() increased by %d, want 1", got)
+	}
+	output := read()
+	replacement := lineContaining(output, sazabi.EncodeErrorKey)
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(replacement), &fields); err != nil {
+		t.Fatalf("replacement entry %q is not JSON: %v", replacement, err)
+	}
+	if fields["msg"] != "first line\nsecond line" || fields[sazabi.EncodeErrorMessageKey] != "entry spans several lines" {
+		t.Errorf("replacement entry = %v, want the message and the reason", fields)
+	}
+	if lineContaining(output, "fine entry") == "" || lineContaining(output, "failed entry") == "" {
+		t.Errorf("valid entries missing from the output:\n%s", output)
+	}
+	if strings.Contains(output, "\nsecond line") {
+		t.Errorf("invalid entry written:\n%s", output)
+	}
+}
+
+func TestOutputValidationJSON(t *testing.T) {
+	restoreDefault(t)
+	read := initializeFile(t, "development", sazabi.WithInteractive(false), sazabi.WithCIEncoding("json"),
+		sazabi.WithOutputValidation())
+	before := sazabi.InvalidOutputCount()
+
+	sazabi.Info("first line\nsecond line")
+	sazabi.Infow("raw", sazabi.RawJSON("payload", []byte(`{"a":[1,2]}`)))
+
+	if got := sazabi.InvalidOutputCount() - before; got != 0 {
+		t.Errorf("InvalidOutputCount() increased by %d, want 0", got)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(read()), "\n") {
+		if !json.Valid([]byte(line)) || strings.Contains(line, sazabi.EncodeErrorKey) {
+			t.Errorf("line %q, want valid entries kept", line)
+		}
+	}
+}
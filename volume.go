@@ -0,0 +1,260 @@
+package sazabi
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// VolumeReportMessage is the message of the entries emitted by WithVolumeReport.
+const VolumeReportMessage = "log_volume_report"
+
+// RootLoggerName is the name VolumeStats reports entries of unnamed loggers under.
+const RootLoggerName = "_root"
+
+// volumeShards is the number of counter shards per logger name, spreading concurrent
+// writers over different cache lines.
+const volumeShards = 8
+
+// VolumeStat is the volume written by the loggers of one name.
+type VolumeStat struct {
+	Entries int64 // Number of entries written
+	Bytes   int64 // Number of encoded bytes written
+}
+
+// volumeCounter counts the entries of one logger name.
+type volumeCounter struct {
+	shards [volumeShards]struct {
+		entries int64
+		bytes   int64
+		_       [48]byte // Padding to a cache line
+	}
+}
+
+// volumes maps logger names to their *volumeCounter.
+var volumes sync.Map
+
+// VolumeStats returns the number of entries and bytes written per logger name since
+// the start of the process or the last ResetVolumeStats. Entries of unnamed loggers
+// are reported under RootLoggerName.
+func VolumeStats() map[string]VolumeStat {
+	stats := make(map[string]VolumeStat)
+	volumes.Range(func(key, value interface{}) bool {
+		var stat VolumeStat
+		c := value.(*volumeCounter)
+		for i := range c.shards {
+			stat.Entries += atomic.LoadInt64(&c.shards[i].entries)
+			stat.Bytes += atomic.LoadInt64(&c.shards[i].bytes)
+		}
+		if stat.Entries > 0 {
+			stats[key.(string)] = stat
+		}
+		return true
+	})
+	return stats
+}
+
+// ResetVolumeStats sets every counter reported by VolumeStats back to zero.
+func ResetVolumeStats() {
+	volumes.Range(func(_, value interface{}) bool {
+		c := value.(*volumeCounter)
+		for i := range c.shards {
+			atomic.StoreInt64(&c.shards[i].entries, 0)
+			atomic.StoreInt64(&c.shards[i].bytes, 0)
+		}
+		return true
+	})
+}
+
+// countVolume adds one entry of size bytes to the counters of name.
+func countVolume(name string, size int, shard int) {
+	if name == "" {
+		name = RootLoggerName
+	}
+	value, ok := volumes.Load(name)
+	if !ok {
+		value, _ = volumes.LoadOrStore(name, &volumeCounter{})
+	}
+	s := &value.(*volumeCounter).shards[shard%volumeShards]
+	atomic.AddInt64(&s.entries, 1)
+	atomic.AddInt64(&s.bytes, int64(size))
+}
+
+// volumeCore is zapcore.NewCore counting the entries and bytes it writes per logger name.
+type volumeCore struct {
+	zapcore.LevelEnabler
+	enc       zapcore.Encoder
+	out       zapcore.WriteSyncer
+	validator *outputValidator // Checks encoded entries, nil without WithOutputValidation
+	global    bool             // Whether entries count towards VolumeStats and the volume budget
+	metrics   MetricsCollector // Counts the entries written, nil without WithMetricsCollector
+}
+
+// newVolumeCore returns a core writing entries encoded by enc to out.
+func newVolumeCore(enc zapcore.Encoder, out zapcore.WriteSyncer, enab zapcore.LevelEnabler) *volumeCore {
+	return &volumeCore{LevelEnabler: enab, enc: enc, out: out}
+}
+
+// Level implements zapcore.LevelOf.
+func (c *volumeCore) Level() zapcore.Level {
+	return zapcore.LevelOf(c.LevelEnabler)
+}
+
+// With implements zapcore.Core.
+func (c *volumeCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &volumeCore{LevelEnabler: c.LevelEnabler, enc: enc, out: c.out, validator: c.validator, global: c.global, metrics: c.metrics}
+}
+
+// Check implements zapcore.Core.
+func (c *volumeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *volumeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		c.countDropped()
+		return err
+	}
+	if c.validator != nil {
+		if buf, err = c.validator.check(ent, buf); err != nil {
+			c.countDropped()
+			return err
+		}
+	}
+	size := buf.Len()
+	_, err = c.out.Write(buf.Bytes())
+	buf.Free()
+	if c.global {
+		countVolume(ent.LoggerName, size, ent.Time.Nanosecond())
+		if b := loadBudget(); b != nil {
+			b.add(size)
+		}
+	}
+	if err != nil {
+		c.countDropped()
+		return err
+	}
+	if c.global {
+		countLevel(ent.Level, ent.Time)
+	}
+	if c.metrics != nil {
+		c.metrics.EntryWritten(ent.Level)
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		c.Sync() // Flush before a panic or exit, as zapcore.NewCore does
+	}
+	return nil
+}
+
+// countDropped counts an entry that could not be written, for the global logger and
+// the metrics collector.
+func (c *volumeCore) countDropped() {
+	if c.global {
+		atomic.AddInt64(&droppedCount, 1)
+	}
+	if c.metrics != nil {
+		c.metrics.WriteFailed()
+	}
+}
+
+// Sync implements zapcore.Core.
+func (c *volumeCore) Sync() error {
+	return c.out.Sync()
+}
+
+// WithVolumeReport makes the global logger emit a VolumeReportMessage entry every
+// interval listing the top logger names by number of entries (see VolumeStats).
+func WithVolumeReport(interval time.Duration, top int) Option {
+	return func(o *options) {
+		o.volumeReportInterval = interval
+		o.volumeReportTop = top
+	}
+}
+
+// stopVolumeReport stops the report of the current global logger, if any. Guarded by initMu.
+var stopVolumeReport func()
+
+// startVolumeReport replaces the running volume report with the one configured by o.
+// initMu must be held.
+func startVolumeReport(o *options) {
+	if stopVolumeReport != nil {
+		stopVolumeReport()
+		stopVolumeReport = nil
+	}
+	if o.volumeReportInterval <= 0 || o.volumeReportTop <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(o.volumeReportInterval)
+	stop := make(chan struct{})
+	go runVolumeReport(ticker.C, stop, o.volumeReportTop)
+	stopVolumeReport = func() {
+		ticker.Stop()
+		close(stop)
+	}
+}
+
+// runVolumeReport emits a volume report every time tick fires, until stop is closed.
+func runVolumeReport(tick <-chan time.Time, stop <-chan struct{}, top int) {
+	for {
+		select {
+		case <-tick:
+			emitVolumeReport(top)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// volumeTalker is one logger name listed in a volume report.
+type volumeTalker struct {
+	Name    string `json:"name"`
+	Entries int64  `json:"entries"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// emitVolumeReport logs the top logger names by number of entries, then bytes.
+func emitVolumeReport(top int) {
+	directLogger().Desugar().WithOptions(zap.WithCaller(false)).Info(VolumeReportMessage,
+		zap.Any("top", topTalkers(top, false)),
+	)
+}
+
+// topTalkers returns the top logger names by number of entries then bytes, or by bytes
+// then entries when byBytes is set.
+func topTalkers(top int, byBytes bool) []volumeTalker {
+	var talkers []volumeTalker
+	for name, stat := range VolumeStats() {
+		talkers = append(talkers, volumeTalker{Name: name, Entries: stat.Entries, Bytes: stat.Bytes})
+	}
+	sort.Slice(talkers, func(i, j int) bool {
+		first, second := talkers[i].Entries-talkers[j].Entries, talkers[i].Bytes-talkers[j].Bytes
+		if byBytes {
+			first, second = second, first
+		}
+		if first != 0 {
+			return first > 0
+		}
+		if second != 0 {
+			return second > 0
+		}
+		return talkers[i].Name < talkers[j].Name
+	})
+	if len(talkers) > top {
+		talkers = talkers[:top]
+	}
+	return talkers
+}
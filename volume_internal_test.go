@@ -0,0 +1,48 @@
+//go:build test
+// +build test
+
+package sazabi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVolumeReport(t *testing.T) {
+	Initialize(ProductionEnvName, WithOutputPaths(t.TempDir()+"/volume.log"))
+	ResetVolumeStats()
+	for name, entries := range map[string]int{"worker": 4, "http": 2, "db": 3} {
+		for i := 0; i < entries; i++ {
+			Named(name).Info("volume entry")
+		}
+	}
+	Info("volume entry")
+
+	capture, stop := StartCapture()
+	defer stop()
+
+	tick := make(chan time.Time)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		runVolumeReport(tick, done, 3)
+		close(finished)
+	}()
+	tick <- time.Time{}
+	close(done)
+	<-finished
+
+	entries := capture.Entries()
+	if len(entries) != 1 || entries[0].Message != VolumeReportMessage {
+		t.Fatalf("captured %v, want one volume report", entries)
+	}
+	field, _ := entries[0].Field("top")
+	var names []string
+	for _, talker := range field.Interface.([]volumeTalker) {
+		names = append(names, talker.Name)
+	}
+	if want := []string{"worker", "db", "http"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("top talkers = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+)
+
+func TestVolumeStats(t *testing.T) {
+	output := captureStderr(t, func() {
+		sazabi.Initialize(sazabi.ProductionEnvName)
+		sazabi.ResetVolumeStats()
+
+		db := sazabi.Named("db")
+		for i := 0; i < 3; i++ {
+			db.Info("volume db")
+		}
+		sazabi.Named("http").Info("volume http")
+		sazabi.Info("volume root")
+		sazabi.Named("http").Debug("below level") // Not written, not counted
+	})
+
+	stats := sazabi.VolumeStats()
+	want := map[string]int64{"db": 3, "http": 1, sazabi.RootLoggerName: 1}
+	for name, entries := range want {
+		if got := stats[name].Entries; got != entries {
+			t.Errorf("VolumeStats()[%q].Entries = %d, want %d", name, got, entries)
+		}
+	}
+
+	var bytes int64
+	for _, stat := range stats {
+		bytes += stat.Bytes
+	}
+	if bytes != int64(len(output)) {
+		t.Errorf("total bytes = %d, want the %d bytes written", bytes, len(output))
+	}
+
+	sazabi.ResetVolumeStats()
+	if stats := sazabi.VolumeStats(); len(stats) != 0 {
+		t.Errorf("VolumeStats() after reset = %v, want empty", stats)
+	}
+}
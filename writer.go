@@ -0,0 +1,81 @@
+package sazabi
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// maxWriterLineBytes is the size of the longest message logged by Writer.
+const maxWriterLineBytes = 64 << 10
+
+// Writer returns a writer logging each line written to it as an entry of the global
+// logger at level, such as "info", for tools reporting diagnostics to an io.Writer. Lines
+// end with "\n" or "\r\n", which are not part of the messages, and empty lines are
+// skipped. The end of a line split across writes waits for its newline, and lines longer
+// than 64 KiB are logged in 64 KiB pieces. Close logs the pending partial line; the
+// writer can still be used afterwards. It is safe for concurrent use, lines being
+// logged in the order they are completed.
+func Writer(level string) (io.WriteCloser, error) {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	return &lineWriter{level: lvl}, nil
+}
+
+// lineWriter logs the lines written to it.
+type lineWriter struct {
+	level zapcore.Level
+	mu    sync.Mutex
+	buf   []byte // Partial line waiting for its newline
+}
+
+// Write implements io.Writer.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		line := w.buf
+		if len(line) > maxWriterLineBytes {
+			line = line[:maxWriterLineBytes]
+		}
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			w.log(bytes.TrimSuffix(line[:i], []byte("\r")))
+			w.buf = w.buf[i+1:]
+		} else if len(line) == maxWriterLineBytes {
+			w.log(line)
+			w.buf = w.buf[len(line):]
+		} else {
+			break
+		}
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil // Release the memory of long payloads
+	}
+	return len(p), nil
+}
+
+// Close implements io.Closer, logging the pending partial line.
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.log(w.buf)
+	w.buf = nil
+	return nil
+}
+
+// log writes line as an entry, unless it is empty. w.mu must be held.
+func (w *lineWriter) log(line []byte) {
+	if len(line) == 0 {
+		return
+	}
+	if ce := typedLogger().Check(w.level, string(line)); ce != nil {
+		ce.Write()
+	}
+}
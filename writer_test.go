@@ -0,0 +1,123 @@
+//go:build test
+// +build test
+
+package sazabi_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zeroxsolutions/sazabi"
+	"go.uber.org/zap/zapcore"
+)
+
+// messages returns the messages of the entries captured by c.
+func messages(c *sazabi.Capture) []string {
+	var msgs []string
+	for _, e := range c.Entries() {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+func TestWriter(t *testing.T) {
+	restoreDefault(t)
+	c, stop := sazabi.StartCapture()
+	defer stop()
+
+	w, err := sazabi.Writer("warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "first\nsecond\r\n\nthi")
+	if got := messages(c); strings.Join(got, "|") != "first|second" {
+		t.Errorf("messages after the first write = %q, want first and second", got)
+	}
+	io.WriteString(w, "rd\nfou")
+	io.WriteString(w, "rth")
+	if got := messages(c); strings.Join(got, "|") != "first|second|third" {
+		t.Errorf("messages before Close = %q, want the partial line held back", got)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := c.Entries()
+	if got := messages(c); strings.Join(got, "|") != "first|second|third|fourth" {
+		t.Errorf("messages after Close = %q, want one entry per line", got)
+	}
+	for _, e := range entries {
+		if e.Level != zapcore.WarnLevel {
+			t.Errorf("entry %q at %v, want warn", e.Message, e.Level)
+		}
+	}
+}
+
+func TestWriterLongLine(t *testing.T) {
+	restoreDefault(t)
+	c, stop := sazabi.StartCapture()
+	defer stop()
+
+	w, err := sazabi.Writer("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := strings.Repeat("x", 64<<10)
+	io.WriteString(w, long[:1000])
+	io.WriteString(w, long[1000:]+"tail\n")
+
+	got := messages(c)
+	if len(got) != 2 || got[0] != long || got[1] != "tail" {
+		t.Errorf("got %d entries, want the first 64 KiB logged without waiting for the newline, then tail", len(got))
+	}
+}
+
+func TestWriterConcurrent(t *testing.T) {
+	restoreDefault(t)
+	c, stop := sazabi.StartCapture()
+	defer stop()
+
+	w, err := sazabi.Writer("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const writers, lines = 8, 100
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				fmt.Fprintf(w, "writer %d line %d\n", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, msg := range messages(c) {
+		seen[msg] = true
+	}
+	for i := 0; i < writers; i++ {
+		for j := 0; j < lines; j++ {
+			if msg := fmt.Sprintf("writer %d line %d", i, j); !seen[msg] {
+				t.Fatalf("no entry %q among %d entries", msg, len(seen))
+			}
+		}
+	}
+	if len(seen) != writers*lines {
+		t.Errorf("got %d distinct entries, want %d", len(seen), writers*lines)
+	}
+}
+
+func TestWriterInvalidLevel(t *testing.T) {
+	if _, err := sazabi.Writer("loud"); err == nil {
+		t.Error("Writer(\"loud\") succeeded, want an error")
+	}
+}
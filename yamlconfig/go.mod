@@ -0,0 +1,16 @@
+module github.com/zeroxsolutions/sazabi/yamlconfig
+
+go 1.18
+
+require (
+	github.com/zeroxsolutions/sazabi v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/zeroxsolutions/barbatos v0.0.1 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	go.uber.org/zap v1.27.0 // indirect
+)
+
+replace github.com/zeroxsolutions/sazabi => ../
@@ -0,0 +1,27 @@
+// Package yamlconfig registers the YAML decoder of sazabi.LoadConfig for files ending
+// in .yaml or .yml. Import it for its side effects:
+//
+//	import _ "github.com/zeroxsolutions/sazabi/yamlconfig"
+//
+//	cfg, err := sazabi.LoadConfig("/etc/app/logging.yaml")
+package yamlconfig
+
+import (
+	"bytes"
+
+	"github.com/zeroxsolutions/sazabi"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	sazabi.RegisterConfigDecoder(".yaml", Decode)
+	sazabi.RegisterConfigDecoder(".yml", Decode)
+}
+
+// Decode decodes the YAML document in data into v, rejecting unknown fields. Durations
+// are written like "1h30m".
+func Decode(data []byte, v interface{}) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	return dec.Decode(v)
+}
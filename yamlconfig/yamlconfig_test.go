@@ -0,0 +1,64 @@
+//go:build test
+// +build test
+
+package yamlconfig_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeroxsolutions/sazabi"
+	"github.com/zeroxsolutions/sazabi/yamlconfig"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := sazabi.LoadConfig(filepath.Join("testdata", "good.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	want := sazabi.Config{
+		Environment:      "production",
+		Level:            "warn",
+		Encoding:         "json",
+		OutputPaths:      []string{"stdout"},
+		ErrorOutputPaths: []string{"stdout"},
+		Sampling:         &sazabi.SamplingConfig{Initial: 10, Thereafter: 5},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("LoadConfig() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	_, err := sazabi.LoadConfig(filepath.Join("testdata", "bad.yml"))
+	var cerr *sazabi.ConfigError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("LoadConfig() error = %v, want a *ConfigError", err)
+	}
+	for _, field := range []string{"level: ", "sampling: "} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("error %q does not name %s", err, strings.TrimSuffix(field, ": "))
+		}
+	}
+}
+
+func TestLoadConfigUnknownField(t *testing.T) {
+	_, err := sazabi.LoadConfig(filepath.Join("testdata", "unknown.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "field levle not found") {
+		t.Errorf("LoadConfig() error = %v, want the unknown field reported", err)
+	}
+}
+
+func TestDecodeDuration(t *testing.T) {
+	var cfg sazabi.Config
+	if err := yamlconfig.Decode([]byte("rotation:\n  interval: 1h30m\n"), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Rotation == nil || cfg.Rotation.Interval != 90*time.Minute {
+		t.Errorf("rotation = %+v, want a 1h30m interval", cfg.Rotation)
+	}
+}